@@ -0,0 +1,57 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import "time"
+
+// ChangeType specifies what kind of element was added, removed, or changed.
+type ChangeType string
+
+// The types of changes between two discovery results.
+const (
+	NetnsAdded           ChangeType = "netns-added"
+	NetnsRemoved         ChangeType = "netns-removed"
+	ContainerAdded       ChangeType = "container-added"
+	ContainerRemoved     ChangeType = "container-removed"
+	NifAdded             ChangeType = "nif-added"
+	NifRemoved           ChangeType = "nif-removed"
+	NifChanged           ChangeType = "nif-changed"
+	AddressAdded         ChangeType = "address-added"
+	AddressRemoved       ChangeType = "address-removed"
+	RouteAdded           ChangeType = "route-added"
+	RouteRemoved         ChangeType = "route-removed"
+	PortAdded            ChangeType = "port-added"
+	PortRemoved          ChangeType = "port-removed"
+	ForwardedPortAdded   ChangeType = "forwarded-port-added"
+	ForwardedPortRemoved ChangeType = "forwarded-port-removed"
+)
+
+// ChangeEvent describes a single change between two discovery results. The
+// ID is the stable identity of the element concerned, as used by the diff
+// package, so that elements keep their identities across container restarts.
+// The references use the same identifiers as the v1 discovery JSON, so clients
+// can correlate change events with a (re)fetched discovery result; these are
+// the references in the old discovery result for removed elements and in the
+// new discovery result otherwise.
+type ChangeEvent struct {
+	Type         ChangeType `json:"type"`
+	ID           string     `json:"id"`
+	NetnsRef     string     `json:"netns-idref,omitempty"`
+	ContainerRef string     `json:"container-idref,omitempty"`
+	NifRef       string     `json:"network-interface-idref,omitempty"`
+	Name         string     `json:"name,omitempty"`   // container or network interface name.
+	Detail       string     `json:"detail,omitempty"` // address, route, or port in textual form.
+	From         string     `json:"from,omitempty"`   // old operational state of a changed nif.
+	To           string     `json:"to,omitempty"`     // new operational state of a changed nif.
+}
+
+// Changes is a single message in a stream of change events, covering the
+// changes between two successive discovery results.
+type Changes struct {
+	Seq     uint64        `json:"seq"`              // sequence number of the newer discovery result.
+	Time    time.Time     `json:"time"`             // when the newer discovery result was taken.
+	Missed  bool          `json:"missed,omitempty"` // intermediate discovery results were skipped.
+	Changes []ChangeEvent `json:"changes"`
+}
@@ -0,0 +1,30 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v1 change events", func() {
+
+	It("marshals according to the API specification", func() {
+		changes := []ChangeEvent{
+			{Type: ContainerAdded, ID: "docker:web", NetnsRef: "netns-4026532666",
+				ContainerRef: "cont-1234", Name: "web"},
+			{Type: NifChanged, ID: "docker:web/eth0", NetnsRef: "netns-4026532666",
+				NifRef: "nif-4026532666-7", Name: "eth0", From: "up", To: "down"},
+			{Type: PortRemoved, ID: "docker:web/tcp/0.0.0.0:80", NetnsRef: "netns-4026532666",
+				Detail: "tcp 0.0.0.0:80"},
+		}
+		jtext, err := json.Marshal(changes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(validate(v1apispec, "Change-Events", jtext)).To(Succeed())
+	})
+
+})
@@ -0,0 +1,190 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/decorator/kuhbernetes"
+	"github.com/thediveo/lxkns/model"
+)
+
+// CommunicationsResult contains the communication edges between the
+// discovered network namespaces and to the outside for JSON marshalling.
+type CommunicationsResult struct {
+	Metadata       Metadata        `json:"metadata"`
+	Communications []communication `json:"communications"`
+}
+
+// communication describes a single communication edge between a client and a
+// server, based on connected sockets.
+type communication struct {
+	Protocol      string                `json:"protocol"`
+	Kind          string                `json:"kind"`
+	Client        communicationEndpoint `json:"client"`
+	Server        communicationEndpoint `json:"server"`
+	ForwardedPort *communicationFwdPort `json:"forwarded-port,omitempty"`
+}
+
+// communicationEndpoint describes one end of a communication edge. External
+// endpoints only have an address and port.
+type communicationEndpoint struct {
+	Address     net.IP     `json:"address"`
+	Port        uint16     `json:"port"`
+	ServiceName string     `json:"servicename"`
+	External    bool       `json:"external"`
+	NetnsRef    string     `json:"netns-idref,omitempty"`
+	NetnsID     uint64     `json:"netnsid,omitempty"`
+	Owners      []owner    `json:"owners"`
+	Workloads   []workload `json:"workloads"`
+}
+
+// communicationFwdPort describes the forwarded port a communication passes
+// through.
+type communicationFwdPort struct {
+	NetnsRef    string `json:"netns-idref"`
+	IP          net.IP `json:"ip"`
+	Port        uint16 `json:"port"`
+	ForwardIP   net.IP `json:"forward-ip"`
+	ForwardPort uint16 `json:"forward-port"`
+}
+
+// workload describes a container or stand-alone process owning an endpoint.
+type workload struct {
+	Name         string        `json:"name"`
+	Type         string        `json:"type"` // container type, or "process"
+	PID          model.PIDType `json:"pid"`
+	Pod          string        `json:"pod,omitempty"`
+	ContainerRef string        `json:"container-idref"`
+}
+
+// NewCommunicationsResult returns a new CommunicationsResult for the specified
+// discovery results, to be marshalled into JSON.
+func NewCommunicationsResult(result gostwire.DiscoveryResult) CommunicationsResult {
+	edges := network.NewCommunicationEdges(result.Netns)
+	comms := make([]communication, 0, len(edges))
+	for _, edge := range edges {
+		protocol := strings.ToLower(edge.Protocol.String())
+		comm := communication{
+			Protocol: protocol,
+			Kind:     edge.Kind().String(),
+			Client:   newCommunicationEndpoint(edge.Client, protocol),
+			Server:   newCommunicationEndpoint(edge.Server, protocol),
+		}
+		if fwd := edge.Forwarded; fwd != nil {
+			var netnsref string
+			for _, netns := range result.Netns {
+				if containsForwardedPort(netns, fwd) {
+					netnsref = netnsID(netns)
+					break
+				}
+			}
+			comm.ForwardedPort = &communicationFwdPort{
+				NetnsRef:    netnsref,
+				IP:          fwd.IP,
+				Port:        edge.Server.Port - fwd.ForwardPortMin + fwd.PortMin,
+				ForwardIP:   fwd.ForwardIP,
+				ForwardPort: edge.Server.Port,
+			}
+		}
+		comms = append(comms, comm)
+	}
+	return CommunicationsResult{
+		Metadata:       NewMetadata(result),
+		Communications: comms,
+	}
+}
+
+// newCommunicationEndpoint returns the JSON representation of the specified
+// communication endpoint.
+func newCommunicationEndpoint(ep network.CommunicationEndpoint, protocol string) communicationEndpoint {
+	jep := communicationEndpoint{
+		Address:     ep.IP,
+		Port:        ep.Port,
+		ServiceName: serviceName(ep.Port, protocol),
+		External:    ep.External(),
+		Owners:      []owner{},
+		Workloads:   []workload{},
+	}
+	if ep.Netns == nil {
+		return jep
+	}
+	jep.NetnsRef = netnsID(ep.Netns)
+	jep.NetnsID = ep.Netns.ID().Ino
+	var procs []*model.Process
+	if ep.Socket != nil {
+		jep.Owners = newOwners(ep.Socket.Processes, ep.Socket.ForeignProcesses, ep.Netns.SocketOwners)
+		for _, proc := range ep.Socket.Processes {
+			procs = append(procs, leader(proc))
+		}
+	}
+	// Without any known socket owners we can at least tell which workloads
+	// are attached to the endpoint's network namespace.
+	if len(procs) == 0 {
+		for _, tenant := range ep.Netns.Tenants {
+			if tenant.Process.PPID == 0 && tenant.Process.PID == 2 {
+				continue // skip kthreadd
+			}
+			procs = append(procs, tenant.Process)
+		}
+	}
+	seen := map[model.PIDType]bool{}
+	for _, proc := range procs {
+		if seen[proc.PID] {
+			continue
+		}
+		seen[proc.PID] = true
+		jep.Workloads = append(jep.Workloads, newWorkload(proc))
+	}
+	return jep
+}
+
+// newWorkload returns the workload information for the specified (leader)
+// process.
+func newWorkload(proc *model.Process) workload {
+	w := workload{
+		Name:         proc.Name,
+		Type:         "process",
+		PID:          proc.PID,
+		ContainerRef: cntrID(proc),
+	}
+	if c := proc.Container; c != nil {
+		w.Name = c.Name
+		w.Type = v1ContainerType(c.Type)
+		for _, g := range c.Groups {
+			if g.Type == kuhbernetes.PodGroupType {
+				w.Pod = g.Name
+				break
+			}
+		}
+	}
+	return w
+}
+
+// netnsID returns the JSON document-local identifier for the specified network
+// namespace.
+func netnsID(netns *network.NetworkNamespace) string {
+	return "netns-" + strconv.FormatUint(netns.ID().Ino, 10)
+}
+
+// containsForwardedPort returns true if the specified forwarded port belongs
+// to the specified network namespace.
+func containsForwardedPort(netns *network.NetworkNamespace, fwd *network.ForwardedPort) bool {
+	for idx := range netns.ForwardedPortsv4 {
+		if &netns.ForwardedPortsv4[idx] == fwd {
+			return true
+		}
+	}
+	for idx := range netns.ForwardedPortsv6 {
+		if &netns.ForwardedPortsv6[idx] == fwd {
+			return true
+		}
+	}
+	return false
+}
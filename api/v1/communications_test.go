@@ -0,0 +1,37 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ohler55/ojg/oj"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v1 communications API", func() {
+
+	BeforeEach(func() {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+	})
+
+	It("conforms to its API spec", func() {
+		c := NewCommunicationsResult(disco)
+		jtext, err := json.Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validate(v1apispec, "CommunicationsResult", jtext)).To(Succeed())
+
+		v, err := oj.Parse(jtext)
+		Expect(err).NotTo(HaveOccurred(), "json: %s", string(jtext))
+		Expect(jsnpsl(v, `$.communications[*]`)).To(HaveLen(len(c.Communications)))
+	})
+
+})
@@ -0,0 +1,77 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// volatileFields are the names of JSON object fields whose values change with
+// nearly every discovery, even if nothing changed in the discovered system:
+// socket queue lengths, TCP and MPTCP connection statistics, as well as
+// multicast routing statistics.
+var volatileFields = map[string]struct{}{
+	"recv-queue":  {},
+	"send-queue":  {},
+	"tcp-info":    {},
+	"mptcp-info":  {},
+	"bytes-in":    {},
+	"packets-in":  {},
+	"bytes-out":   {},
+	"packets-out": {},
+	"bytes":       {},
+	"packets":     {},
+	"wrong-if":    {},
+}
+
+// ContentHash returns a hex-encoded hash of the specified JSON rendering of a
+// discovery result, covering only its stable projection: the top-level
+// "metadata" element as well as all volatile statistics fields are left out.
+// Thus, discovery results differing only in, say, their creation timestamps
+// or socket queue lengths get the same hash.
+//
+// As some parts of discovery results are marshalled in map iteration order,
+// the JSON gets canonicalized before hashing it, relying on marshalling maps
+// sorting their keys.
+func ContentHash(rendering []byte) (string, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(rendering))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return "", err
+	}
+	if fields, ok := doc.(map[string]interface{}); ok {
+		delete(fields, "metadata")
+	}
+	canonical, err := json.Marshal(stableProjection(doc))
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(canonical)
+	return hex.EncodeToString(hash[:16]), nil
+}
+
+// stableProjection removes all volatile fields from the specified decoded
+// JSON value, in place, returning the value for convenience.
+func stableProjection(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if _, ok := volatileFields[name]; ok {
+				delete(v, name)
+				continue
+			}
+			stableProjection(value)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			stableProjection(elem)
+		}
+	}
+	return v
+}
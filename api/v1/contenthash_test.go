@@ -0,0 +1,31 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("content hash", func() {
+
+	It("hashes only the stable projection", func() {
+		h, err := ContentHash([]byte(`{
+			"metadata": {"creation-timestamp": "2023-01-01T00:00:00Z"},
+			"a": 1, "b": [{"local-port": 80, "recv-queue": 1, "tcp-info": {"rtt": 42}}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(h).To(MatchRegexp(`^[0-9a-f]{32}$`))
+
+		Expect(ContentHash([]byte(`{
+			"b": [{"tcp-info": {"rtt": 666}, "local-port": 80, "recv-queue": 2, "send-queue": 3}],
+			"a": 1,
+			"metadata": {"creation-timestamp": "2023-01-02T00:00:00Z"}}`))).To(Equal(h))
+		Expect(ContentHash([]byte(`{"a": 1, "b": [{"local-port": 81}]}`))).NotTo(Equal(h))
+
+		_, err = ContentHash([]byte(`{`))
+		Expect(err).To(HaveOccurred())
+	})
+
+})
@@ -0,0 +1,88 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/species"
+)
+
+// CountersResult contains the protocol-level counters of the discovered
+// network namespaces for JSON marshalling.
+type CountersResult struct {
+	Metadata          Metadata        `json:"metadata"`
+	NetworkNamespaces []netnsCounters `json:"network-namespaces"`
+}
+
+// netnsCounters contains the protocol-level counters of a single network
+// namespace, and optionally the changes since an earlier sample.
+type netnsCounters struct {
+	ID        string                      `json:"id"`
+	NetnsID   uint64                      `json:"netnsid"`
+	Timestamp time.Time                   `json:"timestamp"`
+	Counters  map[string]map[string]int64 `json:"counters"`
+	Sockstat  map[string]map[string]int64 `json:"sockstat"`
+	Delta     *countersDelta              `json:"delta,omitempty"`
+}
+
+// countersDelta contains only the changed counters between two samples.
+type countersDelta struct {
+	Interval float64                     `json:"interval"` // in seconds
+	Counters map[string]map[string]int64 `json:"counters"`
+	Sockstat map[string]map[string]int64 `json:"sockstat"`
+}
+
+// NewCountersResult returns a new CountersResult for the specified discovery
+// results, to be marshalled into JSON. If later samples are specified, then
+// these later samples are reported instead, together with their changes since
+// the samples taken during discovery. Network namespaces without any samples
+// are skipped.
+func NewCountersResult(
+	result gostwire.DiscoveryResult,
+	later map[species.NamespaceID]*network.ProtocolCounters,
+	opts ...ResultOption,
+) CountersResult {
+	allcounters := make([]netnsCounters, 0, len(result.Netns))
+	for netnsid, netns := range result.Netns {
+		sample := netns.ProtocolCounters
+		if sample == nil {
+			continue
+		}
+		var delta *countersDelta
+		if later != nil {
+			latersample := later[netnsid]
+			if latersample == nil {
+				continue
+			}
+			d := latersample.Delta(sample)
+			delta = &countersDelta{
+				Interval: d.Interval.Seconds(),
+				Counters: d.Counters,
+				Sockstat: d.Sockstat,
+			}
+			sample = latersample
+		}
+		allcounters = append(allcounters, netnsCounters{
+			ID:        "netns-" + strconv.FormatUint(netnsid.Ino, 10),
+			NetnsID:   netnsid.Ino,
+			Timestamp: sample.Timestamp.UTC(),
+			Counters:  sample.Counters,
+			Sockstat:  sample.Sockstat,
+			Delta:     delta,
+		})
+	}
+	sort.Slice(allcounters, func(a, b int) bool {
+		return allcounters[a].NetnsID < allcounters[b].NetnsID
+	})
+	return CountersResult{
+		Metadata:          MetadataOf(result, opts...),
+		NetworkNamespaces: allcounters,
+	}
+}
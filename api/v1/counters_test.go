@@ -0,0 +1,61 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ohler55/ojg/oj"
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v1 counters API", func() {
+
+	BeforeEach(func() {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+	})
+
+	It("conforms to its API spec", func() {
+		c := NewCountersResult(disco, nil)
+		jtext, err := json.Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validate(v1apispec, "CountersResult", jtext)).To(Succeed())
+	})
+
+	It("reports counters and deltas", func() {
+		c := NewCountersResult(disco, nil)
+		jtext, err := json.Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		v, err := oj.Parse(jtext)
+		Expect(err).NotTo(HaveOccurred(), "json: %s", string(jtext))
+		Expect(jsnpsl(v, `$['network-namespaces'][*]`)).To(HaveLen(len(disco.Netns)))
+		Expect(jsnpsl(v, `$['network-namespaces'][*].counters.TcpExt.ListenOverflows`)).NotTo(BeEmpty())
+		Expect(jsnpsl(v, `$['network-namespaces'][*].delta`)).To(BeEmpty())
+
+		later := map[species.NamespaceID]*network.ProtocolCounters{}
+		for netnsid, netns := range disco.Netns {
+			later[netnsid], err = netns.SampleProtocolCounters()
+			Expect(err).NotTo(HaveOccurred())
+		}
+		c = NewCountersResult(disco, later)
+		jtext, err = json.Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(validate(v1apispec, "CountersResult", jtext)).To(Succeed())
+		for _, netnsc := range c.NetworkNamespaces {
+			Expect(netnsc.Delta).NotTo(BeNil())
+			Expect(netnsc.Delta.Interval).To(BeNumerically(">", 0))
+		}
+	})
+
+})
@@ -18,10 +18,11 @@ type DiscoveryResult struct {
 }
 
 // NewDiscoveryResult returns a JSON marshallable DiscoveryResult for the given
-// gostwire.DiscoveryResult.
-func NewDiscoveryResult(result gostwire.DiscoveryResult) DiscoveryResult {
+// gostwire.DiscoveryResult. Unless specified using WithMetadata, it creates new
+// metadata.
+func NewDiscoveryResult(result gostwire.DiscoveryResult, opts ...ResultOption) DiscoveryResult {
 	return DiscoveryResult{
-		Metadata:          NewMetadata(result),
+		Metadata:          MetadataOf(result, opts...),
 		NetworkNamespaces: newNetworkNamespace(result.Netns),
 		PIDNamespaces:     pidNamespaces(result.Lxkns.Namespaces[model.PIDNS]),
 	}
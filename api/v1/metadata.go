@@ -20,9 +20,19 @@ const CreatorID = "gostwire"
 // ("Dr. Livingstone, I presume") and when the discovery was done.
 type Metadata map[string]interface{}
 
+// PluginRun is the outcome of running a particular decorator or metadata
+// plugin, with the duration in milliseconds.
+type PluginRun struct {
+	Plugin   string  `json:"plugin"`
+	Group    string  `json:"group"`
+	Outcome  string  `json:"outcome"`
+	Duration float64 `json:"duration"`
+}
+
 // NewMetadata returns new and properly filled-in discovery meta data. It
 // invokes the registered metadata plugins to augment the baseline metadata with
-// additional tidbits of information.
+// additional tidbits of information. Finally, it adds the diagnostics of the
+// discovery, as well as the discovery phase timings and plugin outcomes.
 func NewMetadata(result gostwire.DiscoveryResult) Metadata {
 	basemd := Metadata{
 		"creator":            "Gostwire Linux virtual networking topology and configuration discovery engine",
@@ -32,7 +42,52 @@ func NewMetadata(result gostwire.DiscoveryResult) Metadata {
 	}
 	md, err := metadata.Augment(result, basemd)
 	if err != nil {
-		return basemd
+		md = basemd
+	}
+	md["diagnostics"] = result.Diagnostics.List()
+	timings := map[string]float64{}
+	for _, timing := range result.Diagnostics.Timings() {
+		timings[string(timing.Phase)] = float64(timing.Duration) / float64(time.Millisecond)
+	}
+	md["timings"] = timings
+	plugins := []PluginRun{}
+	for _, run := range result.Diagnostics.PluginRuns() {
+		plugins = append(plugins, PluginRun{
+			Plugin:   run.Plugin,
+			Group:    string(run.Group),
+			Outcome:  string(run.Outcome),
+			Duration: float64(run.Duration) / float64(time.Millisecond),
+		})
 	}
+	md["plugins"] = plugins
 	return md
 }
+
+// ResultOption is an option for creating JSON marshallable results from a
+// discovery result.
+type ResultOption func(*resultOptions)
+
+type resultOptions struct {
+	metadata Metadata
+}
+
+// WithMetadata uses the specified metadata instead of creating new metadata
+// using NewMetadata. This allows multiple results created from the same
+// discovery result to share their metadata, running the metadata plugins only
+// once.
+func WithMetadata(md Metadata) ResultOption {
+	return func(o *resultOptions) { o.metadata = md }
+}
+
+// MetadataOf returns the metadata passed in the specified options, or
+// otherwise new metadata for the specified discovery result.
+func MetadataOf(result gostwire.DiscoveryResult, opts ...ResultOption) Metadata {
+	var o resultOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.metadata != nil {
+		return o.metadata
+	}
+	return NewMetadata(result)
+}
@@ -0,0 +1,166 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// mptcp describes the MPTCP path manager configuration as well as the MPTCP
+// connections of a network namespace.
+type mptcp struct {
+	Endpoints   []mptcpEndpoint      `json:"endpoints"`
+	Limits      *mptcpLimits         `json:"limits,omitempty"`
+	Connections ipvxMPTCPConnections `json:"connections"`
+}
+
+type ipvxMPTCPConnections struct {
+	IPv4 mptcpConnections `json:"ipv4"`
+	IPv6 mptcpConnections `json:"ipv6"`
+}
+
+// mptcpConnections are MPTCP connections together with the details of their
+// owning processes.
+type mptcpConnections struct {
+	conns  []network.MPTCPConnection
+	owners network.SocketOwners
+}
+
+// mptcpConnection describes an MPTCP socket together with its TCP subflows.
+type mptcpConnection struct {
+	port
+	Info     *mptcpInfo `json:"mptcp-info,omitempty"`
+	Subflows []port     `json:"subflows"`
+}
+
+// mptcpInfo describes the details of an MPTCP connection.
+type mptcpInfo struct {
+	Token              uint32 `json:"token"`
+	Flags              uint32 `json:"flags"`
+	Subflows           uint8  `json:"subflows"`
+	SubflowsMax        uint8  `json:"subflows-max"`
+	AddAddrSignal      uint8  `json:"add-addr-signal"`
+	AddAddrSignalMax   uint8  `json:"add-addr-signal-max"`
+	AddAddrAccepted    uint8  `json:"add-addr-accepted"`
+	AddAddrAcceptedMax uint8  `json:"add-addr-accepted-max"`
+	Retransmits        uint32 `json:"retransmits"`
+	BytesSent          uint64 `json:"bytes-sent"`
+	BytesReceived      uint64 `json:"bytes-received"`
+	BytesAcked         uint64 `json:"bytes-acked"`
+}
+
+// mptcpSubflow describes the MPTCP-specific details of a TCP subflow.
+type mptcpSubflow struct {
+	LocalToken  uint32 `json:"local-token"`
+	RemoteToken uint32 `json:"remote-token"`
+	LocalID     uint8  `json:"local-id"`
+	RemoteID    uint8  `json:"remote-id"`
+	Flags       uint32 `json:"flags"`
+}
+
+// mptcpEndpoint describes an MPTCP path manager endpoint.
+type mptcpEndpoint struct {
+	ID      uint8    `json:"id"`
+	Address net.IP   `json:"address"`
+	Port    uint16   `json:"port,omitempty"`
+	Flags   []string `json:"flags"`
+	NifRef  string   `json:"network-interface-idref,omitempty"`
+}
+
+// mptcpLimits describes the limits of an MPTCP path manager.
+type mptcpLimits struct {
+	AddAddrAccepted uint32 `json:"add-addr-accepted"`
+	Subflows        uint32 `json:"subflows"`
+}
+
+func (c mptcpConnections) MarshalJSON() ([]byte, error) {
+	conns := make([]mptcpConnection, 0, len(c.conns))
+	for _, conn := range c.conns {
+		subflows := make([]port, 0, len(conn.Subflows))
+		for _, subflow := range conn.Subflows {
+			subflows = append(subflows, newPort(subflow, c.owners))
+		}
+		conns = append(conns, mptcpConnection{
+			port:     newPort(conn.ProcessSocket, c.owners),
+			Info:     newMPTCPInfo(conn.Info),
+			Subflows: subflows,
+		})
+	}
+	return json.Marshal(conns)
+}
+
+// newMPTCP returns the JSON representation of the MPTCP details of the
+// specified network namespace, or nil if there's nothing to tell about MPTCP.
+func newMPTCP(netns *network.NetworkNamespace) *mptcp {
+	if netns.MPTCPLimits == nil && len(netns.MPTCPEndpoints) == 0 &&
+		len(netns.MPTCPv4) == 0 && len(netns.MPTCPv6) == 0 {
+		return nil
+	}
+	endpoints := make([]mptcpEndpoint, 0, len(netns.MPTCPEndpoints))
+	for _, endpoint := range netns.MPTCPEndpoints {
+		endpoints = append(endpoints, mptcpEndpoint{
+			ID:      endpoint.ID,
+			Address: endpoint.IP,
+			Port:    endpoint.Port,
+			Flags:   endpoint.Flags.Names(),
+			NifRef:  nifID(endpoint.Nif),
+		})
+	}
+	var limits *mptcpLimits
+	if netns.MPTCPLimits != nil {
+		limits = &mptcpLimits{
+			AddAddrAccepted: netns.MPTCPLimits.AddAddrAccepted,
+			Subflows:        netns.MPTCPLimits.Subflows,
+		}
+	}
+	return &mptcp{
+		Endpoints: endpoints,
+		Limits:    limits,
+		Connections: ipvxMPTCPConnections{
+			IPv4: mptcpConnections{conns: netns.MPTCPv4, owners: netns.SocketOwners},
+			IPv6: mptcpConnections{conns: netns.MPTCPv6, owners: netns.SocketOwners},
+		},
+	}
+}
+
+// newMPTCPInfo returns the JSON representation of the specified MPTCP
+// connection details, or nil.
+func newMPTCPInfo(info *network.MPTCPInfo) *mptcpInfo {
+	if info == nil {
+		return nil
+	}
+	return &mptcpInfo{
+		Token:              info.Token,
+		Flags:              info.Flags,
+		Subflows:           info.Subflows,
+		SubflowsMax:        info.SubflowsMax,
+		AddAddrSignal:      info.AddAddrSignal,
+		AddAddrSignalMax:   info.AddAddrSignalMax,
+		AddAddrAccepted:    info.AddAddrAccepted,
+		AddAddrAcceptedMax: info.AddAddrAcceptedMax,
+		Retransmits:        info.Retransmits,
+		BytesSent:          info.BytesSent,
+		BytesReceived:      info.BytesReceived,
+		BytesAcked:         info.BytesAcked,
+	}
+}
+
+// newMPTCPSubflow returns the JSON representation of the specified MPTCP
+// subflow details, or nil.
+func newMPTCPSubflow(subflow *network.MPTCPSubflow) *mptcpSubflow {
+	if subflow == nil {
+		return nil
+	}
+	return &mptcpSubflow{
+		LocalToken:  subflow.LocalToken,
+		RemoteToken: subflow.RemoteToken,
+		LocalID:     subflow.LocalID,
+		RemoteID:    subflow.RemoteID,
+		Flags:       subflow.Flags,
+	}
+}
@@ -0,0 +1,195 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// multicastGroups describes the multicast groups joined on a network
+// interface, as well as its link-layer multicast addresses.
+type multicastGroups struct {
+	IPv4 []multicastGroup `json:"ipv4"`
+	IPv6 []multicastGroup `json:"ipv6"`
+	L2   []string         `json:"l2"`
+}
+
+// multicastGroup is a joined IPv4 or IPv6 multicast group, together with the
+// processes that could be attributed to this group.
+type multicastGroup struct {
+	Group  net.IP  `json:"group"`
+	Users  int     `json:"users"`
+	Owners []owner `json:"owners"`
+}
+
+// bridgeMulticast describes the IGMP/MLD snooping state of a bridge.
+type bridgeMulticast struct {
+	Snooping    bool                `json:"snooping"`
+	Querier     bool                `json:"querier"`
+	IGMPVersion uint8               `json:"igmp-version,omitempty"`
+	MLDVersion  uint8               `json:"mld-version,omitempty"`
+	MDB         []bridgeMulticastDB `json:"mdb"`
+}
+
+// bridgeMulticastDB is a single bridge multicast database entry.
+type bridgeMulticastDB struct {
+	Port      *nifRef `json:"port,omitempty"`
+	Group     string  `json:"group"`
+	VID       uint16  `json:"vid,omitempty"`
+	Permanent bool    `json:"permanent"`
+}
+
+// ipvxMulticastRouting describes the IPv4 and IPv6 multicast routing state of
+// a network namespace; either one is omitted when not routing multicast.
+type ipvxMulticastRouting struct {
+	IPv4 *multicastRouting `json:"ipv4,omitempty"`
+	IPv6 *multicastRouting `json:"ipv6,omitempty"`
+}
+
+type multicastRouting struct {
+	VIFs   []multicastVIF   `json:"vifs"`
+	Routes []multicastRoute `json:"routes"`
+}
+
+type multicastVIF struct {
+	Index    int     `json:"index"`
+	Name     string  `json:"name"`
+	Nif      *nifRef `json:"nif,omitempty"`
+	BytesIn  uint64  `json:"bytes-in"`
+	PktsIn   uint64  `json:"packets-in"`
+	BytesOut uint64  `json:"bytes-out"`
+	PktsOut  uint64  `json:"packets-out"`
+	Flags    uint32  `json:"flags"`
+	Local    net.IP  `json:"local,omitempty"`
+	Remote   net.IP  `json:"remote,omitempty"`
+}
+
+type multicastRoute struct {
+	Group      net.IP      `json:"group"`
+	Origin     net.IP      `json:"origin"`
+	InputVIF   int         `json:"input-vif"`
+	Unresolved bool        `json:"unresolved"`
+	Packets    uint64      `json:"packets"`
+	Bytes      uint64      `json:"bytes"`
+	WrongIf    uint64      `json:"wrong-if"`
+	Outputs    []outputVIF `json:"output-vifs"`
+}
+
+type outputVIF struct {
+	VIF int `json:"vif"`
+	TTL int `json:"ttl"`
+}
+
+// newMulticastGroups returns the JSON representation of the multicast groups
+// of the specified network interface, or nil if there are none.
+func newMulticastGroups(nifattrs *network.NifAttrs) *multicastGroups {
+	if len(nifattrs.McastGroupsv4) == 0 && len(nifattrs.McastGroupsv6) == 0 &&
+		len(nifattrs.McastL2Addrs) == 0 {
+		return nil
+	}
+	l2addrs := make([]string, 0, len(nifattrs.McastL2Addrs))
+	for _, l2addr := range nifattrs.McastL2Addrs {
+		l2addrs = append(l2addrs, l2addr.String())
+	}
+	return &multicastGroups{
+		IPv4: newMulticastGroupList(nifattrs.McastGroupsv4),
+		IPv6: newMulticastGroupList(nifattrs.McastGroupsv6),
+		L2:   l2addrs,
+	}
+}
+
+func newMulticastGroupList(groups []network.MulticastGroup) []multicastGroup {
+	mcgroups := make([]multicastGroup, 0, len(groups))
+	for _, group := range groups {
+		owners := make([]owner, 0, len(group.Processes))
+		for _, proc := range group.Processes {
+			owners = append(owners, owner{
+				PID:          proc.PID,
+				Cmdline:      strings.Join(proc.Cmdline, " "),
+				ContainerRef: cntrID(leader(proc)),
+			})
+		}
+		mcgroups = append(mcgroups, multicastGroup{
+			Group:  group.Address,
+			Users:  group.Users,
+			Owners: owners,
+		})
+	}
+	return mcgroups
+}
+
+// newBridgeMulticast returns the JSON representation of the IGMP/MLD snooping
+// state of the specified bridge.
+func newBridgeMulticast(br *network.BridgeAttrs) *bridgeMulticast {
+	mdb := make([]bridgeMulticastDB, 0, len(br.MulticastDB))
+	for _, entry := range br.MulticastDB {
+		group := entry.L2Group.String()
+		if entry.Group != nil {
+			group = entry.Group.String()
+		}
+		mdb = append(mdb, bridgeMulticastDB{
+			Port:      newNifRef(entry.Port),
+			Group:     group,
+			VID:       entry.VID,
+			Permanent: entry.Permanent,
+		})
+	}
+	return &bridgeMulticast{
+		Snooping:    br.MulticastSnooping,
+		Querier:     br.MulticastQuerier,
+		IGMPVersion: br.IGMPVersion,
+		MLDVersion:  br.MLDVersion,
+		MDB:         mdb,
+	}
+}
+
+// newMulticastRouting returns the JSON representation of the multicast
+// routing state, or nil.
+func newMulticastRouting(mr *network.MulticastRouting) *multicastRouting {
+	if mr == nil {
+		return nil
+	}
+	vifs := make([]multicastVIF, 0, len(mr.VIFs))
+	for _, vif := range mr.VIFs {
+		vifs = append(vifs, multicastVIF{
+			Index:    vif.Index,
+			Name:     vif.Name,
+			Nif:      newNifRef(vif.Nif),
+			BytesIn:  vif.BytesIn,
+			PktsIn:   vif.PktsIn,
+			BytesOut: vif.BytesOut,
+			PktsOut:  vif.PktsOut,
+			Flags:    vif.Flags,
+			Local:    vif.Local,
+			Remote:   vif.Remote,
+		})
+	}
+	routes := make([]multicastRoute, 0, len(mr.Routes))
+	for _, route := range mr.Routes {
+		outputs := make([]outputVIF, 0, len(route.Outputs))
+		for vif, ttl := range route.Outputs {
+			outputs = append(outputs, outputVIF{VIF: vif, TTL: ttl})
+		}
+		sort.Slice(outputs, func(a, b int) bool { return outputs[a].VIF < outputs[b].VIF })
+		routes = append(routes, multicastRoute{
+			Group:      route.Group,
+			Origin:     route.Origin,
+			InputVIF:   route.InputVIF,
+			Unresolved: route.Unresolved(),
+			Packets:    route.Packets,
+			Bytes:      route.Bytes,
+			WrongIf:    route.WrongIf,
+			Outputs:    outputs,
+		})
+	}
+	return &multicastRouting{
+		VIFs:   vifs,
+		Routes: routes,
+	}
+}
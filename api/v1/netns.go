@@ -20,6 +20,7 @@ import (
 	"github.com/thediveo/whalewatcher/engineclient/moby"
 	"github.com/thediveo/whalewatcher/watcher/containerd"
 	"github.com/thediveo/whalewatcher/watcher/cri"
+	"golang.org/x/exp/slices"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -88,7 +89,16 @@ func (n *networkNamespaces) MarshalJSON() ([]byte, error) {
 	b := bytes.Buffer{}
 	b.WriteRune('[')
 	first := true
+	// Emit the network namespaces in a stable order, so that unchanged
+	// discovery results also result in the same JSON.
+	netnses := make([]*network.NetworkNamespace, 0, len(n.NetworkNamespaces))
 	for _, netns := range n.NetworkNamespaces {
+		netnses = append(netnses, netns)
+	}
+	slices.SortFunc(netnses, func(a, b *network.NetworkNamespace) int {
+		return cmpUint64(a.ID().Ino, b.ID().Ino)
+	})
+	for _, netns := range netnses {
 		if first {
 			first = false
 		} else {
@@ -109,14 +119,20 @@ type networkNamespace network.NetworkNamespace
 // NetworkNamespaceJSON describes discovery details for a single network
 // namespace, marshallable into JSON.
 type NetworkNamespaceJSON struct {
-	ContainerGroups   []*containerGroup  `json:"container-groups"`
-	Containers        []container        `json:"containers"`
-	ID                string             `json:"id"`
-	NetnsID           uint64             `json:"netnsid"`
-	NetworkInterfaces []networkInterface `json:"network-interfaces"`
-	Routes            ipvxRoutes         `json:"routes"`
-	TransportPorts    ipvxPorts          `json:"transport-ports"`
-	ForwardedPorts    ipvxForwardedPorts `json:"forwarded-ports"`
+	ContainerGroups   []*containerGroup     `json:"container-groups"`
+	Containers        []container           `json:"containers"`
+	ID                string                `json:"id"`
+	NetnsID           uint64                `json:"netnsid"`
+	NetworkInterfaces []networkInterface    `json:"network-interfaces"`
+	Routes            ipvxRoutes            `json:"routes"`
+	TransportPorts    ipvxPorts             `json:"transport-ports"`
+	MPTCP             *mptcp                `json:"mptcp,omitempty"`
+	ForwardedPorts    ipvxForwardedPorts    `json:"forwarded-ports"`
+	McastRouting      *ipvxMulticastRouting `json:"multicast-routing,omitempty"`
+	Sysctls           network.Sysctls       `json:"sysctls,omitempty"` // only deviating sysctls
+	PacketSockets     []packetSocket        `json:"packet-sockets,omitempty"`
+	UnixSockets       []unixSocket          `json:"unix-sockets,omitempty"`
+	BridgingProcesses []bridgingProcess     `json:"bridging-processes,omitempty"`
 }
 
 // mashal emits all the API v1 information about a single network namespace in
@@ -129,7 +145,11 @@ func (n *networkNamespace) marshal(allnetns *networkNamespaces) ([]byte, error)
 	cntrs := make([]container, 0)
 	// Set up the "containers" and also the groups, if necessary.
 	if len(n.Tenants) != 0 {
-		for _, tenant := range n.Tenants {
+		tenants := slices.Clone(n.Tenants)
+		slices.SortFunc(tenants, func(a, b *network.Tenant) int {
+			return cmpUint64(uint64(a.Process.PID), uint64(b.Process.PID))
+		})
+		for _, tenant := range tenants {
 			if tenant.Process.PPID == 0 && tenant.Process.PID == 2 {
 				// skip kthreadd(2) in order to not bedazzle users.
 				continue
@@ -201,6 +221,8 @@ func (n *networkNamespace) marshal(allnetns *networkNamespaces) ([]byte, error)
 					Policy:   tenant.Process.Policy,
 					Priority: tenant.Process.Policy,
 					Nice:     tenant.Process.Nice,
+
+					EngineAPIMounts: newEngineAPIMounts(tenant.EngineAPIMounts),
 				})
 			} else {
 				// It's a stand-alone process
@@ -242,10 +264,23 @@ func (n *networkNamespace) marshal(allnetns *networkNamespaces) ([]byte, error)
 	for _, group := range podgroups {
 		grps = append(grps, group)
 	}
+	slices.SortFunc(grps, func(a, b *containerGroup) int {
+		return strings.Compare(a.ID, b.ID)
+	})
 	nifs := make([]networkInterface, 0, len(n.Nifs))
 	for _, nif := range n.Nifs {
 		nifs = append(nifs, newNif(nif))
 	}
+	slices.SortFunc(nifs, func(a, b networkInterface) int {
+		return cmpUint64(uint64(a.Index), uint64(b.Index))
+	})
+	var mcastrouting *ipvxMulticastRouting
+	if n.McastRoutingv4 != nil || n.McastRoutingv6 != nil {
+		mcastrouting = &ipvxMulticastRouting{
+			IPv4: newMulticastRouting(n.McastRoutingv4),
+			IPv6: newMulticastRouting(n.McastRoutingv6),
+		}
+	}
 	return json.Marshal(&NetworkNamespaceJSON{
 		ContainerGroups:   grps,
 		Containers:        cntrs,
@@ -257,13 +292,19 @@ func (n *networkNamespace) marshal(allnetns *networkNamespaces) ([]byte, error)
 			IPv6: n.Routesv6,
 		},
 		TransportPorts: ipvxPorts{
-			IPv4: n.Portsv4,
-			IPv6: n.Portsv6,
+			IPv4: ports{sockets: n.Portsv4, owners: n.SocketOwners},
+			IPv6: ports{sockets: n.Portsv6, owners: n.SocketOwners},
 		},
+		MPTCP: newMPTCP((*network.NetworkNamespace)(n)),
 		ForwardedPorts: ipvxForwardedPorts{
 			IPv4: n.ForwardedPortsv4,
 			IPv6: n.ForwardedPortsv6,
 		},
+		McastRouting:      mcastrouting,
+		Sysctls:           n.Sysctls.Deviations(),
+		PacketSockets:     newPacketSockets(n.PacketSockets, n.SocketOwners),
+		UnixSockets:       newUnixSockets(n.UnixSockets, n.SocketOwners),
+		BridgingProcesses: newBridgingProcesses(n.BridgingProcesses),
 	})
 }
 
@@ -309,6 +350,8 @@ type container struct {
 	//   - SCHED_BATCH: nice is taken into account.
 	//   - SCHED_IDLE: nice is ignored (basically below a nic of +19).
 	Nice int `json:"nice,omitempty"`
+	// container engine API sockets mounted into this container.
+	EngineAPIMounts []engineAPIMount `json:"engine-api-mounts,omitempty"`
 }
 
 type dns struct {
@@ -329,6 +372,9 @@ func newDns(t *network.Tenant) dns {
 			IP:   ip,
 		})
 	}
+	slices.SortFunc(etchosts, func(a, b namedIP) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 	return dns{
 		DnsConfiguration: &t.DNS,
 		EtcHosts:         etchosts,
@@ -352,3 +398,15 @@ var containerTypesToV1 = map[string]string{
 	podman.Type:     "podman",
 	cri.Type:        "CRI",
 }
+
+// cmpUint64 returns -1, 0, or +1 depending on whether a is less, equal, or
+// greater than b.
+func cmpUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -37,6 +37,9 @@ type networkInterface struct {
 	Vlan          *vlanConfig           `json:"vlan,omitempty"`
 	SRIOVRole     network.SRIOVRole     `json:"sr-iov-role,omitempty"`
 	PF            *nifRef               `json:"pf,omitempty"`
+	Multicast     *multicastGroups      `json:"multicast,omitempty"`
+	BridgeMcast   *bridgeMulticast      `json:"bridge-multicast,omitempty"`
+	Sysctls       network.Sysctls       `json:"sysctls,omitempty"` // only deviating sysctls
 }
 
 type addresses struct {
@@ -112,10 +115,12 @@ func newNif(nif network.Interface) networkInterface {
 	}
 	// Handle slaves of bridges, but also of other masters, and even of PFs.
 	var slaves []*nifRef
+	var bridgemcast *bridgeMulticast
 	if bridge, ok := nif.(network.Bridge); ok {
 		for _, port := range bridge.Bridge().Ports {
 			slaves = append(slaves, newNifRef(port))
 		}
+		bridgemcast = newBridgeMulticast(bridge.Bridge())
 	}
 	var macvlans []*nifRef
 	for _, nif := range nif.Nif().Slaves {
@@ -224,6 +229,9 @@ func newNif(nif network.Interface) networkInterface {
 		Vlan:          vlancfg,
 		SRIOVRole:     nifattrs.SRIOVRole,
 		PF:            pf,
+		Multicast:     newMulticastGroups(nifattrs),
+		BridgeMcast:   bridgemcast,
+		Sysctls:       nifattrs.Sysctls.Deviations(),
 	}
 }
 
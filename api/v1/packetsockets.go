@@ -0,0 +1,58 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/sys/unix"
+)
+
+// packetSocket describes an AF_PACKET socket sniffing or injecting link-layer
+// traffic.
+type packetSocket struct {
+	Type         string  `json:"type"`
+	Protocol     uint16  `json:"protocol"`
+	ProtocolName string  `json:"protocol-name,omitempty"`
+	NifRef       string  `json:"network-interface-idref,omitempty"`
+	Running      bool    `json:"running"`
+	Filtered     bool    `json:"filtered"`
+	Inode        uint64  `json:"inode"`
+	UID          uint32  `json:"uid"`
+	Owners       []owner `json:"owners"`
+}
+
+// ethProtocolNames maps the more common Ethernet protocol numbers to names.
+var ethProtocolNames = map[uint16]string{
+	unix.ETH_P_ALL:   "all",
+	unix.ETH_P_IP:    "ip",
+	unix.ETH_P_IPV6:  "ipv6",
+	unix.ETH_P_ARP:   "arp",
+	unix.ETH_P_8021Q: "802.1q",
+	unix.ETH_P_PAE:   "eapol",
+	unix.ETH_P_LLDP:  "lldp",
+}
+
+// newPacketSockets returns the JSON representation of the specified AF_PACKET
+// sockets, or nil if there are none.
+func newPacketSockets(packsox []network.PacketSocket, details network.SocketOwners) []packetSocket {
+	if len(packsox) == 0 {
+		return nil
+	}
+	sox := make([]packetSocket, 0, len(packsox))
+	for _, sock := range packsox {
+		sox = append(sox, packetSocket{
+			Type:         network.PacketSocketTypeName(sock.Type),
+			Protocol:     sock.Protocol,
+			ProtocolName: ethProtocolNames[sock.Protocol],
+			NifRef:       nifID(sock.Nif),
+			Running:      sock.Running,
+			Filtered:     sock.Filtered,
+			Inode:        sock.Inode,
+			UID:          sock.UID,
+			Owners:       newOwners(sock.Processes, sock.ForeignProcesses, details),
+		})
+	}
+	return sox
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/thediveo/lxkns/model"
+	"golang.org/x/exp/slices"
 )
 
 // pidNamespaces represents a set of PID namespaces and implements JSON
@@ -22,7 +23,14 @@ func (p pidNamespaces) MarshalJSON() ([]byte, error) {
 	b := bytes.Buffer{}
 	b.WriteRune('[')
 	first := true
+	pidnses := make([]model.Namespace, 0, len(p))
 	for _, pidns := range p {
+		pidnses = append(pidnses, pidns)
+	}
+	slices.SortFunc(pidnses, func(a, b model.Namespace) int {
+		return cmpUint64(a.ID().Ino, b.ID().Ino)
+	})
+	for _, pidns := range pidnses {
 		// At the top level only the root PID namespace ... that's the one
 		// without any parent.
 		if pidns.(model.Hierarchy).Parent() != nil {
@@ -66,6 +74,9 @@ func newPidNamespace(pidns model.Namespace) pidNamespace {
 	for _, pidnsChild := range pidnsChildren {
 		children = append(children, newPidNamespace(pidnsChild.(model.Namespace)))
 	}
+	slices.SortFunc(children, func(a, b pidNamespace) int {
+		return cmpUint64(a.PIDNsID, b.PIDNsID)
+	})
 	crefs := make([]string, 0, len(pidns.Leaders()))
 	for _, proc := range pidns.Leaders() {
 		if proc.Container == nil {
@@ -73,6 +84,7 @@ func newPidNamespace(pidns model.Namespace) pidNamespace {
 		}
 		crefs = append(crefs, cntrID(proc))
 	}
+	slices.Sort(crefs)
 	return pidNamespace{
 		ID:              pidnsID(pidns),
 		PIDNsID:         pidns.ID().Ino,
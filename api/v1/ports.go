@@ -7,6 +7,7 @@ package v1
 import (
 	"encoding/json"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/siemens/ghostwire/v2/network"
@@ -21,7 +22,11 @@ type ipvxPorts struct {
 	IPv6 ports `json:"ipv6"`
 }
 
-type ports []network.ProcessSocket
+// ports are sockets together with the details of their owning processes.
+type ports struct {
+	sockets []network.ProcessSocket
+	owners  network.SocketOwners
+}
 
 // port describes actually a local socket, but anyway...
 type port struct {
@@ -37,6 +42,37 @@ type port struct {
 	Macrostate        string                `json:"macrostate"`
 	Owners            []owner               `json:"owners"`
 	NifRefs           []string              `json:"network-interface-idrefs"`
+	Inode             uint64                `json:"inode,omitempty"`
+	UID               uint32                `json:"uid"`
+	RecvQueue         uint32                `json:"recv-queue"`
+	SendQueue         uint32                `json:"send-queue"`
+	ListenBacklog     uint32                `json:"listen-backlog,omitempty"`
+	Mark              uint32                `json:"mark,omitempty"`
+	CgroupID          uint64                `json:"cgroup-id,omitempty"`
+	TCPInfo           *tcpInfo              `json:"tcp-info,omitempty"`
+	LocalAddresses    []net.IP              `json:"local-addresses,omitempty"`
+	RemoteAddresses   []net.IP              `json:"remote-addresses,omitempty"`
+	MPTCPSubflow      *mptcpSubflow         `json:"mptcp-subflow,omitempty"`
+}
+
+// tcpInfo describes the details of a TCP connection, with all times in
+// microseconds.
+type tcpInfo struct {
+	RTT           int64  `json:"rtt"`
+	RTTVar        int64  `json:"rttvar"`
+	MinRTT        int64  `json:"min-rtt"`
+	RTO           int64  `json:"rto"`
+	SndMSS        uint32 `json:"snd-mss"`
+	RcvMSS        uint32 `json:"rcv-mss"`
+	SndCwnd       uint32 `json:"snd-cwnd"`
+	SndSsthresh   uint32 `json:"snd-ssthresh"`
+	Unacked       uint32 `json:"unacked"`
+	Lost          uint32 `json:"lost"`
+	Retrans       uint32 `json:"retrans"`
+	Retransmits   uint8  `json:"retransmits"`
+	TotalRetrans  uint32 `json:"total-retrans"`
+	BytesAcked    uint64 `json:"bytes-acked"`
+	BytesReceived uint64 `json:"bytes-received"`
 }
 
 // owner describes a process attached to a particular transport port (rather,
@@ -45,44 +81,156 @@ type owner struct {
 	PID          model.PIDType `json:"pid"`
 	Cmdline      string        `json:"cmdline"`
 	ContainerRef string        `json:"container-idref,omitempty"`
+	// set if the process is attached to a different network namespace than
+	// the socket, referencing the process' network namespace.
+	Foreign  bool   `json:"foreign,omitempty"`
+	NetnsRef string `json:"netns-idref,omitempty"`
+	// further details, if known.
+	UID          *uint32 `json:"uid,omitempty"`
+	GID          *uint32 `json:"gid,omitempty"`
+	UserName     string  `json:"username,omitempty"`
+	GroupName    string  `json:"groupname,omitempty"`
+	Executable   string  `json:"exe,omitempty"`
+	Cgroup       string  `json:"cgroup,omitempty"`
+	SystemdUnit  string  `json:"systemd-unit,omitempty"`
+	SystemdSlice string  `json:"systemd-slice,omitempty"`
 }
 
 func (p ports) MarshalJSON() ([]byte, error) {
-	prts := make([]port, 0, len(p))
-	for _, prt := range p {
-		protocol := strings.ToLower(prt.Protocol.String())
-		// Gather the JSON document-local network interface identifier
-		// references for the network interfaces covered by this socket...
-		nifrefs := make([]string, 0, len(prt.Nifs))
-		for _, nif := range prt.Nifs {
-			nifrefs = append(nifrefs, nifID(nif))
+	prts := make([]port, 0, len(p.sockets))
+	for _, prt := range p.sockets {
+		prts = append(prts, newPort(prt, p.owners))
+	}
+	return json.Marshal(prts)
+}
+
+// newPort returns the JSON representation of the specified socket.
+func newPort(prt network.ProcessSocket, details network.SocketOwners) port {
+	protocol := strings.ToLower(prt.Protocol.String())
+	// Gather the JSON document-local network interface identifier
+	// references for the network interfaces covered by this socket...
+	nifrefs := make([]string, 0, len(prt.Nifs))
+	for _, nif := range prt.Nifs {
+		nifrefs = append(nifrefs, nifID(nif))
+	}
+	// Gather the "ownership" information about the processes using a socket
+	// for this port...
+	owners := newOwners(prt.Processes, prt.ForeignProcesses, details)
+	return port{
+		Family:            prt.Family,
+		Protocol:          protocol,
+		LocalAddress:      prt.LocalIP,
+		LocalPort:         prt.LocalPort,
+		LocalServiceName:  serviceName(prt.LocalPort, protocol),
+		RemoteAddress:     prt.RemoteIP,
+		RemotePort:        prt.RemotePort,
+		RemoteServiceName: serviceName(prt.RemotePort, protocol),
+		State:             prt.State.String(),
+		Macrostate:        prt.SimplifiedState.String(),
+		Owners:            owners,
+		NifRefs:           nifrefs,
+		Inode:             prt.Inode,
+		UID:               prt.UID,
+		RecvQueue:         prt.RecvQueue,
+		SendQueue:         prt.SendQueue,
+		ListenBacklog:     prt.ListenBacklog,
+		Mark:              prt.Mark,
+		CgroupID:          prt.CgroupID,
+		TCPInfo:           newTCPInfo(prt.TCPInfo),
+		LocalAddresses:    prt.LocalAddrs,
+		RemoteAddresses:   prt.RemoteAddrs,
+		MPTCPSubflow:      newMPTCPSubflow(prt.MPTCPSubflow),
+	}
+}
+
+// newOwners returns the "ownership" information about the specified processes
+// using a socket. Foreign processes are attached to network namespaces other
+// than the socket's network namespace. The details of the processes are
+// optional.
+func newOwners(procs []*model.Process, foreign []*model.Process, details network.SocketOwners) []owner {
+	owners := make([]owner, 0, len(procs))
+	for _, proc := range procs {
+		o := owner{
+			PID:          proc.PID,
+			Cmdline:      strings.Join(proc.Cmdline, " "),
+			ContainerRef: cntrID(leader(proc)),
+		}
+		if slices.Contains(foreign, proc) {
+			o.Foreign = true
+			if netns := proc.Namespaces[model.NetNS]; netns != nil {
+				o.NetnsRef = "netns-" + strconv.FormatUint(netns.ID().Ino, 10)
+			}
 		}
-		// Gather the "ownership" information about the processes using a socket
-		// for this port...
-		owners := make([]owner, 0, len(prt.Processes))
-		for _, proc := range prt.Processes {
-			owners = append(owners, owner{
-				PID:          proc.PID,
-				Cmdline:      strings.Join(proc.Cmdline, " "),
-				ContainerRef: cntrID(leader(proc)),
-			})
+		if d, ok := details[proc.PID]; ok {
+			uid, gid := d.UID, d.GID
+			o.UID = &uid
+			o.GID = &gid
+			o.UserName = d.UserName
+			o.GroupName = d.GroupName
+			o.Executable = d.Executable
+			o.Cgroup = d.Cgroup
+			o.SystemdUnit = d.SystemdUnit
+			o.SystemdSlice = d.SystemdSlice
 		}
-		prts = append(prts, port{
-			Family:            prt.Family,
-			Protocol:          protocol,
-			LocalAddress:      prt.LocalIP,
-			LocalPort:         prt.LocalPort,
-			LocalServiceName:  serviceName(prt.LocalPort, protocol),
-			RemoteAddress:     prt.RemoteIP,
-			RemotePort:        prt.RemotePort,
-			RemoteServiceName: serviceName(prt.RemotePort, protocol),
-			State:             prt.State.String(),
-			Macrostate:        prt.SimplifiedState.String(),
-			Owners:            owners,
-			NifRefs:           nifrefs,
+		owners = append(owners, o)
+	}
+	return owners
+}
+
+// bridgingProcess describes a process attached to a network namespace that
+// holds sockets of other network namespaces.
+type bridgingProcess struct {
+	PID              model.PIDType `json:"pid"`
+	Cmdline          string        `json:"cmdline"`
+	ContainerRef     string        `json:"container-idref,omitempty"`
+	ForeignNetnsRefs []string      `json:"foreign-netns-idrefs"`
+}
+
+// newBridgingProcesses returns the JSON representation of the specified
+// processes bridging network namespaces.
+func newBridgingProcesses(bridges []*network.BridgingProcess) []bridgingProcess {
+	if len(bridges) == 0 {
+		return nil
+	}
+	bps := make([]bridgingProcess, 0, len(bridges))
+	for _, bridge := range bridges {
+		netnsrefs := make([]string, 0, len(bridge.ForeignNetns))
+		for _, netns := range bridge.ForeignNetns {
+			netnsrefs = append(netnsrefs, "netns-"+strconv.FormatUint(netns.ID().Ino, 10))
+		}
+		bps = append(bps, bridgingProcess{
+			PID:              bridge.Process.PID,
+			Cmdline:          strings.Join(bridge.Process.Cmdline, " "),
+			ContainerRef:     cntrID(leader(bridge.Process)),
+			ForeignNetnsRefs: netnsrefs,
 		})
 	}
-	return json.Marshal(prts)
+	return bps
+}
+
+// newTCPInfo returns the JSON representation of the specified TCP connection
+// details, or nil.
+func newTCPInfo(info *network.TCPInfo) *tcpInfo {
+	if info == nil {
+		return nil
+	}
+	return &tcpInfo{
+		RTT:           info.RTT.Microseconds(),
+		RTTVar:        info.RTTVar.Microseconds(),
+		MinRTT:        info.MinRTT.Microseconds(),
+		RTO:           info.RTO.Microseconds(),
+		SndMSS:        info.SndMSS,
+		RcvMSS:        info.RcvMSS,
+		SndCwnd:       info.SndCwnd,
+		SndSsthresh:   info.SndSsthresh,
+		Unacked:       info.Unacked,
+		Lost:          info.Lost,
+		Retrans:       info.Retrans,
+		Retransmits:   info.Retransmits,
+		TotalRetrans:  info.TotalRetrans,
+		BytesAcked:    info.BytesAcked,
+		BytesReceived: info.BytesReceived,
+	}
 }
 
 // serviceName returns the name (if known) for the service on the given port and
@@ -15,9 +15,10 @@ type TargetDiscoveryResult struct {
 
 // NewTargetDiscoveryResult returns a new TargetDiscoveryResult for the
 // specified network namespace discovery results, to be marshalled into JSON.
-func NewTargetDiscoveryResult(result gostwire.DiscoveryResult) TargetDiscoveryResult {
+// Unless specified using WithMetadata, it creates new metadata.
+func NewTargetDiscoveryResult(result gostwire.DiscoveryResult, opts ...ResultOption) TargetDiscoveryResult {
 	return TargetDiscoveryResult{
-		Metadata:   NewMetadata(result),
+		Metadata:   MetadataOf(result, opts...),
 		Containers: newCaptureTargets(result),
 	}
 }
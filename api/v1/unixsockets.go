@@ -0,0 +1,124 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v1
+
+import (
+	"strconv"
+
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/model"
+)
+
+// unixSocket describes a Unix domain socket, together with its connected peer,
+// if any.
+type unixSocket struct {
+	Type       string          `json:"type"`
+	State      string          `json:"state"`
+	Macrostate string          `json:"macrostate"`
+	Path       string          `json:"path,omitempty"`
+	Abstract   bool            `json:"abstract,omitempty"`
+	Inode      uint64          `json:"inode"`
+	RecvQueue  uint32          `json:"recv-queue"`
+	SendQueue  uint32          `json:"send-queue"`
+	UID        uint32          `json:"uid"`
+	Owners     []owner         `json:"owners"`
+	Peer       *unixSocketPeer `json:"peer,omitempty"`
+	EngineAPI  *engineAPI      `json:"engine-api,omitempty"`
+}
+
+// unixSocketPeer describes the peer of a connected Unix domain socket, which
+// might be located in a different network namespace.
+type unixSocketPeer struct {
+	Inode    uint64  `json:"inode"`
+	NetnsRef string  `json:"netns-idref,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Owners   []owner `json:"owners"`
+}
+
+// engineAPI describes a container engine by its API.
+type engineAPI struct {
+	Type string        `json:"type"`
+	API  string        `json:"api-path"`
+	PID  model.PIDType `json:"pid"`
+}
+
+// engineAPIMount describes a container engine API socket mounted into a
+// container.
+type engineAPIMount struct {
+	engineAPI
+	Path string `json:"path"`
+}
+
+// newUnixSockets returns the JSON representation of the specified Unix domain
+// sockets, skipping unnamed and unconnected sockets. It returns nil if there
+// are no sockets left.
+func newUnixSockets(unixsox []*network.UnixSocket, details network.SocketOwners) []unixSocket {
+	if len(unixsox) == 0 {
+		return nil
+	}
+	sox := make([]unixSocket, 0, len(unixsox))
+	for _, sock := range unixsox {
+		// Skip unnamed and unconnected sockets, as these don't tell us
+		// anything about who is communicating with whom.
+		if sock.Path == "" && sock.PeerInode == 0 {
+			continue
+		}
+		var peer *unixSocketPeer
+		if sock.PeerInode != 0 {
+			peer = &unixSocketPeer{Inode: sock.PeerInode, Owners: []owner{}}
+			if sock.Peer != nil {
+				peer.Path = sock.Peer.Path
+				peer.Owners = newOwners(sock.Peer.Processes, sock.Peer.ForeignProcesses, details)
+				if sock.Peer.Netns != nil {
+					peer.NetnsRef = "netns-" + strconv.FormatUint(sock.Peer.Netns.ID().Ino, 10)
+				}
+			}
+		}
+		sox = append(sox, unixSocket{
+			Type:       network.UnixSocketTypeName(sock.Type),
+			State:      sock.State.String(),
+			Macrostate: sock.SimplifiedState.String(),
+			Path:       sock.Path,
+			Abstract:   sock.Abstract(),
+			Inode:      sock.Inode,
+			RecvQueue:  sock.RecvQueue,
+			SendQueue:  sock.SendQueue,
+			UID:        sock.UID,
+			Owners:     newOwners(sock.Processes, sock.ForeignProcesses, details),
+			Peer:       peer,
+			EngineAPI:  newEngineAPI(sock.EngineAPI),
+		})
+	}
+	return sox
+}
+
+// newEngineAPI returns the API information about the specified container
+// engine, or nil.
+func newEngineAPI(engine *model.ContainerEngine) *engineAPI {
+	if engine == nil {
+		return nil
+	}
+	return &engineAPI{
+		Type: engine.Type,
+		API:  engine.API,
+		PID:  engine.PID,
+	}
+}
+
+// newEngineAPIMounts returns the information about the container engine API
+// sockets mounted into a container, or nil.
+func newEngineAPIMounts(mounts []network.EngineAPIMount) []engineAPIMount {
+	if len(mounts) == 0 {
+		return nil
+	}
+	apimounts := make([]engineAPIMount, 0, len(mounts))
+	for _, mount := range mounts {
+		apimounts = append(apimounts, engineAPIMount{
+			engineAPI: *newEngineAPI(mount.Engine),
+			Path:      mount.Path,
+		})
+	}
+	return apimounts
+}
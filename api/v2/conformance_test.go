@@ -0,0 +1,73 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"context"
+	"os"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/turtlefinder"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+)
+
+var _ = Describe("v2 API conformance", func() {
+
+	check := func(result gostwire.DiscoveryResult) {
+		defer func(ml int) { format.MaxLength = ml }(format.MaxLength)
+		format.MaxLength = 0
+
+		netnses := NewNetworkNamespaces(result)
+		Expect(validate(v2apispec, "Netns-List", &netnses)).To(Succeed())
+		for _, netns := range netnses.NetworkNamespaces {
+			Expect(validate(v2apispec, "Netns", netns)).To(Succeed(), "netns %s", netns.ID)
+		}
+
+		cntrs := NewContainers(result)
+		Expect(validate(v2apispec, "Container-List", &cntrs)).To(Succeed())
+		for _, cntr := range cntrs.Containers {
+			Expect(validate(v2apispec, "Container", cntr)).To(Succeed(), "container %s", cntr.ID)
+		}
+
+		nifs := NewInterfaces(result)
+		Expect(validate(v2apispec, "Interface-List", &nifs)).To(Succeed())
+		for _, nif := range nifs.Interfaces {
+			Expect(validate(v2apispec, "Interface", nif)).To(Succeed(), "interface %s", nif.ID)
+		}
+
+		engines := NewEngines(result)
+		Expect(validate(v2apispec, "Engine-List", &engines)).To(Succeed())
+	}
+
+	It("conforms with synthetic discovery results", func() {
+		check(testResult())
+	})
+
+	It("rejects unspecified properties", func() {
+		e := newEngine(testResult().Lxkns.Containers[0].Engine)
+		Expect(validate(v2apispec, "Engine", e)).To(Succeed())
+		Expect(validate(v2apispec, "Engine", map[string]interface{}{
+			"id": e.ID, "type": e.Type, "version": e.Version, "api": e.API, "pid": e.PID,
+			"foo": "bar",
+		})).NotTo(Succeed())
+	})
+
+	It("conforms with a live discovery", func(ctx context.Context) {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+		result := gostwire.Discover(ctx, cizer, nil)
+		Expect(result.Netns).NotTo(BeEmpty())
+		check(result)
+	})
+
+})
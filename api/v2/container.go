@@ -0,0 +1,193 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"strings"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/exp/slices"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// Containers is the API v2 JSON representation of the list of discovered
+// containers, together with the discovery metadata.
+type Containers struct {
+	Metadata   apiv1.Metadata `json:"metadata"`
+	Containers []*Container   `json:"containers"`
+}
+
+// Container is the API v2 JSON representation of a container.
+type Container struct {
+	ID      string                    `json:"id"`
+	Name    string                    `json:"name"`
+	Type    string                    `json:"type"`
+	Flavor  string                    `json:"flavor"`
+	PID     model.PIDType             `json:"pid"`
+	Status  string                    `json:"status"`
+	Labels  model.Labels              `json:"labels"`
+	NetnsID string                    `json:"netns-id"`
+	Engine  *Engine                   `json:"engine,omitempty"`
+	Groups  []Group                   `json:"groups"`
+	DNS     *network.DnsConfiguration `json:"dns,omitempty"`
+}
+
+// Group is the API v2 JSON representation of a container group, such as a
+// Kubernetes pod or a Docker Compose project.
+type Group struct {
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Flavor string       `json:"flavor"`
+	Labels model.Labels `json:"labels"`
+}
+
+// Engines is the API v2 JSON representation of the list of discovered
+// container engines, together with the discovery metadata.
+type Engines struct {
+	Metadata apiv1.Metadata `json:"metadata"`
+	Engines  []*Engine      `json:"engines"`
+}
+
+// Engine is the API v2 JSON representation of a container engine. Only the
+// engines listed by NewEngines have their container identifiers set.
+type Engine struct {
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	Version      string        `json:"version"`
+	API          string        `json:"api"`
+	PID          model.PIDType `json:"pid"`
+	ContainerIDs []string      `json:"container-ids,omitempty"`
+}
+
+// NewContainers returns the API v2 JSON representation of all containers
+// attached to the network namespaces of the specified discovery result,
+// ordered by their names and identifiers.
+// Unless specified using apiv1.WithMetadata, it creates new metadata.
+func NewContainers(result gostwire.DiscoveryResult, opts ...apiv1.ResultOption) Containers {
+	cntrs := []*Container{}
+	for _, netns := range result.Netns {
+		for _, tenant := range netns.Tenants {
+			if tenant.Process.Container != nil {
+				cntrs = append(cntrs, newContainer(netns, tenant))
+			}
+		}
+	}
+	slices.SortFunc(cntrs, func(a, b *Container) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return Containers{
+		Metadata:   apiv1.MetadataOf(result, opts...),
+		Containers: cntrs,
+	}
+}
+
+// FindContainer returns the API v2 JSON representation of the container with
+// the specified engine-assigned identifier, or nil if there is no such
+// container attached to any of the network namespaces of the discovery result.
+func FindContainer(result gostwire.DiscoveryResult, id string) *Container {
+	for _, netns := range result.Netns {
+		for _, tenant := range netns.Tenants {
+			if c := tenant.Process.Container; c != nil && c.ID == id {
+				return newContainer(netns, tenant)
+			}
+		}
+	}
+	return nil
+}
+
+// newContainer returns the API v2 JSON representation of the container of the
+// specified tenant of a network namespace.
+func newContainer(netns *network.NetworkNamespace, tenant *network.Tenant) *Container {
+	c := tenant.Process.Container
+	status := "running"
+	if c.Paused {
+		status = "paused"
+	}
+	groups := make([]Group, 0, len(c.Groups))
+	for _, group := range c.Groups {
+		groups = append(groups, Group{
+			Name:   group.Name,
+			Type:   group.Type,
+			Flavor: group.Flavor,
+			Labels: labels(group.Labels),
+		})
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	var engine *Engine
+	if c.Engine != nil {
+		engine = newEngine(c.Engine)
+	}
+	return &Container{
+		ID:      c.ID,
+		Name:    c.Name,
+		Type:    c.Type,
+		Flavor:  c.Flavor,
+		PID:     c.PID,
+		Status:  status,
+		Labels:  labels(c.Labels),
+		NetnsID: NetnsID(netns),
+		Engine:  engine,
+		Groups:  groups,
+		DNS:     &tenant.DNS,
+	}
+}
+
+// NewEngines returns the API v2 JSON representation of all container engines
+// of the specified discovery result, even if without any containers, ordered
+// by their types and identifiers.
+// Unless specified using apiv1.WithMetadata, it creates new metadata.
+func NewEngines(result gostwire.DiscoveryResult, opts ...apiv1.ResultOption) Engines {
+	engines := make([]*Engine, 0, len(result.Engines))
+	for _, engine := range result.Engines {
+		e := newEngine(engine)
+		e.ContainerIDs = make([]string, 0, len(engine.Containers))
+		for _, c := range engine.Containers {
+			e.ContainerIDs = append(e.ContainerIDs, c.ID)
+		}
+		slices.Sort(e.ContainerIDs)
+		engines = append(engines, e)
+	}
+	slices.SortFunc(engines, func(a, b *Engine) int {
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return Engines{
+		Metadata: apiv1.MetadataOf(result, opts...),
+		Engines:  engines,
+	}
+}
+
+// newEngine returns the API v2 JSON representation of the specified container
+// engine, without its containers.
+func newEngine(engine *model.ContainerEngine) *Engine {
+	return &Engine{
+		ID:      engine.ID,
+		Type:    engine.Type,
+		Version: engine.Version,
+		API:     engine.API,
+		PID:     engine.PID,
+	}
+}
+
+// labels returns the specified labels, or empty labels instead of nil.
+func labels(l model.Labels) model.Labels {
+	if l == nil {
+		return model.Labels{}
+	}
+	return l
+}
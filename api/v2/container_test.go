@@ -0,0 +1,62 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"net"
+
+	"github.com/thediveo/lxkns/decorator/composer"
+	"github.com/thediveo/lxkns/decorator/kuhbernetes"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/whalewatcher/engineclient/moby"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v2 containers and engines", func() {
+
+	It("lists containers", func() {
+		cntrs := NewContainers(testResult())
+		Expect(cntrs.Metadata).To(HaveKey("creator-id"))
+		Expect(cntrs.Containers).To(HaveExactElements(
+			HaveField("Name", "myproject_web_1"),
+			HaveField("Name", "sidecar"),
+		))
+	})
+
+	It("includes all groups, engines, and labels", func() {
+		web := FindContainer(testResult(), "4242abcd")
+		Expect(web).NotTo(BeNil())
+		Expect(web.Type).To(Equal(moby.Type))
+		Expect(web.Status).To(Equal("running"))
+		Expect(web.NetnsID).To(Equal("4026532000"))
+		Expect(web.Labels).To(HaveKeyWithValue(composer.ComposerProjectLabel, "myproject"))
+		Expect(web.Groups).To(ConsistOf(Group{
+			Name: "myproject", Type: composer.ComposerGroupType, Flavor: composer.ComposerGroupType,
+			Labels: model.Labels{}}))
+		Expect(web.Engine).To(Equal(&Engine{
+			ID: "dockerd-1", Type: moby.Type, Version: "24.0.7", API: "unix:///run/docker.sock", PID: 100}))
+		Expect(web.DNS.Hosts).To(HaveKeyWithValue("localhost", net.IPv4(127, 0, 0, 1)))
+
+		sidecar := FindContainer(testResult(), "default/mypod/sidecar")
+		Expect(sidecar).NotTo(BeNil())
+		Expect(sidecar.Status).To(Equal("paused"))
+		Expect(sidecar.Labels).To(BeEmpty())
+		Expect(sidecar.Groups).To(ConsistOf(HaveField("Type", kuhbernetes.PodGroupType)))
+
+		Expect(FindContainer(testResult(), "sidecar")).To(BeNil())
+	})
+
+	It("lists engines, even without containers", func() {
+		engines := NewEngines(testResult())
+		Expect(engines.Engines).To(HaveExactElements(
+			And(HaveField("ID", "containerd-1"), HaveField("ContainerIDs", ConsistOf("default/mypod/sidecar"))),
+			And(HaveField("ID", "dockerd-1"), HaveField("ContainerIDs", ConsistOf("4242abcd"))),
+			And(HaveField("ID", "podman-1"), HaveField("ContainerIDs", BeEmpty())),
+		))
+	})
+
+})
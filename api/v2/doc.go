@@ -1,4 +1,28 @@
 /*
-Package v2 implements the Gostwire v2 API.
+Package v2 implements the JSON marshalling of Gostwire discovery results for
+the v2 REST API endpoints “/v2/netns”, “/v2/containers”, “/v2/interfaces”, and
+“/v2/engines”, as well as their individual resource endpoints.
+
+In contrast to the v1 API, the v2 API doesn't emit a single huge document with
+document-local identifiers, but instead separate resources with stable
+identifiers:
+
+  - network namespaces are identified by their inode numbers, such as
+    "4026531840".
+  - containers are identified by the identifiers assigned by their container
+    engines.
+  - network interfaces are identified by the inode number of their network
+    namespace together with their interface index, such as "4026531840-2".
+
+Resources reference each other only using these identifiers. The v2 API
+additionally exposes information the v1 API drops, such as all container
+groups (and not only Kubernetes pods), container engines, network interface
+labels, VLAN, VXLAN, TUN/TAP, and MACVLAN details, SR-IOV roles, route tables
+and priorities, and forwarded port ranges. The details of MPTCP, multicast
+routing, packet and unix sockets remain the domain of the v1 API for the time
+being.
+
+The v2 API is specified in api/openapi-spec/ghostwire-v2.yaml. Please note that
+unmarshalling isn't supported.
 */
 package v2
@@ -0,0 +1,73 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// NetnsID returns the stable v2 identifier of the specified network namespace,
+// which is its inode number in decimal text format.
+func NetnsID(netns *network.NetworkNamespace) string {
+	return strconv.FormatUint(netns.ID().Ino, 10)
+}
+
+// InterfaceID returns the stable v2 identifier of the specified network
+// interface, consisting of the inode number of its network namespace and its
+// interface index, such as "4026531840-1". Returns "" in case of a nil
+// network.Interface.
+func InterfaceID(nif network.Interface) string {
+	if nif == nil {
+		return ""
+	}
+	return strconv.FormatUint(nif.Nif().Netns.ID().Ino, 10) +
+		"-" + strconv.Itoa(nif.Nif().Index)
+}
+
+// parseInterfaceID returns the network namespace inode number and interface
+// index of the specified v2 network interface identifier, or false if the
+// identifier is invalid.
+func parseInterfaceID(id string) (ino uint64, index int, ok bool) {
+	inotext, indextext, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ino, err := strconv.ParseUint(inotext, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	index, err = strconv.Atoi(indextext)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ino, index, true
+}
+
+// interfaceIDs returns the v2 identifiers of the specified network interfaces,
+// never returning nil.
+func interfaceIDs(nifs []network.Interface) []string {
+	ids := make([]string, 0, len(nifs))
+	for _, nif := range nifs {
+		ids = append(ids, InterfaceID(nif))
+	}
+	return ids
+}
+
+// containerOf returns the container the specified process belongs to, or nil.
+// As only the ealdorman process of a container references its container, the
+// process' ancestors need to be searched too.
+func containerOf(proc *model.Process) *model.Container {
+	for ; proc != nil; proc = proc.Parent {
+		if proc.Container != nil {
+			return proc.Container
+		}
+	}
+	return nil
+}
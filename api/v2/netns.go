@@ -0,0 +1,298 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/exp/slices"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// NetworkNamespaces is the API v2 JSON representation of the list of
+// discovered network namespaces, together with the discovery metadata.
+type NetworkNamespaces struct {
+	Metadata          apiv1.Metadata      `json:"metadata"`
+	NetworkNamespaces []*NetworkNamespace `json:"network-namespaces"`
+}
+
+// NetworkNamespace is the API v2 JSON representation of a network namespace.
+type NetworkNamespace struct {
+	ID             string          `json:"id"`
+	Inode          uint64          `json:"inode"`
+	ContainerIDs   []string        `json:"container-ids"`
+	Processes      []Process       `json:"processes"` // stand-alone processes only.
+	Interfaces     []InterfaceRef  `json:"interfaces"`
+	Routes         []Route         `json:"routes"`
+	TransportPorts []Port          `json:"transport-ports"`
+	ForwardedPorts []ForwardedPort `json:"forwarded-ports"`
+}
+
+// Process is the API v2 JSON representation of a process, either a
+// stand-alone process attached to a network namespace or a process using a
+// socket.
+type Process struct {
+	PID         model.PIDType `json:"pid"`
+	Name        string        `json:"name"`
+	Cmdline     []string      `json:"cmdline"`
+	ContainerID string        `json:"container-id,omitempty"`
+}
+
+// InterfaceRef references a network interface by its v2 identifier, together
+// with its name and index for convenience.
+type InterfaceRef struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+}
+
+// Route is the API v2 JSON representation of an IPv4 or IPv6 route.
+type Route struct {
+	Family       string `json:"family"`
+	Type         string `json:"type"`
+	Destination  net.IP `json:"destination"`
+	PrefixLength int    `json:"prefix-length"`
+	NextHop      net.IP `json:"next-hop,omitempty"`
+	InterfaceID  string `json:"interface-id,omitempty"`
+	Table        int    `json:"table"`
+	Priority     int    `json:"priority"`
+	Preference   int    `json:"preference"`
+}
+
+// Port is the API v2 JSON representation of a transport-layer socket.
+type Port struct {
+	Family        string    `json:"family"`
+	Protocol      string    `json:"protocol"`
+	LocalAddress  net.IP    `json:"local-address"`
+	LocalPort     uint16    `json:"local-port"`
+	RemoteAddress net.IP    `json:"remote-address"`
+	RemotePort    uint16    `json:"remote-port"`
+	State         string    `json:"state"`
+	Macrostate    string    `json:"macrostate"`
+	Inode         uint64    `json:"inode,omitempty"`
+	Owners        []Process `json:"owners"`
+	InterfaceIDs  []string  `json:"interface-ids"`
+}
+
+// ForwardedPort is the API v2 JSON representation of a forwarded port range.
+type ForwardedPort struct {
+	Family         string    `json:"family"`
+	Protocol       string    `json:"protocol"`
+	Address        net.IP    `json:"address,omitempty"` // any address, if unspecified.
+	PortMin        uint16    `json:"port-min"`
+	PortMax        uint16    `json:"port-max"`
+	ForwardAddress net.IP    `json:"forward-address"`
+	ForwardPortMin uint16    `json:"forward-port-min"`
+	ForwardPortMax uint16    `json:"forward-port-max"`
+	ForwardNetnsID string    `json:"forward-netns-id,omitempty"`
+	Owners         []Process `json:"owners"`
+	InterfaceIDs   []string  `json:"interface-ids"`
+}
+
+// NewNetworkNamespaces returns the API v2 JSON representation of all network
+// namespaces of the specified discovery result, ordered by their inode
+// numbers.
+// Unless specified using apiv1.WithMetadata, it creates new metadata.
+func NewNetworkNamespaces(result gostwire.DiscoveryResult, opts ...apiv1.ResultOption) NetworkNamespaces {
+	netnses := make([]*NetworkNamespace, 0, len(result.Netns))
+	for _, netns := range sortedNetns(result.Netns) {
+		netnses = append(netnses, NewNetworkNamespace(netns))
+	}
+	return NetworkNamespaces{
+		Metadata:          apiv1.MetadataOf(result, opts...),
+		NetworkNamespaces: netnses,
+	}
+}
+
+// FindNetworkNamespace returns the API v2 JSON representation of the network
+// namespace with the specified v2 identifier, or nil if there is no such
+// network namespace in the discovery result.
+func FindNetworkNamespace(result gostwire.DiscoveryResult, id string) *NetworkNamespace {
+	if netns := findNetns(result.Netns, id); netns != nil {
+		return NewNetworkNamespace(netns)
+	}
+	return nil
+}
+
+// NewNetworkNamespace returns the API v2 JSON representation of the specified
+// network namespace.
+func NewNetworkNamespace(netns *network.NetworkNamespace) *NetworkNamespace {
+	cntrids := []string{}
+	procs := []Process{}
+	tenants := slices.Clone(netns.Tenants)
+	slices.SortFunc(tenants, func(a, b *network.Tenant) int {
+		return cmpUint64(uint64(a.Process.PID), uint64(b.Process.PID))
+	})
+	for _, tenant := range tenants {
+		if tenant.Process.PPID == 0 && tenant.Process.PID == 2 {
+			continue // skip kthreadd(2) in order to not bedazzle users.
+		}
+		if c := tenant.Process.Container; c != nil {
+			cntrids = append(cntrids, c.ID)
+			continue
+		}
+		procs = append(procs, newProcess(tenant.Process))
+	}
+	nifs := make([]InterfaceRef, 0, len(netns.Nifs))
+	for _, nif := range sortedNifs(netns) {
+		nifs = append(nifs, InterfaceRef{
+			ID:    InterfaceID(nif),
+			Name:  nif.Nif().Name,
+			Index: nif.Nif().Index,
+		})
+	}
+	routes := make([]Route, 0, len(netns.Routesv4)+len(netns.Routesv6))
+	for _, rts := range [][]network.Route{netns.Routesv4, netns.Routesv6} {
+		for _, rt := range rts {
+			routes = append(routes, Route{
+				Family:       family(rt.Family),
+				Type:         rt.Type.String(),
+				Destination:  rt.Destination.IP,
+				PrefixLength: rt.DestinationPrefixLen,
+				NextHop:      rt.NextHop,
+				InterfaceID:  InterfaceID(rt.Nif),
+				Table:        rt.Table,
+				Priority:     rt.Priority,
+				Preference:   int(rt.Preference),
+			})
+		}
+	}
+	ports := make([]Port, 0, len(netns.Portsv4)+len(netns.Portsv6))
+	for _, socks := range [][]network.ProcessSocket{netns.Portsv4, netns.Portsv6} {
+		for _, sock := range socks {
+			ports = append(ports, Port{
+				Family:        family(sock.Family),
+				Protocol:      strings.ToLower(sock.Protocol.String()),
+				LocalAddress:  sock.LocalIP,
+				LocalPort:     sock.LocalPort,
+				RemoteAddress: sock.RemoteIP,
+				RemotePort:    sock.RemotePort,
+				State:         sock.State.String(),
+				Macrostate:    sock.SimplifiedState.String(),
+				Inode:         sock.Inode,
+				Owners:        newProcesses(sock.Processes),
+				InterfaceIDs:  interfaceIDs(sock.Nifs),
+			})
+		}
+	}
+	fwdports := make([]ForwardedPort, 0, len(netns.ForwardedPortsv4)+len(netns.ForwardedPortsv6))
+	for _, fps := range [][]network.ForwardedPort{netns.ForwardedPortsv4, netns.ForwardedPortsv6} {
+		for _, fp := range fps {
+			fam := "ipv4"
+			if fp.ForwardIP.To4() == nil {
+				fam = "ipv6"
+			}
+			var fwdnetnsid string
+			if fp.DestinationNetns != nil {
+				fwdnetnsid = NetnsID(fp.DestinationNetns)
+			}
+			var addr net.IP
+			if len(fp.IP) != 0 && !fp.IP.IsUnspecified() {
+				addr = fp.IP
+			}
+			fwdports = append(fwdports, ForwardedPort{
+				Family:         fam,
+				Protocol:       strings.ToLower(fp.Protocol.String()),
+				Address:        addr,
+				PortMin:        fp.PortMin,
+				PortMax:        fp.PortMax,
+				ForwardAddress: fp.ForwardIP,
+				ForwardPortMin: fp.ForwardPortMin,
+				ForwardPortMax: fp.ForwardPortMin + (fp.PortMax - fp.PortMin),
+				ForwardNetnsID: fwdnetnsid,
+				Owners:         newProcesses(fp.Processes),
+				InterfaceIDs:   interfaceIDs(fp.Nifs),
+			})
+		}
+	}
+	return &NetworkNamespace{
+		ID:             NetnsID(netns),
+		Inode:          netns.ID().Ino,
+		ContainerIDs:   cntrids,
+		Processes:      procs,
+		Interfaces:     nifs,
+		Routes:         routes,
+		TransportPorts: ports,
+		ForwardedPorts: fwdports,
+	}
+}
+
+// newProcess returns the API v2 JSON representation of the specified process.
+func newProcess(proc *model.Process) Process {
+	p := Process{
+		PID:     proc.PID,
+		Name:    proc.Name,
+		Cmdline: proc.Cmdline,
+	}
+	if p.Cmdline == nil {
+		p.Cmdline = []string{}
+	}
+	if c := containerOf(proc); c != nil {
+		p.ContainerID = c.ID
+	}
+	return p
+}
+
+// newProcesses returns the API v2 JSON representations of the specified
+// processes, never returning nil.
+func newProcesses(procs []*model.Process) []Process {
+	ps := make([]Process, 0, len(procs))
+	for _, proc := range procs {
+		ps = append(ps, newProcess(proc))
+	}
+	return ps
+}
+
+// family returns the v2 name of the specified address family, that is, either
+// "ipv4" or "ipv6".
+func family(af network.AddressFamily) string {
+	return strings.ToLower(af.String())
+}
+
+// sortedNetns returns the specified network namespaces ordered by their inode
+// numbers.
+func sortedNetns(netnses network.NetworkNamespaces) []*network.NetworkNamespace {
+	sorted := make([]*network.NetworkNamespace, 0, len(netnses))
+	for _, netns := range netnses {
+		sorted = append(sorted, netns)
+	}
+	slices.SortFunc(sorted, func(a, b *network.NetworkNamespace) int {
+		return cmpUint64(a.ID().Ino, b.ID().Ino)
+	})
+	return sorted
+}
+
+// findNetns returns the network namespace with the specified v2 identifier, or
+// nil.
+func findNetns(netnses network.NetworkNamespaces, id string) *network.NetworkNamespace {
+	ino, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+	for _, netns := range netnses {
+		if netns.ID().Ino == ino {
+			return netns
+		}
+	}
+	return nil
+}
+
+// cmpUint64 returns -1, 0, or +1 depending on whether a is less, equal, or
+// greater than b.
+func cmpUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v2 network namespaces", func() {
+
+	It("lists network namespaces with stable identifiers", func() {
+		netnses := NewNetworkNamespaces(testResult())
+		Expect(netnses.Metadata).To(HaveKeyWithValue("creator-id", "gostwire"))
+		Expect(netnses.NetworkNamespaces).To(HaveExactElements(
+			HaveField("ID", "4026531840"),
+			HaveField("ID", "4026532000"),
+		))
+	})
+
+	It("references containers and interfaces, and lists stand-alone processes", func() {
+		hostnetns := FindNetworkNamespace(testResult(), "4026531840")
+		Expect(hostnetns).NotTo(BeNil())
+		Expect(hostnetns.Inode).To(Equal(uint64(hostnetnsino)))
+		Expect(hostnetns.ContainerIDs).To(BeEmpty())
+		Expect(hostnetns.Processes).To(ConsistOf(Process{
+			PID: 1, Name: "systemd", Cmdline: []string{"/sbin/init"}}))
+		Expect(hostnetns.Interfaces).To(HaveLen(9))
+		Expect(hostnetns.Interfaces[1]).To(Equal(InterfaceRef{ID: "4026531840-2", Name: "eth0", Index: 2}))
+
+		cntrnetns := FindNetworkNamespace(testResult(), "4026532000")
+		Expect(cntrnetns).NotTo(BeNil())
+		Expect(cntrnetns.ContainerIDs).To(HaveExactElements("4242abcd", "default/mypod/sidecar"))
+		Expect(cntrnetns.Processes).To(BeEmpty())
+
+		Expect(FindNetworkNamespace(testResult(), "666")).To(BeNil())
+		Expect(FindNetworkNamespace(testResult(), "netns-4026531840")).To(BeNil())
+	})
+
+	It("includes route tables and priorities", func() {
+		hostnetns := FindNetworkNamespace(testResult(), "4026531840")
+		Expect(hostnetns.Routes).To(HaveExactElements(
+			Route{Family: "ipv4", Type: "unicast", Destination: net.IPv4zero.To4(), PrefixLength: 0,
+				NextHop: net.IP{192, 168, 0, 1}, InterfaceID: "4026531840-2", Table: 254, Priority: 100},
+			Route{Family: "ipv4", Type: "local", Destination: net.IP{127, 0, 0, 0}, PrefixLength: 8,
+				InterfaceID: "4026531840-1", Table: 255},
+			Route{Family: "ipv6", Type: "unicast", Destination: net.ParseIP("fe80::"), PrefixLength: 64,
+				InterfaceID: "4026531840-2", Table: 254, Priority: 256, Preference: 1},
+		))
+	})
+
+	It("includes transport ports with their owners", func() {
+		cntrnetns := FindNetworkNamespace(testResult(), "4026532000")
+		Expect(cntrnetns.TransportPorts).To(ConsistOf(And(
+			HaveField("Family", "ipv4"),
+			HaveField("Protocol", "tcp"),
+			HaveField("LocalPort", uint16(80)),
+			HaveField("Macrostate", "listening"),
+			HaveField("Owners", ConsistOf(Process{
+				PID: 1001, Name: "nginx", Cmdline: []string{"nginx: worker"}, ContainerID: "4242abcd"})),
+			HaveField("InterfaceIDs", ConsistOf("4026532000-1", "4026532000-2")),
+		)))
+	})
+
+	It("includes forwarded port ranges", func() {
+		hostnetns := FindNetworkNamespace(testResult(), "4026531840")
+		Expect(hostnetns.ForwardedPorts).To(ConsistOf(And(
+			HaveField("Family", "ipv4"),
+			HaveField("Protocol", "tcp"),
+			HaveField("Address", BeNil()),
+			HaveField("PortMin", uint16(8000)),
+			HaveField("PortMax", uint16(8010)),
+			HaveField("ForwardAddress", net.IP{172, 17, 0, 2}),
+			HaveField("ForwardPortMin", uint16(80)),
+			HaveField("ForwardPortMax", uint16(90)),
+			HaveField("ForwardNetnsID", "4026532000"),
+			HaveField("Owners", ConsistOf(HaveField("ContainerID", "4242abcd"))),
+			HaveField("InterfaceIDs", ConsistOf("4026532000-2")),
+		)))
+	})
+
+})
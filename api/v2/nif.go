@@ -0,0 +1,264 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"net"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/exp/slices"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// Interfaces is the API v2 JSON representation of the list of discovered
+// network interfaces, together with the discovery metadata.
+type Interfaces struct {
+	Metadata   apiv1.Metadata `json:"metadata"`
+	Interfaces []*Interface   `json:"interfaces"`
+}
+
+// Interface is the API v2 JSON representation of a network interface. All
+// relations to other network interfaces are in form of v2 network interface
+// identifiers.
+type Interface struct {
+	ID          string                 `json:"id"`
+	NetnsID     string                 `json:"netns-id"`
+	Name        string                 `json:"name"`
+	Alias       string                 `json:"alias,omitempty"`
+	Index       int                    `json:"index"`
+	Kind        string                 `json:"kind"`
+	Operstate   string                 `json:"operstate"`
+	Physical    bool                   `json:"physical"`
+	Promiscuous bool                   `json:"promiscuous"`
+	Driver      *network.NifDriverInfo `json:"driver,omitempty"`
+	Labels      model.Labels           `json:"labels"`
+	MAC         string                 `json:"mac"`
+	Addresses   []Address              `json:"addresses"`
+	SRIOVRole   string                 `json:"sr-iov-role"`
+	PFID        string                 `json:"pf-id,omitempty"`
+	VFIDs       []string               `json:"vf-ids,omitempty"`
+	BridgeID    string                 `json:"bridge-id,omitempty"`
+	PortIDs     []string               `json:"port-ids,omitempty"`
+	LowerID     string                 `json:"lower-id,omitempty"`
+	UpperIDs    []string               `json:"upper-ids,omitempty"`
+	PeerID      string                 `json:"peer-id,omitempty"`
+	Vlan        *Vlan                  `json:"vlan,omitempty"`
+	Vxlan       *Vxlan                 `json:"vxlan,omitempty"`
+	TunTap      *TunTap                `json:"tuntap,omitempty"`
+	Macvlan     *Macvlan               `json:"macvlan,omitempty"`
+}
+
+// Address is the API v2 JSON representation of an IPv4 or IPv6 address
+// assigned to a network interface.
+type Address struct {
+	Family            string   `json:"family"`
+	Address           net.IP   `json:"address"`
+	PrefixLength      uint     `json:"prefix-length"`
+	Scope             int      `json:"scope"`
+	Flags             []string `json:"flags"`
+	Origin            string   `json:"origin"`
+	PreferredLifetime uint32   `json:"preferred-lifetime"`
+	ValidLifetime     uint32   `json:"valid-lifetime"`
+	Label             string   `json:"label,omitempty"`
+	Broadcast         net.IP   `json:"broadcast,omitempty"`
+	Peer              net.IP   `json:"peer,omitempty"`
+}
+
+// Vlan carries the VLAN-specific details of a network interface.
+type Vlan struct {
+	VID      uint16 `json:"vid"`
+	Protocol string `json:"protocol"`
+}
+
+// Vxlan carries the VXLAN-specific details of a network interface.
+type Vxlan struct {
+	VID             uint32 `json:"vid"`
+	Group           net.IP `json:"group,omitempty"`
+	Source          net.IP `json:"source,omitempty"`
+	DestinationPort uint16 `json:"destination-port"`
+	SourcePortMin   uint16 `json:"source-port-min"`
+	SourcePortMax   uint16 `json:"source-port-max"`
+	TTL             uint8  `json:"ttl"`
+	TOS             uint8  `json:"tos"`
+	ArpProxy        bool   `json:"arp-proxy"`
+}
+
+// TunTap carries the TUN/TAP-specific details of a network interface,
+// including the processes serving it.
+type TunTap struct {
+	Mode       string    `json:"mode"`
+	Processors []Process `json:"processors"`
+}
+
+// Macvlan carries the MACVLAN-specific details of a network interface.
+type Macvlan struct {
+	Mode string `json:"mode"`
+}
+
+// sriovRoles maps SR-IOV roles to their v2 names.
+var sriovRoles = map[network.SRIOVRole]string{
+	network.PCI_NIC:      "none",
+	network.PCI_SRIOV_PF: "pf",
+	network.PCI_SRIOV_VF: "vf",
+}
+
+// NewInterfaces returns the API v2 JSON representation of all network
+// interfaces of the specified discovery result, ordered by their network
+// namespaces and then by their interface indices.
+// Unless specified using apiv1.WithMetadata, it creates new metadata.
+func NewInterfaces(result gostwire.DiscoveryResult, opts ...apiv1.ResultOption) Interfaces {
+	nifs := []*Interface{}
+	for _, netns := range sortedNetns(result.Netns) {
+		for _, nif := range sortedNifs(netns) {
+			nifs = append(nifs, NewInterface(nif))
+		}
+	}
+	return Interfaces{
+		Metadata:   apiv1.MetadataOf(result, opts...),
+		Interfaces: nifs,
+	}
+}
+
+// FindInterface returns the API v2 JSON representation of the network
+// interface with the specified v2 identifier, or nil if there is no such
+// network interface in the discovery result.
+func FindInterface(result gostwire.DiscoveryResult, id string) *Interface {
+	ino, index, ok := parseInterfaceID(id)
+	if !ok {
+		return nil
+	}
+	for _, netns := range result.Netns {
+		if netns.ID().Ino != ino {
+			continue
+		}
+		if nif, ok := netns.Nifs[index]; ok {
+			return NewInterface(nif)
+		}
+		return nil
+	}
+	return nil
+}
+
+// NewInterface returns the API v2 JSON representation of the specified network
+// interface.
+func NewInterface(nif network.Interface) *Interface {
+	attrs := nif.Nif()
+	j := &Interface{
+		ID:          InterfaceID(nif),
+		NetnsID:     NetnsID(attrs.Netns),
+		Name:        attrs.Name,
+		Alias:       attrs.Alias,
+		Index:       attrs.Index,
+		Kind:        attrs.Kind,
+		Operstate:   attrs.State.Name(),
+		Physical:    attrs.Physical,
+		Promiscuous: attrs.Promiscuous,
+		Labels:      labels(attrs.Labels),
+		MAC:         attrs.L2Addr.String(),
+		SRIOVRole:   sriovRoles[attrs.SRIOVRole],
+		PFID:        InterfaceID(attrs.PF),
+		BridgeID:    InterfaceID(attrs.Bridge),
+	}
+	if attrs.DriverInfo.Driver != "" {
+		j.Driver = &attrs.DriverInfo
+	}
+	j.Addresses = make([]Address, 0, len(attrs.Addrsv4)+len(attrs.Addrsv6))
+	for _, addrs := range []network.Addresses{attrs.Addrsv4, attrs.Addrsv6} {
+		for _, addr := range addrs {
+			j.Addresses = append(j.Addresses, Address{
+				Family:            family(network.AddressFamily(addr.Family)),
+				Address:           addr.Address,
+				PrefixLength:      addr.PrefixLength,
+				Scope:             addr.Scope,
+				Flags:             addr.Flags.Names(addr.Family),
+				Origin:            addr.Origin.String(),
+				PreferredLifetime: addr.PreferredLifetime,
+				ValidLifetime:     addr.ValidLifetime,
+				Label:             addr.Label,
+				Broadcast:         addr.Broadcast,
+				Peer:              addr.Peer,
+			})
+		}
+	}
+	// Sort the "lower" network interfaces stacked on top of this network
+	// interface into VFs and all others, such as MACVLANs, VLANs, and VXLANs.
+	for _, slave := range attrs.Slaves {
+		if slave.Nif().PF != nil && slave.Nif().PF.Nif() == attrs {
+			j.VFIDs = append(j.VFIDs, InterfaceID(slave))
+			continue
+		}
+		j.UpperIDs = append(j.UpperIDs, InterfaceID(slave))
+	}
+	switch nif := nif.(type) {
+	case network.Bridge:
+		j.PortIDs = interfaceIDs(nif.Bridge().Ports)
+	case network.Veth:
+		j.PeerID = InterfaceID(nif.Veth().Peer)
+	case network.Vlan:
+		vlan := nif.Vlan()
+		j.LowerID = InterfaceID(vlan.Master)
+		j.Vlan = &Vlan{
+			VID:      vlan.VID,
+			Protocol: vlan.VlanProtocol.String(),
+		}
+	case network.Vxlan:
+		vxlan := nif.Vxlan()
+		j.LowerID = InterfaceID(vxlan.Master)
+		j.Vxlan = &Vxlan{
+			VID:             vxlan.VID,
+			Group:           firstIP(vxlan.Groupv4, vxlan.Groupv6),
+			Source:          firstIP(vxlan.Sourcev4, vxlan.Sourcev6),
+			DestinationPort: vxlan.DestinationPort,
+			SourcePortMin:   vxlan.SourcePortLow,
+			SourcePortMax:   vxlan.SourcePortHigh,
+			TTL:             vxlan.TTL,
+			TOS:             vxlan.TOS,
+			ArpProxy:        vxlan.ArpProxy,
+		}
+	case network.Macvlan:
+		macvlan := nif.Macvlan()
+		j.LowerID = InterfaceID(macvlan.Master)
+		j.Macvlan = &Macvlan{
+			Mode: macvlan.Mode.String(),
+		}
+	case network.TunTap:
+		tuntap := nif.TunTap()
+		mode := "tun"
+		if tuntap.Mode == network.TunTapModeTap {
+			mode = "tap"
+		}
+		j.TunTap = &TunTap{
+			Mode:       mode,
+			Processors: newProcesses(tuntap.Processors),
+		}
+	}
+	return j
+}
+
+// sortedNifs returns the network interfaces of the specified network
+// namespace ordered by their interface indices.
+func sortedNifs(netns *network.NetworkNamespace) []network.Interface {
+	nifs := make([]network.Interface, 0, len(netns.Nifs))
+	for _, nif := range netns.Nifs {
+		nifs = append(nifs, nif)
+	}
+	slices.SortFunc(nifs, func(a, b network.Interface) int {
+		return a.Nif().Index - b.Nif().Index
+	})
+	return nifs
+}
+
+// firstIP returns the first non-empty IP address, or nil.
+func firstIP(ips ...net.IP) net.IP {
+	for _, ip := range ips {
+		if len(ip) != 0 {
+			return ip
+		}
+	}
+	return nil
+}
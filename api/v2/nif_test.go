@@ -0,0 +1,89 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("v2 network interfaces", func() {
+
+	It("lists network interfaces", func() {
+		nifs := NewInterfaces(testResult())
+		Expect(nifs.Metadata).To(HaveKey("creator-id"))
+		Expect(nifs.Interfaces).To(HaveLen(11))
+		Expect(nifs.Interfaces[0].ID).To(Equal("4026531840-1"))
+		Expect(nifs.Interfaces[10].ID).To(Equal("4026532000-2"))
+	})
+
+	It("finds network interfaces by their identifiers", func() {
+		Expect(FindInterface(testResult(), "4026532000-2")).To(HaveField("Name", "eth0"))
+		Expect(FindInterface(testResult(), "4026532000-42")).To(BeNil())
+		Expect(FindInterface(testResult(), "666-1")).To(BeNil())
+		Expect(FindInterface(testResult(), "4026532000")).To(BeNil())
+		Expect(FindInterface(testResult(), "4026532000-eth0")).To(BeNil())
+		Expect(FindInterface(testResult(), "nif-4026532000-2")).To(BeNil())
+	})
+
+	It("includes SR-IOV roles, labels, and addresses", func() {
+		eth0 := FindInterface(testResult(), "4026531840-2")
+		Expect(eth0.SRIOVRole).To(Equal("pf"))
+		Expect(eth0.VFIDs).To(ConsistOf("4026531840-3"))
+		Expect(eth0.UpperIDs).To(ConsistOf("4026531840-4", "4026531840-5", "4026531840-9"))
+		Expect(eth0.Labels).To(HaveKeyWithValue("gostwire/dhcp", "dhclient"))
+		Expect(eth0.Driver).To(HaveField("Driver", "ixgbe"))
+		Expect(eth0.MAC).To(Equal("52:54:00:12:34:56"))
+		Expect(eth0.Addresses).To(HaveExactElements(
+			And(HaveField("Family", "ipv4"), HaveField("Address", net.IP{192, 168, 0, 2}),
+				HaveField("PrefixLength", uint(24)), HaveField("Flags", BeEmpty()),
+				HaveField("Broadcast", net.IP{192, 168, 0, 255})),
+			And(HaveField("Family", "ipv6"), HaveField("Flags", ConsistOf("permanent")),
+				HaveField("Origin", "kernel_ll")),
+		))
+
+		vf := FindInterface(testResult(), "4026531840-3")
+		Expect(vf.SRIOVRole).To(Equal("vf"))
+		Expect(vf.PFID).To(Equal("4026531840-2"))
+
+		Expect(FindInterface(testResult(), "4026531840-1").SRIOVRole).To(Equal("none"))
+		Expect(FindInterface(testResult(), "4026531840-1").Labels).To(BeEmpty())
+	})
+
+	It("includes VLAN, VXLAN, MACVLAN, and TAP details", func() {
+		vlan := FindInterface(testResult(), "4026531840-4")
+		Expect(vlan.LowerID).To(Equal("4026531840-2"))
+		Expect(vlan.Vlan).To(Equal(&Vlan{VID: 42, Protocol: "802.1ad"}))
+
+		vxlan := FindInterface(testResult(), "4026531840-5")
+		Expect(vxlan.LowerID).To(Equal("4026531840-2"))
+		Expect(vxlan.Vxlan).To(Equal(&Vxlan{
+			VID: 4711, Group: net.IP{239, 1, 1, 1}, Source: net.IP{192, 168, 0, 2},
+			DestinationPort: 4789, SourcePortMin: 10000, SourcePortMax: 20000,
+			TTL: 64, TOS: 1, ArpProxy: true,
+		}))
+
+		mv0 := FindInterface(testResult(), "4026531840-9")
+		Expect(mv0.LowerID).To(Equal("4026531840-2"))
+		Expect(mv0.Macvlan).To(Equal(&Macvlan{Mode: "bridge"}))
+
+		tap0 := FindInterface(testResult(), "4026531840-8")
+		Expect(tap0.TunTap).To(Equal(&TunTap{Mode: "tap", Processors: []Process{
+			{PID: 1001, Name: "nginx", Cmdline: []string{"nginx: worker"}, ContainerID: "4242abcd"},
+		}}))
+	})
+
+	It("references bridges, ports, and peers", func() {
+		br0 := FindInterface(testResult(), "4026531840-6")
+		Expect(br0.PortIDs).To(ConsistOf("4026531840-7"))
+		veth0 := FindInterface(testResult(), "4026531840-7")
+		Expect(veth0.BridgeID).To(Equal("4026531840-6"))
+		Expect(veth0.PeerID).To(Equal("4026532000-2"))
+		Expect(FindInterface(testResult(), "4026532000-2").PeerID).To(Equal("4026531840-7"))
+	})
+
+})
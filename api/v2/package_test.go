@@ -0,0 +1,208 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/decorator/composer"
+	"github.com/thediveo/lxkns/decorator/kuhbernetes"
+	lxknsdiscover "github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"github.com/thediveo/nufftables/portfinder"
+	"github.com/thediveo/whalewatcher/engineclient/moby"
+	"github.com/thediveo/whalewatcher/watcher/containerd"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	hostnetnsino = 4026531840
+	cntrnetnsino = 4026532000
+)
+
+func TestGostwireApiV2(t *testing.T) {
+	openapi3.DefineIPv4Format()
+	openapi3.DefineIPv6Format()
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ghostwire/api/v2 package")
+}
+
+var v2apispec *openapi3.T
+
+var _ = BeforeSuite(func() {
+	By("loading the v2 specification")
+	var err error
+	v2apispec, err = openapi3.NewLoader().LoadFromFile("../openapi-spec/ghostwire-v2.yaml")
+	Expect(err).To(Succeed())
+	Expect(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return v2apispec.Validate(ctx)
+	}()).To(Succeed(), "Ghostwire API v2 OpenAPI specification is invalid")
+})
+
+// testResult returns a synthetic discovery result with the host network
+// namespace and a container network namespace. The host network namespace has
+// an SR-IOV PF with a VF, a VLAN, a VXLAN, and a MACVLAN stacked on top of the
+// PF, as well as a bridge with a VETH port, and a TAP. The container network
+// namespace is shared by a Docker Compose container and a Kubernetes pod
+// container.
+func testResult() gostwire.DiscoveryResult {
+	hostnetns := &network.NetworkNamespace{
+		Namespace: fakeNamespace{id: species.NamespaceID{Dev: 4, Ino: hostnetnsino}}}
+	cntrnetns := &network.NetworkNamespace{
+		Namespace: fakeNamespace{id: species.NamespaceID{Dev: 4, Ino: cntrnetnsino}}}
+
+	// Container engines, containers, groups, and processes.
+	dockerd := &model.ContainerEngine{ID: "dockerd-1", Type: moby.Type, Version: "24.0.7",
+		API: "unix:///run/docker.sock", PID: 100}
+	containerdengine := &model.ContainerEngine{ID: "containerd-1", Type: containerd.Type,
+		Version: "1.7.2", API: "/run/containerd/containerd.sock", PID: 200}
+	idle := &model.ContainerEngine{ID: "podman-1", Type: "podman.io", API: "/run/podman/podman.sock"}
+
+	initproc := &model.Process{PID: 1, ProTaskCommon: model.ProTaskCommon{Name: "systemd"}, Cmdline: []string{"/sbin/init"}}
+	webproc := &model.Process{PID: 1000, PPID: 1, ProTaskCommon: model.ProTaskCommon{Name: "nginx"}, Cmdline: []string{"nginx", "-g", "daemon off;"},
+		Parent: initproc}
+	webworker := &model.Process{PID: 1001, PPID: 1000, ProTaskCommon: model.ProTaskCommon{Name: "nginx"}, Cmdline: []string{"nginx: worker"},
+		Parent: webproc}
+	sidecarproc := &model.Process{PID: 2000, PPID: 1, ProTaskCommon: model.ProTaskCommon{Name: "envoy"}, Parent: initproc}
+
+	web := &model.Container{ID: "4242abcd", Name: "myproject_web_1", Type: moby.Type, Flavor: moby.Type,
+		PID: 1000, Labels: model.Labels{composer.ComposerProjectLabel: "myproject"},
+		Engine: dockerd, Process: webproc}
+	webproc.Container = web
+	dockerd.AddContainer(web)
+	project := &model.Group{Name: "myproject", Type: composer.ComposerGroupType,
+		Flavor: composer.ComposerGroupType}
+	project.AddContainer(web)
+
+	sidecar := &model.Container{ID: "default/mypod/sidecar", Name: "sidecar", Type: containerd.Type,
+		Flavor: containerd.Type, PID: 2000, Paused: true, Engine: containerdengine, Process: sidecarproc}
+	sidecarproc.Container = sidecar
+	containerdengine.AddContainer(sidecar)
+	pod := &model.Group{Name: "default/mypod", Type: kuhbernetes.PodGroupType,
+		Flavor: kuhbernetes.PodGroupType}
+	pod.AddContainer(sidecar)
+
+	hostnetns.Tenants = network.Tenants{{Process: initproc}}
+	cntrnetns.Tenants = network.Tenants{
+		{Process: sidecarproc},
+		{Process: webproc, DNS: network.DnsConfiguration{
+			Hostname:    "web",
+			Hosts:       map[string]net.IP{"localhost": net.IPv4(127, 0, 0, 1)},
+			Nameservers: []net.IP{net.IPv4(127, 0, 0, 11)},
+			Searchlist:  []string{},
+		}},
+	}
+
+	// Network interfaces...
+	lo := &network.NifAttrs{Netns: hostnetns, Name: "lo", Index: 1, State: network.Unknown,
+		L2Addr: net.HardwareAddr{0, 0, 0, 0, 0, 0},
+		Addrsv4: network.Addresses{{Family: unix.AF_INET, Address: net.IP{127, 0, 0, 1}, PrefixLength: 8,
+			Scope: unix.RT_SCOPE_HOST, Flags: network.AddressPermanent}}}
+	eth0 := &network.NifAttrs{Netns: hostnetns, Name: "eth0", Index: 2, State: network.Up,
+		Physical: true, SRIOVRole: network.PCI_SRIOV_PF,
+		DriverInfo: network.NifDriverInfo{Driver: "ixgbe", BusInfo: "0000:01:00.0"},
+		Labels:     model.Labels{"gostwire/dhcp": "dhclient"},
+		L2Addr:     net.HardwareAddr{0x52, 0x54, 0, 0x12, 0x34, 0x56},
+		Addrsv4: network.Addresses{{Family: unix.AF_INET, Address: net.IP{192, 168, 0, 2}, PrefixLength: 24,
+			Broadcast: net.IP{192, 168, 0, 255}, ValidLifetime: 3600, PreferredLifetime: 3600}},
+		Addrsv6: network.Addresses{{Family: unix.AF_INET6, Address: net.ParseIP("fe80::5054:ff:fe12:3456"),
+			PrefixLength: 64, Scope: unix.RT_SCOPE_LINK, Flags: network.AddressPermanent,
+			Origin: network.AddressOriginKernelLL}}}
+	vf := &network.NifAttrs{Netns: hostnetns, Name: "eth0v0", Index: 3, State: network.Down,
+		Physical: true, SRIOVRole: network.PCI_SRIOV_VF, PF: eth0}
+	vlan := &network.VlanAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "vlan", Name: "eth0.42",
+		Index: 4, State: network.Up}, Master: eth0, VID: 42, VlanProtocol: netlink.VLAN_PROTOCOL_8021AD}
+	vxlan := &network.VxlanAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "vxlan", Name: "vxlan0",
+		Index: 5, State: network.Unknown}, Master: eth0, VID: 4711, Groupv4: net.IP{239, 1, 1, 1},
+		Sourcev4: net.IP{192, 168, 0, 2}, DestinationPort: 4789, SourcePortLow: 10000, SourcePortHigh: 20000,
+		TTL: 64, TOS: 1, ArpProxy: true}
+	br0 := &network.BridgeAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "bridge", Name: "br0",
+		Index: 6, State: network.Up}}
+	veth0 := &network.VethAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "veth", Name: "veth0",
+		Index: 7, State: network.Up, Bridge: br0}}
+	tap0 := &network.TunTapAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "tuntap", Name: "tap0",
+		Index: 8, State: network.Down}, Mode: network.TunTapModeTap, Processors: []*model.Process{webworker}}
+	mv0 := &network.MacvlanAttrs{NifAttrs: network.NifAttrs{Netns: hostnetns, Kind: "macvlan", Name: "mv0",
+		Index: 9, State: network.Up}, Master: eth0,
+		Mode: network.MacvlanMode(netlink.MACVLAN_MODE_BRIDGE)}
+	eth0.Slaves = network.Interfaces{vf, vlan, vxlan, mv0}
+	br0.Ports = network.Interfaces{veth0}
+
+	cntrlo := &network.NifAttrs{Netns: cntrnetns, Name: "lo", Index: 1, State: network.Unknown}
+	cntreth0 := &network.VethAttrs{NifAttrs: network.NifAttrs{Netns: cntrnetns, Kind: "veth", Name: "eth0",
+		Index: 2, State: network.Up,
+		Addrsv4: network.Addresses{{Family: unix.AF_INET, Address: net.IP{172, 17, 0, 2}, PrefixLength: 16}}}}
+	veth0.Peer, cntreth0.Peer = cntreth0, veth0
+
+	hostnetns.Nifs = map[int]network.Interface{
+		1: lo, 2: eth0, 3: vf, 4: vlan, 5: vxlan, 6: br0, 7: veth0, 8: tap0, 9: mv0}
+	cntrnetns.Nifs = map[int]network.Interface{1: cntrlo, 2: cntreth0}
+
+	// Routes, ports, and forwarded ports...
+	_, defaultnet, _ := net.ParseCIDR("0.0.0.0/0")
+	_, localnet, _ := net.ParseCIDR("127.0.0.0/8")
+	_, llnet, _ := net.ParseCIDR("fe80::/64")
+	hostnetns.Routesv4 = []network.Route{
+		{Family: unix.AF_INET, Type: unix.RTN_UNICAST, Destination: *defaultnet,
+			NextHop: net.IP{192, 168, 0, 1}, Index: 2, Nif: eth0, Table: unix.RT_TABLE_MAIN, Priority: 100},
+		{Family: unix.AF_INET, Type: unix.RTN_LOCAL, Destination: *localnet, DestinationPrefixLen: 8,
+			Index: 1, Nif: lo, Table: unix.RT_TABLE_LOCAL},
+	}
+	hostnetns.Routesv6 = []network.Route{
+		{Family: unix.AF_INET6, Type: unix.RTN_UNICAST, Destination: *llnet, DestinationPrefixLen: 64,
+			Index: 2, Nif: eth0, Table: unix.RT_TABLE_MAIN, Priority: 256, Preference: 1},
+	}
+	hostnetns.Portsv4 = []network.ProcessSocket{
+		{Family: unix.AF_INET, Protocol: unix.IPPROTO_TCP,
+			LocalIP: net.IP{0, 0, 0, 0}, LocalPort: 22, RemoteIP: net.IP{0, 0, 0, 0},
+			State: network.TCP_LISTEN, SimplifiedState: network.Listening, Inode: 12345,
+			Processes: []*model.Process{initproc}, Nifs: network.Interfaces{lo, eth0}},
+	}
+	cntrnetns.Portsv4 = []network.ProcessSocket{
+		{Family: unix.AF_INET, Protocol: unix.IPPROTO_TCP,
+			LocalIP: net.IP{0, 0, 0, 0}, LocalPort: 80, RemoteIP: net.IP{0, 0, 0, 0},
+			State: network.TCP_LISTEN, SimplifiedState: network.Listening,
+			Processes: []*model.Process{webworker}, Nifs: network.Interfaces{cntrlo, cntreth0}},
+	}
+	hostnetns.ForwardedPortsv4 = []network.ForwardedPort{
+		{
+			ForwardedPortRange: portfinder.ForwardedPortRange{Protocol: "tcp",
+				IP: net.IP{0, 0, 0, 0}, PortMin: 8000, PortMax: 8010,
+				ForwardIP: net.IP{172, 17, 0, 2}, ForwardPortMin: 80},
+			Protocol:         unix.IPPROTO_TCP,
+			DestinationNetns: cntrnetns,
+			Processes:        []*model.Process{webworker},
+			Nifs:             network.Interfaces{cntreth0},
+		},
+	}
+
+	return gostwire.DiscoveryResult{
+		Netns: network.NetworkNamespaces{
+			hostnetns.ID(): hostnetns,
+			cntrnetns.ID(): cntrnetns,
+		},
+		Lxkns: &lxknsdiscover.Result{
+			Processes:  model.ProcessTable{1: initproc, 1000: webproc, 1001: webworker, 2000: sidecarproc},
+			Containers: model.Containers{web, sidecar},
+		},
+		Engines:     []*model.ContainerEngine{dockerd, containerdengine, idle},
+		Diagnostics: diagnostics.New(),
+	}
+}
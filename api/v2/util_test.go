@@ -0,0 +1,40 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+)
+
+// validate validates the JSON representation of v against the named schema of
+// the specified OpenAPI specification.
+func validate(openapispec *openapi3.T, schemaname string, v interface{}) error {
+	schemaref, ok := openapispec.Components.Schemas[schemaname]
+	if !ok {
+		return fmt.Errorf("invalid schema reference %q", schemaname)
+	}
+	jsondata, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var jsonobj interface{}
+	if err := json.Unmarshal(jsondata, &jsonobj); err != nil {
+		return err
+	}
+	return schemaref.Value.VisitJSON(jsonobj)
+}
+
+// fakeNamespace is a model.Namespace that only knows its identifier.
+type fakeNamespace struct {
+	model.Namespace
+	id species.NamespaceID
+}
+
+func (n fakeNamespace) ID() species.NamespaceID { return n.id }
@@ -0,0 +1,133 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/diff"
+	"github.com/siemens/turtlefinder"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("support bundles", func() {
+
+	It("ignores sequence numbers in netlink request keys", func() {
+		newReq := func() *nl.NetlinkRequest {
+			req := nl.NewNetlinkRequest(unix.NLM_F_DUMP, unix.NLM_F_REQUEST)
+			req.AddData(&nl.Genlmsg{Command: nl.GENL_CTRL_CMD_GETFAMILY, Version: nl.GENL_CTRL_VERSION})
+			return req
+		}
+		req1 := newReq()
+		req2 := newReq()
+		Expect(req1.Seq).NotTo(Equal(req2.Seq))
+		Expect(netlinkKey(req1, unix.NETLINK_GENERIC, 0)).To(
+			Equal(netlinkKey(req2, unix.NETLINK_GENERIC, 0)))
+	})
+
+	It("records and replays files", func() {
+		tmpdir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(tmpdir, "foo"), []byte("bar"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(tmpdir, "dir"), 0755)).To(Succeed())
+		Expect(os.Symlink("foo", filepath.Join(tmpdir, "link"))).To(Succeed())
+
+		rec := NewRecorder(gostwire.LiveSource())
+		defer rec.Close()
+		Expect(rec.ReadFile(filepath.Join(tmpdir, "foo"))).To(Equal([]byte("bar")))
+		Expect(rec.ReadDir(tmpdir)).To(HaveLen(3))
+		Expect(rec.Readlink(filepath.Join(tmpdir, "link"))).To(Equal("foo"))
+		stat := Successful(rec.Stat(filepath.Join(tmpdir, "link")))
+		_, err := rec.ReadFile(filepath.Join(tmpdir, "dir"))
+		Expect(err).To(MatchError(unix.EISDIR))
+
+		var buf bytes.Buffer
+		Expect(rec.Write(&buf)).To(Succeed())
+		recording := Successful(readRecording(&buf))
+		Expect(recording.Manifest.Format).To(Equal(FormatVersion))
+		files := replayFiles{recording.Host}
+
+		Expect(files.ReadFile(filepath.Join(tmpdir, "foo"))).To(Equal([]byte("bar")))
+		entries := Successful(files.ReadDir(tmpdir + "/"))
+		Expect(entries).To(ConsistOf(
+			HaveField("Name()", "foo"),
+			HaveField("IsDir()", true),
+			HaveField("Type()", fs.ModeSymlink)))
+		Expect(files.Readlink(filepath.Join(tmpdir, "link"))).To(Equal("foo"))
+		Expect(files.Stat(filepath.Join(tmpdir, "link"))).To(Equal(stat))
+		_, err = files.ReadFile(filepath.Join(tmpdir, "dir"))
+		Expect(err).To(MatchError(unix.EISDIR))
+		_, err = files.ReadFile(filepath.Join(tmpdir, "nada"))
+		Expect(err).To(MatchError(fs.ErrNotExist))
+		_, err = files.Lstat(filepath.Join(tmpdir, "foo"))
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("rejects invalid support bundles", func() {
+		Expect(Read(strings.NewReader("foobar"))).Error().To(HaveOccurred())
+
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gzw)
+		Expect(tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg, Name: "foo", Mode: 0644, Size: 3,
+		})).To(Succeed())
+		Expect(tw.Write([]byte("bar"))).Error().NotTo(HaveOccurred())
+		Expect(tw.Close()).To(Succeed())
+		Expect(gzw.Close()).To(Succeed())
+		Expect(Read(&buf)).Error().To(MatchError(ContainSubstring("missing " + manifestName)))
+
+		Expect(Open(filepath.Join(GinkgoT().TempDir(), "nada"))).Error().To(
+			MatchError(fs.ErrNotExist))
+	})
+
+	It("captures and replays a discovery", NodeTimeout(60*time.Second), func(ctx context.Context) {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+
+		var buf bytes.Buffer
+		Expect(Capture(ctx, cizer, &buf)).To(Succeed())
+		replay := Successful(Read(&buf))
+		Expect(replay.Manifest().Hostname).NotTo(BeEmpty())
+		Expect(replay.Discovery()).NotTo(BeEmpty())
+
+		// The same discovery result must come out of the support bundle
+		// regardless of how often we replay it.
+		snapshot := func() *diff.Snapshot {
+			return Successful(diff.FromResult(
+				gostwire.Discover(ctx, nil, nil, gostwire.WithSource(replay))))
+		}
+		captured := snapshot()
+		Expect(captured.Netns).NotTo(BeEmpty())
+		Expect(diff.Compare(captured, snapshot()).Empty()).To(BeTrue())
+
+		// And the replayed discovery result must match the live discovery
+		// result captured in the bundle. Diff snapshots leave out the metadata,
+		// such as timestamps, timings, and plugin runs, which cannot be
+		// replayed.
+		live := Successful(diff.Load(bytes.NewReader(replay.Discovery())))
+		d := diff.Compare(live, captured)
+		var text bytes.Buffer
+		Expect(d.WriteText(&text)).To(Succeed())
+		Expect(d.Empty()).To(BeTrue(), "live and replayed discovery differ:\n%s", text.String())
+	})
+
+})
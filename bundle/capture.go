@@ -0,0 +1,40 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/internal/discover"
+
+	"github.com/thediveo/lxkns/containerizer"
+)
+
+// Capture runs a discovery using the specified containerizer and discovery
+// options and writes a support bundle with all the system information read in
+// the course of the discovery, including the metadata plugins. Unless the
+// discovery options specify a source, the host Capture runs on gets captured.
+func Capture(ctx context.Context, cizer containerizer.Containerizer, w io.Writer, opts ...gostwire.DiscoveryOption) error {
+	options := discover.NewOptions(opts...)
+	rec := NewRecorder(options.SourceOrLive())
+	defer rec.Close()
+	result := gostwire.Discover(ctx, cizer, nil,
+		append(opts[:len(opts):len(opts)], gostwire.WithSource(rec))...)
+	// Rendering the v1 JSON discovery result also runs the metadata plugins, so
+	// that the system information they read gets recorded too.
+	v1result := apiv1.NewDiscoveryResult(result)
+	discovery, err := json.Marshal(&v1result)
+	if err != nil {
+		return err
+	}
+	rec.mu.Lock()
+	rec.rec.Discovery = discovery
+	rec.mu.Unlock()
+	return rec.Write(w)
+}
@@ -0,0 +1,39 @@
+/*
+Package bundle captures support bundles containing all the system information a
+Ghostwire discovery is based on, and replays discoveries from such bundles on
+other hosts.
+
+A support bundle is a gzip-compressed tarball with the following contents:
+
+  - manifest.json: bundle format version, capture time, as well as the host
+    name and kernel release of the host the bundle was captured on.
+  - namespaces.json: the Linux-kernel namespace, process, and container
+    discovery result in lxkns JSON format.
+  - engines.json: the container engines, even without any workload.
+  - system.json: host names of UTS namespaces, mount points of processes, and
+    the network namespaces of TAP/TUN netdevs.
+  - engines-api.json: the container engine API responses.
+  - netns/INO.json: the netlink replies, NSIDs, driver information, and
+    netfilter tables of the network namespace with inode number INO.
+  - files/host/..., files/net/INO/..., and files/mnt/INO/...: the files read
+    from the host, from the procfs and sysfs of network namespaces, as well as
+    from inside mount namespaces. The corresponding files/host.json,
+    files/net/INO.json, and files/mnt/INO.json list directories, symbolic
+    links, file stats, and failed accesses.
+  - discovery.json: the discovery result in v1 JSON format for reference.
+
+Capture discovers the host it is running on and writes a support bundle of
+everything read in the course of this discovery. Open (or Read) a support
+bundle and then pass it to [gostwire.Discover] using [gostwire.WithSource] in
+order to replay discoveries from it:
+
+	replay, err := bundle.Open("gostwire-bundle.tar.gz")
+	result := gostwire.Discover(ctx, nil, nil, gostwire.WithSource(replay))
+
+Please note that replays are only as complete as the captured discovery was;
+for instance, when discovering only some network namespaces during capture, the
+other network namespaces won't be present in the replayed discovery results.
+Namespaces are identified by their inode numbers only, as the nsfs device ID
+differs between hosts.
+*/
+package bundle
@@ -0,0 +1,429 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/go-mntinfo"
+	"github.com/thediveo/lxkns/model"
+	"golang.org/x/sys/unix"
+)
+
+// FormatVersion is the version of the support bundle format written by
+// Capture; Read refuses bundles of other versions.
+const FormatVersion = 1
+
+// ErrNotRecorded is returned when replaying something that has not been
+// recorded in a support bundle, such as a network namespace that wasn't
+// discovered during capture.
+var ErrNotRecorded = errors.New("not recorded in support bundle")
+
+// Names of the (top-level) bundle tarball entries; see the package
+// documentation for details.
+const (
+	manifestName   = "manifest.json"
+	namespacesName = "namespaces.json"
+	enginesName    = "engines.json"
+	systemName     = "system.json"
+	engineAPIName  = "engines-api.json"
+	discoveryName  = "discovery.json"
+	netnsDir       = "netns"
+	filesDir       = "files"
+	hostScope      = "host"
+	netScope       = "net"
+	mntScope       = "mnt"
+)
+
+// Manifest describes a support bundle.
+type Manifest struct {
+	Format   int       `json:"format"`   // bundle format version.
+	Created  time.Time `json:"created"`  // time of capture.
+	Hostname string    `json:"hostname"` // name of the host captured.
+	Kernel   string    `json:"kernel"`   // kernel release of the host captured.
+	Version  string    `json:"version"`  // Gostwire semantic version capturing.
+}
+
+// recording is everything recorded in a support bundle.
+type recording struct {
+	Manifest   Manifest
+	Namespaces json.RawMessage // lxkns JSON discovery result.
+	Engines    json.RawMessage // container engines.
+	System     system
+	EngineAPI  map[string]*httpExchange // keyed by httpKey.
+	Netns      map[uint64]*netnsRecording
+	Host       *fileScope
+	Mntns      map[uint64]*fileScope
+	Discovery  json.RawMessage // v1 JSON discovery result, for reference only.
+}
+
+// system records the process and namespace-related information that isn't
+// read from files.
+type system struct {
+	Hostnames map[uint64]hostname                   `json:"hostnames,omitempty"` // keyed by UTS namespace inode.
+	Mounts    map[model.PIDType][]mntinfo.Mountinfo `json:"mounts,omitempty"`
+	TunTaps   map[string]tuntap                     `json:"tuntaps,omitempty"` // keyed by "PID/FD".
+}
+
+type hostname struct {
+	Name    string   `json:"name,omitempty"`
+	Failure *failure `json:"failure,omitempty"`
+}
+
+type tuntap struct {
+	Netns   uint64   `json:"netns,omitempty"` // network namespace inode.
+	Failure *failure `json:"failure,omitempty"`
+}
+
+// netnsRecording records the information specific to a network namespace.
+type netnsRecording struct {
+	Failure    *failure                 `json:"failure,omitempty"` // visiting failed.
+	Netlink    map[string]*netlinkReply `json:"netlink,omitempty"` // keyed by netlinkKey.
+	NSIDs      map[uint64]nsid          `json:"nsids,omitempty"`   // keyed by peer netns inode.
+	DriverInfo map[string]driverInfo    `json:"driverinfo,omitempty"`
+	Netfilter  map[string]*netlinkReply `json:"netfilter,omitempty"` // keyed by netfilterKey.
+	NftFailure *failure                 `json:"nftfailure,omitempty"`
+	files      *fileScope
+}
+
+// netlinkReply records the reply messages to a netlink request, or the failure
+// of the request.
+type netlinkReply struct {
+	Messages [][]byte `json:"messages,omitempty"`
+	Failure  *failure `json:"failure,omitempty"`
+}
+
+type nsid struct {
+	NSID    int      `json:"nsid"`
+	Failure *failure `json:"failure,omitempty"`
+}
+
+type driverInfo struct {
+	Info    *unix.EthtoolDrvinfo `json:"info,omitempty"`
+	Failure *failure             `json:"failure,omitempty"`
+}
+
+// httpExchange records the response to a container engine API request.
+type httpExchange struct {
+	Status  int                 `json:"status,omitempty"`
+	Header  map[string][]string `json:"header,omitempty"`
+	Body    []byte              `json:"body,omitempty"`
+	Failure *failure            `json:"failure,omitempty"`
+}
+
+// fileScope records the files, directories, and symbolic links read from the
+// host, from a network namespace, or from a mount namespace. The file contents
+// are stored separately as tarball entries, while the JSON index only lists the
+// directories, symbolic links, file stats, and failures.
+type fileScope struct {
+	contents map[string][]byte
+	Dirs     map[string][]dirEntry       `json:"dirs,omitempty"`
+	Links    map[string]string           `json:"links,omitempty"`
+	Stats    map[string]network.FileStat `json:"stats,omitempty"`
+	Lstats   map[string]network.FileStat `json:"lstats,omitempty"`
+	Failures map[string]failure          `json:"failures,omitempty"` // keyed by "op path".
+}
+
+// dirEntry records a directory entry, including its file info.
+type dirEntry struct {
+	Name    string      `json:"name"`
+	Mode    fs.FileMode `json:"mode"`
+	Size    int64       `json:"size,omitempty"`
+	ModTime time.Time   `json:"modtime,omitempty"`
+}
+
+// failure records an error, preferably as its errno so that it can be replayed
+// faithfully.
+type failure struct {
+	Errno   unix.Errno `json:"errno,omitempty"`
+	Message string     `json:"message"`
+}
+
+func newRecording() *recording {
+	return &recording{
+		System: system{
+			Hostnames: map[uint64]hostname{},
+			Mounts:    map[model.PIDType][]mntinfo.Mountinfo{},
+			TunTaps:   map[string]tuntap{},
+		},
+		EngineAPI: map[string]*httpExchange{},
+		Netns:     map[uint64]*netnsRecording{},
+		Host:      newFileScope(),
+		Mntns:     map[uint64]*fileScope{},
+	}
+}
+
+func newNetnsRecording() *netnsRecording {
+	return &netnsRecording{
+		Netlink:    map[string]*netlinkReply{},
+		NSIDs:      map[uint64]nsid{},
+		DriverInfo: map[string]driverInfo{},
+		Netfilter:  map[string]*netlinkReply{},
+		files:      newFileScope(),
+	}
+}
+
+func newFileScope() *fileScope {
+	return &fileScope{
+		contents: map[string][]byte{},
+		Dirs:     map[string][]dirEntry{},
+		Links:    map[string]string{},
+		Stats:    map[string]network.FileStat{},
+		Lstats:   map[string]network.FileStat{},
+		Failures: map[string]failure{},
+	}
+}
+
+// newFailure returns the failure record for the specified error, or nil if
+// there is no error.
+func newFailure(err error) *failure {
+	if err == nil {
+		return nil
+	}
+	f := &failure{Message: err.Error()}
+	_ = errors.As(err, &f.Errno)
+	return f
+}
+
+// err returns the error recorded, preferably the original errno.
+func (f *failure) err() error {
+	if f == nil {
+		return nil
+	}
+	if f.Errno != 0 {
+		return f.Errno
+	}
+	return errors.New(f.Message)
+}
+
+// pathErr returns the recorded error for the specified file operation and
+// path.
+func (f *failure) pathErr(op string, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: f.err()}
+}
+
+// write writes this recording as a gzip-compressed tarball.
+func (r *recording) write(w io.Writer) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	modtime := r.Manifest.Created
+	put := func(name string, contents []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			ModTime:  modtime,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(contents)
+		return err
+	}
+	putJSON := func(name string, v interface{}) error {
+		contents, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return put(name, contents)
+	}
+	putScope := func(dir string, scope *fileScope) error {
+		if err := putJSON(dir+".json", scope); err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(scope.contents) {
+			if err := put(dir+"/"+strings.TrimPrefix(path.Clean("/"+name), "/"), scope.contents[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := putJSON(manifestName, r.Manifest)
+	if err == nil && r.Namespaces != nil {
+		err = put(namespacesName, r.Namespaces)
+	}
+	if err == nil && r.Engines != nil {
+		err = put(enginesName, r.Engines)
+	}
+	if err == nil {
+		err = putJSON(systemName, r.System)
+	}
+	if err == nil {
+		err = putJSON(engineAPIName, r.EngineAPI)
+	}
+	for _, ino := range sortedKeys(r.Netns) {
+		if err != nil {
+			break
+		}
+		netns := r.Netns[ino]
+		err = putJSON(path.Join(netnsDir, strconv.FormatUint(ino, 10)+".json"), netns)
+		if err == nil {
+			err = putScope(path.Join(filesDir, netScope, strconv.FormatUint(ino, 10)), netns.files)
+		}
+	}
+	if err == nil {
+		err = putScope(path.Join(filesDir, hostScope), r.Host)
+	}
+	for _, ino := range sortedKeys(r.Mntns) {
+		if err != nil {
+			break
+		}
+		err = putScope(path.Join(filesDir, mntScope, strconv.FormatUint(ino, 10)), r.Mntns[ino])
+	}
+	if err == nil && r.Discovery != nil {
+		err = put(discoveryName, r.Discovery)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// readRecording reads a recording from a gzip-compressed tarball.
+func readRecording(rd io.Reader) (*recording, error) {
+	gzr, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid support bundle, reason: %w", err)
+	}
+	defer gzr.Close()
+	r := newRecording()
+	var hasManifest bool
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid support bundle, reason: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		contents, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid support bundle, reason: %w", err)
+		}
+		if err := r.add(hdr.Name, contents); err != nil {
+			return nil, fmt.Errorf("invalid support bundle entry %q, reason: %w", hdr.Name, err)
+		}
+		hasManifest = hasManifest || hdr.Name == manifestName
+	}
+	if !hasManifest {
+		return nil, errors.New("invalid support bundle, missing " + manifestName)
+	}
+	if r.Manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported support bundle format version %d", r.Manifest.Format)
+	}
+	return r, nil
+}
+
+// add adds the specified bundle tarball entry to this recording.
+func (r *recording) add(name string, contents []byte) error {
+	switch name {
+	case manifestName:
+		return json.Unmarshal(contents, &r.Manifest)
+	case namespacesName:
+		r.Namespaces = contents
+		return nil
+	case enginesName:
+		r.Engines = contents
+		return nil
+	case systemName:
+		return json.Unmarshal(contents, &r.System)
+	case engineAPIName:
+		return json.Unmarshal(contents, &r.EngineAPI)
+	case discoveryName:
+		r.Discovery = contents
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(name, netnsDir+"/"); ok {
+		ino, err := strconv.ParseUint(strings.TrimSuffix(rest, ".json"), 10, 64)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(contents, r.netns(ino))
+	}
+	rest, ok := strings.CutPrefix(name, filesDir+"/")
+	if !ok {
+		return nil // ignore unknown entries.
+	}
+	scopename, rest, _ := strings.Cut(rest, "/")
+	var scope *fileScope
+	switch scopename {
+	case hostScope:
+		scope = r.Host
+	case hostScope + ".json":
+		return json.Unmarshal(contents, r.Host)
+	case netScope, mntScope:
+		inoname, filename, _ := strings.Cut(rest, "/")
+		ino, err := strconv.ParseUint(strings.TrimSuffix(inoname, ".json"), 10, 64)
+		if err != nil {
+			return err
+		}
+		if scopename == netScope {
+			scope = r.netns(ino).files
+		} else {
+			scope = r.mntns(ino)
+		}
+		if filename == "" {
+			return json.Unmarshal(contents, scope)
+		}
+		rest = filename
+	default:
+		return nil
+	}
+	scope.contents["/"+rest] = contents
+	return nil
+}
+
+// netns returns the recording of the network namespace with the specified
+// inode number, creating it if necessary.
+func (r *recording) netns(ino uint64) *netnsRecording {
+	netns, ok := r.Netns[ino]
+	if !ok {
+		netns = newNetnsRecording()
+		r.Netns[ino] = netns
+	}
+	return netns
+}
+
+// mntns returns the files recorded from the mount namespace with the specified
+// inode number, creating the file scope if necessary.
+func (r *recording) mntns(ino uint64) *fileScope {
+	scope, ok := r.Mntns[ino]
+	if !ok {
+		scope = newFileScope()
+		r.Mntns[ino] = scope
+	}
+	return scope
+}
+
+// sortedKeys returns the keys of the specified map in sort order, so that
+// bundles get written in a deterministic order.
+func sortedKeys[K string | uint64, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
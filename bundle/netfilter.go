@@ -0,0 +1,94 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// netlinkKey returns the key for recording the reply to the specified netlink
+// request, ignoring the request's sequence number and port ID.
+func netlinkKey(req *nl.NetlinkRequest, sockType int, resType uint16) string {
+	b := req.Serialize()
+	if len(b) >= unix.SizeofNlMsghdr {
+		clear(b[8:unix.SizeofNlMsghdr]) // nlmsg_seq and nlmsg_pid
+	}
+	// nl.Genlmsg serializes whatever happens to follow it in memory as the
+	// reserved field of struct genlmsghdr.
+	if sockType == unix.NETLINK_GENERIC && len(b) >= unix.SizeofNlMsghdr+nl.SizeofGenlmsg {
+		clear(b[unix.SizeofNlMsghdr+2 : unix.SizeofNlMsghdr+nl.SizeofGenlmsg])
+	}
+	return fmt.Sprintf("%d/%d/%x", sockType, resType, b)
+}
+
+// netfilterKey returns the key for recording the reply to the specified
+// netfilter netlink request, ignoring the request's sequence number and port
+// ID.
+func netfilterKey(req netlink.Message) string {
+	return fmt.Sprintf("%d/%d/%x", req.Header.Type, req.Header.Flags, req.Data)
+}
+
+// marshalNetfilterMessage returns the binary representation of the specified
+// netfilter netlink reply message, with its sequence number and port ID
+// cleared.
+func marshalNetfilterMessage(msg netlink.Message) []byte {
+	msg.Header.Sequence = 0
+	msg.Header.PID = 0
+	b, _ := msg.MarshalBinary()
+	return b
+}
+
+// unmarshalNetfilterMessages returns the netfilter netlink reply messages from
+// their binary representations.
+func unmarshalNetfilterMessages(bs [][]byte) ([]netlink.Message, error) {
+	msgs := make([]netlink.Message, 0, len(bs))
+	for _, b := range bs {
+		var msg netlink.Message
+		if err := msg.UnmarshalBinary(b); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// netfilterFunc returns an nltest.Func for use with nftables.WithTestDial that
+// executes the individual netfilter netlink requests using the specified
+// execute function. The reply messages are stamped with the sequence numbers
+// of their requests and multi-part replies get terminated as expected by the
+// nltest connection.
+func netfilterFunc(execute func(req netlink.Message) ([]netlink.Message, error)) nltest.Func {
+	return func(reqs []netlink.Message) ([]netlink.Message, error) {
+		var replies []netlink.Message
+		for _, req := range reqs {
+			msgs, err := execute(req)
+			if err != nil {
+				return nil, err
+			}
+			multi := false
+			for _, msg := range msgs {
+				msg.Header.Sequence = req.Header.Sequence
+				msg.Header.PID = 0
+				multi = multi || msg.Header.Flags&netlink.Multi != 0
+				replies = append(replies, msg)
+			}
+			if multi {
+				replies = append(replies, netlink.Message{
+					Header: netlink.Header{
+						Type:     netlink.Done,
+						Flags:    netlink.Multi,
+						Sequence: req.Header.Sequence,
+					},
+				})
+			}
+		}
+		return replies, nil
+	}
+}
@@ -0,0 +1,437 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/google/nftables"
+	"github.com/mdlayher/netlink"
+	"github.com/thediveo/go-mntinfo"
+	"github.com/thediveo/lxkns/api/types"
+	"github.com/thediveo/lxkns/containerizer"
+	lxknsdiscover "github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Recorder is a discovery source recording all system information read from
+// another source, usually the live source, so that it can later be written as
+// a support bundle. A Recorder must be closed after use in order to release
+// the netfilter netlink connections it opened.
+type Recorder struct {
+	src gostwire.Source
+
+	mu       sync.Mutex
+	rec      *recording
+	fds      map[int]uint64 // network namespace fds to netns inode numbers.
+	nftconns []*netlink.Conn
+}
+
+var _ gostwire.Source = (*Recorder)(nil)
+
+// NewRecorder returns a new Recorder recording the system information read
+// from the specified source.
+func NewRecorder(src gostwire.Source) *Recorder {
+	return &Recorder{
+		src: src,
+		rec: newRecording(),
+		fds: map[int]uint64{},
+	}
+}
+
+// Close releases the netfilter netlink connections opened while recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, conn := range r.nftconns {
+		_ = conn.Close()
+	}
+	r.nftconns = nil
+	return nil
+}
+
+// Write writes everything recorded so far as a support bundle.
+func (r *Recorder) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rec.Manifest.Created.IsZero() {
+		r.rec.Manifest = newManifest()
+	}
+	return r.rec.write(w)
+}
+
+// newManifest returns the manifest describing a support bundle captured on
+// this host right now.
+func newManifest() Manifest {
+	m := Manifest{
+		Format:  FormatVersion,
+		Created: time.Now().UTC(),
+		Version: gostwire.SemVersion,
+	}
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		m.Hostname = unix.ByteSliceToString(uts.Nodename[:])
+		m.Kernel = unix.ByteSliceToString(uts.Release[:])
+	}
+	return m
+}
+
+// Namespaces records the result of the namespace, process, and container
+// discovery.
+func (r *Recorder) Namespaces(cizer containerizer.Containerizer, labels map[string]string) *lxknsdiscover.Result {
+	result := r.src.Namespaces(cizer, labels)
+	// Marshal right now, before decorators get their hands on the containers.
+	namespaces, err := json.Marshal(types.NewDiscoveryResult(types.WithResult(result)))
+	if err != nil {
+		log.Errorf("cannot record namespace discovery, reason: %s", err.Error())
+	}
+	r.mu.Lock()
+	r.rec.Namespaces = namespaces
+	r.mu.Unlock()
+	return result
+}
+
+// Engines records the container engines.
+func (r *Recorder) Engines(cizer containerizer.Containerizer) []*model.ContainerEngine {
+	engines := r.src.Engines(cizer)
+	enginesJSON, err := json.Marshal(engines)
+	if err != nil {
+		log.Errorf("cannot record container engines, reason: %s", err.Error())
+	}
+	r.mu.Lock()
+	r.rec.Engines = enginesJSON
+	r.mu.Unlock()
+	return engines
+}
+
+// Visit records the information read from the specified network namespace.
+func (r *Recorder) Visit(netns model.Namespace, fn func(network.NetnsAccess) error) error {
+	r.mu.Lock()
+	rec := r.rec.netns(netns.ID().Ino)
+	r.mu.Unlock()
+	visited := false
+	err := r.src.Visit(netns, func(nsa network.NetnsAccess) error {
+		visited = true
+		return fn(&recordingNetnsAccess{
+			recordingFiles: recordingFiles{r: r, files: nsa, scope: rec.files},
+			nsa:            nsa,
+			rec:            rec,
+		})
+	})
+	if !visited && err != nil {
+		r.mu.Lock()
+		rec.Failure = newFailure(err)
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// NetnsFd returns an open fd referencing the specified network namespace and
+// remembers the network namespace for recording NSIDs.
+func (r *Recorder) NetnsFd(netns model.Namespace) (int, func(), error) {
+	fd, closer, err := r.src.NetnsFd(netns)
+	if err != nil {
+		return fd, closer, err
+	}
+	r.mu.Lock()
+	r.fds[fd] = netns.ID().Ino
+	r.mu.Unlock()
+	return fd, func() {
+		r.mu.Lock()
+		delete(r.fds, fd)
+		r.mu.Unlock()
+		closer()
+	}, nil
+}
+
+// MountFiles records the files read from inside the specified mount namespace.
+func (r *Recorder) MountFiles(mntns model.Namespace) (network.Files, func(), error) {
+	files, closer, err := r.src.MountFiles(mntns)
+	if err != nil {
+		return files, closer, err
+	}
+	r.mu.Lock()
+	scope := r.rec.mntns(mntns.ID().Ino)
+	r.mu.Unlock()
+	return recordingFiles{r: r, files: files, scope: scope}, closer, nil
+}
+
+// Hostname records the host name of the specified UTS namespace.
+func (r *Recorder) Hostname(utsns model.Namespace) (string, error) {
+	name, err := r.src.Hostname(utsns)
+	r.mu.Lock()
+	r.rec.System.Hostnames[utsns.ID().Ino] = hostname{Name: name, Failure: newFailure(err)}
+	r.mu.Unlock()
+	return name, err
+}
+
+// Mounts records the mount points in the mount namespace of the specified
+// process.
+func (r *Recorder) Mounts(pid model.PIDType) []mntinfo.Mountinfo {
+	mounts := r.src.Mounts(pid)
+	r.mu.Lock()
+	r.rec.System.Mounts[pid] = mounts
+	r.mu.Unlock()
+	return mounts
+}
+
+// TunTapNetns records the network namespace of a TAP/TUN netdev.
+func (r *Recorder) TunTapNetns(pid model.PIDType, fd int) (species.NamespaceID, error) {
+	netnsid, err := r.src.TunTapNetns(pid, fd)
+	r.mu.Lock()
+	r.rec.System.TunTaps[tuntapKey(pid, fd)] = tuntap{Netns: netnsid.Ino, Failure: newFailure(err)}
+	r.mu.Unlock()
+	return netnsid, err
+}
+
+// tuntapKey returns the key for recording TAP/TUN netdev network namespaces.
+func tuntapKey(pid model.PIDType, fd int) string {
+	return strconv.FormatInt(int64(pid), 10) + "/" + strconv.Itoa(fd)
+}
+
+// EngineClient returns an HTTP client recording the responses of the specified
+// container engine API.
+func (r *Recorder) EngineClient(api string) *http.Client {
+	client := r.src.EngineClient(api)
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &recordingTransport{r: r, api: api, next: next}
+	return client
+}
+
+// recordingTransport records the responses of a container engine API.
+type recordingTransport struct {
+	r    *Recorder
+	api  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := httpKey(t.api, req)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.r.record(func(rec *recording) { rec.EngineAPI[key] = &httpExchange{Failure: newFailure(err)} })
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.r.record(func(rec *recording) { rec.EngineAPI[key] = &httpExchange{Failure: newFailure(err)} })
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.r.record(func(rec *recording) {
+		rec.EngineAPI[key] = &httpExchange{
+			Status: resp.StatusCode,
+			Header: resp.Header.Clone(),
+			Body:   body,
+		}
+	})
+	return resp, nil
+}
+
+// httpKey returns the key for recording the response to the specified engine
+// API request.
+func httpKey(api string, req *http.Request) string {
+	return api + " " + req.Method + " " + req.URL.RequestURI()
+}
+
+// record calls fn with the recording while holding the lock.
+func (r *Recorder) record(fn func(rec *recording)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r.rec)
+}
+
+// recordingNetnsAccess records the information read from a network namespace.
+type recordingNetnsAccess struct {
+	recordingFiles
+	nsa network.NetnsAccess
+	rec *netnsRecording
+}
+
+func (a *recordingNetnsAccess) Execute(req *nl.NetlinkRequest, sockType int, resType uint16) ([][]byte, error) {
+	key := netlinkKey(req, sockType, resType)
+	msgs, err := a.nsa.Execute(req, sockType, resType)
+	a.r.mu.Lock()
+	a.rec.Netlink[key] = &netlinkReply{Messages: msgs, Failure: newFailure(err)}
+	a.r.mu.Unlock()
+	return msgs, err
+}
+
+func (a *recordingNetnsAccess) NSID(peerfd int) (int, error) {
+	id, err := a.nsa.NSID(peerfd)
+	a.r.mu.Lock()
+	if ino, ok := a.r.fds[peerfd]; ok {
+		a.rec.NSIDs[ino] = nsid{NSID: id, Failure: newFailure(err)}
+	}
+	a.r.mu.Unlock()
+	return id, err
+}
+
+func (a *recordingNetnsAccess) DriverInfo(name string) (*unix.EthtoolDrvinfo, error) {
+	info, err := a.nsa.DriverInfo(name)
+	a.r.mu.Lock()
+	a.rec.DriverInfo[name] = driverInfo{Info: info, Failure: newFailure(err)}
+	a.r.mu.Unlock()
+	return info, err
+}
+
+// Netfilter returns a netfilter connection recording the netfilter netlink
+// conversation. As nftables doesn't allow intercepting its netlink
+// conversation, the conversation is proxied through an mdlayher netlink
+// connection opened in the network namespace currently visited.
+func (a *recordingNetnsAccess) Netfilter() (*nftables.Conn, error) {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		a.r.mu.Lock()
+		a.rec.NftFailure = newFailure(err)
+		a.r.mu.Unlock()
+		return nil, err
+	}
+	a.r.mu.Lock()
+	a.r.nftconns = append(a.r.nftconns, conn)
+	a.r.mu.Unlock()
+	return nftables.New(nftables.WithTestDial(
+		netfilterFunc(func(req netlink.Message) ([]netlink.Message, error) {
+			key := netfilterKey(req)
+			msgs, err := conn.Execute(req)
+			reply := &netlinkReply{Failure: newFailure(err)}
+			for _, msg := range msgs {
+				reply.Messages = append(reply.Messages, marshalNetfilterMessage(msg))
+			}
+			a.r.mu.Lock()
+			a.rec.Netfilter[key] = reply
+			a.r.mu.Unlock()
+			return msgs, err
+		})))
+}
+
+// recordingFiles records the files, directories, and symbolic links read.
+type recordingFiles struct {
+	r     *Recorder
+	files network.Files
+	scope *fileScope
+}
+
+func (f recordingFiles) ReadFile(name string) ([]byte, error) {
+	contents, err := f.files.ReadFile(name)
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	name = path.Clean(name)
+	if err != nil {
+		f.scope.Failures["readfile "+name] = *newFailure(err)
+		return contents, err
+	}
+	f.scope.contents[name] = contents
+	return contents, nil
+}
+
+func (f recordingFiles) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := f.files.ReadDir(name)
+	recorded := make([]dirEntry, 0, len(entries))
+	for _, entry := range entries {
+		dentry := dirEntry{Name: entry.Name(), Mode: entry.Type()}
+		// Entries might have vanished in the meantime, such as fds of
+		// processes, so we then just stick to the type bits.
+		if info, err := entry.Info(); err == nil {
+			dentry.Mode = info.Mode()
+			dentry.Size = info.Size()
+			dentry.ModTime = info.ModTime()
+		}
+		recorded = append(recorded, dentry)
+	}
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	name = path.Clean(name)
+	if err != nil {
+		f.scope.Failures["readdir "+name] = *newFailure(err)
+		return entries, err
+	}
+	f.scope.Dirs[name] = recorded
+	return entries, nil
+}
+
+func (f recordingFiles) Readlink(name string) (string, error) {
+	link, err := f.files.Readlink(name)
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	name = path.Clean(name)
+	if err != nil {
+		f.scope.Failures["readlink "+name] = *newFailure(err)
+		return link, err
+	}
+	f.scope.Links[name] = link
+	return link, nil
+}
+
+func (f recordingFiles) Stat(name string) (network.FileStat, error) {
+	stat, err := f.files.Stat(name)
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	name = path.Clean(name)
+	if err != nil {
+		f.scope.Failures["stat "+name] = *newFailure(err)
+		return stat, err
+	}
+	f.scope.Stats[name] = stat
+	return stat, nil
+}
+
+func (f recordingFiles) Lstat(name string) (network.FileStat, error) {
+	stat, err := f.files.Lstat(name)
+	f.r.mu.Lock()
+	defer f.r.mu.Unlock()
+	name = path.Clean(name)
+	if err != nil {
+		f.scope.Failures["lstat "+name] = *newFailure(err)
+		return stat, err
+	}
+	f.scope.Lstats[name] = stat
+	return stat, nil
+}
+
+// Files of the host.
+
+func (r *Recorder) ReadFile(name string) ([]byte, error) {
+	return r.hostFiles().ReadFile(name)
+}
+
+func (r *Recorder) ReadDir(name string) ([]fs.DirEntry, error) {
+	return r.hostFiles().ReadDir(name)
+}
+
+func (r *Recorder) Readlink(name string) (string, error) {
+	return r.hostFiles().Readlink(name)
+}
+
+func (r *Recorder) Stat(name string) (network.FileStat, error) {
+	return r.hostFiles().Stat(name)
+}
+
+func (r *Recorder) Lstat(name string) (network.FileStat, error) {
+	return r.hostFiles().Lstat(name)
+}
+
+func (r *Recorder) hostFiles() recordingFiles {
+	return recordingFiles{r: r, files: r.src, scope: r.rec.Host}
+}
@@ -0,0 +1,407 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/google/nftables"
+	"github.com/mdlayher/netlink"
+	"github.com/thediveo/go-mntinfo"
+	"github.com/thediveo/lxkns/api/types"
+	"github.com/thediveo/lxkns/containerizer"
+	lxknsdiscover "github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Replay is a discovery source replaying the system information recorded in a
+// support bundle. The containerizer and labels passed to gostwire.Discover are
+// ignored when replaying, as the containers have already been discovered
+// during capture.
+type Replay struct {
+	rec *recording
+
+	mu     sync.Mutex
+	fds    map[int]uint64 // pseudo network namespace fds to netns inode numbers.
+	nextfd int
+}
+
+var _ gostwire.Source = (*Replay)(nil)
+
+// Open opens the support bundle with the specified file name for replay.
+func Open(name string) (*Replay, error) {
+	f, err := os.Open(name) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a support bundle for replay.
+func Read(r io.Reader) (*Replay, error) {
+	rec, err := readRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	replay := &Replay{
+		rec: rec,
+		fds: map[int]uint64{},
+	}
+	// Check early on that the namespace discovery and engines can be replayed,
+	// so we don't fail only later when trying to discover.
+	if _, err := replay.namespaces(); err != nil {
+		return nil, fmt.Errorf("invalid support bundle namespaces, reason: %w", err)
+	}
+	if _, err := replay.engines(); err != nil {
+		return nil, fmt.Errorf("invalid support bundle engines, reason: %w", err)
+	}
+	return replay, nil
+}
+
+// Manifest returns the manifest of the replayed support bundle.
+func (r *Replay) Manifest() Manifest {
+	return r.rec.Manifest
+}
+
+// Discovery returns the v1 JSON discovery result captured together with the
+// support bundle, if any.
+func (r *Replay) Discovery() []byte {
+	return r.rec.Discovery
+}
+
+// Namespaces returns a fresh copy of the recorded namespace, process, and
+// container discovery result.
+func (r *Replay) Namespaces(containerizer.Containerizer, map[string]string) *lxknsdiscover.Result {
+	result, _ := r.namespaces() // already checked when reading the bundle.
+	return result
+}
+
+func (r *Replay) namespaces() (*lxknsdiscover.Result, error) {
+	dr := types.NewDiscoveryResult()
+	if r.rec.Namespaces != nil {
+		if err := json.Unmarshal(r.rec.Namespaces, dr); err != nil {
+			return types.NewDiscoveryResult().Result(), err
+		}
+	}
+	result := dr.Result()
+	// The lxkns JSON representation doesn't link processes to their
+	// containers, so we need to do that ourselves.
+	for _, cntr := range result.Containers {
+		if proc := result.Processes[cntr.PID]; proc != nil {
+			proc.Container = cntr
+			cntr.Process = proc
+		}
+	}
+	return result, nil
+}
+
+// Engines returns a fresh copy of the recorded container engines.
+func (r *Replay) Engines(containerizer.Containerizer) []*model.ContainerEngine {
+	engines, _ := r.engines() // already checked when reading the bundle.
+	return engines
+}
+
+func (r *Replay) engines() ([]*model.ContainerEngine, error) {
+	engines := []*model.ContainerEngine{}
+	if r.rec.Engines == nil {
+		return engines, nil
+	}
+	if err := json.Unmarshal(r.rec.Engines, &engines); err != nil {
+		return []*model.ContainerEngine{}, err
+	}
+	return engines, nil
+}
+
+// Visit calls fn with access to the information recorded for the specified
+// network namespace.
+func (r *Replay) Visit(netns model.Namespace, fn func(network.NetnsAccess) error) error {
+	rec, ok := r.rec.Netns[netns.ID().Ino]
+	if !ok {
+		return fmt.Errorf("net:[%d] %w", netns.ID().Ino, ErrNotRecorded)
+	}
+	if rec.Failure != nil {
+		return rec.Failure.err()
+	}
+	return fn(&replayNetnsAccess{replayFiles: replayFiles{rec.files}, r: r, rec: rec})
+}
+
+// NetnsFd returns a pseudo fd referencing the specified network namespace for
+// use with NetnsAccess.NSID.
+func (r *Replay) NetnsFd(netns model.Namespace) (int, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fd := r.nextfd
+	r.nextfd++
+	r.fds[fd] = netns.ID().Ino
+	return fd, func() {
+		r.mu.Lock()
+		delete(r.fds, fd)
+		r.mu.Unlock()
+	}, nil
+}
+
+// MountFiles gives access to the files recorded from inside the specified
+// mount namespace.
+func (r *Replay) MountFiles(mntns model.Namespace) (network.Files, func(), error) {
+	scope, ok := r.rec.Mntns[mntns.ID().Ino]
+	if !ok {
+		return nil, nil, fmt.Errorf("mnt:[%d] %w", mntns.ID().Ino, ErrNotRecorded)
+	}
+	return replayFiles{scope}, func() {}, nil
+}
+
+// Hostname returns the recorded host name of the specified UTS namespace.
+func (r *Replay) Hostname(utsns model.Namespace) (string, error) {
+	name, ok := r.rec.System.Hostnames[utsns.ID().Ino]
+	if !ok {
+		return "", fmt.Errorf("uts:[%d] %w", utsns.ID().Ino, ErrNotRecorded)
+	}
+	return name.Name, name.Failure.err()
+}
+
+// Mounts returns the recorded mount points of the specified process.
+func (r *Replay) Mounts(pid model.PIDType) []mntinfo.Mountinfo {
+	return r.rec.System.Mounts[pid]
+}
+
+// TunTapNetns returns the recorded network namespace of a TAP/TUN netdev.
+func (r *Replay) TunTapNetns(pid model.PIDType, fd int) (species.NamespaceID, error) {
+	tt, ok := r.rec.System.TunTaps[tuntapKey(pid, fd)]
+	if !ok {
+		return species.NoneID, fmt.Errorf("TAP/TUN fd %d of process %d %w", fd, pid, ErrNotRecorded)
+	}
+	if tt.Failure != nil {
+		return species.NoneID, tt.Failure.err()
+	}
+	return species.NamespaceIDfromInode(tt.Netns), nil
+}
+
+// EngineClient returns an HTTP client replaying the recorded responses of the
+// specified container engine API.
+func (r *Replay) EngineClient(api string) *http.Client {
+	return &http.Client{Transport: &replayTransport{r: r, api: api}}
+}
+
+// replayTransport replays the recorded responses of a container engine API.
+// Requests not recorded get a 404 response.
+type replayTransport struct {
+	r   *Replay
+	api string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	exchange, ok := t.r.rec.EngineAPI[httpKey(t.api, req)]
+	if !ok {
+		body := []byte(`{"message":"` + ErrNotRecorded.Error() + `"}`)
+		return &http.Response{
+			Status:        "404 Not Found",
+			StatusCode:    http.StatusNotFound,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	if exchange.Failure != nil {
+		return nil, exchange.Failure.err()
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(exchange.Header).Clone(),
+		Body:          io.NopCloser(bytes.NewReader(exchange.Body)),
+		ContentLength: int64(len(exchange.Body)),
+		Request:       req,
+	}, nil
+}
+
+// replayNetnsAccess replays the information recorded for a network namespace.
+type replayNetnsAccess struct {
+	replayFiles
+	r   *Replay
+	rec *netnsRecording
+}
+
+func (a *replayNetnsAccess) Execute(req *nl.NetlinkRequest, sockType int, resType uint16) ([][]byte, error) {
+	reply, ok := a.rec.Netlink[netlinkKey(req, sockType, resType)]
+	if !ok {
+		return nil, fmt.Errorf("netlink request %w", ErrNotRecorded)
+	}
+	if reply.Failure != nil {
+		return nil, reply.Failure.err()
+	}
+	return reply.Messages, nil
+}
+
+func (a *replayNetnsAccess) NSID(peerfd int) (int, error) {
+	a.r.mu.Lock()
+	ino, ok := a.r.fds[peerfd]
+	a.r.mu.Unlock()
+	if !ok {
+		return -1, unix.EBADF
+	}
+	id, ok := a.rec.NSIDs[ino]
+	if !ok {
+		return -1, fmt.Errorf("NSID of net:[%d] %w", ino, ErrNotRecorded)
+	}
+	return id.NSID, id.Failure.err()
+}
+
+func (a *replayNetnsAccess) DriverInfo(name string) (*unix.EthtoolDrvinfo, error) {
+	info, ok := a.rec.DriverInfo[name]
+	if !ok {
+		return nil, fmt.Errorf("driver information of %q %w", name, ErrNotRecorded)
+	}
+	if info.Failure != nil {
+		return nil, info.Failure.err()
+	}
+	return info.Info, nil
+}
+
+// Netfilter returns a netfilter connection replaying the recorded netfilter
+// netlink conversation.
+func (a *replayNetnsAccess) Netfilter() (*nftables.Conn, error) {
+	if a.rec.NftFailure != nil {
+		return nil, a.rec.NftFailure.err()
+	}
+	return nftables.New(nftables.WithTestDial(
+		netfilterFunc(func(req netlink.Message) ([]netlink.Message, error) {
+			reply, ok := a.rec.Netfilter[netfilterKey(req)]
+			if !ok {
+				return nil, unix.ENOENT
+			}
+			if reply.Failure != nil {
+				return nil, reply.Failure.err()
+			}
+			return unmarshalNetfilterMessages(reply.Messages)
+		})))
+}
+
+// replayFiles replays the files, directories, and symbolic links recorded.
+// Files not recorded don't exist.
+type replayFiles struct {
+	scope *fileScope
+}
+
+// failed returns the recorded error for the specified file operation and path,
+// or an ENOENT path error if nothing has been recorded.
+func (f replayFiles) failed(op string, name string) error {
+	if failure, ok := f.scope.Failures[op+" "+name]; ok {
+		return failure.pathErr(op, name)
+	}
+	return &fs.PathError{Op: op, Path: name, Err: unix.ENOENT}
+}
+
+func (f replayFiles) ReadFile(name string) ([]byte, error) {
+	name = path.Clean(name)
+	if contents, ok := f.scope.contents[name]; ok {
+		return bytes.Clone(contents), nil
+	}
+	return nil, f.failed("readfile", name)
+}
+
+func (f replayFiles) ReadDir(name string) ([]fs.DirEntry, error) {
+	name = path.Clean(name)
+	recorded, ok := f.scope.Dirs[name]
+	if !ok {
+		return nil, f.failed("readdir", name)
+	}
+	entries := make([]fs.DirEntry, 0, len(recorded))
+	for _, entry := range recorded {
+		entries = append(entries, replayDirEntry{entry})
+	}
+	return entries, nil
+}
+
+func (f replayFiles) Readlink(name string) (string, error) {
+	name = path.Clean(name)
+	if link, ok := f.scope.Links[name]; ok {
+		return link, nil
+	}
+	return "", f.failed("readlink", name)
+}
+
+func (f replayFiles) Stat(name string) (network.FileStat, error) {
+	name = path.Clean(name)
+	if stat, ok := f.scope.Stats[name]; ok {
+		return stat, nil
+	}
+	return network.FileStat{}, f.failed("stat", name)
+}
+
+func (f replayFiles) Lstat(name string) (network.FileStat, error) {
+	name = path.Clean(name)
+	if stat, ok := f.scope.Lstats[name]; ok {
+		return stat, nil
+	}
+	return network.FileStat{}, f.failed("lstat", name)
+}
+
+// Files of the host.
+
+func (r *Replay) ReadFile(name string) ([]byte, error) {
+	return replayFiles{r.rec.Host}.ReadFile(name)
+}
+
+func (r *Replay) ReadDir(name string) ([]fs.DirEntry, error) {
+	return replayFiles{r.rec.Host}.ReadDir(name)
+}
+
+func (r *Replay) Readlink(name string) (string, error) {
+	return replayFiles{r.rec.Host}.Readlink(name)
+}
+
+func (r *Replay) Stat(name string) (network.FileStat, error) {
+	return replayFiles{r.rec.Host}.Stat(name)
+}
+
+func (r *Replay) Lstat(name string) (network.FileStat, error) {
+	return replayFiles{r.rec.Host}.Lstat(name)
+}
+
+// replayDirEntry is a recorded directory entry, which also is its own file
+// info.
+type replayDirEntry struct {
+	entry dirEntry
+}
+
+var (
+	_ fs.DirEntry = replayDirEntry{}
+	_ fs.FileInfo = replayDirEntry{}
+)
+
+func (e replayDirEntry) Name() string               { return e.entry.Name }
+func (e replayDirEntry) IsDir() bool                { return e.entry.Mode.IsDir() }
+func (e replayDirEntry) Type() fs.FileMode          { return e.entry.Mode.Type() }
+func (e replayDirEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e replayDirEntry) Mode() fs.FileMode          { return e.entry.Mode }
+func (e replayDirEntry) Size() int64                { return e.entry.Size }
+func (e replayDirEntry) ModTime() time.Time         { return e.entry.ModTime }
+func (e replayDirEntry) Sys() any                   { return nil }
@@ -0,0 +1,200 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/siemens/ghostwire/v2/mobydig"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client talks to the v1 REST API of a Gostwire service.
+type Client struct {
+	base   *url.URL
+	http   *http.Client
+	dialer *websocket.Dialer
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient configures the HTTP client to use for fetching discovery
+// results, instead of the http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(client *Client) {
+		client.http = c
+	}
+}
+
+// WithDialer configures the websocket dialer to use for streaming, instead of
+// the websocket.DefaultDialer.
+func WithDialer(d *websocket.Dialer) Option {
+	return func(client *Client) {
+		client.dialer = d
+	}
+}
+
+// StatusError is returned when the Gostwire service responds with a status
+// other than 200 OK.
+type StatusError struct {
+	StatusCode int    // HTTP status code.
+	Status     string // HTTP status text.
+	Message    string // (error) message in the response body, if any.
+}
+
+// Error returns the textual representation of a service response error.
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return "Gostwire service error " + e.Status
+	}
+	return "Gostwire service error " + e.Status + ": " + e.Message
+}
+
+// New returns a new Client for the Gostwire service at the specified base URL,
+// such as "http://localhost:5000".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Gostwire service URL, reason: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Gostwire service URL %q, scheme must be http or https",
+			baseURL)
+	}
+	c := &Client{
+		base:   base,
+		http:   http.DefaultClient,
+		dialer: websocket.DefaultDialer,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Discovery fetches a discovery result from the “/json” endpoint. The
+// optional query parameters restrict and tune the discovery, such as
+// "container", "netns", "skip", and "refresh".
+func (c *Client) Discovery(ctx context.Context, query url.Values) (*DiscoveryResult, error) {
+	var result DiscoveryResult
+	if err := c.get(ctx, "/json", query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CaptureTargets fetches the capture targets from the “/mobyshark” endpoint.
+func (c *Client) CaptureTargets(ctx context.Context) (*TargetDiscoveryResult, error) {
+	var result TargetDiscoveryResult
+	if err := c.get(ctx, "/mobyshark", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Dig streams the verdicts about the neighborhood services of the specified
+// Docker container from the “/mobydig” endpoint, calling fn for each verdict
+// as it arrives. Dig returns after all verdicts have been streamed, or when fn
+// returns an error, or the context gets cancelled.
+func (c *Client) Dig(ctx context.Context, target string, fn func(verdict mobydig.FQDNAddressVerdict) error) error {
+	u := c.url("/mobydig", url.Values{"target": []string{target}})
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	conn, resp, err := c.dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return statusError(resp)
+		}
+		return err
+	}
+	defer conn.Close()
+	// Unblock reading from the websocket when the context gets cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				return fmt.Errorf("digging neighborhood services failed: %s", closeErr.Text)
+			}
+			return err
+		}
+		var verdict mobydig.FQDNAddressVerdict
+		if err := json.Unmarshal(msg, &verdict); err != nil {
+			return fmt.Errorf("invalid neighborhood service verdict, reason: %w", err)
+		}
+		if err := fn(verdict); err != nil {
+			return err
+		}
+	}
+}
+
+// get fetches the JSON document from the specified API endpoint path and
+// decodes it into v.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path, query).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid %s response, reason: %w", path, err)
+	}
+	return nil
+}
+
+// url returns the URL for the specified API endpoint path and query
+// parameters, relative to the base URL of the Gostwire service.
+func (c *Client) url(path string, query url.Values) *url.URL {
+	u := *c.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// statusError returns a StatusError for the specified (unsuccessful) response.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Message:    strings.TrimSpace(string(body)),
+	}
+}
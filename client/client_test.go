@@ -0,0 +1,126 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/mobydig"
+
+	"github.com/gorilla/websocket"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+const mobyshark = `{
+	"metadata": {"creator-id": "gostwire"},
+	"containers": [
+		{"name": "pod", "type": "pod", "netns": 4026532000, "network-interfaces": ["lo", "eth0"],
+		 "pid": 42, "starttime": 666, "prefix": ""}
+	]
+}`
+
+var _ = Describe("Gostwire service client", func() {
+
+	var srv *httptest.Server
+	var query url.Values
+
+	BeforeEach(func() {
+		upgrader := websocket.Upgrader{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/json", func(w http.ResponseWriter, req *http.Request) {
+			query = req.URL.Query()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(v1result))
+		})
+		mux.HandleFunc("/mobyshark", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(mobyshark))
+		})
+		mux.HandleFunc("/mobydig", func(w http.ResponseWriter, req *http.Request) {
+			target := req.URL.Query().Get("target")
+			conn, err := upgrader.Upgrade(w, req, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if target != "app" {
+				_ = conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(4400, "Docker container \""+target+"\" not found"))
+				return
+			}
+			for _, verdict := range []string{
+				`{"fqdn":"foo.","address":"10.0.0.1","quality":"verified"}`,
+				`{"fqdn":"bar.","error":"no such host"}`,
+			} {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(verdict))
+			}
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			_, _, _ = conn.ReadMessage()
+		})
+		srv = httptest.NewServer(mux)
+		DeferCleanup(srv.Close)
+	})
+
+	It("rejects invalid service URLs", func() {
+		Expect(New("ftp://localhost")).Error().To(HaveOccurred())
+		Expect(New(":")).Error().To(HaveOccurred())
+	})
+
+	It("fetches discovery results", func(ctx context.Context) {
+		c := Successful(New(srv.URL+"/", WithHTTPClient(srv.Client())))
+		result := Successful(c.Discovery(ctx, url.Values{"container": []string{"app"}}))
+		Expect(query.Get("container")).To(Equal("app"))
+		Expect(result.ContainerByName("app").Netns.Nif("eth0").PeerNif().Name).To(Equal("veth0"))
+	})
+
+	It("fetches capture targets", func(ctx context.Context) {
+		c := Successful(New(srv.URL))
+		targets := Successful(c.CaptureTargets(ctx))
+		Expect(targets.Containers).To(ConsistOf(And(
+			HaveField("Name", "pod"),
+			HaveField("NetnsID", uint64(4026532000)),
+			HaveField("NifNames", ConsistOf("lo", "eth0")),
+		)))
+	})
+
+	It("reports service errors", func(ctx context.Context) {
+		c := Successful(New(srv.URL + "/nada"))
+		_, err := c.Discovery(ctx, nil)
+		var statusErr *StatusError
+		Expect(errors.As(err, &statusErr)).To(BeTrue())
+		Expect(statusErr.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(statusErr.Error()).To(ContainSubstring("404"))
+	})
+
+	It("streams neighborhood service verdicts", func(ctx context.Context) {
+		c := Successful(New(srv.URL))
+		verdicts := []mobydig.FQDNAddressVerdict{}
+		Expect(c.Dig(ctx, "app", func(verdict mobydig.FQDNAddressVerdict) error {
+			verdicts = append(verdicts, verdict)
+			return nil
+		})).To(Succeed())
+		Expect(verdicts).To(ConsistOf(
+			mobydig.FQDNAddressVerdict{FQDN: "foo.", Address: "10.0.0.1", Quality: "verified"},
+			mobydig.FQDNAddressVerdict{FQDN: "bar.", Err: "no such host"},
+		))
+
+		Expect(c.Dig(ctx, "nada", func(mobydig.FQDNAddressVerdict) error { return nil })).To(
+			MatchError(ContainSubstring(`"nada" not found`)))
+
+		stop := errors.New("stop")
+		Expect(c.Dig(ctx, "app", func(mobydig.FQDNAddressVerdict) error { return stop })).To(
+			MatchError(stop))
+	}, NodeTimeout(10*time.Second))
+
+})
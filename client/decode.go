@@ -0,0 +1,241 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// Load decodes a v1 JSON discovery result, as served by the “/json” endpoint,
+// from the specified reader.
+func Load(r io.Reader) (*DiscoveryResult, error) {
+	var result DiscoveryResult
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// LoadFile decodes a v1 JSON discovery result from the specified file.
+func LoadFile(name string) (*DiscoveryResult, error) {
+	f, err := os.Open(name) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// UnmarshalJSON decodes a v1 JSON discovery result and then resolves the JSON
+// document-local references into pointers.
+func (r *DiscoveryResult) UnmarshalJSON(b []byte) error {
+	type result DiscoveryResult // avoid infinite recursion
+	var res result
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+	*r = DiscoveryResult(res)
+	r.resolve()
+	return nil
+}
+
+// resolve indexes the network namespaces, containers, network interfaces, and
+// PID namespaces by their JSON document-local identifiers and then resolves
+// all references to them.
+func (r *DiscoveryResult) resolve() {
+	r.netns = map[string]*NetworkNamespace{}
+	r.containers = map[string]*Container{}
+	r.nifs = map[string]*NetworkInterface{}
+	r.pidns = map[string]*PIDNamespace{}
+	for _, netns := range r.NetworkNamespaces {
+		r.netns[netns.ID] = netns
+		for _, cntr := range netns.Containers {
+			cntr.Netns = netns
+			r.containers[cntr.ID] = cntr
+		}
+		for _, nif := range netns.NetworkInterfaces {
+			nif.Netns = netns
+			r.nifs[nif.ID] = nif
+		}
+	}
+	var indexPIDNamespaces func(parent *PIDNamespace, pidnses []*PIDNamespace)
+	indexPIDNamespaces = func(parent *PIDNamespace, pidnses []*PIDNamespace) {
+		for _, pidns := range pidnses {
+			pidns.Parent = parent
+			pidns.Containers = r.resolveContainers(pidns.ContainerIDRefs)
+			r.pidns[pidns.ID] = pidns
+			indexPIDNamespaces(pidns, pidns.Children)
+		}
+	}
+	indexPIDNamespaces(nil, r.PIDNamespaces)
+
+	for _, netns := range r.NetworkNamespaces {
+		r.resolveNetns(netns)
+	}
+}
+
+// resolveNetns resolves the references of the specified network namespace's
+// elements.
+func (r *DiscoveryResult) resolveNetns(netns *NetworkNamespace) {
+	groups := map[string]*ContainerGroup{}
+	for _, group := range netns.ContainerGroups {
+		group.Containers = r.resolveContainers(group.ContainerIDs)
+		groups[group.ID] = group
+	}
+	for _, cntr := range netns.Containers {
+		cntr.Group = groups[cntr.GroupID]
+		cntr.PIDNamespace = r.pidns[cntr.PIDNSRef]
+	}
+	for _, nif := range netns.NetworkInterfaces {
+		r.resolveNif(nif)
+	}
+	for _, routes := range [][]Route{netns.Routes.IPv4, netns.Routes.IPv6} {
+		for idx := range routes {
+			routes[idx].Nif = r.nifs[routes[idx].NifRef]
+		}
+	}
+	for _, ports := range [][]Port{netns.TransportPorts.IPv4, netns.TransportPorts.IPv6} {
+		for idx := range ports {
+			r.resolvePort(&ports[idx])
+		}
+	}
+	for _, fwdports := range [][]ForwardedPort{netns.ForwardedPorts.IPv4, netns.ForwardedPorts.IPv6} {
+		for idx := range fwdports {
+			r.resolveOwners(fwdports[idx].Owners)
+			fwdports[idx].Nifs = r.resolveNifs(fwdports[idx].NifRefs)
+		}
+	}
+	if mptcp := netns.MPTCP; mptcp != nil {
+		for idx := range mptcp.Endpoints {
+			mptcp.Endpoints[idx].Nif = r.nifs[mptcp.Endpoints[idx].NifRef]
+		}
+		for _, conns := range [][]MPTCPConnection{mptcp.Connections.IPv4, mptcp.Connections.IPv6} {
+			for idx := range conns {
+				r.resolvePort(&conns[idx].Port)
+				for sidx := range conns[idx].Subflows {
+					r.resolvePort(&conns[idx].Subflows[sidx])
+				}
+			}
+		}
+	}
+	if mcast := netns.McastRouting; mcast != nil {
+		for _, routing := range []*MulticastRouting{mcast.IPv4, mcast.IPv6} {
+			if routing == nil {
+				continue
+			}
+			for idx := range routing.VIFs {
+				r.resolveNifRef(routing.VIFs[idx].Nif)
+			}
+		}
+	}
+	for idx := range netns.PacketSockets {
+		sock := &netns.PacketSockets[idx]
+		sock.Nif = r.nifs[sock.NifRef]
+		r.resolveOwners(sock.Owners)
+	}
+	for idx := range netns.UnixSockets {
+		sock := &netns.UnixSockets[idx]
+		r.resolveOwners(sock.Owners)
+		if peer := sock.Peer; peer != nil {
+			peer.Netns = r.netns[peer.NetnsRef]
+			r.resolveOwners(peer.Owners)
+		}
+	}
+	for idx := range netns.BridgingProcesses {
+		bridge := &netns.BridgingProcesses[idx]
+		bridge.Container = r.containers[bridge.ContainerRef]
+		bridge.ForeignNetns = make([]*NetworkNamespace, 0, len(bridge.ForeignNetnsRefs))
+		for _, ref := range bridge.ForeignNetnsRefs {
+			if foreign := r.netns[ref]; foreign != nil {
+				bridge.ForeignNetns = append(bridge.ForeignNetns, foreign)
+			}
+		}
+	}
+}
+
+// resolveNif resolves the references of the specified network interface to
+// other network interfaces and to processes.
+func (r *DiscoveryResult) resolveNif(nif *NetworkInterface) {
+	r.resolveNifRef(nif.Master)
+	r.resolveNifRef(nif.MacvlanMaster)
+	r.resolveNifRef(nif.PF)
+	for _, ref := range nif.Macvlans {
+		r.resolveNifRef(ref)
+	}
+	for _, ref := range nif.Slaves {
+		r.resolveNifRef(ref)
+	}
+	if peer := nif.Peer; peer != nil {
+		peer.Nif = r.nifs[peer.ID]
+	}
+	if vxlan := nif.Vxlan; vxlan != nil {
+		vxlan.Underlay = r.nifs[vxlan.UnderlayID]
+	}
+	if tuntap := nif.TunTap; tuntap != nil {
+		r.resolveOwners(tuntap.Processors)
+	}
+	if mcast := nif.Multicast; mcast != nil {
+		for _, groups := range [][]MulticastGroup{mcast.IPv4, mcast.IPv6} {
+			for idx := range groups {
+				r.resolveOwners(groups[idx].Owners)
+			}
+		}
+	}
+	if brmcast := nif.BridgeMcast; brmcast != nil {
+		for idx := range brmcast.MDB {
+			r.resolveNifRef(brmcast.MDB[idx].Port)
+		}
+	}
+}
+
+// resolvePort resolves the references of the specified transport port to
+// network interfaces and processes.
+func (r *DiscoveryResult) resolvePort(port *Port) {
+	r.resolveOwners(port.Owners)
+	port.Nifs = r.resolveNifs(port.NifRefs)
+}
+
+// resolveNifRef resolves the specified network interface reference, if any.
+func (r *DiscoveryResult) resolveNifRef(ref *NifRef) {
+	if ref == nil {
+		return
+	}
+	ref.Nif = r.nifs[ref.ID]
+}
+
+// resolveNifs returns the network interfaces for the specified references,
+// skipping unknown references.
+func (r *DiscoveryResult) resolveNifs(ids []string) Interfaces {
+	nifs := make(Interfaces, 0, len(ids))
+	for _, id := range ids {
+		if nif := r.nifs[id]; nif != nil {
+			nifs = append(nifs, nif)
+		}
+	}
+	return nifs
+}
+
+// resolveContainers returns the containers for the specified references,
+// skipping unknown references.
+func (r *DiscoveryResult) resolveContainers(ids []string) []*Container {
+	cntrs := make([]*Container, 0, len(ids))
+	for _, id := range ids {
+		if cntr := r.containers[id]; cntr != nil {
+			cntrs = append(cntrs, cntr)
+		}
+	}
+	return cntrs
+}
+
+// resolveOwners resolves the container and (foreign) network namespace
+// references of the specified owners in-place.
+func (r *DiscoveryResult) resolveOwners(owners []Owner) {
+	for idx := range owners {
+		owners[idx].Container = r.containers[owners[idx].ContainerRef]
+		owners[idx].Netns = r.netns[owners[idx].NetnsRef]
+	}
+}
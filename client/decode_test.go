@@ -0,0 +1,195 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/turtlefinder"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// v1result is a (heavily) reduced v1 JSON discovery result with the host
+// network namespace and a pod network namespace, connected by a VETH pair with
+// the host end attached to a bridge.
+const v1result = `{
+	"metadata": {"creation-timestamp": "2023-06-01T12:00:00Z"},
+	"network-namespaces": [
+		{
+			"container-groups": [],
+			"containers": [
+				{"id": "cont-1", "name": "systemd(1)", "type": "proc", "pid": 1, "status": "running",
+				 "pidns-idref": "pidns-4026531836", "dns": {"etc-hosts": []}}
+			],
+			"id": "netns-4026531840",
+			"netnsid": 4026531840,
+			"network-interfaces": [
+				{"id": "nif-4026531840-1", "kind": "", "name": "lo", "index": 1},
+				{"id": "nif-4026531840-2", "kind": "bridge", "name": "br0", "index": 2,
+				 "slaves": [{"idref": "nif-4026531840-3", "index": 3, "name": "veth0"}]},
+				{"id": "nif-4026531840-3", "kind": "veth", "name": "veth0", "index": 3,
+				 "master": {"idref": "nif-4026531840-2", "index": 2, "name": "br0"},
+				 "peer": {"peer-idref": "nif-4026532000-2", "peer-index": 2, "peer-name": "eth0"}}
+			],
+			"routes": {
+				"ipv4": [
+					{"type": "unicast", "destination": "10.0.0.0", "destination-prefixlen": 24,
+					 "network-interface-idref": "nif-4026531840-2", "table": 254}
+				],
+				"ipv6": []
+			},
+			"transport-ports": {
+				"ipv4": [
+					{"protocol": "tcp", "local-address": "0.0.0.0", "local-port": 22, "macrostate": "listening",
+					 "owners": [{"pid": 1, "cmdline": "systemd", "container-idref": "cont-1"}],
+					 "network-interface-idrefs": ["nif-4026531840-1", "nif-4026531840-2"]}
+				],
+				"ipv6": []
+			},
+			"forwarded-ports": {"ipv4": [], "ipv6": []},
+			"bridging-processes": [
+				{"pid": 1, "cmdline": "systemd", "container-idref": "cont-1",
+				 "foreign-netns-idrefs": ["netns-4026532000"]}
+			]
+		},
+		{
+			"container-groups": [
+				{"id": "group-0", "name": "default/pod", "type": "pod", "type-text": "Pod",
+				 "container-idrefs": ["cont-42", "cont-43"]}
+			],
+			"containers": [
+				{"id": "cont-42", "name": "pause", "type": "containerd", "pid": 42, "status": "running",
+				 "group": "group-0", "pidns-idref": "pidns-4026532100", "dns": {"etc-hosts": []}},
+				{"id": "cont-43", "name": "app", "type": "containerd", "pid": 43, "status": "paused",
+				 "group": "group-0", "pidns-idref": "pidns-4026532100", "dns": {"etc-hosts": []}}
+			],
+			"id": "netns-4026532000",
+			"netnsid": 4026532000,
+			"network-interfaces": [
+				{"id": "nif-4026532000-1", "kind": "", "name": "lo", "index": 1},
+				{"id": "nif-4026532000-2", "kind": "veth", "name": "eth0", "index": 2,
+				 "peer": {"peer-idref": "nif-4026531840-3", "peer-index": 3, "peer-name": "veth0"}}
+			],
+			"routes": {"ipv4": [], "ipv6": []},
+			"transport-ports": {"ipv4": [], "ipv6": []},
+			"forwarded-ports": {"ipv4": [], "ipv6": []}
+		}
+	],
+	"pid-namespaces": [
+		{"id": "pidns-4026531836", "pidnsid": 4026531836, "container-idrefs": [],
+		 "children": [
+			{"id": "pidns-4026532100", "pidnsid": 4026532100, "container-idrefs": ["cont-42", "cont-43"],
+			 "children": []}
+		 ]}
+	]
+}`
+
+var _ = Describe("decoding v1 JSON discovery results", func() {
+
+	It("resolves references", func() {
+		result := Successful(Load(strings.NewReader(v1result)))
+		Expect(result.NetworkNamespaces).To(HaveLen(2))
+
+		hostnetns := result.NetnsByID(4026531840)
+		Expect(hostnetns).NotTo(BeNil())
+		Expect(result.Netns("netns-4026531840")).To(BeIdenticalTo(hostnetns))
+		Expect(hostnetns.DisplayName()).To(Equal("⚙️  systemd(1)"))
+		podnetns := result.Netns("netns-4026532000")
+		Expect(podnetns).NotTo(BeNil())
+		Expect(podnetns.DisplayName()).To(Equal("📦 pause, …"))
+		Expect(result.NetnsByID(666)).To(BeNil())
+
+		By("resolving network interface references")
+		br0 := hostnetns.Nif("br0")
+		veth0 := hostnetns.Nif("veth0")
+		eth0 := podnetns.Nif("eth0")
+		Expect(br0).NotTo(BeNil())
+		Expect(br0.Netns).To(BeIdenticalTo(hostnetns))
+		Expect(br0.SlaveNifs()).To(ConsistOf(BeIdenticalTo(veth0)))
+		Expect(veth0.MasterNif()).To(BeIdenticalTo(br0))
+		Expect(veth0.PeerNif()).To(BeIdenticalTo(eth0))
+		Expect(eth0.PeerNif()).To(BeIdenticalTo(veth0))
+		Expect(eth0.Netns).To(BeIdenticalTo(podnetns))
+		Expect(eth0.MasterNif()).To(BeNil())
+		Expect(result.Nif("nif-4026532000-2")).To(BeIdenticalTo(eth0))
+		Expect(hostnetns.NetworkInterfaces.OfKind("veth")).To(ConsistOf(BeIdenticalTo(veth0)))
+		Expect(hostnetns.NifsString()).To(Equal("⚙️  systemd(1): lo(1), br0(2), veth0(3)"))
+
+		By("resolving containers, groups, and PID namespaces")
+		systemd := result.ContainerByPID(1)
+		Expect(systemd).NotTo(BeNil())
+		Expect(systemd.IsContainer()).To(BeFalse())
+		Expect(systemd.Netns).To(BeIdenticalTo(hostnetns))
+		Expect(systemd.Group).To(BeNil())
+		Expect(result.ContainerByName("systemd(1)")).To(BeNil())
+		app := result.ContainerByName("app")
+		Expect(app).NotTo(BeNil())
+		Expect(result.Container("cont-43")).To(BeIdenticalTo(app))
+		Expect(result.ByContainer(app)).To(BeIdenticalTo(podnetns))
+		Expect(app.Group).NotTo(BeNil())
+		Expect(app.Group.Name).To(Equal("default/pod"))
+		Expect(app.Group.Containers).To(ConsistOf(
+			BeIdenticalTo(result.ContainerByName("pause")), BeIdenticalTo(app)))
+		Expect(app.PIDNamespace).NotTo(BeNil())
+		Expect(app.PIDNamespace.Parent).To(BeIdenticalTo(result.PIDNamespace("pidns-4026531836")))
+		Expect(app.PIDNamespace.Containers).To(ContainElement(BeIdenticalTo(app)))
+		Expect(systemd.PIDNamespace.Parent).To(BeNil())
+
+		By("resolving routes, ports, and owners")
+		Expect(hostnetns.Routes.IPv4[0].Nif).To(BeIdenticalTo(br0))
+		port := hostnetns.TransportPorts.IPv4[0]
+		Expect(port.Nifs).To(ConsistOf(BeIdenticalTo(hostnetns.Nif("lo")), BeIdenticalTo(br0)))
+		Expect(port.Owners[0].Container).To(BeIdenticalTo(systemd))
+		Expect(hostnetns.BridgingProcesses[0].Container).To(BeIdenticalTo(systemd))
+		Expect(hostnetns.BridgingProcesses[0].ForeignNetns).To(ConsistOf(BeIdenticalTo(podnetns)))
+	})
+
+	It("loads from files", func() {
+		name := filepath.Join(GinkgoT().TempDir(), "discovery.json")
+		Expect(os.WriteFile(name, []byte(v1result), 0644)).To(Succeed())
+		result := Successful(LoadFile(name))
+		Expect(result.NetworkNamespaces).To(HaveLen(2))
+
+		Expect(LoadFile(name + ".nada")).Error().To(HaveOccurred())
+		Expect(Load(strings.NewReader("{"))).Error().To(HaveOccurred())
+	})
+
+	It("round-trips a live discovery result", NodeTimeout(30*time.Second), func(ctx context.Context) {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+		v1 := apiv1.NewDiscoveryResult(gostwire.Discover(ctx, cizer, nil))
+		j := Successful(json.Marshal(&v1))
+
+		var result DiscoveryResult
+		Expect(json.Unmarshal(j, &result)).To(Succeed())
+		Expect(result.NetworkNamespaces).NotTo(BeEmpty())
+		for _, netns := range result.NetworkNamespaces {
+			for _, cntr := range netns.Containers {
+				Expect(cntr.Netns).To(BeIdenticalTo(netns))
+			}
+			for _, nif := range netns.NetworkInterfaces {
+				Expect(nif.Netns).To(BeIdenticalTo(netns))
+				if nif.Master != nil {
+					Expect(nif.MasterNif()).NotTo(BeNil(), "unresolved master of %s", nif.ID)
+				}
+			}
+		}
+		Expect(json.Marshal(&result)).To(MatchJSON(j))
+	})
+
+})
@@ -0,0 +1,28 @@
+/*
+Package client is a Go client for the Ghostwire v1 REST API of a Gostwire
+service. It fetches the discovery results from the “/json” endpoint, the
+capture targets from the “/mobyshark” endpoint, and streams the verdicts of
+neighborhood service digging from the “/mobydig” endpoint.
+
+Different from the api/v1 package, which only marshals discovery results into
+JSON, this package decodes v1 JSON discovery results into a navigable
+information model: the JSON document-local references to network interfaces,
+VETH peers, containers, pod groups, network namespaces and PID namespaces get
+resolved into pointers.
+
+	c, err := client.New("http://localhost:5000")
+	result, err := c.Discovery(ctx)
+	for _, netns := range result.NetworkNamespaces {
+		for _, nif := range netns.NetworkInterfaces.OfKind("veth") {
+			if peer := nif.PeerNif(); peer != nil {
+				fmt.Printf("%s <-> %s in %s\n", nif.Name, peer.Name, peer.Netns.DisplayName())
+			}
+		}
+	}
+
+Discovery results previously saved from the “/json” endpoint can be decoded
+using [Load] and [LoadFile] without any service or live host at all. As the
+decoded information model marshals back into the same v1 JSON, it can be
+passed on unchanged, such as into the diff package.
+*/
+package client
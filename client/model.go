@@ -0,0 +1,169 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// DiscoveryResult is a decoded Ghostwire v1 discovery result, consisting of
+// some metadata, the discovered network namespaces and PID namespaces.
+type DiscoveryResult struct {
+	Metadata          map[string]interface{} `json:"metadata"`
+	NetworkNamespaces []*NetworkNamespace    `json:"network-namespaces"`
+	PIDNamespaces     []*PIDNamespace        `json:"pid-namespaces"`
+
+	netns      map[string]*NetworkNamespace // by "netns-..." identifiers.
+	containers map[string]*Container        // by "cont-..." identifiers.
+	nifs       map[string]*NetworkInterface // by "nif-..." identifiers.
+	pidns      map[string]*PIDNamespace     // by "pidns-..." identifiers.
+}
+
+// IPvX is a pair of IPv4 and IPv6 specific lists of things, such as routes
+// and transport ports.
+type IPvX[T any] struct {
+	IPv4 []T `json:"ipv4"`
+	IPv6 []T `json:"ipv6"`
+}
+
+// NetworkNamespace describes the discovery details of a single network
+// namespace.
+type NetworkNamespace struct {
+	ContainerGroups   []*ContainerGroup     `json:"container-groups"`
+	Containers        []*Container          `json:"containers"`
+	ID                string                `json:"id"`
+	NetnsID           uint64                `json:"netnsid"`
+	NetworkInterfaces Interfaces            `json:"network-interfaces"`
+	Routes            IPvX[Route]           `json:"routes"`
+	TransportPorts    IPvX[Port]            `json:"transport-ports"`
+	MPTCP             *MPTCP                `json:"mptcp,omitempty"`
+	ForwardedPorts    IPvX[ForwardedPort]   `json:"forwarded-ports"`
+	McastRouting      *IPvXMulticastRouting `json:"multicast-routing,omitempty"`
+	Sysctls           network.Sysctls       `json:"sysctls,omitempty"`
+	PacketSockets     []PacketSocket        `json:"packet-sockets,omitempty"`
+	UnixSockets       []UnixSocket          `json:"unix-sockets,omitempty"`
+	BridgingProcesses []BridgingProcess     `json:"bridging-processes,omitempty"`
+}
+
+// ContainerGroup is a Kubernetes pod of containers sharing the same network
+// namespace; the Ghostwire v1 API doesn't know any other container groups.
+type ContainerGroup struct {
+	ContainerIDs []string `json:"container-idrefs"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	TypeText     string   `json:"type-text"`
+
+	Containers []*Container `json:"-"` // resolved container-idrefs.
+}
+
+// Container is either a container, a stand-alone process, or a pseudo
+// container for a bind-mounted network namespace without any processes.
+type Container struct {
+	Cmdline         string           `json:"cmdline"`
+	DNS             DNS              `json:"dns"`
+	GroupID         string           `json:"group"`
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`
+	Prefix          string           `json:"prefix"`
+	Labels          model.Labels     `json:"labels,omitempty"`
+	API             string           `json:"api-path"`
+	PID             model.PIDType    `json:"pid"`
+	PIDNS           uint64           `json:"pidns"`
+	PIDNSRef        string           `json:"pidns-idref"`
+	CapBnd          string           `json:"capbnd,omitempty"`
+	Status          string           `json:"status"`
+	Type            string           `json:"type"`
+	TypeText        string           `json:"type-text"`
+	Affinity        model.CPUList    `json:"affinity,omitempty"`
+	Policy          int              `json:"policy,omitempty"`
+	Priority        int              `json:"priority,omitempty"`
+	Nice            int              `json:"nice,omitempty"`
+	EngineAPIMounts []EngineAPIMount `json:"engine-api-mounts,omitempty"`
+
+	Netns        *NetworkNamespace `json:"-"` // network namespace this container is attached to.
+	Group        *ContainerGroup   `json:"-"` // resolved group, if any.
+	PIDNamespace *PIDNamespace     `json:"-"` // resolved pidns-idref, if any.
+}
+
+// DNS is the DNS and name resolution configuration of a container.
+type DNS struct {
+	Hostname      string    `json:"uts-hostname"`
+	EtcHostname   string    `json:"etc-hostname"`
+	EtcDomainname string    `json:"domainname"`
+	EtcHosts      []NamedIP `json:"etc-hosts"`
+	Nameservers   []net.IP  `json:"nameservers"`
+	Searchlist    []string  `json:"searchlist"`
+}
+
+// NamedIP is a name to IP address mapping from /etc/hosts.
+type NamedIP struct {
+	Name string `json:"name"`
+	IP   net.IP `json:"address"`
+}
+
+// EngineAPI describes a container engine API endpoint.
+type EngineAPI struct {
+	Type string        `json:"type"`
+	API  string        `json:"api-path"`
+	PID  model.PIDType `json:"pid"`
+}
+
+// EngineAPIMount describes a container engine API socket mounted into a
+// container.
+type EngineAPIMount struct {
+	EngineAPI
+	Path string `json:"path"`
+}
+
+// PIDNamespace is a PID namespace with its child PID namespaces and the
+// containers whose initial processes are in it.
+type PIDNamespace struct {
+	ID              string          `json:"id"`
+	PIDNsID         uint64          `json:"pidnsid"`
+	Children        []*PIDNamespace `json:"children"`
+	ContainerIDRefs []string        `json:"container-idrefs"`
+
+	Parent     *PIDNamespace `json:"-"` // parent PID namespace, nil for the root.
+	Containers []*Container  `json:"-"` // resolved container-idrefs.
+}
+
+// Owner is a process using a socket, multicast group, or TUN/TAP network
+// interface.
+type Owner struct {
+	PID          model.PIDType `json:"pid"`
+	Cmdline      string        `json:"cmdline"`
+	ContainerRef string        `json:"container-idref,omitempty"`
+	Foreign      bool          `json:"foreign,omitempty"`
+	NetnsRef     string        `json:"netns-idref,omitempty"`
+	UID          *uint32       `json:"uid,omitempty"`
+	GID          *uint32       `json:"gid,omitempty"`
+	UserName     string        `json:"username,omitempty"`
+	GroupName    string        `json:"groupname,omitempty"`
+	Executable   string        `json:"exe,omitempty"`
+	Cgroup       string        `json:"cgroup,omitempty"`
+	SystemdUnit  string        `json:"systemd-unit,omitempty"`
+	SystemdSlice string        `json:"systemd-slice,omitempty"`
+
+	Container *Container        `json:"-"` // resolved container-idref, if any.
+	Netns     *NetworkNamespace `json:"-"` // resolved netns-idref of a foreign process, if any.
+}
+
+// MarshalJSON marshals the DNS configuration the same way as the Ghostwire v1
+// API does: pseudo containers for bind-mounted network namespaces lack any DNS
+// configuration, including the /etc/hosts mappings, which then are null
+// instead of an empty list.
+func (d DNS) MarshalJSON() ([]byte, error) {
+	type dns DNS // avoid infinite recursion
+	if d.EtcHosts == nil {
+		return []byte(`{"etc-hosts":null}`), nil
+	}
+	return json.Marshal(dns(d))
+}
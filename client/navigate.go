@@ -0,0 +1,122 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// Netns returns the network namespace with the specified JSON document-local
+// identifier, such as "netns-4026531840", or nil.
+func (r *DiscoveryResult) Netns(id string) *NetworkNamespace {
+	return r.netns[id]
+}
+
+// NetnsByID returns the network namespace with the specified inode number, or
+// nil.
+func (r *DiscoveryResult) NetnsByID(netnsid uint64) *NetworkNamespace {
+	for _, netns := range r.NetworkNamespaces {
+		if netns.NetnsID == netnsid {
+			return netns
+		}
+	}
+	return nil
+}
+
+// Container returns the container or stand-alone process with the specified
+// JSON document-local identifier, such as "cont-42", or nil.
+func (r *DiscoveryResult) Container(id string) *Container {
+	return r.containers[id]
+}
+
+// ContainerByName returns the first container with the specified name, or
+// nil. Stand-alone processes and bind-mounted network namespaces never match.
+func (r *DiscoveryResult) ContainerByName(name string) *Container {
+	for _, netns := range r.NetworkNamespaces {
+		for _, cntr := range netns.Containers {
+			if cntr.Name == name && cntr.IsContainer() {
+				return cntr
+			}
+		}
+	}
+	return nil
+}
+
+// ContainerByPID returns the container or stand-alone process with the
+// specified (initial) PID, or nil.
+func (r *DiscoveryResult) ContainerByPID(pid model.PIDType) *Container {
+	return r.containers["cont-"+strconv.FormatUint(uint64(pid), 10)]
+}
+
+// Nif returns the network interface with the specified JSON document-local
+// identifier, such as "nif-4026531840-1", or nil.
+func (r *DiscoveryResult) Nif(id string) *NetworkInterface {
+	return r.nifs[id]
+}
+
+// PIDNamespace returns the PID namespace with the specified JSON
+// document-local identifier, such as "pidns-4026531836", or nil.
+func (r *DiscoveryResult) PIDNamespace(id string) *PIDNamespace {
+	return r.pidns[id]
+}
+
+// ByContainer returns the network namespace the specified container is
+// attached to, or nil.
+func (r *DiscoveryResult) ByContainer(cntr *Container) *NetworkNamespace {
+	if cntr == nil {
+		return nil
+	}
+	return cntr.Netns
+}
+
+// IsContainer returns true if this is a container, and false if this is a
+// stand-alone process or a bind-mounted network namespace.
+func (c *Container) IsContainer() bool {
+	return c.Type != "proc" && c.Type != "bindmount"
+}
+
+// DisplayName returns a "simplified" name for "simple" display use cases,
+// where it is desirable to identify network namespaces by the names of their
+// containers and stand-alone processes. In case of multiple containers and
+// processes, the display name will be the name of the first one, which is the
+// one with the lowest PID.
+func (n *NetworkNamespace) DisplayName() string {
+	if len(n.Containers) == 0 {
+		return n.ID
+	}
+	var name string
+	switch cntr := n.Containers[0]; cntr.Type {
+	case "bindmount":
+		return cntr.Name
+	case "proc":
+		name = "⚙️  " + cntr.Name
+	default:
+		name = "📦 " + cntr.Name
+	}
+	if len(n.Containers) > 1 {
+		return name + ", …"
+	}
+	return name
+}
+
+// NifsString returns the display name of this network namespace together with
+// the names of its network interface (and interface indices).
+func (n *NetworkNamespace) NifsString() string {
+	names := make([]string, 0, len(n.NetworkInterfaces))
+	for _, nif := range n.NetworkInterfaces {
+		names = append(names, fmt.Sprintf("%s(%d)", nif.Name, nif.Index))
+	}
+	return n.DisplayName() + ": " + strings.Join(names, ", ")
+}
+
+// Nif returns the network interface with the specified name in this network
+// namespace, or nil.
+func (n *NetworkNamespace) Nif(name string) *NetworkInterface {
+	return n.NetworkInterfaces.ByName(name)
+}
@@ -0,0 +1,240 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"net"
+	"sort"
+
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// NetworkInterface is a network interface in a particular network namespace.
+type NetworkInterface struct {
+	ID            string                `json:"id"`
+	Kind          string                `json:"kind"`
+	Name          string                `json:"name"`
+	Alias         string                `json:"alias,omitempty"`
+	Index         int                   `json:"index"`
+	Addresses     Addresses             `json:"addresses"`
+	Operstate     string                `json:"operstate"`
+	Physical      bool                  `json:"physical"`
+	DriverInfo    network.NifDriverInfo `json:"driverinfo"`
+	Promiscuous   bool                  `json:"promisc"`
+	Labels        model.Labels          `json:"labels,omitempty"`
+	Master        *NifRef               `json:"master,omitempty"`
+	MacvlanMaster *NifRef               `json:"macvlan,omitempty"`
+	Macvlans      []*NifRef             `json:"macvlans,omitempty"`
+	Slaves        []*NifRef             `json:"slaves,omitempty"`
+	Peer          *PeerNifRef           `json:"peer,omitempty"`
+	TunTap        *TunTapConfig         `json:"tuntap,omitempty"`
+	Vxlan         *VxlanConfig          `json:"vxlan,omitempty"`
+	Vlan          *VlanConfig           `json:"vlan,omitempty"`
+	SRIOVRole     network.SRIOVRole     `json:"sr-iov-role,omitempty"`
+	PF            *NifRef               `json:"pf,omitempty"`
+	Multicast     *MulticastGroups      `json:"multicast,omitempty"`
+	BridgeMcast   *BridgeMulticast      `json:"bridge-multicast,omitempty"`
+	Sysctls       network.Sysctls       `json:"sysctls,omitempty"`
+
+	Netns *NetworkNamespace `json:"-"` // network namespace this interface is in.
+}
+
+// Addresses are the MAC and IP addresses of a network interface.
+type Addresses struct {
+	MAC  string            `json:"mac"`
+	IPv4 []network.Address `json:"ipv4"`
+	IPv6 []network.Address `json:"ipv6"`
+}
+
+// NifRef references a network interface, which might be located in a
+// different network namespace.
+type NifRef struct {
+	ID    string `json:"idref"`
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+
+	Nif *NetworkInterface `json:"-"` // resolved idref.
+}
+
+// PeerNifRef references the peer network interface of a VETH pair.
+type PeerNifRef struct {
+	ID    string `json:"peer-idref"`
+	Index int    `json:"peer-index"`
+	Name  string `json:"peer-name"`
+
+	Nif *NetworkInterface `json:"-"` // resolved peer-idref.
+}
+
+// VxlanConfig carries VXLAN-specific network interface information.
+type VxlanConfig struct {
+	UnderlayID      string          `json:"idref"`
+	VID             uint32          `json:"vid"`
+	ArpProxy        bool            `json:"arp-proxy"`
+	Source          *SourceIP       `json:"source,omitempty"`
+	SourcePortRange SourcePortRange `json:"source-portrange,omitempty"`
+	Remote          *RemoteIP       `json:"remote,omitempty"`
+	RemotePort      uint16          `json:"remote-port"`
+
+	Underlay *NetworkInterface `json:"-"` // resolved idref of the underlay interface, if any.
+}
+
+// SourceIP is the optional source IP address of a VXLAN.
+type SourceIP struct {
+	IPv4 net.IP `json:"source_ipv4,omitempty"`
+	IPv6 net.IP `json:"source_ipv6,omitempty"`
+}
+
+// SourcePortRange is the source port range of a VXLAN.
+type SourcePortRange struct {
+	Low  uint16 `json:"low"`
+	High uint16 `json:"high"`
+}
+
+// RemoteIP is the optional remote (group) IP address of a VXLAN.
+type RemoteIP struct {
+	IPv4 net.IP `json:"remote_ipv4,omitempty"`
+	IPv6 net.IP `json:"remote_ipv6,omitempty"`
+}
+
+// TunTapConfig carries TUN/TAP-specific network interface information, with
+// the processes serving it.
+type TunTapConfig struct {
+	Mode       string  `json:"mode"`
+	Processors []Owner `json:"processors"`
+}
+
+// VlanConfig carries VLAN-specific network interface information.
+type VlanConfig struct {
+	VID          uint16 `json:"vid"`
+	VlanProtocol int    `json:"vlan-protocol"`
+}
+
+// MulticastGroups are the multicast groups joined on a network interface.
+type MulticastGroups struct {
+	IPv4 []MulticastGroup `json:"ipv4"`
+	IPv6 []MulticastGroup `json:"ipv6"`
+	L2   []string         `json:"l2"`
+}
+
+// MulticastGroup is a multicast group joined on a network interface, together
+// with the processes that joined it.
+type MulticastGroup struct {
+	Group  net.IP  `json:"group"`
+	Users  int     `json:"users"`
+	Owners []Owner `json:"owners"`
+}
+
+// BridgeMulticast is the multicast configuration and database of a bridge.
+type BridgeMulticast struct {
+	Snooping    bool                `json:"snooping"`
+	Querier     bool                `json:"querier"`
+	IGMPVersion uint8               `json:"igmp-version,omitempty"`
+	MLDVersion  uint8               `json:"mld-version,omitempty"`
+	MDB         []BridgeMulticastDB `json:"mdb"`
+}
+
+// BridgeMulticastDB is an entry of a bridge's multicast database.
+type BridgeMulticastDB struct {
+	Port      *NifRef `json:"port,omitempty"`
+	Group     string  `json:"group"`
+	VID       uint16  `json:"vid,omitempty"`
+	Permanent bool    `json:"permanent"`
+}
+
+// Interfaces is a list of network interfaces.
+type Interfaces []*NetworkInterface
+
+// Sort sorts the list of network interfaces in-place by their names, with "lo"
+// always coming first.
+func (i Interfaces) Sort() {
+	sort.SliceStable(i, func(a, b int) bool {
+		nameA := i[a].Name
+		nameB := i[b].Name
+		if alo := nameA == "lo"; alo || nameB == "lo" {
+			return alo
+		}
+		return nameA < nameB
+	})
+}
+
+// OfKind returns only the network interfaces of the specified kind.
+func (i Interfaces) OfKind(kind string) Interfaces {
+	kindifs := make(Interfaces, 0, len(i))
+	for _, nif := range i {
+		if nif.Kind != kind {
+			continue
+		}
+		kindifs = append(kindifs, nif)
+	}
+	return kindifs
+}
+
+// ByName returns the network interface with the specified name, or nil.
+func (i Interfaces) ByName(name string) *NetworkInterface {
+	for _, nif := range i {
+		if nif.Name == name {
+			return nif
+		}
+	}
+	return nil
+}
+
+// MasterNif returns the master network interface, such as a bridge, or nil.
+func (n *NetworkInterface) MasterNif() *NetworkInterface {
+	return n.Master.nif()
+}
+
+// MacvlanMasterNif returns the master network interface of a MACVLAN, or nil.
+func (n *NetworkInterface) MacvlanMasterNif() *NetworkInterface {
+	return n.MacvlanMaster.nif()
+}
+
+// PeerNif returns the peer network interface of a VETH, or nil.
+func (n *NetworkInterface) PeerNif() *NetworkInterface {
+	if n.Peer == nil {
+		return nil
+	}
+	return n.Peer.Nif
+}
+
+// PFNif returns the physical function network interface of an SR-IOV virtual
+// function, or nil.
+func (n *NetworkInterface) PFNif() *NetworkInterface {
+	return n.PF.nif()
+}
+
+// SlaveNifs returns the slave network interfaces, such as the ports of a
+// bridge.
+func (n *NetworkInterface) SlaveNifs() Interfaces {
+	return nifs(n.Slaves)
+}
+
+// MacvlanNifs returns the MACVLAN network interfaces using this network
+// interface as their master.
+func (n *NetworkInterface) MacvlanNifs() Interfaces {
+	return nifs(n.Macvlans)
+}
+
+// nif returns the referenced network interface, or nil.
+func (r *NifRef) nif() *NetworkInterface {
+	if r == nil {
+		return nil
+	}
+	return r.Nif
+}
+
+// nifs returns the resolved network interfaces of the specified references,
+// skipping unresolved references.
+func nifs(refs []*NifRef) Interfaces {
+	nifs := make(Interfaces, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Nif != nil {
+			nifs = append(nifs, ref.Nif)
+		}
+	}
+	return nifs
+}
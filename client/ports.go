@@ -0,0 +1,254 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"net"
+
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// Route is a single IPv4 or IPv6 route.
+type Route struct {
+	Type           string                `json:"type"`
+	Family         network.AddressFamily `json:"family"`
+	Destination    net.IP                `json:"destination"`
+	DestinationLen int                   `json:"destination-prefixlen"`
+	Index          int                   `json:"index,omitempty"`
+	NifRef         string                `json:"network-interface-idref,omitempty"`
+	NextHop        net.IP                `json:"next-hop,omitempty"`
+	Preference     string                `json:"preference"`
+	Priority       int                   `json:"priority"`
+	Table          int                   `json:"table"`
+
+	Nif *NetworkInterface `json:"-"` // resolved network-interface-idref, if any.
+}
+
+// Port is a transport-layer socket, together with the processes using it.
+type Port struct {
+	Family            network.AddressFamily `json:"family"`
+	Protocol          string                `json:"protocol"`
+	LocalAddress      net.IP                `json:"local-address"`
+	LocalPort         uint16                `json:"local-port"`
+	LocalServiceName  string                `json:"local-servicename"`
+	RemoteAddress     net.IP                `json:"remote-address"`
+	RemotePort        uint16                `json:"remote-port"`
+	RemoteServiceName string                `json:"remote-servicename"`
+	State             string                `json:"state"`
+	Macrostate        string                `json:"macrostate"`
+	Owners            []Owner               `json:"owners"`
+	NifRefs           []string              `json:"network-interface-idrefs"`
+	Inode             uint64                `json:"inode,omitempty"`
+	UID               uint32                `json:"uid"`
+	RecvQueue         uint32                `json:"recv-queue"`
+	SendQueue         uint32                `json:"send-queue"`
+	ListenBacklog     uint32                `json:"listen-backlog,omitempty"`
+	Mark              uint32                `json:"mark,omitempty"`
+	CgroupID          uint64                `json:"cgroup-id,omitempty"`
+	TCPInfo           *TCPInfo              `json:"tcp-info,omitempty"`
+	LocalAddresses    []net.IP              `json:"local-addresses,omitempty"`
+	RemoteAddresses   []net.IP              `json:"remote-addresses,omitempty"`
+	MPTCPSubflow      *MPTCPSubflow         `json:"mptcp-subflow,omitempty"`
+
+	Nifs Interfaces `json:"-"` // resolved network-interface-idrefs.
+}
+
+// TCPInfo are the details of a TCP connection, with all times in
+// microseconds.
+type TCPInfo struct {
+	RTT           int64  `json:"rtt"`
+	RTTVar        int64  `json:"rttvar"`
+	MinRTT        int64  `json:"min-rtt"`
+	RTO           int64  `json:"rto"`
+	SndMSS        uint32 `json:"snd-mss"`
+	RcvMSS        uint32 `json:"rcv-mss"`
+	SndCwnd       uint32 `json:"snd-cwnd"`
+	SndSsthresh   uint32 `json:"snd-ssthresh"`
+	Unacked       uint32 `json:"unacked"`
+	Lost          uint32 `json:"lost"`
+	Retrans       uint32 `json:"retrans"`
+	Retransmits   uint8  `json:"retransmits"`
+	TotalRetrans  uint32 `json:"total-retrans"`
+	BytesAcked    uint64 `json:"bytes-acked"`
+	BytesReceived uint64 `json:"bytes-received"`
+}
+
+// ForwardedPort is a port forwarded to another IP address and port, such as
+// a container's published port.
+type ForwardedPort struct {
+	Family             network.AddressFamily `json:"family"`
+	Protocol           string                `json:"protocol"`
+	IP                 net.IP                `json:"ip"`
+	Port               uint16                `json:"port"`
+	ServiceName        string                `json:"servicename"`
+	ForwardIP          net.IP                `json:"forward-ip"`
+	ForwardPort        uint16                `json:"forward-port"`
+	ForwardServiceName string                `json:"forward-servicename"`
+	NetnsID            uint64                `json:"netnsid"`
+	Owners             []Owner               `json:"owners"`
+	NifRefs            []string              `json:"network-interface-refs"`
+
+	Nifs Interfaces `json:"-"` // resolved network-interface-refs.
+}
+
+// MPTCP is the multipath TCP configuration and connections of a network
+// namespace.
+type MPTCP struct {
+	Endpoints   []MPTCPEndpoint       `json:"endpoints"`
+	Limits      *MPTCPLimits          `json:"limits,omitempty"`
+	Connections IPvX[MPTCPConnection] `json:"connections"`
+}
+
+// MPTCPConnection is a multipath TCP connection with its subflows.
+type MPTCPConnection struct {
+	Port
+	Info     *MPTCPInfo `json:"mptcp-info,omitempty"`
+	Subflows []Port     `json:"subflows"`
+}
+
+// MPTCPInfo are the details of a multipath TCP connection.
+type MPTCPInfo struct {
+	Token              uint32 `json:"token"`
+	Flags              uint32 `json:"flags"`
+	Subflows           uint8  `json:"subflows"`
+	SubflowsMax        uint8  `json:"subflows-max"`
+	AddAddrSignal      uint8  `json:"add-addr-signal"`
+	AddAddrSignalMax   uint8  `json:"add-addr-signal-max"`
+	AddAddrAccepted    uint8  `json:"add-addr-accepted"`
+	AddAddrAcceptedMax uint8  `json:"add-addr-accepted-max"`
+	Retransmits        uint32 `json:"retransmits"`
+	BytesSent          uint64 `json:"bytes-sent"`
+	BytesReceived      uint64 `json:"bytes-received"`
+	BytesAcked         uint64 `json:"bytes-acked"`
+}
+
+// MPTCPSubflow are the details of a TCP socket acting as a multipath TCP
+// subflow.
+type MPTCPSubflow struct {
+	LocalToken  uint32 `json:"local-token"`
+	RemoteToken uint32 `json:"remote-token"`
+	LocalID     uint8  `json:"local-id"`
+	RemoteID    uint8  `json:"remote-id"`
+	Flags       uint32 `json:"flags"`
+}
+
+// MPTCPEndpoint is a multipath TCP endpoint.
+type MPTCPEndpoint struct {
+	ID      uint8    `json:"id"`
+	Address net.IP   `json:"address"`
+	Port    uint16   `json:"port,omitempty"`
+	Flags   []string `json:"flags"`
+	NifRef  string   `json:"network-interface-idref,omitempty"`
+
+	Nif *NetworkInterface `json:"-"` // resolved network-interface-idref, if any.
+}
+
+// MPTCPLimits are the multipath TCP limits of a network namespace.
+type MPTCPLimits struct {
+	AddAddrAccepted uint32 `json:"add-addr-accepted"`
+	Subflows        uint32 `json:"subflows"`
+}
+
+// IPvXMulticastRouting is the IPv4 and IPv6 multicast routing of a network
+// namespace.
+type IPvXMulticastRouting struct {
+	IPv4 *MulticastRouting `json:"ipv4,omitempty"`
+	IPv6 *MulticastRouting `json:"ipv6,omitempty"`
+}
+
+// MulticastRouting are the multicast virtual interfaces and routes.
+type MulticastRouting struct {
+	VIFs   []MulticastVIF   `json:"vifs"`
+	Routes []MulticastRoute `json:"routes"`
+}
+
+// MulticastVIF is a multicast virtual interface.
+type MulticastVIF struct {
+	Index    int     `json:"index"`
+	Name     string  `json:"name"`
+	Nif      *NifRef `json:"nif,omitempty"`
+	BytesIn  uint64  `json:"bytes-in"`
+	PktsIn   uint64  `json:"packets-in"`
+	BytesOut uint64  `json:"bytes-out"`
+	PktsOut  uint64  `json:"packets-out"`
+	Flags    uint32  `json:"flags"`
+	Local    net.IP  `json:"local,omitempty"`
+	Remote   net.IP  `json:"remote,omitempty"`
+}
+
+// MulticastRoute is a multicast route.
+type MulticastRoute struct {
+	Group      net.IP      `json:"group"`
+	Origin     net.IP      `json:"origin"`
+	InputVIF   int         `json:"input-vif"`
+	Unresolved bool        `json:"unresolved"`
+	Packets    uint64      `json:"packets"`
+	Bytes      uint64      `json:"bytes"`
+	WrongIf    uint64      `json:"wrong-if"`
+	Outputs    []OutputVIF `json:"output-vifs"`
+}
+
+// OutputVIF is an output multicast virtual interface of a multicast route,
+// with its TTL threshold.
+type OutputVIF struct {
+	VIF int `json:"vif"`
+	TTL int `json:"ttl"`
+}
+
+// PacketSocket is an AF_PACKET socket.
+type PacketSocket struct {
+	Type         string  `json:"type"`
+	Protocol     uint16  `json:"protocol"`
+	ProtocolName string  `json:"protocol-name,omitempty"`
+	NifRef       string  `json:"network-interface-idref,omitempty"`
+	Running      bool    `json:"running"`
+	Filtered     bool    `json:"filtered"`
+	Inode        uint64  `json:"inode"`
+	UID          uint32  `json:"uid"`
+	Owners       []Owner `json:"owners"`
+
+	Nif *NetworkInterface `json:"-"` // resolved network-interface-idref, if bound.
+}
+
+// UnixSocket is a named or connected AF_UNIX socket.
+type UnixSocket struct {
+	Type       string          `json:"type"`
+	State      string          `json:"state"`
+	Macrostate string          `json:"macrostate"`
+	Path       string          `json:"path,omitempty"`
+	Abstract   bool            `json:"abstract,omitempty"`
+	Inode      uint64          `json:"inode"`
+	RecvQueue  uint32          `json:"recv-queue"`
+	SendQueue  uint32          `json:"send-queue"`
+	UID        uint32          `json:"uid"`
+	Owners     []Owner         `json:"owners"`
+	Peer       *UnixSocketPeer `json:"peer,omitempty"`
+	EngineAPI  *EngineAPI      `json:"engine-api,omitempty"`
+}
+
+// UnixSocketPeer is the peer of a connected AF_UNIX socket, which might be
+// attached to a different network namespace.
+type UnixSocketPeer struct {
+	Inode    uint64  `json:"inode"`
+	NetnsRef string  `json:"netns-idref,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Owners   []Owner `json:"owners"`
+
+	Netns *NetworkNamespace `json:"-"` // resolved netns-idref, if any.
+}
+
+// BridgingProcess is a process attached to a network namespace that holds
+// sockets of other network namespaces.
+type BridgingProcess struct {
+	PID              model.PIDType `json:"pid"`
+	Cmdline          string        `json:"cmdline"`
+	ContainerRef     string        `json:"container-idref,omitempty"`
+	ForeignNetnsRefs []string      `json:"foreign-netns-idrefs"`
+
+	Container    *Container          `json:"-"` // resolved container-idref, if any.
+	ForeignNetns []*NetworkNamespace `json:"-"` // resolved foreign-netns-idrefs.
+}
@@ -0,0 +1,26 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import "github.com/thediveo/lxkns/model"
+
+// TargetDiscoveryResult is a decoded “/mobyshark” capture target discovery
+// result.
+type TargetDiscoveryResult struct {
+	Metadata   map[string]interface{} `json:"metadata"`
+	Containers []CaptureTarget        `json:"containers"`
+}
+
+// CaptureTarget is a network namespace to capture network traffic from,
+// identified by a container, pod, stand-alone process, or bind-mount.
+type CaptureTarget struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	NetnsID   uint64        `json:"netns"`
+	NifNames  []string      `json:"network-interfaces"`
+	PID       model.PIDType `json:"pid"`
+	Starttime uint64        `json:"starttime"`
+	Prefix    string        `json:"prefix"`
+}
@@ -0,0 +1,121 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/bundle"
+	"github.com/siemens/turtlefinder"
+
+	"github.com/spf13/cobra"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+)
+
+// replay is the support bundle discoveries get replayed from instead of
+// discovering this host; nil when discovering this host.
+var replay *bundle.Replay
+
+// discover runs a discovery, either of this host or replaying the support
+// bundle specified using the --bundle CLI flag.
+func discover(ctx context.Context, cizer containerizer.Containerizer, labels map[string]string, opts ...gostwire.DiscoveryOption) gostwire.DiscoveryResult {
+	return gostwire.Discover(ctx, cizer, labels, withReplay(opts)...)
+}
+
+// withReplay returns the specified discovery options, adding the support
+// bundle to replay from, if any.
+func withReplay(opts []gostwire.DiscoveryOption) []gostwire.DiscoveryOption {
+	if replay == nil {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], gostwire.WithSource(replay))
+}
+
+// openReplay opens the support bundle specified using the --bundle CLI flag,
+// if any, for replaying discoveries from it.
+func openReplay(cmd *cobra.Command, _ []string) error {
+	name, _ := cmd.Flags().GetString("bundle")
+	if name == "" {
+		return nil
+	}
+	r, err := bundle.Open(name)
+	if err != nil {
+		return fmt.Errorf("cannot open support bundle, reason: %w", err)
+	}
+	manifest := r.Manifest()
+	log.Infof("replaying support bundle %s captured on %s (kernel %s) at %s",
+		name, manifest.Hostname, manifest.Kernel, manifest.Created.Format(time.RFC3339))
+	replay = r
+	return nil
+}
+
+// newBundleCmd returns the "bundle" subcommand that captures a support bundle
+// for later replay on another host.
+func newBundleCmd() *cobra.Command {
+	bundleCmd := &cobra.Command{
+		Use:   "bundle [FILE]",
+		Short: "capture a support bundle for offline replay",
+		Long: `Discovers this host and captures all system information read in the course of
+this discovery into a support bundle: netlink replies, procfs and sysfs files,
+netfilter tables, container engine API responses, tenant DNS configuration
+files, et cetera.
+
+FILE defaults to "gostwire-bundle-HOSTNAME-TIMESTAMP.tar.gz"; "-" writes the
+support bundle to stdout. Use "gostwire --bundle FILE" on another host to
+replay discoveries from the support bundle, such as in the web UI.`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := bundlecmd(cmd, args)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s\n", err.Error())
+			}
+			return err
+		},
+	}
+	return bundleCmd
+}
+
+// bundlecmd captures a support bundle into the file specified by the CLI args.
+func bundlecmd(cmd *cobra.Command, args []string) error {
+	if silent, _ := cmd.Flags().GetBool("silent"); silent {
+		log.SetLevel(log.ErrorLevel)
+	}
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		hostname, _ := os.Hostname()
+		name = fmt.Sprintf("gostwire-bundle-%s-%s.tar.gz",
+			hostname, time.Now().UTC().Format("20060102-150405"))
+	}
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	cizer := turtlefinder.New(func() context.Context { return ctx })
+	defer cizer.Close()
+	if name == "-" {
+		return bundle.Capture(ctx, cizer, cmd.OutOrStdout(), withReplay(nil)...)
+	}
+	f, err := os.Create(name) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if err := bundle.Capture(ctx, cizer, f, withReplay(nil)...); err != nil {
+		_ = f.Close()
+		_ = os.Remove(name)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "support bundle written to %s\n", name)
+	return nil
+}
@@ -0,0 +1,307 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/cmd/internal/wsconn"
+	"github.com/siemens/ghostwire/v2/diff"
+	"github.com/siemens/ghostwire/v2/watcher"
+
+	"github.com/gorilla/websocket"
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/model"
+)
+
+// changesKeepalive is the interval of SSE comments keeping otherwise idle
+// change streams alive when passing proxies.
+const changesKeepalive = 30 * time.Second
+
+// changesBuffer is the number of change notifications buffered per client.
+const changesBuffer = 16
+
+// retainedSnapshots is the number of recent discovery snapshots retained for
+// diffing.
+const retainedSnapshots = 64
+
+// lazyWatcher starts the discovery watcher only when the first client asks for
+// a change stream, so that services never streaming changes don't pay for
+// watching.
+type lazyWatcher struct {
+	once  sync.Once
+	cizer containerizer.Containerizer
+	w     *watcher.Watcher
+}
+
+// Watcher returns the running watcher, starting it if necessary.
+func (l *lazyWatcher) Watcher() *watcher.Watcher {
+	l.once.Do(func() {
+		log.Infof("starting discovery watcher for change streams")
+		l.w = watcher.New(l.cizer,
+			watcher.WithRetain(retainedSnapshots),
+			watcher.WithDiscoveryOptions(withReplay(nil)...))
+		go func() { _ = l.w.Run(context.Background()) }()
+	})
+	return l.w
+}
+
+// registerChanges registers the /changes route and handler with the route
+// handler plugin mechanism. Clients either get a Server-Sent Events stream or,
+// when asking for a websocket upgrade, a websocket with a text message per
+// change notification.
+func registerChanges(lazy *lazyWatcher) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/changes",
+				func(w http.ResponseWriter, req *http.Request) {
+					ctx := req.Context()
+					filter := newChangeFilter(req.URL.Query())
+					watch := lazy.Watcher()
+					// Subscribe before getting the current snapshot, so we
+					// don't miss any change in between.
+					sub := watch.Subscribe(changesBuffer)
+					defer sub.Close()
+					select {
+					case <-watch.Ready():
+					case <-ctx.Done():
+						return
+					}
+					last := watch.Snapshot()
+					lastsnap, err := diff.FromResult(last.Result)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					stream := &changeStream{
+						sub:      sub,
+						last:     last,
+						lastsnap: lastsnap,
+						filter:   filter,
+					}
+					if websocket.IsWebSocketUpgrade(req) {
+						stream.serveWebsocket(w, req)
+						return
+					}
+					stream.serveSSE(w, req)
+				}
+		}, plugger.WithPlugin("changes"))
+}
+
+// changeStream turns the snapshots of a watcher subscription into (filtered)
+// change event messages.
+type changeStream struct {
+	sub      *watcher.Subscription
+	last     *watcher.Snapshot
+	lastsnap *diff.Snapshot // stable identities of the last snapshot.
+	filter   changeFilter
+}
+
+// changes returns the change event message for the specified watcher change,
+// or false if there are no (unfiltered) change events.
+func (s *changeStream) changes(change watcher.Change) (apiv1.Changes, bool) {
+	if change.Snapshot.Seq <= s.last.Seq {
+		return apiv1.Changes{}, false
+	}
+	newsnap, err := diff.FromResult(change.Snapshot.Result)
+	if err != nil {
+		log.Errorf("change events snapshot error: %s", err.Error())
+		return apiv1.Changes{}, false
+	}
+	// As a subscriber might have missed some changes, we always diff against
+	// the last snapshot this particular client has seen.
+	events := s.filter.apply(
+		diff.ChangeEvents(s.lastsnap, newsnap),
+		s.last.Result, change.Snapshot.Result)
+	missed := change.Missed || change.Snapshot.Seq > s.last.Seq+1
+	s.last = change.Snapshot
+	s.lastsnap = newsnap
+	if len(events) == 0 {
+		return apiv1.Changes{}, false
+	}
+	return apiv1.Changes{
+		Seq:     change.Snapshot.Seq,
+		Time:    change.Snapshot.Time,
+		Missed:  missed,
+		Changes: events,
+	}, true
+}
+
+// serveSSE streams the change event messages as Server-Sent Events, using the
+// discovery sequence numbers as event IDs.
+func (s *changeStream) serveSSE(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(": seq " + strconv.FormatUint(s.last.Seq, 10) + "\n\n"))
+	flusher.Flush()
+	keepalive := time.NewTicker(changesKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case change, ok := <-s.sub.C:
+			if !ok {
+				return
+			}
+			msg, ok := s.changes(change)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(&msg)
+			if err != nil {
+				log.Errorf("change events marshalling error: %s", err.Error())
+				return
+			}
+			if _, err := w.Write([]byte("id: " + strconv.FormatUint(msg.Seq, 10) +
+				"\nevent: changes\ndata: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// serveWebsocket streams the change event messages as websocket text messages.
+func (s *changeStream) serveWebsocket(w http.ResponseWriter, req *http.Request) {
+	conn, err := wsconn.NewWSConn(w, req)
+	if err != nil {
+		return
+	}
+	closed := make(chan struct{})
+	go func() {
+		conn.Watch()
+		close(closed)
+	}()
+	defer conn.InitiateGracefulClose(websocket.CloseNormalClosure, "")
+	conn.Debugf("streaming changes")
+	for {
+		select {
+		case <-closed:
+			return
+		case <-req.Context().Done():
+			return
+		case change, ok := <-s.sub.C:
+			if !ok {
+				return
+			}
+			msg, ok := s.changes(change)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(&msg)
+			if err != nil {
+				conn.Errorf("change events marshalling error: %s", err.Error())
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// changeFilter restricts change events to the specified containers (by name,
+// ID, or v1 container reference) and network namespaces (by inode number or v1
+// netns reference). An empty filter passes all change events.
+type changeFilter struct {
+	containers map[string]struct{}
+	netns      map[string]struct{} // v1 netns references.
+}
+
+// newChangeFilter returns a change filter based on the "container" and "netns"
+// query parameters.
+func newChangeFilter(query url.Values) changeFilter {
+	f := changeFilter{
+		containers: map[string]struct{}{},
+		netns:      map[string]struct{}{},
+	}
+	for _, nameorid := range query["container"] {
+		f.containers[nameorid] = struct{}{}
+	}
+	for _, netns := range query["netns"] {
+		if !strings.HasPrefix(netns, "netns-") {
+			netns = "netns-" + netns
+		}
+		f.netns[netns] = struct{}{}
+	}
+	return f
+}
+
+// apply returns only those events that pass the filter. As containers might
+// have vanished or appeared, the containers from both the old and new
+// discovery results are taken into account.
+func (f changeFilter) apply(events []apiv1.ChangeEvent, old, new gostwire.DiscoveryResult) []apiv1.ChangeEvent {
+	if len(f.containers) == 0 && len(f.netns) == 0 {
+		return events
+	}
+	netnsrefs := map[string]struct{}{}
+	for ref := range f.netns {
+		netnsrefs[ref] = struct{}{}
+	}
+	cntrrefs := map[string]struct{}{}
+	for _, result := range []gostwire.DiscoveryResult{old, new} {
+		if result.Lxkns == nil {
+			continue
+		}
+		for _, cntr := range result.Lxkns.Containers {
+			if !f.matchesContainer(cntr) || cntr.Process == nil {
+				continue
+			}
+			cntrrefs["cont-"+strconv.FormatUint(uint64(cntr.PID), 10)] = struct{}{}
+			if netns := cntr.Process.Namespaces[model.NetNS]; netns != nil {
+				netnsrefs["netns-"+strconv.FormatUint(netns.ID().Ino, 10)] = struct{}{}
+			}
+		}
+	}
+	filtered := []apiv1.ChangeEvent{}
+	for _, event := range events {
+		if _, ok := cntrrefs[event.ContainerRef]; ok && event.ContainerRef != "" {
+			filtered = append(filtered, event)
+			continue
+		}
+		if _, ok := netnsrefs[event.NetnsRef]; ok && event.NetnsRef != "" {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// matchesContainer returns true if the specified container matches the filter
+// by name, ID, or v1 container reference.
+func (f changeFilter) matchesContainer(cntr *model.Container) bool {
+	for _, key := range []string{
+		cntr.Name, cntr.ID, "cont-" + strconv.FormatUint(uint64(cntr.PID), 10),
+	} {
+		if _, ok := f.containers[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/siemens/turtlefinder"
 
 	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 
 	"github.com/spf13/cobra"
 	"github.com/thediveo/lxkns/log"
@@ -148,15 +150,27 @@ func gostwireservice(cmd *cobra.Command, _ []string) error {
 	cizer := turtlefinder.New(func() context.Context { return enginectx })
 	defer enginecancel()
 
-	// prime the list of discovered engines in the background...
-	log.Debugf("priming list of discovered container engines in background")
-	go func() {
-		_ = gostwire.Discover(enginectx, cizer, nil)
-	}()
+	// prime the list of discovered engines in the background, unless we're
+	// replaying a support bundle...
+	if replay == nil {
+		log.Debugf("priming list of discovered container engines in background")
+		go func() {
+			_ = gostwire.Discover(enginectx, cizer, nil)
+		}()
+	}
 
 	// Fire up the service
 	addr, _ := cmd.PersistentFlags().GetString("http")
-	if _, err := startServer(addr, cizer); err != nil {
+	maxAge, _ := cmd.PersistentFlags().GetDuration("cache-max-age")
+	recorder, err := newHistoryRecorder(cmd)
+	if err != nil {
+		log.Errorf("cannot open discovery history, error: %s", err.Error())
+		os.Exit(1)
+	}
+	if recorder != nil {
+		defer recorder.stop()
+	}
+	if _, err := startServer(addr, cizer, discache.New(maxAge), recorder); err != nil {
 		log.Errorf("cannot start service, error: %s", err.Error())
 		os.Exit(1)
 	}
@@ -177,6 +191,12 @@ func newRootCmd() (rootCmd *cobra.Command) {
 		Version: gostwire.SemVersion,
 		Args:    cobra.NoArgs,
 		RunE:    gostwireservice,
+		// Subcommands inherit configuring the plugin circuit breakers as
+		// well as opening the support bundle to replay from, if any.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			configureBreakers(cmd)
+			return openReplay(cmd, args)
+		},
 	}
 
 	// Sets up the flags.
@@ -185,6 +205,14 @@ func newRootCmd() (rootCmd *cobra.Command) {
 	pf.Bool("silent", false, "silences everything below the error level")
 	pf.String("http", "[::]:5000", "HTTP service address")
 	pf.Duration("shutdown", 15*time.Second, "graceful shutdown duration limit")
+	pf.Duration("cache-max-age", 2*time.Second, "maximum age of cached discovery results; 0 disables caching")
+	pf.String("history", "", "SQLite database file to record the discovery history in; empty disables history")
+	pf.Duration("history-interval", time.Minute, "interval of periodic history recording in addition to recording changes; 0 disables periodic recording")
+	pf.Duration("history-max-age", 7*24*time.Hour, "maximum age of history snapshots; 0 keeps snapshots regardless of age")
+	pf.Int("history-max-snapshots", 10000, "maximum number of history snapshots; 0 keeps any number of snapshots")
+	pf.String("bundle", "", "support bundle to replay discoveries from instead of discovering this host")
+	pf.Int("plugin-failure-threshold", guard.DefaultThreshold, "consecutive failures after which a plugin (or container engine) gets skipped; 0 never skips")
+	pf.Duration("plugin-cooldown", guard.DefaultCooldown, "time to skip a plugin (or container engine) after repeated failures")
 
 	// Work around docker-compose currently having no means to set "cgroupns:
 	// host" during deployment. There's a CLI flag, but no docker-composer
@@ -197,8 +225,18 @@ func newRootCmd() (rootCmd *cobra.Command) {
 	brandName = pf.StringP("brand", "", "Ghostwire", "brand name to show in the UI")
 	brandIcon = pf.StringP("brandicon", "", "", "brand icon SVG markup (optionally base64 encoded)")
 
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newBundleCmd())
 	return
 }
 
+// configureBreakers configures the circuit breakers shared by all discoveries
+// that temporarily skip repeatedly failing plugins and container engines.
+func configureBreakers(cmd *cobra.Command) {
+	threshold, _ := cmd.Flags().GetInt("plugin-failure-threshold")
+	cooldown, _ := cmd.Flags().GetDuration("plugin-cooldown")
+	guard.Breakers.Configure(threshold, cooldown)
+}
+
 var brandName *string
 var brandIcon *string
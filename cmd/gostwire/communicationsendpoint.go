@@ -0,0 +1,36 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+)
+
+// registerCommunications registers the /communications route and handler with
+// the route handler plugin mechanism.
+func registerCommunications(cizer containerizer.Containerizer) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/communications",
+				func(w http.ResponseWriter, req *http.Request) {
+					allnetns := discover(req.Context(), cizer, nil)
+					result := apiv1.NewCommunicationsResult(allnetns)
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					err := json.NewEncoder(w).Encode(&result)
+					if err != nil {
+						log.Errorf("communications result marshalling error: %s", err.Error())
+					}
+				}
+		}, plugger.WithPlugin("communications"))
+}
@@ -0,0 +1,201 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/exp/slices"
+
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/species"
+)
+
+// maxCountersInterval limits the interval between two protocol counter samples
+// a client can ask for.
+const maxCountersInterval = 60 * time.Second
+
+// registerCounters registers the /counters and /metrics routes and handlers
+// with the route handler plugin mechanism. The discovery results are served
+// from the specified cache, sharing in-flight discoveries between concurrent
+// requests as well as with the other endpoints doing unscoped discoveries,
+// such as /json. Sampling the counters a second time in order to calculate
+// their deltas is only supported for live discoveries, but not when replaying
+// a support bundle.
+func registerCounters(cizer containerizer.Containerizer, cache *discache.Cache) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/counters",
+				func(w http.ResponseWriter, req *http.Request) {
+					var interval time.Duration
+					if intervals, ok := req.URL.Query()["interval"]; ok {
+						// Replayed network namespaces cannot be sampled a
+						// second time.
+						if replay != nil {
+							http.Error(w, "interval not supported when replaying a support bundle",
+								http.StatusBadRequest)
+							return
+						}
+						var err error
+						interval, err = time.ParseDuration(intervals[0])
+						if err != nil || interval < 0 || interval > maxCountersInterval {
+							http.Error(w, fmt.Sprintf("invalid interval, must be within 0s..%s",
+								maxCountersInterval), http.StatusBadRequest)
+							return
+						}
+					}
+					entry, err := cache.Get(req.Context(), discoveryKey(nil), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil)
+						})
+					if err != nil {
+						return // client gave up.
+					}
+					if interval == 0 {
+						serveCachedJSON(w, req, cache, entry, "counters", func(e *discache.Cached) interface{} {
+							result := apiv1.NewCountersResult(e.Result(), nil, apiv1.WithMetadata(e.Metadata()))
+							return &result
+						})
+						return
+					}
+					// The deltas are calculated from the actual sample
+					// timestamps, so it doesn't matter when the cached
+					// discovery took its samples.
+					allnetns := entry.Result()
+					select {
+					case <-req.Context().Done():
+						return
+					case <-time.After(interval):
+					}
+					later := map[species.NamespaceID]*network.ProtocolCounters{}
+					for netnsid, netns := range allnetns.Netns {
+						counters, err := netns.SampleProtocolCounters()
+						if err != nil {
+							continue
+						}
+						later[netnsid] = counters
+					}
+					result := apiv1.NewCountersResult(allnetns, later, apiv1.WithMetadata(entry.Metadata()))
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					err = json.NewEncoder(w).Encode(&result)
+					if err != nil {
+						log.Errorf("counters result marshalling error: %s", err.Error())
+					}
+				}
+		}, plugger.WithPlugin("counters"))
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/metrics",
+				func(w http.ResponseWriter, req *http.Request) {
+					entry, err := cache.Get(req.Context(), discoveryKey(nil), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil)
+						})
+					if err != nil {
+						return // client gave up.
+					}
+					w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+					if err := writeMetrics(w, entry.Result().Netns); err != nil {
+						log.Errorf("metrics writing error: %s", err.Error())
+					}
+				}
+		}, plugger.WithPlugin("metrics"))
+}
+
+// writeMetrics writes the protocol counters of the specified network
+// namespaces in the Prometheus text exposition format. Network namespaces are
+// identified by their inode numbers as well as their (first) tenant names.
+func writeMetrics(w io.Writer, allnetns network.NetworkNamespaces) error {
+	netnses := make([]*network.NetworkNamespace, 0, len(allnetns))
+	for _, netns := range allnetns {
+		if netns.ProtocolCounters != nil {
+			netnses = append(netnses, netns)
+		}
+	}
+	sort.Slice(netnses, func(a, b int) bool { return netnses[a].ID().Ino < netnses[b].ID().Ino })
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP ghostwire_netns_protocol_counter Protocol-level counter of a network namespace from /proc/net/{snmp,snmp6,netstat}.")
+	fmt.Fprintln(bw, "# TYPE ghostwire_netns_protocol_counter untyped")
+	for _, netns := range netnses {
+		writeMetricSamples(bw, "ghostwire_netns_protocol_counter", netnsLabels(netns),
+			"group", "counter", netns.ProtocolCounters.Counters)
+	}
+	fmt.Fprintln(bw, "# HELP ghostwire_netns_sockstat Socket usage of a network namespace from /proc/net/sockstat{,6}.")
+	fmt.Fprintln(bw, "# TYPE ghostwire_netns_sockstat gauge")
+	for _, netns := range netnses {
+		writeMetricSamples(bw, "ghostwire_netns_sockstat", netnsLabels(netns),
+			"protocol", "stat", netns.ProtocolCounters.Sockstat)
+	}
+	return bw.Flush()
+}
+
+// writeMetricSamples writes the samples of a two-level map of counters in
+// sorted order, using the specified label names for the map keys.
+func writeMetricSamples(w io.Writer, metric string, labels string,
+	grouplabel, namelabel string, counters map[string]map[string]int64) {
+	groups := make([]string, 0, len(counters))
+	for group := range counters {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		names := make([]string, 0, len(counters[group]))
+		for name := range counters[group] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{%s,%s=%q,%s=%q} %d\n",
+				metric, labels, grouplabel, name2label(group), namelabel, name2label(name),
+				counters[group][name])
+		}
+	}
+}
+
+// netnsLabels returns the Prometheus labels identifying the specified network
+// namespace.
+func netnsLabels(netns *network.NetworkNamespace) string {
+	tenant := ""
+	if len(netns.Tenants) != 0 {
+		// Don't sort the tenants in place, as the discovery result might be
+		// shared with other requests.
+		tenants := slices.Clone(netns.Tenants)
+		tenants.Sort()
+		tenant = tenants[0].Name()
+	}
+	return "netns=\"" + strconv.FormatUint(netns.ID().Ino, 10) + "\",tenant=" + strconv.Quote(name2label(tenant))
+}
+
+// name2label returns the specified name as a valid Prometheus label value when
+// quoted by %q, that is, without any non-ASCII characters that would otherwise
+// end up in Go-specific escapes.
+func name2label(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, name)
+}
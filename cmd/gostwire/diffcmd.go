@@ -0,0 +1,125 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/siemens/ghostwire/v2/diff"
+	"github.com/siemens/turtlefinder"
+
+	"github.com/spf13/cobra"
+	"github.com/thediveo/lxkns/log"
+)
+
+// liveSource is the discovery result source denoting a live discovery on this
+// host.
+const liveSource = "live"
+
+// errDiffering signals that there are differences when the --exit-code flag
+// has been specified.
+var errDiffering = errors.New("discovery results differ")
+
+// newDiffCmd returns the "diff" subcommand that compares two discovery
+// results, each either from a saved v1 JSON file, stdin ("-"), a Ghostwire
+// service URL, or a live discovery.
+func newDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff OLD [NEW]",
+		Short: "compare two discovery results",
+		Long: `Compares two discovery results and lists the added, removed, and changed
+network namespaces, containers, network interfaces, addresses, routes, open
+ports, and forwarded ports.
+
+OLD and NEW each are either a file with a saved v1 JSON discovery result, "-"
+for reading such a result from stdin, an http(s) URL of a Ghostwire service's
+/json endpoint, or "live" for discovering this host (or replaying the support
+bundle specified using --bundle). NEW defaults to "live".`,
+		Args:          cobra.RangeArgs(1, 2),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diffcmd(cmd, args)
+			if err != nil && err != errDiffering {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s\n", err.Error())
+			}
+			return err
+		},
+	}
+	diffCmd.Flags().Bool("json", false, "output the differences in JSON format")
+	diffCmd.Flags().Bool("exit-code", false, "exit with status 1 if there are differences")
+	return diffCmd
+}
+
+// diffcmd compares the discovery results specified by the CLI args.
+func diffcmd(cmd *cobra.Command, args []string) error {
+	if silent, _ := cmd.Flags().GetBool("silent"); silent {
+		log.SetLevel(log.ErrorLevel)
+	}
+	if len(args) < 2 {
+		args = append(args, liveSource)
+	}
+	old, err := loadSnapshot(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	new, err := loadSnapshot(cmd.Context(), args[1])
+	if err != nil {
+		return err
+	}
+	d := diff.Compare(old, new)
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		err = enc.Encode(d)
+	} else {
+		err = d.WriteText(cmd.OutOrStdout())
+	}
+	if err != nil {
+		return err
+	}
+	if exitcode, _ := cmd.Flags().GetBool("exit-code"); exitcode && !d.Empty() {
+		return errDiffering
+	}
+	return nil
+}
+
+// loadSnapshot returns the snapshot of the discovery result from the specified
+// source.
+func loadSnapshot(ctx context.Context, source string) (*diff.Snapshot, error) {
+	switch {
+	case source == liveSource:
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+		return diff.FromResult(discover(ctx, cizer, nil))
+	case source == "-":
+		return diff.Load(os.Stdin)
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("cannot fetch discovery result from %s, status: %s",
+				source, resp.Status)
+		}
+		return diff.Load(resp.Body)
+	default:
+		return diff.LoadFile(source)
+	}
+}
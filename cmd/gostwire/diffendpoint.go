@@ -0,0 +1,155 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/diff"
+	"github.com/siemens/ghostwire/v2/history"
+	"github.com/siemens/ghostwire/v2/watcher"
+
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/log"
+)
+
+// maxDiffBody limits the size of v1 JSON discovery results POSTed to /diff.
+const maxDiffBody = 64 << 20
+
+// diffResult is the JSON response of the /diff endpoint.
+type diffResult struct {
+	Since    uint64 `json:"since,omitempty"`    // sequence number of the old snapshot, if any.
+	Snapshot int64  `json:"snapshot,omitempty"` // history snapshot ID of the old snapshot, if any.
+	Seq      uint64 `json:"seq"`                // sequence number of the current snapshot.
+	*diff.Diff
+}
+
+// registerDiff registers the /diff routes and handlers with the route handler
+// plugin mechanism. The handlers compare an old discovery result with the
+// current discovery snapshot, where the old discovery result is either:
+//   - a recent discovery snapshot, as identified by its sequence number in the
+//     "since" query parameter,
+//   - a history snapshot, as identified by its ID in the "snapshot" query
+//     parameter,
+//   - or a v1 JSON discovery result POSTed in the request body.
+//
+// Without any of them, the current snapshot is compared with itself, so
+// clients learn the current sequence number to later diff against.
+func registerDiff(lazy *lazyWatcher, store *history.Store) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		watch := lazy.Watcher()
+		select {
+		case <-watch.Ready():
+		case <-ctx.Done():
+			return
+		}
+		current := watch.Snapshot()
+		new, err := diff.FromResult(current.Result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result := diffResult{Seq: current.Seq}
+		old, oldTime, status, err := oldDiffSnapshot(w, req, watch, current, store, &result)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		result.Diff = diff.Compare(old, new)
+		result.Diff.Old = oldTime
+		result.Diff.New = current.Time
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(&result); err != nil {
+			log.Errorf("diff result marshalling error: %s", err.Error())
+		}
+	}
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET", "/diff", handler
+		}, plugger.WithPlugin("diff"))
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "POST", "/diff", handler
+		}, plugger.WithPlugin("diff-post"))
+}
+
+// oldDiffSnapshot returns the old snapshot to compare with the current watcher
+// snapshot, as well as the time the old snapshot was taken, noting its
+// sequence number or history snapshot ID in the specified diff result. In case
+// of an error, it also returns the HTTP status code to answer with.
+func oldDiffSnapshot(
+	w http.ResponseWriter,
+	req *http.Request,
+	watch *watcher.Watcher,
+	current *watcher.Snapshot,
+	store *history.Store,
+	result *diffResult,
+) (*diff.Snapshot, time.Time, int, error) {
+	query := req.URL.Query()
+	switch {
+	case req.Method == http.MethodPost:
+		old, err := diff.Load(http.MaxBytesReader(w, req.Body, maxDiffBody))
+		if err != nil {
+			return nil, time.Time{}, http.StatusBadRequest, err
+		}
+		return old, old.Time, 0, nil
+	case query.Get("snapshot") != "":
+		id, err := strconv.ParseInt(query.Get("snapshot"), 10, 64)
+		if err != nil {
+			return nil, time.Time{}, http.StatusBadRequest, errors.New("invalid history snapshot id")
+		}
+		if store == nil {
+			return nil, time.Time{}, http.StatusNotFound, errors.New("discovery history not enabled")
+		}
+		info, v1json, err := store.Get(req.Context(), id)
+		if errors.Is(err, history.ErrNotFound) {
+			return nil, time.Time{}, http.StatusNotFound, err
+		}
+		if err != nil {
+			return nil, time.Time{}, http.StatusInternalServerError, err
+		}
+		old, err := diff.Load(bytes.NewReader(v1json))
+		if err != nil {
+			return nil, time.Time{}, http.StatusInternalServerError, err
+		}
+		result.Snapshot = info.ID
+		return old, info.Time, 0, nil
+	case query.Get("since") != "":
+		seq, err := strconv.ParseUint(query.Get("since"), 10, 64)
+		if err != nil {
+			return nil, time.Time{}, http.StatusBadRequest,
+				errors.New("invalid since snapshot sequence number")
+		}
+		since := watch.SnapshotAt(seq)
+		if since == nil {
+			if seq >= 1 && seq < current.Seq {
+				return nil, time.Time{}, http.StatusGone,
+					fmt.Errorf("snapshot %d not retained anymore", seq)
+			}
+			return nil, time.Time{}, http.StatusNotFound,
+				fmt.Errorf("snapshot %d not available", seq)
+		}
+		old, err := diff.FromResult(since.Result)
+		if err != nil {
+			return nil, time.Time{}, http.StatusInternalServerError, err
+		}
+		result.Since = since.Seq
+		return old, since.Time, 0, nil
+	}
+	old, err := diff.FromResult(current.Result)
+	if err != nil {
+		return nil, time.Time{}, http.StatusInternalServerError, err
+	}
+	result.Since = current.Seq
+	return old, current.Time, 0, nil
+}
@@ -0,0 +1,144 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
+
+	"github.com/thediveo/lxkns/species"
+)
+
+// discoveryOptions returns the discovery options as specified in the query
+// parameters of a discovery request, or an error if there are any invalid
+// query parameters. Query parameters taking lists accept either comma-separated
+// lists or multiple occurrences of the same query parameter.
+//
+//   - netns: network namespace inode numbers to restrict the discovery to.
+//   - container: container names or IDs to restrict the discovery to.
+//   - engine: container engine types or IDs to restrict the discovery to.
+//   - skip: "sockets", "forwarded-ports", and/or "dns".
+//   - skip-decorators: names of decorator plugins to skip.
+//   - skip-metadata: names of metadata plugins to skip.
+//   - network-timeout, decorators-timeout, metadata-timeout: per-phase
+//     timeouts, such as "2s".
+//   - plugin-timeout: per-plugin timeouts, either plain durations applying to
+//     all plugins, or "name:duration" applying only to the named plugin.
+func discoveryOptions(query url.Values) ([]gostwire.DiscoveryOption, error) {
+	opts := []gostwire.DiscoveryOption{}
+	if netnses := queryList(query, "netns"); len(netnses) != 0 {
+		netnsids := make([]species.NamespaceID, 0, len(netnses))
+		for _, netns := range netnses {
+			ino, err := strconv.ParseUint(strings.TrimPrefix(netns, "netns-"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid netns %q", netns)
+			}
+			netnsids = append(netnsids, species.NamespaceIDfromInode(ino))
+		}
+		opts = append(opts, gostwire.WithNetworkNamespaces(netnsids...))
+	}
+	if containers := queryList(query, "container"); len(containers) != 0 {
+		opts = append(opts, gostwire.WithContainers(containers...))
+	}
+	if engines := queryList(query, "engine"); len(engines) != 0 {
+		opts = append(opts, gostwire.WithEngines(engines...))
+	}
+	for _, skip := range queryList(query, "skip") {
+		switch skip {
+		case "sockets":
+			opts = append(opts, gostwire.WithoutSockets())
+		case "forwarded-ports":
+			opts = append(opts, gostwire.WithoutForwardedPorts())
+		case "dns":
+			opts = append(opts, gostwire.WithoutDNS())
+		default:
+			return nil, fmt.Errorf("invalid skip %q, must be sockets, forwarded-ports, or dns", skip)
+		}
+	}
+	if decorators := queryList(query, "skip-decorators"); len(decorators) != 0 {
+		opts = append(opts, gostwire.WithoutDecorators(decorators...))
+	}
+	if metadata := queryList(query, "skip-metadata"); len(metadata) != 0 {
+		opts = append(opts, gostwire.WithoutMetadata(metadata...))
+	}
+	for _, timeout := range []struct {
+		name string
+		opt  func(time.Duration) gostwire.DiscoveryOption
+	}{
+		{"network-timeout", gostwire.WithNetworkTimeout},
+		{"decorators-timeout", gostwire.WithDecoratorsTimeout},
+		{"metadata-timeout", gostwire.WithMetadataTimeout},
+	} {
+		values, ok := query[timeout.name]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(values[0])
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %q", timeout.name, values[0])
+		}
+		opts = append(opts, timeout.opt(d))
+	}
+	for _, timeout := range queryList(query, "plugin-timeout") {
+		name, duration := "", timeout
+		if idx := strings.LastIndex(timeout, ":"); idx >= 0 {
+			name, duration = timeout[:idx], timeout[idx+1:]
+		}
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin-timeout %q", timeout)
+		}
+		if name == "" {
+			opts = append(opts, gostwire.WithPluginTimeout(d))
+			continue
+		}
+		opts = append(opts, gostwire.WithPluginTimeoutFor(name, d))
+	}
+	return opts, nil
+}
+
+// discoveryQueryParams are the query parameters influencing a discovery
+// itself, as opposed to only its representation.
+var discoveryQueryParams = []string{
+	"netns", "container", "engine",
+	"skip", "skip-decorators", "skip-metadata",
+	"network-timeout", "decorators-timeout", "metadata-timeout", "plugin-timeout",
+	"ieappicons",
+}
+
+// discoveryKey returns the cache key of the discovery as specified by the
+// query parameters of a discovery request. As the key covers only the query
+// parameters influencing the discovery itself, all endpoints share the same
+// cached discovery results, rendering them into their own representations.
+func discoveryKey(query url.Values) string {
+	q := url.Values{}
+	for _, name := range discoveryQueryParams {
+		if values, ok := query[name]; ok {
+			q[name] = values
+		}
+	}
+	return discache.Key("/discovery", q)
+}
+
+// queryList returns the non-empty elements of the specified query parameter,
+// which may be given multiple times as well as in form of comma-separated
+// lists.
+func queryList(query url.Values, name string) []string {
+	var list []string
+	for _, value := range query[name] {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				list = append(list, element)
+			}
+		}
+	}
+	return list
+}
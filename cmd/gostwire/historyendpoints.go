@@ -0,0 +1,272 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/history"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+)
+
+// historyRecorder records discovery results into a history store whenever the
+// discovery watcher notices changes, as well as periodically in order to also
+// catch changes not triggering any events, such as new listening ports.
+type historyRecorder struct {
+	store    *history.Store
+	interval time.Duration // zero disables periodic recording.
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// newHistoryRecorder returns a new history recorder configured from the CLI
+// flags, or nil if history recording is disabled.
+func newHistoryRecorder(cmd *cobra.Command) (*historyRecorder, error) {
+	pf := cmd.PersistentFlags()
+	name, _ := pf.GetString("history")
+	if name == "" {
+		return nil, nil
+	}
+	interval, _ := pf.GetDuration("history-interval")
+	maxAge, _ := pf.GetDuration("history-max-age")
+	maxSnapshots, _ := pf.GetInt("history-max-snapshots")
+	store, err := history.Open(name,
+		history.WithMaxAge(maxAge), history.WithMaxSnapshots(maxSnapshots))
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("recording discovery history in %s", name)
+	return &historyRecorder{store: store, interval: interval}, nil
+}
+
+// start starts recording discovery results in the background.
+func (h *historyRecorder) start(lazy *lazyWatcher, cizer containerizer.Containerizer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	go func() {
+		defer close(h.done)
+		h.run(ctx, lazy, cizer)
+	}()
+}
+
+// stop stops recording, if started, and then closes the history store.
+func (h *historyRecorder) stop() {
+	if h.cancel != nil {
+		h.cancel()
+		<-h.done
+	}
+	if err := h.store.Close(); err != nil {
+		log.Errorf("cannot close discovery history, reason: %s", err.Error())
+	}
+}
+
+// run records discovery results until the passed context gets cancelled.
+func (h *historyRecorder) run(ctx context.Context, lazy *lazyWatcher, cizer containerizer.Containerizer) {
+	watch := lazy.Watcher()
+	sub := watch.Subscribe(changesBuffer)
+	defer sub.Close()
+	select {
+	case <-watch.Ready():
+	case <-ctx.Done():
+		return
+	}
+	current := watch.Snapshot()
+	h.record(ctx, current.Time, current.Result)
+
+	var tick <-chan time.Time
+	if h.interval > 0 {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			h.record(ctx, change.Snapshot.Time, change.Snapshot.Result)
+		case <-tick:
+			h.record(ctx, time.Now(), discover(ctx, cizer, nil))
+		}
+	}
+}
+
+// record records a single discovery result, logging any error.
+func (h *historyRecorder) record(ctx context.Context, at time.Time, result gostwire.DiscoveryResult) {
+	info, err := h.store.Record(ctx, at, result)
+	if err != nil {
+		if ctx.Err() != nil {
+			return // shutting down, so don't care.
+		}
+		log.Errorf("cannot record discovery history, reason: %s", err.Error())
+		return
+	}
+	log.Debugf("recorded discovery history snapshot %d", info.ID)
+}
+
+// registerHistory registers the /history/... routes and handlers with the
+// route handler plugin mechanism. If there is no history store, then the
+// handlers always answer with 404.
+func registerHistory(store *history.Store) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/history/snapshots",
+				func(w http.ResponseWriter, req *http.Request) {
+					if !historyEnabled(w, store) {
+						return
+					}
+					query := req.URL.Query()
+					from, to, ok := timeRange(w, query)
+					if !ok {
+						return
+					}
+					limit := 0
+					if limits := query.Get("limit"); limits != "" {
+						var err error
+						if limit, err = strconv.Atoi(limits); err != nil || limit < 0 {
+							http.Error(w, "invalid limit", http.StatusBadRequest)
+							return
+						}
+					}
+					infos, err := store.List(req.Context(), from, to, limit)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					writeHistoryJSON(w, struct {
+						Snapshots []history.Info `json:"snapshots"`
+					}{Snapshots: infos})
+				}
+		}, plugger.WithPlugin("history-snapshots"))
+
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/history/json",
+				func(w http.ResponseWriter, req *http.Request) {
+					if !historyEnabled(w, store) {
+						return
+					}
+					query := req.URL.Query()
+					var info history.Info
+					var v1json []byte
+					var err error
+					switch {
+					case query.Get("id") != "":
+						id, perr := strconv.ParseInt(query.Get("id"), 10, 64)
+						if perr != nil {
+							http.Error(w, "invalid snapshot id", http.StatusBadRequest)
+							return
+						}
+						info, v1json, err = store.Get(req.Context(), id)
+					case query.Get("at") != "":
+						at, perr := time.Parse(time.RFC3339Nano, query.Get("at"))
+						if perr != nil {
+							http.Error(w, "invalid at time, must be RFC3339", http.StatusBadRequest)
+							return
+						}
+						info, v1json, err = store.AsOf(req.Context(), at)
+					default:
+						http.Error(w, "missing at time or snapshot id", http.StatusBadRequest)
+						return
+					}
+					if errors.Is(err, history.ErrNotFound) {
+						http.Error(w, err.Error(), http.StatusNotFound)
+						return
+					}
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Last-Modified", info.Time.UTC().Format(http.TimeFormat))
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(v1json)
+				}
+		}, plugger.WithPlugin("history-json"))
+
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				"/history/containers/{container:.+}",
+				func(w http.ResponseWriter, req *http.Request) {
+					if !historyEnabled(w, store) {
+						return
+					}
+					from, to, ok := timeRange(w, req.URL.Query())
+					if !ok {
+						return
+					}
+					container := mux.Vars(req)["container"]
+					states, err := store.ContainerHistory(req.Context(), container, from, to)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+					writeHistoryJSON(w, struct {
+						Container string                   `json:"container"`
+						States    []history.ContainerState `json:"states"`
+					}{Container: container, States: states})
+				}
+		}, plugger.WithPlugin("history-container"))
+}
+
+// historyEnabled returns true if there is a history store, otherwise it
+// answers the request with 404.
+func historyEnabled(w http.ResponseWriter, store *history.Store) bool {
+	if store == nil {
+		http.Error(w, "history not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// timeRange returns the optional "from" and "to" RFC3339 times from the
+// specified query parameters. If either is invalid, then it answers the request
+// with 400 and returns false.
+func timeRange(w http.ResponseWriter, query url.Values) (from, to time.Time, ok bool) {
+	for _, param := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			http.Error(w, "invalid "+param.name+" time, must be RFC3339", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		*param.t = t
+	}
+	return from, to, true
+}
+
+// writeHistoryJSON answers the request with the specified value in JSON.
+func writeHistoryJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("history result marshalling error: %s", err.Error())
+	}
+}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/gorilla/mux"
 	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
+	"github.com/siemens/ghostwire/v2/history"
 	"github.com/thediveo/lxkns/containerizer"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/spaserve"
@@ -62,7 +64,7 @@ func requestLogger(next http.Handler) http.Handler {
 	})
 }
 
-func startServer(address string, cizer containerizer.Containerizer) (net.Addr, error) {
+func startServer(address string, cizer containerizer.Containerizer, cache *discache.Cache, recorder *historyRecorder) (net.Addr, error) {
 	// Create the HTTP server listening transport...
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
@@ -73,8 +75,20 @@ func startServer(address string, cizer containerizer.Containerizer) (net.Addr, e
 	// handlers.
 	r := mux.NewRouter()
 	r.Use(requestLogger)
-	registerDiscovery(cizer)
-	registerMobyDigger(cizer)
+	registerDiscovery(cizer, cache)
+	registerV2(cizer, cache)
+	registerMobyDigger(cizer, cache)
+	registerCounters(cizer, cache)
+	registerCommunications(cizer)
+	lazy := &lazyWatcher{cizer: cizer}
+	registerChanges(lazy)
+	var store *history.Store
+	if recorder != nil {
+		store = recorder.store
+		recorder.start(lazy, cizer)
+	}
+	registerDiff(lazy, store)
+	registerHistory(store)
 	registerRouteHandlers(r)
 
 	r.PathPrefix("/").Handler(spaserve.NewSPAHandler(
@@ -5,10 +5,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
 	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
 	"github.com/siemens/ghostwire/v2/cmd/internal/wsconn"
 	"github.com/siemens/ghostwire/v2/mobydig"
 
@@ -22,9 +24,10 @@ import (
 const maxDiggers = 8
 const maxVerifiers = 8
 
-// registerDiscovery registers the /json discovery route and handler with the
-// route handler plugin mechanism.
-func registerMobyDigger(cizer containerizer.Containerizer) {
+// registerMobyDigger registers the /mobydig route and handler with the route
+// handler plugin mechanism. The discovery results are taken from the specified
+// cache.
+func registerMobyDigger(cizer containerizer.Containerizer, cache *discache.Cache) {
 	plugger.Group[RouteHandler]().Register(
 		func() (string, string, http.HandlerFunc) {
 			return "GET",
@@ -48,7 +51,15 @@ func registerMobyDigger(cizer containerizer.Containerizer) {
 					}()
 					conn.Debugf("discovering and verifying nearby neighborhood services at %q", target[0])
 
-					allnetns := gostwire.Discover(req.Context(), cizer, nil)
+					// Shares the discovery with the other endpoints.
+					entry, err := cache.Get(ctx, discoveryKey(nil), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil)
+						})
+					if err != nil {
+						return
+					}
+					allnetns := entry.Result()
 					startContainer := allnetns.Lxkns.Containers.FirstWithNameType(target[0], moby.Type)
 					if startContainer == nil {
 						log.Errorf("Docker container %q not found", target[0])
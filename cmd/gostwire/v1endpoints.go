@@ -5,11 +5,12 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 
 	gostwire "github.com/siemens/ghostwire/v2"
 	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
 	"github.com/siemens/ghostwire/v2/decorator/ieappicon"
 
 	"github.com/thediveo/go-plugger/v3"
@@ -18,8 +19,9 @@ import (
 )
 
 // registerDiscovery registers the /json discovery route and handler with the
-// route handler plugin mechanism.
-func registerDiscovery(cizer containerizer.Containerizer) {
+// route handler plugin mechanism. The discovery results are served from the
+// specified cache, sharing in-flight discoveries between concurrent requests.
+func registerDiscovery(cizer containerizer.Containerizer, cache *discache.Cache) {
 	plugger.Group[RouteHandler]().Register(
 		func() (string, string, http.HandlerFunc) {
 			return "GET",
@@ -30,14 +32,22 @@ func registerDiscovery(cizer containerizer.Containerizer) {
 					if ieappicons, ok := query["ieappicons"]; ok {
 						discoveryLabels[ieappicon.IEAppDiscoveryLabel] = ieappicons[0]
 					}
-					allnetns := gostwire.Discover(req.Context(), cizer, discoveryLabels)
-					result := apiv1.NewDiscoveryResult(allnetns)
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					err := json.NewEncoder(w).Encode(&result)
+					opts, err := discoveryOptions(query)
 					if err != nil {
-						log.Errorf("discovery result marshalling error: %s", err.Error())
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
 					}
+					entry, err := cache.Get(req.Context(), discoveryKey(query), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, discoveryLabels, opts...)
+						})
+					if err != nil {
+						return // client gave up.
+					}
+					serveCachedJSON(w, req, cache, entry, "json", func(e *discache.Cached) interface{} {
+						result := apiv1.NewDiscoveryResult(e.Result(), apiv1.WithMetadata(e.Metadata()))
+						return &result
+					})
 				}
 		}, plugger.WithPlugin("json"))
 	plugger.Group[RouteHandler]().Register(
@@ -45,14 +55,33 @@ func registerDiscovery(cizer containerizer.Containerizer) {
 			return "GET",
 				"/mobyshark",
 				func(w http.ResponseWriter, req *http.Request) {
-					allnetns := gostwire.Discover(req.Context(), cizer, nil)
-					result := apiv1.NewTargetDiscoveryResult(allnetns)
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					err := json.NewEncoder(w).Encode(&result)
+					entry, err := cache.Get(req.Context(), discoveryKey(nil), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil)
+						})
 					if err != nil {
-						log.Errorf("capture target discovery result marshalling error: %s", err.Error())
+						return // client gave up.
 					}
+					serveCachedJSON(w, req, cache, entry, "mobyshark", func(e *discache.Cached) interface{} {
+						result := apiv1.NewTargetDiscoveryResult(e.Result(), apiv1.WithMetadata(e.Metadata()))
+						return &result
+					})
 				}
 		}, plugger.WithPlugin("mobyshark"))
 }
+
+// serveCachedJSON serves the specified JSON representation of a cached
+// discovery result.
+func serveCachedJSON(
+	w http.ResponseWriter, req *http.Request,
+	cache *discache.Cache, entry *discache.Cached,
+	name string, render discache.RenderFn,
+) {
+	rendering, err := entry.Render(name, render)
+	if err != nil {
+		log.Errorf("%s discovery result marshalling error: %s", name, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cache.ServeJSON(w, req, entry, rendering)
+}
@@ -0,0 +1,132 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	apiv2 "github.com/siemens/ghostwire/v2/api/v2"
+	"github.com/siemens/ghostwire/v2/cmd/internal/discache"
+
+	"github.com/gorilla/mux"
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/containerizer"
+)
+
+// registerV2 registers the /v2/... routes and handlers with the route handler
+// plugin mechanism. The discovery results are served from the specified cache,
+// sharing in-flight discoveries between concurrent requests.
+func registerV2(cizer containerizer.Containerizer, cache *discache.Cache) {
+	registerV2List(cizer, cache, "v2-netns", "/v2/netns", func(e *discache.Cached) interface{} {
+		netnses := apiv2.NewNetworkNamespaces(e.Result(), apiv1.WithMetadata(e.Metadata()))
+		return &netnses
+	})
+	registerV2List(cizer, cache, "v2-containers", "/v2/containers", func(e *discache.Cached) interface{} {
+		cntrs := apiv2.NewContainers(e.Result(), apiv1.WithMetadata(e.Metadata()))
+		return &cntrs
+	})
+	registerV2List(cizer, cache, "v2-interfaces", "/v2/interfaces", func(e *discache.Cached) interface{} {
+		nifs := apiv2.NewInterfaces(e.Result(), apiv1.WithMetadata(e.Metadata()))
+		return &nifs
+	})
+	registerV2List(cizer, cache, "v2-engines", "/v2/engines", func(e *discache.Cached) interface{} {
+		engines := apiv2.NewEngines(e.Result(), apiv1.WithMetadata(e.Metadata()))
+		return &engines
+	})
+	registerV2Resource(cizer, cache, "v2-netns-resource", "/v2/netns/{id}", "network namespace",
+		func(result gostwire.DiscoveryResult, id string) interface{} {
+			if netns := apiv2.FindNetworkNamespace(result, id); netns != nil {
+				return netns
+			}
+			return nil
+		})
+	registerV2Resource(cizer, cache, "v2-container-resource", "/v2/containers/{id}", "container",
+		func(result gostwire.DiscoveryResult, id string) interface{} {
+			if cntr := apiv2.FindContainer(result, id); cntr != nil {
+				return cntr
+			}
+			return nil
+		})
+	registerV2Resource(cizer, cache, "v2-interface-resource", "/v2/interfaces/{id}", "network interface",
+		func(result gostwire.DiscoveryResult, id string) interface{} {
+			if nif := apiv2.FindInterface(result, id); nif != nil {
+				return nif
+			}
+			return nil
+		})
+}
+
+// registerV2List registers a v2 list endpoint plugin with the specified name
+// for the specified path, using the list function to render the list from a
+// cached discovery result.
+func registerV2List(
+	cizer containerizer.Containerizer, cache *discache.Cache,
+	name string, path string, list discache.RenderFn,
+) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				path,
+				func(w http.ResponseWriter, req *http.Request) {
+					query := req.URL.Query()
+					opts, err := discoveryOptions(query)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					entry, err := cache.Get(req.Context(), discoveryKey(query), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil, opts...)
+						})
+					if err != nil {
+						return // client gave up.
+					}
+					serveCachedJSON(w, req, cache, entry, path, list)
+				}
+		}, plugger.WithPlugin(name))
+}
+
+// registerV2Resource registers a v2 individual resource endpoint plugin with
+// the specified name for the specified path with an "{id}" variable, using the
+// find function to look up the resource with the requested identifier in a
+// discovery result. Unknown identifiers get a 404 response.
+func registerV2Resource(
+	cizer containerizer.Containerizer, cache *discache.Cache,
+	name string, path string, what string, find func(result gostwire.DiscoveryResult, id string) interface{},
+) {
+	plugger.Group[RouteHandler]().Register(
+		func() (string, string, http.HandlerFunc) {
+			return "GET",
+				path,
+				func(w http.ResponseWriter, req *http.Request) {
+					id := mux.Vars(req)["id"]
+					query := req.URL.Query()
+					opts, err := discoveryOptions(query)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					entry, err := cache.Get(req.Context(), discoveryKey(query), discache.RefreshRequested(req),
+						func(ctx context.Context) gostwire.DiscoveryResult {
+							return discover(ctx, cizer, nil, opts...)
+						})
+					if err != nil {
+						return // client gave up.
+					}
+					resource := find(entry.Result(), id)
+					if resource == nil {
+						http.Error(w, fmt.Sprintf("unknown %s %q", what, id), http.StatusNotFound)
+						return
+					}
+					serveCachedJSON(w, req, cache, entry, req.URL.Path, func(*discache.Cached) interface{} {
+						return resource
+					})
+				}
+		}, plugger.WithPlugin(name))
+}
@@ -0,0 +1,140 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+)
+
+// DiscoverFn runs a discovery, using the specified context.
+type DiscoverFn func(ctx context.Context) gostwire.DiscoveryResult
+
+// Cache caches discovery results per key for a maximum age, with concurrent
+// callers for the same key sharing a single in-flight discovery. A Cache is
+// safe for concurrent use.
+type Cache struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*Cached
+}
+
+// Cached is a cached discovery result, or an in-flight discovery.
+type Cached struct {
+	done   chan struct{}            // closed when discovery has finished.
+	result gostwire.DiscoveryResult // valid only after done.
+	at     time.Time                // when the discovery finished.
+
+	metaOnce sync.Once
+	metadata apiv1.Metadata // metadata of result, created on demand.
+
+	mu         sync.Mutex
+	renderings map[string]*Rendering // cached JSON renderings of result.
+}
+
+// New returns a new Cache keeping discovery results for the specified maximum
+// age. A maximum age of zero disables caching finished discoveries, yet
+// concurrent callers still share in-flight discoveries.
+func New(maxAge time.Duration) *Cache {
+	return &Cache{
+		maxAge:  maxAge,
+		now:     time.Now,
+		entries: map[string]*Cached{},
+	}
+}
+
+// MaxAge returns the maximum age of cached discovery results.
+func (c *Cache) MaxAge() time.Duration {
+	return c.maxAge
+}
+
+// Get returns the discovery result for the specified key, either from the cache
+// or by running the specified discovery function. If refresh is true, then Get
+// ignores any finished discovery result in the cache, but still joins an
+// in-flight discovery for the same key. The discovery runs detached from the
+// specified context's cancellation, as other callers might share it; Get
+// returns early with the context's error when the context is done before the
+// discovery finished.
+func (c *Cache) Get(ctx context.Context, key string, refresh bool, discover DiscoverFn) (*Cached, error) {
+	c.mu.Lock()
+	now := c.now()
+	for k, e := range c.entries {
+		if e.finished() && !c.fresh(e, now) {
+			delete(c.entries, k)
+		}
+	}
+	e, ok := c.entries[key]
+	if !ok || (refresh && e.finished()) {
+		e = &Cached{
+			done:       make(chan struct{}),
+			renderings: map[string]*Rendering{},
+		}
+		c.entries[key] = e
+		go func(ctx context.Context) {
+			result := discover(ctx)
+			c.mu.Lock()
+			e.result = result
+			e.at = c.now()
+			close(e.done)
+			if c.maxAge <= 0 && c.entries[key] == e {
+				delete(c.entries, key)
+			}
+			c.mu.Unlock()
+		}(context.WithoutCancel(ctx))
+	}
+	c.mu.Unlock()
+	select {
+	case <-e.done:
+		return e, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Age returns the age of the cached discovery result.
+func (c *Cache) Age(e *Cached) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now().Sub(e.at)
+}
+
+// fresh returns true if the specified finished entry hasn't yet exceeded the
+// maximum age. The caller must hold the cache lock.
+func (c *Cache) fresh(e *Cached, now time.Time) bool {
+	return now.Sub(e.at) < c.maxAge
+}
+
+// finished returns true if the discovery of this entry has finished.
+func (e *Cached) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result returns the discovery result of this entry.
+func (e *Cached) Result() gostwire.DiscoveryResult {
+	<-e.done
+	return e.result
+}
+
+// Metadata returns the v1 metadata of this entry's discovery result, creating
+// it only once per entry, so that all renderings share the same metadata and
+// the metadata plugins run only once per discovery result.
+func (e *Cached) Metadata() apiv1.Metadata {
+	<-e.done
+	e.metaOnce.Do(func() {
+		e.metadata = apiv1.NewMetadata(e.result)
+	})
+	return e.metadata
+}
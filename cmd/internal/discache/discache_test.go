@@ -0,0 +1,221 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discache
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// fakeNamespace is a model.Namespace that only knows its identifier and
+// reference.
+type fakeNamespace struct {
+	model.Namespace
+	id species.NamespaceID
+}
+
+func (n fakeNamespace) ID() species.NamespaceID { return n.id }
+func (n fakeNamespace) Ref() model.NamespaceRef { return model.NamespaceRef{"/run/netns/fake"} }
+
+// socketResult returns a discovery result with a single network namespace
+// with a single TCP socket, using the specified local port, queue lengths, and
+// round trip time.
+func socketResult(port uint16, queue uint32, rtt time.Duration) DiscoverFn {
+	return func(ctx context.Context) gostwire.DiscoveryResult {
+		netns := &network.NetworkNamespace{
+			Namespace: fakeNamespace{id: species.NamespaceID{Dev: 4, Ino: 4026531840}},
+			Portsv4: []network.ProcessSocket{{
+				Family:    unix.AF_INET,
+				Protocol:  unix.IPPROTO_TCP,
+				LocalIP:   net.ParseIP("127.0.0.1").To4(),
+				LocalPort: port,
+				RecvQueue: queue,
+				SendQueue: queue,
+				TCPInfo:   &network.TCPInfo{RTT: rtt, BytesReceived: uint64(rtt)},
+			}},
+		}
+		return gostwire.DiscoveryResult{
+			Netns: network.NetworkNamespaces{netns.ID(): netns},
+			Lxkns: &discover.Result{},
+		}
+	}
+}
+
+// counting returns a discovery function counting its calls and blocking until
+// the release channel gets closed.
+func counting(calls *int32, release <-chan struct{}) DiscoverFn {
+	return func(ctx context.Context) gostwire.DiscoveryResult {
+		atomic.AddInt32(calls, 1)
+		<-release
+		return gostwire.DiscoveryResult{}
+	}
+}
+
+var _ = Describe("discovery cache", func() {
+
+	var now time.Time
+	var c *Cache
+
+	BeforeEach(func() {
+		now = time.Now()
+		c = New(time.Minute)
+		c.now = func() time.Time { return now }
+	})
+
+	It("coalesces concurrent discoveries", func() {
+		var calls int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		entries := make([]*Cached, 10)
+		for i := range entries {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				entries[i] = Successful(c.Get(context.Background(), "foo", i%2 == 0, counting(&calls, release)))
+			}(i)
+		}
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(1)))
+		close(release)
+		wg.Wait()
+		Expect(calls).To(Equal(int32(1)))
+		for _, e := range entries {
+			Expect(e).To(BeIdenticalTo(entries[0]))
+		}
+	})
+
+	It("caches up to the maximum age and refreshes on demand", func() {
+		var calls int32
+		release := make(chan struct{})
+		close(release)
+		e := Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))
+		Expect(Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))).To(BeIdenticalTo(e))
+		Expect(Successful(c.Get(context.Background(), "bar", false, counting(&calls, release)))).NotTo(BeIdenticalTo(e))
+		Expect(calls).To(Equal(int32(2)))
+
+		e2 := Successful(c.Get(context.Background(), "foo", true, counting(&calls, release)))
+		Expect(e2).NotTo(BeIdenticalTo(e))
+		Expect(calls).To(Equal(int32(3)))
+
+		now = now.Add(time.Minute)
+		Expect(c.Age(e2)).To(Equal(time.Minute))
+		Expect(Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))).NotTo(BeIdenticalTo(e2))
+		Expect(calls).To(Equal(int32(4)))
+	})
+
+	It("doesn't cache with a zero maximum age", func() {
+		c := New(0)
+		var calls int32
+		release := make(chan struct{})
+		close(release)
+		e := Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))
+		Expect(Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))).NotTo(BeIdenticalTo(e))
+		Expect(calls).To(Equal(int32(2)))
+	})
+
+	It("returns early when the caller gives up", func() {
+		var calls int32
+		release := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(c.Get(ctx, "foo", false, counting(&calls, release))).Error().To(MatchError(context.Canceled))
+		close(release)
+		e := Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))
+		Expect(e).NotTo(BeNil())
+		Expect(calls).To(Equal(int32(1)))
+	})
+
+	It("derives keys and refresh requests", func() {
+		Expect(Key("/json", nil)).To(Equal("/json"))
+		Expect(Key("/json", url.Values{"refresh": {""}, "b": {"2"}, "a": {"1"}})).To(Equal("/json?a=1&b=2"))
+
+		req := httptest.NewRequest("GET", "/json", nil)
+		Expect(RefreshRequested(req)).To(BeFalse())
+		req.Header.Set("Cache-Control", "No-Cache")
+		Expect(RefreshRequested(req)).To(BeTrue())
+		Expect(RefreshRequested(httptest.NewRequest("GET", "/json?refresh", nil))).To(BeTrue())
+	})
+
+	It("serves renderings with ETags", func() {
+		release := make(chan struct{})
+		close(release)
+		var calls int32
+		e := Successful(c.Get(context.Background(), "foo", false, counting(&calls, release)))
+		renderings := 0
+		render := func(e *Cached) interface{} {
+			renderings++
+			return map[string]interface{}{
+				"metadata": map[string]interface{}{"renderings": renderings},
+				"foo":      "bar",
+			}
+		}
+		r := Successful(e.Render("json", render))
+		Expect(Successful(e.Render("json", render))).To(BeIdenticalTo(r))
+		r2 := Successful(e.Render("other", render))
+		Expect(r2.Body).NotTo(Equal(r.Body))
+		Expect(r2.ETag).To(Equal(r.ETag))
+		Expect(r.ETag).To(MatchRegexp(`^W/"[0-9a-f]{32}"$`))
+
+		now = now.Add(20 * time.Second)
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, httptest.NewRequest("GET", "/json", nil), e, r)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Cache-Control")).To(Equal("private, max-age=40"))
+		Expect(rec.Header().Get("Age")).To(Equal("20"))
+		Expect(rec.Header().Get("ETag")).To(Equal(r.ETag))
+		Expect(rec.Body.String()).To(MatchJSON(`{"metadata":{"renderings":1},"foo":"bar"}`))
+
+		req := httptest.NewRequest("GET", "/json", nil)
+		req.Header.Set("If-None-Match", `"nope", `+r.ETag[2:])
+		rec = httptest.NewRecorder()
+		c.ServeJSON(rec, req, e, r)
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+		Expect(rec.Body.Len()).To(BeZero())
+	})
+
+	It("tags discoveries differing only in socket statistics the same", func() {
+		render := func(e *Cached) interface{} {
+			result := apiv1.NewDiscoveryResult(e.Result(), apiv1.WithMetadata(apiv1.Metadata{"at": e.at}))
+			return &result
+		}
+		e1 := Successful(c.Get(context.Background(), "1", false, socketResult(80, 1, 100*time.Microsecond)))
+		now = now.Add(time.Second)
+		e2 := Successful(c.Get(context.Background(), "2", false, socketResult(80, 42, 666*time.Microsecond)))
+		r1 := Successful(e1.Render("json", render))
+		r2 := Successful(e2.Render("json", render))
+		Expect(r2.Body).NotTo(Equal(r1.Body))
+		Expect(r2.ETag).To(Equal(r1.ETag))
+
+		e3 := Successful(c.Get(context.Background(), "3", false, socketResult(8080, 1, 100*time.Microsecond)))
+		Expect(Successful(e3.Render("json", render)).ETag).NotTo(Equal(r1.ETag))
+	})
+
+	It("creates metadata only once per entry", func() {
+		e := Successful(c.Get(context.Background(), "foo", false, socketResult(80, 0, 0)))
+		md := e.Metadata()
+		Expect(md).To(HaveKey("creation-timestamp"))
+		md["foo"] = "bar"
+		Expect(e.Metadata()).To(HaveKeyWithValue("foo", "bar"))
+	})
+
+})
@@ -0,0 +1,9 @@
+/*
+Package discache caches discovery results for the Gostwire service, so that
+multiple browser tabs and polling clients don't multiply the cost of
+discoveries. Concurrent requests for the same kind of discovery share a single
+in-flight discovery. Cached discovery results are served as JSON with
+Cache-Control and ETag headers, so that clients can revalidate their copies
+and get “304 Not Modified” responses when nothing changed.
+*/
+package discache
@@ -0,0 +1,123 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discache
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+)
+
+// RefreshQueryParam is the query parameter forcing a fresh discovery.
+const RefreshQueryParam = "refresh"
+
+// Rendering is the JSON rendering of a cached discovery result, together with
+// its entity tag.
+type Rendering struct {
+	Body []byte
+	ETag string
+}
+
+// RenderFn returns the JSON marshallable representation of a discovery result.
+type RenderFn func(e *Cached) interface{}
+
+// Render returns the JSON rendering of this entry's discovery result for the
+// specified representation name, rendering it only once per entry. The entity
+// tag of the rendering covers only the stable projection of the rendering, as
+// returned by apiv1.ContentHash, so that discovery results differing only in
+// their metadata or volatile socket statistics get the same (weak) entity tag.
+func (e *Cached) Render(name string, render RenderFn) (*Rendering, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r, ok := e.renderings[name]; ok {
+		return r, nil
+	}
+	body, err := json.Marshal(render(e))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := apiv1.ContentHash(body)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rendering{
+		Body: append(body, '\n'),
+		ETag: `W/"` + hash + `"`,
+	}
+	e.renderings[name] = r
+	return r, nil
+}
+
+// Key returns the cache key for a discovery request to the specified path and
+// with the specified query parameters, leaving out the refresh query parameter.
+// Representations of the same discovery should use the same key, rendering
+// their metadata using Cached.Metadata, so that the metadata plugins run only
+// once per discovery.
+func Key(path string, query url.Values) string {
+	q := url.Values{}
+	for name, values := range query {
+		if name != RefreshQueryParam {
+			q[name] = values
+		}
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// RefreshRequested returns true if the request asks for a fresh discovery,
+// either using the refresh query parameter or a "Cache-Control: no-cache" or
+// "max-age=0" request header.
+func RefreshRequested(req *http.Request) bool {
+	if _, ok := req.URL.Query()[RefreshQueryParam]; ok {
+		return true
+	}
+	for _, directive := range strings.Split(req.Header.Get("Cache-Control"), ",") {
+		switch strings.TrimSpace(strings.ToLower(directive)) {
+		case "no-cache", "no-store", "max-age=0":
+			return true
+		}
+	}
+	return false
+}
+
+// ServeJSON serves the JSON rendering of a cached discovery result with
+// Cache-Control, Age, and ETag headers, responding with "304 Not Modified"
+// instead if the request's If-None-Match header matches the entity tag.
+func (c *Cache) ServeJSON(w http.ResponseWriter, req *http.Request, e *Cached, r *Rendering) {
+	age := c.Age(e)
+	maxAge := int(math.Max(0, (c.maxAge - age).Seconds()))
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.Header().Set("Age", fmt.Sprintf("%d", int(age.Seconds())))
+	w.Header().Set("ETag", r.ETag)
+	if etagMatch(req.Header.Get("If-None-Match"), r.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(r.Body)
+}
+
+// etagMatch returns true if the If-None-Match header value matches the
+// specified entity tag, using the weak comparison.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
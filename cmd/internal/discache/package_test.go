@@ -0,0 +1,17 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discache
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiscache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ghostwire/cmd/internal/discache package")
+}
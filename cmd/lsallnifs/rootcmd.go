@@ -7,6 +7,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	gostwire "github.com/siemens/ghostwire/v2"
@@ -15,6 +18,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/model"
 	"github.com/thediveo/netdb"
 )
 
@@ -48,6 +52,18 @@ func newRootCmd() (rootCmd *cobra.Command) {
 	rootCmd.PersistentFlags().BoolP(
 		"routes", "r", false,
 		"show routes")
+	rootCmd.PersistentFlags().BoolP(
+		"multicast", "m", false,
+		"show multicast groups and routing")
+	rootCmd.PersistentFlags().BoolP(
+		"sysctls", "s", false,
+		"show deviating sysctls")
+	rootCmd.PersistentFlags().BoolP(
+		"unix", "u", false,
+		"show named and connected unix domain sockets")
+	rootCmd.PersistentFlags().BoolP(
+		"communications", "c", false,
+		"show communications between connected sockets")
 
 	return
 }
@@ -69,6 +85,10 @@ func lsallnifs(cmd *cobra.Command, _ []string) error {
 	showTenants, _ := cmd.PersistentFlags().GetBool("tenants")
 	showPorts, _ := cmd.PersistentFlags().GetBool("ports")
 	showAddrs, _ := cmd.PersistentFlags().GetBool("addresses")
+	showMcast, _ := cmd.PersistentFlags().GetBool("multicast")
+	showSysctls, _ := cmd.PersistentFlags().GetBool("sysctls")
+	showUnix, _ := cmd.PersistentFlags().GetBool("unix")
+	showComms, _ := cmd.PersistentFlags().GetBool("communications")
 	//showRoutes, _ := cmd.PersistentFlags().GetBool("routes")
 
 	log.Debugf("using TurtleFinder")
@@ -129,14 +149,100 @@ func lsallnifs(cmd *cobra.Command, _ []string) error {
 					}
 					localservice := netdb.ServiceByPort(int(port.LocalPort), strings.ToLower(port.Protocol.String()))
 					remoteservice := netdb.ServiceByPort(int(port.RemotePort), strings.ToLower(port.RemoteIP.String()))
-					log.Infof("    %s %s%s %s:%d%s %s:%d%s ↷ %s",
+					log.Infof("    %s %s%s %s:%d%s %s:%d%s ↷ %s%s%s",
 						lc[port.SimplifiedState], port.Protocol.String(), viasock6,
 						network.IP(port.LocalIP).String(), port.LocalPort, serviceList(localservice),
 						network.IP(port.RemoteIP).String(), port.RemotePort, serviceList(remoteservice),
-						strings.Join(nifnames, ", "))
+						strings.Join(nifnames, ", "), ownersText(port.Processes, netns.SocketOwners),
+						foreignOwners(port.ForeignProcesses))
 				}
 			}
 			listPorts(append(netns.Portsv4[:], netns.Portsv6...))
+			for _, endpoint := range netns.MPTCPEndpoints {
+				nifname := "*"
+				if endpoint.Nif != nil {
+					nifname = endpoint.Nif.Nif().Name
+				}
+				log.Infof("    MPTCP endpoint #%d %s:%d [%s] on %s",
+					endpoint.ID, network.IP(endpoint.IP).String(), endpoint.Port,
+					endpoint.Flags.String(), nifname)
+			}
+			for _, conn := range append(netns.MPTCPv4[:], netns.MPTCPv6...) {
+				log.Infof("    %s MPTCP %s:%d %s:%d with %d subflow(s)",
+					lc[conn.SimplifiedState],
+					network.IP(conn.LocalIP).String(), conn.LocalPort,
+					network.IP(conn.RemoteIP).String(), conn.RemotePort,
+					len(conn.Subflows))
+			}
+			for _, packsock := range netns.PacketSockets {
+				nifname := "*"
+				if packsock.Nif != nil {
+					nifname = packsock.Nif.Nif().Name
+				}
+				pids := make([]string, 0, len(packsock.PIDs))
+				for _, pid := range packsock.PIDs {
+					pids = append(pids, strconv.FormatUint(uint64(pid), 10))
+				}
+				log.Infof("    PACKET %s proto 0x%04x on %s ↶ PID(s) %s%s",
+					network.PacketSocketTypeName(packsock.Type), packsock.Protocol,
+					nifname, strings.Join(pids, ", "), foreignOwners(packsock.ForeignProcesses))
+			}
+			for _, bridge := range netns.BridgingProcesses {
+				netnses := make([]string, 0, len(bridge.ForeignNetns))
+				for _, foreign := range bridge.ForeignNetns {
+					netnses = append(netnses, fmt.Sprintf("net:[%d]", foreign.ID().Ino))
+				}
+				log.Infof("    ⇄ %s(%d) holds sockets of %s",
+					bridge.Process.Name, bridge.Process.PID, strings.Join(netnses, ", "))
+			}
+		}
+
+		// Section "Unix Domain Sockets"
+		if showAll || showUnix {
+			log.Infof("  unix domain sockets:")
+			for _, sock := range netns.UnixSockets {
+				if sock.Path == "" && sock.PeerInode == 0 {
+					continue
+				}
+				path := sock.Path
+				if path == "" {
+					path = "(unnamed)"
+				}
+				peer := ""
+				if sock.Peer != nil {
+					peer = fmt.Sprintf(" ↔ %d in net:[%d]", sock.Peer.Inode, sock.Peer.Netns.ID().Ino)
+				}
+				engine := ""
+				if sock.EngineAPI != nil {
+					engine = fmt.Sprintf(" ⚠ %s API", sock.EngineAPI.Type)
+				}
+				log.Infof("    %s %s %s%s%s",
+					lc[sock.SimplifiedState], network.UnixSocketTypeName(sock.Type), path, peer, engine)
+			}
+		}
+
+		// Section "Sysctls"
+		if showAll || showSysctls {
+			log.Infof("  deviating sysctls:")
+			listSysctls("    net.", netns.Sysctls)
+		}
+
+		// Section "Multicast Routing"
+		if showAll || showMcast {
+			for _, mr := range []*network.MulticastRouting{netns.McastRoutingv4, netns.McastRoutingv6} {
+				if mr == nil {
+					continue
+				}
+				log.Infof("  multicast routing:")
+				for _, vif := range mr.VIFs {
+					log.Infof("    VIF %d: %s, in %d pkts, out %d pkts",
+						vif.Index, vif.Name, vif.PktsIn, vif.PktsOut)
+				}
+				for _, route := range mr.Routes {
+					log.Infof("    (%s, %s) iif %d, %d pkts",
+						route.Origin.String(), route.Group.String(), route.InputVIF, route.Packets)
+				}
+			}
 		}
 
 		// Section "Network Interfaces"
@@ -157,7 +263,50 @@ func lsallnifs(cmd *cobra.Command, _ []string) error {
 				nifaddrs := append(nif.Addrsv4, nif.Addrsv6...)
 				nifaddrs.Sort()
 				for _, addr := range nifaddrs {
-					log.Infof("        %s/%d", addr.Address.String(), addr.PrefixLength)
+					details := ""
+					if flags := addr.Flags.Names(addr.Family); len(flags) != 0 {
+						details = " " + strings.Join(flags, " ")
+					}
+					if addr.Origin != network.AddressOriginUnspec {
+						details += " proto " + addr.Origin.String()
+					}
+					log.Infof("        %s/%d%s", addr.Address.String(), addr.PrefixLength, details)
+				}
+			}
+
+			// Deviating per-interface sysctls...
+			if showAll || showSysctls {
+				listSysctls("        ", nif.Sysctls)
+			}
+
+			// Multicast group memberships...
+			if showAll || showMcast {
+				for _, group := range append(nif.McastGroupsv4[:], nif.McastGroupsv6...) {
+					pids := []string{}
+					for _, pid := range group.PIDs {
+						pids = append(pids, strconv.FormatUint(uint64(pid), 10))
+					}
+					owners := ""
+					if len(pids) != 0 {
+						owners = " by PID " + strings.Join(pids, ", ")
+					}
+					log.Infof("        ⊛ %s (%d users)%s", group.Address.String(), group.Users, owners)
+				}
+				if bridge, ok := netif.(network.Bridge); ok && bridge.Bridge().MulticastSnooping {
+					bridge := bridge.Bridge()
+					log.Infof("        snooping, querier %t, IGMPv%d, MLDv%d",
+						bridge.MulticastQuerier, bridge.IGMPVersion, bridge.MLDVersion)
+					for _, entry := range bridge.MulticastDB {
+						group := entry.L2Group.String()
+						if entry.Group != nil {
+							group = entry.Group.String()
+						}
+						port := "?"
+						if entry.Port != nil {
+							port = entry.Port.Nif().Name
+						}
+						log.Infof("        ⊛ %s on port %s", group, port)
+					}
 				}
 			}
 
@@ -217,9 +366,100 @@ func lsallnifs(cmd *cobra.Command, _ []string) error {
 			}
 		}
 	}
+
+	// Section "Communications"
+	if showAll || showComms {
+		log.Infof("communications:")
+		for _, edge := range network.NewCommunicationEdges(allnetns.Netns) {
+			via := ""
+			if fwd := edge.Forwarded; fwd != nil {
+				via = fmt.Sprintf(" via forwarded port %d", fwd.PortMin+edge.Server.Port-fwd.ForwardPortMin)
+			}
+			log.Infof("  %s %s: %s → %s%s",
+				strings.ToLower(edge.Protocol.String()), edge.Kind().String(),
+				endpointText(edge.Client), endpointText(edge.Server), via)
+		}
+	}
 	return nil
 }
 
+// ownersText returns a textual list of the specified socket owners, preferring
+// systemd units over process names, or "" if there are no owners.
+func ownersText(procs []*model.Process, details network.SocketOwners) string {
+	if len(procs) == 0 {
+		return ""
+	}
+	owners := make([]string, 0, len(procs))
+	for _, proc := range procs {
+		name := proc.Name
+		user := ""
+		if d, ok := details[proc.PID]; ok {
+			if d.SystemdUnit != "" {
+				name = d.SystemdUnit
+			}
+			user = d.UserName
+		}
+		if user != "" {
+			user = " as " + user
+		}
+		owners = append(owners, fmt.Sprintf("%s(%d)%s", name, proc.PID, user))
+	}
+	return " ↶ " + strings.Join(owners, ", ")
+}
+
+// foreignOwners returns a textual marker listing the specified foreign socket
+// owners, or "" if there are none.
+func foreignOwners(procs []*model.Process) string {
+	if len(procs) == 0 {
+		return ""
+	}
+	owners := make([]string, 0, len(procs))
+	for _, proc := range procs {
+		owners = append(owners, fmt.Sprintf("%s(%d) in net:[%d]",
+			proc.Name, proc.PID, proc.Namespaces[model.NetNS].ID().Ino))
+	}
+	return " ⇄ foreign " + strings.Join(owners, ", ")
+}
+
+// endpointText returns a textual description of the specified communication
+// endpoint, including its owning processes or otherwise its network namespace.
+func endpointText(ep network.CommunicationEndpoint) string {
+	addr := net.JoinHostPort(ep.IP.String(), strconv.FormatUint(uint64(ep.Port), 10))
+	if ep.External() {
+		return addr + " (external)"
+	}
+	if ep.Socket != nil && len(ep.Socket.Processes) != 0 {
+		owners := []string{}
+		for _, proc := range ep.Socket.Processes {
+			owners = append(owners, fmt.Sprintf("%s(%d)", proc.Name, proc.PID))
+		}
+		return fmt.Sprintf("%s [%s in net:[%d]]", addr, strings.Join(owners, ", "), ep.Netns.ID().Ino)
+	}
+	return fmt.Sprintf("%s [%s]", addr, ep.Netns.DisplayName())
+}
+
+// listSysctls logs the deviating sysctls in alphabetical order, together with
+// the initial namespace and kernel default values, if known.
+func listSysctls(prefix string, sysctls network.Sysctls) {
+	deviations := sysctls.Deviations()
+	names := make([]string, 0, len(deviations))
+	for name := range deviations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sysctl := deviations[name]
+		details := []string{}
+		if sysctl.DiffersFromInitial {
+			details = append(details, "initial "+sysctl.Initial)
+		}
+		if sysctl.DiffersFromDefault {
+			details = append(details, "default "+sysctl.Default)
+		}
+		log.Infof("%s%s = %s (%s)", prefix, name, sysctl.Value, strings.Join(details, ", "))
+	}
+}
+
 func serviceList(s *netdb.Service) string {
 	if s == nil {
 		return ""
@@ -5,6 +5,7 @@
 package all
 
 import (
+	_ "github.com/siemens/ghostwire/v2/decorator/dhcplease"   // activate DHCP lease discovery and correlation with addresses.
 	_ "github.com/siemens/ghostwire/v2/decorator/dockernet"   // activate Docker-managed network alias name decoration.
 	_ "github.com/siemens/ghostwire/v2/decorator/dockerproxy" // activate nerdctl-managed CNI network alias name decoration.
 	_ "github.com/siemens/ghostwire/v2/decorator/ieappicon"   // include (on-demand) IE App icon decoration.
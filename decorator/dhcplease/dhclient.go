@@ -0,0 +1,145 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package dhcplease
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// dhclientLeaseDirs lists the directories where ISC dhclient usually stores its
+// lease files, depending on the Linux distribution.
+var dhclientLeaseDirs = []string{
+	"/var/lib/dhcp",
+	"/var/lib/dhclient",
+	"/var/db",
+}
+
+// networkManagerLeaseDir is the directory where NetworkManager stores the
+// lease files of the dhclient instances it runs.
+const networkManagerLeaseDir = "/var/lib/NetworkManager"
+
+// readDhclientLeases reads the IPv4 leases from the ISC dhclient lease files.
+func readDhclientLeases(lfs leaseFS) []lease {
+	leases := []lease{}
+	for _, dir := range dhclientLeaseDirs {
+		leases = append(leases, readDhclientLeaseDir(lfs, dir, "dhclient")...)
+	}
+	return leases
+}
+
+// readDhclientLeaseDir reads all dhclient IPv4 lease files in the specified
+// directory, attributing them to the specified client.
+func readDhclientLeaseDir(lfs leaseFS, dir string, client string) []lease {
+	entries, err := lfs.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	leases := []lease{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "dhclient") ||
+			!(strings.HasSuffix(name, ".lease") || strings.HasSuffix(name, ".leases")) ||
+			strings.HasPrefix(name, "dhclient6") {
+			continue
+		}
+		filename := path.Join(dir, name)
+		content, err := lfs.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		leases = append(leases, parseDhclientLeases(content, filename, client)...)
+	}
+	return leases
+}
+
+// parseDhclientLeases parses the "lease { ... }" blocks of a dhclient lease
+// file, see also: https://linux.die.net/man/5/dhclient.leases.
+func parseDhclientLeases(content []byte, filename string, client string) []lease {
+	leases := []lease{}
+	var l *network.DHCPLease
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "lease {" {
+			l = &network.DHCPLease{
+				Client:  client,
+				File:    filename,
+				Options: map[string]string{},
+			}
+			continue
+		}
+		if l == nil {
+			continue
+		}
+		if line == "}" {
+			if l.Address != nil {
+				leases = append(leases, lease{DHCPLease: l})
+			}
+			l = nil
+			continue
+		}
+		// Strip any trailing comment first, then the statement-terminating
+		// semicolon.
+		if idx := strings.Index(line, ";"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "interface":
+			l.Interface = strings.Trim(fields[1], `"`)
+		case "fixed-address":
+			l.Address = parseIPv4(fields[1])
+		case "expire":
+			l.Expiry = parseDhclientTime(fields[1:])
+		case "option":
+			if len(fields) < 3 {
+				continue
+			}
+			value := strings.Trim(strings.Join(fields[2:], " "), `"`)
+			switch fields[1] {
+			case "dhcp-server-identifier":
+				l.Server = parseIPv4(value)
+			case "dhcp-lease-time":
+				if secs, err := strconv.ParseUint(value, 10, 32); err == nil {
+					l.LeaseTime = uint32(secs)
+				}
+			default:
+				l.Options[fields[1]] = value
+			}
+		}
+	}
+	return leases
+}
+
+// parseDhclientTime parses dhclient's lease time specifications, which are
+// either in the "W YYYY/MM/DD HH:MM:SS" UTC format or in the "epoch SECONDS"
+// format.
+func parseDhclientTime(fields []string) time.Time {
+	if len(fields) >= 2 && fields[0] == "epoch" {
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		return time.Unix(secs, 0).UTC()
+	}
+	if len(fields) < 3 {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,166 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package dhcplease
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// dhcpcdLeaseDirs lists the directories where dhcpcd usually stores its lease
+// files, depending on the Linux distribution and dhcpcd version.
+var dhcpcdLeaseDirs = []string{
+	"/var/lib/dhcpcd",
+	"/var/lib/dhcpcd5",
+	"/var/db/dhcpcd",
+}
+
+// BOOTP message layout and DHCP option codes, see also RFC 2131 and RFC 2132.
+const (
+	bootpYiaddrOffset  = 16
+	bootpOptionsOffset = 240 // ...including the 4 octet magic cookie.
+
+	dhcpOptPad        = 0
+	dhcpOptSubnetMask = 1
+	dhcpOptRouters    = 3
+	dhcpOptDNS        = 6
+	dhcpOptHostname   = 12
+	dhcpOptDomainName = 15
+	dhcpOptBroadcast  = 28
+	dhcpOptNTP        = 42
+	dhcpOptLeaseTime  = 51
+	dhcpOptServerID   = 54
+	dhcpOptEnd        = 255
+)
+
+// dhcpMagicCookie marks the beginning of DHCP options in BOOTP messages.
+var dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+// readDhcpcdLeases reads the IPv4 leases from dhcpcd lease files. Since dhcpcd
+// 6 the lease files are the raw DHCP ACK messages received from the DHCP
+// servers, so the lease expiry is relative to the modification time of the
+// lease file.
+func readDhcpcdLeases(lfs leaseFS) []lease {
+	leases := []lease{}
+	for _, dir := range dhcpcdLeaseDirs {
+		entries, err := lfs.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".lease") {
+				continue
+			}
+			filename := path.Join(dir, name)
+			content, err := lfs.ReadFile(filename)
+			if err != nil {
+				continue
+			}
+			l := parseBOOTPLease(content)
+			if l == nil {
+				continue
+			}
+			l.Client = "dhcpcd"
+			l.File = filename
+			// Lease files are named "IFNAME.lease", or "IFNAME-SSID.lease"
+			// for wireless interfaces, or "dhcpcd-IFNAME.lease" for older
+			// dhcpcd versions. As interface names might contain "-" too, we
+			// can tell only later when correlating with the network
+			// interfaces whether there's an SSID suffix.
+			l.Interface = strings.TrimPrefix(strings.TrimSuffix(name, ".lease"), "dhcpcd-")
+			if info, err := entry.Info(); err == nil && l.LeaseTime != 0 {
+				l.Expiry = info.ModTime().Add(time.Duration(l.LeaseTime) * time.Second).UTC()
+			}
+			leases = append(leases, lease{DHCPLease: l, suffixed: strings.Contains(l.Interface, "-")})
+		}
+	}
+	return leases
+}
+
+// parseBOOTPLease parses a raw BOOTP/DHCP message and returns the lease
+// information contained in it, or nil if the message is invalid.
+func parseBOOTPLease(msg []byte) *network.DHCPLease {
+	if len(msg) < bootpOptionsOffset ||
+		string(msg[bootpOptionsOffset-4:bootpOptionsOffset]) != string(dhcpMagicCookie) {
+		return nil
+	}
+	yiaddr := net.IP(append([]byte{}, msg[bootpYiaddrOffset:bootpYiaddrOffset+4]...))
+	if yiaddr.IsUnspecified() {
+		return nil
+	}
+	l := &network.DHCPLease{
+		Address: yiaddr,
+		Options: map[string]string{},
+	}
+	opts := msg[bootpOptionsOffset:]
+	for len(opts) > 0 {
+		code := opts[0]
+		if code == dhcpOptPad {
+			opts = opts[1:]
+			continue
+		}
+		if code == dhcpOptEnd || len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			break
+		}
+		value := opts[2 : 2+int(opts[1])]
+		opts = opts[2+int(opts[1]):]
+		switch code {
+		case dhcpOptServerID:
+			if len(value) == net.IPv4len {
+				l.Server = net.IP(append([]byte{}, value...))
+			}
+		case dhcpOptLeaseTime:
+			if len(value) == 4 {
+				l.LeaseTime = binary.BigEndian.Uint32(value)
+			}
+		case dhcpOptSubnetMask:
+			l.Options["subnet-mask"] = ipList(value)
+		case dhcpOptRouters:
+			l.Options["routers"] = ipList(value)
+		case dhcpOptDNS:
+			l.Options["domain-name-servers"] = ipList(value)
+		case dhcpOptNTP:
+			l.Options["ntp-servers"] = ipList(value)
+		case dhcpOptBroadcast:
+			l.Options["broadcast-address"] = ipList(value)
+		case dhcpOptHostname:
+			l.Options["host-name"] = string(value)
+		case dhcpOptDomainName:
+			l.Options["domain-name"] = string(value)
+		default:
+			l.Options["option-"+strconv.Itoa(int(code))] = hexString(value)
+		}
+	}
+	return l
+}
+
+// ipList returns the comma-separated textual representation of the list of
+// IPv4 addresses in the specified option value.
+func ipList(value []byte) string {
+	ips := make([]string, 0, len(value)/net.IPv4len)
+	for len(value) >= net.IPv4len {
+		ips = append(ips, net.IP(value[:net.IPv4len]).String())
+		value = value[net.IPv4len:]
+	}
+	return strings.Join(ips, ",")
+}
+
+// hexString returns the colon-separated, zero-padded hex representation of an
+// option value, such as "0a:ff".
+func hexString(value []byte) string {
+	digits := make([]string, 0, len(value))
+	for _, b := range value {
+		digits = append(digits, hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(digits, ":")
+}
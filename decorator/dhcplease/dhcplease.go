@@ -0,0 +1,259 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package dhcplease
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"strings"
+
+	"github.com/siemens/ghostwire/v2/decorator"
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/exp/slices"
+
+	"github.com/thediveo/go-plugger/v3"
+	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/ops/mountineer"
+)
+
+// Register this Decorator plugin.
+func init() {
+	plugger.Group[decorator.Decorate]().Register(
+		Decorate, plugger.WithPlugin("dhcplease"))
+}
+
+// leaseFS gives access to the files in a tenant's mount namespace; it is
+// satisfied by mountineer.Mountineer as well as network.Files.
+type leaseFS interface {
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+var (
+	_ leaseFS = (*mountineer.Mountineer)(nil)
+	_ leaseFS = (network.Files)(nil)
+)
+
+// lease is a DHCP lease as read from a lease file, together with the index of
+// the network interface it belongs to, if the lease file tells only the index
+// but not the name.
+type lease struct {
+	*network.DHCPLease
+	ifindex  int  // optional network interface index; zero if unknown.
+	suffixed bool // interface name might be followed by "-SSID".
+}
+
+// pinned returns true if the lease unambiguously names its network interface,
+// either by index or name.
+func (l lease) pinned() bool {
+	return l.ifindex != 0 || (l.Interface != "" && !l.suffixed)
+}
+
+// matchesNif returns true if the lease might belong to the specified network
+// interface, and whether this is an exact interface name match.
+func (l lease) matchesNif(nif *network.NifAttrs) (matches bool, exact bool) {
+	if l.ifindex != 0 && l.ifindex != nif.Index {
+		return false, false
+	}
+	switch {
+	case l.Interface == "":
+		return true, false
+	case l.Interface == nif.Name:
+		return true, true
+	case l.suffixed && strings.HasPrefix(l.Interface, nif.Name+"-"):
+		return true, false
+	}
+	return false, false
+}
+
+// leaseReader reads the leases of a particular kind of DHCP client.
+type leaseReader func(lfs leaseFS) []lease
+
+// leaseReaders lists the readers for all supported DHCP clients.
+var leaseReaders = []leaseReader{
+	readDhclientLeases,
+	readDhcpcdLeases,
+	readUdhcpcLeases,
+	readNetworkdLeases,
+	readNetworkManagerLeases,
+}
+
+// Decorate discovers the DHCP leases in the mount namespaces of the tenants of
+// all network namespaces and then correlates them with the addresses of the
+// network interfaces in the respective network namespaces.
+func Decorate(
+	ctx context.Context,
+	allnetns network.NetworkNamespaces,
+	allprocs model.ProcessTable,
+	engines []*model.ContainerEngine,
+) {
+	log.Debugf("discovering DHCP leases")
+	src := network.SourceFromContext(ctx)
+	// Different tenants, even of different network namespaces, might well
+	// share the same mount namespace, so we need to read the lease files only
+	// once per mount namespace. We then correlate the leases with all network
+	// namespaces sharing the mount namespace.
+	mntnses := []model.Namespace{}
+	netnses := map[model.Namespace][]*network.NetworkNamespace{}
+	for _, netns := range allnetns {
+		if !hasDynamicAddresses(netns) {
+			continue
+		}
+		for _, tenant := range netns.Tenants {
+			mntns := tenant.Process.Namespaces[model.MountNS]
+			if mntns == nil {
+				continue
+			}
+			shared, ok := netnses[mntns]
+			if !ok {
+				mntnses = append(mntnses, mntns)
+			}
+			if !slices.Contains(shared, netns) {
+				netnses[mntns] = append(shared, netns)
+			}
+		}
+	}
+	total := 0
+	for _, mntns := range mntnses {
+		tenantfs, closer, err := src.MountFiles(mntns)
+		if err != nil {
+			continue
+		}
+		leases := readLeases(tenantfs)
+		closer()
+		total += correlate(netnses[mntns], leases)
+	}
+	log.Debugf("correlated %d DHCP leases with addresses", total)
+}
+
+// hasDynamicAddresses returns true if there is at least one network interface
+// in the specified network namespace with an IPv4 address that could have been
+// leased, that is, neither a loopback nor a link-local address.
+func hasDynamicAddresses(netns *network.NetworkNamespace) bool {
+	for _, nif := range netns.Nifs {
+		for _, addr := range nif.Nif().Addrsv4 {
+			if !addr.Address.IsLoopback() && !addr.Address.IsLinkLocalUnicast() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readLeases returns the leases of all supported DHCP clients found in the
+// specified file system.
+func readLeases(lfs leaseFS) []lease {
+	leases := []lease{}
+	for _, reader := range leaseReaders {
+		leases = append(leases, reader(lfs)...)
+	}
+	return leases
+}
+
+// leasedAddr is a candidate address of a network interface a lease might
+// belong to.
+type leasedAddr struct {
+	nif   *network.NifAttrs
+	addr  *network.Address
+	exact bool
+}
+
+// correlate attaches copies of the specified leases to the matching addresses
+// of the network interfaces in the specified network namespaces, leaving the
+// specified leases untouched. The copies always name the network interfaces
+// they're attached to, even if the lease files didn't. Expired leases are
+// ignored. Leases not telling their network interface are only attached if
+// they match a single address. If multiple leases match the same address, such
+// as historic leases still present in lease files, then the lease with the
+// latest expiry wins. correlate returns the number of leases attached.
+func correlate(netnses []*network.NetworkNamespace, leases []lease) int {
+	count := 0
+	for _, l := range leases {
+		ip := l.Address.To4()
+		if ip == nil || l.Expired() {
+			continue
+		}
+		candidates := l.candidates(netnses, ip)
+		if len(candidates) == 0 {
+			continue
+		}
+		if len(candidates) > 1 && !l.pinned() {
+			log.Debugf("ignoring ambiguous DHCP lease for address %s from %s via %s",
+				ip.String(), l.Server, l.Client)
+			continue
+		}
+		attached := false
+		for _, c := range candidates {
+			if c.addr.Lease != nil && !l.Expiry.After(c.addr.Lease.Expiry) {
+				continue
+			}
+			// Attach a copy of the lease, as the same lease might get
+			// attached to addresses of different network interfaces, such
+			// as in different network namespaces sharing the same mount
+			// namespace, and we complete the copy's interface name.
+			leased := *l.DHCPLease
+			if leased.Interface == "" || l.suffixed {
+				leased.Interface = c.nif.Name
+			}
+			c.addr.Lease = &leased
+			attached = true
+			log.Debugf("address %s of nif %s leased from %s via %s",
+				ip.String(), c.nif.Name, l.Server, l.Client)
+		}
+		if attached {
+			count++
+		}
+	}
+	return count
+}
+
+// candidates returns the addresses of the network interfaces in the specified
+// network namespaces the lease for the specified IPv4 address might belong to.
+// If the lease's interface name might carry an SSID suffix, then exact
+// interface name matches take precedence.
+func (l lease) candidates(netnses []*network.NetworkNamespace, ip net.IP) []leasedAddr {
+	candidates := []leasedAddr{}
+	exacts := 0
+	for _, netns := range netnses {
+		for _, nif := range netns.Nifs {
+			nifattrs := nif.Nif()
+			matches, exact := l.matchesNif(nifattrs)
+			if !matches {
+				continue
+			}
+			for idx := range nifattrs.Addrsv4 {
+				addr := &nifattrs.Addrsv4[idx]
+				if !addr.Address.Equal(ip) {
+					continue
+				}
+				candidates = append(candidates, leasedAddr{nif: nifattrs, addr: addr, exact: exact})
+				if exact {
+					exacts++
+				}
+			}
+		}
+	}
+	if l.suffixed && exacts > 0 && exacts < len(candidates) {
+		exactcandidates := make([]leasedAddr, 0, exacts)
+		for _, c := range candidates {
+			if c.exact {
+				exactcandidates = append(exactcandidates, c)
+			}
+		}
+		return exactcandidates
+	}
+	return candidates
+}
+
+// parseIPv4 returns the specified IPv4 address in its 4 byte form, or nil.
+func parseIPv4(s string) net.IP {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	return ip.To4()
+}
@@ -0,0 +1,287 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package dhcplease
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/network"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// dirFS is a leaseFS rooted at a (temporary) directory.
+type dirFS string
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), name))
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.Join(string(d), name))
+}
+
+const dhclientLeases = `lease {
+  interface "eth0";
+  fixed-address 192.168.1.10;
+  option subnet-mask 255.255.255.0;
+  option routers 192.168.1.1;
+  option dhcp-lease-time 600;
+  option dhcp-server-identifier 192.168.1.2;
+  option domain-name "example.org";
+  renew 2 2023/01/03 10:00:00;
+  expire 2 2023/01/03 12:00:00;
+}
+lease {
+  interface "eth0";
+  fixed-address 192.168.1.10;
+  option dhcp-lease-time 86400;
+  option dhcp-server-identifier 192.168.1.1;
+  expire epoch 1893456000; # Mon Jan 01 00:00:00 2030
+}
+`
+
+const networkdLease = `# This is private data. Do not parse.
+ADDRESS=10.0.0.42
+NETMASK=255.255.0.0
+ROUTER=10.0.0.1
+SERVER_ADDRESS=10.0.0.1
+LIFETIME=3600
+DNS=10.0.0.53
+`
+
+const nmDevice = `[device]
+managed=true
+
+[dhcp4]
+ip_address=172.16.0.5
+dhcp_server_identifier=172.16.0.1
+dhcp_lease_time=7200
+expiry=1893456000
+routers=172.16.0.1
+`
+
+const udhcpcLease = `interface=wlan0
+ip=10.1.2.3
+subnet=255.255.255.0
+serverid=10.1.2.1
+lease=1200
+`
+
+// bootpMessage returns a minimal DHCP ACK message for the specified yiaddr,
+// server and lease time.
+func bootpMessage(yiaddr, server net.IP, leasetime uint32) []byte {
+	msg := make([]byte, bootpOptionsOffset)
+	copy(msg[bootpYiaddrOffset:], yiaddr.To4())
+	copy(msg[bootpOptionsOffset-4:], dhcpMagicCookie)
+	lt := make([]byte, 4)
+	binary.BigEndian.PutUint32(lt, leasetime)
+	msg = append(msg, dhcpOptPad)
+	msg = append(msg, dhcpOptServerID, 4)
+	msg = append(msg, server.To4()...)
+	msg = append(msg, dhcpOptLeaseTime, 4)
+	msg = append(msg, lt...)
+	msg = append(msg, dhcpOptRouters, 8, 10, 9, 0, 1, 10, 9, 0, 2)
+	msg = append(msg, dhcpOptDomainName, 3, 'l', 'a', 'n')
+	msg = append(msg, 224, 2, 0xca, 0xfe)
+	msg = append(msg, dhcpOptEnd)
+	return msg
+}
+
+func ifindex(l lease) int { return l.ifindex }
+
+func writeFile(root string, name string, content []byte) {
+	GinkgoHelper()
+	path := filepath.Join(root, name)
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	Expect(os.WriteFile(path, content, 0644)).To(Succeed())
+}
+
+var _ = Describe("DHCP leases", func() {
+
+	It("parses dhclient leases", func() {
+		leases := parseDhclientLeases([]byte(dhclientLeases), "/var/lib/dhcp/dhclient.leases", "dhclient")
+		Expect(leases).To(HaveLen(2))
+		Expect(*leases[0].DHCPLease).To(MatchFields(IgnoreExtras, Fields{
+			"Client":    Equal("dhclient"),
+			"Interface": Equal("eth0"),
+			"Address":   Equal(net.ParseIP("192.168.1.10").To4()),
+			"Server":    Equal(net.ParseIP("192.168.1.2").To4()),
+			"LeaseTime": Equal(uint32(600)),
+			"Expiry":    Equal(time.Date(2023, 1, 3, 12, 0, 0, 0, time.UTC)),
+			"Options": And(
+				HaveKeyWithValue("routers", "192.168.1.1"),
+				HaveKeyWithValue("domain-name", "example.org")),
+		}))
+		Expect(leases[1].Expiry).To(Equal(time.Unix(1893456000, 0).UTC()))
+	})
+
+	It("parses dhcpcd BOOTP leases", func() {
+		Expect(parseBOOTPLease([]byte{1, 2, 3})).To(BeNil())
+		l := parseBOOTPLease(bootpMessage(net.ParseIP("10.9.0.42"), net.ParseIP("10.9.0.1"), 3600))
+		Expect(l).NotTo(BeNil())
+		Expect(l.Address.String()).To(Equal("10.9.0.42"))
+		Expect(l.Server.String()).To(Equal("10.9.0.1"))
+		Expect(l.LeaseTime).To(Equal(uint32(3600)))
+		Expect(l.Options).To(And(
+			HaveKeyWithValue("routers", "10.9.0.1,10.9.0.2"),
+			HaveKeyWithValue("domain-name", "lan"),
+			HaveKeyWithValue("option-224", "ca:fe")))
+		Expect(hexString([]byte{0x0a, 0x00, 0xff})).To(Equal("0a:00:ff"))
+	})
+
+	It("parses ini and env-style files", func() {
+		Expect(parseEnvStyle([]byte(nmDevice), "dhcp4")).To(And(
+			HaveKeyWithValue("ip_address", "172.16.0.5"),
+			Not(HaveKey("managed"))))
+		Expect(parseEnvStyle([]byte("A='1'\nB=\"2\"\n#C=3\nD"), "")).To(Equal(
+			map[string]string{"A": "1", "B": "2"}))
+	})
+
+	It("reads leases of all supported DHCP clients", func() {
+		root := GinkgoT().TempDir()
+		writeFile(root, "/var/lib/dhcp/dhclient.eth0.leases", []byte(dhclientLeases))
+		writeFile(root, "/var/lib/dhcp/dhclient6.eth0.leases", []byte(dhclientLeases))
+		writeFile(root, "/var/lib/dhcpcd/veth-x.lease",
+			bootpMessage(net.ParseIP("10.9.0.42"), net.ParseIP("10.9.0.1"), 3600))
+		writeFile(root, "/run/systemd/netif/leases/3", []byte(networkdLease))
+		writeFile(root, "/run/NetworkManager/devices/4", []byte(nmDevice))
+		writeFile(root, "/run/udhcpc/wlan0.env", []byte(udhcpcLease))
+
+		leases := readLeases(dirFS(root))
+		Expect(leases).To(HaveLen(6))
+		Expect(leases).To(ContainElements(
+			HaveField("DHCPLease.Client", "dhclient"),
+			And(HaveField("DHCPLease.Client", "dhcpcd"), HaveField("DHCPLease.Interface", "veth-x")),
+			And(HaveField("DHCPLease.Client", "systemd-networkd"), WithTransform(ifindex, Equal(3))),
+			And(HaveField("DHCPLease.Client", "NetworkManager"), WithTransform(ifindex, Equal(4))),
+			And(HaveField("DHCPLease.Client", "udhcpc"), HaveField("DHCPLease.Interface", "wlan0")),
+		))
+		for _, l := range leases {
+			if l.Client == "dhcpcd" || l.Client == "systemd-networkd" {
+				Expect(l.Expiry).To(BeTemporally(">", time.Now()))
+			}
+		}
+	})
+
+	It("correlates leases with addresses", func() {
+		eth0 := &network.NifAttrs{
+			Name:  "eth0",
+			Index: 2,
+			Addrsv4: network.Addresses{
+				{Family: unix.AF_INET, Address: net.ParseIP("192.168.1.10").To4(), Index: 2},
+				{Family: unix.AF_INET, Address: net.ParseIP("192.168.1.11").To4(), Index: 2},
+			},
+		}
+		eth1 := &network.NifAttrs{
+			Name:  "eth1",
+			Index: 3,
+			Addrsv4: network.Addresses{
+				{Family: unix.AF_INET, Address: net.ParseIP("10.0.0.42").To4(), Index: 3},
+			},
+		}
+		netns := &network.NetworkNamespace{
+			Nifs: map[int]network.Interface{2: eth0, 3: eth1},
+		}
+		Expect(hasDynamicAddresses(netns)).To(BeTrue())
+
+		leases := parseDhclientLeases([]byte(dhclientLeases), "dhclient.leases", "dhclient")
+		leases = append(leases,
+			lease{DHCPLease: &network.DHCPLease{Client: "systemd-networkd", Address: net.ParseIP("10.0.0.42")}, ifindex: 3},
+			lease{DHCPLease: &network.DHCPLease{Client: "systemd-networkd", Address: net.ParseIP("10.0.0.42")}, ifindex: 2},
+			lease{DHCPLease: &network.DHCPLease{Client: "dhcpcd", Address: net.ParseIP("192.168.1.11"), Interface: "eth1"}},
+		)
+		Expect(correlate([]*network.NetworkNamespace{netns}, leases)).To(Equal(2))
+
+		Expect(eth0.Addrsv4[0].Lease).NotTo(BeNil())
+		Expect(eth0.Addrsv4[0].Lease.Server.String()).To(Equal("192.168.1.1")) // latest lease wins
+		Expect(eth0.Addrsv4[0].Lease.Expired()).To(BeFalse())
+		Expect(eth0.Addrsv4[1].Lease).To(BeNil())
+		Expect(eth1.Addrsv4[0].Lease).NotTo(BeNil())
+		Expect(eth1.Addrsv4[0].Lease.Interface).To(Equal("eth1"))
+	})
+
+	It("ignores expired and ambiguous leases", func() {
+		nifWithAddr := func(netns *network.NetworkNamespace, name string, index int, ip string) *network.NifAttrs {
+			nif := &network.NifAttrs{
+				Netns: netns,
+				Name:  name,
+				Index: index,
+				Addrsv4: network.Addresses{
+					{Family: unix.AF_INET, Address: net.ParseIP(ip).To4(), Index: index},
+				},
+			}
+			if netns.Nifs == nil {
+				netns.Nifs = map[int]network.Interface{}
+			}
+			netns.Nifs[index] = nif
+			return nif
+		}
+		netns1 := &network.NetworkNamespace{}
+		netns2 := &network.NetworkNamespace{}
+		eth0 := nifWithAddr(netns1, "eth0", 2, "10.0.0.1")
+		cntreth0 := nifWithAddr(netns2, "eth0", 2, "10.0.0.1")
+		vethx := nifWithAddr(netns1, "veth-x", 3, "10.0.1.1")
+		veth := nifWithAddr(netns1, "veth", 4, "10.0.1.1")
+		wlan0 := nifWithAddr(netns1, "wlan0", 5, "10.0.2.1")
+		unique := nifWithAddr(netns2, "eth1", 3, "10.0.3.1")
+
+		future := time.Now().Add(time.Hour)
+		netnses := []*network.NetworkNamespace{netns1, netns2}
+		Expect(correlate(netnses, []lease{
+			{DHCPLease: &network.DHCPLease{Client: "udhcpc", Address: net.ParseIP("10.0.0.1"), Expiry: future}},
+			{DHCPLease: &network.DHCPLease{Client: "udhcpc", Address: net.ParseIP("10.0.3.1"),
+				Expiry: time.Now().Add(-time.Hour)}},
+		})).To(BeZero())
+		Expect(eth0.Addrsv4[0].Lease).To(BeNil())
+		Expect(cntreth0.Addrsv4[0].Lease).To(BeNil())
+		Expect(unique.Addrsv4[0].Lease).To(BeNil())
+
+		leases := []lease{
+			{DHCPLease: &network.DHCPLease{Client: "udhcpc", Address: net.ParseIP("10.0.3.1"), Expiry: future}},
+			{DHCPLease: &network.DHCPLease{Client: "dhcpcd", Address: net.ParseIP("10.0.1.1"), Interface: "veth-x"},
+				suffixed: true},
+			{DHCPLease: &network.DHCPLease{Client: "dhcpcd", Address: net.ParseIP("10.0.2.1"), Interface: "wlan0-MySSID"},
+				suffixed: true},
+		}
+		Expect(correlate(netnses, leases)).To(Equal(3))
+		Expect(leases[0].Interface).To(BeEmpty(), "must not modify the leases read")
+		Expect(leases[2].Interface).To(Equal("wlan0-MySSID"), "must not modify the leases read")
+		Expect(unique.Addrsv4[0].Lease).NotTo(BeNil())
+		Expect(unique.Addrsv4[0].Lease.Interface).To(Equal("eth1"))
+		Expect(vethx.Addrsv4[0].Lease).NotTo(BeNil())
+		Expect(veth.Addrsv4[0].Lease).To(BeNil())
+		Expect(wlan0.Addrsv4[0].Lease).NotTo(BeNil())
+		Expect(wlan0.Addrsv4[0].Lease.Interface).To(Equal("wlan0"))
+	})
+
+	It("attaches separate lease copies to separate network interfaces", func() {
+		netns1 := &network.NetworkNamespace{}
+		netns2 := &network.NetworkNamespace{}
+		eth0 := &network.NifAttrs{Netns: netns1, Name: "eth0", Index: 2, Addrsv4: network.Addresses{
+			{Family: unix.AF_INET, Address: net.ParseIP("10.0.0.1").To4(), Index: 2}}}
+		eth1 := &network.NifAttrs{Netns: netns2, Name: "eth1", Index: 2, Addrsv4: network.Addresses{
+			{Family: unix.AF_INET, Address: net.ParseIP("10.0.0.1").To4(), Index: 2}}}
+		netns1.Nifs = map[int]network.Interface{2: eth0}
+		netns2.Nifs = map[int]network.Interface{2: eth1}
+
+		l := lease{DHCPLease: &network.DHCPLease{Client: "networkd", Address: net.ParseIP("10.0.0.1"),
+			Expiry: time.Now().Add(time.Hour)}, ifindex: 2}
+		Expect(correlate([]*network.NetworkNamespace{netns1, netns2}, []lease{l})).To(Equal(1))
+		Expect(eth0.Addrsv4[0].Lease).To(HaveField("Interface", "eth0"))
+		Expect(eth1.Addrsv4[0].Lease).To(HaveField("Interface", "eth1"))
+		Expect(eth0.Addrsv4[0].Lease).NotTo(BeIdenticalTo(eth1.Addrsv4[0].Lease))
+		Expect(l.Interface).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,28 @@
+/*
+Package dhcplease implements a Gostwire decorator that discovers DHCP leases and
+correlates them with the IP addresses assigned to the network interfaces of
+network namespaces. This tells whether an address inside a container or on the
+host was obtained via DHCP, and from which DHCP server.
+
+The decorator reads the lease files through the mount namespaces of the tenants
+of each network namespace, so it sees the lease files of DHCP clients running
+inside containers as well as on the host. The following DHCP clients are
+supported:
+
+  - ISC dhclient: /var/lib/dhcp/, /var/lib/dhclient/, and /var/db/.
+  - dhcpcd: /var/lib/dhcpcd/, /var/lib/dhcpcd5/, and /var/db/dhcpcd/; these
+    lease files are raw (binary) BOOTP/DHCP messages.
+  - busybox udhcpc: as udhcpc itself doesn't persist leases, only lease dumps
+    in form of the udhcpc script environment variables (such as "ip=",
+    "serverid=", "lease=") in /var/lib/udhcpc/ and /run/udhcpc/ are
+    supported.
+  - systemd-networkd: /run/systemd/netif/leases/.
+  - NetworkManager: the device state files in /run/NetworkManager/devices/ as
+    well as the dhclient lease files in /var/lib/NetworkManager/.
+
+Only IPv4 DHCP leases are supported. Expired leases are ignored. Leases that
+tell neither the name nor the index of their network interface are only
+correlated if they match exactly one address. Each address gets its own copy
+of the lease, naming the network interface the address is assigned to.
+*/
+package dhcplease
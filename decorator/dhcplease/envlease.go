@@ -0,0 +1,226 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package dhcplease
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// networkdLeaseDir is the directory where systemd-networkd stores its DHCP
+// leases, in files named after the interface indices.
+const networkdLeaseDir = "/run/systemd/netif/leases"
+
+// udhcpcLeaseDirs lists the directories where udhcpc scripts are expected to
+// dump the udhcpc environment variables of a lease.
+var udhcpcLeaseDirs = []string{
+	"/var/lib/udhcpc",
+	"/run/udhcpc",
+}
+
+// networkManagerDevicesDir is the directory where NetworkManager stores the
+// state of devices, including DHCP options, in files named after the interface
+// indices.
+const networkManagerDevicesDir = "/run/NetworkManager/devices"
+
+// readNetworkdLeases reads the systemd-networkd leases, which are in
+// environment-style "KEY=value" format.
+func readNetworkdLeases(lfs leaseFS) []lease {
+	entries, err := lfs.ReadDir(networkdLeaseDir)
+	if err != nil {
+		return nil
+	}
+	leases := []lease{}
+	for _, entry := range entries {
+		ifindex, err := strconv.Atoi(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		filename := path.Join(networkdLeaseDir, entry.Name())
+		content, err := lfs.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		vars := parseEnvStyle(content, "")
+		l := &network.DHCPLease{
+			Client:  "systemd-networkd",
+			File:    filename,
+			Address: parseIPv4(vars["ADDRESS"]),
+			Server:  parseIPv4(vars["SERVER_ADDRESS"]),
+			Options: map[string]string{},
+		}
+		if l.Address == nil {
+			continue
+		}
+		if secs, err := strconv.ParseUint(vars["LIFETIME"], 10, 32); err == nil {
+			l.LeaseTime = uint32(secs)
+			if info, err := entry.Info(); err == nil {
+				l.Expiry = info.ModTime().Add(time.Duration(secs) * time.Second).UTC()
+			}
+		}
+		copyOptions(l.Options, vars, map[string]string{
+			"NETMASK":    "subnet-mask",
+			"ROUTER":     "routers",
+			"DNS":        "domain-name-servers",
+			"NTP":        "ntp-servers",
+			"DOMAINNAME": "domain-name",
+			"HOSTNAME":   "host-name",
+			"T1":         "renewal-time",
+			"T2":         "rebinding-time",
+		})
+		leases = append(leases, lease{DHCPLease: l, ifindex: ifindex})
+	}
+	return leases
+}
+
+// readUdhcpcLeases reads lease dumps in form of the environment variables
+// passed by busybox's udhcpc to its script, in "key=value" format.
+func readUdhcpcLeases(lfs leaseFS) []lease {
+	leases := []lease{}
+	for _, dir := range udhcpcLeaseDirs {
+		entries, err := lfs.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			filename := path.Join(dir, entry.Name())
+			content, err := lfs.ReadFile(filename)
+			if err != nil {
+				continue
+			}
+			vars := parseEnvStyle(content, "")
+			l := &network.DHCPLease{
+				Client:    "udhcpc",
+				File:      filename,
+				Interface: vars["interface"],
+				Address:   parseIPv4(vars["ip"]),
+				Server:    parseIPv4(vars["serverid"]),
+				Options:   map[string]string{},
+			}
+			if l.Address == nil {
+				continue
+			}
+			if l.Interface == "" {
+				l.Interface = strings.SplitN(entry.Name(), ".", 2)[0]
+			}
+			if secs, err := strconv.ParseUint(vars["lease"], 10, 32); err == nil {
+				l.LeaseTime = uint32(secs)
+				if info, err := entry.Info(); err == nil {
+					l.Expiry = info.ModTime().Add(time.Duration(secs) * time.Second).UTC()
+				}
+			}
+			copyOptions(l.Options, vars, map[string]string{
+				"subnet":    "subnet-mask",
+				"router":    "routers",
+				"dns":       "domain-name-servers",
+				"ntpsrv":    "ntp-servers",
+				"domain":    "domain-name",
+				"hostname":  "host-name",
+				"broadcast": "broadcast-address",
+			})
+			leases = append(leases, lease{DHCPLease: l})
+		}
+	}
+	return leases
+}
+
+// readNetworkManagerLeases reads the DHCP information from NetworkManager's
+// device state files, as well as the leases from dhclient instances run by
+// NetworkManager.
+func readNetworkManagerLeases(lfs leaseFS) []lease {
+	leases := readDhclientLeaseDir(lfs, networkManagerLeaseDir, "NetworkManager")
+	entries, err := lfs.ReadDir(networkManagerDevicesDir)
+	if err != nil {
+		return leases
+	}
+	for _, entry := range entries {
+		ifindex, err := strconv.Atoi(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		filename := path.Join(networkManagerDevicesDir, entry.Name())
+		content, err := lfs.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		vars := parseEnvStyle(content, "dhcp4")
+		l := &network.DHCPLease{
+			Client:  "NetworkManager",
+			File:    filename,
+			Address: parseIPv4(vars["ip_address"]),
+			Server:  parseIPv4(vars["dhcp_server_identifier"]),
+			Options: map[string]string{},
+		}
+		if l.Address == nil {
+			continue
+		}
+		if secs, err := strconv.ParseUint(vars["dhcp_lease_time"], 10, 32); err == nil {
+			l.LeaseTime = uint32(secs)
+		}
+		if expiry, err := strconv.ParseInt(vars["expiry"], 10, 64); err == nil {
+			l.Expiry = time.Unix(expiry, 0).UTC()
+		}
+		copyOptions(l.Options, vars, map[string]string{
+			"subnet_mask":         "subnet-mask",
+			"routers":             "routers",
+			"domain_name_servers": "domain-name-servers",
+			"ntp_servers":         "ntp-servers",
+			"domain_name":         "domain-name",
+			"host_name":           "host-name",
+			"broadcast_address":   "broadcast-address",
+		})
+		leases = append(leases, lease{DHCPLease: l, ifindex: ifindex})
+	}
+	return leases
+}
+
+// parseEnvStyle parses "key=value" lines, optionally only inside the
+// specified "[section]" of an ini-style file. Values can be optionally
+// enclosed in single or double quotes.
+func parseEnvStyle(content []byte, section string) map[string]string {
+	vars := map[string]string{}
+	insection := section == ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if section != "" {
+				insection = line[1:len(line)-1] == section
+			}
+			continue
+		}
+		if !insection {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		vars[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return vars
+}
+
+// copyOptions copies the variables found in the specified name mapping into
+// the options map, using the mapped option names.
+func copyOptions(options map[string]string, vars map[string]string, names map[string]string) {
+	for key, name := range names {
+		if value, ok := vars[key]; ok && value != "" {
+			options[name] = value
+		}
+	}
+}
@@ -6,10 +6,11 @@ package dockernet
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"github.com/siemens/ghostwire/v2/decorator"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 	"github.com/siemens/ghostwire/v2/network"
 
 	"github.com/docker/docker/api/types"
@@ -17,7 +18,6 @@ import (
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
-	"github.com/thediveo/lxkns/ops"
 	"github.com/thediveo/whalewatcher/engineclient/moby"
 )
 
@@ -66,28 +66,50 @@ type dockerNetworks struct {
 // dockerNetworks object will be returned instead, to be used in the engine map
 // to signal that we asked the engine, but it failed, so no more attempts to
 // talk to it, please.
-func makeDockerNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces) (
+func makeDockerNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces, allprocs model.ProcessTable) (
 	docknets dockerNetworks,
 ) {
-	dockerclient, err := client.NewClientWithOpts(
-		client.WithHost(engine.API),
-		client.WithAPIVersionNegotiation())
+	// Skip engines that failed repeatedly before for a while, so a hung engine
+	// API doesn't slow down every discovery.
+	breakerKey := "dockernet/" + engine.API
+	if !guard.Breakers.Allow(breakerKey) {
+		diagnostics.FromContext(ctx).Warnf(diagnostics.PluginSuspended, diagnostics.Scope{Plugin: "dockernet"},
+			"skipping Docker engine API %s after repeated failures", engine.API)
+		return
+	}
+	dockerclient, err := newDockerClient(ctx, engine.API)
 	if err != nil {
-		log.Warnf("cannot discover Docker-managed networks from API %s, reason: %s",
+		diagnostics.FromContext(ctx).Warnf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "dockernet"},
+			"cannot discover Docker-managed networks from API %s, reason: %s",
 			engine.API, err.Error())
+		guard.Breakers.Failure(breakerKey)
 		return
 	}
-	networks, _ := dockerclient.NetworkList(ctx, types.NetworkListOptions{})
+	networks, err := dockerclient.NetworkList(ctx, types.NetworkListOptions{})
 	_ = dockerclient.Close()
-	netnsid, _ := ops.NamespacePath(fmt.Sprintf("/proc/%d/ns/net", engine.PID)).ID()
+	if err != nil {
+		diagnostics.FromContext(ctx).Warnf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "dockernet"},
+			"cannot list Docker-managed networks from API %s, reason: %s",
+			engine.API, err.Error())
+	}
+	guard.Breakers.Record(breakerKey, err)
 	docknets.networks = networks
 	docknets.engine = engine
-	docknets.engineNetns = allnetns[netnsid]
+	docknets.engineNetns = network.EngineNetns(engine, allnetns, allprocs)
 	log.Infof("found %d Docker networks related to net:[%d] %s",
 		len(networks), docknets.engineNetns.ID().Ino, docknets.engineNetns.DisplayName())
 	return
 }
 
+// newDockerClient returns a new Docker client for the specified engine API,
+// talking to the engine through the discovery source carried in the context.
+func newDockerClient(ctx context.Context, api string) (*client.Client, error) {
+	return client.NewClientWithOpts(
+		client.WithHost(api),
+		client.WithHTTPClient(network.SourceFromContext(ctx).EngineClient(api)),
+		client.WithAPIVersionNegotiation())
+}
+
 // Decorate decorates bridge and macvlan master network interfaces with alias
 // names that are the names of their corresponding Docker “bridge” or “macvlan”
 // networks, where applicable (a copy is stored also in the labels in Gostwire's
@@ -110,7 +132,7 @@ func Decorate(
 		if engine.Type != moby.Type {
 			continue
 		}
-		dockerNets[engine.PID] = makeDockerNetworks(ctx, engine, allnetns)
+		dockerNets[engine.PID] = makeDockerNetworks(ctx, engine, allnetns, allprocs)
 	}
 	// Now that we know about the Docker networks, try to locate the matching
 	// Linux-kernel network interfaces so we can set/override the alias names of
@@ -190,16 +212,15 @@ func Decorate(
 				// passthrough network.
 				if !retrievedDetails {
 					retrievedDetails = true
-					dockerclient, err := client.NewClientWithOpts(
-						client.WithHost(docknet.engine.API),
-						client.WithAPIVersionNegotiation())
+					dockerclient, err := newDockerClient(ctx, docknet.engine.API)
 					if err == nil {
 						netwDetails, err := dockerclient.NetworkInspect(ctx, netw.ID, types.NetworkInspectOptions{})
 						_ = dockerclient.Close()
 						if err == nil {
 							netw = netwDetails
 						} else {
-							log.Errorf("cannot inspect Docker network %s more closely, reason: %s",
+							diagnostics.FromContext(ctx).Errorf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "dockernet"},
+								"cannot inspect Docker network %s more closely, reason: %s",
 								netw.Name, err.Error())
 						}
 					}
@@ -7,19 +7,17 @@ package nerdctlnet
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"path/filepath"
 
 	"github.com/siemens/ghostwire/v2/decorator"
 	"github.com/siemens/ghostwire/v2/decorator/dockernet"
+	"github.com/siemens/ghostwire/v2/diagnostics"
 	"github.com/siemens/ghostwire/v2/network"
 
 	"github.com/containernetworking/cni/libcni"
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
-	"github.com/thediveo/lxkns/ops"
-	"github.com/thediveo/lxkns/ops/mountineer"
 	"github.com/thediveo/whalewatcher/watcher/containerd"
 )
 
@@ -103,35 +101,47 @@ func (n *nerdctlNetwork) PluginField(typ string, field string) string {
 
 // newNerdctlNetworks returns configuration information about the
 // nerdctl-managed networks for the specified containerd engine.
-func newNerdctlNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces) nerdctlNetworks {
-	netnsid, _ := ops.NamespacePath(fmt.Sprintf("/proc/%d/ns/net", engine.PID)).ID()
+func newNerdctlNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces, allprocs model.ProcessTable) nerdctlNetworks {
 	nerdynets := nerdctlNetworks{
-		engineNetns: allnetns[netnsid],
+		engineNetns: network.EngineNetns(engine, allnetns, allprocs),
 		networks:    []nerdctlNetwork{},
 	}
-	mntneer, err := mountineer.New(model.NamespaceRef{fmt.Sprintf("/proc/%d/ns/mnt", engine.PID)}, nil)
-	if err != nil {
-		log.Errorf("cannot access mount namespace of nerdctl engine, reason: %s",
-			err.Error())
+	var mntns model.Namespace
+	if proc := allprocs[engine.PID]; proc != nil {
+		mntns = proc.Namespaces[model.MountNS]
+	}
+	if mntns == nil {
+		diagnostics.FromContext(ctx).Errorf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "nerdctlnet"},
+			"cannot access mount namespace of nerdctl engine, reason: unknown engine process %d", engine.PID)
 		return nerdynets
 	}
-	defer mntneer.Close()
-	netwConfigsDir, err := mntneer.Resolve(NetworkConfigurationsDir)
+	enginefs, closer, err := network.SourceFromContext(ctx).MountFiles(mntns)
 	if err != nil {
-		log.Infof("cannot resolve CNI plugins configuration path, reason: %s",
-			err.Error())
+		diagnostics.FromContext(ctx).Errorf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "nerdctlnet"},
+			"cannot access mount namespace of nerdctl engine, reason: %s", err.Error())
 		return nerdynets
 	}
-	configFilenames, err := filepath.Glob(filepath.Join(netwConfigsDir, NetworkConfigurationsGlob))
+	defer closer()
+	entries, err := enginefs.ReadDir(NetworkConfigurationsDir)
 	if err != nil {
+		log.Infof("cannot read CNI plugins configuration path, reason: %s",
+			err.Error())
 		return nerdynets
 	}
-	for _, configFilename := range configFilenames {
+	for _, entry := range entries {
+		if match, _ := filepath.Match(NetworkConfigurationsGlob, entry.Name()); !match {
+			continue
+		}
+		configFilename := filepath.Join(NetworkConfigurationsDir, entry.Name())
 		log.Debugf("found CNI configuration file %q", configFilename)
-		nerdynetworkconf, err := libcni.ConfListFromFile(configFilename)
+		config, err := enginefs.ReadFile(configFilename)
+		if err != nil {
+			continue
+		}
+		nerdynetworkconf, err := libcni.ConfListFromBytes(config)
 		if err != nil {
-			log.Errorf("invalid CNI configuration file %q, reason: %s",
-				configFilename, err.Error())
+			diagnostics.FromContext(ctx).Warnf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "nerdctlnet"},
+				"invalid CNI configuration file %q, reason: %s", configFilename, err.Error())
 			continue
 		}
 		// Oh well ... libcni puts the original raw JSON into the "Bytes"
@@ -175,7 +185,7 @@ func Decorate(
 		if engine.Type != containerd.Type {
 			continue
 		}
-		nerdctlNets[engine.PID] = newNerdctlNetworks(ctx, engine, allnetns)
+		nerdctlNets[engine.PID] = newNerdctlNetworks(ctx, engine, allnetns, allprocs)
 	}
 	// Now that we know about the nerdctl-managed CNI networks, try to locate
 	// the matching Linux-kernel network interfaces so we can set/override the
@@ -9,11 +9,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
 	"path"
-	"time"
 )
 
 // UserAgent specifies the HTTP agent string used when talking to podman's
@@ -29,11 +27,13 @@ type Client struct {
 }
 
 // newLibpodClient returns a new podman libpod API client. The endpoint must be
-// using the "unix" protocol.
+// using the "unix" protocol. The HTTP client must talk to the endpoint
+// regardless of the host in request URLs, such as the HTTP clients returned by
+// network.Source.EngineClient.
 //
 // Please note that this libpod API client is absolutely minimalist and just
 // suffices for querying the podman-managed networks.
-func newLibpodClient(endpoint string) (*Client, error) {
+func newLibpodClient(endpoint string, httpClient *http.Client) (*Client, error) {
 	epurl, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("invalid endpoint, reason: %w", err)
@@ -41,26 +41,10 @@ func newLibpodClient(endpoint string) (*Client, error) {
 	if epurl.Scheme != "unix" {
 		return nil, fmt.Errorf("unsupported endpoint protocol '%s'", epurl.Scheme)
 	}
-	c := &Client{
-		httpClient: &http.Client{
-			Transport: &http.Transport{
-				DisableCompression: true,
-			},
-		},
+	return &Client{
+		httpClient:  httpClient,
 		endpointURL: epurl,
-	}
-	dialer := &net.Dialer{
-		// same as Docker's unix socket default transport configuration, see
-		// also
-		// https://github.com/docker/go-connections/blob/fa09c952e3eadbffaf8afc5b8a1667158ba38ace/sockets/sockets.go#L11
-		Timeout: 10 * time.Second,
-	}
-	c.httpClient.Transport.(*http.Transport).DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
-		// we don't want to dial the libpod API endpoint, but instead the engine
-		// API endpoint as such...
-		return dialer.DialContext(ctx, epurl.Scheme, epurl.Path)
-	}
-	return c, nil
+	}, nil
 }
 
 // Close closes idle connections.
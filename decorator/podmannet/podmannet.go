@@ -6,17 +6,17 @@ package podmannet
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/siemens/ghostwire/v2/decorator"
 	"github.com/siemens/ghostwire/v2/decorator/dockernet"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 	"github.com/siemens/ghostwire/v2/network"
 	"github.com/siemens/turtlefinder/activator/podman"
 
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
-	"github.com/thediveo/lxkns/ops"
 )
 
 // GostwireNetworkNameKey defines the label key for storing the Docker network
@@ -44,22 +44,38 @@ type podmanNetworks struct {
 // podmanNetworks object will be returned instead, to be used in the engine map
 // to signal that we asked the engine, but it failed, so no more attempts to
 // talk to it, please.
-func makePodmanNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces) (
+func makePodmanNetworks(ctx context.Context, engine *model.ContainerEngine, allnetns network.NetworkNamespaces, allprocs model.ProcessTable) (
 	podmannets podmanNetworks,
 ) {
-	libpodclient, err := newLibpodClient(engine.API)
+	// Skip engines that failed repeatedly before for a while, so a hung engine
+	// API doesn't slow down every discovery.
+	breakerKey := "podmannet-v4+/" + engine.API
+	if !guard.Breakers.Allow(breakerKey) {
+		diagnostics.FromContext(ctx).Warnf(diagnostics.PluginSuspended, diagnostics.Scope{Plugin: "podmannet-v4+"},
+			"skipping podman engine API %s after repeated failures", engine.API)
+		return
+	}
+	libpodclient, err := newLibpodClient(engine.API,
+		network.SourceFromContext(ctx).EngineClient(engine.API))
 	if err != nil {
-		log.Warnf("cannot discover podman-managed networks from API %s, reason: %s",
+		diagnostics.FromContext(ctx).Warnf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "podmannet-v4+"},
+			"cannot discover podman-managed networks from API %s, reason: %s",
 			engine.API, err.Error())
+		guard.Breakers.Failure(breakerKey)
 		return
 	}
 	libpodclient.libpodVersion = libpodclient.ping(ctx)
-	networks, _ := libpodclient.networkList(ctx)
+	networks, err := libpodclient.networkList(ctx)
 	_ = libpodclient.Close()
-	netnsid, _ := ops.NamespacePath(fmt.Sprintf("/proc/%d/ns/net", engine.PID)).ID()
+	if err != nil {
+		diagnostics.FromContext(ctx).Warnf(diagnostics.DecoratorFailed, diagnostics.Scope{Plugin: "podmannet-v4+"},
+			"cannot list podman-managed networks from API %s, reason: %s",
+			engine.API, err.Error())
+	}
+	guard.Breakers.Record(breakerKey, err)
 	podmannets.networks = networks
 	podmannets.engine = engine
-	podmannets.engineNetns = allnetns[netnsid]
+	podmannets.engineNetns = network.EngineNetns(engine, allnetns, allprocs)
 	log.Infof("found %d podman networks related to net:[%d] %s",
 		len(networks), podmannets.engineNetns.ID().Ino, podmannets.engineNetns.DisplayName())
 	return
@@ -87,7 +103,7 @@ func Decorate(
 		if engine.Type != podman.Type {
 			continue
 		}
-		podmanNets[engine.PID] = makePodmanNetworks(ctx, engine, allnetns)
+		podmanNets[engine.PID] = makePodmanNetworks(ctx, engine, allnetns, allprocs)
 	}
 	// Now that we know about the podman networks, try to locate the matching
 	// Linux-kernel network interfaces so we can set/override the alias names of
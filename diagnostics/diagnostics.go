@@ -0,0 +1,218 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/thediveo/lxkns/log"
+)
+
+// Severity of a diagnostic.
+type Severity string
+
+// The severities of diagnostics: warnings signal a degraded, yet still usable
+// discovery result, while errors signal that some part of the discovery failed
+// completely.
+const (
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// Code identifies the kind of problem a diagnostic is about.
+type Code string
+
+// Codes of the diagnostics Gostwire itself reports.
+const (
+	NetnsUnavailable            Code = "netns-unavailable"             // network namespace not accessible at all.
+	InterfacesUnavailable       Code = "interfaces-unavailable"        // network interfaces cannot be listed.
+	RoutesUnavailable           Code = "routes-unavailable"            // routes cannot be listed.
+	EthtoolUnavailable          Code = "ethtool-unavailable"           // ethtool API not available.
+	AddressDetailsUnavailable   Code = "address-details-unavailable"   // address details cannot be discovered.
+	SysctlsUnavailable          Code = "sysctls-unavailable"           // sysctls cannot be read.
+	ProtocolCountersUnavailable Code = "protocol-counters-unavailable" // protocol counters cannot be sampled.
+	NSIDsUnavailable            Code = "nsids-unavailable"             // NSIDs of peer network namespaces unknown.
+	ForwardedPortsUnavailable   Code = "forwarded-ports-unavailable"   // netfilter tables cannot be read.
+	MulticastUnavailable        Code = "multicast-unavailable"         // multicast details cannot be discovered.
+	Cancelled                   Code = "cancelled"                     // discovery (phase) cancelled or timed out.
+	DecoratorFailed             Code = "decorator-failed"              // decorator plugin failed.
+	DecoratorSkipped            Code = "decorator-skipped"             // decorator plugin not run.
+	MetadataFailed              Code = "metadata-failed"               // metadata plugin failed.
+	MetadataSkipped             Code = "metadata-skipped"              // metadata plugin not run.
+	PluginTimeout               Code = "plugin-timeout"                // plugin didn't finish in time.
+	PluginPanic                 Code = "plugin-panic"                  // plugin panicked.
+	PluginSuspended             Code = "plugin-suspended"              // plugin or engine skipped after repeated failures.
+)
+
+// Scope identifies what a diagnostic is about. Zero fields are out of scope.
+type Scope struct {
+	Netns     uint64 `json:"netns,omitempty"`     // inode number of network namespace.
+	Interface string `json:"interface,omitempty"` // name of network interface.
+	Plugin    string `json:"plugin,omitempty"`    // name of decorator or metadata plugin.
+}
+
+// Diagnostic is a single warning or error that occurred during discovery.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     Code     `json:"code"`
+	Scope    Scope    `json:"scope"`
+	Message  string   `json:"message"`
+}
+
+// Phase of a discovery run.
+type Phase string
+
+// The phases of a discovery run, in order.
+const (
+	PhaseNamespaces Phase = "namespaces" // namespaces, processes, and containers.
+	PhaseNetwork    Phase = "network"    // network namespace details.
+	PhaseDecorators Phase = "decorators" // decorator plugins.
+	PhaseMetadata   Phase = "metadata"   // metadata plugins.
+)
+
+// Timing is the duration of a particular discovery phase.
+type Timing struct {
+	Phase    Phase
+	Duration time.Duration
+}
+
+// PluginGroup is the kind of plugin a PluginRun is about.
+type PluginGroup string
+
+// The plugin groups run during discovery.
+const (
+	DecoratorPlugin PluginGroup = "decorator"
+	MetadataPlugin  PluginGroup = "metadata"
+)
+
+// Outcome of running a single plugin.
+type Outcome string
+
+// The outcomes of running a plugin.
+const (
+	OutcomeOK        Outcome = "ok"        // plugin finished in time.
+	OutcomeSkipped   Outcome = "skipped"   // plugin not run due to options or phase timeout.
+	OutcomeSuspended Outcome = "suspended" // plugin not run after repeated failures.
+	OutcomeTimeout   Outcome = "timeout"   // plugin didn't finish in time.
+	OutcomePanic     Outcome = "panic"     // plugin panicked.
+)
+
+// PluginRun is the outcome of running a particular decorator or metadata
+// plugin.
+type PluginRun struct {
+	Plugin   string
+	Group    PluginGroup
+	Outcome  Outcome
+	Duration time.Duration
+}
+
+// Diagnostics collects the diagnostics, phase timings, and plugin outcomes of
+// a single discovery run. Diagnostics is safe for concurrent use.
+type Diagnostics struct {
+	mu      sync.Mutex
+	diags   []Diagnostic
+	timings []Timing
+	plugins []PluginRun
+}
+
+// New returns a new and empty diagnostics collector.
+func New() *Diagnostics {
+	return &Diagnostics{}
+}
+
+// Warnf logs and adds a warning with the specified code and scope.
+func (d *Diagnostics) Warnf(code Code, scope Scope, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Warnf("%s", msg)
+	d.add(Diagnostic{Severity: Warning, Code: code, Scope: scope, Message: msg})
+}
+
+// Errorf logs and adds an error with the specified code and scope.
+func (d *Diagnostics) Errorf(code Code, scope Scope, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Errorf("%s", msg)
+	d.add(Diagnostic{Severity: Error, Code: code, Scope: scope, Message: msg})
+}
+
+func (d *Diagnostics) add(diag Diagnostic) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.diags = append(d.diags, diag)
+}
+
+// Time records the duration of the specified phase as the time passed since
+// the specified start time, so that it can be deferred.
+func (d *Diagnostics) Time(phase Phase, start time.Time) {
+	if d == nil {
+		return
+	}
+	duration := time.Since(start)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timings = append(d.timings, Timing{Phase: phase, Duration: duration})
+}
+
+// List returns the diagnostics collected so far, in the order they were
+// added. The returned list is never nil.
+func (d *Diagnostics) List() []Diagnostic {
+	if d == nil {
+		return []Diagnostic{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Diagnostic{}, d.diags...)
+}
+
+// Timings returns the phase timings recorded so far, in the order they were
+// recorded. The returned list is never nil.
+func (d *Diagnostics) Timings() []Timing {
+	if d == nil {
+		return []Timing{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Timing{}, d.timings...)
+}
+
+// Ran records the outcome of running a plugin.
+func (d *Diagnostics) Ran(run PluginRun) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.plugins = append(d.plugins, run)
+}
+
+// PluginRuns returns the plugin outcomes recorded so far, in the order they
+// were recorded. The returned list is never nil.
+func (d *Diagnostics) PluginRuns() []PluginRun {
+	if d == nil {
+		return []PluginRun{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]PluginRun{}, d.plugins...)
+}
+
+// diagnosticsKey is the context key for Diagnostics.
+type diagnosticsKey struct{}
+
+// NewContext returns a new context carrying the specified diagnostics.
+func NewContext(ctx context.Context, d *Diagnostics) context.Context {
+	return context.WithValue(ctx, diagnosticsKey{}, d)
+}
+
+// FromContext returns the diagnostics carried by the specified context, or nil.
+func FromContext(ctx context.Context) *Diagnostics {
+	d, _ := ctx.Value(diagnosticsKey{}).(*Diagnostics)
+	return d
+}
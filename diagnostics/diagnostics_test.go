@@ -0,0 +1,84 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diagnostics", func() {
+
+	It("collects diagnostics and timings", func() {
+		d := New()
+		d.Warnf(ForwardedPortsUnavailable, Scope{Netns: 42}, "cannot connect to %s", "netfilters")
+		d.Errorf(DecoratorFailed, Scope{Plugin: "dockernet"}, "boom")
+		d.Time(PhaseNetwork, time.Now().Add(-time.Second))
+		Expect(d.List()).To(Equal([]Diagnostic{
+			{Severity: Warning, Code: ForwardedPortsUnavailable, Scope: Scope{Netns: 42}, Message: "cannot connect to netfilters"},
+			{Severity: Error, Code: DecoratorFailed, Scope: Scope{Plugin: "dockernet"}, Message: "boom"},
+		}))
+		Expect(d.Timings()).To(ConsistOf(
+			HaveField("Phase", PhaseNetwork)))
+		Expect(d.Timings()[0].Duration).To(BeNumerically(">=", time.Second))
+		Expect(json.Marshal(d.List()[0])).To(MatchJSON(`{
+			"severity": "warning",
+			"code": "forwarded-ports-unavailable",
+			"scope": {"netns": 42},
+			"message": "cannot connect to netfilters"
+		}`))
+	})
+
+	It("is nil-safe", func() {
+		var d *Diagnostics
+		Expect(func() {
+			d.Warnf(Cancelled, Scope{}, "foo")
+			d.Time(PhaseMetadata, time.Now())
+		}).NotTo(Panic())
+		Expect(d.List()).To(BeEmpty())
+		Expect(d.List()).NotTo(BeNil())
+		Expect(d.Timings()).NotTo(BeNil())
+		Expect(func() { d.Ran(PluginRun{Plugin: "foo"}) }).NotTo(Panic())
+		Expect(d.PluginRuns()).NotTo(BeNil())
+	})
+
+	It("records plugin outcomes", func() {
+		d := New()
+		d.Ran(PluginRun{Plugin: "dockernet", Group: DecoratorPlugin, Outcome: OutcomeTimeout, Duration: time.Second})
+		d.Ran(PluginRun{Plugin: "host", Group: MetadataPlugin, Outcome: OutcomeOK})
+		Expect(d.PluginRuns()).To(HaveExactElements(
+			PluginRun{Plugin: "dockernet", Group: DecoratorPlugin, Outcome: OutcomeTimeout, Duration: time.Second},
+			PluginRun{Plugin: "host", Group: MetadataPlugin, Outcome: OutcomeOK},
+		))
+	})
+
+	It("passes diagnostics via contexts", func() {
+		Expect(FromContext(context.Background())).To(BeNil())
+		d := New()
+		Expect(FromContext(NewContext(context.Background(), d))).To(BeIdenticalTo(d))
+	})
+
+	It("is safe for concurrent use", func() {
+		d := New()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.Warnf(Cancelled, Scope{}, "foo")
+				d.Time(PhaseNetwork, time.Now())
+			}()
+		}
+		wg.Wait()
+		Expect(d.List()).To(HaveLen(10))
+		Expect(d.Timings()).To(HaveLen(10))
+	})
+
+})
@@ -0,0 +1,14 @@
+/*
+Package diagnostics collects structured warnings and errors during a discovery
+run, together with the durations of the individual discovery phases. This
+allows API consumers to tell complete discovery results from degraded ones,
+such as when the forwarded ports of a particular network namespace could not
+be discovered.
+
+Each Diagnostic has a Code telling the kind of problem, as well as a Scope
+identifying the network namespace, network interface, and/or plugin
+concerned. Diagnostics are safe for concurrent use and can be passed down to
+plugins using NewContext and FromContext. All methods of Diagnostics can be
+called on a nil *Diagnostics, which then only logs.
+*/
+package diagnostics
@@ -0,0 +1,125 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"sort"
+
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+)
+
+// Element kinds, ordering change events and indexing their change types.
+const (
+	netnsKind = iota
+	containerKind
+	nifKind
+	addressKind
+	routeKind
+	portKind
+	forwardedPortKind
+	kinds // number of element kinds.
+)
+
+var addedTypes = [kinds]apiv1.ChangeType{
+	apiv1.NetnsAdded, apiv1.ContainerAdded, apiv1.NifAdded, apiv1.AddressAdded,
+	apiv1.RouteAdded, apiv1.PortAdded, apiv1.ForwardedPortAdded,
+}
+
+var removedTypes = [kinds]apiv1.ChangeType{
+	apiv1.NetnsRemoved, apiv1.ContainerRemoved, apiv1.NifRemoved, apiv1.AddressRemoved,
+	apiv1.RouteRemoved, apiv1.PortRemoved, apiv1.ForwardedPortRemoved,
+}
+
+// ChangeEvents returns the change events between the old and the new
+// Snapshot: network namespaces and containers appearing or vanishing, network
+// interfaces added, removed, renamed, or changing their operational state, as
+// well as addresses, routes, listening ports, and forwarded ports added or
+// removed.
+//
+// As elements are compared by their stable identities, restarting a container
+// doesn't remove and add its network namespace, network interfaces, et cetera,
+// but only results in change events for what actually changed.
+//
+// The change events are sorted so that removals and additions of network
+// namespaces come first, followed by containers, network interfaces, et
+// cetera.
+func ChangeEvents(old, new *Snapshot) []apiv1.ChangeEvent {
+	events := []apiv1.ChangeEvent{}
+	for kind := 0; kind < kinds; kind++ {
+		changes := compare(old.elements(kind), new.elements(kind))
+		kindevents := make([]apiv1.ChangeEvent, 0, len(changes.Added)+len(changes.Removed))
+		for _, id := range changes.Removed {
+			event := old.events[kind][id]
+			event.Type = removedTypes[kind]
+			kindevents = append(kindevents, event)
+		}
+		for _, id := range changes.Added {
+			event := new.events[kind][id]
+			event.Type = addedTypes[kind]
+			kindevents = append(kindevents, event)
+		}
+		if kind == nifKind {
+			kindevents = renamedNifs(kindevents, old, new)
+			for _, changed := range changes.Changed {
+				for _, prop := range changed.Properties {
+					if prop.Name != "operstate" {
+						continue
+					}
+					event := new.events[kind][changed.ID]
+					event.Type = apiv1.NifChanged
+					event.From = prop.Old
+					event.To = prop.New
+					kindevents = append(kindevents, event)
+				}
+			}
+		}
+		sort.Slice(kindevents, func(a, b int) bool {
+			if kindevents[a].ID != kindevents[b].ID {
+				return kindevents[a].ID < kindevents[b].ID
+			}
+			return kindevents[a].Type < kindevents[b].Type
+		})
+		events = append(events, kindevents...)
+	}
+	return events
+}
+
+// renamedNifs replaces the removal and addition of the same network interface
+// with a single change event for the renamed network interface. Network
+// interfaces are the same if they have the same v1 reference, that is, the same
+// network namespace and interface index.
+func renamedNifs(events []apiv1.ChangeEvent, old, new *Snapshot) []apiv1.ChangeEvent {
+	removed := map[string]apiv1.ChangeEvent{}
+	for _, event := range events {
+		if event.Type == apiv1.NifRemoved && event.NifRef != "" {
+			removed[event.NifRef] = event
+		}
+	}
+	renamed := map[string]struct{}{}
+	for idx, event := range events {
+		if event.Type != apiv1.NifAdded {
+			continue
+		}
+		oldevent, ok := removed[event.NifRef]
+		if !ok {
+			continue
+		}
+		renamed[event.NifRef] = struct{}{}
+		events[idx].Type = apiv1.NifChanged
+		events[idx].From = old.Interfaces[oldevent.ID]["operstate"]
+		events[idx].To = new.Interfaces[event.ID]["operstate"]
+	}
+	if len(renamed) == 0 {
+		return events
+	}
+	kept := events[:0]
+	for _, event := range events {
+		if _, ok := renamed[event.NifRef]; ok && event.Type == apiv1.NifRemoved {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	return kept
+}
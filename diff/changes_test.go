@@ -0,0 +1,116 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"strings"
+
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("change events", func() {
+
+	var old *Snapshot
+
+	BeforeEach(func() {
+		old = Successful(Load(strings.NewReader(v1result)))
+	})
+
+	It("doesn't report changes when there are none", func() {
+		Expect(ChangeEvents(old, old)).To(BeEmpty())
+	})
+
+	It("reports everything as added", func() {
+		events := ChangeEvents(Successful(Load(strings.NewReader(`{}`))), old)
+		Expect(events).NotTo(BeEmpty())
+		for _, event := range events {
+			Expect(string(event.Type)).To(HaveSuffix("-added"))
+		}
+		Expect(events[0]).To(And(
+			HaveField("Type", apiv1.NetnsAdded),
+			HaveField("ID", "docker:web")))
+		Expect(events).To(ContainElement(apiv1.ChangeEvent{
+			Type:         apiv1.ContainerAdded,
+			ID:           "docker:web",
+			ContainerRef: "cont-1234",
+			Name:         "web",
+		}))
+		Expect(events).To(ContainElement(apiv1.ChangeEvent{
+			Type:   apiv1.NifAdded,
+			ID:     "docker:web/eth0",
+			NifRef: "nif-4026532666-7",
+			Name:   "eth0",
+		}))
+		Expect(events).To(ContainElement(And(
+			HaveField("Type", apiv1.RouteAdded),
+			HaveField("NifRef", "nif-4026531840-2"),
+			HaveField("Detail", "unicast default via 192.168.0.1 dev eth0 table 254"))))
+		Expect(events).To(ContainElement(And(
+			HaveField("Type", apiv1.PortAdded),
+			HaveField("Detail", "tcp 0.0.0.0:22"))))
+		Expect(events).To(ContainElement(And(
+			HaveField("Type", apiv1.ForwardedPortAdded),
+			HaveField("Detail", "tcp 0.0.0.0:8080 to 172.17.0.2:80"))))
+	})
+
+	It("doesn't report a restarted container as removed and added", func() {
+		restarted := strings.NewReplacer(
+			`"netnsid": 4026532666`, `"netnsid": 4026532777`,
+			`nif-4026532666-`, `nif-4026532777-`,
+			`"cont-1234"`, `"cont-2345"`,
+			`"pid": 1234`, `"pid": 2345`,
+		).Replace(v1result)
+		Expect(ChangeEvents(old, Successful(Load(strings.NewReader(restarted))))).To(BeEmpty())
+	})
+
+	It("reports removed and changed elements", func() {
+		newresult := strings.NewReplacer(
+			`"name": "eth0", "operstate": "up",
+				 "addresses": {"mac": "02:42:ac:11:00:02"`,
+			`"name": "eth0", "operstate": "down",
+				 "addresses": {"mac": "02:42:ac:11:00:02"`,
+			`{"protocol": "tcp", "ip": "0.0.0.0", "port": 8080, "forward-ip": "172.17.0.2", "forward-port": 80}`, ``,
+		).Replace(v1result)
+		Expect(ChangeEvents(old, Successful(Load(strings.NewReader(newresult))))).To(Equal([]apiv1.ChangeEvent{
+			{
+				Type:   apiv1.NifChanged,
+				ID:     "docker:web/eth0",
+				NifRef: "nif-4026532666-7",
+				Name:   "eth0",
+				From:   "up",
+				To:     "down",
+			},
+			{
+				Type:   apiv1.ForwardedPortRemoved,
+				ID:     "host/tcp/0.0.0.0:8080",
+				Detail: "tcp 0.0.0.0:8080 to 172.17.0.2:80",
+			},
+		}))
+	})
+
+	It("reports renamed network interfaces", func() {
+		renamed := strings.Replace(v1result,
+			`"name": "eth0", "operstate": "up",
+				 "addresses": {"mac": "02:42:ac:11:00:02"`,
+			`"name": "eth1", "operstate": "up",
+				 "addresses": {"mac": "02:42:ac:11:00:02"`, 1)
+		events := ChangeEvents(old, Successful(Load(strings.NewReader(renamed))))
+		Expect(events).To(ContainElement(apiv1.ChangeEvent{
+			Type:   apiv1.NifChanged,
+			ID:     "docker:web/eth1",
+			NifRef: "nif-4026532666-7",
+			Name:   "eth1",
+			From:   "up",
+			To:     "up",
+		}))
+		Expect(events).NotTo(ContainElement(HaveField("Type", apiv1.NifRemoved)))
+		Expect(events).NotTo(ContainElement(HaveField("Type", apiv1.NifAdded)))
+	})
+
+})
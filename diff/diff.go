@@ -0,0 +1,169 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Diff is the structured difference between two Snapshots.
+type Diff struct {
+	Old            time.Time `json:"old"` // creation timestamp of the old discovery result, if known.
+	New            time.Time `json:"new"` // creation timestamp of the new discovery result, if known.
+	Netns          Changes   `json:"network-namespaces"`
+	Containers     Changes   `json:"containers"`
+	Interfaces     Changes   `json:"network-interfaces"`
+	Addresses      Changes   `json:"addresses"`
+	Routes         Changes   `json:"routes"`
+	Ports          Changes   `json:"open-ports"`
+	ForwardedPorts Changes   `json:"forwarded-ports"`
+}
+
+// Changes lists the identities of the added and removed elements of a
+// particular kind, as well as the elements present in both Snapshots, but
+// with changed properties.
+type Changes struct {
+	Added   []string  `json:"added"`
+	Removed []string  `json:"removed"`
+	Changed []Changed `json:"changed"`
+}
+
+// Changed describes an element present in both Snapshots, but with changed
+// properties.
+type Changed struct {
+	ID         string           `json:"id"`
+	Properties []PropertyChange `json:"properties"`
+}
+
+// PropertyChange describes a single changed property of an element.
+type PropertyChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Compare returns the differences between the old and the new Snapshot.
+func Compare(old, new *Snapshot) *Diff {
+	return &Diff{
+		Old:            old.Time,
+		New:            new.Time,
+		Netns:          compare(old.Netns, new.Netns),
+		Containers:     compare(old.Containers, new.Containers),
+		Interfaces:     compare(old.Interfaces, new.Interfaces),
+		Addresses:      compare(old.Addresses, new.Addresses),
+		Routes:         compare(old.Routes, new.Routes),
+		Ports:          compare(old.Ports, new.Ports),
+		ForwardedPorts: compare(old.ForwardedPorts, new.ForwardedPorts),
+	}
+}
+
+// compare returns the changes between the old and new elements of the same
+// kind, sorted by their identities.
+func compare(old, new map[string]Element) Changes {
+	changes := Changes{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []Changed{},
+	}
+	for id, newel := range new {
+		oldel, ok := old[id]
+		if !ok {
+			changes.Added = append(changes.Added, id)
+			continue
+		}
+		props := []PropertyChange{}
+		for name, newval := range newel {
+			if oldval := oldel[name]; oldval != newval {
+				props = append(props, PropertyChange{Name: name, Old: oldval, New: newval})
+			}
+		}
+		for name, oldval := range oldel {
+			if _, ok := newel[name]; !ok {
+				props = append(props, PropertyChange{Name: name, Old: oldval})
+			}
+		}
+		if len(props) > 0 {
+			sort.Slice(props, func(a, b int) bool { return props[a].Name < props[b].Name })
+			changes.Changed = append(changes.Changed, Changed{ID: id, Properties: props})
+		}
+	}
+	for id := range old {
+		if _, ok := new[id]; !ok {
+			changes.Removed = append(changes.Removed, id)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Slice(changes.Changed, func(a, b int) bool {
+		return changes.Changed[a].ID < changes.Changed[b].ID
+	})
+	return changes
+}
+
+// Empty returns true if there are no changes.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// Empty returns true if there are no differences at all.
+func (d *Diff) Empty() bool {
+	for _, kind := range d.kinds() {
+		if !kind.changes.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// kinds returns the changes of each kind of element together with a
+// human-readable name, in the order of reporting.
+func (d *Diff) kinds() []struct {
+	name    string
+	changes Changes
+} {
+	return []struct {
+		name    string
+		changes Changes
+	}{
+		{"network namespace", d.Netns},
+		{"container", d.Containers},
+		{"network interface", d.Interfaces},
+		{"address", d.Addresses},
+		{"route", d.Routes},
+		{"open port", d.Ports},
+		{"forwarded port", d.ForwardedPorts},
+	}
+}
+
+// WriteText writes the differences in a human-readable, line-oriented format
+// to w, in the style of "+ added", "- removed", and "~ changed".
+func (d *Diff) WriteText(w io.Writer) error {
+	for _, kind := range d.kinds() {
+		for _, id := range kind.changes.Removed {
+			if _, err := fmt.Fprintf(w, "- %s %s\n", kind.name, id); err != nil {
+				return err
+			}
+		}
+		for _, id := range kind.changes.Added {
+			if _, err := fmt.Fprintf(w, "+ %s %s\n", kind.name, id); err != nil {
+				return err
+			}
+		}
+		for _, changed := range kind.changes.Changed {
+			if _, err := fmt.Fprintf(w, "~ %s %s\n", kind.name, changed.ID); err != nil {
+				return err
+			}
+			for _, prop := range changed.Properties {
+				if _, err := fmt.Fprintf(w, "    %s: %q -> %q\n", prop.Name, prop.Old, prop.New); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
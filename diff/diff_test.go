@@ -0,0 +1,94 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("diffing", func() {
+
+	var old *Snapshot
+
+	BeforeEach(func() {
+		old = Successful(Load(strings.NewReader(v1result)))
+	})
+
+	It("finds no differences in the same snapshot", func() {
+		d := Compare(old, old)
+		Expect(d.Empty()).To(BeTrue())
+		var text strings.Builder
+		Expect(d.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(BeEmpty())
+	})
+
+	It("reports added, removed, and changed elements", func() {
+		// Simulate a restart of the "web" container with a new network
+		// namespace, the host's eth0 going down and losing its address, as well
+		// as the port forwarding getting removed.
+		newresult := strings.NewReplacer(
+			`"netnsid": 4026532666`, `"netnsid": 4026532777`,
+			`"pid": 1234`, `"pid": 2345`,
+			`"operstate": "up",
+				 "addresses": {"mac": "52:54:00:12:34:56",
+				   "ipv4": [{"address": "192.168.0.2", "prefixlen": 24}], "ipv6": []}`,
+			`"operstate": "down",
+				 "addresses": {"mac": "52:54:00:12:34:56", "ipv4": [], "ipv6": []}`,
+			`{"protocol": "tcp", "ip": "0.0.0.0", "port": 8080, "forward-ip": "172.17.0.2", "forward-port": 80}`, ``,
+			`"local-port": 80,`, `"local-port": 8000,`,
+		).Replace(v1result)
+		d := Compare(old, Successful(Load(strings.NewReader(newresult))))
+		Expect(d.Empty()).To(BeFalse())
+
+		Expect(d.Netns.Added).To(BeEmpty())
+		Expect(d.Netns.Removed).To(BeEmpty())
+		Expect(d.Netns.Changed).To(ConsistOf(Changed{
+			ID:         "docker:web",
+			Properties: []PropertyChange{{Name: "netnsid", Old: "4026532666", New: "4026532777"}},
+		}))
+		Expect(d.Containers.Changed).To(ConsistOf(Changed{
+			ID:         "docker:web",
+			Properties: []PropertyChange{{Name: "pid", Old: "1234", New: "2345"}},
+		}))
+		Expect(d.Interfaces.Changed).To(ConsistOf(Changed{
+			ID:         "host/eth0",
+			Properties: []PropertyChange{{Name: "operstate", Old: "up", New: "down"}},
+		}))
+		Expect(d.Addresses.Removed).To(ConsistOf("host/eth0/192.168.0.2/24"))
+		Expect(d.Addresses.Added).To(BeEmpty())
+		Expect(d.Routes.Empty()).To(BeTrue())
+		Expect(d.Ports.Removed).To(ConsistOf("docker:web/tcp/0.0.0.0:80"))
+		Expect(d.Ports.Added).To(ConsistOf("docker:web/tcp/0.0.0.0:8000"))
+		Expect(d.ForwardedPorts.Removed).To(ConsistOf("host/tcp/0.0.0.0:8080"))
+
+		var text strings.Builder
+		Expect(d.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(And(
+			ContainSubstring("~ network namespace docker:web\n    netnsid: \"4026532666\" -> \"4026532777\"\n"),
+			ContainSubstring("- address host/eth0/192.168.0.2/24\n"),
+			ContainSubstring("+ open port docker:web/tcp/0.0.0.0:8000\n"),
+			ContainSubstring("- forwarded port host/tcp/0.0.0.0:8080\n"),
+		))
+
+		j := Successful(json.Marshal(d))
+		Expect(string(j)).To(ContainSubstring(`"open-ports":{"added":["docker:web/tcp/0.0.0.0:8000"]`))
+	})
+
+	It("reports added and removed network namespaces", func() {
+		empty := Successful(Load(strings.NewReader(`{"network-namespaces":[]}`)))
+		d := Compare(empty, old)
+		Expect(d.Netns.Added).To(ConsistOf(HostNetns, "docker:web", "proc:unshare"))
+		Expect(d.Containers.Added).To(ConsistOf("docker:web"))
+		d = Compare(old, empty)
+		Expect(d.Netns.Removed).To(ConsistOf(HostNetns, "docker:web", "proc:unshare"))
+		Expect(d.Interfaces.Removed).To(HaveLen(4))
+	})
+
+})
@@ -0,0 +1,28 @@
+/*
+Package diff compares two Ghostwire discovery results and reports which network
+namespaces, containers, network interfaces, addresses, routes, open
+(listening) ports, and forwarded ports were added, removed, or changed.
+
+Discovery results are compared in their v1 JSON representation, as decoded by
+the client package, so they can either come from a live discovery (see
+FromResult), from saved /json responses (see Load), or from a client (see
+FromClient). In contrast to the v1 JSON document-local identifiers,
+which are based on ephemeral PIDs, inode numbers, and interface indices, diff
+uses stable identities that survive container restarts and even reboots:
+
+  - containers are identified by their type, optional prefix, and name.
+  - network namespaces are identified by the containers attached to them; the
+    initial network namespace is always "host".
+  - network interfaces are identified by their network namespace and name.
+  - addresses, routes, and ports are identified by their network namespace (or
+    network interface) and their textual representation.
+
+For instance, a container that got restarted and thus has a new network
+namespace with a new inode number shows up as a changed network namespace
+instead of a removed and an added one.
+
+ChangeEvents turns the differences between two Snapshots into the change events
+streamed by the /changes endpoint, identifying the elements by their stable
+identities in addition to their v1 JSON document-local references.
+*/
+package diff
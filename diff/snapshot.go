@@ -0,0 +1,283 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/client"
+	"github.com/siemens/ghostwire/v2/network"
+)
+
+// HostNetns is the stable identity of the initial network namespace.
+const HostNetns = "host"
+
+// Snapshot is a discovery result reduced to the elements diff compares, each
+// indexed by its stable identity. Snapshots are created by Load, LoadFile,
+// FromResult, or FromClient.
+type Snapshot struct {
+	Time           time.Time // creation timestamp of the discovery result, if known.
+	Netns          map[string]Element
+	Containers     map[string]Element
+	Interfaces     map[string]Element
+	Addresses      map[string]Element
+	Routes         map[string]Element
+	Ports          map[string]Element
+	ForwardedPorts map[string]Element
+
+	events [kinds]map[string]apiv1.ChangeEvent // change event templates by kind and identity.
+}
+
+// Element is a single element of a Snapshot with its properties that are
+// compared when the element is present in both Snapshots.
+type Element map[string]string
+
+// Load returns the Snapshot of the v1 JSON discovery result read from r.
+func Load(r io.Reader) (*Snapshot, error) {
+	result, err := client.Load(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v1 discovery result, reason: %w", err)
+	}
+	return FromClient(result), nil
+}
+
+// LoadFile returns the Snapshot of the v1 JSON discovery result stored in the
+// specified file.
+func LoadFile(name string) (*Snapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// FromResult returns the Snapshot of the specified discovery result.
+func FromResult(result gostwire.DiscoveryResult) (*Snapshot, error) {
+	v1result := apiv1.NewDiscoveryResult(result)
+	b, err := json.Marshal(&v1result)
+	if err != nil {
+		return nil, err
+	}
+	return Load(bytes.NewReader(b))
+}
+
+// FromClient returns the Snapshot of the specified v1 discovery result, as
+// decoded by the client package.
+func FromClient(result *client.DiscoveryResult) *Snapshot {
+	s := &Snapshot{
+		Netns:          map[string]Element{},
+		Containers:     map[string]Element{},
+		Interfaces:     map[string]Element{},
+		Addresses:      map[string]Element{},
+		Routes:         map[string]Element{},
+		Ports:          map[string]Element{},
+		ForwardedPorts: map[string]Element{},
+	}
+	for kind := range s.events {
+		s.events[kind] = map[string]apiv1.ChangeEvent{}
+	}
+	if ts, ok := result.Metadata["creation-timestamp"].(string); ok {
+		s.Time, _ = time.Parse(time.RFC3339Nano, ts)
+	}
+	for _, netns := range result.NetworkNamespaces {
+		s.addNetns(netns)
+	}
+	return s
+}
+
+// elements returns the elements of the specified kind.
+func (s *Snapshot) elements(kind int) map[string]Element {
+	switch kind {
+	case netnsKind:
+		return s.Netns
+	case containerKind:
+		return s.Containers
+	case nifKind:
+		return s.Interfaces
+	case addressKind:
+		return s.Addresses
+	case routeKind:
+		return s.Routes
+	case portKind:
+		return s.Ports
+	default:
+		return s.ForwardedPorts
+	}
+}
+
+// add adds the element of the specified kind with the specified identity,
+// together with the template for its change events.
+func (s *Snapshot) add(kind int, id string, el Element, event apiv1.ChangeEvent) {
+	s.elements(kind)[id] = el
+	event.ID = id
+	s.events[kind][id] = event
+}
+
+// addNetns adds the specified network namespace and its elements.
+func (s *Snapshot) addNetns(netns *client.NetworkNamespace) {
+	netnsid := netnsIdentity(netns)
+	s.add(netnsKind, netnsid, Element{
+		"netnsid": strconv.FormatUint(netns.NetnsID, 10),
+	}, apiv1.ChangeEvent{NetnsRef: netns.ID})
+	for _, cntr := range netns.Containers {
+		if cntr.Type == "proc" || cntr.Type == "bindmount" {
+			continue
+		}
+		s.add(containerKind, containerIdentity(cntr), Element{
+			"netns":  netnsid,
+			"status": cntr.Status,
+			"pid":    strconv.FormatUint(uint64(cntr.PID), 10),
+		}, apiv1.ChangeEvent{
+			NetnsRef:     netns.ID,
+			ContainerRef: cntr.ID,
+			Name:         cntr.Name,
+		})
+	}
+	for _, nif := range netns.NetworkInterfaces {
+		nifid := netnsid + "/" + nif.Name
+		s.add(nifKind, nifid, Element{
+			"kind":      nif.Kind,
+			"alias":     nif.Alias,
+			"operstate": nif.Operstate,
+			"mac":       nif.Addresses.MAC,
+			"promisc":   strconv.FormatBool(nif.Promiscuous),
+		}, apiv1.ChangeEvent{
+			NetnsRef: netns.ID,
+			NifRef:   nif.ID,
+			Name:     nif.Name,
+		})
+		for _, addrs := range [][]network.Address{nif.Addresses.IPv4, nif.Addresses.IPv6} {
+			for _, addr := range addrs {
+				detail := addr.Address.String() + "/" + strconv.FormatUint(uint64(addr.PrefixLength), 10)
+				s.add(addressKind, nifid+"/"+detail, Element{}, apiv1.ChangeEvent{
+					NetnsRef: netns.ID,
+					NifRef:   nif.ID,
+					Name:     nif.Name,
+					Detail:   detail,
+				})
+			}
+		}
+	}
+	for _, routes := range [][]client.Route{netns.Routes.IPv4, netns.Routes.IPv6} {
+		for _, rt := range routes {
+			detail := routeIdentity(rt)
+			s.add(routeKind, netnsid+"/"+detail, Element{
+				"priority": strconv.Itoa(rt.Priority),
+			}, apiv1.ChangeEvent{
+				NetnsRef: netns.ID,
+				NifRef:   rt.NifRef,
+				Detail:   detail,
+			})
+		}
+	}
+	for _, ports := range [][]client.Port{netns.TransportPorts.IPv4, netns.TransportPorts.IPv6} {
+		for _, prt := range ports {
+			if prt.Macrostate != "listening" {
+				continue
+			}
+			cmdlines := make([]string, 0, len(prt.Owners))
+			for _, owner := range prt.Owners {
+				cmdlines = append(cmdlines, owner.Cmdline)
+			}
+			sort.Strings(cmdlines)
+			addr := net.JoinHostPort(prt.LocalAddress.String(), strconv.FormatUint(uint64(prt.LocalPort), 10))
+			s.add(portKind, netnsid+"/"+prt.Protocol+"/"+addr, Element{
+				"owners": strings.Join(cmdlines, "; "),
+			}, apiv1.ChangeEvent{
+				NetnsRef: netns.ID,
+				Detail:   prt.Protocol + " " + addr,
+			})
+		}
+	}
+	for _, fwdports := range [][]client.ForwardedPort{netns.ForwardedPorts.IPv4, netns.ForwardedPorts.IPv6} {
+		for _, fwd := range fwdports {
+			addr := net.JoinHostPort(fwd.IP.String(), strconv.FormatUint(uint64(fwd.Port), 10))
+			forward := net.JoinHostPort(fwd.ForwardIP.String(), strconv.FormatUint(uint64(fwd.ForwardPort), 10))
+			s.add(forwardedPortKind, netnsid+"/"+fwd.Protocol+"/"+addr, Element{
+				"forward": forward,
+			}, apiv1.ChangeEvent{
+				NetnsRef: netns.ID,
+				Detail:   fwd.Protocol + " " + addr + " to " + forward,
+			})
+		}
+	}
+}
+
+// netnsIdentity returns the stable identity of the specified network
+// namespace, based on the containers or processes attached to it.
+func netnsIdentity(netns *client.NetworkNamespace) string {
+	cntrs := []string{}
+	var procs []*client.Container
+	for _, cntr := range netns.Containers {
+		switch cntr.Type {
+		case "proc":
+			if cntr.PID == 1 {
+				return HostNetns
+			}
+			procs = append(procs, cntr)
+		case "bindmount":
+			return "bindmount:" + cntr.Name
+		default:
+			cntrs = append(cntrs, containerIdentity(cntr))
+		}
+	}
+	if len(cntrs) > 0 {
+		sort.Strings(cntrs)
+		return strings.Join(cntrs, "+")
+	}
+	if len(procs) > 0 {
+		sort.Slice(procs, func(a, b int) bool { return procs[a].PID < procs[b].PID })
+		// Strip the ephemeral PID from the "name(PID)" of stand-alone
+		// processes.
+		name := procs[0].Name
+		if idx := strings.LastIndex(name, "("); idx > 0 && strings.HasSuffix(name, ")") {
+			name = name[:idx]
+		}
+		return "proc:" + name
+	}
+	return "netns:" + strconv.FormatUint(netns.NetnsID, 10)
+}
+
+// containerIdentity returns the stable identity of the specified container.
+func containerIdentity(cntr *client.Container) string {
+	name := cntr.Name
+	if cntr.Prefix != "" {
+		name = cntr.Prefix + ":" + name
+	}
+	return cntr.Type + ":" + name
+}
+
+// routeIdentity returns the stable identity of the specified route within its
+// network namespace, in the style of "ip route".
+func routeIdentity(rt client.Route) string {
+	var b strings.Builder
+	b.WriteString(rt.Type)
+	b.WriteRune(' ')
+	if rt.Destination == nil || (rt.Destination.IsUnspecified() && rt.DestinationLen == 0) {
+		b.WriteString("default")
+	} else {
+		fmt.Fprintf(&b, "%s/%d", rt.Destination, rt.DestinationLen)
+	}
+	if rt.NextHop != nil {
+		fmt.Fprintf(&b, " via %s", rt.NextHop)
+	}
+	if rt.Nif != nil {
+		fmt.Fprintf(&b, " dev %s", rt.Nif.Name)
+	}
+	fmt.Fprintf(&b, " table %d", rt.Table)
+	return b.String()
+}
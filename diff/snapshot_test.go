@@ -0,0 +1,146 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/turtlefinder"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// v1result is a (heavily) reduced v1 JSON discovery result with the host
+// network namespace and a single container network namespace.
+const v1result = `{
+	"metadata": {"creation-timestamp": "2023-06-01T12:00:00Z"},
+	"network-namespaces": [
+		{
+			"netnsid": 4026531840,
+			"containers": [
+				{"id": "cont-1", "name": "systemd", "type": "proc", "pid": 1, "status": "running"},
+				{"id": "cont-42", "name": "dockerd(42)", "type": "proc", "pid": 42, "status": "running"}
+			],
+			"network-interfaces": [
+				{"id": "nif-4026531840-1", "kind": "", "name": "lo", "operstate": "unknown",
+				 "addresses": {"mac": "00:00:00:00:00:00",
+				   "ipv4": [{"address": "127.0.0.1", "prefixlen": 8}], "ipv6": []}},
+				{"id": "nif-4026531840-2", "kind": "", "name": "eth0", "operstate": "up",
+				 "addresses": {"mac": "52:54:00:12:34:56",
+				   "ipv4": [{"address": "192.168.0.2", "prefixlen": 24}], "ipv6": []}}
+			],
+			"routes": {
+				"ipv4": [
+					{"type": "unicast", "destination": "0.0.0.0", "destination-prefixlen": 0,
+					 "network-interface-idref": "nif-4026531840-2", "next-hop": "192.168.0.1", "table": 254}
+				],
+				"ipv6": []
+			},
+			"transport-ports": {
+				"ipv4": [
+					{"protocol": "tcp", "local-address": "0.0.0.0", "local-port": 22, "macrostate": "listening",
+					 "owners": [{"cmdline": "sshd"}]},
+					{"protocol": "tcp", "local-address": "192.168.0.2", "local-port": 22, "macrostate": "connected"}
+				],
+				"ipv6": []
+			},
+			"forwarded-ports": {
+				"ipv4": [
+					{"protocol": "tcp", "ip": "0.0.0.0", "port": 8080, "forward-ip": "172.17.0.2", "forward-port": 80}
+				],
+				"ipv6": []
+			}
+		},
+		{
+			"netnsid": 4026532666,
+			"containers": [
+				{"id": "cont-1234", "name": "web", "type": "docker", "pid": 1234, "status": "running"}
+			],
+			"network-interfaces": [
+				{"id": "nif-4026532666-1", "kind": "", "name": "lo", "operstate": "unknown",
+				 "addresses": {"mac": "00:00:00:00:00:00", "ipv4": [], "ipv6": []}},
+				{"id": "nif-4026532666-7", "kind": "veth", "name": "eth0", "operstate": "up",
+				 "addresses": {"mac": "02:42:ac:11:00:02",
+				   "ipv4": [{"address": "172.17.0.2", "prefixlen": 16}], "ipv6": []}}
+			],
+			"routes": {"ipv4": [], "ipv6": []},
+			"transport-ports": {
+				"ipv4": [{"protocol": "tcp", "local-address": "0.0.0.0", "local-port": 80, "macrostate": "listening"}],
+				"ipv6": []
+			},
+			"forwarded-ports": {"ipv4": [], "ipv6": []}
+		},
+		{
+			"netnsid": 4026532888,
+			"containers": [
+				{"id": "cont-666", "name": "unshare(666)", "type": "proc", "pid": 666, "status": "running"}
+			],
+			"network-interfaces": [],
+			"routes": {"ipv4": [], "ipv6": []},
+			"transport-ports": {"ipv4": [], "ipv6": []},
+			"forwarded-ports": {"ipv4": [], "ipv6": []}
+		}
+	]
+}`
+
+var _ = Describe("snapshots", func() {
+
+	It("rejects invalid JSON", func() {
+		Expect(Load(strings.NewReader("{"))).Error().To(HaveOccurred())
+	})
+
+	It("loads v1 JSON with stable identities", func() {
+		s := Successful(Load(strings.NewReader(v1result)))
+		Expect(s.Time).To(Equal(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)))
+		Expect(s.Netns).To(HaveLen(3))
+		Expect(s.Netns).To(HaveKeyWithValue(HostNetns, HaveKeyWithValue("netnsid", "4026531840")))
+		Expect(s.Netns).To(HaveKey("docker:web"))
+		Expect(s.Netns).To(HaveKey("proc:unshare"))
+		Expect(s.Containers).To(HaveLen(1))
+		Expect(s.Containers).To(HaveKeyWithValue("docker:web", And(
+			HaveKeyWithValue("netns", "docker:web"),
+			HaveKeyWithValue("pid", "1234"))))
+		Expect(s.Interfaces).To(HaveKey("host/eth0"))
+		Expect(s.Interfaces).To(HaveKeyWithValue("docker:web/eth0", HaveKeyWithValue("kind", "veth")))
+		Expect(s.Addresses).To(HaveKey("host/eth0/192.168.0.2/24"))
+		Expect(s.Addresses).To(HaveKey("docker:web/eth0/172.17.0.2/16"))
+		Expect(s.Routes).To(HaveKey("host/unicast default via 192.168.0.1 dev eth0 table 254"))
+		Expect(s.Ports).To(ConsistOf(
+			HaveKeyWithValue("owners", "sshd"),
+			HaveKeyWithValue("owners", "")))
+		Expect(s.Ports).To(HaveKey("host/tcp/0.0.0.0:22"))
+		Expect(s.Ports).To(HaveKey("docker:web/tcp/0.0.0.0:80"))
+		Expect(s.ForwardedPorts).To(HaveKeyWithValue("host/tcp/0.0.0.0:8080",
+			HaveKeyWithValue("forward", "172.17.0.2:80")))
+	})
+
+	It("loads a file", func() {
+		Expect(LoadFile("./nonexisting.json")).Error().To(HaveOccurred())
+		name := GinkgoT().TempDir() + "/result.json"
+		Expect(os.WriteFile(name, []byte(v1result), 0600)).To(Succeed())
+		Expect(Successful(LoadFile(name)).Netns).To(HaveLen(3))
+	})
+
+	It("snapshots a live discovery result", NodeTimeout(30*time.Second), func(ctx context.Context) {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+		s := Successful(FromResult(gostwire.Discover(ctx, cizer, nil)))
+		Expect(s.Netns).NotTo(BeEmpty())
+		Expect(s.Interfaces).To(HaveKey(HaveSuffix("/lo")))
+		Expect(Compare(s, s).Empty()).To(BeTrue())
+	})
+
+})
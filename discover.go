@@ -8,9 +8,9 @@ import (
 	"context"
 
 	_ "github.com/siemens/ghostwire/v2/decorator/all" // activate all Gostwire-specific decorators.
+	"github.com/siemens/ghostwire/v2/diagnostics"
 	"github.com/siemens/ghostwire/v2/internal/discover"
 	"github.com/siemens/ghostwire/v2/network"
-	"github.com/siemens/turtlefinder"
 	"github.com/thediveo/lxkns/containerizer"
 	lxknsdiscover "github.com/thediveo/lxkns/discover"
 	"github.com/thediveo/lxkns/model"
@@ -20,22 +20,33 @@ import (
 // results, as well as the Linux-kernel namespace, process, and container
 // discovery results.
 type DiscoveryResult struct {
-	Netns   network.NetworkNamespaces // network discovery
-	Lxkns   *lxknsdiscover.Result     // namespaces, process, and containers discovery
-	Engines []*model.ContainerEngine  // discovered container engines, even if without workload
+	Netns       network.NetworkNamespaces // network discovery
+	Lxkns       *lxknsdiscover.Result     // namespaces, process, and containers discovery
+	Engines     []*model.ContainerEngine  // discovered container engines, even if without workload
+	Options     DiscoveryOptions          // options the discovery was run with
+	Diagnostics *diagnostics.Diagnostics  // problems encountered and phase timings
 }
 
 // Discover returns the discovered network stacks, virtual network topology, and
 // network-related configuration. Labels optionally control certain aspects of
 // "decorating" (that is, enriching) the discovery results for some decorator
-// plugins supporting labels (such as the ieappicon decorator plugin).
-func Discover(ctx context.Context, cizer containerizer.Containerizer, labels map[string]string) DiscoveryResult {
+// plugins supporting labels (such as the ieappicon decorator plugin). Options
+// optionally restrict the discovery to only some network namespaces, and skip
+// certain parts of the discovery. The result's diagnostics tell about any
+// problems encountered, so that a degraded result can be told from a complete
+// one.
+func Discover(ctx context.Context, cizer containerizer.Containerizer, labels map[string]string, opts ...DiscoveryOption) DiscoveryResult {
 	// break the vicious import cycle which otherwise happens for some unit test
 	// needing discovery.
-	allnetns, nsdisco := discover.Discover(ctx, cizer, labels)
-	var engines []*model.ContainerEngine
-	if overseer, ok := cizer.(turtlefinder.Overseer); ok {
-		engines = overseer.Engines()
+	diags := diagnostics.New()
+	allnetns, nsdisco := discover.Discover(
+		diagnostics.NewContext(ctx, diags), cizer, labels, opts...)
+	options := discover.NewOptions(opts...)
+	return DiscoveryResult{
+		Netns:       allnetns,
+		Lxkns:       nsdisco,
+		Engines:     options.SourceOrLive().Engines(cizer),
+		Options:     options,
+		Diagnostics: diags,
 	}
-	return DiscoveryResult{Netns: allnetns, Lxkns: nsdisco, Engines: engines}
 }
@@ -14,6 +14,8 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/websocket v1.5.3
 	github.com/jinzhu/copier v0.4.0
+	github.com/mattn/go-sqlite3 v1.14.17
+	github.com/mdlayher/netlink v1.7.2
 	github.com/ohler55/ojg v1.23.0
 	github.com/onsi/ginkgo/v2 v2.19.0
 	github.com/onsi/gomega v1.33.1
@@ -26,6 +28,7 @@ require (
 	github.com/spf13/cobra v1.8.1
 	github.com/thediveo/deferrer v0.1.0
 	github.com/thediveo/fdooze v0.3.1
+	github.com/thediveo/go-mntinfo v1.0.2
 	github.com/thediveo/go-plugger/v3 v3.1.0
 	github.com/thediveo/ioctl v0.9.3
 	github.com/thediveo/lxkns v0.36.0
@@ -41,6 +44,7 @@ require (
 	github.com/thediveo/testbasher v1.0.8
 	github.com/thediveo/whalewatcher v0.11.3
 	github.com/vishvananda/netlink v1.2.1-beta.2.0.20240223175432-6ab7f5a3765c
+	github.com/vishvananda/netns v0.0.4
 	golang.org/x/exp v0.0.0-20240719175910-8a7402abbf56
 	golang.org/x/sys v0.22.0
 	golang.org/x/text v0.16.0
@@ -100,8 +104,6 @@ require (
 	github.com/klauspost/compress v1.17.5 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
-	github.com/mattn/go-sqlite3 v1.14.17 // indirect
-	github.com/mdlayher/netlink v1.7.2 // indirect
 	github.com/mdlayher/socket v0.5.0 // indirect
 	github.com/miekg/dns v1.1.59 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
@@ -125,8 +127,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/prometheus-community/pro-bing v0.4.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/thediveo/go-mntinfo v1.0.2 // indirect
-	github.com/vishvananda/netns v0.0.4 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
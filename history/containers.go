@@ -0,0 +1,148 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/diff"
+)
+
+// ContainerState describes a container's network namespace, network interfaces,
+// and open (listening) ports at the time of a snapshot.
+type ContainerState struct {
+	Snapshot   int64            `json:"snapshot"` // ID of the snapshot.
+	Time       time.Time        `json:"time"`     // when the snapshot was taken.
+	Container  string           `json:"container"`
+	Netns      string           `json:"netns"`
+	Status     string           `json:"status"`
+	PID        string           `json:"pid"`
+	Interfaces []InterfaceState `json:"network-interfaces"`
+	Ports      []string         `json:"open-ports"` // such as "tcp/0.0.0.0:80".
+}
+
+// InterfaceState describes a network interface at the time of a snapshot.
+type InterfaceState struct {
+	Name      string   `json:"name"`
+	Operstate string   `json:"operstate"`
+	MAC       string   `json:"mac"`
+	Addresses []string `json:"addresses"` // such as "172.17.0.2/16".
+}
+
+// ContainerHistory returns the states of the specified container in the
+// snapshots taken within the specified time range, oldest first. Zero times
+// leave the range open. The container is specified either by its stable
+// identity, such as "docker:web", or only by its name, such as "web".
+func (s *Store) ContainerHistory(ctx context.Context, container string, from, to time.Time) ([]ContainerState, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT snapshots.id, snapshots.taken, containers.state
+		FROM containers JOIN snapshots ON containers.snapshot = snapshots.id
+		WHERE (containers.container = ?1
+			OR substr(containers.container, -length(?1)-1) = ':' || ?1)
+			AND snapshots.taken >= ?2 AND snapshots.taken <= ?3
+		ORDER BY snapshots.taken, snapshots.id, containers.container`,
+		container, lowerBound(from), upperBound(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	states := []ContainerState{}
+	for rows.Next() {
+		var id, taken int64
+		var jstate string
+		if err := rows.Scan(&id, &taken, &jstate); err != nil {
+			return nil, err
+		}
+		var state ContainerState
+		if err := json.Unmarshal([]byte(jstate), &state); err != nil {
+			return nil, err
+		}
+		state.Snapshot = id
+		state.Time = time.Unix(0, taken)
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// containerStates returns the states of all containers in the specified
+// snapshot. The network interfaces, addresses, and ports of a container are
+// those of its network namespace, relying on the diff package's stable
+// identities of interfaces ("netns/name"), addresses ("netns/name/ip/len"),
+// and open ports ("netns/protocol/address:port").
+func containerStates(snap *diff.Snapshot) []ContainerState {
+	// Index the network interfaces and ports by their network namespaces and
+	// the addresses by their network interfaces once, instead of scanning all
+	// of them for each container and network interface.
+	nifsByNetns := byParent(snap.Interfaces, 0)
+	addrsByNif := byParent(snap.Addresses, 1)
+	portsByNetns := byParent(snap.Ports, 1)
+
+	states := make([]ContainerState, 0, len(snap.Containers))
+	for id, cntr := range snap.Containers {
+		netns := cntr["netns"]
+		state := ContainerState{
+			Container:  id,
+			Netns:      netns,
+			Status:     cntr["status"],
+			PID:        cntr["pid"],
+			Interfaces: []InterfaceState{},
+			Ports:      []string{},
+		}
+		for _, nif := range nifsByNetns[netns] {
+			nifstate := InterfaceState{
+				Name:      nif.name,
+				Operstate: snap.Interfaces[nif.id]["operstate"],
+				MAC:       snap.Interfaces[nif.id]["mac"],
+				Addresses: []string{},
+			}
+			for _, addr := range addrsByNif[nif.id] {
+				nifstate.Addresses = append(nifstate.Addresses, addr.name)
+			}
+			sort.Strings(nifstate.Addresses)
+			state.Interfaces = append(state.Interfaces, nifstate)
+		}
+		sort.Slice(state.Interfaces, func(a, b int) bool {
+			return state.Interfaces[a].Name < state.Interfaces[b].Name
+		})
+		for _, port := range portsByNetns[netns] {
+			state.Ports = append(state.Ports, port.name)
+		}
+		sort.Strings(state.Ports)
+		states = append(states, state)
+	}
+	sort.Slice(states, func(a, b int) bool { return states[a].Container < states[b].Container })
+	return states
+}
+
+// childElement is an element identity together with the remainder of the
+// identity after the identity of its parent.
+type childElement struct {
+	id   string
+	name string
+}
+
+// byParent returns the identities of the specified elements indexed by the
+// identities of their parents, where the remainder of an element's identity
+// after its parent's identity contains exactly the specified number of
+// slashes.
+func byParent(elements map[string]diff.Element, slashes int) map[string][]childElement {
+	children := map[string][]childElement{}
+	for id := range elements {
+		sep := len(id)
+		for n := 0; n <= slashes && sep >= 0; n++ {
+			sep = strings.LastIndexByte(id[:sep], '/')
+		}
+		if sep < 0 {
+			continue
+		}
+		parent := id[:sep]
+		children[parent] = append(children[parent], childElement{id: id, name: id[sep+1:]})
+	}
+	return children
+}
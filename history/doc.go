@@ -0,0 +1,19 @@
+/*
+Package history records discovery results in an embedded SQLite database, so
+that it later becomes possible to look back at how the network looked like at a
+certain point in time, such as when an incident happened.
+
+A Store records snapshots of discovery results in their v1 JSON
+representation. Recording a discovery result that didn't change since the
+latest snapshot, except for its metadata and volatile statistics (see
+apiv1.ContentHash), only updates when the latest snapshot was last seen. In
+addition to the full snapshots, a Store keeps track of the interfaces and open
+ports of each container, so that the history of a single container can be
+queried without loading all snapshots. Snapshots are decoded using the client
+package and containers are identified by the stable identities of the diff
+package, such as "docker:web".
+
+Stores optionally limit the age and number of snapshots retained; the latest
+snapshot is always retained.
+*/
+package history
@@ -0,0 +1,325 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	apiv1 "github.com/siemens/ghostwire/v2/api/v1"
+	"github.com/siemens/ghostwire/v2/client"
+	"github.com/siemens/ghostwire/v2/diff"
+
+	_ "github.com/mattn/go-sqlite3" // pull in "sqlite3" driver
+)
+
+// ErrNotFound is returned when there is no matching snapshot.
+var ErrNotFound = errors.New("no such snapshot")
+
+// dbDriverName is the SQL driver used for the history database.
+const dbDriverName = "sqlite3"
+
+// schema of the history database.
+const schema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	taken  INTEGER NOT NULL, -- UNIX time in ns when the snapshot was taken.
+	seen   INTEGER NOT NULL, -- UNIX time in ns when the snapshot was last seen unchanged.
+	hash   TEXT NOT NULL,    -- hash of the v1 JSON except for its metadata.
+	size   INTEGER NOT NULL, -- size of the uncompressed v1 JSON.
+	result BLOB NOT NULL     -- gzip-compressed v1 JSON.
+);
+CREATE INDEX IF NOT EXISTS snapshots_taken ON snapshots(taken);
+CREATE TABLE IF NOT EXISTS containers (
+	snapshot  INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+	container TEXT NOT NULL, -- stable container identity.
+	state     TEXT NOT NULL  -- JSON ContainerState.
+);
+CREATE INDEX IF NOT EXISTS containers_container ON containers(container);
+CREATE INDEX IF NOT EXISTS containers_snapshot ON containers(snapshot);
+`
+
+// Store is a history of discovery result snapshots stored in an SQLite
+// database.
+type Store struct {
+	db           *sql.DB
+	maxAge       time.Duration
+	maxSnapshots int
+}
+
+// Option configures a Store when passed to Open.
+type Option func(*Store)
+
+// WithMaxAge limits the age of the snapshots retained; zero keeps snapshots
+// regardless of their age.
+func WithMaxAge(d time.Duration) Option {
+	return func(s *Store) {
+		s.maxAge = d
+	}
+}
+
+// WithMaxSnapshots limits the number of snapshots retained; zero keeps any
+// number of snapshots.
+func WithMaxSnapshots(n int) Option {
+	return func(s *Store) {
+		s.maxSnapshots = n
+	}
+}
+
+// Info describes a snapshot without its discovery result.
+type Info struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"` // when the snapshot was taken.
+	Seen time.Time `json:"seen"` // when the snapshot was last seen unchanged.
+	Hash string    `json:"hash"` // hash of the stable projection; see apiv1.ContentHash.
+	Size int       `json:"size"` // size in bytes of the v1 JSON.
+}
+
+// Open opens the history database with the specified file name, creating it if
+// necessary.
+func Open(name string, opts ...Option) (*Store, error) {
+	db, err := sql.Open(dbDriverName,
+		"file:"+name+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite doesn't like concurrent writers, so we serialize all database
+	// accesses.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Close closes the history database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record records the specified discovery result taken at the specified time,
+// returning the information about the snapshot recorded or, if the discovery
+// result didn't change, about the updated latest snapshot.
+func (s *Store) Record(ctx context.Context, at time.Time, result gostwire.DiscoveryResult) (Info, error) {
+	v1result := apiv1.NewDiscoveryResult(result)
+	v1json, err := json.Marshal(&v1result)
+	if err != nil {
+		return Info{}, err
+	}
+	return s.RecordJSON(ctx, at, v1json)
+}
+
+// RecordJSON records the specified v1 JSON discovery result taken at the
+// specified time, returning the information about the snapshot recorded or, if
+// the discovery result didn't change, about the updated latest snapshot.
+func (s *Store) RecordJSON(ctx context.Context, at time.Time, v1json []byte) (Info, error) {
+	hash, err := apiv1.ContentHash(v1json)
+	if err != nil {
+		return Info{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	latest, err := scanInfo(tx.QueryRowContext(ctx,
+		`SELECT id, taken, seen, hash, size FROM snapshots ORDER BY taken DESC, id DESC LIMIT 1`))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Info{}, err
+	}
+	if err == nil && latest.Hash == hash && !at.Before(latest.Seen) {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE snapshots SET seen=? WHERE id=?`, at.UnixNano(), latest.ID); err != nil {
+			return Info{}, err
+		}
+		latest.Seen = at
+		return latest, tx.Commit()
+	}
+
+	result, err := client.Load(bytes.NewReader(v1json))
+	if err != nil {
+		return Info{}, err
+	}
+	snap := diff.FromClient(result)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(v1json); err != nil {
+		return Info{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Info{}, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO snapshots (taken, seen, hash, size, result) VALUES (?, ?, ?, ?, ?)`,
+		at.UnixNano(), at.UnixNano(), hash, len(v1json), compressed.Bytes())
+	if err != nil {
+		return Info{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Info{}, err
+	}
+	for _, state := range containerStates(snap) {
+		jstate, err := json.Marshal(state)
+		if err != nil {
+			return Info{}, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO containers (snapshot, container, state) VALUES (?, ?, ?)`,
+			id, state.Container, string(jstate)); err != nil {
+			return Info{}, err
+		}
+	}
+	if err := s.prune(ctx, tx, at); err != nil {
+		return Info{}, err
+	}
+	return Info{ID: id, Time: at, Seen: at, Hash: hash, Size: len(v1json)}, tx.Commit()
+}
+
+// prune removes the snapshots beyond the retention limits, but never the
+// latest snapshot.
+func (s *Store) prune(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	if s.maxAge > 0 {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM snapshots WHERE taken < ? AND id != (SELECT MAX(id) FROM snapshots)`,
+			now.Add(-s.maxAge).UnixNano()); err != nil {
+			return err
+		}
+	}
+	if s.maxSnapshots > 0 {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM snapshots WHERE id NOT IN (
+				SELECT id FROM snapshots ORDER BY taken DESC, id DESC LIMIT ?)`,
+			s.maxSnapshots); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the information about the snapshots taken within the specified
+// time range, oldest first. Zero times leave the range open. A limit of zero
+// returns all snapshots in the range, otherwise only the latest limit
+// snapshots.
+func (s *Store) List(ctx context.Context, from, to time.Time, limit int) ([]Info, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, taken, seen, hash, size FROM (
+			SELECT id, taken, seen, hash, size FROM snapshots
+			WHERE taken >= ? AND taken <= ?
+			ORDER BY taken DESC, id DESC LIMIT ?)
+		ORDER BY taken, id`,
+		lowerBound(from), upperBound(to), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	infos := []Info{}
+	for rows.Next() {
+		info, err := scanInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// AsOf returns the snapshot information and the v1 JSON discovery result of
+// the snapshot current at the specified time, or ErrNotFound if there is no
+// snapshot that old.
+func (s *Store) AsOf(ctx context.Context, at time.Time) (Info, []byte, error) {
+	return s.get(ctx,
+		`SELECT id, taken, seen, hash, size, result FROM snapshots
+		WHERE taken <= ? ORDER BY taken DESC, id DESC LIMIT 1`, at.UnixNano())
+}
+
+// Get returns the snapshot information and the v1 JSON discovery result of the
+// snapshot with the specified ID, or ErrNotFound.
+func (s *Store) Get(ctx context.Context, id int64) (Info, []byte, error) {
+	return s.get(ctx,
+		`SELECT id, taken, seen, hash, size, result FROM snapshots WHERE id=?`, id)
+}
+
+// get returns the snapshot information and the v1 JSON discovery result
+// returned by the specified query.
+func (s *Store) get(ctx context.Context, query string, args ...interface{}) (Info, []byte, error) {
+	var info Info
+	var taken, seen int64
+	var compressed []byte
+	err := s.db.QueryRowContext(ctx, query, args...).
+		Scan(&info.ID, &taken, &seen, &info.Hash, &info.Size, &compressed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Info{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, nil, err
+	}
+	info.Time = time.Unix(0, taken)
+	info.Seen = time.Unix(0, seen)
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return Info{}, nil, err
+	}
+	v1json, err := io.ReadAll(zr)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	return info, v1json, nil
+}
+
+// scanner is either a single sql.Row or sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanInfo returns the snapshot information scanned from the specified row.
+func scanInfo(row scanner) (Info, error) {
+	var info Info
+	var taken, seen int64
+	err := row.Scan(&info.ID, &taken, &seen, &info.Hash, &info.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	info.Time = time.Unix(0, taken)
+	info.Seen = time.Unix(0, seen)
+	return info, nil
+}
+
+// lowerBound returns the UNIX time in ns of the specified time, or the minimum
+// time for the zero time.
+func lowerBound(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// upperBound returns the UNIX time in ns of the specified time, or the maximum
+// time for the zero time.
+func upperBound(t time.Time) int64 {
+	if t.IsZero() {
+		return 1<<63 - 1
+	}
+	return t.UnixNano()
+}
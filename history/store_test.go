@@ -0,0 +1,222 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/turtlefinder"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// v1result returns a (heavily) reduced v1 JSON discovery result with the host
+// network namespace and a single container network namespace, with the
+// container's eth0 in the specified operational state and listening on the
+// specified TCP port. The creation timestamp and the socket statistics are
+// intentionally varying, as they must not influence whether a result changed.
+func v1result(operstate string, port int) []byte {
+	return []byte(fmt.Sprintf(`{
+	"metadata": {"creation-timestamp": %q},
+	"network-namespaces": [
+		{
+			"netnsid": 4026531840,
+			"containers": [
+				{"id": "cont-1", "name": "systemd", "type": "proc", "pid": 1, "status": "running"}
+			],
+			"network-interfaces": [
+				{"id": "nif-4026531840-1", "kind": "", "name": "lo", "operstate": "unknown",
+				 "addresses": {"mac": "00:00:00:00:00:00",
+				   "ipv4": [{"address": "127.0.0.1", "prefixlen": 8}], "ipv6": []}}
+			],
+			"routes": {"ipv4": [], "ipv6": []},
+			"transport-ports": {"ipv4": [], "ipv6": []},
+			"forwarded-ports": {"ipv4": [], "ipv6": []}
+		},
+		{
+			"netnsid": 4026532666,
+			"containers": [
+				{"id": "cont-1234", "name": "web", "type": "docker", "pid": 1234, "status": "running"}
+			],
+			"network-interfaces": [
+				{"id": "nif-4026532666-1", "kind": "", "name": "lo", "operstate": "unknown",
+				 "addresses": {"mac": "00:00:00:00:00:00", "ipv4": [], "ipv6": []}},
+				{"id": "nif-4026532666-7", "kind": "veth", "name": "eth0", "operstate": %q,
+				 "addresses": {"mac": "02:42:ac:11:00:02",
+				   "ipv4": [{"address": "172.17.0.2", "prefixlen": 16}], "ipv6": []}}
+			],
+			"routes": {"ipv4": [], "ipv6": []},
+			"transport-ports": {
+				"ipv4": [{"protocol": "tcp", "local-address": "0.0.0.0", "local-port": %d, "macrostate": "listening",
+				          "recv-queue": %d, "send-queue": 0, "tcp-info": {"rtt": %d}}],
+				"ipv6": []
+			},
+			"forwarded-ports": {"ipv4": [], "ipv6": []}
+		}
+	]
+}`, time.Now().Format(time.RFC3339Nano), operstate, port,
+		time.Now().Nanosecond()%100, time.Now().Nanosecond()))
+}
+
+var t0 = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return t0.Add(time.Duration(minutes) * time.Minute)
+}
+
+func openStore(opts ...Option) *Store {
+	s := Successful(Open(GinkgoT().TempDir()+"/history.db", opts...))
+	DeferCleanup(func() { _ = s.Close() })
+	return s
+}
+
+var _ = Describe("history store", func() {
+
+	It("fails on an invalid database file", func() {
+		name := GinkgoT().TempDir() + "/bogus.db"
+		Expect(os.WriteFile(name, []byte("not a database, definitely not"), 0600)).To(Succeed())
+		Expect(Open(name)).Error().To(HaveOccurred())
+	})
+
+	It("rejects invalid JSON", func(ctx context.Context) {
+		s := openStore()
+		Expect(s.RecordJSON(ctx, at(0), []byte("{"))).Error().To(HaveOccurred())
+		Expect(s.List(ctx, time.Time{}, time.Time{}, 0)).To(BeEmpty())
+	})
+
+	It("records only changed results", func(ctx context.Context) {
+		s := openStore()
+		first := Successful(s.RecordJSON(ctx, at(0), v1result("up", 80)))
+		Expect(first.ID).NotTo(BeZero())
+		Expect(first.Time).To(BeTemporally("==", at(0)))
+
+		same := Successful(s.RecordJSON(ctx, at(1), v1result("up", 80)))
+		Expect(same.ID).To(Equal(first.ID))
+		Expect(same.Time).To(BeTemporally("==", at(0)))
+		Expect(same.Seen).To(BeTemporally("==", at(1)))
+
+		changed := Successful(s.RecordJSON(ctx, at(2), v1result("down", 80)))
+		Expect(changed.ID).NotTo(Equal(first.ID))
+		Expect(changed.Hash).NotTo(Equal(first.Hash))
+
+		infos := Successful(s.List(ctx, time.Time{}, time.Time{}, 0))
+		Expect(infos).To(HaveLen(2))
+		Expect(infos[0].ID).To(Equal(first.ID))
+		Expect(infos[0].Seen).To(BeTemporally("==", at(1)))
+		Expect(infos[1].ID).To(Equal(changed.ID))
+
+		Expect(s.List(ctx, at(1), time.Time{}, 0)).To(ConsistOf(
+			HaveField("ID", changed.ID)))
+		Expect(s.List(ctx, time.Time{}, at(1), 0)).To(ConsistOf(
+			HaveField("ID", first.ID)))
+		Expect(s.List(ctx, time.Time{}, time.Time{}, 1)).To(ConsistOf(
+			HaveField("ID", changed.ID)))
+	})
+
+	It("travels in time", func(ctx context.Context) {
+		s := openStore()
+		first := Successful(s.RecordJSON(ctx, at(0), v1result("up", 80)))
+		second := Successful(s.RecordJSON(ctx, at(10), v1result("down", 80)))
+
+		_, _, err := s.AsOf(ctx, at(-1))
+		Expect(err).To(MatchError(ErrNotFound))
+
+		info, v1json, err := s.AsOf(ctx, at(5))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ID).To(Equal(first.ID))
+		Expect(string(v1json)).To(ContainSubstring(`"operstate": "up"`))
+		Expect(json.Valid(v1json)).To(BeTrue())
+
+		info, v1json, err = s.AsOf(ctx, at(60))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ID).To(Equal(second.ID))
+		Expect(string(v1json)).To(ContainSubstring(`"operstate": "down"`))
+
+		info, _, err = s.Get(ctx, first.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Time).To(BeTemporally("==", at(0)))
+		_, _, err = s.Get(ctx, 666)
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("returns the history of a container", func(ctx context.Context) {
+		s := openStore()
+		Expect(s.RecordJSON(ctx, at(0), v1result("up", 80))).Error().NotTo(HaveOccurred())
+		Expect(s.RecordJSON(ctx, at(1), v1result("down", 80))).Error().NotTo(HaveOccurred())
+		Expect(s.RecordJSON(ctx, at(2), v1result("up", 8080))).Error().NotTo(HaveOccurred())
+
+		Expect(s.ContainerHistory(ctx, "docker:foo", time.Time{}, time.Time{})).To(BeEmpty())
+		Expect(s.ContainerHistory(ctx, "eb", time.Time{}, time.Time{})).To(BeEmpty())
+
+		states := Successful(s.ContainerHistory(ctx, "web", time.Time{}, time.Time{}))
+		Expect(states).To(HaveLen(3))
+		Expect(states[0]).To(And(
+			HaveField("Container", "docker:web"),
+			HaveField("Netns", "docker:web"),
+			HaveField("PID", "1234"),
+			HaveField("Interfaces", ConsistOf(
+				HaveField("Name", "lo"),
+				And(HaveField("Name", "eth0"),
+					HaveField("Operstate", "up"),
+					HaveField("Addresses", ConsistOf("172.17.0.2/16"))))),
+			HaveField("Ports", ConsistOf("tcp/0.0.0.0:80"))))
+		Expect(states[0].Time).To(BeTemporally("==", at(0)))
+		Expect(states[1].Interfaces).To(ContainElement(And(
+			HaveField("Name", "eth0"), HaveField("Operstate", "down"))))
+		Expect(states[2].Ports).To(ConsistOf("tcp/0.0.0.0:8080"))
+
+		Expect(s.ContainerHistory(ctx, "docker:web", at(1), at(1))).To(ConsistOf(
+			HaveField("Snapshot", states[1].Snapshot)))
+	})
+
+	It("prunes snapshots by number", func(ctx context.Context) {
+		s := openStore(WithMaxSnapshots(2))
+		for idx := 0; idx < 5; idx++ {
+			Expect(s.RecordJSON(ctx, at(idx), v1result("up", 80+idx))).Error().NotTo(HaveOccurred())
+		}
+		infos := Successful(s.List(ctx, time.Time{}, time.Time{}, 0))
+		Expect(infos).To(HaveLen(2))
+		Expect(infos[0].Time).To(BeTemporally("==", at(3)))
+		Expect(s.ContainerHistory(ctx, "web", time.Time{}, time.Time{})).To(HaveLen(2))
+	})
+
+	It("prunes snapshots by age, but keeps the latest", func(ctx context.Context) {
+		s := openStore(WithMaxAge(time.Hour))
+		Expect(s.RecordJSON(ctx, at(0), v1result("up", 80))).Error().NotTo(HaveOccurred())
+		Expect(s.RecordJSON(ctx, at(30), v1result("up", 81))).Error().NotTo(HaveOccurred())
+		Expect(s.RecordJSON(ctx, at(90), v1result("up", 81))).Error().NotTo(HaveOccurred())
+		Expect(s.List(ctx, time.Time{}, time.Time{}, 0)).To(HaveLen(2))
+
+		Expect(s.RecordJSON(ctx, at(120), v1result("up", 82))).Error().NotTo(HaveOccurred())
+		infos := Successful(s.List(ctx, time.Time{}, time.Time{}, 0))
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Time).To(BeTemporally("==", at(120)))
+	})
+
+	It("records a live discovery result", NodeTimeout(30*time.Second), func(ctx context.Context) {
+		if os.Getuid() != 0 {
+			Skip("needs root")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		cizer := turtlefinder.New(func() context.Context { return ctx })
+		defer cizer.Close()
+		s := openStore()
+		result := gostwire.Discover(ctx, cizer, nil)
+		info := Successful(s.Record(ctx, time.Now(), result))
+		Expect(Successful(s.Record(ctx, time.Now(), result)).ID).To(Equal(info.ID))
+		_, v1json, err := s.Get(ctx, info.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v1json).To(HaveLen(info.Size))
+	})
+
+})
@@ -6,53 +6,140 @@ package discover
 
 import (
 	"context"
+	"time"
 
 	"github.com/siemens/ghostwire/v2/decorator"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 	"github.com/siemens/ghostwire/v2/network"
-	"github.com/siemens/turtlefinder"
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/containerizer"
 	lxknsdiscover "github.com/thediveo/lxkns/discover"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
 	"github.com/thediveo/lxkns/species"
+	"golang.org/x/exp/slices"
 )
 
 // Discover returns the discovered network stacks, virtual network topology, and
 // network-related configuration. Labels optionally control certain aspects of
 // "decorating" (that is, enriching) the discovery results for some decorator
-// plugins supporting labels (such as the ieappicon decorator plugin).
-func Discover(ctx context.Context, cizer containerizer.Containerizer, labels map[string]string) (network.NetworkNamespaces, *lxknsdiscover.Result) {
+// plugins supporting labels (such as the ieappicon decorator plugin). Options
+// optionally restrict the discovery to only some network namespaces and skip
+// certain parts of the discovery. Each decorator runs with its own timeout and
+// panic recovery, and decorators failing repeatedly get suspended for a while.
+// Problems encountered during discovery as well
+// as the phase timings are collected in the diagnostics carried by the
+// context, if any. All system information is taken from the source in the
+// options, falling back to the live source.
+func Discover(ctx context.Context, cizer containerizer.Containerizer, labels map[string]string, opts ...Option) (network.NetworkNamespaces, *lxknsdiscover.Result) {
+	o := NewOptions(opts...)
+	src := o.SourceOrLive()
+	diags := diagnostics.FromContext(ctx)
 	// First phase: run a Linux-kernel namespace (+container) discovery,
 	// courtesy of lxkns.
-	discoverednetns := lxknsdiscover.Namespaces(
-		lxknsdiscover.FromProcs(),
-		lxknsdiscover.FromBindmounts(),
-		lxknsdiscover.WithNamespaceTypes(
-			species.CLONE_NEWNET|species.CLONE_NEWPID|species.CLONE_NEWNS|species.CLONE_NEWUTS),
-		lxknsdiscover.WithHierarchy(),
-		lxknsdiscover.WithContainerizer(cizer),
-		lxknsdiscover.WithPIDMapper(),
-		lxknsdiscover.WithLabels(labels),
-		lxknsdiscover.WithAffinityAndScheduling(),
-	)
+	start := time.Now()
+	discoverednetns := src.Namespaces(cizer, labels)
+	diags.Time(diagnostics.PhaseNamespaces, start)
 	// Second phase: create the Gostwire-specific information model based on the
 	// lxkns discovery and augment the model with additional network-related
 	// details, such as tenant DNS resolver configuration, Docker network names,
 	// et cetera.
 	log.Debugf("discovering network namespace details (interfaces, address, routes, ...)")
+	start = time.Now()
+	netctx, cancel := withOptionalTimeout(ctx, o.NetworkTimeout)
+	defer cancel()
 	allnetns := network.NewNetworkNamespaces(
 		discoverednetns.Namespaces[model.NetNS],
 		discoverednetns.Processes,
-		discoverednetns.Containers)
-	engines := []*model.ContainerEngine{}
-	if overseer, ok := cizer.(turtlefinder.Overseer); ok {
-		engines = overseer.Engines()
-	}
+		discoverednetns.Containers,
+		networkOptions(netctx, &o, discoverednetns)...)
+	diags.Time(diagnostics.PhaseNetwork, start)
+	engines := src.Engines(cizer)
 	log.Debugf("running gostwire decorators")
-	for _, decorateur := range plugger.Group[decorator.Decorate]().Symbols() {
-		decorateur(ctx, allnetns, discoverednetns.Processes, engines)
+	start = time.Now()
+	decoctx, cancel := withOptionalTimeout(network.NewContextWithSource(ctx, src), o.DecoratorsTimeout)
+	defer cancel()
+	for _, decorateur := range plugger.Group[decorator.Decorate]().PluginsSymbols() {
+		if o.SkipsDecorator(decorateur.Plugin) {
+			log.Debugf("skipping decorator '%s'", decorateur.Plugin)
+			diags.Ran(diagnostics.PluginRun{
+				Plugin: decorateur.Plugin, Group: diagnostics.DecoratorPlugin, Outcome: diagnostics.OutcomeSkipped})
+			continue
+		}
+		if err := decoctx.Err(); err != nil {
+			diags.Warnf(diagnostics.DecoratorSkipped, diagnostics.Scope{Plugin: decorateur.Plugin},
+				"skipping decorator '%s', reason: %s", decorateur.Plugin, err.Error())
+			diags.Ran(diagnostics.PluginRun{
+				Plugin: decorateur.Plugin, Group: diagnostics.DecoratorPlugin, Outcome: diagnostics.OutcomeSkipped})
+			continue
+		}
+		if guard.Suspended(diags, diagnostics.DecoratorPlugin, decorateur.Plugin) {
+			continue
+		}
+		start := time.Now()
+		err := guard.Call(decoctx, o.PluginTimeoutOf(decorateur.Plugin), func(ctx context.Context) {
+			decorateur.S(ctx, allnetns, discoverednetns.Processes, engines)
+		})
+		guard.Finished(diags, diagnostics.DecoratorPlugin, decorateur.Plugin, start, err)
 	}
+	diags.Time(diagnostics.PhaseDecorators, start)
 	log.Debugf("gostwire discovery finished")
 	return allnetns, discoverednetns
 }
+
+// networkOptions returns the network discovery options corresponding with the
+// specified discovery options.
+func networkOptions(ctx context.Context, o *Options, discoverednetns *lxknsdiscover.Result) []network.DiscoveryOption {
+	nopts := []network.DiscoveryOption{
+		network.WithContext(ctx),
+		network.WithMaxWorkers(o.MaxWorkers),
+		network.WithDiagnostics(diagnostics.FromContext(ctx)),
+		network.WithSource(o.SourceOrLive()),
+	}
+	if o.Restricted() {
+		nopts = append(nopts, network.WithNetworkNamespaces(
+			restrictedNetworkNamespaces(o, discoverednetns.Containers)...))
+	}
+	if o.SkipSockets {
+		nopts = append(nopts, network.WithoutSockets())
+	}
+	if o.SkipForwardedPorts {
+		nopts = append(nopts, network.WithoutForwardedPorts())
+	}
+	if o.SkipDNS {
+		nopts = append(nopts, network.WithoutDNS())
+	}
+	return nopts
+}
+
+// restrictedNetworkNamespaces returns the IDs of the network namespaces to
+// restrict the discovery to: the explicitly specified network namespaces, as
+// well as the network namespaces of the specified containers and of the
+// containers managed by the specified container engines.
+func restrictedNetworkNamespaces(o *Options, containers model.Containers) []species.NamespaceID {
+	netnsids := slices.Clone(o.NetworkNamespaces)
+	for _, cntr := range containers {
+		if !slices.Contains(o.Containers, cntr.Name) && !slices.Contains(o.Containers, cntr.ID) &&
+			(cntr.Engine == nil ||
+				(!slices.Contains(o.Engines, cntr.Engine.Type) && !slices.Contains(o.Engines, cntr.Engine.ID))) {
+			continue
+		}
+		if cntr.Process == nil {
+			continue
+		}
+		if netns := cntr.Process.Namespaces[model.NetNS]; netns != nil {
+			netnsids = append(netnsids, netns.ID())
+		}
+	}
+	return netnsids
+}
+
+// withOptionalTimeout returns a context with the specified timeout, unless the
+// timeout is zero, where it returns a cancellable context without any timeout.
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,106 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discover
+
+import (
+	"time"
+
+	"github.com/thediveo/lxkns/species"
+	"golang.org/x/exp/slices"
+)
+
+// DefaultPluginTimeout is the time each decorator and metadata plugin gets to
+// finish, unless specified otherwise.
+const DefaultPluginTimeout = 10 * time.Second
+
+// Options controls the scope of a discovery run as well as which parts of the
+// discovery to skip. The zero value discovers everything without any per-phase
+// time limits, but with each plugin limited to the DefaultPluginTimeout.
+type Options struct {
+	// Restricts the network discovery to the specified network namespaces,
+	// the network namespaces of the specified containers (names or IDs), and
+	// the network namespaces of the containers managed by the specified
+	// container engines (types or IDs). If all are empty, then all network
+	// namespaces are discovered.
+	NetworkNamespaces []species.NamespaceID
+	Containers        []string
+	Engines           []string
+
+	SkipSockets        bool     // skip discovering sockets and their processes.
+	SkipForwardedPorts bool     // skip discovering forwarded ports.
+	SkipDNS            bool     // skip discovering the DNS configuration of tenants.
+	SkipDecorators     []string // names of decorator plugins to skip.
+	SkipMetadata       []string // names of metadata plugins to skip.
+
+	// Per-phase timeouts, with zero meaning no timeout. Please note that the
+	// initial namespace and container discovery cannot be timed out.
+	NetworkTimeout    time.Duration // network namespace details discovery.
+	DecoratorsTimeout time.Duration // running all decorator plugins.
+	MetadataTimeout   time.Duration // running all metadata plugins.
+
+	// Per-plugin timeouts: PluginTimeouts maps individual plugin names to their
+	// timeouts, while PluginTimeout applies to all other plugins. Zero means
+	// DefaultPluginTimeout and negative timeouts mean no timeout.
+	PluginTimeout  time.Duration
+	PluginTimeouts map[string]time.Duration
+
+	MaxWorkers int // maximum concurrent network namespace discoveries; 0 for default.
+
+	Source Source // source of the system information; nil for the live source.
+}
+
+// Option sets a particular discovery option.
+type Option func(*Options)
+
+// NewOptions returns the discovery options resulting from applying the
+// specified options to the zero Options.
+func NewOptions(opts ...Option) Options {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Restricted returns true if the discovery is restricted to only some network
+// namespaces.
+func (o *Options) Restricted() bool {
+	return len(o.NetworkNamespaces) != 0 || len(o.Containers) != 0 || len(o.Engines) != 0
+}
+
+// SourceOrLive returns the source of the system information to discover,
+// falling back to the live source.
+func (o *Options) SourceOrLive() Source {
+	if o.Source == nil {
+		return LiveSource()
+	}
+	return o.Source
+}
+
+// SkipsDecorator returns true if the named decorator plugin is to be skipped.
+func (o *Options) SkipsDecorator(name string) bool {
+	return slices.Contains(o.SkipDecorators, name)
+}
+
+// SkipsMetadata returns true if the named metadata plugin is to be skipped.
+func (o *Options) SkipsMetadata(name string) bool {
+	return slices.Contains(o.SkipMetadata, name)
+}
+
+// PluginTimeoutOf returns the timeout for the named decorator or metadata
+// plugin, with zero meaning no timeout.
+func (o *Options) PluginTimeoutOf(name string) time.Duration {
+	timeout, ok := o.PluginTimeouts[name]
+	if !ok || timeout == 0 {
+		timeout = o.PluginTimeout
+	}
+	switch {
+	case timeout == 0:
+		return DefaultPluginTimeout
+	case timeout < 0:
+		return 0
+	}
+	return timeout
+}
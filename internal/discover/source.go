@@ -0,0 +1,63 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package discover
+
+import (
+	"github.com/siemens/ghostwire/v2/network"
+	"github.com/siemens/turtlefinder"
+	"github.com/thediveo/lxkns/containerizer"
+	lxknsdiscover "github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+)
+
+// Source supplies a discovery with all the system information it is based on.
+// Besides the information needed by the network discovery, this is the
+// Linux-kernel namespace, process, and container discovery as well as the
+// container engines.
+type Source interface {
+	network.Source
+	// Namespaces returns the namespaces, processes, and containers, with the
+	// containers discovered using the specified containerizer.
+	Namespaces(cizer containerizer.Containerizer, labels map[string]string) *lxknsdiscover.Result
+	// Engines returns the container engines, even without any workload.
+	Engines(cizer containerizer.Containerizer) []*model.ContainerEngine
+}
+
+// LiveSource returns the Source discovering the host the discovery runs on.
+func LiveSource() Source {
+	return liveSource{Source: network.LiveSource()}
+}
+
+// liveSource discovers the host the discovery runs on.
+type liveSource struct {
+	network.Source
+}
+
+// Namespaces runs a Linux-kernel namespace (+container) discovery, courtesy of
+// lxkns.
+func (liveSource) Namespaces(cizer containerizer.Containerizer, labels map[string]string) *lxknsdiscover.Result {
+	return lxknsdiscover.Namespaces(
+		lxknsdiscover.FromProcs(),
+		lxknsdiscover.FromBindmounts(),
+		lxknsdiscover.WithNamespaceTypes(
+			species.CLONE_NEWNET|species.CLONE_NEWPID|species.CLONE_NEWNS|species.CLONE_NEWUTS),
+		lxknsdiscover.WithHierarchy(),
+		lxknsdiscover.WithContainerizer(cizer),
+		lxknsdiscover.WithPIDMapper(),
+		lxknsdiscover.WithLabels(labels),
+		lxknsdiscover.WithAffinityAndScheduling(),
+	)
+}
+
+// Engines returns the container engines watched by the specified containerizer,
+// if it is a turtlefinder.
+func (liveSource) Engines(cizer containerizer.Containerizer) []*model.ContainerEngine {
+	engines := []*model.ContainerEngine{}
+	if overseer, ok := cizer.(turtlefinder.Overseer); ok {
+		engines = overseer.Engines()
+	}
+	return engines
+}
@@ -0,0 +1,125 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"sync"
+	"time"
+)
+
+// Default settings of the Breakers circuit breaker.
+const (
+	DefaultThreshold = 3               // consecutive failures tripping a breaker.
+	DefaultCooldown  = 1 * time.Minute // time to skip after tripping.
+)
+
+// Breakers is the circuit breaker shared by all discoveries, keyed by plugin
+// names or by plugin-specific keys, such as container engine API paths.
+var Breakers = NewBreaker(DefaultThreshold, DefaultCooldown)
+
+// Breaker is a set of circuit breakers keyed by strings. After a key has seen
+// threshold consecutive failures, the breaker for this key trips and the key
+// isn't allowed anymore for the duration of the cooldown. After the cooldown a
+// single attempt is allowed again: if it succeeds, the breaker gets reset,
+// otherwise it immediately trips again. A Breaker is safe for concurrent use.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+// breakerState is the state of the breaker for a single key.
+type breakerState struct {
+	failures int       // consecutive failures.
+	openedAt time.Time // when tripped; zero while closed.
+	probing  bool      // single attempt after cooldown in progress.
+}
+
+// NewBreaker returns a new Breaker tripping after the specified number of
+// consecutive failures and then skipping for the specified cooldown period.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		states:    map[string]*breakerState{},
+	}
+}
+
+// Configure changes the number of consecutive failures tripping breakers and
+// the cooldown period of tripped breakers, such as when configuring the
+// Breakers from CLI flags. A threshold of zero or less disables the breakers.
+func (b *Breaker) Configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// Allow returns true if the specified key should be attempted, or false if
+// it is to be skipped because its breaker has tripped and is cooling down.
+func (b *Breaker) Allow(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[key]
+	if !ok || state.openedAt.IsZero() || b.threshold <= 0 {
+		return true
+	}
+	if state.probing || b.now().Sub(state.openedAt) < b.cooldown {
+		return false
+	}
+	state.probing = true
+	return true
+}
+
+// Success resets the breaker for the specified key.
+func (b *Breaker) Success(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, key)
+}
+
+// Failure records a failure for the specified key, tripping its breaker after
+// reaching the threshold of consecutive failures, or when the single attempt
+// after a cooldown failed.
+func (b *Breaker) Failure(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[key]
+	if !ok {
+		state = &breakerState{}
+		b.states[key] = state
+	}
+	state.failures++
+	if state.probing || state.failures >= b.threshold {
+		state.openedAt = b.now()
+		state.probing = false
+	}
+}
+
+// Abandon ends a single attempt after a cooldown without recording its
+// outcome, such as when the attempt got cancelled before it could succeed or
+// fail. The breaker stays tripped, but allows another single attempt with the
+// next Allow.
+func (b *Breaker) Abandon(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state, ok := b.states[key]; ok {
+		state.probing = false
+	}
+}
+
+// Record records the outcome of an attempt for the specified key: a nil error
+// means success, otherwise failure.
+func (b *Breaker) Record(key string, err error) {
+	if err != nil {
+		b.Failure(key)
+		return
+	}
+	b.Success(key)
+}
@@ -0,0 +1,82 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("circuit breaker", func() {
+
+	var now time.Time
+	var b *Breaker
+
+	BeforeEach(func() {
+		now = time.Now()
+		b = NewBreaker(2, time.Minute)
+		b.now = func() time.Time { return now }
+	})
+
+	It("trips after consecutive failures", func() {
+		Expect(b.Allow("foo")).To(BeTrue())
+		b.Failure("foo")
+		Expect(b.Allow("foo")).To(BeTrue())
+		b.Success("foo")
+		b.Failure("foo")
+		Expect(b.Allow("foo")).To(BeTrue())
+		b.Record("foo", errors.New("D'oh!"))
+		Expect(b.Allow("foo")).To(BeFalse())
+		Expect(b.Allow("bar")).To(BeTrue())
+	})
+
+	It("allows a single attempt after cooldown", func() {
+		b.Failure("foo")
+		b.Failure("foo")
+		Expect(b.Allow("foo")).To(BeFalse())
+
+		now = now.Add(time.Minute)
+		Expect(b.Allow("foo")).To(BeTrue())
+		Expect(b.Allow("foo")).To(BeFalse())
+		b.Failure("foo")
+		Expect(b.Allow("foo")).To(BeFalse())
+
+		now = now.Add(time.Minute)
+		Expect(b.Allow("foo")).To(BeTrue())
+		b.Record("foo", nil)
+		Expect(b.Allow("foo")).To(BeTrue())
+		Expect(b.Allow("foo")).To(BeTrue())
+	})
+
+	It("allows another attempt after an abandoned one", func() {
+		b.Failure("foo")
+		b.Failure("foo")
+
+		now = now.Add(time.Minute)
+		Expect(b.Allow("foo")).To(BeTrue())
+		Expect(b.Allow("foo")).To(BeFalse())
+		b.Abandon("foo")
+		Expect(b.Allow("foo")).To(BeTrue())
+		b.Abandon("foo")
+
+		b.Abandon("bar")
+		Expect(b.Allow("bar")).To(BeTrue())
+	})
+
+	It("can be reconfigured", func() {
+		b.Configure(1, time.Hour)
+		b.Failure("foo")
+		Expect(b.Allow("foo")).To(BeFalse())
+		now = now.Add(time.Minute)
+		Expect(b.Allow("foo")).To(BeFalse())
+
+		b.Configure(0, time.Hour)
+		Expect(b.Allow("foo")).To(BeTrue())
+	})
+
+})
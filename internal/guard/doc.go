@@ -0,0 +1,15 @@
+/*
+Package guard limits the damage misbehaving plugins can do to discoveries. Call
+and CallDetached run plugin functions with their own timeout and recover from
+panics, while a Breaker temporarily skips plugins (or the container engines
+they talk to) after they repeatedly failed.
+
+Please note that Call always waits for its function to return, as decorators
+modify the discovery result in place and thus cannot be left behind. A hung
+decorator ignoring its context, such as one stuck in a container engine API
+call without a deadline, consequently still blocks the discovery; its timeout
+only counts as a failure towards tripping its breaker, so that later
+discoveries skip it. Only CallDetached, as used for metadata plugins, stops
+waiting at the timeout.
+*/
+package guard
@@ -0,0 +1,95 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout signals that a guarded function didn't finish in time.
+var ErrTimeout = errors.New("timeout")
+
+// PanicError signals that a guarded function panicked.
+type PanicError struct {
+	Value interface{} // value passed to panic.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Call calls fn with a context that is done after the specified timeout (if
+// non-zero), recovering from any panic in fn. Call always waits for fn to
+// return, so fn must honor its context in order to not block the caller for
+// longer than the timeout. Call thus is suitable for functions modifying data
+// shared with the caller, such as decorators.
+//
+// Call returns ErrTimeout if fn only returned after the timeout, or a
+// *PanicError if fn panicked.
+func Call(ctx context.Context, timeout time.Duration, fn func(ctx context.Context)) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	if err := recovered(func() { fn(ctx) }); err != nil {
+		return err
+	}
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return nil
+}
+
+// CallDetached calls fn in a separate goroutine with a context that is done
+// after the specified timeout (if non-zero), recovering from any panic in fn.
+// In contrast to Call, CallDetached returns as soon as the timeout is reached,
+// returning ErrTimeout and leaving fn running in the background until it
+// finally returns. CallDetached thus is only suitable for functions that do not
+// modify any data shared with the caller, such as metadata plugins.
+func CallDetached[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) T) (T, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1) // never block the detached goroutine.
+	go func() {
+		defer cancel()
+		var r result
+		r.err = recovered(func() { r.value = fn(ctx) })
+		done <- r
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, ErrTimeout
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// recovered calls fn and returns a *PanicError if fn panicked.
+func recovered(fn func()) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Value: value}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// withTimeout returns a context with the specified timeout, unless the timeout
+// is zero or negative, where it returns a context without any timeout.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
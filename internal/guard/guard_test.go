@@ -0,0 +1,79 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("guarded plugin calls", func() {
+
+	It("calls and waits", func() {
+		called := false
+		Expect(Call(context.Background(), time.Second, func(ctx context.Context) {
+			_, ok := ctx.Deadline()
+			Expect(ok).To(BeTrue())
+			called = true
+		})).To(Succeed())
+		Expect(called).To(BeTrue())
+
+		Expect(Call(context.Background(), 0, func(ctx context.Context) {
+			_, ok := ctx.Deadline()
+			Expect(ok).To(BeFalse())
+		})).To(Succeed())
+	})
+
+	It("reports timeouts", func() {
+		Expect(Call(context.Background(), 50*time.Millisecond, func(ctx context.Context) {
+			<-ctx.Done()
+		})).To(MatchError(ErrTimeout))
+	})
+
+	It("recovers from panics", func() {
+		err := Call(context.Background(), 0, func(context.Context) { panic("D'oh!") })
+		var perr *PanicError
+		Expect(err).To(BeAssignableToTypeOf(perr))
+		Expect(err.(*PanicError).Value).To(Equal("D'oh!"))
+		Expect(err.Error()).To(Equal("panic: D'oh!"))
+
+		_, err = CallDetached(context.Background(), time.Second, func(context.Context) int { panic("D'oh!") })
+		Expect(err).To(BeAssignableToTypeOf(perr))
+	})
+
+	It("calls detached and returns results", func() {
+		Expect(Successful(CallDetached(context.Background(), time.Second, func(context.Context) int {
+			return 42
+		}))).To(Equal(42))
+	})
+
+	It("leaves hung functions behind", func() {
+		hang := make(chan struct{})
+		defer close(hang)
+		start := time.Now()
+		v, err := CallDetached(context.Background(), 50*time.Millisecond, func(context.Context) int {
+			<-hang
+			return 42
+		})
+		Expect(err).To(MatchError(ErrTimeout))
+		Expect(v).To(BeZero())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("passes on cancellation", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := CallDetached(ctx, time.Second, func(ctx context.Context) int {
+			<-ctx.Done()
+			return 42
+		})
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+})
@@ -0,0 +1,66 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"errors"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+)
+
+// Suspended returns true if the named plugin of the specified group is to be
+// skipped because it failed repeatedly before, reporting the skipped plugin in
+// the specified diagnostics.
+func Suspended(diags *diagnostics.Diagnostics, group diagnostics.PluginGroup, name string) bool {
+	if Breakers.Allow(pluginKey(group, name)) {
+		return false
+	}
+	diags.Warnf(diagnostics.PluginSuspended, diagnostics.Scope{Plugin: name},
+		"skipping %s plugin '%s' after repeated failures", group, name)
+	diags.Ran(diagnostics.PluginRun{Plugin: name, Group: group, Outcome: diagnostics.OutcomeSuspended})
+	return true
+}
+
+// Finished reports the outcome of running the named plugin of the specified
+// group since start, as returned by Call or CallDetached, in the specified
+// diagnostics. Timeouts and panics count as failures towards suspending the
+// plugin, whereas cancellation of the whole discovery doesn't; it only ends a
+// single attempt after a cooldown so that the next discovery attempts again.
+func Finished(diags *diagnostics.Diagnostics, group diagnostics.PluginGroup, name string, start time.Time, err error) {
+	run := diagnostics.PluginRun{
+		Plugin:   name,
+		Group:    group,
+		Outcome:  diagnostics.OutcomeOK,
+		Duration: time.Since(start),
+	}
+	var perr *PanicError
+	switch {
+	case err == nil:
+		Breakers.Success(pluginKey(group, name))
+	case errors.Is(err, ErrTimeout):
+		run.Outcome = diagnostics.OutcomeTimeout
+		Breakers.Failure(pluginKey(group, name))
+		diags.Warnf(diagnostics.PluginTimeout, diagnostics.Scope{Plugin: name},
+			"%s plugin '%s' didn't finish within %s", group, name, run.Duration.Round(time.Millisecond))
+	case errors.As(err, &perr):
+		run.Outcome = diagnostics.OutcomePanic
+		Breakers.Failure(pluginKey(group, name))
+		diags.Errorf(diagnostics.PluginPanic, diagnostics.Scope{Plugin: name},
+			"%s plugin '%s' panicked: %v", group, name, perr.Value)
+	default:
+		run.Outcome = diagnostics.OutcomeSkipped
+		Breakers.Abandon(pluginKey(group, name))
+		diags.Warnf(diagnostics.Cancelled, diagnostics.Scope{Plugin: name},
+			"%s plugin '%s' cancelled, reason: %s", group, name, err.Error())
+	}
+	diags.Ran(run)
+}
+
+// pluginKey returns the Breakers key for the named plugin of the specified
+// group.
+func pluginKey(group diagnostics.PluginGroup, name string) string {
+	return string(group) + "/" + name
+}
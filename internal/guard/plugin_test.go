@@ -0,0 +1,37 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package guard
+
+import (
+	"context"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("guarded plugins", func() {
+
+	It("doesn't suspend plugins forever after a cancelled attempt", func() {
+		now := time.Now()
+		b := NewBreaker(2, time.Minute)
+		b.now = func() time.Time { return now }
+		defer func(breakers *Breaker) { Breakers = breakers }(Breakers)
+		Breakers = b
+		diags := diagnostics.New()
+
+		Finished(diags, diagnostics.DecoratorPlugin, "foo", now, ErrTimeout)
+		Finished(diags, diagnostics.DecoratorPlugin, "foo", now, ErrTimeout)
+		Expect(Suspended(diags, diagnostics.DecoratorPlugin, "foo")).To(BeTrue())
+
+		now = now.Add(time.Minute)
+		Expect(Suspended(diags, diagnostics.DecoratorPlugin, "foo")).To(BeFalse())
+		Finished(diags, diagnostics.DecoratorPlugin, "foo", now, context.Canceled)
+		Expect(Suspended(diags, diagnostics.DecoratorPlugin, "foo")).To(BeFalse())
+	})
+
+})
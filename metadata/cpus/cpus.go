@@ -5,7 +5,6 @@
 package cpus
 
 import (
-	"os"
 	"strconv"
 	"strings"
 
@@ -25,7 +24,7 @@ func init() {
 // Metadata returns metadata describing certain aspects of the host the
 // discovery was run on, such as its host name, OS version, ...
 func Metadata(r gostwire.DiscoveryResult) map[string]interface{} {
-	onlinecpus, err := os.ReadFile("/sys/devices/system/cpu/online")
+	onlinecpus, err := r.Options.SourceOrLive().ReadFile("/sys/devices/system/cpu/online")
 	if err != nil {
 		log.Errorf("cannot retrieve list of online cpus, reason: %s", err.Error())
 		return nil
@@ -6,6 +6,7 @@ package host
 
 import (
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/siemens/ghostwire/v2/metadata"
@@ -30,6 +31,9 @@ var hostMeta = map[string]interface{}{}
 // Metadata returns metadata describing certain aspects of the host the
 // discovery was run on, such as its host name, OS version, ...
 func Metadata(r gostwire.DiscoveryResult) map[string]interface{} {
+	if src := r.Options.Source; src != nil {
+		return sourceMetadata(r, src)
+	}
 	once.Do(func() {
 		hostMeta["hostname"] = getHostname(r)
 		if osrelvars := getHostOsrelVars(); osrelvars != nil {
@@ -43,6 +47,65 @@ func Metadata(r gostwire.DiscoveryResult) map[string]interface{} {
 	return hostMeta
 }
 
+// sourceMetadata returns the host metadata as supplied by a discovery source
+// other than the live host, such as a replayed support bundle.
+func sourceMetadata(r gostwire.DiscoveryResult, src gostwire.Source) map[string]interface{} {
+	meta := map[string]interface{}{
+		"hostname": getHostname(r),
+	}
+	if proc1 := r.Lxkns.Processes[1]; proc1 != nil && proc1.Namespaces[model.MountNS] != nil {
+		if hostfs, closer, err := src.MountFiles(proc1.Namespaces[model.MountNS]); err == nil {
+			if osrelvars, err := readOsrelVars(hostfs); err == nil {
+				meta["osrel-name"] = osrelvars["NAME"]
+				meta["osrel-version"] = osrelvars["VERSION"]
+			}
+			closer()
+		}
+	}
+	if kv, _ := src.ReadFile("/proc/version"); kv != nil {
+		meta["kernel-version"] = string(kv)
+	}
+	return meta
+}
+
+// fileReader reads files from a particular filesystem view, such as the view
+// of a particular mount namespace.
+type fileReader interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// readOsrelVars reads the os-release variables from the filesystem view of the
+// specified file reader, trying "/etc/os-release" first and falling back to
+// "/usr/lib/os-release".
+func readOsrelVars(fsys fileReader) (map[string]string, error) {
+	osrel, err := fsys.ReadFile("/etc/os-release")
+	if err != nil {
+		var fallbackErr error
+		if osrel, fallbackErr = fsys.ReadFile("/usr/lib/os-release"); fallbackErr != nil {
+			return nil, err
+		}
+	}
+	vars := map[string]string{}
+	for _, line := range strings.Split(string(osrel), "\n") {
+		// Following the os-release specification, comment lines must begin
+		// with "#" and lines that aren't variable assignments with properly
+		// quoted values are skipped.
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		variable, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value, err := osrelease.Unquote(value)
+		if err != nil {
+			continue
+		}
+		vars[variable] = value
+	}
+	return vars, nil
+}
+
 // getHostrelVars fetches the host's os-release variables. It does so by reading
 // from the filesystem view as manifested through the initial mount namespace
 // (of PID 1). It returns a nil variables map on failure.
@@ -53,14 +116,10 @@ func getHostOsrelVars() map[string]string {
 		return nil
 	}
 	defer hostfs.Close()
-	osrelPath, err := hostfs.Resolve("/etc/os-release")
-	if err != nil {
-		log.Errorf("cannot resolve /etc/os-release-container host path: %s", err.Error())
-		return nil
-	}
-	vars, err := osrelease.NewFromNameErr(osrelPath)
+	vars, err := readOsrelVars(hostfs)
 	if err != nil {
 		log.Warnf("cannot fetch OS release information, reason: %s", err.Error())
+		return nil
 	}
 	log.Debugf("OS information...")
 	for key, value := range vars {
@@ -11,5 +11,10 @@ The IE core/runtime version is read from the container's
 /etc/os-release-container file, and its VERSION_ID variable in particular.
 Please note that this plugin silently fixes the broken “-e VERSION_ID” variable
 name present in some versions of the runtime container.
+
+This plugin only returns metadata for discoveries of the live host, as the
+runtime's device database cannot be read from other discovery sources, such as
+replayed support bundles. For discoveries from such sources the plugin returns
+no metadata at all, so there is no “industrial-edge” object.
 */
 package iecore
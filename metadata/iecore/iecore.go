@@ -29,8 +29,14 @@ var once = &sync.Once{} // We need to query this only once; no hot core update (
 var coreMeta = map[string]interface{}{}
 
 // Metadata returns metadata describing certain aspects of the host the
-// discovery was run on.
+// discovery was run on. It returns nil for discoveries from sources other than
+// the live host, such as replayed support bundles.
 func Metadata(r gostwire.DiscoveryResult) map[string]interface{} {
+	// The edge core's device database can only be read from the live host,
+	// but not from a replayed discovery.
+	if r.Options.Source != nil {
+		return nil
+	}
 	once.Do(func() {
 		coreMeta = gatherMetadata(r)
 	})
@@ -5,9 +5,13 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 
 	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/log"
@@ -22,20 +26,52 @@ type Metadata func(gostwire.DiscoveryResult) map[string]interface{}
 
 // Augment augments the passed metadata with additional plugin-supplied metadata
 // and returns the final result in form of a string-indexed map, ready to be
-// used in JSON marshalling, et cetera.
+// used in JSON marshalling, et cetera. Metadata plugins are skipped as
+// specified in the discovery options of the result, as well as after reaching
+// the optional metadata timeout. Each plugin runs with its own timeout and
+// panic recovery, and plugins failing repeatedly get suspended for a while.
 func Augment(result gostwire.DiscoveryResult, metadata interface{}) (map[string]interface{}, error) {
 	log.Debugf("metadata discovery started...")
 	augmented, err := toMap(metadata)
 	if err != nil {
 		return nil, err
 	}
+	defer result.Diagnostics.Time(diagnostics.PhaseMetadata, time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	if result.Options.MetadataTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), result.Options.MetadataTimeout)
+	}
+	defer cancel()
 	for _, metadata := range plugger.Group[Metadata]().PluginsSymbols() {
-		if md := metadata.S(result); md != nil {
+		if result.Options.SkipsMetadata(metadata.Plugin) {
+			log.Debugf("skipping metadata plugin '%s'", metadata.Plugin)
+			result.Diagnostics.Ran(diagnostics.PluginRun{
+				Plugin: metadata.Plugin, Group: diagnostics.MetadataPlugin, Outcome: diagnostics.OutcomeSkipped})
+			continue
+		}
+		if ctx.Err() != nil {
+			result.Diagnostics.Warnf(diagnostics.MetadataSkipped, diagnostics.Scope{Plugin: metadata.Plugin},
+				"skipping metadata plugin '%s', reason: timeout", metadata.Plugin)
+			result.Diagnostics.Ran(diagnostics.PluginRun{
+				Plugin: metadata.Plugin, Group: diagnostics.MetadataPlugin, Outcome: diagnostics.OutcomeSkipped})
+			continue
+		}
+		if guard.Suspended(result.Diagnostics, diagnostics.MetadataPlugin, metadata.Plugin) {
+			continue
+		}
+		// Metadata plugins only read the discovery result, so we can safely
+		// leave them behind when they don't finish in time.
+		start := time.Now()
+		md, err := guard.CallDetached(ctx, result.Options.PluginTimeoutOf(metadata.Plugin),
+			func(context.Context) map[string]interface{} { return metadata.S(result) })
+		guard.Finished(result.Diagnostics, diagnostics.MetadataPlugin, metadata.Plugin, start, err)
+		if md != nil {
 			// ...merges metadata returned by plugin
 			log.Debugf("merging metadata from plugin '%s': %v", metadata.Plugin, md)
 			m, err := toMap(md)
 			if err != nil {
-				log.Warnf("cannot merge metadata from plugin '%s': %s", metadata.Plugin, err.Error())
+				result.Diagnostics.Warnf(diagnostics.MetadataFailed, diagnostics.Scope{Plugin: metadata.Plugin},
+					"cannot merge metadata from plugin '%s': %s", metadata.Plugin, err.Error())
 			}
 			deepMapMerge(m, augmented)
 		}
@@ -6,8 +6,11 @@ package metadata
 
 import (
 	"encoding/json"
+	"time"
 
 	gostwire "github.com/siemens/ghostwire/v2"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/siemens/ghostwire/v2/internal/guard"
 	"github.com/thediveo/go-plugger/v3"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -24,7 +27,16 @@ type testMetadata2 struct {
 	Baz string `json:"baz"`
 }
 
+// misbehave is called by the misbehaving test metadata plugin, if set.
+var misbehave func()
+
 func init() {
+	plugger.Group[Metadata]().Register(func(gostwire.DiscoveryResult) map[string]interface{} {
+		if misbehave != nil {
+			misbehave()
+		}
+		return nil
+	}, plugger.WithPlugin(testMetadataPluginName+"-misbehaving"))
 	plugger.Group[Metadata]().Register(func(gostwire.DiscoveryResult) map[string]interface{} {
 		return map[string]interface{}{
 			"testmeta": testMetadata1{Bar: "BAR"},
@@ -88,4 +100,78 @@ var _ = Describe("metadata", func() {
 }`))
 	})
 
+	It("skips metadata plugins", func() {
+		diags := diagnostics.New()
+		md, err := Augment(gostwire.DiscoveryResult{
+			Options: gostwire.DiscoveryOptions{
+				SkipMetadata: []string{testMetadataPluginName + "-1"},
+			},
+			Diagnostics: diags,
+		}, struct{}{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Marshal(md)).To(MatchJSON(`{"testmeta":{"bar":"BAR"}}`))
+		Expect(diags.Timings()).To(ConsistOf(HaveField("Phase", diagnostics.PhaseMetadata)))
+	})
+
+	When("plugins misbehave", func() {
+
+		BeforeEach(func() {
+			oldBreakers := guard.Breakers
+			guard.Breakers = guard.NewBreaker(2, time.Hour)
+			DeferCleanup(func() {
+				guard.Breakers = oldBreakers
+				misbehave = nil
+			})
+		})
+
+		It("recovers from panics and suspends repeatedly failing plugins", func() {
+			misbehave = func() { panic("D'oh!") }
+			for i := 0; i < 2; i++ {
+				diags := diagnostics.New()
+				md, err := Augment(gostwire.DiscoveryResult{Diagnostics: diags}, struct{}{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(md).To(HaveKey("testmeta"))
+				Expect(diags.List()).To(ConsistOf(And(
+					HaveField("Code", diagnostics.PluginPanic),
+					HaveField("Scope.Plugin", testMetadataPluginName+"-misbehaving"))))
+				Expect(diags.PluginRuns()).To(ContainElement(And(
+					HaveField("Plugin", testMetadataPluginName+"-misbehaving"),
+					HaveField("Outcome", diagnostics.OutcomePanic))))
+			}
+			diags := diagnostics.New()
+			_, _ = Augment(gostwire.DiscoveryResult{Diagnostics: diags}, struct{}{})
+			Expect(diags.List()).To(ConsistOf(HaveField("Code", diagnostics.PluginSuspended)))
+			Expect(diags.PluginRuns()).To(ContainElement(And(
+				HaveField("Plugin", testMetadataPluginName+"-misbehaving"),
+				HaveField("Outcome", diagnostics.OutcomeSuspended))))
+		})
+
+		It("leaves hung plugins behind", func() {
+			hang := make(chan struct{})
+			defer close(hang)
+			misbehave = func() { <-hang }
+			diags := diagnostics.New()
+			start := time.Now()
+			md, err := Augment(gostwire.DiscoveryResult{
+				Options: gostwire.DiscoveryOptions{
+					PluginTimeouts: map[string]time.Duration{
+						testMetadataPluginName + "-misbehaving": 100 * time.Millisecond,
+					},
+				},
+				Diagnostics: diags,
+			}, struct{}{})
+			Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Marshal(md)).To(MatchJSON(`{"testmeta":{"bar":"BAR","baz":"BAZZ"}}`))
+			Expect(diags.List()).To(ConsistOf(HaveField("Code", diagnostics.PluginTimeout)))
+			Expect(diags.PluginRuns()).To(ContainElements(
+				And(HaveField("Plugin", testMetadataPluginName+"-misbehaving"),
+					HaveField("Outcome", diagnostics.OutcomeTimeout)),
+				And(HaveField("Plugin", testMetadataPluginName+"-1"),
+					HaveField("Outcome", diagnostics.OutcomeOK)),
+			))
+		})
+
+	})
+
 })
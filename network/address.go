@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net"
 	"sort"
+	"strings"
 	"syscall"
 
 	"golang.org/x/sys/unix"
@@ -16,13 +17,163 @@ import (
 
 // Address represents a network-layer address with associated information.
 type Address struct {
-	Family            int    `json:"family"`
-	Address           net.IP `json:"address"`
-	PrefixLength      uint   `json:"prefixlen"`
-	PreferredLifetime uint32 `json:"preferred-lifetime"`
-	ValidLifetime     uint32 `json:"valid-lifetime"`
-	Scope             int    `json:"scope"`
-	Index             int    `json:"index"` // index of network interface the address is assigned to.
+	Family            int           `json:"family"`
+	Address           net.IP        `json:"address"`
+	PrefixLength      uint          `json:"prefixlen"`
+	PreferredLifetime uint32        `json:"preferred-lifetime"`
+	ValidLifetime     uint32        `json:"valid-lifetime"`
+	Scope             int           `json:"scope"`
+	Index             int           `json:"index"`                    // index of network interface the address is assigned to.
+	Flags             AddressFlags  `json:"flags"`                    // IFA_F_xxx address flags, such as temporary, tentative, ...
+	Origin            AddressOrigin `json:"origin"`                   // IFA_PROTO origin of this address, if known.
+	Label             string        `json:"label,omitempty"`          // optional (IPv4-only) address label.
+	Broadcast         net.IP        `json:"broadcast,omitempty"`      // optional IPv4 broadcast address.
+	Peer              net.IP        `json:"peer,omitempty"`           // optional peer address of point-to-point links.
+	PeerPrefixLength  uint          `json:"peer-prefixlen,omitempty"` // prefix length of peer address, if any.
+	Created           uint32        `json:"created"`                  // creation timestamp in 1/100s since system boot.
+	Updated           uint32        `json:"updated"`                  // last update timestamp in 1/100s since system boot.
+	Lease             *DHCPLease    `json:"dhcp-lease,omitempty"`     // DHCP lease this address was obtained from, if any.
+}
+
+// AddressFlags represents the set of IFA_F_xxx flags of an Address.
+// Additionally, AddressFlags can be String-ified into a comma-separated list
+// of lower-case flag names, such as "permanent,noprefixroute".
+type AddressFlags uint32
+
+// The address flags as defined in
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/if_addr.h
+const (
+	AddressSecondary      AddressFlags = unix.IFA_F_SECONDARY // IPv4 only
+	AddressTemporary      AddressFlags = unix.IFA_F_TEMPORARY // IPv6 only
+	AddressNoDAD          AddressFlags = unix.IFA_F_NODAD
+	AddressOptimistic     AddressFlags = unix.IFA_F_OPTIMISTIC
+	AddressDADFailed      AddressFlags = unix.IFA_F_DADFAILED
+	AddressHomeAddress    AddressFlags = unix.IFA_F_HOMEADDRESS
+	AddressDeprecated     AddressFlags = unix.IFA_F_DEPRECATED
+	AddressTentative      AddressFlags = unix.IFA_F_TENTATIVE
+	AddressPermanent      AddressFlags = unix.IFA_F_PERMANENT
+	AddressManageTempAddr AddressFlags = unix.IFA_F_MANAGETEMPADDR
+	AddressNoPrefixRoute  AddressFlags = unix.IFA_F_NOPREFIXROUTE
+	AddressMcAutoJoin     AddressFlags = unix.IFA_F_MCAUTOJOIN
+	AddressStablePrivacy  AddressFlags = unix.IFA_F_STABLE_PRIVACY
+)
+
+// addressFlagNames lists the address flag names in the same order as the "ip
+// address" command shows them. Please note that IFA_F_SECONDARY and
+// IFA_F_TEMPORARY share the same bit, so the name depends on the address
+// family.
+var addressFlagNames = []struct {
+	flag AddressFlags
+	name string
+}{
+	{AddressNoDAD, "nodad"},
+	{AddressOptimistic, "optimistic"},
+	{AddressDADFailed, "dadfailed"},
+	{AddressHomeAddress, "home"},
+	{AddressDeprecated, "deprecated"},
+	{AddressTentative, "tentative"},
+	{AddressPermanent, "permanent"},
+	{AddressManageTempAddr, "mngtmpaddr"},
+	{AddressNoPrefixRoute, "noprefixroute"},
+	{AddressMcAutoJoin, "autojoin"},
+	{AddressStablePrivacy, "stable-privacy"},
+}
+
+// Names returns the names of the flags set, taking the address family into
+// account in order to correctly differentiate between "secondary" (IPv4) and
+// "temporary" (IPv6).
+func (f AddressFlags) Names(family int) []string {
+	names := []string{}
+	if f&AddressSecondary != 0 {
+		if family == unix.AF_INET6 {
+			names = append(names, "temporary")
+		} else {
+			names = append(names, "secondary")
+		}
+	}
+	known := AddressSecondary
+	for _, flagname := range addressFlagNames {
+		known |= flagname.flag
+		if f&flagname.flag != 0 {
+			names = append(names, flagname.name)
+		}
+	}
+	if unknown := f &^ known; unknown != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint32(unknown)))
+	}
+	return names
+}
+
+// String returns the comma-separated list of names of the flags set. As
+// String doesn't know the address family, it always names bit 0 "secondary";
+// use Names instead for correct family-specific names.
+func (f AddressFlags) String() string {
+	return strings.Join(f.Names(unix.AF_INET), ",")
+}
+
+// Tentative returns true if the address is still undergoing duplicate address
+// detection (DAD) and thus cannot be used yet.
+func (a Address) Tentative() bool {
+	return a.Flags&AddressTentative != 0 && a.Flags&AddressDADFailed == 0
+}
+
+// DADFailed returns true if duplicate address detection (DAD) failed for this
+// address, so it is unusable. A common cause are containers with duplicated
+// MAC addresses, and thus duplicated IPv6 link-local addresses.
+func (a Address) DADFailed() bool {
+	return a.Flags&AddressDADFailed != 0
+}
+
+// Deprecated returns true if the address' preferred lifetime has expired, so
+// that the address shouldn't be used anymore for new connections.
+func (a Address) Deprecated() bool {
+	return a.Flags&AddressDeprecated != 0
+}
+
+// Temporary returns true if this is an IPv6 temporary (privacy extension)
+// address.
+func (a Address) Temporary() bool {
+	return a.Family == unix.AF_INET6 && a.Flags&AddressTemporary != 0
+}
+
+// Permanent returns true if this address has been statically assigned, as
+// opposed to dynamically assigned, such as through SLAAC, or DHCP clients
+// setting lifetimes.
+func (a Address) Permanent() bool {
+	return a.Flags&AddressPermanent != 0
+}
+
+// AddressOrigin represents the IFA_PROTO information about the originator of
+// an address, if known. The kernel sets this information only for addresses
+// it creates itself, otherwise it is up to user space to set this information
+// when adding an address (and most user space doesn't).
+type AddressOrigin uint8
+
+// The address origins defined by the kernel; please note that all other
+// values can be freely used by user space, such as DHCP clients.
+const (
+	AddressOriginUnspec   AddressOrigin = 0 // no origin information; usually assigned by user space.
+	AddressOriginKernelLo AddressOrigin = 1 // loopback address assigned by the kernel.
+	AddressOriginKernelRA AddressOrigin = 2 // autoconfigured by the kernel from a router advertisement.
+	AddressOriginKernelLL AddressOrigin = 3 // link-local address autoconfigured by the kernel.
+)
+
+// String returns the name of an address origin, such as "kernel_ra", mimicking
+// the names used by the "ip address" command. Any user space-defined origin
+// values are returned in the form "AddressOrigin(42)".
+func (o AddressOrigin) String() string {
+	switch o {
+	case AddressOriginUnspec:
+		return "unspec"
+	case AddressOriginKernelLo:
+		return "kernel_lo"
+	case AddressOriginKernelRA:
+		return "kernel_ra"
+	case AddressOriginKernelLL:
+		return "kernel_ll"
+	default:
+		return fmt.Sprintf("AddressOrigin(%d)", o)
+	}
 }
 
 // Addresses is an unordered list of Address elements.
@@ -113,12 +264,12 @@ func (af AddressFamily) String() string {
 	}
 }
 
-// Protocol represents a (transport) protocol number for TCP or UDP.
-// Additionally, Protocol can be String-ified into the text strings "TCP" and
-// "UDP".
+// Protocol represents a (transport) protocol number, such as for TCP or UDP.
+// Additionally, Protocol can be String-ified into the text strings "TCP",
+// "UDP", "SCTP", "UDPLite", "RAW", and "MPTCP".
 type Protocol int
 
-// String returns either "TCP" or "UDP" for the given protocol number.
+// String returns "TCP", "UDP", et cetera, for the given protocol number.
 func (p Protocol) String() string {
 	switch p {
 	case syscall.IPPROTO_TCP:
@@ -127,6 +278,12 @@ func (p Protocol) String() string {
 		return "UDP"
 	case syscall.IPPROTO_SCTP:
 		return "SCTP"
+	case syscall.IPPROTO_UDPLITE:
+		return "UDPLite"
+	case syscall.IPPROTO_RAW:
+		return "RAW"
+	case unix.IPPROTO_MPTCP:
+		return "MPTCP"
 	default:
 		return fmt.Sprintf("Protocol(%d)", p)
 	}
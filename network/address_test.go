@@ -11,6 +11,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
+	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
 )
 
@@ -107,6 +108,8 @@ var _ = Describe("handles network addresses", func() {
 	It("stringifies UDP/TCP protocol numbers", func() {
 		Expect(Protocol(syscall.IPPROTO_TCP).String()).To(Equal("TCP"))
 		Expect(Protocol(syscall.IPPROTO_UDP).String()).To(Equal("UDP"))
+		Expect(Protocol(syscall.IPPROTO_UDPLITE).String()).To(Equal("UDPLite"))
+		Expect(Protocol(syscall.IPPROTO_RAW).String()).To(Equal("RAW"))
 		Expect(Protocol(0).String()).To(Equal("Protocol(0)"))
 	})
 
@@ -116,6 +119,61 @@ var _ = Describe("handles network addresses", func() {
 		Expect(AddressFamily(0).String()).To(Equal("AddressFamily(0)"))
 	})
 
+	It("names address flags", func() {
+		Expect(AddressFlags(0).Names(unix.AF_INET)).To(BeEmpty())
+		Expect(AddressSecondary.Names(unix.AF_INET)).To(ConsistOf("secondary"))
+		Expect(AddressTemporary.Names(unix.AF_INET6)).To(ConsistOf("temporary"))
+		Expect((AddressPermanent | AddressNoPrefixRoute | 0x10000).Names(unix.AF_INET6)).To(
+			HaveExactElements("permanent", "noprefixroute", "0x10000"))
+		Expect((AddressTentative | AddressDADFailed).String()).To(Equal("dadfailed,tentative"))
+	})
+
+	It("interprets address flags", func() {
+		a := Address{Family: unix.AF_INET6, Flags: AddressTentative}
+		Expect(a.Tentative()).To(BeTrue())
+		Expect(a.DADFailed()).To(BeFalse())
+		a.Flags |= AddressDADFailed
+		Expect(a.Tentative()).To(BeFalse())
+		Expect(a.DADFailed()).To(BeTrue())
+
+		a = Address{Family: unix.AF_INET6, Flags: AddressTemporary | AddressDeprecated}
+		Expect(a.Temporary()).To(BeTrue())
+		Expect(a.Deprecated()).To(BeTrue())
+		Expect(a.Permanent()).To(BeFalse())
+		a.Family = unix.AF_INET
+		Expect(a.Temporary()).To(BeFalse())
+	})
+
+	It("stringifies address origins", func() {
+		Expect(AddressOriginUnspec.String()).To(Equal("unspec"))
+		Expect(AddressOriginKernelLo.String()).To(Equal("kernel_lo"))
+		Expect(AddressOriginKernelRA.String()).To(Equal("kernel_ra"))
+		Expect(AddressOriginKernelLL.String()).To(Equal("kernel_ll"))
+		Expect(AddressOrigin(42).String()).To(Equal("AddressOrigin(42)"))
+	})
+
+	It("parses address details", func() {
+		ifamsg := nl.NewIfAddrmsg(unix.AF_INET6)
+		ifamsg.Index = 42
+		cacheinfo := nl.IfaCacheInfo{IfaCacheinfo: unix.IfaCacheinfo{Cstamp: 123, Tstamp: 456}}
+		b := ifamsg.Serialize()
+		b = append(b, nl.NewRtAttr(unix.IFA_ADDRESS, net.ParseIP("fe80::1")).Serialize()...)
+		b = append(b, nl.NewRtAttr(ifaProto, []byte{byte(AddressOriginKernelLL)}).Serialize()...)
+		b = append(b, nl.NewRtAttr(unix.IFA_CACHEINFO, cacheinfo.Serialize()).Serialize()...)
+
+		index, ip, origin, ci, ok := parseAddressDetails(b)
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal(42))
+		Expect(ip.String()).To(Equal("fe80::1"))
+		Expect(origin).To(Equal(AddressOriginKernelLL))
+		Expect(ci).NotTo(BeNil())
+		Expect(ci.Cstamp).To(Equal(uint32(123)))
+		Expect(ci.Tstamp).To(Equal(uint32(456)))
+
+		_, _, _, _, ok = parseAddressDetails(b[:4])
+		Expect(ok).To(BeFalse())
+	})
+
 	It("correctly stringifies IP addresses in port contexts", func() {
 		Expect(IP(net.ParseIP("127.0.0.1")).String()).To(Equal("127.0.0.1"))
 		Expect(IP([]byte{127, 0, 0, 1}).String()).To(Equal("127.0.0.1"))
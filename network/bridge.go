@@ -5,6 +5,8 @@
 package network
 
 import (
+	"net"
+
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/vishvananda/netlink"
 )
@@ -25,6 +27,23 @@ type Bridge interface {
 type BridgeAttrs struct {
 	NifAttrs
 	Ports []Interface // "enslaved" network interfaces acting as bridge ports
+
+	MulticastSnooping bool                   // IGMP/MLD snooping enabled?
+	MulticastQuerier  bool                   // bridge acts as IGMP/MLD querier?
+	IGMPVersion       uint8                  // IGMP version used by the querier
+	MLDVersion        uint8                  // MLD version used by the querier
+	MulticastDB       []BridgeMulticastEntry // multicast group port memberships (MDB)
+}
+
+// BridgeMulticastEntry is an entry of a bridge's multicast database (MDB),
+// relating a multicast group to a bridge port where members of this group have
+// been seen via IGMP/MLD snooping or have been configured statically.
+type BridgeMulticastEntry struct {
+	Port      Interface        // bridge port with members of the multicast group.
+	Group     net.IP           // IPv4/IPv6 multicast group, unless it is an L2 group.
+	L2Group   net.HardwareAddr // link-layer multicast group, if not an IP group.
+	VID       uint16           // VLAN ID, if any.
+	Permanent bool             // static entry, otherwise learned by snooping.
 }
 
 var _ Bridge = (*BridgeAttrs)(nil)
@@ -40,8 +59,19 @@ func (n *BridgeAttrs) Bridge() *BridgeAttrs { return n }
 // Init initializes this Bridge Nif from information specified in the
 // NetworkNamespace and lots of netlink.Link information.
 func (n *BridgeAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
-	n.NifAttrs.Init(nlh, netns, link)
-	// nothing more to be done here.
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this Bridge Nif, getting its addresses from the
+// specified AddrLister.
+func (n *BridgeAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.initFromSource(nlh, netns, link)
+	// The querier and IGMP/MLD version details as well as the multicast
+	// database are discovered later for all bridges in a network namespace in
+	// one go, see discoverMulticast.
+	if br, ok := link.(*netlink.Bridge); ok && br.MulticastSnooping != nil {
+		n.MulticastSnooping = *br.MulticastSnooping
+	}
 }
 
 // ResolveRelations resolves relations to the enslaved "port" network
@@ -0,0 +1,346 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"syscall"
+
+	"github.com/thediveo/lxkns/species"
+)
+
+// CommunicationKind classifies communication edges by where their endpoints
+// are located.
+type CommunicationKind int
+
+// The kinds of communication edges.
+const (
+	IntraNetnsCommunication CommunicationKind = iota // both endpoints in the same network namespace
+	CrossNetnsCommunication                          // endpoints in different network namespaces
+	ExternalCommunication                            // one endpoint outside the discovered network namespaces
+)
+
+// String returns the textual representation of a communication kind.
+func (k CommunicationKind) String() string {
+	switch k {
+	case IntraNetnsCommunication:
+		return "intra-netns"
+	case CrossNetnsCommunication:
+		return "cross-netns"
+	case ExternalCommunication:
+		return "external"
+	}
+	return fmt.Sprintf("CommunicationKind(%d)", k)
+}
+
+// CommunicationEndpoint is one end of a communication edge. External endpoints
+// are not located in any of the discovered network namespaces, so only their
+// address and port are known. Endpoints inside discovered network namespaces
+// might still lack a socket when traffic got forwarded into a network
+// namespace without any matching socket.
+type CommunicationEndpoint struct {
+	Netns  *NetworkNamespace // network namespace of endpoint, or nil if external.
+	Socket *ProcessSocket    // socket of endpoint, if known.
+	IP     net.IP            // IP address of endpoint; IPv4 addresses in .To4() format.
+	Port   uint16            // transport-layer port of endpoint.
+}
+
+// External returns true if the endpoint is outside all discovered network
+// namespaces.
+func (e CommunicationEndpoint) External() bool { return e.Netns == nil }
+
+// CommunicationEdge connects a client with a server endpoint, based on
+// connected transport-layer sockets. Where the server side cannot be
+// determined, the client is the side where the connected socket was found
+// first.
+type CommunicationEdge struct {
+	Protocol  Protocol              // transport protocol, such as syscall.IPPROTO_TCP, ...
+	Client    CommunicationEndpoint // client side, usually with an ephemeral port.
+	Server    CommunicationEndpoint // server side, with a listening socket on the port.
+	Forwarded *ForwardedPort        // port forwarding between client and server, if any.
+}
+
+// Kind returns the kind of communication, depending on the locations of the
+// edge's endpoints.
+func (e CommunicationEdge) Kind() CommunicationKind {
+	switch {
+	case e.Client.External() || e.Server.External():
+		return ExternalCommunication
+	case e.Client.Netns == e.Server.Netns:
+		return IntraNetnsCommunication
+	}
+	return CrossNetnsCommunication
+}
+
+// CommunicationEdges is a list of communication edges.
+type CommunicationEdges []CommunicationEdge
+
+// socketRef references a particular socket in a network namespace.
+type socketRef struct {
+	netns  *NetworkNamespace
+	socket *ProcessSocket
+}
+
+// connectionKey identifies a connected socket in a network namespace by its
+// protocol, local and remote addresses and ports.
+type connectionKey struct {
+	netns      *NetworkNamespace
+	proto      Protocol
+	local      string
+	localPort  uint16
+	remote     string
+	remotePort uint16
+}
+
+// listenerKey identifies a listening port in a network namespace.
+type listenerKey struct {
+	netns *NetworkNamespace
+	proto Protocol
+	port  uint16
+}
+
+// communicationIndex indexes the connected and listening sockets across all
+// network namespaces.
+type communicationIndex struct {
+	connections map[connectionKey]socketRef
+	// connected sockets, but without their remote addresses in order to find
+	// sockets with their remote addresses rewritten by SNAT.
+	natted    map[connectionKey][]socketRef
+	listeners map[listenerKey]bool
+	sockets   []socketRef // all connected sockets in order of discovery.
+}
+
+// NewCommunicationEdges returns the communication edges between the connected
+// TCP, UDP, UDP-Lite, and SCTP sockets in the specified network namespaces.
+// Connected sockets are paired with their counterparts in the same or other
+// network namespaces, taking port forwarding into account. Connected sockets
+// without counterparts in any of the discovered network namespaces become
+// edges with external endpoints.
+//
+// The edges are sorted by protocol, client and then server addresses and
+// ports.
+func NewCommunicationEdges(netspaces NetworkNamespaces) CommunicationEdges {
+	idx := newCommunicationIndex(netspaces)
+	paired := map[*ProcessSocket]bool{}
+	edges := CommunicationEdges{}
+	for _, ref := range idx.sockets {
+		if paired[ref.socket] {
+			continue
+		}
+		paired[ref.socket] = true
+		local := CommunicationEndpoint{
+			Netns:  ref.netns,
+			Socket: ref.socket,
+			IP:     normalizedIP(ref.socket.LocalIP),
+			Port:   ref.socket.LocalPort,
+		}
+		remote, fwd := idx.counterpart(ref)
+		if remote.Socket != nil {
+			paired[remote.Socket] = true
+		}
+		edge := CommunicationEdge{
+			Protocol:  ref.socket.Protocol,
+			Client:    local,
+			Server:    remote,
+			Forwarded: fwd,
+		}
+		// When traffic gets forwarded, the remote end always is the server;
+		// otherwise check for our end being the listening side.
+		if fwd == nil && idx.listeners[listenerKey{netns: ref.netns, proto: ref.socket.Protocol, port: local.Port}] &&
+			!(remote.Netns != nil && idx.listeners[listenerKey{netns: remote.Netns, proto: ref.socket.Protocol, port: remote.Port}]) {
+			edge.Client, edge.Server = remote, local
+		}
+		edges = append(edges, edge)
+	}
+	edges.Sort()
+	return edges
+}
+
+// Sort the communication edges in place by protocol, client and then server
+// addresses and ports.
+func (e CommunicationEdges) Sort() {
+	sort.SliceStable(e, func(a, b int) bool {
+		edgeA, edgeB := e[a], e[b]
+		if edgeA.Protocol != edgeB.Protocol {
+			return edgeA.Protocol < edgeB.Protocol
+		}
+		if c := compareEndpoints(edgeA.Client, edgeB.Client); c != 0 {
+			return c < 0
+		}
+		return compareEndpoints(edgeA.Server, edgeB.Server) < 0
+	})
+}
+
+// compareEndpoints compares two endpoints by their IP addresses and then their
+// ports.
+func compareEndpoints(a, b CommunicationEndpoint) int {
+	if c := bytes.Compare(a.IP, b.IP); c != 0 {
+		return c
+	}
+	return int(a.Port) - int(b.Port)
+}
+
+// newCommunicationIndex indexes the connected and listening sockets in the
+// specified network namespaces. IPv6 sockets with IPv4-mapped addresses are
+// indexed with their IPv4 addresses, while their IPv4 aliases are skipped.
+func newCommunicationIndex(netspaces NetworkNamespaces) *communicationIndex {
+	idx := &communicationIndex{
+		connections: map[connectionKey]socketRef{},
+		natted:      map[connectionKey][]socketRef{},
+		listeners:   map[listenerKey]bool{},
+	}
+	// Index in a stable order, so that the communication edges become
+	// deterministic even where we cannot tell clients from servers.
+	netnsids := make([]species.NamespaceID, 0, len(netspaces))
+	for netnsid := range netspaces {
+		netnsids = append(netnsids, netnsid)
+	}
+	sort.Slice(netnsids, func(a, b int) bool {
+		if netnsids[a].Dev != netnsids[b].Dev {
+			return netnsids[a].Dev < netnsids[b].Dev
+		}
+		return netnsids[a].Ino < netnsids[b].Ino
+	})
+	for _, netnsid := range netnsids {
+		netns := netspaces[netnsid]
+		for _, sockets := range [][]ProcessSocket{netns.Portsv4, netns.Portsv6} {
+			for sidx := range sockets {
+				socket := &sockets[sidx]
+				if socket.IPv4Mapped || !isConnectionProtocol(socket.Protocol) {
+					continue
+				}
+				switch socket.SimplifiedState {
+				case Listening:
+					idx.listeners[listenerKey{netns: netns, proto: socket.Protocol, port: socket.LocalPort}] = true
+				case Connected:
+					if socket.RemotePort == 0 || socket.RemoteIP.IsUnspecified() {
+						continue
+					}
+					ref := socketRef{netns: netns, socket: socket}
+					key := connectionKey{
+						netns:      netns,
+						proto:      socket.Protocol,
+						local:      string(normalizedIP(socket.LocalIP)),
+						localPort:  socket.LocalPort,
+						remote:     string(normalizedIP(socket.RemoteIP)),
+						remotePort: socket.RemotePort,
+					}
+					idx.connections[key] = ref
+					key.remote = ""
+					idx.natted[key] = append(idx.natted[key], ref)
+					idx.sockets = append(idx.sockets, ref)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// counterpart returns the remote endpoint of the specified connected socket,
+// as well as the forwarded port the traffic passes through, if any.
+func (idx *communicationIndex) counterpart(ref socketRef) (CommunicationEndpoint, *ForwardedPort) {
+	socket := ref.socket
+	remoteIP := normalizedIP(socket.RemoteIP)
+	remote := CommunicationEndpoint{IP: remoteIP, Port: socket.RemotePort}
+	destNetns, _ := ref.netns.WhereIs(remoteIP)
+	if destNetns != nil {
+		remote.Netns = destNetns
+		if peer, ok := idx.connections[connectionKey{
+			netns:      destNetns,
+			proto:      socket.Protocol,
+			local:      string(remoteIP),
+			localPort:  socket.RemotePort,
+			remote:     string(normalizedIP(socket.LocalIP)),
+			remotePort: socket.LocalPort,
+		}]; ok {
+			remote.Socket = peer.socket
+			return remote, nil
+		}
+	}
+	// The connection might pass through a forwarded port, either in the
+	// network namespace the traffic is routed to (PREROUTING), or for locally
+	// generated traffic in our own network namespace (OUTPUT).
+	candidates := []*NetworkNamespace{ref.netns}
+	if destNetns != nil && destNetns != ref.netns {
+		candidates = []*NetworkNamespace{destNetns, ref.netns}
+	}
+	for _, netns := range candidates {
+		fwd := findForwardedPort(netns, socket.Protocol, remoteIP, socket.RemotePort)
+		if fwd == nil {
+			continue
+		}
+		target := CommunicationEndpoint{
+			Netns: fwd.DestinationNetns,
+			IP:    normalizedIP(fwd.ForwardIP),
+			Port:  fwd.ForwardPortMin + (socket.RemotePort - fwd.PortMin),
+		}
+		key := connectionKey{
+			netns:      target.Netns,
+			proto:      socket.Protocol,
+			local:      string(target.IP),
+			localPort:  target.Port,
+			remote:     string(normalizedIP(socket.LocalIP)),
+			remotePort: socket.LocalPort,
+		}
+		if peer, ok := idx.connections[key]; ok {
+			target.Socket = peer.socket
+			return target, fwd
+		}
+		// The source address might have been rewritten (SNAT, masquerading)
+		// so we need to fall back to matching only the ports; but we accept
+		// only unambiguous matches.
+		key.remote = ""
+		if peers := idx.natted[key]; len(peers) == 1 {
+			target.Socket = peers[0].socket
+		}
+		return target, fwd
+	}
+	return remote, nil
+}
+
+// findForwardedPort returns the forwarded port in the specified network
+// namespace matching the specified protocol, destination address and port, or
+// nil. Only forwarded ports with a known destination network namespace are
+// taken into consideration.
+func findForwardedPort(netns *NetworkNamespace, proto Protocol, ip net.IP, port uint16) *ForwardedPort {
+	fwdports := netns.ForwardedPortsv4
+	if len(ip) == net.IPv6len {
+		fwdports = netns.ForwardedPortsv6
+	}
+	for idx := range fwdports {
+		fwd := &fwdports[idx]
+		if fwd.Protocol != proto || fwd.DestinationNetns == nil ||
+			port < fwd.PortMin || port > fwd.PortMax {
+			continue
+		}
+		if fwd.IP != nil && !fwd.IP.IsUnspecified() && !normalizedIP(fwd.IP).Equal(ip) {
+			continue
+		}
+		return fwd
+	}
+	return nil
+}
+
+// isConnectionProtocol returns true for the transport-layer protocols that
+// have connected sockets.
+func isConnectionProtocol(proto Protocol) bool {
+	switch proto {
+	case syscall.IPPROTO_TCP, syscall.IPPROTO_UDP, syscall.IPPROTO_UDPLITE, syscall.IPPROTO_SCTP:
+		return true
+	}
+	return false
+}
+
+// normalizedIP returns IPv4 and IPv4-mapped IPv6 addresses in .To4() format,
+// and all other addresses unchanged.
+func normalizedIP(ip net.IP) net.IP {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4
+	}
+	return ip
+}
@@ -0,0 +1,140 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/thediveo/lxkns/species"
+	"github.com/thediveo/nufftables/portfinder"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// tcpsock returns a TCP socket for testing communication edges.
+func tcpsock(state SocketState, local string, lport uint16, remote string, rport uint16) ProcessSocket {
+	simplified, _ := simplifySocketState(syscall.IPPROTO_TCP, state)
+	return ProcessSocket{
+		Family:          unix.AF_INET,
+		Protocol:        syscall.IPPROTO_TCP,
+		LocalIP:         net.ParseIP(local).To4(),
+		LocalPort:       lport,
+		RemoteIP:        net.ParseIP(remote).To4(),
+		RemotePort:      rport,
+		State:           state,
+		SimplifiedState: simplified,
+	}
+}
+
+var _ = Describe("communication edges", func() {
+
+	It("stringifies kinds", func() {
+		Expect(IntraNetnsCommunication.String()).To(Equal("intra-netns"))
+		Expect(CrossNetnsCommunication.String()).To(Equal("cross-netns"))
+		Expect(ExternalCommunication.String()).To(Equal("external"))
+		Expect(CommunicationKind(42).String()).To(Equal("CommunicationKind(42)"))
+	})
+
+	It("pairs connected sockets across network namespaces", func() {
+		hostnetns := &NetworkNamespace{}
+		cntrnetns := &NetworkNamespace{}
+
+		lo := &NifAttrs{Netns: hostnetns, Name: "lo", Index: 1,
+			Addrsv4: Addresses{{Family: unix.AF_INET, Address: net.IP{127, 0, 0, 1}, PrefixLength: 8}}}
+		veth0 := &VethAttrs{NifAttrs: NifAttrs{Netns: hostnetns, Kind: "veth", Name: "veth0", Index: 2,
+			Addrsv4: Addresses{{Family: unix.AF_INET, Address: net.IP{10, 0, 0, 1}, PrefixLength: 24}}}}
+		veth1 := &VethAttrs{NifAttrs: NifAttrs{Netns: cntrnetns, Kind: "veth", Name: "eth0", Index: 2,
+			Addrsv4: Addresses{{Family: unix.AF_INET, Address: net.IP{10, 0, 0, 2}, PrefixLength: 24}}}}
+		veth0.Peer, veth1.Peer = veth1, veth0
+
+		_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+		_, anynet, _ := net.ParseCIDR("0.0.0.0/0")
+
+		hostnetns.Nifs = map[int]Interface{1: lo, 2: veth0}
+		hostnetns.NamedNifs = map[string]Interface{"lo": lo, "veth0": veth0}
+		hostnetns.Routesv4 = []Route{{Destination: *subnet, DestinationPrefixLen: 24, Nif: veth0}}
+		hostnetns.Portsv4 = []ProcessSocket{
+			tcpsock(TCP_LISTEN, "0.0.0.0", 80, "0.0.0.0", 0),
+			tcpsock(TCP_ESTABLISHED, "10.0.0.1", 80, "10.0.0.2", 40000),
+			tcpsock(TCP_ESTABLISHED, "127.0.0.1", 80, "127.0.0.1", 50002),
+			tcpsock(TCP_ESTABLISHED, "127.0.0.1", 50002, "127.0.0.1", 80),
+			tcpsock(TCP_ESTABLISHED, "127.0.0.1", 50001, "127.0.0.1", 8080),
+		}
+		hostnetns.ForwardedPortsv4 = []ForwardedPort{{
+			ForwardedPortRange: portfinder.ForwardedPortRange{
+				Protocol:       "tcp",
+				IP:             net.IP{0, 0, 0, 0},
+				PortMin:        8080,
+				PortMax:        8080,
+				ForwardIP:      net.IP{10, 0, 0, 2},
+				ForwardPortMin: 80,
+			},
+			Protocol:         syscall.IPPROTO_TCP,
+			DestinationNetns: cntrnetns,
+		}}
+
+		cntrnetns.Nifs = map[int]Interface{2: veth1}
+		cntrnetns.NamedNifs = map[string]Interface{"eth0": veth1}
+		cntrnetns.Routesv4 = []Route{
+			{Destination: *subnet, DestinationPrefixLen: 24, Nif: veth1},
+			{Destination: *anynet, DestinationPrefixLen: 0, NextHop: net.IP{10, 0, 0, 1}, Nif: veth1},
+		}
+		cntrnetns.Portsv4 = []ProcessSocket{
+			tcpsock(TCP_LISTEN, "0.0.0.0", 80, "0.0.0.0", 0),
+			tcpsock(TCP_ESTABLISHED, "10.0.0.2", 40000, "10.0.0.1", 80),
+			tcpsock(TCP_ESTABLISHED, "10.0.0.2", 40001, "8.8.8.8", 443),
+			tcpsock(TCP_ESTABLISHED, "10.0.0.2", 80, "10.0.0.1", 50001), // masqueraded
+		}
+		// IPv4-mapped aliases must be ignored.
+		alias := tcpsock(TCP_ESTABLISHED, "10.0.0.2", 40000, "10.0.0.1", 80)
+		alias.IPv4Mapped = true
+		cntrnetns.Portsv4 = append(cntrnetns.Portsv4, alias)
+
+		edges := NewCommunicationEdges(NetworkNamespaces{
+			species.NamespaceID{Dev: 1, Ino: 1}: hostnetns,
+			species.NamespaceID{Dev: 1, Ino: 2}: cntrnetns,
+		})
+		Expect(edges).To(HaveLen(4))
+
+		Expect(edges[0].Kind()).To(Equal(CrossNetnsCommunication))
+		Expect(edges[0].Forwarded).To(BeNil())
+		Expect(edges[0].Client).To(MatchFields(IgnoreExtras, Fields{
+			"Netns":  BeIdenticalTo(cntrnetns),
+			"Socket": BeIdenticalTo(&cntrnetns.Portsv4[1]),
+			"Port":   Equal(uint16(40000)),
+		}))
+		Expect(edges[0].Server).To(MatchFields(IgnoreExtras, Fields{
+			"Netns":  BeIdenticalTo(hostnetns),
+			"Socket": BeIdenticalTo(&hostnetns.Portsv4[1]),
+			"IP":     Equal(net.IP{10, 0, 0, 1}),
+			"Port":   Equal(uint16(80)),
+		}))
+
+		Expect(edges[1].Kind()).To(Equal(ExternalCommunication))
+		Expect(edges[1].Client.Socket).To(BeIdenticalTo(&cntrnetns.Portsv4[2]))
+		Expect(edges[1].Server.External()).To(BeTrue())
+		Expect(edges[1].Server.Socket).To(BeNil())
+		Expect(edges[1].Server.IP).To(Equal(net.IP{8, 8, 8, 8}))
+
+		Expect(edges[2].Kind()).To(Equal(CrossNetnsCommunication))
+		Expect(edges[2].Forwarded).To(BeIdenticalTo(&hostnetns.ForwardedPortsv4[0]))
+		Expect(edges[2].Client.Socket).To(BeIdenticalTo(&hostnetns.Portsv4[4]))
+		Expect(edges[2].Server).To(MatchFields(IgnoreExtras, Fields{
+			"Netns":  BeIdenticalTo(cntrnetns),
+			"Socket": BeIdenticalTo(&cntrnetns.Portsv4[3]),
+			"IP":     Equal(net.IP{10, 0, 0, 2}),
+			"Port":   Equal(uint16(80)),
+		}))
+
+		Expect(edges[3].Kind()).To(Equal(IntraNetnsCommunication))
+		Expect(edges[3].Client.Socket).To(BeIdenticalTo(&hostnetns.Portsv4[3]))
+		Expect(edges[3].Server.Socket).To(BeIdenticalTo(&hostnetns.Portsv4[2]))
+	})
+
+})
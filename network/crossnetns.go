@@ -0,0 +1,81 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"sort"
+
+	"github.com/thediveo/lxkns/model"
+	"golang.org/x/exp/slices"
+)
+
+// BridgingProcess is a process holding sockets created in network namespaces
+// other than the one it is attached to, such as after switching network
+// namespaces using setns(2), or after receiving socket file descriptors from
+// other processes. Such processes bridge between network namespaces.
+type BridgingProcess struct {
+	Process      *model.Process      // process holding sockets of other network namespaces.
+	ForeignNetns []*NetworkNamespace // the other network namespaces, sorted by inode number.
+}
+
+// resolveForeignSocketOwners determines the owners of sockets that are
+// attached to network namespaces different from the sockets' own network
+// namespaces. Socket owners are discovered from the open file descriptors of
+// all processes, regardless of their network namespaces, so a socket listed in
+// a network namespace might well be owned by a process living elsewhere. It
+// then lists such processes as bridging processes with the network namespaces
+// they are attached to.
+func resolveForeignSocketOwners(netspaces NetworkNamespaces) {
+	bridging := map[*model.Process]*BridgingProcess{}
+	foreigners := func(netns *NetworkNamespace, procs []*model.Process) []*model.Process {
+		var foreign []*model.Process
+		for _, proc := range procs {
+			procnetns := proc.Namespaces[model.NetNS]
+			if procnetns == nil || procnetns.ID() == netns.ID() {
+				continue
+			}
+			foreign = append(foreign, proc)
+			home := netspaces[procnetns.ID()]
+			if home == nil {
+				continue
+			}
+			bridge, ok := bridging[proc]
+			if !ok {
+				bridge = &BridgingProcess{Process: proc}
+				bridging[proc] = bridge
+				home.BridgingProcesses = append(home.BridgingProcesses, bridge)
+			}
+			if !slices.Contains(bridge.ForeignNetns, netns) {
+				bridge.ForeignNetns = append(bridge.ForeignNetns, netns)
+			}
+		}
+		return foreign
+	}
+	for _, netns := range netspaces {
+		for _, ports := range [][]ProcessSocket{netns.Portsv4, netns.Portsv6} {
+			for idx := range ports {
+				ports[idx].ForeignProcesses = foreigners(netns, ports[idx].Processes)
+			}
+		}
+		for idx := range netns.PacketSockets {
+			netns.PacketSockets[idx].ForeignProcesses = foreigners(netns, netns.PacketSockets[idx].Processes)
+		}
+		for _, sock := range netns.UnixSockets {
+			sock.ForeignProcesses = foreigners(netns, sock.Processes)
+		}
+	}
+	// Ensure stable results, as we've been iterating over the network
+	// namespaces map in random order.
+	for _, netns := range netspaces {
+		sort.Slice(netns.BridgingProcesses, func(a, b int) bool {
+			return netns.BridgingProcesses[a].Process.PID < netns.BridgingProcesses[b].Process.PID
+		})
+		for _, bridge := range netns.BridgingProcesses {
+			sort.Slice(bridge.ForeignNetns, func(a, b int) bool {
+				return bridge.ForeignNetns[a].ID().Ino < bridge.ForeignNetns[b].ID().Ino
+			})
+		}
+	}
+}
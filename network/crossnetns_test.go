@@ -0,0 +1,60 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeNamespace is a model.Namespace that only knows its identifier.
+type fakeNamespace struct {
+	model.Namespace
+	id species.NamespaceID
+}
+
+func (n fakeNamespace) ID() species.NamespaceID { return n.id }
+
+var _ = Describe("cross-namespace socket owners", func() {
+
+	It("detects processes bridging network namespaces", func() {
+		hostnetnsid := species.NamespaceID{Dev: 1, Ino: 1}
+		cntrnetnsid := species.NamespaceID{Dev: 1, Ino: 2}
+		hostnetns := &NetworkNamespace{Namespace: fakeNamespace{id: hostnetnsid}}
+		cntrnetns := &NetworkNamespace{Namespace: fakeNamespace{id: cntrnetnsid}}
+
+		hostproc := &model.Process{PID: 42}
+		hostproc.Namespaces[model.NetNS] = hostnetns.Namespace
+		cntrproc := &model.Process{PID: 666}
+		cntrproc.Namespaces[model.NetNS] = cntrnetns.Namespace
+		limbo := &model.Process{PID: 1234}
+
+		hostnetns.Portsv4 = []ProcessSocket{{Processes: []*model.Process{hostproc}}}
+		hostnetns.UnixSockets = []*UnixSocket{{Processes: []*model.Process{cntrproc, hostproc}}}
+		cntrnetns.Portsv6 = []ProcessSocket{{Processes: []*model.Process{cntrproc, hostproc, limbo}}}
+		cntrnetns.PacketSockets = []PacketSocket{{Processes: []*model.Process{hostproc}}}
+
+		resolveForeignSocketOwners(NetworkNamespaces{
+			hostnetnsid: hostnetns,
+			cntrnetnsid: cntrnetns,
+		})
+
+		Expect(hostnetns.Portsv4[0].ForeignProcesses).To(BeEmpty())
+		Expect(hostnetns.UnixSockets[0].ForeignProcesses).To(ConsistOf(cntrproc))
+		Expect(cntrnetns.Portsv6[0].ForeignProcesses).To(ConsistOf(hostproc))
+		Expect(cntrnetns.PacketSockets[0].ForeignProcesses).To(ConsistOf(hostproc))
+
+		Expect(hostnetns.BridgingProcesses).To(HaveLen(1))
+		Expect(hostnetns.BridgingProcesses[0].Process).To(BeIdenticalTo(hostproc))
+		Expect(hostnetns.BridgingProcesses[0].ForeignNetns).To(ConsistOf(cntrnetns))
+		Expect(cntrnetns.BridgingProcesses).To(HaveLen(1))
+		Expect(cntrnetns.BridgingProcesses[0].Process).To(BeIdenticalTo(cntrproc))
+		Expect(cntrnetns.BridgingProcesses[0].ForeignNetns).To(ConsistOf(hostnetns))
+	})
+
+})
@@ -0,0 +1,30 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"net"
+	"time"
+)
+
+// DHCPLease describes the DHCP lease an Address was obtained from, as found
+// in the lease files of the DHCP client managing the address. DHCP leases are
+// only known after the dhcplease decorator has run.
+type DHCPLease struct {
+	Client    string            `json:"client"`              // DHCP client, such as "dhclient", "systemd-networkd", ...
+	File      string            `json:"file"`                // path of lease file inside the tenant's mount namespace.
+	Interface string            `json:"interface,omitempty"` // name of network interface, if known from the lease.
+	Address   net.IP            `json:"address"`             // leased IP address.
+	Server    net.IP            `json:"server,omitempty"`    // DHCP server identifier (address).
+	LeaseTime uint32            `json:"lease-time"`          // lease time in seconds.
+	Expiry    time.Time         `json:"expiry"`              // absolute lease expiry time; zero if unknown.
+	Options   map[string]string `json:"options,omitempty"`   // further offered options, such as routers, DNS servers, ...
+}
+
+// Expired returns true if the lease's expiry time is known and has already
+// passed.
+func (l *DHCPLease) Expired() bool {
+	return !l.Expiry.IsZero() && l.Expiry.Before(time.Now())
+}
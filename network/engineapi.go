@@ -0,0 +1,144 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+	"golang.org/x/sys/unix"
+)
+
+// EngineAPIMount describes a container engine API socket that has been
+// (bind-)mounted into a container, giving the container control over the
+// container engine, and thus usually over the host.
+type EngineAPIMount struct {
+	Engine *model.ContainerEngine // container engine whose API socket is mounted.
+	Path   string                 // mount point inside the container.
+}
+
+// engineAPISocket identifies the socket file of a container engine API by its
+// device and inode numbers.
+type engineAPISocket struct {
+	dev uint64
+	ino uint64
+}
+
+// resolveEngineAPISockets identifies the Unix domain sockets serving container
+// engine APIs as well as the sockets connected to them, and the containers
+// having engine API sockets mounted. We identify engine API sockets not by
+// their paths, as these differ between mount namespaces, but instead by the
+// device and inode numbers of their socket files.
+func resolveEngineAPISockets(netspaces NetworkNamespaces, src Source, allprocs model.ProcessTable) {
+	apis := engineAPISockets(netspaces, src, allprocs)
+	if len(apis) == 0 {
+		return
+	}
+	for _, netns := range netspaces {
+		for _, sock := range netns.UnixSockets {
+			if sock.VFSInode == 0 {
+				continue
+			}
+			sock.EngineAPI = apis[engineAPISocket{dev: sock.VFSDev, ino: sock.VFSInode}]
+		}
+	}
+	// Now that we know the engine API serving sockets we can mark the client
+	// sockets connected to them, regardless of the paths the clients used.
+	for _, netns := range netspaces {
+		for _, sock := range netns.UnixSockets {
+			if sock.EngineAPI == nil && sock.Peer != nil {
+				sock.EngineAPI = sock.Peer.EngineAPI
+			}
+		}
+	}
+	for _, netns := range netspaces {
+		for _, tenant := range netns.Tenants {
+			if tenant.Process.Container == nil {
+				continue
+			}
+			tenant.EngineAPIMounts = engineAPIMounts(src, tenant.Process.PID, apis)
+		}
+	}
+}
+
+// engineAPISockets returns the container engines found in the specified
+// network namespaces, indexed by the device and inode numbers of their API
+// socket files.
+func engineAPISockets(netspaces NetworkNamespaces, src Source, allprocs model.ProcessTable) map[engineAPISocket]*model.ContainerEngine {
+	// Container engine API paths are always relative to the initial mount
+	// namespace, so we might need to take a detour if we're not running in
+	// the initial mount namespace ourselves.
+	var initialfs Files = src
+	if proc1 := allprocs[1]; proc1 != nil && proc1.Namespaces[model.MountNS] != nil {
+		if mntfs, closer, err := src.MountFiles(proc1.Namespaces[model.MountNS]); err == nil {
+			defer closer()
+			initialfs = mntfs
+		}
+	}
+	apis := map[engineAPISocket]*model.ContainerEngine{}
+	for _, netns := range netspaces {
+		for _, tenant := range netns.Tenants {
+			cntr := tenant.Process.Container
+			if cntr == nil || cntr.Engine == nil {
+				continue
+			}
+			apipath := strings.TrimPrefix(cntr.Engine.API, "unix://")
+			if !filepath.IsAbs(apipath) {
+				continue
+			}
+			stat, err := initialfs.Stat(apipath)
+			if err != nil || stat.Mode&unix.S_IFMT != unix.S_IFSOCK {
+				continue
+			}
+			apis[engineAPISocket{dev: stat.Dev, ino: stat.Ino}] = cntr.Engine
+		}
+	}
+	return apis
+}
+
+// engineAPIMounts returns the container engine API sockets mounted into the
+// mount namespace of the specified process.
+func engineAPIMounts(src Source, pid model.PIDType, apis map[engineAPISocket]*model.ContainerEngine) []EngineAPIMount {
+	var mounts []EngineAPIMount
+	root := "/proc/" + strconv.FormatUint(uint64(pid), 10) + "/root"
+	for _, mount := range src.Mounts(pid) {
+		// Only bother to stat mount points on the same devices as the engine
+		// API sockets.
+		dev := unix.Mkdev(uint32(mount.Major), uint32(mount.Minor)) // #nosec G115
+		candidate := false
+		for api := range apis {
+			if api.dev == dev {
+				candidate = true
+				break
+			}
+		}
+		if !candidate {
+			continue
+		}
+		stat, err := src.Lstat(root + mount.MountPoint)
+		if err != nil {
+			continue
+		}
+		if engine, ok := apis[engineAPISocket{dev: stat.Dev, ino: stat.Ino}]; ok {
+			mounts = append(mounts, EngineAPIMount{
+				Engine: engine,
+				Path:   mount.MountPoint,
+			})
+		}
+	}
+	return mounts
+}
+
+// EngineNetns returns the network namespace the process of the specified
+// container engine is attached to, or nil if unknown.
+func EngineNetns(engine *model.ContainerEngine, netspaces NetworkNamespaces, allprocs model.ProcessTable) *NetworkNamespace {
+	proc := allprocs[engine.PID]
+	if proc == nil || proc.Namespaces[model.NetNS] == nil {
+		return nil
+	}
+	return netspaces[proc.Namespaces[model.NetNS].ID()]
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/siemens/ghostwire/v2/diagnostics"
 	"github.com/thediveo/go-plugger/v3"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
@@ -55,6 +56,11 @@ type NifAttrs struct {
 	Addrsv6     Addresses         // assigned IPv6 network addresses.
 	SRIOVRole   SRIOVRole         // ...when network interface is an SR-IOV PF or VF.
 
+	McastGroupsv4 []MulticastGroup   // joined IPv4 multicast groups.
+	McastGroupsv6 []MulticastGroup   // joined IPv6 multicast groups.
+	McastL2Addrs  []net.HardwareAddr // link-layer multicast addresses.
+	Sysctls       Sysctls            // per-interface configuration sysctls.
+
 	// Relations with other network interfaces
 	Bridge Interface  // when interface is a "port" of a bridge interface.
 	Slaves Interfaces // MACVLANs, VXLANs, VFs, others (but not VETH peers).
@@ -107,9 +113,19 @@ type initializer interface {
 	Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) // initializes a network interface-based type
 }
 
+// sourceInitializer instructs a network Interface to initialize itself when
+// there is no netlink handle, such as when replaying a discovery from a support
+// bundle, getting its addresses from the specified AddrLister instead. Network
+// Interface types not implementing sourceInitializer only get their common
+// network interface attributes initialized in this case.
+type sourceInitializer interface {
+	initFromSource(addrs AddrLister, netns *NetworkNamespace, link netlink.Link)
+}
+
 var _ Interface = (*NifAttrs)(nil)
 var _ resolver = (*NifAttrs)(nil)
 var _ initializer = (*NifAttrs)(nil)
+var _ sourceInitializer = (*NifAttrs)(nil)
 
 // Nif returns the common network interface attributes.
 func (n *NifAttrs) Nif() *NifAttrs { return n }
@@ -120,6 +136,14 @@ func (n *NifAttrs) Nif() *NifAttrs { return n }
 // the specific kind of link. If there is no dedicated Gostwire type, then the
 // generic NifAttr type is created and returned instead.
 func NewInterface(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) Interface {
+	return newInterface(nlh, nlh, netns, link)
+}
+
+// newInterface returns a new network.Interface for the specified link. If
+// there is a netlink handle, the network interface gets initialized using it.
+// Otherwise, the network interface gets its addresses from the specified
+// AddrLister.
+func newInterface(nlh *netlink.Handle, addrs AddrLister, netns *NetworkNamespace, link netlink.Link) Interface {
 	if len(nifMakers) == 0 {
 		collectNifMakers()
 	}
@@ -129,10 +153,28 @@ func NewInterface(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Lin
 	} else {
 		nif = &NifAttrs{}
 	}
-	nif.(initializer).Init(nlh, netns, link)
+	initInterface(nif, nlh, addrs, netns, link)
 	return nif
 }
 
+// initInterface initializes the specified network interface using the netlink
+// handle, if any, or otherwise from the specified AddrLister.
+func initInterface(nif Interface, nlh *netlink.Handle, addrs AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	if nlh != nil {
+		if nifinit, ok := nif.(initializer); ok {
+			nifinit.Init(nlh, netns, link)
+		} else {
+			nif.Nif().Init(nlh, netns, link)
+		}
+		return
+	}
+	if nifinit, ok := nif.(sourceInitializer); ok {
+		nifinit.initFromSource(addrs, netns, link)
+	} else {
+		nif.Nif().initFromSource(addrs, netns, link)
+	}
+}
+
 // collectNifMakers populates the map with the NifMakers for the particular kind
 // of network interface.
 func collectNifMakers() {
@@ -159,6 +201,12 @@ func (n *NifAttrs) Interface() Interface {
 // Init initializes this Nif from information specified in the NetworkNamespace
 // and lots of netlink.Link information.
 func (n *NifAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this Nif, getting its addresses from the
+// specified AddrLister.
+func (n *NifAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
 	attrs := link.Attrs()
 	l2addr := attrs.HardwareAddr
 	if len(l2addr) == 0 {
@@ -192,6 +240,18 @@ func (n *NifAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netli
 				Index:             addr.LinkIndex,
 				PreferredLifetime: uint32(addr.PreferedLft),
 				ValidLifetime:     uint32(addr.ValidLft),
+				Flags:             AddressFlags(addr.Flags),
+				Label:             addr.Label,
+				Broadcast:         addr.Broadcast,
+			}
+			if addr.Peer != nil {
+				peerprefixlen, _ := addr.Peer.Mask.Size()
+				a.Peer = addr.Peer.IP
+				a.PeerPrefixLength = uint(peerprefixlen)
+			}
+			if a.DADFailed() {
+				log.Warnf("duplicate address detection failed for %s on nif %q in net:[%d]",
+					addr.IP.String(), attrs.Name, netns.ID().Ino)
 			}
 			if family == unix.AF_INET6 {
 				addrsv6 = append(addrsv6, a)
@@ -243,13 +303,21 @@ func (n *NifAttrs) HasAddress(ip net.IP) bool {
 // updates the interface's BusAddr field. We don't do bus address discovery in
 // Init because we don't want to allocate "private" local ethtool API file
 // descriptors over and over again individually for each physical network
-// interface. Instead, NetworkNamespace.discoverNetworkInterfaces allocates an
-// ethtool API file descriptor only once per network namespace, and only when
-// physical network interfaces are present in a network namespace.
-func (n *NifAttrs) discoverBusAddress(ethtoolFd int) {
-	driverInfo, err := unix.IoctlGetEthtoolDrvinfo(ethtoolFd, n.Name)
+// interface. Instead, the network namespace access used by
+// NetworkNamespace.discoverNetworkInterfaces allocates an ethtool API file
+// descriptor only once per network namespace, and only when physical network
+// interfaces are present in a network namespace.
+func (n *NifAttrs) discoverBusAddress(nsa NetnsAccess) {
+	driverInfo, err := nsa.DriverInfo(n.Name)
 	if err != nil {
-		log.Errorf("cannot query ethtool API driver information for nif %q, reason: %s", n.Name, err.Error())
+		scope := diagnostics.Scope{Interface: n.Name}
+		var diags *diagnostics.Diagnostics
+		if n.Netns != nil {
+			scope.Netns = n.Netns.ID().Ino
+			diags = n.Netns.diags
+		}
+		diags.Errorf(diagnostics.EthtoolUnavailable, scope,
+			"cannot query ethtool API driver information for nif %q, reason: %s", n.Name, err.Error())
 		return
 	}
 	n.DriverInfo.Driver = strings.TrimRight(string(driverInfo.Driver[:]), "\x00")
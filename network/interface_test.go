@@ -5,10 +5,27 @@
 package network
 
 import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+// handleOnlyNif is a network interface type that only knows how to initialize
+// itself from a netlink handle, as network interface types from other packages
+// do.
+type handleOnlyNif struct {
+	NifAttrs
+	nlh *netlink.Handle
+}
+
+func (n *handleOnlyNif) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.Init(nlh, netns, link)
+	n.nlh = nlh
+}
+
 var _ = Describe("network interface", func() {
 
 	It("has all nif makers correctly registered", func() {
@@ -24,6 +41,40 @@ var _ = Describe("network interface", func() {
 		}
 	})
 
+	It("initializes network interfaces with and without netlink handles", func() {
+		link := &netlink.Bridge{
+			LinkAttrs:         netlink.LinkAttrs{Name: "br-42", Index: 42},
+			MulticastSnooping: func() *bool { b := true; return &b }(),
+		}
+		addrs := dumpedAddrs{42: {{Addr: netlink.Addr{IPNet: &net.IPNet{
+			IP:   net.ParseIP("10.0.0.42").To4(),
+			Mask: net.CIDRMask(24, 32),
+		}}}}}
+
+		By("initializing own types from a source")
+		nif := newInterface(nil, addrs, nil, link)
+		Expect(nif).To(BeAssignableToTypeOf(&BridgeAttrs{}))
+		Expect(nif.Nif().Name).To(Equal("br-42"))
+		Expect(nif.(*BridgeAttrs).MulticastSnooping).To(BeTrue())
+		Expect(nif.Nif().Addrsv4).To(ConsistOf(HaveField("Address", net.ParseIP("10.0.0.42").To4())))
+
+		By("passing the netlink handle")
+		nlh, err := netlink.NewHandle()
+		Expect(err).NotTo(HaveOccurred())
+		defer nlh.Close()
+		hnif := &handleOnlyNif{}
+		initInterface(hnif, nlh, nil, nil, link)
+		Expect(hnif.nlh).To(BeIdenticalTo(nlh))
+		Expect(hnif.Name).To(Equal("br-42"))
+
+		By("initializing only the common attributes without netlink handle")
+		hnif = &handleOnlyNif{}
+		initInterface(hnif, nil, addrs, nil, link)
+		Expect(hnif.nlh).To(BeNil())
+		Expect(hnif.Name).To(Equal("br-42"))
+		Expect(hnif.Addrsv4).To(HaveLen(1))
+	})
+
 	It("returns interfaces of a kind", func() {
 		nifs := Interfaces{
 			&NifAttrs{Name: "lo", Kind: ""},
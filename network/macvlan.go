@@ -42,7 +42,13 @@ func (n *MacvlanAttrs) Macvlan() *MacvlanAttrs { return n }
 // Init initializes this MACVLAN Nif from information specified in the
 // NetworkNamespace and lots of netlink.Link information.
 func (n *MacvlanAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
-	n.NifAttrs.Init(nlh, netns, link)
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this MACVLAN Nif, getting its addresses from the
+// specified AddrLister.
+func (n *MacvlanAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.initFromSource(nlh, netns, link)
 	n.Mode = MacvlanMode(link.(*netlink.Macvlan).Mode)
 }
 
@@ -0,0 +1,470 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// MPTCP path manager generic netlink family, commands and attributes; see
+// also: https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/mptcp.h
+const (
+	mptcpPMName = "mptcp_pm"
+	mptcpPMVer  = 1
+
+	mptcpPMCmdGetAddr   = 3
+	mptcpPMCmdGetLimits = 6
+
+	mptcpPMAttrAddr        = 1
+	mptcpPMAttrRcvAddAddrs = 2
+	mptcpPMAttrSubflows    = 3
+
+	mptcpPMAddrAttrFamily = 1
+	mptcpPMAddrAttrID     = 2
+	mptcpPMAddrAttrAddr4  = 3
+	mptcpPMAddrAttrAddr6  = 4
+	mptcpPMAddrAttrPort   = 5
+	mptcpPMAddrAttrFlags  = 6
+	mptcpPMAddrAttrIfIdx  = 7
+)
+
+// MPTCP subflow diagnostic attributes, nested inside INET_DIAG_ULP_INFO; see
+// also: https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/mptcp.h
+const (
+	inetDiagReqProtocol = 3
+
+	inetULPInfoName  = 1
+	inetULPInfoMPTCP = 3
+
+	mptcpSubflowAttrTokenRem = 1
+	mptcpSubflowAttrTokenLoc = 2
+	mptcpSubflowAttrFlags    = 8
+	mptcpSubflowAttrIDRem    = 9
+	mptcpSubflowAttrIDLoc    = 10
+)
+
+// Offsets into struct mptcp_info as well as its size in a v6.1 kernel.
+const (
+	mptcpInfoFlags         = 8
+	mptcpInfoToken         = 12
+	mptcpInfoRetransmits   = 44
+	mptcpInfoBytesSent     = 56
+	mptcpInfoBytesReceived = 64
+	mptcpInfoBytesAcked    = 72
+	sizeofMPTCPInfo        = 81
+)
+
+// MPTCPEndpointFlags are the flags of an MPTCP path manager endpoint.
+type MPTCPEndpointFlags uint32
+
+// MPTCP path manager endpoint flags.
+const (
+	MPTCPEndpointSignal   MPTCPEndpointFlags = 1 << iota // announce address to peers
+	MPTCPEndpointSubflow                                 // create subflows from address
+	MPTCPEndpointBackup                                  // backup path
+	MPTCPEndpointFullmesh                                // subflows to all announced peer addresses
+	MPTCPEndpointImplicit                                // implicitly created by the kernel
+)
+
+var mptcpEndpointFlagNames = []string{"signal", "subflow", "backup", "fullmesh", "implicit"}
+
+// Names returns the names of the flags set.
+func (f MPTCPEndpointFlags) Names() []string {
+	names := []string{}
+	for bit, name := range mptcpEndpointFlagNames {
+		if f&(1<<bit) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// String returns the names of the flags set, separated by commas.
+func (f MPTCPEndpointFlags) String() string {
+	return strings.Join(f.Names(), ",")
+}
+
+// MPTCPEndpoint is an address the MPTCP in-kernel path manager of a network
+// namespace uses for announcing and creating additional subflows.
+type MPTCPEndpoint struct {
+	ID      uint8              // endpoint ID.
+	IP      net.IP             // endpoint address; IPv4 addresses are in .To4() format.
+	Port    uint16             // optional port, only for signal endpoints.
+	Flags   MPTCPEndpointFlags // endpoint flags.
+	Nif     Interface          // network interface to use, if any.
+	ifindex int
+}
+
+// MPTCPLimits are the limits of the MPTCP in-kernel path manager of a network
+// namespace.
+type MPTCPLimits struct {
+	AddAddrAccepted uint32 // max. number of ADD_ADDR announcements accepted per connection.
+	Subflows        uint32 // max. number of additional subflows per connection.
+}
+
+// MPTCPSubflow contains the MPTCP-specific details of a TCP socket acting as
+// an MPTCP subflow.
+type MPTCPSubflow struct {
+	LocalToken  uint32 // token of the MPTCP connection this subflow belongs to.
+	RemoteToken uint32 // token of the peer's MPTCP connection.
+	LocalID     uint8  // local address ID.
+	RemoteID    uint8  // remote address ID.
+	Flags       uint32 // subflow flags.
+}
+
+// MPTCPInfo contains selected MPTCP connection details from the kernel's
+// struct mptcp_info.
+type MPTCPInfo struct {
+	Subflows           uint8  // number of additional subflows.
+	AddAddrSignal      uint8  // number of addresses announced.
+	AddAddrAccepted    uint8  // number of accepted peer address announcements.
+	SubflowsMax        uint8  // max. number of additional subflows.
+	AddAddrSignalMax   uint8  // max. number of addresses to announce.
+	AddAddrAcceptedMax uint8  // max. number of peer address announcements to accept.
+	Flags              uint32 // connection flags.
+	Token              uint32 // local connection token.
+	Retransmits        uint32 // number of retransmissions.
+	BytesSent          uint64
+	BytesReceived      uint64
+	BytesAcked         uint64
+}
+
+// MPTCPConnection is an MPTCP socket together with its TCP subflows. The
+// embedded ProcessSocket describes the MPTCP socket itself, with its
+// addresses being those of the initial subflow.
+type MPTCPConnection struct {
+	ProcessSocket
+	Info     *MPTCPInfo      // MPTCP connection details, if available.
+	Subflows []ProcessSocket // TCP subflows of this MPTCP connection.
+}
+
+// discoverMPTCP discovers the MPTCP path manager endpoints and limits, as well
+// as the MPTCP sockets with their subflows in this network namespace. As the
+// TCP subflows aren't owned by any process, they are attributed to the
+// processes owning their MPTCP socket. Please note that discoverMPTCP needs to
+// run after the transport-layer ports have been discovered.
+func (n *NetworkNamespace) discoverMPTCP(sm socketToProcessMap, allprocs model.ProcessTable) {
+	var conns map[int][]MPTCPConnection
+	_ = sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
+		if familyID, err := genlFamilyID(nsa, mptcpPMName); err == nil {
+			if endpoints, err := mptcpEndpoints(nsa, familyID); err == nil {
+				n.MPTCPEndpoints = endpoints
+			}
+			if limits, err := mptcpLimits(nsa, familyID); err == nil {
+				n.MPTCPLimits = limits
+			}
+		}
+		conns = map[int][]MPTCPConnection{}
+		for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+			if c, err := diagMPTCPConnections(nsa, af, sm); err == nil {
+				conns[af] = c
+			}
+		}
+		return nil
+	})
+	for idx := range n.MPTCPEndpoints {
+		if n.MPTCPEndpoints[idx].ifindex != 0 {
+			n.MPTCPEndpoints[idx].Nif = n.Nifs[n.MPTCPEndpoints[idx].ifindex]
+		}
+	}
+	n.MPTCPv4 = n.groupMPTCPSubflows(conns[unix.AF_INET], allprocs)
+	n.MPTCPv6 = n.groupMPTCPSubflows(conns[unix.AF_INET6], allprocs)
+}
+
+// groupMPTCPSubflows attributes the MPTCP connections to their processes and
+// groups the TCP subflows under their MPTCP connections. Connected subflows
+// are related by connection token, while listening subflows are related by
+// their local address and port.
+func (n *NetworkNamespace) groupMPTCPSubflows(conns []MPTCPConnection, allprocs model.ProcessTable) []MPTCPConnection {
+	for cidx := range conns {
+		conn := &conns[cidx]
+		conn.Processes = allprocs.ProcessesByPIDs(conn.PIDs...)
+		for _, ports := range [][]ProcessSocket{n.Portsv4, n.Portsv6} {
+			for pidx := range ports {
+				subflow := &ports[pidx]
+				if subflow.MPTCPSubflow == nil || !conn.isSubflow(subflow) {
+					continue
+				}
+				subflow.PIDs = conn.PIDs
+				subflow.Processes = conn.Processes
+				if !subflow.IPv4Mapped {
+					conn.Subflows = append(conn.Subflows, *subflow)
+				}
+			}
+		}
+	}
+	return conns
+}
+
+// isSubflow returns true if the specified TCP socket is a subflow of this MPTCP
+// connection.
+func (c *MPTCPConnection) isSubflow(subflow *ProcessSocket) bool {
+	if c.State == TCP_LISTEN {
+		return subflow.State == TCP_LISTEN &&
+			subflow.LocalPort == c.LocalPort &&
+			normalizedIP(subflow.LocalIP).Equal(normalizedIP(c.LocalIP))
+	}
+	return c.Info != nil && c.Info.Token != 0 && subflow.MPTCPSubflow.LocalToken == c.Info.Token
+}
+
+// genlFamilyID returns the ID of the named generic netlink family, such as
+// MPTCP_PM_NAME; this is what netlink.GenlFamilyGet does, minus decoding the
+// family's operations and multicast groups.
+func genlFamilyID(nsa NetnsAccess, name string) (uint16, error) {
+	req := nl.NewNetlinkRequest(nl.GENL_ID_CTRL, 0)
+	req.AddData(&nl.Genlmsg{Command: nl.GENL_CTRL_CMD_GETFAMILY, Version: nl.GENL_CTRL_VERSION})
+	req.AddData(nl.NewRtAttr(nl.GENL_CTRL_ATTR_FAMILY_NAME, nl.ZeroTerminated(name)))
+	msgs, err := nsa.Execute(req, unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) != 1 || len(msgs[0]) < nl.SizeofGenlmsg {
+		return 0, errors.New("invalid generic netlink family response")
+	}
+	attrs, err := nl.ParseRouteAttr(msgs[0][nl.SizeofGenlmsg:])
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type == nl.GENL_CTRL_ATTR_FAMILY_ID && len(attr.Value) >= 2 {
+			return nl.NativeEndian().Uint16(attr.Value), nil
+		}
+	}
+	return 0, errors.New("generic netlink family response lacks family ID")
+}
+
+// mptcpEndpoints returns the MPTCP path manager endpoints of the visited
+// network namespace.
+func mptcpEndpoints(nsa NetnsAccess, familyID uint16) ([]MPTCPEndpoint, error) {
+	req := nl.NewNetlinkRequest(int(familyID), unix.NLM_F_DUMP)
+	req.AddData(&nl.Genlmsg{Command: mptcpPMCmdGetAddr, Version: mptcpPMVer})
+	msgs, err := nsa.Execute(req, unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]MPTCPEndpoint, 0, len(msgs))
+	for _, msg := range msgs {
+		if len(msg) < nl.SizeofGenlmsg {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(msg[nl.SizeofGenlmsg:])
+		if err != nil {
+			continue
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type&nl.NLA_TYPE_MASK != mptcpPMAttrAddr {
+				continue
+			}
+			if endpoint, ok := newMPTCPEndpoint(attr.Value); ok {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// newMPTCPEndpoint returns the MPTCP path manager endpoint described by the
+// specified nested address attributes.
+func newMPTCPEndpoint(b []byte) (MPTCPEndpoint, bool) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return MPTCPEndpoint{}, false
+	}
+	var endpoint MPTCPEndpoint
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case mptcpPMAddrAttrID:
+			if len(attr.Value) >= 1 {
+				endpoint.ID = attr.Value[0]
+			}
+		case mptcpPMAddrAttrAddr4:
+			if len(attr.Value) >= net.IPv4len {
+				endpoint.IP = net.IP(append([]byte{}, attr.Value[:net.IPv4len]...))
+			}
+		case mptcpPMAddrAttrAddr6:
+			if len(attr.Value) >= net.IPv6len {
+				endpoint.IP = net.IP(append([]byte{}, attr.Value[:net.IPv6len]...))
+			}
+		case mptcpPMAddrAttrPort:
+			if len(attr.Value) >= 2 {
+				endpoint.Port = nl.NativeEndian().Uint16(attr.Value)
+			}
+		case mptcpPMAddrAttrFlags:
+			if len(attr.Value) >= 4 {
+				endpoint.Flags = MPTCPEndpointFlags(nl.NativeEndian().Uint32(attr.Value))
+			}
+		case mptcpPMAddrAttrIfIdx:
+			if len(attr.Value) >= 4 {
+				endpoint.ifindex = int(int32(nl.NativeEndian().Uint32(attr.Value)))
+			}
+		}
+	}
+	return endpoint, endpoint.IP != nil
+}
+
+// mptcpLimits returns the MPTCP path manager limits of the visited network
+// namespace.
+func mptcpLimits(nsa NetnsAccess, familyID uint16) (*MPTCPLimits, error) {
+	req := nl.NewNetlinkRequest(int(familyID), 0)
+	req.AddData(&nl.Genlmsg{Command: mptcpPMCmdGetLimits, Version: mptcpPMVer})
+	msgs, err := nsa.Execute(req, unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 || len(msgs[0]) < nl.SizeofGenlmsg {
+		return nil, errors.New("invalid MPTCP limits response")
+	}
+	return newMPTCPLimits(msgs[0][nl.SizeofGenlmsg:])
+}
+
+// newMPTCPLimits returns the MPTCP path manager limits from the specified
+// attributes.
+func newMPTCPLimits(b []byte) (*MPTCPLimits, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	limits := &MPTCPLimits{}
+	for _, attr := range attrs {
+		if len(attr.Value) < 4 {
+			continue
+		}
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case mptcpPMAttrRcvAddAddrs:
+			limits.AddAddrAccepted = nl.NativeEndian().Uint32(attr.Value)
+		case mptcpPMAttrSubflows:
+			limits.Subflows = nl.NativeEndian().Uint32(attr.Value)
+		}
+	}
+	return limits, nil
+}
+
+// diagMPTCPConnections dumps the MPTCP sockets of the specified address family
+// in the visited network namespace. As the MPTCP protocol number doesn't fit
+// into the inet_diag_req_v2 request, it is passed as a separate attribute.
+func diagMPTCPConnections(nsa NetnsAccess, af int, sm socketToProcessMap) ([]MPTCPConnection, error) {
+	var msgs [][]byte
+	var err error
+	for attempt := 0; attempt < maxSockDiagDumpAttempts; attempt++ {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, unix.NLM_F_DUMP)
+		req.AddData(&inetDiagReqV2{
+			Family: uint8(af),
+			Ext:    1 << (inetDiagInfo - 1),
+			States: ^uint32(0), // all states
+		})
+		req.AddData(nl.NewRtAttr(inetDiagReqProtocol, nl.Uint32Attr(unix.IPPROTO_MPTCP)))
+		msgs, err = nsa.Execute(req, unix.NETLINK_INET_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	conns := make([]MPTCPConnection, 0, len(msgs))
+	for _, msg := range msgs {
+		if conn, ok := newDiagMPTCPConnection(msg, af, sm); ok {
+			conns = append(conns, conn)
+		}
+	}
+	return conns, nil
+}
+
+// newDiagMPTCPConnection returns an MPTCP connection (without subflows) with
+// the information from a sock_diag inet_diag_msg message.
+func newDiagMPTCPConnection(msg []byte, af int, sm socketToProcessMap) (MPTCPConnection, bool) {
+	procsock := newDiagProcessSocket(msg, af, unix.IPPROTO_MPTCP, sm)
+	if procsock.State == 0 {
+		return MPTCPConnection{}, false
+	}
+	conn := MPTCPConnection{ProcessSocket: procsock}
+	attrs, err := nl.ParseRouteAttr(msg[sizeofInetDiagMsg:])
+	if err != nil {
+		return conn, true
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK == inetDiagInfo {
+			conn.Info = newMPTCPInfo(attr.Value)
+		}
+	}
+	return conn, true
+}
+
+// newMPTCPInfo returns the MPTCP connection details from a binary struct
+// mptcp_info, or nil. Fields missing from older kernels are zeroed.
+func newMPTCPInfo(b []byte) *MPTCPInfo {
+	if len(b) == 0 {
+		return nil
+	}
+	buf := make([]byte, sizeofMPTCPInfo)
+	copy(buf, b)
+	return &MPTCPInfo{
+		Subflows:           buf[0],
+		AddAddrSignal:      buf[1],
+		AddAddrAccepted:    buf[2],
+		SubflowsMax:        buf[3],
+		AddAddrSignalMax:   buf[4],
+		AddAddrAcceptedMax: buf[5],
+		Flags:              nl.NativeEndian().Uint32(buf[mptcpInfoFlags:]),
+		Token:              nl.NativeEndian().Uint32(buf[mptcpInfoToken:]),
+		Retransmits:        nl.NativeEndian().Uint32(buf[mptcpInfoRetransmits:]),
+		BytesSent:          nl.NativeEndian().Uint64(buf[mptcpInfoBytesSent:]),
+		BytesReceived:      nl.NativeEndian().Uint64(buf[mptcpInfoBytesReceived:]),
+		BytesAcked:         nl.NativeEndian().Uint64(buf[mptcpInfoBytesAcked:]),
+	}
+}
+
+// newMPTCPSubflow returns the MPTCP subflow details from the nested attributes
+// of an INET_DIAG_ULP_INFO attribute, or nil if the TCP socket isn't an MPTCP
+// subflow.
+func newMPTCPSubflow(b []byte) *MPTCPSubflow {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != inetULPInfoMPTCP {
+			continue
+		}
+		sfattrs, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil
+		}
+		subflow := &MPTCPSubflow{}
+		for _, sfattr := range sfattrs {
+			v := sfattr.Value
+			switch sfattr.Attr.Type & nl.NLA_TYPE_MASK {
+			case mptcpSubflowAttrTokenRem:
+				if len(v) >= 4 {
+					subflow.RemoteToken = nl.NativeEndian().Uint32(v)
+				}
+			case mptcpSubflowAttrTokenLoc:
+				if len(v) >= 4 {
+					subflow.LocalToken = nl.NativeEndian().Uint32(v)
+				}
+			case mptcpSubflowAttrFlags:
+				if len(v) >= 4 {
+					subflow.Flags = nl.NativeEndian().Uint32(v)
+				}
+			case mptcpSubflowAttrIDRem:
+				if len(v) >= 1 {
+					subflow.RemoteID = v[0]
+				}
+			case mptcpSubflowAttrIDLoc:
+				if len(v) >= 1 {
+					subflow.LocalID = v[0]
+				}
+			}
+		}
+		return subflow
+	}
+	return nil
+}
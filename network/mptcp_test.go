@@ -0,0 +1,203 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// serializeAttrs returns the serialized netlink attributes.
+func serializeAttrs(attrs ...*nl.RtAttr) []byte {
+	b := []byte{}
+	for _, attr := range attrs {
+		b = append(b, attr.Serialize()...)
+	}
+	return b
+}
+
+var _ = Describe("MPTCP", func() {
+
+	It("names endpoint flags", func() {
+		Expect(MPTCPEndpointFlags(0).String()).To(BeEmpty())
+		Expect((MPTCPEndpointSignal | MPTCPEndpointBackup).Names()).To(
+			ConsistOf("signal", "backup"))
+		Expect((MPTCPEndpointSubflow | MPTCPEndpointFullmesh | MPTCPEndpointImplicit).String()).To(
+			Equal("subflow,fullmesh,implicit"))
+	})
+
+	It("parses endpoints and limits", func() {
+		_, ok := newMPTCPEndpoint([]byte{1})
+		Expect(ok).To(BeFalse())
+
+		endpoint, ok := newMPTCPEndpoint(serializeAttrs(
+			nl.NewRtAttr(mptcpPMAddrAttrFamily, nl.Uint16Attr(unix.AF_INET)),
+			nl.NewRtAttr(mptcpPMAddrAttrID, []byte{42}),
+			nl.NewRtAttr(mptcpPMAddrAttrAddr4, []byte{10, 0, 0, 1}),
+			nl.NewRtAttr(mptcpPMAddrAttrPort, nl.Uint16Attr(1234)),
+			nl.NewRtAttr(mptcpPMAddrAttrFlags, nl.Uint32Attr(uint32(MPTCPEndpointSubflow))),
+			nl.NewRtAttr(mptcpPMAddrAttrIfIdx, nl.Uint32Attr(2)),
+		))
+		Expect(ok).To(BeTrue())
+		Expect(endpoint.ID).To(Equal(uint8(42)))
+		Expect(endpoint.IP).To(Equal(net.IP{10, 0, 0, 1}))
+		Expect(endpoint.Port).To(Equal(uint16(1234)))
+		Expect(endpoint.Flags).To(Equal(MPTCPEndpointSubflow))
+		Expect(endpoint.ifindex).To(Equal(2))
+
+		endpoint, ok = newMPTCPEndpoint(serializeAttrs(
+			nl.NewRtAttr(mptcpPMAddrAttrAddr6, net.ParseIP("fe80::1"))))
+		Expect(ok).To(BeTrue())
+		Expect(endpoint.IP).To(Equal(net.ParseIP("fe80::1")))
+
+		limits, err := newMPTCPLimits(serializeAttrs(
+			nl.NewRtAttr(mptcpPMAttrRcvAddAddrs, nl.Uint32Attr(4)),
+			nl.NewRtAttr(mptcpPMAttrSubflows, nl.Uint32Attr(2)),
+		))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).To(Equal(&MPTCPLimits{AddAddrAccepted: 4, Subflows: 2}))
+	})
+
+	It("parses MPTCP connection and subflow details", func() {
+		Expect(newMPTCPInfo(nil)).To(BeNil())
+		info := make([]byte, sizeofMPTCPInfo)
+		info[0] = 2
+		info[3] = 8
+		nl.NativeEndian().PutUint32(info[mptcpInfoToken:], 0xdeadbeef)
+		nl.NativeEndian().PutUint64(info[mptcpInfoBytesSent:], 1<<40)
+		Expect(newMPTCPInfo(info)).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Subflows":    Equal(uint8(2)),
+			"SubflowsMax": Equal(uint8(8)),
+			"Token":       Equal(uint32(0xdeadbeef)),
+			"BytesSent":   Equal(uint64(1 << 40)),
+		})))
+		Expect(newMPTCPInfo(info[:16]).BytesSent).To(BeZero())
+
+		Expect(newMPTCPSubflow(serializeAttrs(
+			nl.NewRtAttr(inetULPInfoName, nl.ZeroTerminated("tls"))))).To(BeNil())
+		sfattrs := nl.NewRtAttr(inetULPInfoMPTCP|unix.NLA_F_NESTED, nil)
+		sfattrs.AddRtAttr(mptcpSubflowAttrTokenRem, nl.Uint32Attr(1))
+		sfattrs.AddRtAttr(mptcpSubflowAttrTokenLoc, nl.Uint32Attr(2))
+		sfattrs.AddRtAttr(mptcpSubflowAttrFlags, nl.Uint32Attr(3))
+		sfattrs.AddRtAttr(mptcpSubflowAttrIDRem, []byte{4})
+		sfattrs.AddRtAttr(mptcpSubflowAttrIDLoc, []byte{5})
+		ulp := nl.NewRtAttr(inetDiagULPInfo|unix.NLA_F_NESTED, nil)
+		ulp.AddRtAttr(inetULPInfoName, nl.ZeroTerminated("mptcp"))
+		ulp.AddChild(sfattrs)
+
+		sox := newDiagProcessSocket(
+			newInetDiagMsg(TCP_ESTABLISHED, net.IP{10, 0, 0, 1}, 1234, net.IP{10, 0, 0, 2}, 80, 0, 0, 0, 0, ulp),
+			unix.AF_INET, syscall.IPPROTO_TCP, nil)
+		Expect(sox.MPTCPSubflow).To(Equal(&MPTCPSubflow{
+			RemoteToken: 1,
+			LocalToken:  2,
+			Flags:       3,
+			RemoteID:    4,
+			LocalID:     5,
+		}))
+	})
+
+	It("groups subflows under their connections", func() {
+		proc := &model.Process{PID: 42}
+		conns := []MPTCPConnection{
+			{
+				ProcessSocket: ProcessSocket{State: TCP_LISTEN, LocalIP: net.IP{0, 0, 0, 0}, LocalPort: 80,
+					PIDs: []model.PIDType{42}},
+			},
+			{
+				ProcessSocket: ProcessSocket{State: TCP_ESTABLISHED, LocalIP: net.IP{10, 0, 0, 1}, LocalPort: 1234,
+					PIDs: []model.PIDType{42}},
+				Info: &MPTCPInfo{Token: 666},
+			},
+		}
+		netns := &NetworkNamespace{
+			Portsv4: []ProcessSocket{
+				{State: TCP_LISTEN, LocalIP: net.IP{0, 0, 0, 0}, LocalPort: 80, MPTCPSubflow: &MPTCPSubflow{}},
+				{State: TCP_ESTABLISHED, LocalPort: 1234, MPTCPSubflow: &MPTCPSubflow{LocalToken: 666}},
+				{State: TCP_ESTABLISHED, LocalPort: 1235, MPTCPSubflow: &MPTCPSubflow{LocalToken: 666}},
+				{State: TCP_ESTABLISHED, LocalPort: 1236, MPTCPSubflow: &MPTCPSubflow{LocalToken: 1}},
+				{State: TCP_ESTABLISHED, LocalPort: 1237},
+			},
+		}
+		conns = netns.groupMPTCPSubflows(conns, model.ProcessTable{42: proc})
+		Expect(conns[0].Processes).To(ConsistOf(proc))
+		Expect(conns[0].Subflows).To(HaveLen(1))
+		Expect(conns[1].Subflows).To(HaveLen(2))
+		Expect(netns.Portsv4[1].Processes).To(ConsistOf(proc))
+		Expect(netns.Portsv4[3].Processes).To(BeEmpty())
+		Expect(netns.Portsv4[4].Processes).To(BeEmpty())
+	})
+
+	It("discovers MPTCP in the current network namespace", func() {
+		if unix.Geteuid() != 0 {
+			Skip("needs root")
+		}
+		lc := net.ListenConfig{}
+		lc.SetMultipathTCP(true)
+		l, err := lc.Listen(context.Background(), "tcp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		port := uint16(l.Addr().(*net.TCPAddr).Port)
+		d := net.Dialer{}
+		d.SetMultipathTCP(true)
+		conn, err := d.Dial("tcp4", l.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		if mptcp, _ := conn.(*net.TCPConn).MultipathTCP(); !mptcp {
+			Skip("MPTCP not available")
+		}
+		accepted, err := l.Accept()
+		Expect(err).NotTo(HaveOccurred())
+		defer accepted.Close()
+
+		conns, err := diagMPTCPConnections(&liveNetnsAccess{ethtoolFd: -1}, unix.AF_INET, nil)
+		if err != nil {
+			Skip("MPTCP sock_diag not available")
+		}
+		Expect(conns).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"ProcessSocket": MatchFields(IgnoreExtras, Fields{
+				"Protocol":  Equal(Protocol(unix.IPPROTO_MPTCP)),
+				"LocalPort": Equal(port),
+				"State":     Equal(TCP_LISTEN),
+			}),
+		})))
+		tcpsox, err := diagSockets(&liveNetnsAccess{ethtoolFd: -1}, unix.AF_INET, syscall.IPPROTO_TCP, nil)
+		Expect(err).NotTo(HaveOccurred())
+		netns := &NetworkNamespace{Portsv4: tcpsox}
+		conns = netns.groupMPTCPSubflows(conns, nil)
+		Expect(conns).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"ProcessSocket": MatchFields(IgnoreExtras, Fields{
+				"RemotePort": Equal(port),
+				"State":      Equal(TCP_ESTABLISHED),
+			}),
+			"Info": PointTo(MatchFields(IgnoreExtras, Fields{
+				"Token": Not(BeZero()),
+			})),
+			"Subflows": ContainElement(MatchFields(IgnoreExtras, Fields{
+				"RemotePort": Equal(port),
+			})),
+		})))
+
+		family, err := netlink.GenlFamilyGet(mptcpPMName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(genlFamilyID(&liveNetnsAccess{ethtoolFd: -1}, mptcpPMName)).To(Equal(family.ID))
+		_, err = mptcpEndpoints(&liveNetnsAccess{ethtoolFd: -1}, family.ID)
+		Expect(err).NotTo(HaveOccurred())
+		limits, err := mptcpLimits(&liveNetnsAccess{ethtoolFd: -1}, family.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).NotTo(BeNil())
+	})
+
+})
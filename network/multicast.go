@@ -0,0 +1,301 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// MulticastGroup is an IPv4 or IPv6 multicast group joined on a particular
+// network interface.
+//
+// The Linux kernel does not tell us which sockets have joined a particular
+// multicast group, so PIDs and Processes are only a best-effort attribution
+// based on UDP sockets bound to the multicast group address. Sockets that
+// joined a group while bound to the unspecified address cannot be attributed.
+type MulticastGroup struct {
+	Address   net.IP           // multicast group address
+	Users     int              // number of group memberships (sockets as well as kernel-internal users)
+	PIDs      []model.PIDType  // processes with sockets bound to the group address
+	Processes []*model.Process // processes with sockets bound to the group address
+}
+
+// MulticastRouting describes the multicast routing state of a network
+// namespace for either IPv4 or IPv6: the virtual interfaces (VIFs) in use by a
+// multicast routing daemon and the multicast forwarding cache.
+type MulticastRouting struct {
+	VIFs   []MulticastVIF   // virtual multicast interfaces
+	Routes []MulticastRoute // multicast forwarding cache entries
+}
+
+// MulticastVIF is a multicast routing virtual interface.
+type MulticastVIF struct {
+	Index    int       // VIF index (not to be confused with the network interface index)
+	Name     string    // name of the network interface, or "none" for unbound VIFs.
+	Nif      Interface // network interface of this VIF, if any.
+	BytesIn  uint64
+	PktsIn   uint64
+	BytesOut uint64
+	PktsOut  uint64
+	Flags    uint32 // VIFF_* flags
+	Local    net.IP // local tunnel address (IPv4 only)
+	Remote   net.IP // remote tunnel address (IPv4 only)
+}
+
+// MulticastRoute is a multicast forwarding cache entry, also known as an
+// "(S,G)" route.
+type MulticastRoute struct {
+	Group    net.IP      // multicast group address
+	Origin   net.IP      // source address
+	InputVIF int         // index of the input VIF, or -1 when unresolved.
+	Packets  uint64      // forwarded packets
+	Bytes    uint64      // forwarded bytes
+	WrongIf  uint64      // packets received on the wrong VIF
+	Outputs  map[int]int // output VIF indices with their TTL thresholds
+}
+
+// Unresolved returns true if this multicast route is still pending resolution
+// by the multicast routing daemon.
+func (r MulticastRoute) Unresolved() bool { return r.InputVIF < 0 }
+
+// parseIGMP parses the contents of /proc/net/igmp, returning the joined IPv4
+// multicast groups indexed by network interface index. The format is
+// interface header lines, each followed by the joined groups lines:
+//
+//	Idx	Device    : Count Querier	Group    Users Timer	Reporter
+//	1	lo        :     1      V3
+//					010000E0     1 0:00000000		0
+//
+// Group addresses are hex dumps of the in-memory big-endian addresses, read
+// as an uint32 in host byte order. Sigh.
+func parseIGMP(r io.Reader) map[int][]MulticastGroup {
+	groups := map[int][]MulticastGroup{}
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() { // skip header
+		return groups
+	}
+	index := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			// Device line; please note that the device name might directly
+			// run into the colon in case of long names, but luckily colons
+			// aren't allowed in network interface names.
+			index = 0
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			idx, err := strconv.ParseUint(fields[0], 10, 31)
+			if err != nil {
+				continue
+			}
+			index = int(idx)
+			continue
+		}
+		if index == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		b, err := hex.DecodeString(fields[0])
+		if err != nil || len(b) != net.IPv4len {
+			continue
+		}
+		if isLE {
+			reverseUint32(b, 0)
+		}
+		users, _ := strconv.Atoi(fields[1])
+		groups[index] = append(groups[index], MulticastGroup{
+			Address: net.IP(b),
+			Users:   users,
+		})
+	}
+	return groups
+}
+
+// parseIGMP6 parses the contents of /proc/net/igmp6, returning the joined IPv6
+// multicast groups indexed by network interface index. Each line describes a
+// single group membership:
+//
+//	1    lo              ff020000000000000000000000000001     1 0000000C 0
+//
+// In contrast to IPv4, the group address is in network byte order.
+func parseIGMP6(r io.Reader) map[int][]MulticastGroup {
+	groups := map[int][]MulticastGroup{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		index, err := strconv.ParseUint(fields[0], 10, 31)
+		if err != nil {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != net.IPv6len {
+			continue
+		}
+		users, _ := strconv.Atoi(fields[3])
+		groups[int(index)] = append(groups[int(index)], MulticastGroup{
+			Address: net.IP(b),
+			Users:   users,
+		})
+	}
+	return groups
+}
+
+// parseDevMcast parses the contents of /proc/net/dev_mcast, returning the
+// link-layer multicast addresses indexed by network interface index.
+//
+//	2    eth0            1     0     01005e000001
+func parseDevMcast(r io.Reader) map[int][]net.HardwareAddr {
+	l2addrs := map[int][]net.HardwareAddr{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		index, err := strconv.ParseUint(fields[0], 10, 31)
+		if err != nil {
+			continue
+		}
+		b, err := hex.DecodeString(fields[4])
+		if err != nil || len(b) == 0 {
+			continue
+		}
+		l2addrs[int(index)] = append(l2addrs[int(index)], net.HardwareAddr(b))
+	}
+	return l2addrs
+}
+
+// parseMrouteVIFs parses the contents of either /proc/net/ip_mr_vif or
+// /proc/net/ip6_mr_vif. Only the IPv4 variant has the local and remote
+// (tunnel) address columns.
+//
+//	Interface      BytesIn  PktsIn  BytesOut PktsOut Flags Local    Remote
+//	 0 eth0         1500      10         0       0 00000 0100000A 00000000
+func parseMrouteVIFs(r io.Reader) []MulticastVIF {
+	vifs := []MulticastVIF{}
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() { // skip header
+		return vifs
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[6], 16, 32)
+		vif := MulticastVIF{
+			Index:    index,
+			Name:     fields[1],
+			BytesIn:  parseUint64(fields[2]),
+			PktsIn:   parseUint64(fields[3]),
+			BytesOut: parseUint64(fields[4]),
+			PktsOut:  parseUint64(fields[5]),
+			Flags:    uint32(flags),
+		}
+		if len(fields) >= 9 {
+			vif.Local = parseHexIPv4(fields[7])
+			vif.Remote = parseHexIPv4(fields[8])
+		}
+		vifs = append(vifs, vif)
+	}
+	return vifs
+}
+
+// parseMrouteCache parses the contents of either /proc/net/ip_mr_cache or
+// /proc/net/ip6_mr_cache. The IPv4 variant dumps the group and origin
+// addresses in the same hex format as for IGMP, while the IPv6 variant uses
+// the canonical textual IPv6 address format. Output VIFs are listed as
+// "vif:ttl" pairs.
+//
+//	Group    Origin   Iif     Pkts    Bytes    Wrong Oifs
+//	010000EF 0100000A 0          5      500        0  1:1    2:1
+func parseMrouteCache(r io.Reader) []MulticastRoute {
+	routes := []MulticastRoute{}
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() { // skip header
+		return routes
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		var group, origin net.IP
+		if len(fields[0]) == 8 {
+			group = parseHexIPv4(fields[0])
+			origin = parseHexIPv4(fields[1])
+		} else {
+			group = net.ParseIP(fields[0])
+			origin = net.ParseIP(fields[1])
+		}
+		if group == nil || origin == nil {
+			continue
+		}
+		iif, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		route := MulticastRoute{
+			Group:    group,
+			Origin:   origin,
+			InputVIF: iif,
+			Packets:  parseUint64(fields[3]),
+			Bytes:    parseUint64(fields[4]),
+			WrongIf:  parseUint64(fields[5]),
+			Outputs:  map[int]int{},
+		}
+		for _, oif := range fields[6:] {
+			vif, ttl, ok := strings.Cut(oif, ":")
+			if !ok {
+				continue
+			}
+			vifidx, err := strconv.Atoi(vif)
+			if err != nil {
+				continue
+			}
+			route.Outputs[vifidx], _ = strconv.Atoi(ttl)
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// parseHexIPv4 returns the IPv4 address from its procfs hex dump in host byte
+// order, or nil.
+func parseHexIPv4(s string) net.IP {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != net.IPv4len {
+		return nil
+	}
+	if isLE {
+		reverseUint32(b, 0)
+	}
+	return net.IP(b)
+}
+
+// parseUint64 returns the decimal number in s, or zero.
+func parseUint64(s string) uint64 {
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
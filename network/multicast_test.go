@@ -0,0 +1,218 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// hexIPv4 returns the IPv4 address in the procfs hex dump format in host byte
+// order.
+func hexIPv4(ip string) string {
+	b := []byte(net.ParseIP(ip).To4())
+	if isLE {
+		reverseUint32(b, 0)
+	}
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+var _ = Describe("multicast", func() {
+
+	It("parses IGMP group memberships", func() {
+		groups := parseIGMP(strings.NewReader(
+			"Idx\tDevice    : Count Querier\tGroup    Users Timer\tReporter\n" +
+				"1\tlo        :     1      V3\n" +
+				"\t\t\t\t" + hexIPv4("224.0.0.1") + "     1 0:00000000\t\t0\n" +
+				"2\tverylongname0:     2      V2\n" +
+				"\t\t\t\t" + hexIPv4("239.1.2.3") + "     3 0:00000000\t\t1\n" +
+				"\t\t\t\tZZZZZZZZ     1 0:00000000\t\t0\n" +
+				"\t\t\t\t" + hexIPv4("224.0.0.1") + "     1 0:00000000\t\t0\n"))
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[1]).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{
+				"Address": Equal(net.ParseIP("224.0.0.1").To4()),
+				"Users":   Equal(1),
+			})))
+		Expect(groups[2]).To(ConsistOf(
+			HaveField("Address", Equal(net.ParseIP("239.1.2.3").To4())),
+			HaveField("Address", Equal(net.ParseIP("224.0.0.1").To4())),
+		))
+		Expect(groups[2][0].Users).To(Equal(3))
+	})
+
+	It("parses IGMP6 group memberships", func() {
+		groups := parseIGMP6(strings.NewReader(
+			"1    lo              ff020000000000000000000000000001     1 0000000C 0\n" +
+				"2    eth0            ff0200000000000000000001ff001234     2 00000004 0\n" +
+				"2    eth0            zz     2 00000004 0\n"))
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[1]).To(ConsistOf(HaveField("Address", Equal(net.ParseIP("ff02::1")))))
+		Expect(groups[2]).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{
+				"Address": Equal(net.ParseIP("ff02::1:ff00:1234")),
+				"Users":   Equal(2),
+			})))
+	})
+
+	It("parses link-layer multicast addresses", func() {
+		l2addrs := parseDevMcast(strings.NewReader(
+			"2    eth0            1     0     01005e000001\n" +
+				"2    eth0            1     0     333300000001\n" +
+				"3    eth1            1     0\n"))
+		Expect(l2addrs).To(HaveLen(1))
+		Expect(l2addrs[2]).To(ConsistOf(
+			net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x01},
+			net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x01},
+		))
+	})
+
+	It("parses multicast routing VIFs", func() {
+		vifs := parseMrouteVIFs(strings.NewReader(
+			"Interface      BytesIn  PktsIn  BytesOut PktsOut Flags Local    Remote\n" +
+				" 0 eth0             1500      10         0       0 00000 " + hexIPv4("10.0.0.1") + " 00000000\n" +
+				" 1 pimreg              0       0       100       1 00004 00000000 00000000\n"))
+		Expect(vifs).To(HaveLen(2))
+		Expect(vifs[0]).To(MatchFields(IgnoreExtras, Fields{
+			"Index":   Equal(0),
+			"Name":    Equal("eth0"),
+			"BytesIn": Equal(uint64(1500)),
+			"PktsIn":  Equal(uint64(10)),
+			"Local":   Equal(net.ParseIP("10.0.0.1").To4()),
+			"Remote":  Equal(net.IPv4zero.To4()),
+		}))
+		Expect(vifs[1].Flags).To(Equal(uint32(4)))
+
+		vifs = parseMrouteVIFs(strings.NewReader(
+			"Interface      BytesIn  PktsIn  BytesOut PktsOut Flags\n" +
+				" 0 eth0                0       0         0       0 00000\n"))
+		Expect(vifs).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Name":  Equal("eth0"),
+			"Local": BeNil(),
+		})))
+	})
+
+	It("parses multicast routing caches", func() {
+		routes := parseMrouteCache(strings.NewReader(
+			"Group    Origin   Iif     Pkts    Bytes    Wrong Oifs\n" +
+				hexIPv4("239.1.2.3") + " " + hexIPv4("10.0.0.42") + " 0          5      500        0  1:1    2:16\n" +
+				hexIPv4("239.1.2.4") + " " + hexIPv4("10.0.0.42") + " -1         0        0        0\n"))
+		Expect(routes).To(HaveLen(2))
+		Expect(routes[0]).To(MatchFields(IgnoreExtras, Fields{
+			"Group":   Equal(net.ParseIP("239.1.2.3").To4()),
+			"Origin":  Equal(net.ParseIP("10.0.0.42").To4()),
+			"Packets": Equal(uint64(5)),
+			"Bytes":   Equal(uint64(500)),
+			"Outputs": Equal(map[int]int{1: 1, 2: 16}),
+		}))
+		Expect(routes[0].Unresolved()).To(BeFalse())
+		Expect(routes[1].Unresolved()).To(BeTrue())
+
+		routes = parseMrouteCache(strings.NewReader(
+			"Group                            Origin                           Iif      Pkts  Bytes     Wrong  Oifs\n" +
+				"ff3e:0000:0000:0000:0000:0000:0000:1234 2001:0db8:0000:0000:0000:0000:0000:0001 1        1        100        0  0:1\n"))
+		Expect(routes).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Group":    Equal(net.ParseIP("ff3e::1234")),
+			"Origin":   Equal(net.ParseIP("2001:db8::1")),
+			"InputVIF": Equal(1),
+		})))
+	})
+
+	It("attributes multicast groups to processes", func() {
+		proc := &model.Process{PID: 42}
+		groups := []MulticastGroup{
+			{Address: net.ParseIP("239.1.2.3").To4()},
+			{Address: net.ParseIP("239.1.2.4").To4()},
+		}
+		(&NetworkNamespace{}).attributeMulticastGroups(groups, []ProcessSocket{
+			{Protocol: syscall.IPPROTO_TCP, LocalIP: net.ParseIP("239.1.2.4"), PIDs: []model.PIDType{666}},
+			{Protocol: syscall.IPPROTO_UDP, LocalIP: net.ParseIP("239.1.2.3"), PIDs: []model.PIDType{42}, Processes: []*model.Process{proc}},
+			{Protocol: syscall.IPPROTO_UDP, LocalIP: net.ParseIP("239.1.2.3"), PIDs: []model.PIDType{42}, Processes: []*model.Process{proc}},
+		})
+		Expect(groups[0].PIDs).To(ConsistOf(model.PIDType(42)))
+		Expect(groups[0].Processes).To(ConsistOf(proc))
+		Expect(groups[1].PIDs).To(BeEmpty())
+	})
+
+	It("returns no multicast routing when not routing multicast", func() {
+		Expect((&NetworkNamespace{}).newMulticastRouting(nil, nil)).To(BeNil())
+	})
+
+	It("parses bridge multicast databases", func() {
+		entry := func(port uint32, state uint8, vid uint16, addr []byte, proto uint16) []byte {
+			b := make([]byte, sizeofBrMdbEntry)
+			nl.NativeEndian().PutUint32(b[0:], port)
+			b[4] = state
+			nl.NativeEndian().PutUint16(b[6:], vid)
+			copy(b[mdbEntryAddrStart:], addr)
+			b[mdbEntryAddrStart+16] = byte(proto >> 8)
+			b[mdbEntryAddrStart+17] = byte(proto)
+			return b
+		}
+		mdb := nl.NewRtAttr(mdbaMdb|int(nl.NLA_F_NESTED), nil)
+		e := mdb.AddRtAttr(mdbaMdbEntry|int(nl.NLA_F_NESTED), nil)
+		e.AddRtAttr(mdbaMdbEntryInfo, entry(3, mdbPermanent, 0, net.ParseIP("239.1.2.3").To4(), ethPIP))
+		e.AddRtAttr(mdbaMdbEntryInfo, entry(4, 0, 42, net.ParseIP("ff3e::1234"), ethPIPv6))
+		e.AddRtAttr(mdbaMdbEntryInfo, entry(5, 0, 0, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}, 0))
+		e.AddRtAttr(mdbaMdbEntryInfo, []byte{1, 2, 3})
+		msg := append((&brPortMsg{Family: unix.AF_BRIDGE, Ifindex: 2}).Serialize(), mdb.Serialize()...)
+
+		index, entries := parseMDB(msg)
+		Expect(index).To(Equal(2))
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0].portIndex).To(Equal(3))
+		Expect(entries[0].BridgeMulticastEntry).To(MatchFields(IgnoreExtras, Fields{
+			"Group":     Equal(net.ParseIP("239.1.2.3").To4()),
+			"Permanent": BeTrue(),
+		}))
+		Expect(entries[1].BridgeMulticastEntry).To(MatchFields(IgnoreExtras, Fields{
+			"Group":     Equal(net.ParseIP("ff3e::1234")),
+			"VID":       Equal(uint16(42)),
+			"Permanent": BeFalse(),
+		}))
+		Expect(entries[2].L2Group).To(Equal(net.HardwareAddr{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}))
+		Expect(entries[2].Group).To(BeNil())
+
+		index, entries = parseMDB([]byte{1, 2})
+		Expect(index).To(BeZero())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("parses bridge querier details", func() {
+		linkinfo := nl.NewRtAttr(unix.IFLA_LINKINFO|int(nl.NLA_F_NESTED), nil)
+		linkinfo.AddRtAttr(unix.IFLA_INFO_KIND, nl.ZeroTerminated("bridge"))
+		data := linkinfo.AddRtAttr(unix.IFLA_INFO_DATA|int(nl.NLA_F_NESTED), nil)
+		data.AddRtAttr(unix.IFLA_BR_MCAST_QUERIER, []byte{1})
+		data.AddRtAttr(unix.IFLA_BR_MCAST_IGMP_VERSION, []byte{3})
+		data.AddRtAttr(unix.IFLA_BR_MCAST_MLD_VERSION, []byte{2})
+		ifimsg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+		ifimsg.Index = 7
+		msg := append(ifimsg.Serialize(), linkinfo.Serialize()...)
+
+		index, querier, igmpv, mldv, ok := parseBridgeMulticastDetails(msg)
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal(7))
+		Expect(querier).To(BeTrue())
+		Expect(igmpv).To(Equal(uint8(3)))
+		Expect(mldv).To(Equal(uint8(2)))
+
+		linkinfo = nl.NewRtAttr(unix.IFLA_LINKINFO|int(nl.NLA_F_NESTED), nil)
+		linkinfo.AddRtAttr(unix.IFLA_INFO_KIND, nl.ZeroTerminated("veth"))
+		msg = append(ifimsg.Serialize(), linkinfo.Serialize()...)
+		_, _, _, _, ok = parseBridgeMulticastDetails(msg)
+		Expect(ok).To(BeFalse())
+	})
+
+})
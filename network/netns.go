@@ -7,18 +7,20 @@ package network
 import (
 	"fmt"
 	"net"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"syscall"
 
+	"github.com/siemens/ghostwire/v2/diagnostics"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
 	"github.com/thediveo/lxkns/ops"
 	"github.com/thediveo/lxkns/ops/mountineer"
 	"github.com/thediveo/lxkns/species"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/exp/slices"
 	"golang.org/x/sys/unix"
 )
 
@@ -44,18 +46,32 @@ const NSID_NONE = ^NSID(0)
 // NetworkNamespace. Sets of containers (=initial process of container) as well
 // as stand-alone (=non-container) processes are referred to as "tenants".
 type NetworkNamespace struct {
-	model.Namespace                       // discovered namespace details courtesy of lxkns.
-	Nifs             map[int]Interface    // map of network interfaces by index number.
-	NamedNifs        map[string]Interface // map of network interfaces indexed by name.
-	Tenants          Tenants              // tenants of this network namespace (=processes/containers with additional information).
-	Routesv4         []Route              // IPv4 routes
-	Routesv6         []Route              // IPv6 routes
-	Portsv4          []ProcessSocket      // sockets/open ports for IPv4 (including IPv6 sockets!)
-	Portsv6          []ProcessSocket      // sockets/open ports for IPv6
-	ForwardedPortsv4 []ForwardedPort      // IPv4 ports forwarded into other network namespaces
-	ForwardedPortsv6 []ForwardedPort      // IPv6 ports forwarded into other network namespaces
+	model.Namespace                        // discovered namespace details courtesy of lxkns.
+	Nifs              map[int]Interface    // map of network interfaces by index number.
+	NamedNifs         map[string]Interface // map of network interfaces indexed by name.
+	Tenants           Tenants              // tenants of this network namespace (=processes/containers with additional information).
+	Routesv4          []Route              // IPv4 routes
+	Routesv6          []Route              // IPv6 routes
+	Portsv4           []ProcessSocket      // sockets/open ports for IPv4 (including IPv6 sockets!)
+	Portsv6           []ProcessSocket      // sockets/open ports for IPv6
+	MPTCPv4           []MPTCPConnection    // MPTCP sockets for IPv4, with their subflows
+	MPTCPv6           []MPTCPConnection    // MPTCP sockets for IPv6, with their subflows
+	MPTCPEndpoints    []MPTCPEndpoint      // MPTCP path manager endpoints
+	MPTCPLimits       *MPTCPLimits         // MPTCP path manager limits, if available
+	ForwardedPortsv4  []ForwardedPort      // IPv4 ports forwarded into other network namespaces
+	ForwardedPortsv6  []ForwardedPort      // IPv6 ports forwarded into other network namespaces
+	McastRoutingv4    *MulticastRouting    // IPv4 multicast routing state, if routing multicast.
+	McastRoutingv6    *MulticastRouting    // IPv6 multicast routing state, if routing multicast.
+	Sysctls           Sysctls              // network-related sysctls of this network namespace.
+	ProtocolCounters  *ProtocolCounters    // protocol-level counters sampled during discovery.
+	PacketSockets     []PacketSocket       // AF_PACKET sockets sniffing or injecting link-layer traffic.
+	UnixSockets       []*UnixSocket        // Unix domain sockets created in this network namespace.
+	BridgingProcesses []*BridgingProcess   // processes holding sockets of other network namespaces.
+	SocketOwners      SocketOwners         // details of socket-owning processes, shared by all network namespaces.
 
 	peerNetns map[NSID]*NetworkNamespace // NSID-to-network namespace map; required for resolving netlink relations.
+	diags     *diagnostics.Diagnostics   // collects discovery problems; might be nil.
+	source    Source                     // supplies the system information; nil for the live source.
 }
 
 // NetworkNamespaceList contains NetworkNamespace elements, and optionally can
@@ -67,52 +83,76 @@ type NetworkNamespaceList []*NetworkNamespace
 // network interfaces and routes discovered. However, the relations between
 // network interfaces remain unresolved at this time.
 func NewNetworkNamespace(netns model.Namespace, tenantProcs []*model.Process) *NetworkNamespace {
+	dopts := newDiscoveryOptions()
+	return newNetworkNamespace(netns, tenantProcs, &dopts)
+}
+
+// newNetworkNamespace returns a new NetworkNamespace with its network
+// interfaces and routes discovered, according to the specified discovery
+// options.
+func newNetworkNamespace(netns model.Namespace, tenantProcs []*model.Process, dopts *discoveryOptions) *NetworkNamespace {
 	nns := &NetworkNamespace{
 		Namespace: netns,
 		Nifs:      map[int]Interface{},
 		peerNetns: map[NSID]*NetworkNamespace{},
 		NamedNifs: map[string]Interface{},
-	}
-	// Get an RTNETLINK socket wired up to this particular network namespace.
-	nlh, err := nns.OpenNetlink()
-	if err != nil {
-		log.Warnf("cannot discover inside net:[%d], reason: %s",
-			netns.ID().Ino, err.Error())
+		diags:     dopts.diags,
+		source:    dopts.source,
+	}
+	// Switch into this particular network namespace only once in order to
+	// discover the network interfaces in this network namespace, together with
+	// the addresses assigned to these network interfaces, the network-related
+	// sysctls, protocol counters, and routes.
+	if err := nns.source.Visit(netns, func(nsa NetnsAccess) error {
+		nns.discoverNetworkInterfaces(nsa)
+		// Index the discovered network interfaces by their names
+		for _, nif := range nns.Nifs {
+			nns.NamedNifs[nif.Nif().Name] = nif
+		}
+		// Network-related sysctls, both namespace-global as well as per
+		// network interface.
+		nns.discoverSysctls(nsa)
+		// Sample the protocol-level counters of this network stack.
+		nns.ProtocolCounters = readProtocolCounters(nsa, "/proc/thread-self/net")
+		// Routes
+		nns.Routesv4 = nns.discoverRoutes(nsa, unix.AF_INET)
+		nns.Routesv6 = nns.discoverRoutes(nsa, unix.AF_INET6)
+		return nil
+	}); err != nil {
+		nns.diags.Errorf(diagnostics.NetnsUnavailable, nns.scope(),
+			"cannot discover inside net:[%d], reason: %s", netns.ID().Ino, err.Error())
 		return nil
 	}
-	defer nlh.Close()
-	// Discover the network interfaces in this network namespace, together with
-	// the addresses assigned to these network interfaces.
-	nns.discoverNetworkInterfaces(nlh)
-	// Index the discovered network interfaces by their names
-	for _, nif := range nns.Nifs {
-		nns.NamedNifs[nif.Nif().Name] = nif
-	}
-	// Routes
-	nns.Routesv4 = nns.discoverRoutes(nlh, unix.AF_INET)
-	nns.Routesv6 = nns.discoverRoutes(nlh, unix.AF_INET6)
 	// Gather DNS-related information, et cetera, for the tenant processes (that
 	// is, network namespace leader processes and container processes) turning
 	// them into "tenants".
 	tenants := make(Tenants, 0, len(tenantProcs))
 	for _, proc := range tenantProcs {
-		tenants = append(tenants, NewTenant(proc))
+		tenants = append(tenants, newTenant(nns.source, proc, !dopts.skipDNS))
 	}
+	tenants.Sort()
 	nns.Tenants = tenants
 
 	return nns
 }
 
+// scope returns the diagnostics scope of this network namespace.
+func (n *NetworkNamespace) scope() diagnostics.Scope {
+	return diagnostics.Scope{Netns: n.ID().Ino}
+}
+
 // DisplayName returns a "simplified" name for "simple" display use cases, where
 // it is desirable to identify network namespaces by the names of their tenants
 // (containers and stand-alone processes). In case of multiple tenants, the
 // display name will be the lexicographically first tenant name, or the name of
 // the process with PID 1, if present.
 func (n *NetworkNamespace) DisplayName() string {
-	tenants := n.Tenants
-	if len(tenants) == 0 {
+	if len(n.Tenants) == 0 {
 		return n.Ref().String()
 	}
+	// Never sort the tenants in place, as the same network namespace might be
+	// concurrently accessed by other goroutines.
+	tenants := slices.Clone(n.Tenants)
 	tenants.Sort()
 	var name string
 	proc := tenants[0].Process
@@ -161,25 +201,32 @@ func (n *NetworkNamespace) related(nsid NSID) *NetworkNamespace {
 // interfaces carrying their traffic. And it notifies about IPv6 sockets
 // handling IPv4 traffic.
 func (n *NetworkNamespace) discoverTransportPorts(sm socketToProcessMap, allprocs model.ProcessTable) {
-	// Without any attached processes, we don't have any /proc/[...]/net/xxx to
-	// read the socket information for this network namespace from.
-	//
-	// FIXME: use mountineer, as other processes might have open fds, yet not
-	// attached to this network namespace.
-	if n.Ealdorman() == nil {
-		return
-	}
-	// Now discover and process TCP und UDP sockets, for both IPv4 and IPv6.
-	// Note our special handling of IPv6 sockets covering IPv4 traffic...
+	// Preferably, we ask the sock_diag netlink API from inside this network
+	// namespace, as this gives us more details and additionally works for
+	// network namespaces without any attached processes (that is, bind-mounted
+	// or fd-referenced network namespaces). Only if the kernel cannot tell us
+	// about certain kinds of sockets we fall back onto the /proc/[...]/net/xxx
+	// information, but this requires a process attached to this network
+	// namespace.
+	diagsox := n.discoverDiagSockets(sm)
+	// Now discover and process TCP, UDP, UDP-Lite, SCTP, and raw IP sockets,
+	// for both IPv4 and IPv6. Note our special handling of IPv6 sockets
+	// covering IPv4 traffic...
 	addrToNifs := n.newAddrToNifMap()
-	for _, proto := range []int{syscall.IPPROTO_TCP, syscall.IPPROTO_UDP} {
+	for _, proto := range diagProtocols {
 		// v4Ports indicates the IPv4 addresses we've seen for this protocol, so
 		// that we don't create any IPv4-"mapped" pseudo entries when there are
 		// both an IPv4 as well as an IPv6 socket covering AF_INET and AF_INET6,
 		// and the IPv6 socket would also include the IPv4 mapped address range.
 		v4Ports := map[comparableAddressPort]struct{}{}
 		for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
-			ports := discoverSockets("/proc", n.Ealdorman().PID, af, proto, sm)
+			ports, ok := diagsox[sockKind{af: af, proto: proto}]
+			if !ok {
+				if n.Ealdorman() == nil {
+					continue
+				}
+				ports = discoverSockets(sourceOf(n), "/proc", n.Ealdorman().PID, af, proto, sm)
+			}
 			for idx, port := range ports {
 				procs := allprocs.ProcessesByPIDs(port.PIDs...)
 				ports[idx].Processes = procs
@@ -199,7 +246,9 @@ func (n *NetworkNamespace) discoverTransportPorts(sm socketToProcessMap, allproc
 				// anyaddr IPv6 socket on a port for which no matching separate
 				// anyaddr IPv4 socket exists, we add one to the list by
 				// ourselves.
-				if af == unix.AF_INET {
+				if proto == syscall.IPPROTO_RAW {
+					// Raw IPv6 sockets never see any IPv4 traffic.
+				} else if af == unix.AF_INET {
 					// On the first run, that always is the IPv4 run, we need to
 					// remember what is already covered ... and thus visible to
 					// IPv4-centric users (including "us").
@@ -225,20 +274,16 @@ func (n *NetworkNamespace) discoverTransportPorts(sm socketToProcessMap, allproc
 					}
 					if _, ok := v4Ports[comparableAddressPort{addr: string(ipv4addr), port: port.LocalPort}]; !ok {
 						nifs := addrToNifs[string(ipv4addr)]
-						n.Portsv4 = append(n.Portsv4, ProcessSocket{
-							Family:          unix.AF_INET,
-							Protocol:        Protocol(proto),
-							LocalIP:         ipv4addr,
-							LocalPort:       port.LocalPort,
-							RemoteIP:        remipv4addr,
-							RemotePort:      port.RemotePort,
-							State:           port.State,
-							SimplifiedState: port.SimplifiedState,
-							PIDs:            port.PIDs,
-							IPv4Mapped:      true,
-							Nifs:            nifs,
-							Processes:       procs,
-						})
+						// The alias shares all socket details, such as queue
+						// lengths and TCP info, with the IPv6 socket.
+						alias := port
+						alias.Family = unix.AF_INET
+						alias.LocalIP = ipv4addr
+						alias.RemoteIP = remipv4addr
+						alias.IPv4Mapped = true
+						alias.Nifs = nifs
+						alias.Processes = procs
+						n.Portsv4 = append(n.Portsv4, alias)
 					}
 				}
 				// Resolve the related/"concerned" network interface(s)...
@@ -249,6 +294,18 @@ func (n *NetworkNamespace) discoverTransportPorts(sm socketToProcessMap, allproc
 				if nifs, ok := addrToNifs[string(localip)]; ok {
 					ports[idx].Nifs = nifs // !!! "port" is a copy, not a pointer, hmpf.
 				}
+				// Multi-homed SCTP sockets might additionally relate to
+				// further network interfaces.
+				for _, addr := range port.LocalAddrs {
+					if ipv4 := addr.To4(); ipv4 != nil {
+						addr = ipv4
+					}
+					for _, nif := range addrToNifs[string(addr)] {
+						if !slices.Contains(ports[idx].Nifs, nif) {
+							ports[idx].Nifs = append(ports[idx].Nifs, nif)
+						}
+					}
+				}
 			}
 			if af == unix.AF_INET {
 				n.Portsv4 = append(n.Portsv4, ports...)
@@ -267,7 +324,11 @@ func (n *NetworkNamespace) newAddrToNifMap() map[string][]Interface {
 	// at least these cases of conversion from []byte to string are cheap and
 	// optimized by the Go compiler.
 	addrToNifs := map[string][]Interface{}
+	// Sort the network interfaces so that sockets bound to the unspecified
+	// addresses always list their network interfaces in the same order,
+	// instead of map iteration order.
 	allNifs := n.NifList()
+	allNifs.Sort()
 	addrToNifs[string(net.IPv4zero.To4())] = allNifs
 	addrToNifs[string(net.IPv6unspecified)] = allNifs
 
@@ -294,61 +355,102 @@ type comparableAddressPort struct {
 	port uint16
 }
 
-// discoverNSID discovers the NSIDs of the peer network namespaces related to
-// our network namespace. As this part of the discovery is run only after we
-// built the full netns map, we need to create our own netlink handle here, so
-// we don't expect one passed onto us.
-func (n *NetworkNamespace) discoverNSIDs(allnetns NetworkNamespaces) {
+// netnsFd is an open file descriptor referencing a particular network
+// namespace.
+type netnsFd struct {
+	netns *NetworkNamespace
+	fd    int
+}
+
+// openNetnsFds opens file descriptors referencing the specified network
+// namespaces, so that the NSID discovery doesn't need to resolve and open the
+// same namespace references over and over again for each pair of network
+// namespaces. Network namespaces that cannot be referenced are skipped. The
+// caller must call the returned closer function when done with the fds.
+func openNetnsFds(src Source, netspaces NetworkNamespaces, diags *diagnostics.Diagnostics) ([]netnsFd, func()) {
+	fds := make([]netnsFd, 0, len(netspaces))
+	closers := make([]func(), 0, len(netspaces))
+	for _, netns := range netspaces {
+		if len(netns.Ref()) == 0 {
+			continue
+		}
+		fd, closer, err := src.NetnsFd(netns.Namespace)
+		if err != nil {
+			diags.Warnf(diagnostics.NSIDsUnavailable, netns.scope(),
+				"cannot access netns %s, reason: %s", netns.Ref(), err.Error())
+			continue
+		}
+		fds = append(fds, netnsFd{netns: netns, fd: fd})
+		closers = append(closers, closer)
+	}
+	return fds, func() {
+		for _, closer := range closers {
+			closer()
+		}
+	}
+}
+
+// NamespaceFd returns an open file descriptor referencing the specified network
+// namespace, together with a function to close it again. The network namespace
+// reference may also be a bind-mount inside another mount namespace; the file
+// descriptor stays valid even after having released that mount namespace.
+func NamespaceFd(netns model.Namespace) (int, func(), error) {
+	ref := netns.Ref()
+	if len(ref) == 0 {
+		return -1, nil, fmt.Errorf("net:[%d] without any reference", netns.ID().Ino)
+	}
+	netnspath := ref[len(ref)-1]
+	if len(ref) > 1 {
+		mntneer, err := mountineer.New(ref[:len(ref)-1], nil)
+		if err != nil {
+			return -1, nil, err
+		}
+		defer mntneer.Close()
+		netnspath, err = mntneer.Resolve(netnspath)
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+	return ops.NewTypedNamespacePath(netnspath, species.CLONE_NEWNET).NsFd()
+}
+
+// discoverNSIDs discovers the NSIDs of the peer network namespaces related to
+// our network namespace, given open fds referencing all network namespaces. As
+// this part of the discovery is run only after we built the full netns map, we
+// need to create our own netlink handle here, so we don't expect one passed
+// onto us.
+func (n *NetworkNamespace) discoverNSIDs(peers []netnsFd) {
 	// The rtnetlink API really is stupid, because it's of the "oracle" sort
 	// (not the one with a capital "O" but with a lower case "o"). It only
 	// answers with a NSID or "no(pe)" when we ask it for a specific network
 	// namespace. It doesn't allow simply listing all the NSIDs, not even for a
 	// single network namespace. Ouch.
-	nlh, err := n.OpenNetlink()
-	if err != nil {
-		log.Errorf("cannot discover NSIDs: %s", err.Error())
-		return
-	}
-	defer nlh.Close()
-	for _, peerNetns := range allnetns {
-		if peerNetns == n.Namespace {
-			continue
-		}
-		ref := peerNetns.Ref()
-		if len(ref) == 0 {
-			continue
-		}
-		netnspath := ref[len(ref)-1]
-		var mntneer *mountineer.Mountineer
-		if len(ref) > 1 {
-			mntneer, err = mountineer.New(ref[:len(ref)-1], nil)
-			if err != nil {
+	err := sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
+		for _, peer := range peers {
+			if peer.netns == n {
 				continue
 			}
-			netnspath, err = mntneer.Resolve(netnspath)
+			nsid, err := nsa.NSID(peer.fd)
 			if err != nil {
+				n.diags.Warnf(diagnostics.NSIDsUnavailable, n.scope(),
+					"cannot determine NSID of peer netns %s in net:[%d], reason: %s",
+					peer.netns.Ref(), n.ID().Ino, err.Error())
 				continue
 			}
+			if NSID(nsid) == NSID_NONE {
+				continue
+			}
+			n.peerNetns[NSID(nsid)] = peer.netns
+			log.Debugfn(func() string {
+				return fmt.Sprintf("net:[%d] NSID %d ↦ net:[%d] %s",
+					n.ID().Ino, nsid, peer.netns.ID().Ino, peer.netns.DisplayName())
+			})
 		}
-		netnsfd, closer, err := ops.NewTypedNamespacePath(netnspath, species.CLONE_NEWNET).NsFd()
-		if mntneer != nil {
-			mntneer.Close()
-		}
-		if err != nil {
-			log.Errorf("cannot access peer netns %s", ref)
-			continue
-		}
-		nsid, err := nlh.GetNetNsIdByFd(netnsfd)
-		if err != nil {
-			log.Errorf("cannot determine NSID of peer netns %s, error: %s", ref, err.Error())
-			continue
-		}
-		closer()
-		if NSID(nsid) == NSID_NONE {
-			continue
-		}
-		n.peerNetns[NSID(nsid)] = peerNetns
-		log.Debugf("net:[%d] NSID %d ↦ net:[%d] %s", n.ID().Ino, nsid, peerNetns.ID().Ino, peerNetns.DisplayName())
+		return nil
+	})
+	if err != nil {
+		n.diags.Errorf(diagnostics.NSIDsUnavailable, n.scope(),
+			"cannot discover NSIDs in net:[%d], reason: %s", n.ID().Ino, err.Error())
 	}
 }
 
@@ -398,7 +500,13 @@ func (n *NetworkNamespace) OpenNetlink() (*netlink.Handle, error) {
 // OpenInNetworkNamespace calls the supplied opener function in the context of
 // this network namespace.
 func (n *NetworkNamespace) OpenInNetworkNamespace(opener func() error) error {
-	ref := n.Namespace.Ref()
+	return openInNetns(n.Namespace, opener)
+}
+
+// openInNetns calls the supplied opener function in the context of the
+// specified network namespace.
+func openInNetns(netns model.Namespace, opener func() error) error {
+	ref := netns.Ref()
 	if len(ref) == 0 {
 		return fmt.Errorf("invalid empty netns reference")
 	}
@@ -412,7 +520,7 @@ func (n *NetworkNamespace) OpenInNetworkNamespace(opener func() error) error {
 			return fmt.Errorf("invalid netns reference %s: %s", ref.String(), err.Error())
 		}
 		// we need to keep the mount namespace open only until we've got the
-		// netlink handle, that is, until the end of this method.
+		// netlink handle, that is, until the end of this function.
 		defer mntneer.Close()
 		netnspath, err = mntneer.Resolve(netnspath)
 		if err != nil {
@@ -453,46 +561,64 @@ func init() {
 }
 
 // discoverNetworkInterfaces discovers the network interfaces in this network
-// namespace.
-func (n *NetworkNamespace) discoverNetworkInterfaces(nlh *netlink.Handle) {
-	links, err := nlh.LinkList()
+// namespace, together with their addresses.
+func (n *NetworkNamespace) discoverNetworkInterfaces(nsa NetnsAccess) {
+	// This is what netlink.Handle.LinkList does, except that we need to go
+	// through the network namespace access in order to support recording and
+	// replaying the netlink dumps.
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP)
+	req.AddData(nl.NewIfInfomsg(unix.AF_UNSPEC))
+	req.AddData(nl.NewRtAttr(unix.IFLA_EXT_MASK, nl.Uint32Attr(nl.RTEXT_FILTER_VF)))
+	linkmsgs, err := nsa.Execute(req, unix.NETLINK_ROUTE, unix.RTM_NEWLINK)
 	if err != nil {
+		n.diags.Errorf(diagnostics.InterfacesUnavailable, n.scope(),
+			"cannot list network interfaces in net:[%d], reason: %s", n.ID().Ino, err.Error())
 		return
 	}
-	// We only open the ethtool API socket on demand when we find actual
-	// physical network interfaces. This way, we don't need to switch network
-	// namespaces and open the socket for containers without any physical
-	// (SR-IOV VF) network interface(s) present.
-	physNifsPresent := false
-	ethtoolFd := -1 // optional AF_INET+SOCK_DGRAM+IPPROTO_IP socket, opened on the fly
-	for _, link := range links {
-		nif := NewInterface(nlh, n, link)
+	// Dump all addresses only once for the whole network namespace, instead
+	// of individually for each network interface.
+	addrmsgs, addrs := n.discoverAddresses(nsa)
+	// When directly accessing the network namespace, network interfaces get
+	// initialized using a netlink handle, otherwise from the address dump.
+	var nlh *netlink.Handle
+	if handler, ok := nsa.(netlinkHandler); ok {
+		if nlh, err = handler.netlinkHandle(); err != nil {
+			n.diags.Errorf(diagnostics.InterfacesUnavailable, n.scope(),
+				"cannot list network interfaces in net:[%d], reason: %s", n.ID().Ino, err.Error())
+			return
+		}
+	}
+	for _, msg := range linkmsgs {
+		link, err := netlink.LinkDeserialize(nil, msg)
+		if err != nil {
+			n.diags.Errorf(diagnostics.InterfacesUnavailable, n.scope(),
+				"cannot list network interfaces in net:[%d], reason: %s", n.ID().Ino, err.Error())
+			return
+		}
+		nif := newInterface(nlh, addrs, n, link)
 		n.Nifs[nif.Nif().Index] = nif
 		// If this is isn't a physical network interface, then it won't have a
-		// bus address anyway.
+		// bus address anyway, so we don't need to bother the ethtool API.
 		if !nif.Nif().Physical {
 			continue
 		}
-		// It's a physical network interface and if we haven't done so already,
-		// we now open the ethtool API in this network namespace.
-		if !physNifsPresent {
-			var err error
-			if ethtoolFd, err = n.OpenEthtool(); err == nil { // TODO: improve error handling
-				physNifsPresent = true
-				defer unix.Close(ethtoolFd)
-			}
-		}
-		nif.Nif().discoverBusAddress(ethtoolFd)
+		nif.Nif().discoverBusAddress(nsa)
 	}
+	// Finally fill in those address details not available via the netlink
+	// package's address parsing.
+	n.resolveAddressDetails(addrmsgs)
 }
 
 // NewNetworkNamespaces takes a set of discovered network namespaces and creates
 // the Gostwire-specific NetworkNamespace objects wrapping them and supplying
-// network layer-related information not discovered by lxkns.
+// network layer-related information not discovered by lxkns. The network
+// namespaces are discovered concurrently, see also WithMaxWorkers. Further
+// discovery options allow restricting and slimming down the discovery.
 func NewNetworkNamespaces(
 	allnetns model.NamespaceMap,
 	allprocs model.ProcessTable,
 	containers model.Containers,
+	opts ...DiscoveryOption,
 ) NetworkNamespaces {
 	// In order to later figure out the tenants of a network namespace, we need
 	// not only to take network namespace leader processes into account but also
@@ -501,19 +627,75 @@ func NewNetworkNamespaces(
 	// leader processes, such as in container-in-container configurations.
 	tenantProcsByNetns := tenantsOfNetnsMap(allnetns, containers)
 	// Now work on the network namespaces and discover their topology and
-	// configuration.
-	netspaces := NetworkNamespaces{}
+	// configuration. As the network namespaces are independent of each other
+	// at this stage, we discover them concurrently.
+	dopts := newDiscoveryOptions(opts...)
+	nslist := make([]model.Namespace, 0, len(allnetns))
 	for netnsid, netns := range allnetns {
-		if netwns := NewNetworkNamespace(netns, tenantProcsByNetns[netns]); netwns != nil {
-			netspaces[netnsid] = netwns
+		if dopts.netns != nil {
+			if _, ok := dopts.netns[netnsid]; !ok {
+				continue
+			}
+		}
+		nslist = append(nslist, netns)
+	}
+	netwnslist := make([]*NetworkNamespace, len(nslist))
+	if skipped := parallelize(dopts.ctx, len(nslist), dopts.maxWorkers, func(idx int) {
+		netwnslist[idx] = newNetworkNamespace(nslist[idx], tenantProcsByNetns[nslist[idx]], &dopts)
+	}); skipped > 0 {
+		dopts.diags.Warnf(diagnostics.Cancelled, diagnostics.Scope{},
+			"network discovery cancelled, skipped %d network namespaces, reason: %s",
+			skipped, dopts.ctx.Err())
+	}
+	netspaces := NetworkNamespaces{}
+	for _, netwns := range netwnslist {
+		if netwns != nil {
+			netspaces[netwns.ID()] = netwns
 		}
 	}
-	soxProcsMap := discoverAllSockInodes("/proc")
-	for nsid, netns := range netspaces {
-		log.Debugf("discovering details of net:[%d]...", nsid.Ino)
-		netns.discoverNSIDs(netspaces)
-		netns.discoverTransportPorts(soxProcsMap, allprocs)
-		netns.discoverForwardedPorts()
+	netwnslist = make([]*NetworkNamespace, 0, len(netspaces))
+	for _, netwns := range netspaces {
+		netwnslist = append(netwnslist, netwns)
+	}
+	// With all network namespaces known, we can now discover their further
+	// details, again concurrently. Each worker only updates the network
+	// namespace it is working on; everything else is only read. When
+	// restricted to only some network namespaces, we only need to look at the
+	// sockets of the processes attached to them.
+	peers, closePeers := openNetnsFds(dopts.source, netspaces, dopts.diags)
+	// The process socket index caches the socket inodes across discoveries of
+	// the host, so other sources need their own index.
+	soxInodes := sockInodes
+	if dopts.source != live {
+		soxInodes = newSocketInodeIndex(dopts.source, "/proc")
+	}
+	var soxProcsMap socketToProcessMap
+	switch {
+	case dopts.skipSockets:
+	case dopts.netns != nil:
+		namespaces := make([]model.Namespace, 0, len(netspaces))
+		for _, netns := range netspaces {
+			namespaces = append(namespaces, netns.Namespace)
+		}
+		soxProcsMap = soxInodes.restrictedSocketToProcessMap(
+			processesOfNamespaces(allprocs, namespaces...))
+	default:
+		soxProcsMap = soxInodes.socketToProcessMap(allprocs)
+	}
+	if skipped := parallelize(dopts.ctx, len(netwnslist), dopts.maxWorkers, func(idx int) {
+		netns := netwnslist[idx]
+		log.Debugf("discovering details of net:[%d]...", netns.ID().Ino)
+		netns.discoverNSIDs(peers)
+		if !dopts.skipSockets {
+			netns.discoverTransportPorts(soxProcsMap, allprocs)
+			netns.discoverMPTCP(soxProcsMap, allprocs)
+			netns.discoverPacketSockets(soxProcsMap, allprocs)
+			netns.discoverUnixSockets(soxProcsMap, allprocs)
+		}
+		if !dopts.skipForwardedPorts {
+			netns.discoverForwardedPorts()
+		}
+		netns.discoverMulticast()
 		log.Debugfn(func() string {
 			nifNames := make([]string, 0, len(netns.Nifs))
 			for _, nif := range netns.Nifs {
@@ -525,7 +707,23 @@ func NewNetworkNamespaces(
 			}
 			return "found nifs: " + strings.Join(nifNames, ", ")
 		})
-	}
+	}); skipped > 0 {
+		dopts.diags.Warnf(diagnostics.Cancelled, diagnostics.Scope{},
+			"network discovery cancelled, skipped details of %d network namespaces, reason: %s",
+			skipped, dopts.ctx.Err())
+	}
+	closePeers()
+	// Now that we know all network namespaces, we can tell which sysctls
+	// deviate from the initial network namespace.
+	resolveSysctlDeviations(netspaces, allprocs)
+	// Connected Unix domain sockets might well have their peers in other
+	// network namespaces, such as when bind-mounting sockets into containers.
+	resolveUnixSocketPeers(netspaces)
+	resolveEngineAPISockets(netspaces, dopts.source, allprocs)
+	// Sockets might be owned by processes attached to other network
+	// namespaces.
+	resolveForeignSocketOwners(netspaces)
+	resolveSocketOwners(netspaces, dopts.source, "/proc")
 	// Resolve the network interfaces topology, except for SR-IOV PFs/VFs. In
 	// the case of SR-IOV we first only build a map of the discovered PFs and
 	// VFs. This map indexes bus addresses to their corresponding interface
@@ -538,9 +736,9 @@ func NewNetworkNamespaces(
 	// Finally resolve the SR-IOV PF/VF topology, based on the bus addresses
 	// seen. Unfortunately, RTNETLINK doesn't give any netdev topology
 	// information.
-	resolveSRIOVTopology(netspaces)
+	resolveSRIOVTopology(netspaces, dopts.source)
 	// Discover the processes serving TAP/TUN devices, if any.
-	resolveTapTunProcessors(netspaces, allprocs)
+	resolveTapTunProcessors(netspaces, dopts.source, allprocs)
 	// Complete the forwarded port information based on our almost complete view
 	// now. This needs to be late(r) in the game.
 	completeForwardedPortInformation(netspaces)
@@ -580,7 +778,7 @@ func tenantsOfNetnsMap(
 
 // resolveSRIOVTopology resolves the PF-VF topology, given a map with PF and VF network
 // interfaces indexed by their (PCI) bus addresses.
-func resolveSRIOVTopology(netspaces NetworkNamespaces) {
+func resolveSRIOVTopology(netspaces NetworkNamespaces, src Source) {
 	sriovNifs := map[string]Interface{}
 	for _, netns := range netspaces {
 		for _, nif := range netns.Nifs {
@@ -595,7 +793,7 @@ func resolveSRIOVTopology(netspaces NetworkNamespaces) {
 		// Is this a VF? Then it has to have a physfn directory, which actually
 		// is a link to the PF's device directory.
 		sysfsBusPath := nif.Nif().SysfsBusPath()
-		physfnLink, err := os.Readlink(sysfsBusPath + "/physfn")
+		physfnLink, err := src.Readlink(sysfsBusPath + "/physfn")
 		if err == nil {
 			nif.Nif().SRIOVRole = PCI_SRIOV_VF
 			// physfn is a symbolic link to the VF sibling in the flat
@@ -614,7 +812,7 @@ func resolveSRIOVTopology(netspaces NetworkNamespaces) {
 		}
 		// Is this a PF? Then it has to have some PF-specific device nodes with
 		// names starting with "sriov_".
-		_, err = os.Stat(sysfsBusPath + "/sriov_numvfs")
+		_, err = src.Stat(sysfsBusPath + "/sriov_numvfs")
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,213 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"net"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// ifaProto is the IFA_PROTO address attribute carrying the address origin
+// ("protocol"), introduced with Linux kernel 5.18; see also:
+// https://elixir.bootlin.com/linux/v5.18/source/include/uapi/linux/if_addr.h#L38
+const ifaProto = 11
+
+// AddrLister lists the addresses assigned to network interfaces; it is
+// satisfied by netlink.Handle.
+type AddrLister interface {
+	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
+}
+
+var _ AddrLister = (*netlink.Handle)(nil)
+
+// dumpedAddrs are the addresses from a single address dump of a network
+// namespace, indexed by their network interface indices.
+type dumpedAddrs map[int][]dumpedAddr
+
+// dumpedAddr is a single dumped address with its address family.
+type dumpedAddr struct {
+	netlink.Addr
+	family int
+}
+
+// AddrList returns the dumped addresses of the specified network interface and
+// address family, with netlink.FAMILY_ALL returning all addresses.
+func (d dumpedAddrs) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	var addrs []netlink.Addr
+	for _, addr := range d[link.Attrs().Index] {
+		if family != netlink.FAMILY_ALL && addr.family != family {
+			continue
+		}
+		addrs = append(addrs, addr.Addr)
+	}
+	return addrs, nil
+}
+
+// discoverAddresses dumps the addresses of all network interfaces in this
+// network namespace, returning the raw messages as well as the parsed
+// addresses. A failing dump leaves all network interfaces without addresses.
+func (n *NetworkNamespace) discoverAddresses(nsa NetnsAccess) ([][]byte, dumpedAddrs) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETADDR, unix.NLM_F_DUMP)
+	req.AddData(nl.NewIfAddrmsg(unix.AF_UNSPEC))
+	msgs, err := nsa.Execute(req, unix.NETLINK_ROUTE, unix.RTM_NEWADDR)
+	if err != nil {
+		n.diags.Warnf(diagnostics.AddressDetailsUnavailable, n.scope(),
+			"cannot discover addresses in net:[%d], reason: %s", n.ID().Ino, err.Error())
+		return nil, dumpedAddrs{}
+	}
+	addrs := dumpedAddrs{}
+	for _, msg := range msgs {
+		addr, family, ok := parseAddr(msg)
+		if !ok {
+			continue
+		}
+		addrs[addr.LinkIndex] = append(addrs[addr.LinkIndex], dumpedAddr{Addr: addr, family: family})
+	}
+	return msgs, addrs
+}
+
+// parseAddr parses a RTM_NEWADDR message the same way vishvananda/netlink
+// does when listing addresses, returning the address and its family.
+func parseAddr(msg []byte) (addr netlink.Addr, family int, ok bool) {
+	if len(msg) < unix.SizeofIfAddrmsg {
+		return
+	}
+	ifamsg := nl.DeserializeIfAddrmsg(msg)
+	attrs, err := nl.ParseRouteAttr(msg[ifamsg.Len():])
+	if err != nil {
+		return
+	}
+	family = int(ifamsg.Family)
+	addr.LinkIndex = int(ifamsg.Index)
+	var local, dst *net.IPNet
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.IFA_ADDRESS:
+			dst = &net.IPNet{
+				IP:   attr.Value,
+				Mask: net.CIDRMask(int(ifamsg.Prefixlen), 8*len(attr.Value)),
+			}
+		case unix.IFA_LOCAL:
+			// A local address with a peer address doesn't have a prefix
+			// length of its own.
+			n := 8 * len(attr.Value)
+			local = &net.IPNet{
+				IP:   attr.Value,
+				Mask: net.CIDRMask(n, n),
+			}
+		case unix.IFA_BROADCAST:
+			addr.Broadcast = attr.Value
+		case unix.IFA_LABEL:
+			if len(attr.Value) > 0 {
+				addr.Label = string(attr.Value[:len(attr.Value)-1])
+			}
+		case unix.IFA_FLAGS:
+			if len(attr.Value) >= 4 {
+				addr.Flags = int(nl.NativeEndian().Uint32(attr.Value))
+			}
+		case unix.IFA_CACHEINFO:
+			if len(attr.Value) >= unix.SizeofIfaCacheinfo {
+				ci := nl.DeserializeIfaCacheInfo(attr.Value)
+				addr.PreferedLft = int(ci.Prefered)
+				addr.ValidLft = int(ci.Valid)
+			}
+		}
+	}
+	// IPv4 sends both IFA_LOCAL and IFA_ADDRESS, with IFA_ADDRESS being the
+	// peer address if they differ. IPv6 sends only IFA_ADDRESS, except for
+	// point-to-point links.
+	switch {
+	case local != nil && family == unix.AF_INET && dst != nil && local.IP.Equal(dst.IP):
+		addr.IPNet = dst
+	case local != nil:
+		addr.IPNet = local
+		addr.Peer = dst
+	default:
+		addr.IPNet = dst
+	}
+	if addr.IPNet == nil {
+		return
+	}
+	addr.Scope = int(ifamsg.Scope)
+	return addr, family, true
+}
+
+// resolveAddressDetails fills in those address details that vishvananda/netlink
+// doesn't decode when listing addresses, namely the IFA_PROTO address origin
+// and the creation and update timestamps from the IFA_CACHEINFO, taken from
+// the specified address dump messages.
+func (n *NetworkNamespace) resolveAddressDetails(msgs [][]byte) {
+	for _, msg := range msgs {
+		index, ip, origin, cacheinfo, ok := parseAddressDetails(msg)
+		if !ok {
+			continue
+		}
+		nif, ok := n.Nifs[index]
+		if !ok {
+			continue
+		}
+		addrs := nif.Nif().Addrsv4
+		if len(ip) == net.IPv6len {
+			addrs = nif.Nif().Addrsv6
+		}
+		for idx := range addrs {
+			if !addrs[idx].Address.Equal(ip) {
+				continue
+			}
+			addrs[idx].Origin = origin
+			if cacheinfo != nil {
+				addrs[idx].Created = cacheinfo.Cstamp
+				addrs[idx].Updated = cacheinfo.Tstamp
+			}
+			break
+		}
+	}
+}
+
+// parseAddressDetails parses a RTM_NEWADDR message and returns the interface
+// index, (local) address, origin, and optional cache information. It returns
+// false if the message cannot be parsed.
+func parseAddressDetails(msg []byte) (index int, ip net.IP, origin AddressOrigin, cacheinfo *unix.IfaCacheinfo, ok bool) {
+	if len(msg) < unix.SizeofIfAddrmsg {
+		return
+	}
+	ifamsg := nl.DeserializeIfAddrmsg(msg)
+	attrs, err := nl.ParseRouteAttr(msg[ifamsg.Len():])
+	if err != nil {
+		return
+	}
+	var local, address net.IP
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.IFA_LOCAL:
+			local = net.IP(attr.Value)
+		case unix.IFA_ADDRESS:
+			address = net.IP(attr.Value)
+		case ifaProto:
+			if len(attr.Value) >= 1 {
+				origin = AddressOrigin(attr.Value[0])
+			}
+		case unix.IFA_CACHEINFO:
+			if len(attr.Value) >= unix.SizeofIfaCacheinfo {
+				cacheinfo = &nl.DeserializeIfaCacheInfo(attr.Value).IfaCacheinfo
+			}
+		}
+	}
+	// Mirror vishvananda/netlink: IPv4 always sends IFA_LOCAL, where
+	// IFA_ADDRESS is the peer address in case of point-to-point links. IPv6
+	// sends only IFA_ADDRESS, except for point-to-point links.
+	ip = address
+	if local != nil {
+		ip = local
+	}
+	if ip == nil {
+		return
+	}
+	return int(ifamsg.Index), ip, origin, cacheinfo, true
+}
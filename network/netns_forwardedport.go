@@ -6,9 +6,11 @@ package network
 
 import (
 	"net"
+	"sync"
 	"syscall"
 
 	"github.com/google/nftables"
+	"github.com/siemens/ghostwire/v2/diagnostics"
 	"github.com/siemens/ghostwire/v2/network/portfwd"
 	_ "github.com/siemens/ghostwire/v2/network/portfwd/all" // activate all port forwarding detectors.
 	"github.com/thediveo/go-plugger/v3"
@@ -43,28 +45,56 @@ type ForwardedPort struct {
 // CNI plugins, and a myriad of other existing software that really doesn't
 // bother itself with netfilter.
 func (n *NetworkNamespace) discoverForwardedPorts() {
+	// Netfilter netlink connections aren't safe for concurrent use, so we
+	// open a separate connection per family in order to read the IPv4 and
+	// IPv6 tables in parallel.
+	connv4, err := n.openNetfilter()
+	if err != nil {
+		n.diags.Errorf(diagnostics.ForwardedPortsUnavailable, n.scope(),
+			"cannot connect to netfilters in net:[%d], reason: %s", n.ID().Ino, err.Error())
+		return
+	}
+	defer connv4.CloseLasting()
+	connv6, err := n.openNetfilter()
+	if err != nil {
+		n.diags.Errorf(diagnostics.ForwardedPortsUnavailable, n.scope(),
+			"cannot connect to netfilters in net:[%d], reason: %s", n.ID().Ino, err.Error())
+		return
+	}
+	defer connv6.CloseLasting()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.ForwardedPortsv6 = n.discoverForwardedPortsOfFamily(connv6, nufftables.TableFamilyIPv6)
+	}()
+	n.ForwardedPortsv4 = n.discoverForwardedPortsOfFamily(connv4, nufftables.TableFamilyIPv4)
+	wg.Wait()
+}
+
+// openNetfilter returns a netfilter netlink connection to this network
+// namespace. The caller is responsible for closing the connection using
+// CloseLasting when not needed anymore.
+func (n *NetworkNamespace) openNetfilter() (*nftables.Conn, error) {
 	// If netfilters doesn't come to us, we simply come to netfilters ;) The
 	// reson is that nftables uses @mdlayher/netlink and that really is a major
-	// P.I.T.A. with its network namespace "support". Luckily, we have
-	// OpenInNetworkNamespace that properly supports the more complex use cases
-	// of namespace references so we simply wrap creating a netfilter netlink
-	// socket in it.
+	// P.I.T.A. with its network namespace "support". Luckily, visiting a
+	// network namespace properly supports the more complex use cases of
+	// namespace references so we simply create a netfilter netlink socket
+	// while visiting.
 	var conn *nftables.Conn
-	err := n.OpenInNetworkNamespace(func() error {
+	err := sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
 		var err error
-		conn, err = nftables.New(nftables.AsLasting())
+		conn, err = nsa.Netfilter()
 		return err
 	})
 	if err != nil {
 		if conn != nil {
 			conn.CloseLasting()
 		}
-		log.Errorf("cannot connect to netfilters, reason: %s", err.Error())
-		return
+		return nil, err
 	}
-	defer conn.CloseLasting()
-	n.ForwardedPortsv4 = n.discoverForwardedPortsOfFamily(conn, nufftables.TableFamilyIPv4)
-	n.ForwardedPortsv6 = n.discoverForwardedPortsOfFamily(conn, nufftables.TableFamilyIPv6)
+	return conn, nil
 }
 
 // discoverForwardedPortsOfFamily discovers and returns forwarded transport
@@ -74,8 +104,9 @@ func (n *NetworkNamespace) discoverForwardedPorts() {
 func (n *NetworkNamespace) discoverForwardedPortsOfFamily(conn *nftables.Conn, family nufftables.TableFamily) []ForwardedPort {
 	iptables, err := nufftables.GetFamilyTables(conn, family)
 	if err != nil {
-		log.Errorf("cannot retrieve %s netfilter tables, reason: %s",
-			family, err.Error())
+		n.diags.Errorf(diagnostics.ForwardedPortsUnavailable, n.scope(),
+			"cannot retrieve %s netfilter tables in net:[%d], reason: %s",
+			family, n.ID().Ino, err.Error())
 		return nil
 	}
 	forwardedPorts := []ForwardedPort{}
@@ -149,9 +180,10 @@ func findMatchingProcesses(port uint16, addr net.IP, proto Protocol, sockets []P
 		if socket.Protocol != proto || socket.LocalPort != port {
 			continue
 		}
-		// Don't take sockets into consideration that are connected TCP sockets,
-		// as we're only interested in a listening TCP socket.
-		if socket.Protocol == syscall.IPPROTO_TCP && socket.SimplifiedState != Listening {
+		// Don't take sockets into consideration that are connected TCP or SCTP
+		// sockets, as we're only interested in a listening TCP or SCTP socket.
+		if (socket.Protocol == syscall.IPPROTO_TCP || socket.Protocol == syscall.IPPROTO_SCTP) &&
+			socket.SimplifiedState != Listening {
 			continue
 		}
 		// If the socket is bound to a specific local address: does it match the
@@ -0,0 +1,326 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bytes"
+	"net"
+	"syscall"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/exp/slices"
+	"golang.org/x/sys/unix"
+)
+
+// Multicast database attributes, see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/if_bridge.h#L420
+const (
+	mdbaMdb          = 1
+	mdbaMdbEntry     = 1
+	mdbaMdbEntryInfo = 1
+
+	mdbPermanent = 1
+)
+
+// Sizes and offsets of struct br_port_msg and struct br_mdb_entry, as well as
+// the ETH_P_* protocol numbers in br_mdb_entry.addr.proto.
+const (
+	sizeofBrPortMsg   = 8
+	sizeofBrMdbEntry  = 28
+	mdbEntryAddrStart = 8
+
+	ethPIP   = 0x0800
+	ethPIPv6 = 0x86dd
+)
+
+// multicastProcfs contains the raw contents of the multicast-related procfs
+// files of a network namespace.
+type multicastProcfs struct {
+	igmp, igmp6, devmcast                  []byte
+	mrvifv4, mrcachev4, mrvifv6, mrcachev6 []byte
+}
+
+// discoverMulticast discovers the multicast group memberships of the network
+// interfaces in this network namespace, the IGMP/MLD snooping state of
+// bridges, as well as the multicast routing state (if any).
+//
+// This must be run only after the transport ports have been discovered, as
+// we're attributing multicast groups to processes based on their sockets.
+func (n *NetworkNamespace) discoverMulticast() {
+	var procfs multicastProcfs
+	var linkmsgs, mdbmsgs [][]byte
+	hasBridges := false
+	for _, nif := range n.Nifs {
+		if _, ok := nif.(Bridge); ok {
+			hasBridges = true
+			break
+		}
+	}
+	if err := sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
+		// As we are now running on a thread attached to the network namespace
+		// in question, "thread-self" gives us the correct view.
+		for _, f := range []struct {
+			name     string
+			contents *[]byte
+		}{
+			{"igmp", &procfs.igmp},
+			{"igmp6", &procfs.igmp6},
+			{"dev_mcast", &procfs.devmcast},
+			{"ip_mr_vif", &procfs.mrvifv4},
+			{"ip_mr_cache", &procfs.mrcachev4},
+			{"ip6_mr_vif", &procfs.mrvifv6},
+			{"ip6_mr_cache", &procfs.mrcachev6},
+		} {
+			// Missing files are fine, such as when IPv6 is disabled or there
+			// is no multicast routing support in the kernel.
+			*f.contents, _ = nsa.ReadFile("/proc/thread-self/net/" + f.name)
+		}
+		if !hasBridges {
+			return nil
+		}
+		var err error
+		req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP)
+		req.AddData(nl.NewIfInfomsg(unix.AF_UNSPEC))
+		if linkmsgs, err = nsa.Execute(req, unix.NETLINK_ROUTE, unix.RTM_NEWLINK); err != nil {
+			return err
+		}
+		req = nl.NewNetlinkRequest(unix.RTM_GETMDB, unix.NLM_F_DUMP)
+		req.AddData(&brPortMsg{Family: unix.AF_BRIDGE})
+		mdbmsgs, err = nsa.Execute(req, unix.NETLINK_ROUTE, unix.RTM_NEWMDB)
+		return err
+	}); err != nil {
+		n.diags.Warnf(diagnostics.MulticastUnavailable, n.scope(),
+			"cannot discover multicast details in net:[%d], reason: %s", n.ID().Ino, err.Error())
+		// carry on with what we might have got so far.
+	}
+	n.resolveMulticastGroups(procfs)
+	n.McastRoutingv4 = n.newMulticastRouting(procfs.mrvifv4, procfs.mrcachev4)
+	n.McastRoutingv6 = n.newMulticastRouting(procfs.mrvifv6, procfs.mrcachev6)
+	for _, msg := range linkmsgs {
+		index, querier, igmpv, mldv, ok := parseBridgeMulticastDetails(msg)
+		if !ok {
+			continue
+		}
+		if br, ok := n.Nifs[index].(Bridge); ok {
+			br.Bridge().MulticastQuerier = querier
+			br.Bridge().IGMPVersion = igmpv
+			br.Bridge().MLDVersion = mldv
+		}
+	}
+	for _, msg := range mdbmsgs {
+		index, entries := parseMDB(msg)
+		br, ok := n.Nifs[index].(Bridge)
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			entry.Port = n.Nifs[entry.portIndex]
+			br.Bridge().MulticastDB = append(br.Bridge().MulticastDB, entry.BridgeMulticastEntry)
+		}
+	}
+}
+
+// resolveMulticastGroups assigns the joined multicast groups and link-layer
+// multicast addresses to the network interfaces of this network namespace and
+// attributes the groups to processes where possible.
+func (n *NetworkNamespace) resolveMulticastGroups(procfs multicastProcfs) {
+	for index, groups := range parseIGMP(bytes.NewReader(procfs.igmp)) {
+		if nif, ok := n.Nifs[index]; ok {
+			n.attributeMulticastGroups(groups, n.Portsv4)
+			nif.Nif().McastGroupsv4 = groups
+		}
+	}
+	for index, groups := range parseIGMP6(bytes.NewReader(procfs.igmp6)) {
+		if nif, ok := n.Nifs[index]; ok {
+			n.attributeMulticastGroups(groups, n.Portsv6)
+			nif.Nif().McastGroupsv6 = groups
+		}
+	}
+	for index, l2addrs := range parseDevMcast(bytes.NewReader(procfs.devmcast)) {
+		if nif, ok := n.Nifs[index]; ok {
+			nif.Nif().McastL2Addrs = l2addrs
+		}
+	}
+}
+
+// attributeMulticastGroups attributes the specified multicast groups to the
+// processes having UDP sockets bound to the multicast group addresses.
+func (n *NetworkNamespace) attributeMulticastGroups(groups []MulticastGroup, ports []ProcessSocket) {
+	for idx := range groups {
+		group := &groups[idx]
+		for _, port := range ports {
+			if port.Protocol != syscall.IPPROTO_UDP || !port.LocalIP.Equal(group.Address) {
+				continue
+			}
+			for pidx, pid := range port.PIDs {
+				if slices.Contains(group.PIDs, pid) {
+					continue
+				}
+				group.PIDs = append(group.PIDs, pid)
+				if pidx < len(port.Processes) {
+					group.Processes = append(group.Processes, port.Processes[pidx])
+				}
+			}
+		}
+	}
+}
+
+// newMulticastRouting returns the multicast routing state from the specified
+// VIF and cache procfs file contents, or nil if the network namespace isn't
+// routing multicast.
+func (n *NetworkNamespace) newMulticastRouting(vifs []byte, cache []byte) *MulticastRouting {
+	mr := &MulticastRouting{
+		VIFs:   parseMrouteVIFs(bytes.NewReader(vifs)),
+		Routes: parseMrouteCache(bytes.NewReader(cache)),
+	}
+	if len(mr.VIFs) == 0 && len(mr.Routes) == 0 {
+		return nil
+	}
+	for idx := range mr.VIFs {
+		mr.VIFs[idx].Nif = n.NamedNifs[mr.VIFs[idx].Name]
+	}
+	return mr
+}
+
+// brPortMsg is the struct br_port_msg header of RTM_GETMDB requests; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/if_bridge.h#L403
+type brPortMsg struct {
+	Family  uint8
+	Ifindex uint32
+}
+
+// Len returns the length of a serialized br_port_msg, including padding.
+func (m *brPortMsg) Len() int { return sizeofBrPortMsg }
+
+// Serialize returns the binary br_port_msg representation.
+func (m *brPortMsg) Serialize() []byte {
+	b := make([]byte, sizeofBrPortMsg)
+	b[0] = m.Family
+	nl.NativeEndian().PutUint32(b[4:], m.Ifindex)
+	return b
+}
+
+// mdbEntry is a bridge multicast database entry with the port index still
+// unresolved.
+type mdbEntry struct {
+	BridgeMulticastEntry
+	portIndex int
+}
+
+// parseMDB parses a RTM_NEWMDB message, returning the bridge's interface index
+// as well as the multicast database entries.
+func parseMDB(msg []byte) (index int, entries []mdbEntry) {
+	if len(msg) < sizeofBrPortMsg {
+		return
+	}
+	index = int(nl.NativeEndian().Uint32(msg[4:8]))
+	attrs, err := nl.ParseRouteAttr(msg[sizeofBrPortMsg:])
+	if err != nil {
+		return
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != mdbaMdb {
+			continue
+		}
+		mdbentries, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			continue
+		}
+		for _, mdbentry := range mdbentries {
+			if mdbentry.Attr.Type&nl.NLA_TYPE_MASK != mdbaMdbEntry {
+				continue
+			}
+			infos, err := nl.ParseRouteAttr(mdbentry.Value)
+			if err != nil {
+				continue
+			}
+			for _, info := range infos {
+				if info.Attr.Type&nl.NLA_TYPE_MASK != mdbaMdbEntryInfo {
+					continue
+				}
+				if entry, ok := parseBrMdbEntry(info.Value); ok {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+	return
+}
+
+// parseBrMdbEntry parses a struct br_mdb_entry; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/if_bridge.h#L621
+func parseBrMdbEntry(b []byte) (entry mdbEntry, ok bool) {
+	if len(b) < sizeofBrMdbEntry {
+		return
+	}
+	entry.portIndex = int(nl.NativeEndian().Uint32(b[0:4]))
+	entry.Permanent = b[4] == mdbPermanent
+	entry.VID = nl.NativeEndian().Uint16(b[6:8])
+	addr := b[mdbEntryAddrStart : mdbEntryAddrStart+16]
+	switch uint16(b[mdbEntryAddrStart+16])<<8 | uint16(b[mdbEntryAddrStart+17]) {
+	case ethPIP:
+		entry.Group = net.IP(slices.Clone(addr[:net.IPv4len]))
+	case ethPIPv6:
+		entry.Group = net.IP(slices.Clone(addr))
+	default:
+		entry.L2Group = net.HardwareAddr(slices.Clone(addr[:6]))
+	}
+	return entry, true
+}
+
+// parseBridgeMulticastDetails parses a RTM_NEWLINK message and returns the
+// IGMP/MLD querier details in case of a bridge, otherwise false.
+func parseBridgeMulticastDetails(msg []byte) (index int, querier bool, igmpv uint8, mldv uint8, ok bool) {
+	if len(msg) < unix.SizeofIfInfomsg {
+		return
+	}
+	ifimsg := nl.DeserializeIfInfomsg(msg)
+	attrs, err := nl.ParseRouteAttr(msg[unix.SizeofIfInfomsg:])
+	if err != nil {
+		return
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != unix.IFLA_LINKINFO {
+			continue
+		}
+		infos, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return
+		}
+		var data []byte
+		isBridge := false
+		for _, info := range infos {
+			switch info.Attr.Type & nl.NLA_TYPE_MASK {
+			case unix.IFLA_INFO_KIND:
+				isBridge = string(bytes.TrimRight(info.Value, "\x00")) == "bridge"
+			case unix.IFLA_INFO_DATA:
+				data = info.Value
+			}
+		}
+		if !isBridge || data == nil {
+			return
+		}
+		brattrs, err := nl.ParseRouteAttr(data)
+		if err != nil {
+			return
+		}
+		for _, brattr := range brattrs {
+			if len(brattr.Value) < 1 {
+				continue
+			}
+			switch brattr.Attr.Type & nl.NLA_TYPE_MASK {
+			case unix.IFLA_BR_MCAST_QUERIER:
+				querier = brattr.Value[0] != 0
+			case unix.IFLA_BR_MCAST_IGMP_VERSION:
+				igmpv = brattr.Value[0]
+			case unix.IFLA_BR_MCAST_MLD_VERSION:
+				mldv = brattr.Value[0]
+			}
+		}
+		return int(ifimsg.Index), querier, igmpv, mldv, true
+	}
+	return
+}
@@ -8,7 +8,8 @@ import (
 	"fmt"
 	"net"
 
-	"github.com/vishvananda/netlink"
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
 )
 
@@ -55,32 +56,34 @@ func (r RouteType) String() string {
 	return fmt.Sprintf("RouteType(%d)", r)
 }
 
-func (n *NetworkNamespace) discoverRoutes(nlh *netlink.Handle, family int) []Route {
-	// Please note that RouteListFiltered filters its result to return only
-	// RT_TABLE_MAIN as long as the filter mask is zero. However, internally,
-	// RouteListFiltered always dumps all tables (cringe), so we opt into table
-	// filtering in the filter mask, but then set the table to filter to to
-	// "unspecified".
-	nlroutes, err := nlh.RouteListFiltered(
-		family,
-		&netlink.Route{
-			Table: unix.RT_TABLE_UNSPEC, // we want to get all routing tables
-		},
-		netlink.RT_FILTER_TABLE)
+// discoverRoutes discovers the routes of the specified address family in the
+// main and local routing tables of this network namespace.
+func (n *NetworkNamespace) discoverRoutes(nsa NetnsAccess, family int) []Route {
+	// We always dump all routing tables and then filter ourselves, as this is
+	// what netlink.Handle.RouteListFiltered internally does anyway.
+	req := nl.NewNetlinkRequest(unix.RTM_GETROUTE, unix.NLM_F_DUMP)
+	req.AddData(&nl.RtMsg{RtMsg: unix.RtMsg{Family: uint8(family)}}) // #nosec G115
+	msgs, err := nsa.Execute(req, unix.NETLINK_ROUTE, unix.RTM_NEWROUTE)
 	if err != nil {
+		n.diags.Warnf(diagnostics.RoutesUnavailable, n.scope(),
+			"cannot discover routes in net:[%d], reason: %s", n.ID().Ino, err.Error())
 		return []Route{} // don't nil, so any marshaller will not try to do unwanted things.
 	}
-	routes := make([]Route, 0, len(nlroutes))
-	for _, route := range nlroutes {
+	routes := make([]Route, 0, len(msgs))
+	for _, msg := range msgs {
+		route, ok := parseRoute(msg)
+		if !ok || int(route.family) != family {
+			continue
+		}
 		// Only process main and local tables; the local table not least
 		// contains the multicast routes.
-		if route.Table != unix.RT_TABLE_MAIN && route.Table != unix.RT_TABLE_LOCAL {
+		if route.table != unix.RT_TABLE_MAIN && route.table != unix.RT_TABLE_LOCAL {
 			continue
 		}
 
 		var dst net.IPNet
-		if route.Dst != nil {
-			dst = *route.Dst
+		if route.dst != nil {
+			dst = *route.dst
 		} else if family == unix.AF_INET {
 			dst.IP = net.IPv4zero
 		} else {
@@ -89,14 +92,14 @@ func (n *NetworkNamespace) discoverRoutes(nlh *netlink.Handle, family int) []Rou
 		prefixlen, _ := dst.Mask.Size()
 		r := Route{
 			Family:               AddressFamily(family),
-			Type:                 RouteType(route.Type),
+			Type:                 RouteType(route.typ),
 			Destination:          dst,
 			DestinationPrefixLen: prefixlen,
-			NextHop:              route.Gw,
-			Index:                route.LinkIndex,         // zero for blackhole routes, etc.
-			Nif:                  n.Nifs[route.LinkIndex], // also works for blackhole routes, etc., giving nil.
-			Table:                route.Table,
-			Priority:             route.Priority,
+			NextHop:              route.gw,
+			Index:                route.linkIndex,         // zero for blackhole routes, etc.
+			Nif:                  n.Nifs[route.linkIndex], // also works for blackhole routes, etc., giving nil.
+			Table:                route.table,
+			Priority:             route.priority,
 			// default to ICMPV6_ROUTER_PREF_MEDIUM for the moment; TODO: support from vishvananda/netlink
 			Preference: 0,
 		}
@@ -104,3 +107,61 @@ func (n *NetworkNamespace) discoverRoutes(nlh *netlink.Handle, family int) []Rou
 	}
 	return routes
 }
+
+// dumpedRoute is a route parsed from a RTM_NEWROUTE message, limited to the
+// details needed for Route.
+type dumpedRoute struct {
+	family    uint8
+	typ       uint8
+	dst       *net.IPNet
+	gw        net.IP
+	linkIndex int // zero for multipath routes.
+	table     int
+	priority  int
+}
+
+// parseRoute parses a RTM_NEWROUTE message the same way vishvananda/netlink
+// does when listing routes, skipping cloned (cached) routes.
+func parseRoute(msg []byte) (route dumpedRoute, ok bool) {
+	if len(msg) < unix.SizeofRtMsg {
+		return
+	}
+	rtmsg := nl.DeserializeRtMsg(msg)
+	if rtmsg.Flags&unix.RTM_F_CLONED != 0 {
+		return
+	}
+	attrs, err := nl.ParseRouteAttr(msg[rtmsg.Len():])
+	if err != nil {
+		return
+	}
+	route = dumpedRoute{
+		family: rtmsg.Family,
+		typ:    rtmsg.Type,
+		table:  int(rtmsg.Table),
+	}
+	native := nl.NativeEndian()
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.RTA_DST:
+			route.dst = &net.IPNet{
+				IP:   attr.Value,
+				Mask: net.CIDRMask(int(rtmsg.Dst_len), 8*len(attr.Value)),
+			}
+		case unix.RTA_GATEWAY:
+			route.gw = attr.Value
+		case unix.RTA_OIF:
+			if len(attr.Value) >= 4 {
+				route.linkIndex = int(native.Uint32(attr.Value))
+			}
+		case unix.RTA_TABLE:
+			if len(attr.Value) >= 4 {
+				route.table = int(native.Uint32(attr.Value))
+			}
+		case unix.RTA_PRIORITY:
+			if len(attr.Value) >= 4 {
+				route.priority = int(native.Uint32(attr.Value))
+			}
+		}
+	}
+	return route, true
+}
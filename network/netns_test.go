@@ -5,10 +5,13 @@
 package network
 
 import (
+	"net"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/thediveo/deferrer"
+	"github.com/thediveo/lxkns/model"
 	"github.com/thediveo/lxkns/nstest"
 	"github.com/thediveo/lxkns/ops"
 	"github.com/thediveo/lxkns/species"
@@ -124,4 +127,48 @@ ip netns del ${netnsname}
 
 	})
 
+	It("returns display names without sorting the tenants in place", func() {
+		proc1 := &model.Process{PID: 1, ProTaskCommon: model.ProTaskCommon{Name: "init"}}
+		proc2 := &model.Process{PID: 42, ProTaskCommon: model.ProTaskCommon{Name: "zzz"}}
+		proc3 := &model.Process{PID: 666, ProTaskCommon: model.ProTaskCommon{Name: "aaa"}}
+		netns := &NetworkNamespace{
+			Tenants: Tenants{{Process: proc2}, {Process: proc3}, {Process: proc1}},
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(netns.DisplayName()).To(Equal("⚙️  init(1), …"))
+			}()
+		}
+		wg.Wait()
+		Expect(netns.Tenants).To(HaveExactElements(
+			HaveField("Process", proc2), HaveField("Process", proc3), HaveField("Process", proc1)))
+	})
+
+	It("maps the unspecified addresses to all network interfaces in order", func() {
+		netns := &NetworkNamespace{}
+		netns.Nifs = map[int]Interface{}
+		for idx, name := range []string{"eth1", "lo", "eth0", "docker0"} {
+			netns.Nifs[idx+1] = &NifAttrs{Netns: netns, Name: name, Index: idx + 1,
+				Addrsv4: Addresses{{Address: net.IPv4(10, 0, 0, byte(idx+1)).To4()}}}
+		}
+		addrToNifs := netns.newAddrToNifMap()
+		names := func(nifs []Interface) []string {
+			n := []string{}
+			for _, nif := range nifs {
+				n = append(n, nif.Nif().Name)
+			}
+			return n
+		}
+		for _, addr := range []net.IP{net.IPv4zero.To4(), net.IPv6unspecified} {
+			Expect(names(addrToNifs[string(addr)])).To(HaveExactElements(
+				"lo", "docker0", "eth0", "eth1"))
+		}
+		Expect(names(addrToNifs[string(net.IPv4(10, 0, 0, 3).To4())])).To(
+			HaveExactElements("eth0"))
+	})
+
 })
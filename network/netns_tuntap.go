@@ -6,25 +6,22 @@ package network
 
 import (
 	"bufio"
-	"os"
+	"bytes"
 	"strconv"
 	"strings"
 
-	"github.com/thediveo/ioctl"
 	"github.com/thediveo/lxkns/log"
 	"github.com/thediveo/lxkns/model"
-	"github.com/thediveo/lxkns/ops"
 	"github.com/thediveo/lxkns/species"
-	"golang.org/x/sys/unix"
 )
 
 // resolveTapTunProcessors checks for the presence of TAP and/or TUN network
 // interfaces and then resolves their serving processes ("processors").
-func resolveTapTunProcessors(netspaces NetworkNamespaces, allprocs model.ProcessTable) {
+func resolveTapTunProcessors(netspaces NetworkNamespaces, src Source, allprocs model.ProcessTable) {
 	if !hasTapTun(netspaces) {
 		return
 	}
-	processors := discoverProcessors(allprocs)
+	processors := discoverProcessors(src, allprocs)
 	for _, processor := range processors {
 		netns := netspaces[processor.NetnsID]
 		if netns == nil {
@@ -54,41 +51,21 @@ type tuntapProcessor struct {
 	NetnsID species.NamespaceID
 }
 
-// getTapNetdevNetnsFd takes an open file descriptor referencing a TAP/TUN
-// netdev and returns a new file descriptor referencing the network namespace
-// the TAP/TUN netdev is currently placed in.
-//
-// For background information, please see:
-// https://unix.stackexchange.com/a/743003
-func getTapNetdevNetnsFd(fd int) (int, error) {
-	return ioctl.RetFd(fd, ioctl.IO('T', 227))
-}
-
 // discoverProcessors returns a list of processes that have file descriptors
 // referencing TAP/TUN network devices.
-func discoverProcessors(allprocs model.ProcessTable) []tuntapProcessor {
+func discoverProcessors(src Source, allprocs model.ProcessTable) []tuntapProcessor {
 	var processors []tuntapProcessor
 	for pid, proc := range allprocs {
 		base := "/proc/" + strconv.Itoa(int(pid)) + "/fdinfo"
-		fdinfoEntries, err := os.ReadDir(base)
+		fdinfoEntries, err := src.ReadDir(base)
 		if err != nil {
 			continue
 		}
-		var pidfd int // as we have stdin, stdout, stderr always connected, this fd cannot be 0.
-	scanFds:
 		for _, fdInfoEntry := range fdinfoEntries {
-			iffName := iff(base + "/" + fdInfoEntry.Name())
+			iffName := iff(src, base+"/"+fdInfoEntry.Name())
 			if iffName == "" {
 				continue
 			}
-
-			if pidfd == 0 {
-				pidfd, err = unix.PidfdOpen(int(pid), 0)
-				if err != nil {
-					break scanFds
-				}
-			}
-
 			// Work around bug(s) #14733/#9295 in CodeQL scanning which
 			// currently block correct parsing using ParseUint(...,
 			// strconv.IntSize-1) and then casting to int.
@@ -96,47 +73,30 @@ func discoverProcessors(allprocs model.ProcessTable) []tuntapProcessor {
 			if err != nil || fd < 0 {
 				continue
 			}
-			taptunFd, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+			netnsID, err := src.TunTapNetns(pid, int(fd))
 			if err != nil {
 				continue
 			}
-			netnsFd, err := getTapNetdevNetnsFd(taptunFd)
-			unix.Close(taptunFd)
-			if err != nil {
-				continue
-			}
-			netnsID, err := ops.NamespaceFd(netnsFd).ID()
-			unix.Close(netnsFd)
-			if err != nil {
-				continue
-			}
-
 			processors = append(processors, tuntapProcessor{
 				Process: proc,
 				NifName: iffName,
 				NetnsID: netnsID,
 			})
 		}
-		if pidfd > 0 {
-			unix.Close(pidfd)
-			pidfd = 0
-		}
 	}
 	return processors
 }
 
 // iff returns the value of the "iff:" entry from a /proc/$PID/fdinfo/$FD pseudo
 // file, if any, otherwise an empty string.
-func iff(path string) string {
+func iff(files Files, path string) string {
 	const iffEntry = "iff:\t"
 
-	f, err := os.Open(path)
+	contents, err := files.ReadFile(path)
 	if err != nil {
 		return ""
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.HasPrefix(line, iffEntry) {
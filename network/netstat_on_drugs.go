@@ -6,11 +6,10 @@ package network
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -107,19 +106,38 @@ func (s SocketSimplifiedState) String() string {
 // ProcessSocket describes the communication parameters of a network socket and
 // which process is using it. Please note that the same socket can be used by
 // multiple processes by sharing its file descriptor.
+//
+// For raw IP sockets, Protocol is syscall.IPPROTO_RAW, while LocalPort instead
+// contains the IP protocol number the raw socket was opened for, such as
+// syscall.IPPROTO_ICMP; this mirrors how the Linux kernel reports raw sockets.
+//
+// For multi-homed SCTP sockets, LocalAddrs and RemoteAddrs list all local
+// and remote addresses, while LocalIP and RemoteIP are the primary addresses.
 type ProcessSocket struct {
-	Family          AddressFamily         // address family, such as unix.AD_INET6, ...
-	Protocol        Protocol              // transport protocol, such as syscall.IPPROTO_TCP, ...
-	LocalIP         net.IP                // local IP address; IPv4 addresses are in .To4() format.
-	LocalPort       uint16                // local TCP/UDP port
-	RemoteIP        net.IP                // remote IP address; IPv4 addresses are in .To4() format.
-	RemotePort      uint16                // remote TCP/UDP port
-	State           SocketState           // (detailed) socket state
-	SimplifiedState SocketSimplifiedState // simplified state: either listening or connected (both TCP and UDP)
-	PIDs            []model.PIDType       // processes using this socket
-	Processes       []*model.Process      // processes using this socket
-	IPv4Mapped      bool                  // IPv6 socket handling IPv4 traffic?
-	Nifs            Interfaces            // network interfaces handling this traffic, based on address/routing data.
+	Family           AddressFamily         // address family, such as unix.AD_INET6, ...
+	Protocol         Protocol              // transport protocol, such as syscall.IPPROTO_TCP, ...
+	LocalIP          net.IP                // local IP address; IPv4 addresses are in .To4() format.
+	LocalPort        uint16                // local TCP/UDP port
+	RemoteIP         net.IP                // remote IP address; IPv4 addresses are in .To4() format.
+	RemotePort       uint16                // remote TCP/UDP port
+	State            SocketState           // (detailed) socket state
+	SimplifiedState  SocketSimplifiedState // simplified state: either listening or connected (both TCP and UDP)
+	PIDs             []model.PIDType       // processes using this socket
+	Processes        []*model.Process      // processes using this socket
+	IPv4Mapped       bool                  // IPv6 socket handling IPv4 traffic?
+	Nifs             Interfaces            // network interfaces handling this traffic, based on address/routing data.
+	Inode            uint64                // socket inode number
+	UID              uint32                // (effective) UID of the socket's creator
+	RecvQueue        uint32                // receive queue length; accept queue length for listening TCP sockets
+	SendQueue        uint32                // send queue length
+	ListenBacklog    uint32                // maximum listen backlog of listening TCP sockets
+	Mark             uint32                // socket mark (SO_MARK), if available
+	CgroupID         uint64                // cgroup v2 ID of the socket's creator, if available
+	TCPInfo          *TCPInfo              // TCP connection details, if available
+	LocalAddrs       []net.IP              // all local addresses of a multi-homed SCTP socket
+	RemoteAddrs      []net.IP              // all remote addresses of an SCTP association
+	ForeignProcesses []*model.Process      // owning processes attached to other network namespaces
+	MPTCPSubflow     *MPTCPSubflow         // MPTCP subflow details, if this TCP socket is an MPTCP subflow
 }
 
 // ProcessSockets is a list of ProcessSocket elements, that optionally can be
@@ -138,13 +156,22 @@ type socketToProcessMap map[uint64][]model.PIDType
 //
 // Please note that the following ProcessSocket fields are not set, but instead
 // need to be resolved by the caller: Nifs.
-func discoverSockets(procroot string, pid model.PIDType, af int, proto int, sm socketToProcessMap) []ProcessSocket {
+func discoverSockets(files Files, procroot string, pid model.PIDType, af int, proto int, sm socketToProcessMap) []ProcessSocket {
 	path := fmt.Sprintf("%s/%d/net/", procroot, pid)
 	switch proto {
 	case syscall.IPPROTO_TCP:
 		path += "tcp"
 	case syscall.IPPROTO_UDP:
 		path += "udp"
+	case syscall.IPPROTO_UDPLITE:
+		path += "udplite"
+	case syscall.IPPROTO_RAW:
+		path += "raw"
+	case syscall.IPPROTO_SCTP:
+		// The procfs SCTP information in /proc/[PID]/net/sctp/ mixes IPv4 and
+		// IPv6 endpoints and associations in a completely different format,
+		// so we don't support SCTP here, but only via sock_diag.
+		return []ProcessSocket{}
 	default:
 		panic(fmt.Sprintf("invalid transport-layer protocol %d", proto))
 	}
@@ -157,15 +184,12 @@ func discoverSockets(procroot string, pid model.PIDType, af int, proto int, sm s
 	}
 	sox := []ProcessSocket{}
 	// "path" is garantueed to be a procfs-based path, so no need to deploy the
-	// mountineers... And no reason for gosec to go berserk: there is path where
-	// user-controlled input can reach procroot and it is otherwise only used in
-	// unit tests with a test-local fake procfs root.
-	f, err := os.Open(path) // #nosec G304
+	// mountineers...
+	contents, err := files.ReadFile(path)
 	if err != nil {
 		return sox
 	}
-	defer func() { _ = f.Close() }()
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 	// Skip the first "header" line.
 	if !scanner.Scan() {
 		return sox
@@ -193,82 +217,79 @@ func newProcessSocket(procnetline string, af int, proto int, sm socketToProcessM
 	if err != nil {
 		return
 	}
+	procsock.Inode = ino
 	procsock.PIDs = sm[ino]
 	state, err := strconv.ParseUint(fields[3], 16, 8)
 	if err != nil {
 		return
 	}
 	procsock.State = SocketState(state)
+	simplified, ok := simplifySocketState(proto, procsock.State)
+	if !ok {
+		procsock.State = 0
+		return
+	}
+	procsock.SimplifiedState = simplified
+	if uid, err := strconv.ParseUint(fields[7], 10, 32); err == nil {
+		procsock.UID = uint32(uid)
+	}
+	// Please note that procfs doesn't tell us the maximum listen backlog, but
+	// for listening TCP sockets reports the current accept queue length as the
+	// receive queue length.
+	if queues := strings.Split(fields[4], ":"); len(queues) == 2 {
+		wqueue, _ := strconv.ParseUint(queues[0], 16, 32)
+		rqueue, _ := strconv.ParseUint(queues[1], 16, 32)
+		procsock.SendQueue = uint32(wqueue)
+		procsock.RecvQueue = uint32(rqueue)
+	}
+	procsock.Family = AddressFamily(af)
+	procsock.Protocol = Protocol(proto)
+	return
+}
+
+// simplifySocketState returns the simplified socket state for the specified
+// transport protocol and detailed socket state, as well as false if the
+// protocol or state is unknown.
+func simplifySocketState(proto int, state SocketState) (SocketSimplifiedState, bool) {
 	switch proto {
-	case syscall.IPPROTO_TCP:
-		switch procsock.State {
+	case syscall.IPPROTO_TCP, unix.IPPROTO_MPTCP:
+		switch state {
 		case TCP_LISTEN:
-			procsock.SimplifiedState = Listening
+			return Listening, true
 		case TCP_ESTABLISHED, TCP_FIN_WAIT1, TCP_FIN_WAIT2:
-			procsock.SimplifiedState = Connected
+			return Connected, true
 		case TCP_CLOSE, TCP_CLOSE_WAIT, TCP_CLOSING, TCP_LAST_ACK, TCP_NEW_SYN_RECV, TCP_SYN_RECV, TCP_SYN_SENT, TCP_TIME_WAIT:
-			procsock.SimplifiedState = Unconnected
-		default:
-			procsock.State = 0
-			return
+			return Unconnected, true
 		}
-	case syscall.IPPROTO_UDP:
-		switch procsock.State {
+	case syscall.IPPROTO_SCTP:
+		// SCTP endpoint socket states reuse TCP socket states, and we map the
+		// SCTP association states onto TCP socket states; see
+		// sctpAssociationState.
+		switch state {
+		case TCP_LISTEN:
+			return Listening, true
+		case TCP_ESTABLISHED, TCP_FIN_WAIT1, TCP_FIN_WAIT2:
+			return Connected, true
+		case TCP_CLOSE, TCP_CLOSE_WAIT, TCP_LAST_ACK, TCP_SYN_SENT:
+			return Unconnected, true
+		}
+	case syscall.IPPROTO_UDP, syscall.IPPROTO_UDPLITE, syscall.IPPROTO_RAW:
+		switch state {
 		case UDP_LISTEN:
-			procsock.SimplifiedState = Listening
+			return Listening, true
 		case UDP_ESTABLISHED:
-			procsock.SimplifiedState = Connected
-		default:
-			procsock.State = 0
-			return
+			return Connected, true
 		}
-	default:
-		procsock.State = 0
-		return
 	}
-	procsock.Family = AddressFamily(af)
-	procsock.Protocol = Protocol(proto)
-	return
+	return Unconnected, false
 }
 
 // discoverAllSockInodes returns a map of the inodes-to-PID for all sockets that
-// currently exist in the system.
+// currently exist in the system, using a fresh socket inode index instead of
+// the shared one.
 func discoverAllSockInodes(procroot string) socketToProcessMap {
-	procentries, err := ioutil.ReadDir(procroot)
-	if err != nil {
-		return nil
-	}
-	spm := socketToProcessMap{}
-	for _, procentry := range procentries {
-		// Get the process PID as a number.
-		pid, err := strconv.ParseInt(procentry.Name(), 10, 32)
-		if err != nil || pid <= 0 {
-			continue
-		}
-		//
-		fdbasepath := procroot + "/" + procentry.Name() + "/fd"
-		fdentries, err := ioutil.ReadDir(fdbasepath)
-		if err != nil {
-			continue
-		}
-		for _, fdentry := range fdentries {
-			stat, err := os.Stat(fdbasepath + "/" + fdentry.Name())
-			if err != nil || stat.Mode()&os.ModeSocket == 0 {
-				continue
-			}
-			sysstat, ok := stat.Sys().(*syscall.Stat_t)
-			if !ok {
-				continue
-			}
-			fdino := sysstat.Ino
-			if pids, ok := spm[fdino]; ok {
-				spm[fdino] = append(pids, model.PIDType(pid))
-			} else {
-				spm[fdino] = []model.PIDType{model.PIDType(pid)}
-			}
-		}
-	}
-	return spm
+	return newSocketInodeIndex(osFiles{}, procroot).socketToProcessMap(
+		model.NewProcessTableFromProcfs(false, false, procroot))
 }
 
 // decodeSockAddrPort returns the IP (v4/v6) address and port number encoded in
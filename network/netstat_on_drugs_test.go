@@ -179,15 +179,18 @@ var _ = Describe("discovers transport ports", func() {
 		})
 
 		It("handles empty socket information", func() {
-			Expect(discoverSockets("./test/proc", 0, unix.AF_INET6, syscall.IPPROTO_TCP, nil)).To(BeEmpty())
+			Expect(discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET6, syscall.IPPROTO_TCP, nil)).To(BeEmpty())
+			Expect(discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET, syscall.IPPROTO_UDPLITE, nil)).To(BeEmpty())
+			Expect(discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET, syscall.IPPROTO_RAW, nil)).To(BeEmpty())
+			Expect(discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET, syscall.IPPROTO_SCTP, nil)).To(BeEmpty())
 		})
 
 		It("discoverSockets() panics on nonsense address families and transport protocols", func() {
 			Expect(func() {
-				_ = discoverSockets("./test/proc", 0, unix.AF_APPLETALK, syscall.IPPROTO_TCP, nil)
+				_ = discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_APPLETALK, syscall.IPPROTO_TCP, nil)
 			}).To(Panic())
 			Expect(func() {
-				_ = discoverSockets("./test/proc", 0, unix.AF_INET, syscall.IPPROTO_ICMP, nil)
+				_ = discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET, syscall.IPPROTO_ICMP, nil)
 			}).To(Panic())
 		})
 
@@ -195,10 +198,10 @@ var _ = Describe("discovers transport ports", func() {
 			sm := socketToProcessMap{
 				71934: []model.PIDType{666},
 			}
-			sox := discoverSockets("./test/proc", 0, unix.AF_INET, syscall.IPPROTO_TCP, sm)
+			sox := discoverSockets(osFiles{}, "./test/proc", 0, unix.AF_INET, syscall.IPPROTO_TCP, sm)
 			Expect(sox).To(BeEmpty())
 
-			sox = discoverSockets("./test/proc", 666, unix.AF_INET, syscall.IPPROTO_TCP, sm)
+			sox = discoverSockets(osFiles{}, "./test/proc", 666, unix.AF_INET, syscall.IPPROTO_TCP, sm)
 			Expect(sox).To(ConsistOf(
 				MatchFields(IgnoreExtras, Fields{
 					"LocalIP": Equal(net.ParseIP("127.0.0.1").To4()),
@@ -208,7 +211,7 @@ var _ = Describe("discovers transport ports", func() {
 				}),
 			))
 
-			sox = discoverSockets("./test/proc", 666, unix.AF_INET6, syscall.IPPROTO_UDP, sm)
+			sox = discoverSockets(osFiles{}, "./test/proc", 666, unix.AF_INET6, syscall.IPPROTO_UDP, sm)
 			Expect(sox).To(ConsistOf(
 				MatchFields(IgnoreExtras, Fields{
 					"LocalIP": Equal(net.ParseIP("127.0.0.10").To4()),
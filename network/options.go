@@ -0,0 +1,125 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/thediveo/lxkns/species"
+)
+
+// DiscoveryOption configures the discovery of network namespaces by
+// NewNetworkNamespaces.
+type DiscoveryOption func(*discoveryOptions)
+
+// discoveryOptions is the configuration of a network namespace discovery.
+type discoveryOptions struct {
+	ctx                context.Context                  // stops discovering further network namespaces when done.
+	maxWorkers         int                              // maximum number of concurrent discovery workers.
+	netns              map[species.NamespaceID]struct{} // network namespaces to discover; nil for all.
+	skipSockets        bool                             // skip discovering sockets and their processes.
+	skipForwardedPorts bool                             // skip discovering forwarded ports.
+	skipDNS            bool                             // skip discovering the DNS configuration of tenants.
+	diags              *diagnostics.Diagnostics         // collects discovery problems; might be nil.
+	source             Source                           // supplies the system information to discover from.
+}
+
+// newDiscoveryOptions returns the discovery configuration with the specified
+// options applied to the defaults.
+func newDiscoveryOptions(opts ...DiscoveryOption) discoveryOptions {
+	dopts := discoveryOptions{}
+	for _, opt := range opts {
+		opt(&dopts)
+	}
+	if dopts.ctx == nil {
+		dopts.ctx = context.Background()
+	}
+	if dopts.source == nil {
+		dopts.source = live
+	}
+	if dopts.maxWorkers <= 0 {
+		dopts.maxWorkers = runtime.GOMAXPROCS(0)
+	}
+	return dopts
+}
+
+// WithMaxWorkers limits the number of network namespaces concurrently being
+// discovered. A limit of zero or less means as many workers as there are CPUs
+// usable by this process.
+func WithMaxWorkers(n int) DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.maxWorkers = n
+	}
+}
+
+// WithContext stops discovering further network namespaces as soon as the
+// specified context is done, such as when it times out. Network namespaces
+// already being discovered at this time are still finished. Network
+// namespaces not discovered at all are left out of the discovery result, while
+// network namespaces only partially discovered lack the details of the
+// remaining discovery steps.
+func WithContext(ctx context.Context) DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithNetworkNamespaces restricts the discovery to only the specified network
+// namespaces, ignoring all other network namespaces. Relations to network
+// namespaces outside this set thus stay unresolved, such as the peers of VETH
+// pairs. Also, only the processes attached to the specified network
+// namespaces are considered as socket owners.
+//
+// Passing no network namespace IDs at all restricts the discovery to no
+// network namespaces at all.
+func WithNetworkNamespaces(ids ...species.NamespaceID) DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.netns = make(map[species.NamespaceID]struct{}, len(ids))
+		for _, id := range ids {
+			o.netns[id] = struct{}{}
+		}
+	}
+}
+
+// WithDiagnostics collects the problems encountered while discovering network
+// namespaces in the specified diagnostics, in addition to logging them.
+func WithDiagnostics(d *diagnostics.Diagnostics) DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.diags = d
+	}
+}
+
+// WithoutSockets skips discovering transport-layer ports, MPTCP connections,
+// packet sockets, Unix domain sockets, as well as their owning processes.
+func WithoutSockets() DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.skipSockets = true
+	}
+}
+
+// WithoutForwardedPorts skips discovering forwarded ports.
+func WithoutForwardedPorts() DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.skipForwardedPorts = true
+	}
+}
+
+// WithoutDNS skips discovering the DNS-related configuration of tenants, such
+// as their hosts files and name servers.
+func WithoutDNS() DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.skipDNS = true
+	}
+}
+
+// WithSource discovers from the specified Source instead of the live system,
+// such as when replaying a support bundle.
+func WithSource(src Source) DiscoveryOption {
+	return func(o *discoveryOptions) {
+		o.source = src
+	}
+}
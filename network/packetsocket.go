@@ -0,0 +1,232 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// packet_diag request "show" flags and attributes; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/packet_diag.h
+const (
+	packetShowInfo   = 0x00000001
+	packetShowFilter = 0x00000020
+
+	packetDiagInfo   = 0
+	packetDiagUID    = 5
+	packetDiagFilter = 7
+
+	pdiRunning = 0x1
+)
+
+// Sizes of struct packet_diag_req, struct packet_diag_msg and struct
+// packet_diag_info.
+const (
+	sizeofPacketDiagReq  = 20
+	sizeofPacketDiagMsg  = 16
+	sizeofPacketDiagInfo = 24
+)
+
+// PacketSocket describes an AF_PACKET socket, which allows processes to
+// receive and send link-layer packets, such as when sniffing or injecting
+// traffic.
+type PacketSocket struct {
+	Type             int              // socket type: unix.SOCK_RAW or unix.SOCK_DGRAM.
+	Protocol         uint16           // Ethernet protocol, such as unix.ETH_P_ALL; 0 if not receiving.
+	Nif              Interface        // network interface bound to; nil if all network interfaces.
+	Running          bool             // socket is hooked into the packet receive path.
+	Filtered         bool             // socket has a (classic) BPF filter attached; only via sock_diag.
+	Inode            uint64           // socket inode number
+	UID              uint32           // (effective) UID of the socket's creator
+	PIDs             []model.PIDType  // processes using this socket
+	Processes        []*model.Process // processes using this socket
+	ForeignProcesses []*model.Process // owning processes attached to other network namespaces
+	ifindex          int
+}
+
+// packetDiagReq is the struct packet_diag_req request for dumping all packet
+// sockets.
+type packetDiagReq struct {
+	Show uint32
+}
+
+// Len returns the length of a serialized packet_diag_req.
+func (r *packetDiagReq) Len() int { return sizeofPacketDiagReq }
+
+// Serialize returns the binary packet_diag_req representation for dumping all
+// packet sockets.
+func (r *packetDiagReq) Serialize() []byte {
+	b := make([]byte, sizeofPacketDiagReq)
+	b[0] = unix.AF_PACKET
+	nl.NativeEndian().PutUint32(b[8:], r.Show)
+	return b
+}
+
+// discoverPacketSockets discovers the AF_PACKET sockets in this network
+// namespace, preferably using the sock_diag netlink API, and otherwise falling
+// back onto procfs. The packet sockets are then related to their network
+// interfaces and processes.
+func (n *NetworkNamespace) discoverPacketSockets(sm socketToProcessMap, allprocs model.ProcessTable) {
+	var sox []PacketSocket
+	src := sourceOf(n)
+	err := src.Visit(n.Namespace, func(nsa NetnsAccess) (err error) {
+		sox, err = diagPacketSockets(nsa, sm)
+		return
+	})
+	if err != nil {
+		if n.Ealdorman() == nil {
+			return
+		}
+		sox = discoverProcfsPacketSockets(src, "/proc", n.Ealdorman().PID, sm)
+	}
+	for idx := range sox {
+		if sox[idx].ifindex != 0 {
+			sox[idx].Nif = n.Nifs[sox[idx].ifindex]
+		}
+		sox[idx].Processes = allprocs.ProcessesByPIDs(sox[idx].PIDs...)
+	}
+	n.PacketSockets = sox
+}
+
+// diagPacketSockets dumps the packet sockets in the visited network namespace.
+func diagPacketSockets(nsa NetnsAccess, sm socketToProcessMap) ([]PacketSocket, error) {
+	var msgs [][]byte
+	var err error
+	for attempt := 0; attempt < maxSockDiagDumpAttempts; attempt++ {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, unix.NLM_F_DUMP)
+		req.AddData(&packetDiagReq{Show: packetShowInfo | packetShowFilter})
+		msgs, err = nsa.Execute(req, unix.NETLINK_SOCK_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	sox := make([]PacketSocket, 0, len(msgs))
+	for _, msg := range msgs {
+		if packsock, ok := newDiagPacketSocket(msg, sm); ok {
+			sox = append(sox, packsock)
+		}
+	}
+	return sox, nil
+}
+
+// newDiagPacketSocket returns a PacketSocket with the information from a
+// sock_diag packet_diag_msg message, including its attributes.
+func newDiagPacketSocket(msg []byte, sm socketToProcessMap) (PacketSocket, bool) {
+	if len(msg) < sizeofPacketDiagMsg || msg[0] != unix.AF_PACKET {
+		return PacketSocket{}, false
+	}
+	packsock := PacketSocket{
+		Type:     int(msg[1]),
+		Protocol: nl.NativeEndian().Uint16(msg[2:]),
+		Inode:    uint64(nl.NativeEndian().Uint32(msg[4:])),
+	}
+	packsock.PIDs = sm[packsock.Inode]
+	attrs, err := nl.ParseRouteAttr(msg[sizeofPacketDiagMsg:])
+	if err != nil {
+		return packsock, true
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case packetDiagInfo:
+			if len(attr.Value) >= sizeofPacketDiagInfo {
+				packsock.ifindex = int(nl.NativeEndian().Uint32(attr.Value))
+				packsock.Running = nl.NativeEndian().Uint32(attr.Value[20:])&pdiRunning != 0
+			}
+		case packetDiagUID:
+			if len(attr.Value) >= 4 {
+				packsock.UID = nl.NativeEndian().Uint32(attr.Value)
+			}
+		case packetDiagFilter:
+			packsock.Filtered = len(attr.Value) != 0
+		}
+	}
+	return packsock, true
+}
+
+// discoverProcfsPacketSockets discovers the packet sockets in a network
+// namespace referenced via one of the processes attached to the network
+// namespace.
+func discoverProcfsPacketSockets(files Files, procroot string, pid model.PIDType, sm socketToProcessMap) []PacketSocket {
+	contents, err := files.ReadFile(fmt.Sprintf("%s/%d/net/packet", procroot, pid))
+	if err != nil {
+		return []PacketSocket{}
+	}
+	return parseProcfsPacketSockets(bytes.NewReader(contents), sm)
+}
+
+// parseProcfsPacketSockets parses the /proc/net/packet format:
+//
+//	sk               RefCnt Type Proto  Iface R Rmem   User   Inode
+//	ffff8d1d4b2e1000 3      3    0003   2     1 0      0      12345
+func parseProcfsPacketSockets(r io.Reader, sm socketToProcessMap) []PacketSocket {
+	sox := []PacketSocket{}
+	scanner := bufio.NewScanner(r)
+	// Skip the first "header" line.
+	if !scanner.Scan() {
+		return sox
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 {
+			continue
+		}
+		socktype, err := strconv.ParseUint(fields[2], 10, 8)
+		if err != nil {
+			continue
+		}
+		proto, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil {
+			continue
+		}
+		ifindex, err := strconv.ParseUint(fields[4], 10, 31)
+		if err != nil {
+			continue
+		}
+		uid, err := strconv.ParseUint(fields[7], 10, 32)
+		if err != nil {
+			continue
+		}
+		ino, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			continue
+		}
+		sox = append(sox, PacketSocket{
+			Type:     int(socktype),
+			Protocol: uint16(proto),
+			Running:  fields[5] == "1",
+			Inode:    ino,
+			UID:      uint32(uid),
+			PIDs:     sm[ino],
+			ifindex:  int(ifindex),
+		})
+	}
+	return sox
+}
+
+// PacketSocketTypeName returns the name of a packet socket type, that is,
+// either "raw" or "dgram".
+func PacketSocketTypeName(socktype int) string {
+	switch socktype {
+	case syscall.SOCK_RAW:
+		return "raw"
+	case syscall.SOCK_DGRAM:
+		return "dgram"
+	}
+	return fmt.Sprintf("SocketType(%d)", socktype)
+}
@@ -0,0 +1,118 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"strings"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("packet sockets", func() {
+
+	It("serializes requests", func() {
+		req := &packetDiagReq{Show: packetShowInfo}
+		b := req.Serialize()
+		Expect(b).To(HaveLen(req.Len()))
+		Expect(b[0]).To(Equal(byte(unix.AF_PACKET)))
+		Expect(nl.NativeEndian().Uint32(b[8:])).To(Equal(uint32(packetShowInfo)))
+	})
+
+	It("parses sock_diag packet sockets", func() {
+		_, ok := newDiagPacketSocket([]byte{unix.AF_PACKET}, nil)
+		Expect(ok).To(BeFalse())
+
+		msg := make([]byte, sizeofPacketDiagMsg)
+		msg[0] = unix.AF_PACKET
+		msg[1] = syscall.SOCK_RAW
+		nl.NativeEndian().PutUint16(msg[2:], unix.ETH_P_ALL)
+		nl.NativeEndian().PutUint32(msg[4:], 12345)
+		info := make([]byte, sizeofPacketDiagInfo)
+		nl.NativeEndian().PutUint32(info, 2)
+		nl.NativeEndian().PutUint32(info[20:], pdiRunning)
+		msg = append(msg, nl.NewRtAttr(packetDiagInfo, info).Serialize()...)
+		msg = append(msg, nl.NewRtAttr(packetDiagUID, nl.Uint32Attr(1000)).Serialize()...)
+		msg = append(msg, nl.NewRtAttr(packetDiagFilter, []byte{1, 2, 3, 4, 5, 6, 7, 8}).Serialize()...)
+
+		packsock, ok := newDiagPacketSocket(msg, socketToProcessMap{12345: []model.PIDType{42}})
+		Expect(ok).To(BeTrue())
+		Expect(packsock).To(MatchFields(IgnoreExtras, Fields{
+			"Type":     Equal(syscall.SOCK_RAW),
+			"Protocol": Equal(uint16(unix.ETH_P_ALL)),
+			"Running":  BeTrue(),
+			"Filtered": BeTrue(),
+			"Inode":    Equal(uint64(12345)),
+			"UID":      Equal(uint32(1000)),
+			"PIDs":     ConsistOf(model.PIDType(42)),
+		}))
+		Expect(packsock.ifindex).To(Equal(2))
+	})
+
+	It("parses procfs packet sockets", func() {
+		sox := parseProcfsPacketSockets(strings.NewReader(
+			"sk               RefCnt Type Proto  Iface R Rmem   User   Inode\n"+
+				"ffff8d1d4b2e1000 3      3    0003   2     1 0      0      12345\n"+
+				"ffff8d1d4b2e2000 3      2    88cc   0     0 0      1000   23456\n"+
+				"garbage\n"),
+			socketToProcessMap{23456: []model.PIDType{42}})
+		Expect(sox).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{
+				"Type":     Equal(syscall.SOCK_RAW),
+				"Protocol": Equal(uint16(unix.ETH_P_ALL)),
+				"Running":  BeTrue(),
+				"Inode":    Equal(uint64(12345)),
+			}),
+			MatchFields(IgnoreExtras, Fields{
+				"Type":     Equal(syscall.SOCK_DGRAM),
+				"Protocol": Equal(uint16(unix.ETH_P_LLDP)),
+				"Running":  BeFalse(),
+				"UID":      Equal(uint32(1000)),
+				"PIDs":     ConsistOf(model.PIDType(42)),
+			}),
+		))
+		Expect(sox[0].ifindex).To(Equal(2))
+		Expect(discoverProcfsPacketSockets(osFiles{}, "./test/proc", 0, nil)).To(BeEmpty())
+	})
+
+	It("names packet socket types", func() {
+		Expect(PacketSocketTypeName(syscall.SOCK_RAW)).To(Equal("raw"))
+		Expect(PacketSocketTypeName(syscall.SOCK_DGRAM)).To(Equal("dgram"))
+		Expect(PacketSocketTypeName(42)).To(Equal("SocketType(42)"))
+	})
+
+	It("discovers packet sockets in the current network namespace", func() {
+		if unix.Geteuid() != 0 {
+			Skip("needs root")
+		}
+		fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ALL)))
+		Expect(err).NotTo(HaveOccurred())
+		defer unix.Close(fd)
+		var stat unix.Stat_t
+		Expect(unix.Fstat(fd, &stat)).To(Succeed())
+
+		sox, err := diagPacketSockets(&liveNetnsAccess{ethtoolFd: -1}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sox).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Inode":    Equal(stat.Ino),
+			"Protocol": Equal(uint16(unix.ETH_P_ALL)),
+			"Running":  BeTrue(),
+		})))
+	})
+
+})
+
+// htons converts a uint16 from host to network byte order.
+func htons(v uint16) uint16 {
+	b := make([]byte, 2)
+	nl.NativeEndian().PutUint16(b, v)
+	return uint16(b[0])<<8 | uint16(b[1])
+}
@@ -0,0 +1,213 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtocolCounters is a sample of the protocol-level counters of a network
+// namespace, as kept by the Linux kernel's network stack and shown in
+// /proc/net/snmp, /proc/net/snmp6, /proc/net/netstat, as well as socket usage
+// from /proc/net/sockstat and /proc/net/sockstat6.
+type ProtocolCounters struct {
+	Timestamp time.Time                   // when this sample was taken.
+	Counters  map[string]map[string]int64 // SNMP MIB and extended counters by group ("Tcp", "TcpExt", "Ip6", ...) and name.
+	Sockstat  map[string]map[string]int64 // socket usage by protocol ("sockets", "TCP", "UDP6", ...) and name.
+}
+
+// ProtocolCountersDelta contains the differences between two protocol counter
+// samples of the same network namespace. Only counters that changed are
+// included.
+type ProtocolCountersDelta struct {
+	Interval time.Duration               // time between the two samples.
+	Counters map[string]map[string]int64 // changed counters by group and name.
+	Sockstat map[string]map[string]int64 // changed socket usage by protocol and name.
+}
+
+// snmp6Groups lists the prefixes of the /proc/net/snmp6 counter names that
+// we map onto groups, in order to align them with the IPv4 groups. Please
+// note that "UdpLite6" must come before "Udp6".
+var snmp6Groups = []string{"Ip6", "Icmp6", "UdpLite6", "Udp6"}
+
+// Counter returns the value of the specified protocol counter, such as group
+// "TcpExt" and name "ListenOverflows", and true if the counter exists.
+func (c *ProtocolCounters) Counter(group, name string) (int64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	value, ok := c.Counters[group][name]
+	return value, ok
+}
+
+// Delta returns the changes of the protocol counters since the specified
+// earlier sample.
+func (c *ProtocolCounters) Delta(earlier *ProtocolCounters) *ProtocolCountersDelta {
+	return &ProtocolCountersDelta{
+		Interval: c.Timestamp.Sub(earlier.Timestamp),
+		Counters: deltaCounters(c.Counters, earlier.Counters),
+		Sockstat: deltaCounters(c.Sockstat, earlier.Sockstat),
+	}
+}
+
+// deltaCounters returns the changed counters, where counters missing from the
+// earlier sample are considered to have been zero.
+func deltaCounters(now, earlier map[string]map[string]int64) map[string]map[string]int64 {
+	deltas := map[string]map[string]int64{}
+	for group, counters := range now {
+		for name, value := range counters {
+			delta := value - earlier[group][name]
+			if delta == 0 {
+				continue
+			}
+			groupdeltas, ok := deltas[group]
+			if !ok {
+				groupdeltas = map[string]int64{}
+				deltas[group] = groupdeltas
+			}
+			groupdeltas[name] = delta
+		}
+	}
+	return deltas
+}
+
+// SampleProtocolCounters returns a fresh sample of the protocol-level
+// counters of this network namespace.
+func (n *NetworkNamespace) SampleProtocolCounters() (*ProtocolCounters, error) {
+	var counters *ProtocolCounters
+	if err := sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
+		// As we are now running on a thread attached to the network namespace
+		// in question, "thread-self" gives us the correct view.
+		counters = readProtocolCounters(nsa, "/proc/thread-self/net")
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// readProtocolCounters reads the protocol counters from the snmp, snmp6,
+// netstat, sockstat and sockstat6 files in the specified directory. Missing
+// files are skipped, such as snmp6 when IPv6 has been disabled.
+func readProtocolCounters(files Files, netdir string) *ProtocolCounters {
+	counters := &ProtocolCounters{
+		Timestamp: time.Now(),
+		Counters:  map[string]map[string]int64{},
+		Sockstat:  map[string]map[string]int64{},
+	}
+	for _, f := range []struct {
+		name   string
+		parser func(io.Reader, map[string]map[string]int64)
+		into   map[string]map[string]int64
+	}{
+		{"snmp", parseSNMP, counters.Counters},
+		{"netstat", parseSNMP, counters.Counters},
+		{"snmp6", parseSNMP6, counters.Counters},
+		{"sockstat", parseSockstat, counters.Sockstat},
+		{"sockstat6", parseSockstat, counters.Sockstat},
+	} {
+		contents, err := files.ReadFile(netdir + "/" + f.name)
+		if err != nil {
+			continue
+		}
+		f.parser(bytes.NewReader(contents), f.into)
+	}
+	return counters
+}
+
+// parseSNMP parses the /proc/net/snmp and /proc/net/netstat formats, where a
+// header line with the counter names is followed by a line with the counter
+// values, both lines starting with the same group name:
+//
+//	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ...
+//	Tcp: 1 200 120000 -1 ...
+func parseSNMP(r io.Reader, counters map[string]map[string]int64) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024)
+	for scanner.Scan() {
+		names := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			return
+		}
+		values := strings.Fields(scanner.Text())
+		if len(names) == 0 || len(names) != len(values) || names[0] != values[0] {
+			continue
+		}
+		group := strings.TrimSuffix(names[0], ":")
+		groupcounters, ok := counters[group]
+		if !ok {
+			groupcounters = map[string]int64{}
+			counters[group] = groupcounters
+		}
+		for idx := 1; idx < len(names); idx++ {
+			groupcounters[names[idx]] = parseCounter(values[idx])
+		}
+	}
+}
+
+// parseSNMP6 parses the /proc/net/snmp6 format of name-value pairs, one per
+// line. The counter names are split into their group and name.
+//
+//	Ip6InReceives                   	3
+func parseSNMP6(r io.Reader, counters map[string]map[string]int64) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		group, name := "Snmp6", fields[0]
+		for _, prefix := range snmp6Groups {
+			if strings.HasPrefix(fields[0], prefix) {
+				group, name = prefix, fields[0][len(prefix):]
+				break
+			}
+		}
+		groupcounters, ok := counters[group]
+		if !ok {
+			groupcounters = map[string]int64{}
+			counters[group] = groupcounters
+		}
+		groupcounters[name] = parseCounter(fields[1])
+	}
+}
+
+// parseSockstat parses the /proc/net/sockstat and /proc/net/sockstat6 formats
+// of protocol names followed by name-value pairs:
+//
+//	TCP: inuse 4 orphan 0 tw 0 alloc 4 mem 0
+func parseSockstat(r io.Reader, sockstat map[string]map[string]int64) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		proto := strings.TrimSuffix(fields[0], ":")
+		protostat, ok := sockstat[proto]
+		if !ok {
+			protostat = map[string]int64{}
+			sockstat[proto] = protostat
+		}
+		for idx := 1; idx+1 < len(fields); idx += 2 {
+			protostat[fields[idx]] = parseCounter(fields[idx+1])
+		}
+	}
+}
+
+// parseCounter returns the counter value in s, or zero. Most counters are
+// unsigned, but a few are signed, such as Tcp MaxConn.
+func parseCounter(s string) int64 {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v
+	}
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return int64(v) // #nosec G115 -- wrap-around is fine for deltas.
+}
@@ -0,0 +1,125 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("protocol counters", func() {
+
+	It("parses snmp and netstat counters", func() {
+		counters := map[string]map[string]int64{}
+		parseSNMP(strings.NewReader(
+			"Tcp: RtoAlgorithm RtoMin MaxConn RetransSegs\n"+
+				"Tcp: 1 200 -1 42\n"+
+				"Udp: InDatagrams InErrors\n"+
+				"Udp: 100\n"+
+				"TcpExt: ListenOverflows ListenDrops\n"+
+				"TcpExt: 7 18446744073709551615\n"), counters)
+		Expect(counters).To(Equal(map[string]map[string]int64{
+			"Tcp": {
+				"RtoAlgorithm": 1,
+				"RtoMin":       200,
+				"MaxConn":      -1,
+				"RetransSegs":  42,
+			},
+			"TcpExt": {
+				"ListenOverflows": 7,
+				"ListenDrops":     -1,
+			},
+		}))
+	})
+
+	It("parses snmp6 counters", func() {
+		counters := map[string]map[string]int64{}
+		parseSNMP6(strings.NewReader(
+			"Ip6InReceives                   \t3\n"+
+				"Icmp6InType133                  \t2\n"+
+				"Udp6InDatagrams                 \t5\n"+
+				"UdpLite6InDatagrams             \t6\n"+
+				"Foo                             \t7\n"+
+				"garbage\n"), counters)
+		Expect(counters).To(Equal(map[string]map[string]int64{
+			"Ip6":      {"InReceives": 3},
+			"Icmp6":    {"InType133": 2},
+			"Udp6":     {"InDatagrams": 5},
+			"UdpLite6": {"InDatagrams": 6},
+			"Snmp6":    {"Foo": 7},
+		}))
+	})
+
+	It("parses sockstats", func() {
+		sockstat := map[string]map[string]int64{}
+		parseSockstat(strings.NewReader(
+			"sockets: used 18\n"+
+				"TCP: inuse 4 orphan 0 tw 1 alloc 4 mem 2\n"+
+				"garbage\n"+
+				"TCP6: inuse 3\n"), sockstat)
+		Expect(sockstat).To(Equal(map[string]map[string]int64{
+			"sockets": {"used": 18},
+			"TCP":     {"inuse": 4, "orphan": 0, "tw": 1, "alloc": 4, "mem": 2},
+			"TCP6":    {"inuse": 3},
+		}))
+	})
+
+	It("reads protocol counters, skipping missing files", func() {
+		netdir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(netdir, "netstat"),
+			[]byte("TcpExt: ListenOverflows\nTcpExt: 666\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(netdir, "sockstat"),
+			[]byte("UDP: inuse 1 mem 2\n"), 0644)).To(Succeed())
+		counters := readProtocolCounters(osFiles{}, netdir)
+		Expect(counters.Timestamp).NotTo(BeZero())
+		value, ok := counters.Counter("TcpExt", "ListenOverflows")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(int64(666)))
+		_, ok = counters.Counter("Tcp", "RetransSegs")
+		Expect(ok).To(BeFalse())
+		Expect(counters.Sockstat).To(HaveKeyWithValue("UDP", HaveKeyWithValue("mem", int64(2))))
+
+		_, ok = (*ProtocolCounters)(nil).Counter("TcpExt", "ListenOverflows")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("compares samples", func() {
+		now := time.Now()
+		earlier := &ProtocolCounters{
+			Timestamp: now.Add(-5 * time.Second),
+			Counters: map[string]map[string]int64{
+				"TcpExt": {"ListenOverflows": 10, "ListenDrops": 10},
+			},
+			Sockstat: map[string]map[string]int64{
+				"TCP": {"inuse": 5},
+			},
+		}
+		later := &ProtocolCounters{
+			Timestamp: now,
+			Counters: map[string]map[string]int64{
+				"TcpExt": {"ListenOverflows": 15, "ListenDrops": 10},
+				"Tcp":    {"RetransSegs": 3},
+			},
+			Sockstat: map[string]map[string]int64{
+				"TCP": {"inuse": 2},
+			},
+		}
+		delta := later.Delta(earlier)
+		Expect(delta.Interval).To(Equal(5 * time.Second))
+		Expect(delta.Counters).To(Equal(map[string]map[string]int64{
+			"TcpExt": {"ListenOverflows": 5},
+			"Tcp":    {"RetransSegs": 3},
+		}))
+		Expect(delta.Sockstat).To(Equal(map[string]map[string]int64{
+			"TCP": {"inuse": -3},
+		}))
+	})
+
+})
@@ -0,0 +1,338 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// inet_diag attributes we're interested in; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/inet_diag.h#L143
+const (
+	inetDiagInfo     = 2
+	inetDiagLocals   = 12
+	inetDiagPeers    = 13
+	inetDiagMark     = 15
+	inetDiagULPInfo  = 19
+	inetDiagCgroupID = 21
+)
+
+// SCTP association states; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/net/sctp/constants.h#L174
+const (
+	sctpStateClosed           = 0
+	sctpStateCookieWait       = 1
+	sctpStateCookieEchoed     = 2
+	sctpStateEstablished      = 3
+	sctpStateShutdownPending  = 4
+	sctpStateShutdownSent     = 5
+	sctpStateShutdownReceived = 6
+	sctpStateAckSent          = 7
+)
+
+// sizeofSockaddrStorage is the size of the struct sockaddr_storage elements in
+// the INET_DIAG_LOCALS and INET_DIAG_PEERS attributes of SCTP sockets.
+const sizeofSockaddrStorage = 128
+
+// diagProtocols lists the transport protocols we discover sockets for via
+// sock_diag.
+var diagProtocols = []int{
+	syscall.IPPROTO_TCP,
+	syscall.IPPROTO_UDP,
+	syscall.IPPROTO_UDPLITE,
+	syscall.IPPROTO_SCTP,
+	syscall.IPPROTO_RAW,
+}
+
+// Sizes of struct inet_diag_req_v2 and struct inet_diag_msg, as well as
+// offsets into struct inet_diag_msg.
+const (
+	sizeofInetDiagReqV2 = 56
+	sizeofInetDiagMsg   = 72
+
+	inetDiagMsgSport  = 4
+	inetDiagMsgDport  = 6
+	inetDiagMsgSrc    = 8
+	inetDiagMsgDst    = 24
+	inetDiagMsgRqueue = 56
+	inetDiagMsgWqueue = 60
+	inetDiagMsgUID    = 64
+	inetDiagMsgInode  = 68
+)
+
+// maxSockDiagDumpAttempts limits how often we retry a socket dump that the
+// kernel has reported as inconsistent because sockets came and went while
+// dumping.
+const maxSockDiagDumpAttempts = 3
+
+// TCPInfo contains selected TCP connection details from the kernel's struct
+// tcp_info.
+type TCPInfo struct {
+	RTT           time.Duration // smoothed round trip time
+	RTTVar        time.Duration // round trip time variance
+	MinRTT        time.Duration // minimum round trip time seen
+	RTO           time.Duration // retransmission timeout
+	SndMSS        uint32        // send maximum segment size
+	RcvMSS        uint32        // receive maximum segment size
+	SndCwnd       uint32        // congestion window, in segments
+	SndSsthresh   uint32        // slow start threshold, in segments
+	Unacked       uint32        // unacknowledged segments in flight
+	Lost          uint32        // segments considered to be lost
+	Retrans       uint32        // segments currently being retransmitted
+	Retransmits   uint8         // number of consecutive retransmission timeouts
+	TotalRetrans  uint32        // total number of retransmitted segments
+	BytesAcked    uint64        // bytes acknowledged by the peer
+	BytesReceived uint64        // bytes received from the peer
+}
+
+// inetDiagReqV2 is the struct inet_diag_req_v2 request for dumping all
+// sockets of a particular address family and transport protocol.
+type inetDiagReqV2 struct {
+	Family      uint8
+	Protocol    uint8
+	Ext         uint8
+	RawProtocol uint8 // only for IPPROTO_RAW: raw protocol to dump, IPPROTO_RAW for all.
+	States      uint32
+}
+
+// Len returns the length of a serialized inet_diag_req_v2.
+func (r *inetDiagReqV2) Len() int { return sizeofInetDiagReqV2 }
+
+// Serialize returns the binary inet_diag_req_v2 representation; as we're
+// always dumping, the socket ID part is all zeros.
+func (r *inetDiagReqV2) Serialize() []byte {
+	b := make([]byte, sizeofInetDiagReqV2)
+	b[0] = r.Family
+	b[1] = r.Protocol
+	b[2] = r.Ext
+	b[3] = r.RawProtocol
+	nl.NativeEndian().PutUint32(b[4:], r.States)
+	return b
+}
+
+// sockKind identifies a combination of address family and transport protocol.
+type sockKind struct {
+	af    int
+	proto int
+}
+
+// discoverDiagSockets discovers the TCP, UDP, UDP-Lite, SCTP and raw IP
+// sockets in this network namespace using the sock_diag netlink API, returning
+// the sockets indexed by their address family and transport protocol. Socket
+// kinds that cannot be discovered, such as when the udp_diag kernel module
+// isn't available, are missing from the returned map, so that the caller can
+// fall back onto procfs.
+//
+// Please note that the following ProcessSocket fields are not set, but instead
+// need to be resolved by the caller: Nifs, Processes.
+func (n *NetworkNamespace) discoverDiagSockets(sm socketToProcessMap) map[sockKind][]ProcessSocket {
+	sox := map[sockKind][]ProcessSocket{}
+	_ = sourceOf(n).Visit(n.Namespace, func(nsa NetnsAccess) error {
+		for _, proto := range diagProtocols {
+			for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+				ports, err := diagSockets(nsa, af, proto, sm)
+				if err != nil {
+					continue
+				}
+				sox[sockKind{af: af, proto: proto}] = ports
+			}
+		}
+		return nil
+	})
+	return sox
+}
+
+// diagSockets dumps the sockets of the specified address family and transport
+// protocol in the visited network namespace.
+func diagSockets(nsa NetnsAccess, af int, proto int, sm socketToProcessMap) ([]ProcessSocket, error) {
+	var msgs [][]byte
+	var err error
+	for attempt := 0; attempt < maxSockDiagDumpAttempts; attempt++ {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, unix.NLM_F_DUMP)
+		diagreq := &inetDiagReqV2{
+			Family:   uint8(af),
+			Protocol: uint8(proto),
+			Ext:      1 << (inetDiagInfo - 1),
+			States:   ^uint32(0), // all states
+		}
+		if proto == syscall.IPPROTO_RAW {
+			diagreq.RawProtocol = syscall.IPPROTO_RAW
+		}
+		req.AddData(diagreq)
+		msgs, err = nsa.Execute(req, unix.NETLINK_INET_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	sox := make([]ProcessSocket, 0, len(msgs))
+	for _, msg := range msgs {
+		if procsock := newDiagProcessSocket(msg, af, proto, sm); procsock.State != 0 {
+			sox = append(sox, procsock)
+		}
+	}
+	return sox, nil
+}
+
+// newDiagProcessSocket returns a ProcessSocket with the information from a
+// sock_diag inet_diag_msg message, including its attributes.
+func newDiagProcessSocket(msg []byte, af int, proto int, sm socketToProcessMap) (procsock ProcessSocket) {
+	if len(msg) < sizeofInetDiagMsg {
+		return
+	}
+	iplen := net.IPv4len
+	if af == unix.AF_INET6 {
+		iplen = net.IPv6len
+	}
+	procsock.LocalIP = net.IP(append([]byte{}, msg[inetDiagMsgSrc:inetDiagMsgSrc+iplen]...))
+	procsock.LocalPort = uint16(msg[inetDiagMsgSport])<<8 | uint16(msg[inetDiagMsgSport+1])
+	procsock.RemoteIP = net.IP(append([]byte{}, msg[inetDiagMsgDst:inetDiagMsgDst+iplen]...))
+	procsock.RemotePort = uint16(msg[inetDiagMsgDport])<<8 | uint16(msg[inetDiagMsgDport+1])
+	procsock.Inode = uint64(nl.NativeEndian().Uint32(msg[inetDiagMsgInode:]))
+	procsock.PIDs = sm[procsock.Inode]
+	procsock.UID = nl.NativeEndian().Uint32(msg[inetDiagMsgUID:])
+	attrs, err := nl.ParseRouteAttr(msg[sizeofInetDiagMsg:])
+	if err != nil {
+		attrs = nil
+	}
+	procsock.State = SocketState(msg[1])
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case inetDiagInfo:
+			if proto == syscall.IPPROTO_TCP {
+				procsock.TCPInfo = newTCPInfo(attr.Value)
+			}
+		case inetDiagULPInfo:
+			if proto == syscall.IPPROTO_TCP {
+				procsock.MPTCPSubflow = newMPTCPSubflow(attr.Value)
+			}
+		case inetDiagMark:
+			if len(attr.Value) >= 4 {
+				procsock.Mark = nl.NativeEndian().Uint32(attr.Value)
+			}
+		case inetDiagCgroupID:
+			if len(attr.Value) >= 8 {
+				procsock.CgroupID = nl.NativeEndian().Uint64(attr.Value)
+			}
+		case inetDiagLocals:
+			procsock.LocalAddrs = parseSockaddrs(attr.Value)
+		case inetDiagPeers:
+			procsock.RemoteAddrs = parseSockaddrs(attr.Value)
+			// Only SCTP associations have peers, and their state is an
+			// association state, not a socket state.
+			procsock.State = sctpAssociationState(msg[1])
+		}
+	}
+	simplified, ok := simplifySocketState(proto, procsock.State)
+	if !ok {
+		procsock.State = 0
+		return
+	}
+	procsock.SimplifiedState = simplified
+	procsock.setQueues(
+		nl.NativeEndian().Uint32(msg[inetDiagMsgRqueue:]),
+		nl.NativeEndian().Uint32(msg[inetDiagMsgWqueue:]),
+		proto)
+	procsock.Family = AddressFamily(af)
+	procsock.Protocol = Protocol(proto)
+	// SCTP doesn't report the primary addresses as part of the inet_diag_msg,
+	// so we then take the first addresses from the lists instead.
+	if procsock.LocalIP.IsUnspecified() && len(procsock.LocalAddrs) != 0 {
+		procsock.LocalIP = procsock.LocalAddrs[0]
+	}
+	if procsock.RemoteIP.IsUnspecified() && len(procsock.RemoteAddrs) != 0 {
+		procsock.RemoteIP = procsock.RemoteAddrs[0]
+	}
+	return
+}
+
+// sctpAssociationState returns the TCP socket state best matching the
+// specified SCTP association state.
+func sctpAssociationState(state uint8) SocketState {
+	switch state {
+	case sctpStateClosed:
+		return TCP_CLOSE
+	case sctpStateCookieWait, sctpStateCookieEchoed:
+		return TCP_SYN_SENT
+	case sctpStateEstablished:
+		return TCP_ESTABLISHED
+	case sctpStateShutdownPending:
+		return TCP_FIN_WAIT1
+	case sctpStateShutdownSent:
+		return TCP_FIN_WAIT2
+	case sctpStateShutdownReceived:
+		return TCP_CLOSE_WAIT
+	case sctpStateAckSent:
+		return TCP_LAST_ACK
+	}
+	return 0
+}
+
+// parseSockaddrs returns the IP addresses from a list of struct
+// sockaddr_storage elements, skipping any non-IP addresses. IPv4 addresses are
+// returned in their 4 byte representation.
+func parseSockaddrs(b []byte) []net.IP {
+	addrs := []net.IP{}
+	for ; len(b) >= sizeofSockaddrStorage; b = b[sizeofSockaddrStorage:] {
+		switch nl.NativeEndian().Uint16(b) {
+		case unix.AF_INET:
+			addrs = append(addrs, net.IP(append([]byte{}, b[4:4+net.IPv4len]...)))
+		case unix.AF_INET6:
+			addrs = append(addrs, net.IP(append([]byte{}, b[8:8+net.IPv6len]...)))
+		}
+	}
+	return addrs
+}
+
+// setQueues sets the receive and send queue lengths from an inet_diag_msg,
+// taking into account that for listening TCP sockets these instead are the
+// current length of the accept queue and the maximum listen backlog.
+func (p *ProcessSocket) setQueues(rqueue, wqueue uint32, proto int) {
+	p.RecvQueue = rqueue
+	if (proto == syscall.IPPROTO_TCP || proto == unix.IPPROTO_MPTCP) && p.State == TCP_LISTEN {
+		p.ListenBacklog = wqueue
+		return
+	}
+	p.SendQueue = wqueue
+}
+
+// newTCPInfo returns the TCP connection details from a binary struct tcp_info,
+// or nil. As the kernel's struct tcp_info has grown over time, we accept both
+// shorter as well as longer binary representations, and zero any fields
+// missing.
+func newTCPInfo(b []byte) *TCPInfo {
+	if len(b) == 0 {
+		return nil
+	}
+	var buf [unix.SizeofTCPInfo]byte
+	copy(buf[:], b)
+	info := (*unix.TCPInfo)(unsafe.Pointer(&buf[0])) // #nosec G103
+	return &TCPInfo{
+		RTT:           time.Duration(info.Rtt) * time.Microsecond,
+		RTTVar:        time.Duration(info.Rttvar) * time.Microsecond,
+		MinRTT:        time.Duration(info.Min_rtt) * time.Microsecond,
+		RTO:           time.Duration(info.Rto) * time.Microsecond,
+		SndMSS:        info.Snd_mss,
+		RcvMSS:        info.Rcv_mss,
+		SndCwnd:       info.Snd_cwnd,
+		SndSsthresh:   info.Snd_ssthresh,
+		Unacked:       info.Unacked,
+		Lost:          info.Lost,
+		Retrans:       info.Retrans,
+		Retransmits:   info.Retransmits,
+		TotalRetrans:  info.Total_retrans,
+		BytesAcked:    info.Bytes_acked,
+		BytesReceived: info.Bytes_received,
+	}
+}
@@ -0,0 +1,223 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// newInetDiagMsg returns a binary inet_diag_msg for testing, followed by the
+// specified attributes.
+func newInetDiagMsg(state SocketState, src net.IP, sport uint16, dst net.IP, dport uint16,
+	rqueue, wqueue, uid, ino uint32, attrs ...*nl.RtAttr) []byte {
+	b := make([]byte, sizeofInetDiagMsg)
+	b[1] = byte(state)
+	b[inetDiagMsgSport], b[inetDiagMsgSport+1] = byte(sport>>8), byte(sport)
+	b[inetDiagMsgDport], b[inetDiagMsgDport+1] = byte(dport>>8), byte(dport)
+	copy(b[inetDiagMsgSrc:], src)
+	copy(b[inetDiagMsgDst:], dst)
+	nl.NativeEndian().PutUint32(b[inetDiagMsgRqueue:], rqueue)
+	nl.NativeEndian().PutUint32(b[inetDiagMsgWqueue:], wqueue)
+	nl.NativeEndian().PutUint32(b[inetDiagMsgUID:], uid)
+	nl.NativeEndian().PutUint32(b[inetDiagMsgInode:], ino)
+	for _, attr := range attrs {
+		b = append(b, attr.Serialize()...)
+	}
+	return b
+}
+
+var _ = Describe("sock_diag", func() {
+
+	It("serializes requests", func() {
+		req := &inetDiagReqV2{
+			Family:   unix.AF_INET6,
+			Protocol: syscall.IPPROTO_TCP,
+			Ext:      1 << (inetDiagInfo - 1),
+			States:   ^uint32(0),
+		}
+		b := req.Serialize()
+		Expect(b).To(HaveLen(req.Len()))
+		Expect(b[:8]).To(Equal([]byte{unix.AF_INET6, syscall.IPPROTO_TCP, 0x02, 0, 0xff, 0xff, 0xff, 0xff}))
+		Expect(b[8:]).To(HaveEach(byte(0)))
+	})
+
+	It("rejects short and unknown sockets", func() {
+		Expect(newDiagProcessSocket([]byte{1, 2, 3}, unix.AF_INET, syscall.IPPROTO_TCP, nil).State).
+			To(BeZero())
+		Expect(newDiagProcessSocket(
+			newInetDiagMsg(TCP_LISTEN, nil, 0, nil, 0, 0, 0, 0, 0),
+			unix.AF_INET, syscall.IPPROTO_UDP, nil).State).To(BeZero())
+	})
+
+	It("parses a listening TCP socket", func() {
+		sox := newDiagProcessSocket(
+			newInetDiagMsg(TCP_LISTEN, net.ParseIP("127.0.0.1").To4(), 8080, nil, 0,
+				2, 4096, 1000, 12345,
+				nl.NewRtAttr(inetDiagMark, nl.Uint32Attr(42)),
+				nl.NewRtAttr(inetDiagCgroupID, nl.Uint64Attr(666))),
+			unix.AF_INET, syscall.IPPROTO_TCP,
+			socketToProcessMap{12345: []model.PIDType{1, 2}})
+		Expect(sox).To(MatchFields(IgnoreExtras, Fields{
+			"Family":          Equal(AddressFamily(unix.AF_INET)),
+			"Protocol":        Equal(Protocol(syscall.IPPROTO_TCP)),
+			"LocalIP":         Equal(net.IP{127, 0, 0, 1}),
+			"LocalPort":       Equal(uint16(8080)),
+			"RemoteIP":        Equal(net.IP{0, 0, 0, 0}),
+			"State":           Equal(TCP_LISTEN),
+			"SimplifiedState": Equal(Listening),
+			"PIDs":            ConsistOf(model.PIDType(1), model.PIDType(2)),
+			"Inode":           Equal(uint64(12345)),
+			"UID":             Equal(uint32(1000)),
+			"RecvQueue":       Equal(uint32(2)),
+			"SendQueue":       BeZero(),
+			"ListenBacklog":   Equal(uint32(4096)),
+			"Mark":            Equal(uint32(42)),
+			"CgroupID":        Equal(uint64(666)),
+			"TCPInfo":         BeNil(),
+		}))
+	})
+
+	It("parses a connected TCP socket with TCP info", func() {
+		var info unix.TCPInfo
+		info.Rtt = 1500
+		info.Snd_cwnd = 10
+		info.Total_retrans = 3
+		info.Bytes_acked = 1 << 40
+		infob := (*[unix.SizeofTCPInfo]byte)(unsafe.Pointer(&info))[:]
+
+		sox := newDiagProcessSocket(
+			newInetDiagMsg(TCP_ESTABLISHED, net.ParseIP("fe80::1"), 22, net.ParseIP("fe80::2"), 54321,
+				10, 20, 0, 1,
+				nl.NewRtAttr(inetDiagInfo, infob)),
+			unix.AF_INET6, syscall.IPPROTO_TCP, nil)
+		Expect(sox).To(MatchFields(IgnoreExtras, Fields{
+			"LocalIP":         Equal(net.ParseIP("fe80::1")),
+			"RemoteIP":        Equal(net.ParseIP("fe80::2")),
+			"RemotePort":      Equal(uint16(54321)),
+			"SimplifiedState": Equal(Connected),
+			"RecvQueue":       Equal(uint32(10)),
+			"SendQueue":       Equal(uint32(20)),
+			"ListenBacklog":   BeZero(),
+			"TCPInfo": PointTo(MatchFields(IgnoreExtras, Fields{
+				"RTT":          Equal(1500 * time.Microsecond),
+				"SndCwnd":      Equal(uint32(10)),
+				"TotalRetrans": Equal(uint32(3)),
+				"BytesAcked":   Equal(uint64(1 << 40)),
+			})),
+		}))
+	})
+
+	It("serializes raw socket requests", func() {
+		b := (&inetDiagReqV2{
+			Family:      unix.AF_INET,
+			Protocol:    syscall.IPPROTO_RAW,
+			RawProtocol: syscall.IPPROTO_RAW,
+		}).Serialize()
+		Expect(b[:4]).To(Equal([]byte{unix.AF_INET, syscall.IPPROTO_RAW, 0, syscall.IPPROTO_RAW}))
+	})
+
+	It("parses raw and UDP-Lite sockets", func() {
+		sox := newDiagProcessSocket(
+			newInetDiagMsg(UDP_LISTEN, net.IPv4zero.To4(), syscall.IPPROTO_ICMP, nil, 0, 0, 0, 0, 1),
+			unix.AF_INET, syscall.IPPROTO_RAW, nil)
+		Expect(sox).To(MatchFields(IgnoreExtras, Fields{
+			"Protocol":        Equal(Protocol(syscall.IPPROTO_RAW)),
+			"LocalPort":       Equal(uint16(syscall.IPPROTO_ICMP)),
+			"SimplifiedState": Equal(Listening),
+		}))
+		sox = newDiagProcessSocket(
+			newInetDiagMsg(UDP_ESTABLISHED, net.IPv4zero.To4(), 1234, net.IPv4zero.To4(), 4321, 0, 0, 0, 1),
+			unix.AF_INET, syscall.IPPROTO_UDPLITE, nil)
+		Expect(sox).To(MatchFields(IgnoreExtras, Fields{
+			"Protocol":        Equal(Protocol(syscall.IPPROTO_UDPLITE)),
+			"SimplifiedState": Equal(Connected),
+		}))
+	})
+
+	It("parses multi-homed SCTP endpoints and associations", func() {
+		sockaddrs := func(ips ...net.IP) []byte {
+			b := []byte{}
+			for _, ip := range ips {
+				sa := make([]byte, sizeofSockaddrStorage)
+				if ip4 := ip.To4(); ip4 != nil {
+					nl.NativeEndian().PutUint16(sa, unix.AF_INET)
+					copy(sa[4:], ip4)
+				} else {
+					nl.NativeEndian().PutUint16(sa, unix.AF_INET6)
+					copy(sa[8:], ip)
+				}
+				b = append(b, sa...)
+			}
+			return b
+		}
+		locals := sockaddrs(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.1.1"))
+
+		ep := newDiagProcessSocket(
+			newInetDiagMsg(TCP_LISTEN, nil, 36412, nil, 0, 0, 128, 0, 42,
+				nl.NewRtAttr(inetDiagLocals, locals)),
+			unix.AF_INET, syscall.IPPROTO_SCTP, nil)
+		Expect(ep).To(MatchFields(IgnoreExtras, Fields{
+			"Protocol":        Equal(Protocol(syscall.IPPROTO_SCTP)),
+			"LocalIP":         Equal(net.IP{10, 0, 0, 1}),
+			"LocalAddrs":      ConsistOf(net.IP{10, 0, 0, 1}, net.IP{10, 0, 1, 1}),
+			"State":           Equal(TCP_LISTEN),
+			"SimplifiedState": Equal(Listening),
+			"SendQueue":       Equal(uint32(128)),
+		}))
+
+		assoc := newDiagProcessSocket(
+			newInetDiagMsg(sctpStateEstablished, nil, 36412, nil, 2905, 0, 0, 0, 42,
+				nl.NewRtAttr(inetDiagLocals, locals),
+				nl.NewRtAttr(inetDiagPeers, sockaddrs(net.ParseIP("10.0.2.1")))),
+			unix.AF_INET, syscall.IPPROTO_SCTP, nil)
+		Expect(assoc).To(MatchFields(IgnoreExtras, Fields{
+			"RemoteIP":        Equal(net.IP{10, 0, 2, 1}),
+			"RemotePort":      Equal(uint16(2905)),
+			"RemoteAddrs":     Equal([]net.IP{{10, 0, 2, 1}}),
+			"State":           Equal(TCP_ESTABLISHED),
+			"SimplifiedState": Equal(Connected),
+		}))
+		Expect(sctpAssociationState(sctpStateCookieWait)).To(Equal(TCP_SYN_SENT))
+		Expect(sctpAssociationState(42)).To(BeZero())
+	})
+
+	It("accepts truncated TCP info", func() {
+		Expect(newTCPInfo(nil)).To(BeNil())
+		info := newTCPInfo([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0x10, 0, 0, 0})
+		Expect(info).NotTo(BeNil())
+		Expect(info.BytesReceived).To(BeZero())
+	})
+
+	It("discovers sockets in the current network namespace", func() {
+		if unix.Geteuid() != 0 {
+			Skip("needs root")
+		}
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+		sox, err := diagSockets(&liveNetnsAccess{ethtoolFd: -1}, unix.AF_INET, syscall.IPPROTO_TCP, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sox).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"LocalPort":     Equal(port),
+			"State":         Equal(TCP_LISTEN),
+			"Inode":         Not(BeZero()),
+			"ListenBacklog": Not(BeZero()),
+		})))
+	})
+
+})
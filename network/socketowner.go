@@ -0,0 +1,217 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+)
+
+// SocketOwner contains additional details about a process owning sockets.
+type SocketOwner struct {
+	UID          uint32 // effective UID.
+	GID          uint32 // effective GID.
+	UserName     string // user name, as seen from the process' own mount namespace.
+	GroupName    string // group name, as seen from the process' own mount namespace.
+	Executable   string // path of the process' executable.
+	Cgroup       string // (CPU) cgroup path.
+	SystemdUnit  string // systemd unit of a host process, such as "sshd.service".
+	SystemdSlice string // systemd slice of a host process, such as "system.slice".
+}
+
+// SocketOwners maps the PIDs of processes owning sockets to their details. A
+// single SocketOwners map is shared between all network namespaces of the
+// same discovery.
+type SocketOwners map[model.PIDType]*SocketOwner
+
+// systemdUnitSuffixes are the suffixes of systemd units that might appear in
+// cgroup paths, except for slices.
+var systemdUnitSuffixes = []string{".service", ".scope"}
+
+// resolveSocketOwners discovers the details of all processes owning sockets
+// in the specified network namespaces, using the specified source and proc
+// filesystem root. User and group names are looked up in the passwd and group databases
+// of the process' mount namespace, so that container processes get their
+// container's user names instead of the host's.
+func resolveSocketOwners(netspaces NetworkNamespaces, src Source, procroot string) {
+	owners := SocketOwners{}
+	databases := map[species.NamespaceID]*userDatabase{}
+	resolve := func(procs []*model.Process) {
+		for _, proc := range procs {
+			if _, ok := owners[proc.PID]; ok {
+				continue
+			}
+			owner := newSocketOwner(src, procroot, proc)
+			if mntns := proc.Namespaces[model.MountNS]; mntns != nil {
+				db, ok := databases[mntns.ID()]
+				if !ok {
+					db = readUserDatabase(src, mntns)
+					databases[mntns.ID()] = db
+				}
+				owner.UserName = db.users[owner.UID]
+				owner.GroupName = db.groups[owner.GID]
+			}
+			owners[proc.PID] = owner
+		}
+	}
+	for _, netns := range netspaces {
+		for _, ports := range [][]ProcessSocket{netns.Portsv4, netns.Portsv6} {
+			for idx := range ports {
+				resolve(ports[idx].Processes)
+			}
+		}
+		for _, conns := range [][]MPTCPConnection{netns.MPTCPv4, netns.MPTCPv6} {
+			for idx := range conns {
+				resolve(conns[idx].Processes)
+			}
+		}
+		for idx := range netns.PacketSockets {
+			resolve(netns.PacketSockets[idx].Processes)
+		}
+		for _, sock := range netns.UnixSockets {
+			resolve(sock.Processes)
+		}
+	}
+	for _, netns := range netspaces {
+		netns.SocketOwners = owners
+	}
+}
+
+// newSocketOwner returns the details of the specified process, except for its
+// user and group names.
+func newSocketOwner(files Files, procroot string, proc *model.Process) *SocketOwner {
+	procpath := procroot + "/" + strconv.FormatUint(uint64(proc.PID), 10)
+	owner := &SocketOwner{
+		Cgroup: proc.CpuCgroup,
+	}
+	owner.Executable, _ = files.Readlink(procpath + "/exe")
+	if status, err := files.ReadFile(procpath + "/status"); err == nil {
+		owner.UID, owner.GID = effectiveIDs(bytes.NewReader(status))
+	}
+	if !inContainer(proc) {
+		owner.SystemdUnit, owner.SystemdSlice = systemdUnit(proc.CpuCgroup)
+	}
+	return owner
+}
+
+// effectiveIDs returns the effective UID and GID from the specified process
+// status information.
+func effectiveIDs(r io.Reader) (uid uint32, gid uint32) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (key != "Uid" && key != "Gid") {
+			continue
+		}
+		// Uid/Gid: real, effective, saved set, and filesystem IDs.
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		if key == "Uid" {
+			uid = uint32(id)
+		} else {
+			gid = uint32(id)
+		}
+	}
+	return
+}
+
+// inContainer returns true if the specified process is a container process or
+// one of its descendants.
+func inContainer(proc *model.Process) bool {
+	for ; proc != nil; proc = proc.Parent {
+		if proc.Container != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// systemdUnit returns the systemd unit and slice derived from the specified
+// cgroup path, such as "sshd.service" and "system.slice" for
+// "/system.slice/sshd.service". In case of nested slices, the innermost slice
+// is returned.
+func systemdUnit(cgroup string) (unit string, slice string) {
+	for _, element := range strings.Split(cgroup, "/") {
+		if strings.HasSuffix(element, ".slice") {
+			slice = element
+			continue
+		}
+		for _, suffix := range systemdUnitSuffixes {
+			if strings.HasSuffix(element, suffix) {
+				return element, slice
+			}
+		}
+		if element != "" {
+			break
+		}
+	}
+	return "", slice
+}
+
+// userDatabase maps user and group IDs to their names.
+type userDatabase struct {
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// readUserDatabase reads the passwd and group databases as seen from the
+// specified mount namespace. Missing databases result in empty maps.
+func readUserDatabase(src Source, mntns model.Namespace) *userDatabase {
+	db := &userDatabase{
+		users:  map[uint32]string{},
+		groups: map[uint32]string{},
+	}
+	mntfs, closer, err := src.MountFiles(mntns)
+	if err != nil {
+		return db
+	}
+	defer closer()
+	if passwd, err := mntfs.ReadFile("/etc/passwd"); err == nil {
+		db.users = readIDNames(bytes.NewReader(passwd))
+	}
+	if group, err := mntfs.ReadFile("/etc/group"); err == nil {
+		db.groups = readIDNames(bytes.NewReader(group))
+	}
+	return db
+}
+
+// readIDNames parses passwd(5) or group(5) formatted lines, returning a map of
+// IDs to names. If there are multiple names for the same ID, the first one
+// wins.
+func readIDNames(r io.Reader) map[uint32]string {
+	names := map[uint32]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		// name:password:ID:...
+		fields := strings.SplitN(line, ":", 4)
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		if _, ok := names[uint32(id)]; !ok {
+			names[uint32(id)] = fields[0]
+		}
+	}
+	return names
+}
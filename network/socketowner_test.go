@@ -0,0 +1,111 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+// refNamespace is a fakeNamespace that additionally knows its reference.
+type refNamespace struct {
+	fakeNamespace
+	ref model.NamespaceRef
+}
+
+func (n refNamespace) Ref() model.NamespaceRef { return n.ref }
+
+var _ = Describe("socket owners", func() {
+
+	It("reads effective UID and GID", func() {
+		uid, gid := effectiveIDs(strings.NewReader(`Name:	sshd
+Uid:	0	1000	0	1000
+Gid:	1	2	3	4
+Groups:	42
+`))
+		Expect(uid).To(Equal(uint32(1000)))
+		Expect(gid).To(Equal(uint32(2)))
+
+		uid, gid = effectiveIDs(strings.NewReader("Uid:\t0\nGid:\tfoo bar\n"))
+		Expect(uid).To(BeZero())
+		Expect(gid).To(BeZero())
+	})
+
+	It("reads user and group names", func() {
+		Expect(readIDNames(strings.NewReader(`# comment
+root:x:0:0:root:/root:/bin/bash
+toor:x:0:0:root:/root:/bin/bash
+:x:1:1::/:
+daemon:x:daemon
+sshd:x:105:65534::/run/sshd:/usr/sbin/nologin
+`))).To(Equal(map[uint32]string{
+			0:   "root",
+			105: "sshd",
+		}))
+	})
+
+	DescribeTable("derives systemd units and slices from cgroups",
+		func(cgroup string, expectedUnit string, expectedSlice string) {
+			unit, slice := systemdUnit(cgroup)
+			Expect(unit).To(Equal(expectedUnit))
+			Expect(slice).To(Equal(expectedSlice))
+		},
+		Entry(nil, "", "", ""),
+		Entry(nil, "/", "", ""),
+		Entry(nil, "/init.scope", "init.scope", ""),
+		Entry(nil, "/system.slice/sshd.service", "sshd.service", "system.slice"),
+		Entry(nil, "/system.slice/foo.service/bar", "foo.service", "system.slice"),
+		Entry(nil, "/user.slice/user-1000.slice/session-2.scope", "session-2.scope", "user-1000.slice"),
+		Entry(nil, "/docker/0123456789abcdef", "", ""),
+	)
+
+	It("tells container processes", func() {
+		cntr := &model.Process{PID: 1, Container: &model.Container{}}
+		child := &model.Process{PID: 2, Parent: cntr}
+		Expect(inContainer(child)).To(BeTrue())
+		Expect(inContainer(&model.Process{PID: 3})).To(BeFalse())
+	})
+
+	It("resolves socket owner details", func() {
+		mypid := model.PIDType(os.Getpid())
+		mntns := refNamespace{
+			fakeNamespace: fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 1}},
+			ref:           model.NamespaceRef{"/proc/" + strconv.Itoa(os.Getpid()) + "/ns/mnt"},
+		}
+		me := &model.Process{PID: mypid}
+		me.CpuCgroup = "/system.slice/ghostwire.service"
+		me.Namespaces[model.MountNS] = mntns
+		netnsid := species.NamespaceID{Dev: 1, Ino: 2}
+		netns := &NetworkNamespace{
+			Namespace: fakeNamespace{id: netnsid},
+			Portsv4:   []ProcessSocket{{Processes: []*model.Process{me}}},
+		}
+
+		resolveSocketOwners(NetworkNamespaces{netnsid: netns}, live, "/proc")
+		exe, err := os.Executable()
+		Expect(err).NotTo(HaveOccurred())
+		fields := Fields{
+			"UID":          Equal(uint32(os.Geteuid())),
+			"GID":          Equal(uint32(os.Getegid())),
+			"Executable":   Equal(exe),
+			"Cgroup":       Equal("/system.slice/ghostwire.service"),
+			"SystemdUnit":  Equal("ghostwire.service"),
+			"SystemdSlice": Equal("system.slice"),
+		}
+		if os.Geteuid() == 0 {
+			fields["UserName"] = Equal("root")
+		}
+		Expect(netns.SocketOwners).To(HaveKeyWithValue(mypid, PointTo(MatchFields(IgnoreExtras, fields))))
+	})
+
+})
@@ -0,0 +1,222 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thediveo/lxkns/model"
+	"golang.org/x/exp/slices"
+)
+
+// defaultFullScanInterval is the default interval after which the index
+// rereads the links of all open file descriptors of a process, not only of
+// its new and socket file descriptors.
+const defaultFullScanInterval = 10 * time.Second
+
+// procSockets is the cached information about the socket inodes a particular
+// process has open file descriptors for.
+type procSockets struct {
+	starttime uint64            // start time of process, telling apart reused PIDs.
+	fds       []string          // sorted names of open file descriptors.
+	sockets   map[string]uint64 // socket inode numbers by file descriptor name.
+	inodes    []uint64          // sorted socket inode numbers.
+	fullscan  time.Time         // when the links of all fds were last read.
+}
+
+// socketInodeIndex incrementally maps socket inode numbers to the processes
+// using these sockets. Processes are identified by their PID and start time,
+// so that reused PIDs are correctly detected.
+//
+// Reading the links of all open file descriptors of all processes is costly,
+// so the index always lists the fd directory of each process, but then reads
+// only the links of new file descriptors as well as of file descriptors it
+// already knows to reference sockets, as the same file descriptor number
+// might have been reused for a different socket in the meantime. The links
+// of already known non-socket file descriptors are only read again after
+// fullScanInterval, in case a file descriptor number got reused for a socket.
+// The socket-to-process map then is only rebuilt if anything changed at all.
+//
+// A socketInodeIndex is safe to be used by concurrent discoveries.
+type socketInodeIndex struct {
+	files            Files
+	procroot         string
+	fullScanInterval time.Duration
+
+	mu       sync.Mutex
+	procs    map[model.PIDType]*procSockets
+	snapshot socketToProcessMap // immutable; nil if outdated.
+}
+
+// sockInodes is the socket inode index shared by all discoveries.
+var sockInodes = newSocketInodeIndex(osFiles{}, "/proc")
+
+// newSocketInodeIndex returns a new and empty socket inode index for the
+// specified proc filesystem root, accessed using the specified files.
+func newSocketInodeIndex(files Files, procroot string) *socketInodeIndex {
+	return &socketInodeIndex{
+		files:            files,
+		procroot:         procroot,
+		fullScanInterval: defaultFullScanInterval,
+		procs:            map[model.PIDType]*procSockets{},
+	}
+}
+
+// socketToProcessMap updates the index for the specified processes, which
+// must be all processes in the system, and then returns the map of socket
+// inode numbers to the processes using these sockets. The returned map must
+// not be modified, as it is shared with other callers.
+func (i *socketInodeIndex) socketToProcessMap(allprocs model.ProcessTable) socketToProcessMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	for _, proc := range allprocs {
+		i.refresh(proc, now)
+	}
+	// Forget about the processes that have terminated in the meantime.
+	for pid := range i.procs {
+		if _, ok := allprocs[pid]; !ok {
+			delete(i.procs, pid)
+			i.snapshot = nil
+		}
+	}
+	if i.snapshot == nil {
+		i.snapshot = socketToProcessMap{}
+		for pid, procsox := range i.procs {
+			i.snapshot.add(pid, procsox.inodes)
+		}
+	}
+	return i.snapshot
+}
+
+// restrictedSocketToProcessMap updates the index only for the specified
+// processes and returns a new map of socket inode numbers to only these
+// processes.
+func (i *socketInodeIndex) restrictedSocketToProcessMap(procs []*model.Process) socketToProcessMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	spm := socketToProcessMap{}
+	for _, proc := range procs {
+		i.refresh(proc, now)
+		if procsox, ok := i.procs[proc.PID]; ok {
+			spm.add(proc.PID, procsox.inodes)
+		}
+	}
+	return spm
+}
+
+// refresh rescans the open socket file descriptors of the specified process,
+// updating the index if the process is new, has terminated, or has a changed
+// set of open sockets. The caller must hold the index lock.
+func (i *socketInodeIndex) refresh(proc *model.Process, now time.Time) {
+	fdpath := i.procroot + "/" + strconv.FormatUint(uint64(proc.PID), 10) + "/fd"
+	old, known := i.procs[proc.PID]
+	if known && old.starttime != proc.Starttime {
+		known = false
+	}
+	fds, ok := fdNames(i.files, fdpath)
+	if !ok {
+		if _, ok := i.procs[proc.PID]; ok {
+			delete(i.procs, proc.PID)
+			i.snapshot = nil
+		}
+		return
+	}
+	fullscan := !known || now.Sub(old.fullscan) >= i.fullScanInterval
+	sockets := map[string]uint64{}
+	for _, fd := range fds {
+		if !fullscan {
+			if _, wasSocket := old.sockets[fd]; !wasSocket {
+				if _, wasOpen := slices.BinarySearch(old.fds, fd); wasOpen {
+					continue // ...known non-socket fd.
+				}
+			}
+		}
+		if ino, ok := socketInode(i.files, fdpath+"/"+fd); ok {
+			sockets[fd] = ino
+		}
+	}
+	inodes := make([]uint64, 0, len(sockets))
+	for _, ino := range sockets {
+		inodes = append(inodes, ino)
+	}
+	slices.Sort(inodes)
+	inodes = slices.Compact(inodes)
+	if known && slices.Equal(old.inodes, inodes) {
+		old.fds = fds
+		old.sockets = sockets
+		if fullscan {
+			old.fullscan = now
+		}
+		return
+	}
+	i.procs[proc.PID] = &procSockets{
+		starttime: proc.Starttime,
+		fds:       fds,
+		sockets:   sockets,
+		inodes:    inodes,
+		fullscan:  now,
+	}
+	i.snapshot = nil
+}
+
+// add the specified socket inodes used by the specified process.
+func (m socketToProcessMap) add(pid model.PIDType, inodes []uint64) {
+	for _, ino := range inodes {
+		m[ino] = append(m[ino], pid)
+	}
+}
+
+// fdNames returns the sorted names of the open file descriptors in the
+// specified fd directory, as well as true; or false if the directory cannot be
+// read, such as when the process has terminated.
+func fdNames(files Files, fdpath string) ([]string, bool) {
+	entries, err := files.ReadDir(fdpath)
+	if err != nil {
+		return nil, false
+	}
+	fds := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fds = append(fds, entry.Name())
+	}
+	slices.Sort(fds)
+	return fds, true
+}
+
+// socketInode returns the inode number of the socket referenced by the
+// specified file descriptor path, as well as true; or false if the file
+// descriptor doesn't reference a socket. Instead of stat'ing the file
+// descriptor we only read its link, which avoids touching the files behind
+// non-socket file descriptors.
+func socketInode(files Files, fdpath string) (uint64, bool) {
+	link, err := files.Readlink(fdpath)
+	if err != nil || !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	ino, err := strconv.ParseUint(link[8:len(link)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return ino, true
+}
+
+// processesOfNamespaces returns the processes attached to at least one of the
+// specified namespaces.
+func processesOfNamespaces(allprocs model.ProcessTable, namespaces ...model.Namespace) []*model.Process {
+	procs := []*model.Process{}
+	for _, proc := range allprocs {
+		for _, ns := range namespaces {
+			if procns := proc.Namespaces[model.TypeIndex(ns.Type())]; procns != nil && procns.ID() == ns.ID() {
+				procs = append(procs, proc)
+				break
+			}
+		}
+	}
+	return procs
+}
@@ -0,0 +1,276 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"golang.org/x/exp/slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// typedNamespace is a fakeNamespace that additionally knows its type.
+type typedNamespace struct {
+	fakeNamespace
+	typ species.NamespaceType
+}
+
+func (n typedNamespace) Type() species.NamespaceType { return n.typ }
+
+// makeProc creates a synthetic process entry in the specified proc root, with
+// open file descriptors referencing the specified socket inodes. It returns
+// the corresponding process object with the specified start time.
+func makeProc(procroot string, pid model.PIDType, starttime uint64, inodes ...uint64) *model.Process {
+	GinkgoHelper()
+	fdpath := filepath.Join(procroot, fmt.Sprint(pid), "fd")
+	Expect(os.RemoveAll(fdpath)).To(Succeed())
+	Expect(os.MkdirAll(fdpath, 0755)).To(Succeed())
+	Expect(os.Symlink("/dev/null", fdpath+"/0")).To(Succeed())
+	for fd, ino := range inodes {
+		Expect(os.Symlink(fmt.Sprintf("socket:[%d]", ino),
+			fmt.Sprintf("%s/%d", fdpath, fd+3))).To(Succeed())
+	}
+	proc := &model.Process{PID: pid}
+	proc.Starttime = starttime
+	return proc
+}
+
+// readlinkCountingFiles are osFiles counting the links read per path.
+type readlinkCountingFiles struct {
+	osFiles
+	readlinks map[string]int
+}
+
+func (f *readlinkCountingFiles) Readlink(name string) (string, error) {
+	f.readlinks[name]++
+	return f.osFiles.Readlink(name)
+}
+
+// mapPointer returns the identity of the specified map.
+func mapPointer(m socketToProcessMap) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+var _ = Describe("socket inode index", func() {
+
+	It("reads socket inodes from file descriptors", func() {
+		fds, ok := fdNames(osFiles{}, "./test/proc/666/fd")
+		Expect(ok).To(BeTrue())
+		Expect(fds).To(Equal([]string{"0", "3", "4", "5", "6"}))
+		var inodes []uint64
+		for _, fd := range fds {
+			if ino, ok := socketInode(osFiles{}, "./test/proc/666/fd/"+fd); ok {
+				inodes = append(inodes, ino)
+			}
+		}
+		Expect(inodes).To(ConsistOf(uint64(777), uint64(12345), uint64(12345)))
+
+		_, ok = fdNames(osFiles{}, "./test/proc/999/fd")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("updates incrementally", func() {
+		procroot := GinkgoT().TempDir()
+		allprocs := model.ProcessTable{
+			1:  makeProc(procroot, 1, 1, 10, 11),
+			42: makeProc(procroot, 42, 100, 11, 12),
+		}
+
+		idx := newSocketInodeIndex(osFiles{}, procroot)
+		idx.fullScanInterval = time.Hour
+		spm := idx.socketToProcessMap(allprocs)
+		Expect(spm.sorted()).To(Equal(socketToProcessMap{
+			10: {1},
+			11: {1, 42},
+			12: {42},
+		}))
+		Expect(mapPointer(idx.socketToProcessMap(allprocs))).To(Equal(mapPointer(spm)),
+			"unchanged processes must not result in a new map")
+
+		allprocs[42] = makeProc(procroot, 42, 100, 13)
+		allprocs[666] = makeProc(procroot, 666, 200)
+		spm = idx.socketToProcessMap(allprocs)
+		Expect(spm.sorted()).To(Equal(socketToProcessMap{
+			10: {1},
+			11: {1},
+			13: {42},
+		}))
+
+		By("reusing the same file descriptor for a different socket")
+		makeProc(procroot, 42, 100, 14)
+		spm = idx.socketToProcessMap(allprocs)
+		Expect(spm).To(HaveKey(uint64(14)))
+		Expect(spm).NotTo(HaveKey(uint64(13)))
+
+		By("reusing a PID")
+		allprocs[42] = makeProc(procroot, 42, 300, 14)
+		idx.socketToProcessMap(allprocs)
+		Expect(idx.procs).To(HaveKey(model.PIDType(42)))
+		Expect(idx.procs[42].starttime).To(Equal(uint64(300)))
+
+		By("terminating processes")
+		delete(allprocs, 42)
+		Expect(idx.socketToProcessMap(allprocs)).NotTo(HaveKey(uint64(14)))
+		Expect(idx.procs).NotTo(HaveKey(model.PIDType(42)))
+		Expect(os.RemoveAll(filepath.Join(procroot, "666"))).To(Succeed())
+		idx.socketToProcessMap(allprocs)
+		Expect(idx.procs).NotTo(HaveKey(model.PIDType(666)))
+
+		By("restricting to specific processes")
+		Expect(idx.restrictedSocketToProcessMap([]*model.Process{{PID: 1234}})).To(BeEmpty())
+		Expect(idx.restrictedSocketToProcessMap([]*model.Process{allprocs[1]})).To(Equal(socketToProcessMap{
+			10: {1},
+			11: {1},
+		}))
+
+		Expect(newSocketInodeIndex(osFiles{}, "/non-existing").socketToProcessMap(allprocs)).To(BeEmpty())
+	})
+
+	It("skips reading links of known non-socket file descriptors", func() {
+		procroot := GinkgoT().TempDir()
+		allprocs := model.ProcessTable{
+			1: makeProc(procroot, 1, 1, 10),
+		}
+		fdpath := procroot + "/1/fd"
+		files := &readlinkCountingFiles{readlinks: map[string]int{}}
+		idx := newSocketInodeIndex(files, procroot)
+		idx.fullScanInterval = time.Hour
+
+		Expect(idx.socketToProcessMap(allprocs)).To(HaveKey(uint64(10)))
+		Expect(files.readlinks).To(Equal(map[string]int{fdpath + "/0": 1, fdpath + "/3": 1}))
+		Expect(idx.socketToProcessMap(allprocs)).To(HaveKey(uint64(10)))
+		Expect(files.readlinks).To(Equal(map[string]int{fdpath + "/0": 1, fdpath + "/3": 2}),
+			"known socket fds must always be read, but not known non-socket fds")
+
+		By("opening new file descriptors")
+		makeProc(procroot, 1, 1, 10, 11)
+		Expect(idx.socketToProcessMap(allprocs)).To(And(HaveKey(uint64(10)), HaveKey(uint64(11))))
+		Expect(files.readlinks).To(Equal(map[string]int{fdpath + "/0": 1, fdpath + "/3": 3, fdpath + "/4": 1}))
+
+		By("reusing a non-socket file descriptor for a socket")
+		Expect(os.Remove(fdpath + "/0")).To(Succeed())
+		Expect(os.Symlink("socket:[12]", fdpath+"/0")).To(Succeed())
+		Expect(idx.socketToProcessMap(allprocs)).NotTo(HaveKey(uint64(12)))
+		idx.fullScanInterval = 0
+		Expect(idx.socketToProcessMap(allprocs)).To(HaveKey(uint64(12)))
+		Expect(files.readlinks[fdpath+"/0"]).To(Equal(2))
+	})
+
+	It("is safe for concurrent use", func() {
+		procroot := GinkgoT().TempDir()
+		allprocs := model.ProcessTable{}
+		for pid := model.PIDType(1); pid <= 10; pid++ {
+			allprocs[pid] = makeProc(procroot, pid, uint64(pid), uint64(pid), 100)
+		}
+		idx := newSocketInodeIndex(osFiles{}, procroot)
+		idx.fullScanInterval = 0
+		var wg sync.WaitGroup
+		for pid := model.PIDType(1); pid <= 10; pid++ {
+			wg.Add(1)
+			go func(pid model.PIDType) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(idx.socketToProcessMap(allprocs)).To(HaveLen(11))
+				Expect(idx.restrictedSocketToProcessMap([]*model.Process{allprocs[pid]})).To(HaveLen(2))
+			}(pid)
+		}
+		wg.Wait()
+	})
+
+	It("restricts to processes of specific namespaces", func() {
+		netns1 := typedNamespace{fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 1}}, species.CLONE_NEWNET}
+		netns2 := typedNamespace{fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 2}}, species.CLONE_NEWNET}
+		proc1 := &model.Process{PID: 1}
+		proc1.Namespaces[model.NetNS] = netns1
+		proc2 := &model.Process{PID: 2}
+		proc2.Namespaces[model.NetNS] = netns2
+		allprocs := model.ProcessTable{1: proc1, 2: proc2, 3: &model.Process{PID: 3}}
+		Expect(processesOfNamespaces(allprocs, netns2)).To(ConsistOf(proc2))
+		Expect(processesOfNamespaces(allprocs, netns1, netns2)).To(ConsistOf(proc1, proc2))
+		Expect(processesOfNamespaces(allprocs)).To(BeEmpty())
+	})
+
+})
+
+// sorted returns the socket-to-process map with its PIDs sorted, for
+// deterministic comparisons.
+func (m socketToProcessMap) sorted() socketToProcessMap {
+	sorted := socketToProcessMap{}
+	for ino, pids := range m {
+		pids = slices.Clone(pids)
+		slices.Sort(pids)
+		sorted[ino] = pids
+	}
+	return sorted
+}
+
+// makeBenchmarkProcfs creates a synthetic proc root with the specified number
+// of processes, each with the specified number of file descriptors, of which
+// every second one references a socket. It returns the proc root as well as
+// the corresponding process table.
+func makeBenchmarkProcfs(b *testing.B, procs int, fds int) (string, model.ProcessTable) {
+	b.Helper()
+	procroot := b.TempDir()
+	allprocs := model.ProcessTable{}
+	for pid := 1; pid <= procs; pid++ {
+		fdpath := filepath.Join(procroot, fmt.Sprint(pid), "fd")
+		if err := os.MkdirAll(fdpath, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for fd := 0; fd < fds; fd++ {
+			target := "/dev/null"
+			if fd%2 != 0 {
+				target = fmt.Sprintf("socket:[%d]", pid*fds+fd)
+			}
+			if err := os.Symlink(target, fmt.Sprintf("%s/%d", fdpath, fd)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		proc := &model.Process{PID: model.PIDType(pid)}
+		proc.Starttime = uint64(pid)
+		allprocs[proc.PID] = proc
+	}
+	return procroot, allprocs
+}
+
+func BenchmarkSocketInodesFullScan(b *testing.B) {
+	procroot, allprocs := makeBenchmarkProcfs(b, 500, 40)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = newSocketInodeIndex(osFiles{}, procroot).socketToProcessMap(allprocs)
+	}
+}
+
+func BenchmarkSocketInodesIncremental(b *testing.B) {
+	procroot, allprocs := makeBenchmarkProcfs(b, 500, 40)
+	idx := newSocketInodeIndex(osFiles{}, procroot)
+	idx.fullScanInterval = time.Hour
+	_ = idx.socketToProcessMap(allprocs)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = idx.socketToProcessMap(allprocs)
+	}
+}
+
+func BenchmarkSocketInodesRestricted(b *testing.B) {
+	procroot, allprocs := makeBenchmarkProcfs(b, 500, 40)
+	idx := newSocketInodeIndex(osFiles{}, procroot)
+	idx.fullScanInterval = time.Hour
+	procs := []*model.Process{allprocs[1], allprocs[2], allprocs[3], allprocs[4], allprocs[5]}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = idx.restrictedSocketToProcessMap(procs)
+	}
+}
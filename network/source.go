@@ -0,0 +1,355 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/nftables"
+	"github.com/thediveo/go-mntinfo"
+	"github.com/thediveo/ioctl"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/ops"
+	"github.com/thediveo/lxkns/ops/mountineer"
+	"github.com/thediveo/lxkns/species"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Source supplies the network discovery with the system information it is
+// based on: netlink dumps, procfs and sysfs files, files inside mount
+// namespaces, netfilter tables, and container engine API responses. The live
+// source queries the host the discovery runs on, while other sources record
+// this information or replay information recorded earlier, such as from
+// support bundles taken on another host.
+//
+// File paths are always absolute and from the perspective of the host, such
+// as "/proc/42/status".
+type Source interface {
+	Files
+	// Visit calls fn with access to the information specific to the specified
+	// network namespace, returning fn's error, if any.
+	Visit(netns model.Namespace, fn func(NetnsAccess) error) error
+	// NetnsFd returns an open file descriptor referencing the specified
+	// network namespace for use with NetnsAccess.NSID, together with a
+	// function to close it again.
+	NetnsFd(netns model.Namespace) (int, func(), error)
+	// MountFiles returns access to the files as seen from inside the specified
+	// mount namespace, together with a function to release this access.
+	MountFiles(mntns model.Namespace) (Files, func(), error)
+	// Hostname returns the host name of the specified UTS namespace.
+	Hostname(utsns model.Namespace) (string, error)
+	// Mounts returns the mount points in the mount namespace of the process
+	// with the specified PID.
+	Mounts(pid model.PIDType) []mntinfo.Mountinfo
+	// TunTapNetns returns the ID of the network namespace the TAP/TUN netdev
+	// referenced by the specified file descriptor of the specified process is
+	// currently placed in.
+	TunTapNetns(pid model.PIDType, fd int) (species.NamespaceID, error)
+	// EngineClient returns an HTTP client for talking to the container engine
+	// API at the specified endpoint, such as "unix:///run/docker.sock".
+	EngineClient(api string) *http.Client
+}
+
+// NetnsAccess gives access to the information specific to a particular network
+// namespace; see Source.Visit.
+type NetnsAccess interface {
+	// Files only gives access to the procfs files depending on the network
+	// namespace of the reader, that is, "/proc/thread-self/net/..." and
+	// "/proc/sys/net/...".
+	Files
+	// Execute executes the specified netlink request, returning the payloads
+	// of the response messages of the specified type; see also
+	// nl.NetlinkRequest.Execute.
+	Execute(req *nl.NetlinkRequest, sockType int, resType uint16) ([][]byte, error)
+	// NSID returns the NSID assigned to the peer network namespace referenced
+	// by the specified fd, as returned by Source.NetnsFd.
+	NSID(peerfd int) (int, error)
+	// DriverInfo returns the ethtool driver information of the named netdev.
+	DriverInfo(name string) (*unix.EthtoolDrvinfo, error)
+	// Netfilter returns a new lasting netfilter netlink connection; the caller
+	// is responsible for closing it using CloseLasting.
+	Netfilter() (*nftables.Conn, error)
+}
+
+// netlinkHandler is optionally implemented by a NetnsAccess giving direct
+// access to the network namespace using a netlink handle, as opposed to
+// recording or replaying netlink dumps.
+type netlinkHandler interface {
+	netlinkHandle() (*netlink.Handle, error)
+}
+
+// Files gives read access to files, directories, and symbolic links.
+type Files interface {
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Readlink(name string) (string, error)
+	Stat(name string) (FileStat, error)  // follows symbolic links.
+	Lstat(name string) (FileStat, error) // doesn't follow symbolic links.
+}
+
+// FileStat identifies a file by its device and inode numbers, together with
+// its mode bits (S_IFMT and permissions).
+type FileStat struct {
+	Dev  uint64 `json:"dev"`
+	Ino  uint64 `json:"ino"`
+	Mode uint32 `json:"mode"`
+}
+
+// live is the Source querying the host the discovery runs on.
+var live Source = liveSource{}
+
+// LiveSource returns the Source querying the host the discovery runs on. This
+// is the default source, unless specified otherwise using WithSource.
+func LiveSource() Source {
+	return live
+}
+
+// sourceKey is the context key for passing a Source to decorators.
+type sourceKey struct{}
+
+// NewContextWithSource returns a new context carrying the specified Source.
+func NewContextWithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceFromContext returns the Source carried by the specified context,
+// falling back to the live source.
+func SourceFromContext(ctx context.Context) Source {
+	if src, ok := ctx.Value(sourceKey{}).(Source); ok && src != nil {
+		return src
+	}
+	return live
+}
+
+// osFiles gives access to the files of the host, as seen from the mount
+// namespace of the discovery.
+type osFiles struct{}
+
+var _ Files = osFiles{}
+
+func (osFiles) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) } // #nosec G304
+
+func (osFiles) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+func (osFiles) Readlink(name string) (string, error) { return os.Readlink(name) }
+
+func (osFiles) Stat(name string) (FileStat, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(name, &stat); err != nil {
+		return FileStat{}, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return FileStat{Dev: stat.Dev, Ino: stat.Ino, Mode: stat.Mode}, nil
+}
+
+func (osFiles) Lstat(name string) (FileStat, error) {
+	var stat unix.Stat_t
+	if err := unix.Lstat(name, &stat); err != nil {
+		return FileStat{}, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return FileStat{Dev: stat.Dev, Ino: stat.Ino, Mode: stat.Mode}, nil
+}
+
+// liveSource implements the Source querying the host the discovery runs on.
+type liveSource struct {
+	osFiles
+}
+
+// Visit calls fn on an OS thread attached to the specified network namespace.
+func (liveSource) Visit(netns model.Namespace, fn func(NetnsAccess) error) error {
+	return openInNetns(netns, func() error {
+		access := &liveNetnsAccess{ethtoolFd: -1}
+		defer access.close()
+		return fn(access)
+	})
+}
+
+func (liveSource) NetnsFd(netns model.Namespace) (int, func(), error) {
+	return NamespaceFd(netns)
+}
+
+func (liveSource) MountFiles(mntns model.Namespace) (Files, func(), error) {
+	mntneer, err := mountineer.New(mntns.Ref(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mountFiles{mntneer}, mntneer.Close, nil
+}
+
+func (liveSource) Hostname(utsns model.Namespace) (string, error) {
+	ref := utsns.Ref()
+	if len(ref) != 1 {
+		return "", &fs.PathError{Op: "uname", Path: ref.String(), Err: fs.ErrNotExist}
+	}
+	var hostname string
+	var err error
+	if visitErr := ops.Visit(func() {
+		hostname, err = os.Hostname()
+	}, ops.NewTypedNamespacePath(ref[0], species.CLONE_NEWUTS)); visitErr != nil {
+		return "", visitErr
+	}
+	return hostname, err
+}
+
+func (liveSource) Mounts(pid model.PIDType) []mntinfo.Mountinfo {
+	return mntinfo.MountsOfPid(int(pid))
+}
+
+func (liveSource) TunTapNetns(pid model.PIDType, fd int) (species.NamespaceID, error) {
+	pidfd, err := unix.PidfdOpen(int(pid), 0)
+	if err != nil {
+		return species.NoneID, err
+	}
+	defer unix.Close(pidfd)
+	taptunFd, err := unix.PidfdGetfd(pidfd, fd, 0)
+	if err != nil {
+		return species.NoneID, err
+	}
+	netnsFd, err := getTapNetdevNetnsFd(taptunFd)
+	unix.Close(taptunFd)
+	if err != nil {
+		return species.NoneID, err
+	}
+	defer unix.Close(netnsFd)
+	return ops.NamespaceFd(netnsFd).ID()
+}
+
+// getTapNetdevNetnsFd takes an open file descriptor referencing a TAP/TUN
+// netdev and returns a new file descriptor referencing the network namespace
+// the TAP/TUN netdev is currently placed in.
+//
+// For background information, please see:
+// https://unix.stackexchange.com/a/743003
+func getTapNetdevNetnsFd(fd int) (int, error) {
+	return ioctl.RetFd(fd, ioctl.IO('T', 227))
+}
+
+func (liveSource) EngineClient(api string) *http.Client {
+	transport := &http.Transport{
+		DisableCompression: true,
+	}
+	if path, ok := strings.CutPrefix(api, "unix://"); ok {
+		dialer := &net.Dialer{
+			// same as Docker's unix socket default transport configuration.
+			Timeout: 10 * time.Second,
+		}
+		transport.DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// liveNetnsAccess gives access to the network namespace the current OS thread
+// is attached to. The netlink handle and the ethtool socket are opened only on
+// demand and are then reused until the end of the visit.
+type liveNetnsAccess struct {
+	osFiles
+	nlh       *netlink.Handle
+	ethtoolFd int
+}
+
+func (a *liveNetnsAccess) close() {
+	if a.nlh != nil {
+		a.nlh.Close()
+	}
+	if a.ethtoolFd >= 0 {
+		_ = unix.Close(a.ethtoolFd)
+	}
+}
+
+func (a *liveNetnsAccess) Execute(req *nl.NetlinkRequest, sockType int, resType uint16) ([][]byte, error) {
+	return req.Execute(sockType, resType)
+}
+
+func (a *liveNetnsAccess) NSID(peerfd int) (int, error) {
+	nlh, err := a.netlinkHandle()
+	if err != nil {
+		return -1, err
+	}
+	return nlh.GetNetNsIdByFd(peerfd)
+}
+
+func (a *liveNetnsAccess) netlinkHandle() (*netlink.Handle, error) {
+	if a.nlh == nil {
+		nlh, err := netlink.NewHandle(unix.NETLINK_ROUTE)
+		if err != nil {
+			return nil, err
+		}
+		a.nlh = nlh
+	}
+	return a.nlh, nil
+}
+
+func (a *liveNetnsAccess) DriverInfo(name string) (*unix.EthtoolDrvinfo, error) {
+	if a.ethtoolFd < 0 {
+		fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, unix.IPPROTO_IP)
+		if err != nil {
+			return nil, err
+		}
+		a.ethtoolFd = fd
+	}
+	return unix.IoctlGetEthtoolDrvinfo(a.ethtoolFd, name)
+}
+
+func (a *liveNetnsAccess) Netfilter() (*nftables.Conn, error) {
+	return nftables.New(nftables.AsLasting())
+}
+
+// mountFiles gives access to the files in a mount namespace.
+type mountFiles struct {
+	*mountineer.Mountineer
+}
+
+func (m mountFiles) Readlink(name string) (string, error) {
+	path, err := m.resolveDir(name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(path)
+}
+
+func (m mountFiles) Stat(name string) (FileStat, error) {
+	path, err := m.Resolve(name)
+	if err != nil {
+		return FileStat{}, err
+	}
+	return osFiles{}.Stat(path)
+}
+
+func (m mountFiles) Lstat(name string) (FileStat, error) {
+	path, err := m.resolveDir(name)
+	if err != nil {
+		return FileStat{}, err
+	}
+	return osFiles{}.Lstat(path)
+}
+
+// resolveDir resolves only the directory part of the specified path, but not
+// its final element.
+func (m mountFiles) resolveDir(name string) (string, error) {
+	dir, err := m.Resolve(filepath.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(name)), nil
+}
+
+// sourceOf returns the Source of the specified network namespace, falling back
+// to the live source.
+func sourceOf(n *NetworkNamespace) Source {
+	if n == nil || n.source == nil {
+		return live
+	}
+	return n.source
+}
@@ -0,0 +1,198 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"strings"
+
+	"github.com/thediveo/lxkns/model"
+)
+
+// Sysctl is the value of a network-related kernel parameter ("sysctl") in a
+// particular network namespace, together with the information whether it
+// deviates from the initial network namespace and from the kernel default.
+// For per-interface sysctls, the "initial" value is instead the
+// "conf.default" value of the network interface's own network namespace.
+type Sysctl struct {
+	Value              string `json:"value"`                          // (normalized) value.
+	Initial            string `json:"initial,omitempty"`              // value in the initial network namespace (or conf.default), if known.
+	Default            string `json:"default,omitempty"`              // kernel default, if known.
+	DiffersFromInitial bool   `json:"differs-from-initial,omitempty"` // value differs from the initial network namespace (or conf.default).
+	DiffersFromDefault bool   `json:"differs-from-default,omitempty"` // value differs from the kernel default.
+}
+
+// Sysctls maps network-related sysctl names to their values.
+//
+// For network namespaces, names are the dotted sysctl names without the
+// leading "net.", such as "ipv4.ip_forward" and "ipv4.conf.all.rp_filter".
+// For network interfaces, names are the per-interface configuration parameter
+// names prefixed by only the address family, such as "ipv4.rp_filter" and
+// "ipv6.accept_ra" (for "net.ipv4.conf.<nif>.rp_filter", et cetera).
+type Sysctls map[string]*Sysctl
+
+// Deviations returns only those sysctls that differ from either the initial
+// network namespace or the kernel defaults.
+func (s Sysctls) Deviations() Sysctls {
+	devs := Sysctls{}
+	for name, sysctl := range s {
+		if sysctl.DiffersFromInitial || sysctl.DiffersFromDefault {
+			devs[name] = sysctl
+		}
+	}
+	return devs
+}
+
+// sysctlDefaults lists the kernel defaults of network-related sysctls commonly
+// involved in container networking trouble. Per-interface defaults are listed
+// in their "conf.default" form. Please note that the bridge-nf-call sysctls
+// only exist with the br_netfilter kernel module loaded.
+var sysctlDefaults = map[string]string{
+	"ipv4.ip_forward":                    "0",
+	"ipv4.ip_unprivileged_port_start":    "1024",
+	"ipv4.ip_local_port_range":           "32768 60999",
+	"ipv4.ping_group_range":              "1 0",
+	"ipv4.tcp_syncookies":                "1",
+	"ipv4.icmp_echo_ignore_all":          "0",
+	"ipv4.conf.all.forwarding":           "0",
+	"ipv4.conf.all.rp_filter":            "0",
+	"ipv4.conf.all.arp_ignore":           "0",
+	"ipv4.conf.all.arp_announce":         "0",
+	"ipv4.conf.all.arp_filter":           "0",
+	"ipv4.conf.all.proxy_arp":            "0",
+	"ipv4.conf.all.route_localnet":       "0",
+	"ipv4.conf.all.accept_redirects":     "1",
+	"ipv4.conf.all.send_redirects":       "1",
+	"ipv4.conf.default.forwarding":       "0",
+	"ipv4.conf.default.rp_filter":        "0",
+	"ipv4.conf.default.arp_ignore":       "0",
+	"ipv4.conf.default.arp_announce":     "0",
+	"ipv4.conf.default.arp_filter":       "0",
+	"ipv4.conf.default.proxy_arp":        "0",
+	"ipv4.conf.default.route_localnet":   "0",
+	"ipv4.conf.default.accept_redirects": "1",
+	"ipv4.conf.default.send_redirects":   "1",
+	"ipv6.conf.all.forwarding":           "0",
+	"ipv6.conf.all.accept_ra":            "1",
+	"ipv6.conf.all.disable_ipv6":         "0",
+	"ipv6.conf.all.autoconf":             "1",
+	"ipv6.conf.default.forwarding":       "0",
+	"ipv6.conf.default.accept_ra":        "1",
+	"ipv6.conf.default.disable_ipv6":     "0",
+	"ipv6.conf.default.autoconf":         "1",
+	"bridge.bridge-nf-call-iptables":     "1",
+	"bridge.bridge-nf-call-ip6tables":    "1",
+	"bridge.bridge-nf-call-arptables":    "1",
+}
+
+// discoverSysctls discovers the network-related sysctls of this network
+// namespace as well as the per-interface configuration sysctls. As
+// /proc/sys/net always shows the network namespace of the reading thread, the
+// sysctls must be read while visiting the network namespace.
+func (n *NetworkNamespace) discoverSysctls(nsa NetnsAccess) {
+	global, perNif := readSysctls(nsa, "/proc/sys/net")
+	n.Sysctls = global
+	for name, sysctls := range perNif {
+		if nif, ok := n.NamedNifs[name]; ok {
+			nif.Nif().Sysctls = sysctls
+		}
+	}
+}
+
+// readSysctls reads the network-related sysctls from the specified root
+// directory, returning the namespace-global sysctls as well as the
+// per-interface configuration sysctls, indexed by network interface name.
+// Sysctls that cannot be read, such as write-only ones, are skipped.
+//
+// Per-interface "neigh" sysctls are skipped, except for the "default" ones.
+func readSysctls(files Files, root string) (global Sysctls, perNif map[string]Sysctls) {
+	global = Sysctls{}
+	perNif = map[string]Sysctls{}
+	var walk func(dir string, elems []string)
+	walk = func(dir string, elems []string) {
+		entries, err := files.ReadDir(dir)
+		if err != nil {
+			return // skip unreadable directories, but carry on.
+		}
+		for _, entry := range entries {
+			elems := append(elems[:len(elems):len(elems)], entry.Name())
+			path := dir + "/" + entry.Name()
+			if entry.IsDir() {
+				if len(elems) == 3 && elems[1] == "neigh" && elems[2] != "default" {
+					continue
+				}
+				walk(path, elems)
+				continue
+			}
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			contents, err := files.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			sysctl := &Sysctl{Value: strings.Join(strings.Fields(string(contents)), " ")}
+			if len(elems) == 4 && elems[1] == "conf" && elems[2] != "all" && elems[2] != "default" {
+				nifname := elems[2]
+				sysctls, ok := perNif[nifname]
+				if !ok {
+					sysctls = Sysctls{}
+					perNif[nifname] = sysctls
+				}
+				sysctls[elems[0]+"."+elems[3]] = sysctl
+				continue
+			}
+			global[strings.Join(elems, ".")] = sysctl
+		}
+	}
+	walk(root, nil)
+	return
+}
+
+// resolveSysctlDeviations determines which sysctls in the network namespaces
+// deviate from the initial network namespace (the one of PID 1) and from the
+// kernel defaults. Per-interface sysctls are instead compared with the
+// "conf.default" sysctls of their own network namespace, as these are what
+// network interfaces get when created in (or moved into) that network
+// namespace; container network namespaces might well have their own defaults.
+func resolveSysctlDeviations(netspaces NetworkNamespaces, allprocs model.ProcessTable) {
+	var initial *NetworkNamespace
+	if initproc, ok := allprocs[1]; ok {
+		if netns := initproc.Namespaces[model.NetNS]; netns != nil {
+			initial = netspaces[netns.ID()]
+		}
+	}
+	var initialSysctls Sysctls
+	if initial != nil {
+		initialSysctls = initial.Sysctls
+	}
+	for _, netns := range netspaces {
+		for name, sysctl := range netns.Sysctls {
+			resolveSysctlDeviation(sysctl, name, initialSysctls, netns == initial)
+		}
+		for _, nif := range netns.Nifs {
+			for name, sysctl := range nif.Nif().Sysctls {
+				family, param, _ := strings.Cut(name, ".")
+				resolveSysctlDeviation(sysctl, family+".conf.default."+param,
+					netns.Sysctls, false)
+			}
+		}
+	}
+}
+
+// resolveSysctlDeviation sets the initial and default values of a single
+// sysctl and whether it deviates from them; the sysctl is looked up in the
+// reference sysctls and defaults using the specified reference name.
+func resolveSysctlDeviation(sysctl *Sysctl, refname string, refSysctls Sysctls, isInitial bool) {
+	if !isInitial {
+		if refSysctl, ok := refSysctls[refname]; ok {
+			sysctl.Initial = refSysctl.Value
+			sysctl.DiffersFromInitial = sysctl.Value != refSysctl.Value
+		}
+	}
+	if dflt, ok := sysctlDefaults[refname]; ok {
+		sysctl.Default = dflt
+		sysctl.DiffersFromDefault = sysctl.Value != dflt
+	}
+}
@@ -0,0 +1,131 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("sysctls", func() {
+
+	It("reads global and per-interface sysctls", func() {
+		root := GinkgoT().TempDir()
+		for path, value := range map[string]string{
+			"ipv4/ip_forward":                  "1\n",
+			"ipv4/ping_group_range":            "0\t2147483647\n",
+			"ipv4/conf/all/rp_filter":          "2\n",
+			"ipv4/conf/default/rp_filter":      "2\n",
+			"ipv4/conf/eth0/rp_filter":         "1\n",
+			"ipv6/conf/eth0/accept_ra":         "0\n",
+			"ipv4/neigh/default/gc_stale_time": "60\n",
+			"ipv4/neigh/eth0/gc_stale_time":    "60\n",
+			"bridge/bridge-nf-call-iptables":   "1\n",
+		} {
+			Expect(os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, path), []byte(value), 0644)).To(Succeed())
+		}
+
+		global, perNif := readSysctls(osFiles{}, root)
+		Expect(global).To(MatchAllKeys(Keys{
+			"ipv4.ip_forward":                  PointTo(HaveField("Value", "1")),
+			"ipv4.ping_group_range":            PointTo(HaveField("Value", "0 2147483647")),
+			"ipv4.conf.all.rp_filter":          PointTo(HaveField("Value", "2")),
+			"ipv4.conf.default.rp_filter":      PointTo(HaveField("Value", "2")),
+			"ipv4.neigh.default.gc_stale_time": PointTo(HaveField("Value", "60")),
+			"bridge.bridge-nf-call-iptables":   PointTo(HaveField("Value", "1")),
+		}))
+		Expect(perNif).To(MatchAllKeys(Keys{
+			"eth0": MatchAllKeys(Keys{
+				"ipv4.rp_filter": PointTo(HaveField("Value", "1")),
+				"ipv6.accept_ra": PointTo(HaveField("Value", "0")),
+			}),
+		}))
+	})
+
+	It("resolves deviations", func() {
+		initial := Sysctls{
+			"ipv4.ip_forward":             {Value: "1"},
+			"ipv4.conf.default.rp_filter": {Value: "2"},
+		}
+
+		sysctl := &Sysctl{Value: "0"}
+		resolveSysctlDeviation(sysctl, "ipv4.ip_forward", initial, false)
+		Expect(sysctl).To(PointTo(MatchAllFields(Fields{
+			"Value":              Equal("0"),
+			"Initial":            Equal("1"),
+			"Default":            Equal("0"),
+			"DiffersFromInitial": BeTrue(),
+			"DiffersFromDefault": BeFalse(),
+		})))
+
+		sysctl = &Sysctl{Value: "1"}
+		resolveSysctlDeviation(sysctl, "ipv4.ip_forward", initial, true)
+		Expect(sysctl.Initial).To(BeEmpty())
+		Expect(sysctl.DiffersFromInitial).To(BeFalse())
+		Expect(sysctl.DiffersFromDefault).To(BeTrue())
+
+		sysctl = &Sysctl{Value: "2"}
+		resolveSysctlDeviation(sysctl, "ipv4.conf.default.rp_filter", initial, false)
+		Expect(sysctl.DiffersFromInitial).To(BeFalse())
+		Expect(sysctl.DiffersFromDefault).To(BeTrue())
+
+		sysctl = &Sysctl{Value: "42"}
+		resolveSysctlDeviation(sysctl, "core.foobar", initial, false)
+		Expect(sysctl).To(Equal(&Sysctl{Value: "42"}))
+
+		Expect(Sysctls{
+			"a": {Value: "1", DiffersFromDefault: true},
+			"b": {Value: "1"},
+			"c": {Value: "1", DiffersFromInitial: true},
+		}.Deviations()).To(HaveKey("a"))
+	})
+
+	It("compares per-interface sysctls with the defaults of their own network namespace", func() {
+		hostnetns := &NetworkNamespace{
+			Namespace: fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 1}},
+			Sysctls: Sysctls{
+				"ipv4.conf.default.rp_filter": {Value: "2"},
+			},
+		}
+		cntrnetns := &NetworkNamespace{
+			Namespace: fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 2}},
+			Sysctls: Sysctls{
+				"ipv4.conf.default.rp_filter": {Value: "1"},
+			},
+		}
+		hostnif := &NifAttrs{Netns: hostnetns, Name: "eth0", Index: 2,
+			Sysctls: Sysctls{"ipv4.rp_filter": {Value: "1"}}}
+		cntrnif := &NifAttrs{Netns: cntrnetns, Name: "eth0", Index: 2,
+			Sysctls: Sysctls{"ipv4.rp_filter": {Value: "1"}}}
+		hostnetns.Nifs = map[int]Interface{2: hostnif}
+		cntrnetns.Nifs = map[int]Interface{2: cntrnif}
+
+		initproc := &model.Process{PID: 1}
+		initproc.Namespaces[model.NetNS] = hostnetns.Namespace
+		resolveSysctlDeviations(NetworkNamespaces{
+			hostnetns.ID(): hostnetns,
+			cntrnetns.ID(): cntrnetns,
+		}, model.ProcessTable{1: initproc})
+
+		Expect(hostnif.Sysctls["ipv4.rp_filter"]).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Initial":            Equal("2"),
+			"DiffersFromInitial": BeTrue(),
+		})))
+		Expect(cntrnif.Sysctls["ipv4.rp_filter"]).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Initial":            Equal("1"),
+			"DiffersFromInitial": BeFalse(),
+		})))
+		Expect(cntrnetns.Sysctls["ipv4.conf.default.rp_filter"].DiffersFromInitial).To(BeTrue())
+	})
+
+})
@@ -6,18 +6,15 @@ package network
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"net"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/thediveo/lxkns/model"
-	"github.com/thediveo/lxkns/ops"
-	"github.com/thediveo/lxkns/ops/mountineer"
-	"github.com/thediveo/lxkns/species"
 )
 
 // Tenant represents a process or even a container together with its
@@ -27,6 +24,8 @@ type Tenant struct {
 	Process      *model.Process   // associated ealdorman process
 	BoundingCaps []byte           // bounding capabilities in form of a byte string with lsb being bit 0 of the last byte.
 	DNS          DnsConfiguration // DNS and name resolution configuration
+	// container engine API sockets mounted into this tenant's container.
+	EngineAPIMounts []EngineAPIMount
 }
 
 // DnsConfiguration contains DNS/name resolution-related configuration
@@ -47,17 +46,31 @@ type Tenants []*Tenant
 // NewTenant returns a new Tenant corresponding with the specified process (and
 // its optional container) and with its DNS configuration discovered.
 func NewTenant(proc *model.Process) *Tenant {
+	return newTenant(live, proc, true)
+}
+
+// newTenant returns a new Tenant corresponding with the specified process (and
+// its optional container), optionally with its DNS configuration discovered,
+// based on the information from the specified source.
+func newTenant(src Source, proc *model.Process, withDNS bool) *Tenant {
 	t := &Tenant{Process: proc}
-	t.BoundingCaps = t.caps("CapBnd")
-	tenantfs, err := mountineer.New(proc.Namespaces[model.MountNS].Ref(), nil)
+	t.BoundingCaps = t.caps(src, "CapBnd")
+	if !withDNS {
+		// ensure non-null when marshalling
+		t.DNS.Hosts = map[string]net.IP{}
+		t.DNS.Nameservers = []net.IP{}
+		t.DNS.Searchlist = []string{}
+		return t
+	}
+	tenantfs, closer, err := src.MountFiles(proc.Namespaces[model.MountNS])
 	if err != nil {
 		return t
 	}
-	defer tenantfs.Close()
+	defer closer()
 
 	t.DNS.EtcHostname = t.readSingleLine(tenantfs, "/etc/hostname")
 	t.DNS.EtcDomainname = t.readSingleLine(tenantfs, "/etc/domainname")
-	t.DNS.Hostname = t.uname()
+	t.DNS.Hostname = t.uname(src)
 	t.DNS.Hosts = t.readHosts(tenantfs)
 	t.DNS.Nameservers, t.DNS.Searchlist = t.readResolvConf(tenantfs)
 
@@ -77,15 +90,14 @@ func (t *Tenant) Name() string {
 // readResolvConfig parses /etc/resolv.conf and returns the list of name servers
 // as well as the search list defined in it. It mimics the parsing behavior
 // specified in https://man7.org/linux/man-pages/man5/resolv.conf.5.html.
-func (t *Tenant) readResolvConf(tfs *mountineer.Mountineer) (nameservers []net.IP, searchlist []string) {
+func (t *Tenant) readResolvConf(tfs Files) (nameservers []net.IP, searchlist []string) {
 	nameservers = []net.IP{} // ensure non-null when marshalling
 	searchlist = []string{}
-	f, err := tfs.Open("/etc/resolv.conf")
+	contents, err := tfs.ReadFile("/etc/resolv.conf")
 	if err != nil {
 		return
 	}
-	defer func() { _ = f.Close() }()
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -134,14 +146,13 @@ func (t *Tenant) readResolvConf(tfs *mountineer.Mountineer) (nameservers []net.I
 // readHosts read /etc/hosts, if present, and returns the hostname-to-IP address
 // mapping defined in it. It mimics the parsing behavior specified in
 // http://man7.org/linux/man-pages/man5/hosts.5.html.
-func (t *Tenant) readHosts(tfs *mountineer.Mountineer) map[string]net.IP {
+func (t *Tenant) readHosts(tfs Files) map[string]net.IP {
 	hosts := map[string]net.IP{}
-	f, err := tfs.Open("/etc/hosts")
+	contents, err := tfs.ReadFile("/etc/hosts")
 	if err != nil {
 		return hosts
 	}
-	defer func() { _ = f.Close() }()
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 
 	for scanner.Scan() {
 		// According to hosts(5), "#" can appear anywhere in a line, starting
@@ -170,13 +181,12 @@ func (t *Tenant) readHosts(tfs *mountineer.Mountineer) map[string]net.IP {
 // anything else. Additionally puts a limit of 256 characters on the line read,
 // which is sufficient for more or less well-formed host and domain names. This
 // avoids attacks on our discovery.
-func (t *Tenant) readSingleLine(tfs *mountineer.Mountineer, path string) string {
-	f, err := tfs.Open(path)
+func (t *Tenant) readSingleLine(tfs Files, path string) string {
+	contents, err := tfs.ReadFile(path)
 	if err != nil {
 		return ""
 	}
-	defer func() { _ = f.Close() }()
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 	scanner.Buffer(make([]byte, 256), 256)
 	if !scanner.Scan() {
 		return ""
@@ -186,29 +196,25 @@ func (t *Tenant) readSingleLine(tfs *mountineer.Mountineer, path string) string
 
 // uname returns the UTS-namespaced uname() of this tenant. Returns the zero
 // name on any failure.
-func (t *Tenant) uname() string {
-	var hostname string
-	utsnsref := t.Process.Namespaces[model.UTSNS].Ref()
-	if len(utsnsref) != 1 {
+func (t *Tenant) uname(src Source) string {
+	utsns := t.Process.Namespaces[model.UTSNS]
+	if utsns == nil {
 		return ""
 	}
-	_ = ops.Visit(func() {
-		hostname, _ = os.Hostname()
-	}, ops.NewTypedNamespacePath(utsnsref[0], species.CLONE_NEWUTS))
+	hostname, _ := src.Hostname(utsns)
 	return hostname
 }
 
 // boundedCaps discovers the set of capabilities from the tenant's process'
 // status information and returns it as a []byte.
-func (t *Tenant) caps(key string) []byte {
-	f, err := os.Open("/proc/" + strconv.FormatUint(uint64(t.Process.PID), 10) + "/status")
+func (t *Tenant) caps(files Files, key string) []byte {
+	contents, err := files.ReadFile("/proc/" + strconv.FormatUint(uint64(t.Process.PID), 10) + "/status")
 	if err != nil {
 		return nil
 	}
-	defer func() { _ = f.Close() }()
 
 	var capBnd []byte
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
 	for scanner.Scan() {
 		fields := strings.SplitN(scanner.Text(), ":", 2)
 		if len(fields) != 2 {
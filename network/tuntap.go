@@ -41,7 +41,13 @@ func (n *TunTapAttrs) TunTap() *TunTapAttrs { return n }
 // Init initializes this TUN/TAP Nif from information specified in the
 // NetworkNamespace and lots of netlink.Link information.
 func (n *TunTapAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
-	n.NifAttrs.Init(nlh, netns, link)
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this TUN/TAP Nif, getting its addresses from the
+// specified AddrLister.
+func (n *TunTapAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.initFromSource(nlh, netns, link)
 	attrs := n.Link.(*netlink.Tuntap)
 	n.Mode = TunTapMode(attrs.Mode)
 }
@@ -59,8 +59,8 @@ var _ = Describe("TAPs and TUNs", func() {
 		Expect(gwtap.TunTap().Mode).To(Equal(TunTapModeTap))
 
 		By("discovering the processor")
-		Expect(iff(fmt.Sprintf("/proc/self/fdinfo/%d", tap.Fds[0].Fd()))).To(Equal(tap.Name))
-		processors := discoverProcessors(result.Processes)
+		Expect(iff(osFiles{}, fmt.Sprintf("/proc/self/fdinfo/%d", tap.Fds[0].Fd()))).To(Equal(tap.Name))
+		processors := discoverProcessors(live, result.Processes)
 		var processor tuntapProcessor
 		Expect(processors).To(ContainElement(
 			HaveField("Process.PID", model.PIDType(os.Getpid())), &processor))
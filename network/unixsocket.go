@@ -0,0 +1,331 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// unix_diag request "show" flags and attributes; see also:
+// https://elixir.bootlin.com/linux/v6.1/source/include/uapi/linux/unix_diag.h
+const (
+	udiagShowName  = 0x00000001
+	udiagShowVFS   = 0x00000002
+	udiagShowPeer  = 0x00000004
+	udiagShowRqlen = 0x00000010
+	udiagShowUID   = 0x00000040
+
+	unixDiagName  = 0
+	unixDiagVFS   = 1
+	unixDiagPeer  = 2
+	unixDiagRqlen = 4
+	unixDiagUID   = 7
+)
+
+// Sizes of struct unix_diag_req and struct unix_diag_msg.
+const (
+	sizeofUnixDiagReq = 24
+	sizeofUnixDiagMsg = 16
+)
+
+// procfs /proc/net/unix flag for listening sockets (__SO_ACCEPTCON) and the
+// socket state for connected sockets (SS_CONNECTED).
+const (
+	procfsUnixAcceptCon = 0x00010000
+	procfsUnixConnected = 3
+)
+
+// UnixSocket describes a Unix domain socket, which processes (and containers)
+// use to communicate with each other, not least using sockets bind-mounted
+// between containers, such as container engine API sockets.
+//
+// Please note that Unix domain sockets belong to the network namespace of
+// their creator, even if the filesystem paths they are bound to are only
+// subject to mount namespaces. Connected Unix domain sockets can thus have
+// peers in other network namespaces.
+type UnixSocket struct {
+	Type             int                    // socket type: unix.SOCK_STREAM, unix.SOCK_DGRAM, or unix.SOCK_SEQPACKET.
+	State            SocketState            // (detailed) socket state, reusing the TCP socket states.
+	SimplifiedState  SocketSimplifiedState  // simplified state: listening, connected, or unconnected.
+	Path             string                 // filesystem path, "@" followed by the abstract name, or "" if unnamed.
+	Inode            uint64                 // socket inode number
+	PeerInode        uint64                 // inode number of the connected peer socket, if known; only via sock_diag.
+	Peer             *UnixSocket            // connected peer socket, if known; possibly in a different network namespace.
+	VFSDev           uint64                 // device of the socket file a socket is bound to; only via sock_diag.
+	VFSInode         uint64                 // inode number of the socket file a socket is bound to; only via sock_diag.
+	RecvQueue        uint32                 // receive queue length; pending connections for listening sockets.
+	SendQueue        uint32                 // send queue length; maximum listen backlog for listening sockets.
+	UID              uint32                 // (effective) UID of the socket's creator; only via sock_diag.
+	PIDs             []model.PIDType        // processes using this socket
+	Processes        []*model.Process       // processes using this socket
+	ForeignProcesses []*model.Process       // owning processes attached to other network namespaces
+	Netns            *NetworkNamespace      // network namespace this socket belongs to.
+	EngineAPI        *model.ContainerEngine // container engine whose API this socket serves or is connected to.
+}
+
+// Abstract returns true if this Unix domain socket has an abstract name,
+// instead of being bound to a filesystem path.
+func (s *UnixSocket) Abstract() bool {
+	return strings.HasPrefix(s.Path, "@")
+}
+
+// UnixSocketTypeName returns the name of a Unix domain socket type, that is,
+// "stream", "dgram", or "seqpacket".
+func UnixSocketTypeName(socktype int) string {
+	switch socktype {
+	case syscall.SOCK_STREAM:
+		return "stream"
+	case syscall.SOCK_DGRAM:
+		return "dgram"
+	case syscall.SOCK_SEQPACKET:
+		return "seqpacket"
+	}
+	return fmt.Sprintf("SocketType(%d)", socktype)
+}
+
+// unixDiagReq is the struct unix_diag_req request for dumping all Unix domain
+// sockets.
+type unixDiagReq struct {
+	States uint32
+	Show   uint32
+}
+
+// Len returns the length of a serialized unix_diag_req.
+func (r *unixDiagReq) Len() int { return sizeofUnixDiagReq }
+
+// Serialize returns the binary unix_diag_req representation for dumping all
+// Unix domain sockets.
+func (r *unixDiagReq) Serialize() []byte {
+	b := make([]byte, sizeofUnixDiagReq)
+	b[0] = unix.AF_UNIX
+	nl.NativeEndian().PutUint32(b[4:], r.States)
+	nl.NativeEndian().PutUint32(b[12:], r.Show)
+	return b
+}
+
+// discoverUnixSockets discovers the Unix domain sockets in this network
+// namespace, preferably using the sock_diag netlink API, and otherwise falling
+// back onto procfs. Resolving peers is left to resolveUnixSocketPeers, as peers
+// might live in network namespaces not yet discovered.
+func (n *NetworkNamespace) discoverUnixSockets(sm socketToProcessMap, allprocs model.ProcessTable) {
+	var sox []*UnixSocket
+	src := sourceOf(n)
+	err := src.Visit(n.Namespace, func(nsa NetnsAccess) (err error) {
+		sox, err = diagUnixSockets(nsa, sm)
+		return
+	})
+	if err != nil {
+		if n.Ealdorman() == nil {
+			return
+		}
+		sox = discoverProcfsUnixSockets(src, "/proc", n.Ealdorman().PID, sm)
+	}
+	for _, sock := range sox {
+		sock.Netns = n
+		sock.Processes = allprocs.ProcessesByPIDs(sock.PIDs...)
+	}
+	n.UnixSockets = sox
+}
+
+// diagUnixSockets dumps the Unix domain sockets in the current network
+// namespace.
+func diagUnixSockets(nsa NetnsAccess, sm socketToProcessMap) ([]*UnixSocket, error) {
+	var msgs [][]byte
+	var err error
+	for attempt := 0; attempt < maxSockDiagDumpAttempts; attempt++ {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, unix.NLM_F_DUMP)
+		req.AddData(&unixDiagReq{
+			States: ^uint32(0), // all states
+			Show:   udiagShowName | udiagShowVFS | udiagShowPeer | udiagShowRqlen | udiagShowUID,
+		})
+		msgs, err = nsa.Execute(req, unix.NETLINK_SOCK_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	sox := make([]*UnixSocket, 0, len(msgs))
+	for _, msg := range msgs {
+		if sock := newDiagUnixSocket(msg, sm); sock != nil {
+			sox = append(sox, sock)
+		}
+	}
+	return sox, nil
+}
+
+// newDiagUnixSocket returns a UnixSocket with the information from a sock_diag
+// unix_diag_msg message, including its attributes, or nil.
+func newDiagUnixSocket(msg []byte, sm socketToProcessMap) *UnixSocket {
+	if len(msg) < sizeofUnixDiagMsg || msg[0] != unix.AF_UNIX {
+		return nil
+	}
+	sock := &UnixSocket{
+		Type:  int(msg[1]),
+		State: SocketState(msg[2]),
+		Inode: uint64(nl.NativeEndian().Uint32(msg[4:])),
+	}
+	sock.PIDs = sm[sock.Inode]
+	attrs, err := nl.ParseRouteAttr(msg[sizeofUnixDiagMsg:])
+	if err != nil {
+		attrs = nil
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case unixDiagName:
+			sock.Path = unixSocketName(attr.Value)
+		case unixDiagVFS:
+			if len(attr.Value) >= 8 {
+				sock.VFSInode = uint64(nl.NativeEndian().Uint32(attr.Value))
+				// The kernel reports its internal device number encoding, so
+				// we need to convert it into the user space encoding in order
+				// to be able to compare it with stat(2) results.
+				kdev := nl.NativeEndian().Uint32(attr.Value[4:])
+				sock.VFSDev = unix.Mkdev(kdev>>20, kdev&0xfffff)
+			}
+		case unixDiagPeer:
+			if len(attr.Value) >= 4 {
+				sock.PeerInode = uint64(nl.NativeEndian().Uint32(attr.Value))
+			}
+		case unixDiagRqlen:
+			if len(attr.Value) >= 8 {
+				sock.RecvQueue = nl.NativeEndian().Uint32(attr.Value)
+				sock.SendQueue = nl.NativeEndian().Uint32(attr.Value[4:])
+			}
+		case unixDiagUID:
+			if len(attr.Value) >= 4 {
+				sock.UID = nl.NativeEndian().Uint32(attr.Value)
+			}
+		}
+	}
+	sock.SimplifiedState = simplifyUnixSocketState(sock.Type, sock.State, sock.Path)
+	return sock
+}
+
+// unixSocketName returns the name of a Unix domain socket from its (binary)
+// sun_path, with abstract names getting prefixed by "@".
+func unixSocketName(sunpath []byte) string {
+	if len(sunpath) == 0 {
+		return ""
+	}
+	if sunpath[0] == 0 {
+		return "@" + string(sunpath[1:])
+	}
+	if idx := bytes.IndexByte(sunpath, 0); idx >= 0 {
+		sunpath = sunpath[:idx]
+	}
+	return string(sunpath)
+}
+
+// simplifyUnixSocketState returns the simplified socket state for a Unix
+// domain socket. Similar to UDP, Gostwire considers named but unconnected
+// datagram sockets to be listening.
+func simplifyUnixSocketState(socktype int, state SocketState, path string) SocketSimplifiedState {
+	switch state {
+	case TCP_LISTEN:
+		return Listening
+	case TCP_ESTABLISHED:
+		return Connected
+	}
+	if socktype == syscall.SOCK_DGRAM && path != "" {
+		return Listening
+	}
+	return Unconnected
+}
+
+// discoverProcfsUnixSockets discovers the Unix domain sockets in a network
+// namespace referenced via one of the processes attached to the network
+// namespace. This lacks peer information.
+func discoverProcfsUnixSockets(files Files, procroot string, pid model.PIDType, sm socketToProcessMap) []*UnixSocket {
+	contents, err := files.ReadFile(fmt.Sprintf("%s/%d/net/unix", procroot, pid))
+	if err != nil {
+		return []*UnixSocket{}
+	}
+	return parseProcfsUnixSockets(bytes.NewReader(contents), sm)
+}
+
+// parseProcfsUnixSockets parses the /proc/net/unix format:
+//
+//	Num       RefCount Protocol Flags    Type St Inode Path
+//	0000000000000000: 00000002 00000000 00010000 0001 01 21733 /run/systemd/private
+func parseProcfsUnixSockets(r io.Reader, sm socketToProcessMap) []*UnixSocket {
+	sox := []*UnixSocket{}
+	scanner := bufio.NewScanner(r)
+	// Skip the first "header" line.
+	if !scanner.Scan() {
+		return sox
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			continue
+		}
+		socktype, err := strconv.ParseUint(fields[4], 16, 8)
+		if err != nil {
+			continue
+		}
+		st, err := strconv.ParseUint(fields[5], 16, 8)
+		if err != nil {
+			continue
+		}
+		ino, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			continue
+		}
+		sock := &UnixSocket{
+			Type:  int(socktype),
+			State: TCP_CLOSE,
+			Inode: ino,
+			PIDs:  sm[ino],
+		}
+		switch {
+		case flags&procfsUnixAcceptCon != 0:
+			sock.State = TCP_LISTEN
+		case st == procfsUnixConnected:
+			sock.State = TCP_ESTABLISHED
+		}
+		if len(fields) > 7 {
+			sock.Path = fields[7]
+		}
+		sock.SimplifiedState = simplifyUnixSocketState(sock.Type, sock.State, sock.Path)
+		sox = append(sox, sock)
+	}
+	return sox
+}
+
+// resolveUnixSocketPeers resolves the peers of connected Unix domain sockets
+// across all network namespaces, as bind-mounted sockets allow connecting to
+// sockets in other network namespaces.
+func resolveUnixSocketPeers(netspaces NetworkNamespaces) {
+	sockets := map[uint64]*UnixSocket{}
+	for _, netns := range netspaces {
+		for _, sock := range netns.UnixSockets {
+			sockets[sock.Inode] = sock
+		}
+	}
+	for _, netns := range netspaces {
+		for _, sock := range netns.UnixSockets {
+			if sock.PeerInode != 0 {
+				sock.Peer = sockets[sock.PeerInode]
+			}
+		}
+	}
+}
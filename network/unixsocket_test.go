@@ -0,0 +1,180 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("Unix domain sockets", func() {
+
+	It("serializes requests", func() {
+		req := &unixDiagReq{States: ^uint32(0), Show: udiagShowName}
+		b := req.Serialize()
+		Expect(b).To(HaveLen(req.Len()))
+		Expect(b[0]).To(Equal(byte(unix.AF_UNIX)))
+		Expect(nl.NativeEndian().Uint32(b[4:])).To(Equal(^uint32(0)))
+		Expect(nl.NativeEndian().Uint32(b[12:])).To(Equal(uint32(udiagShowName)))
+	})
+
+	It("returns names and type names", func() {
+		Expect(unixSocketName(nil)).To(BeEmpty())
+		Expect(unixSocketName([]byte("/run/foo.sock\x00"))).To(Equal("/run/foo.sock"))
+		Expect(unixSocketName([]byte("\x00abstract"))).To(Equal("@abstract"))
+		Expect((&UnixSocket{Path: "@abstract"}).Abstract()).To(BeTrue())
+		Expect((&UnixSocket{Path: "/run/foo.sock"}).Abstract()).To(BeFalse())
+
+		Expect(UnixSocketTypeName(syscall.SOCK_STREAM)).To(Equal("stream"))
+		Expect(UnixSocketTypeName(syscall.SOCK_DGRAM)).To(Equal("dgram"))
+		Expect(UnixSocketTypeName(syscall.SOCK_SEQPACKET)).To(Equal("seqpacket"))
+		Expect(UnixSocketTypeName(42)).To(Equal("SocketType(42)"))
+	})
+
+	It("parses sock_diag Unix domain sockets", func() {
+		Expect(newDiagUnixSocket([]byte{unix.AF_UNIX}, nil)).To(BeNil())
+
+		msg := make([]byte, sizeofUnixDiagMsg)
+		msg[0] = unix.AF_UNIX
+		msg[1] = syscall.SOCK_STREAM
+		msg[2] = byte(TCP_ESTABLISHED)
+		nl.NativeEndian().PutUint32(msg[4:], 12345)
+		vfs := make([]byte, 8)
+		nl.NativeEndian().PutUint32(vfs, 666)
+		nl.NativeEndian().PutUint32(vfs[4:], 8<<20|1) // kernel-internal encoding
+		rqlen := make([]byte, 8)
+		nl.NativeEndian().PutUint32(rqlen, 1)
+		nl.NativeEndian().PutUint32(rqlen[4:], 2)
+		for _, attr := range []*nl.RtAttr{
+			nl.NewRtAttr(unixDiagName, []byte("/run/docker.sock")),
+			nl.NewRtAttr(unixDiagVFS, vfs),
+			nl.NewRtAttr(unixDiagPeer, nl.Uint32Attr(23456)),
+			nl.NewRtAttr(unixDiagRqlen, rqlen),
+			nl.NewRtAttr(unixDiagUID, nl.Uint32Attr(1000)),
+		} {
+			msg = append(msg, attr.Serialize()...)
+		}
+		sock := newDiagUnixSocket(msg, socketToProcessMap{12345: []model.PIDType{42}})
+		Expect(sock).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":            Equal(syscall.SOCK_STREAM),
+			"State":           Equal(TCP_ESTABLISHED),
+			"SimplifiedState": Equal(Connected),
+			"Path":            Equal("/run/docker.sock"),
+			"Inode":           Equal(uint64(12345)),
+			"PeerInode":       Equal(uint64(23456)),
+			"VFSDev":          Equal(unix.Mkdev(8, 1)),
+			"VFSInode":        Equal(uint64(666)),
+			"RecvQueue":       Equal(uint32(1)),
+			"SendQueue":       Equal(uint32(2)),
+			"UID":             Equal(uint32(1000)),
+			"PIDs":            ConsistOf(model.PIDType(42)),
+		})))
+	})
+
+	It("simplifies states", func() {
+		Expect(simplifyUnixSocketState(syscall.SOCK_STREAM, TCP_LISTEN, "")).To(Equal(Listening))
+		Expect(simplifyUnixSocketState(syscall.SOCK_DGRAM, TCP_CLOSE, "/dev/log")).To(Equal(Listening))
+		Expect(simplifyUnixSocketState(syscall.SOCK_DGRAM, TCP_CLOSE, "")).To(Equal(Unconnected))
+		Expect(simplifyUnixSocketState(syscall.SOCK_STREAM, TCP_CLOSE, "/foo")).To(Equal(Unconnected))
+	})
+
+	It("parses procfs Unix domain sockets", func() {
+		sox := parseProcfsUnixSockets(strings.NewReader(
+			"Num       RefCount Protocol Flags    Type St Inode Path\n"+
+				"0000000000000000: 00000002 00000000 00010000 0001 01 21733 /run/systemd/private\n"+
+				"0000000000000000: 00000003 00000000 00000000 0001 03 21734\n"+
+				"0000000000000000: 00000002 00000000 00000000 0002 01 21735 @abstract\n"+
+				"garbage\n"),
+			socketToProcessMap{21734: []model.PIDType{42}})
+		Expect(sox).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Path":            Equal("/run/systemd/private"),
+				"State":           Equal(TCP_LISTEN),
+				"SimplifiedState": Equal(Listening),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Path":            BeEmpty(),
+				"SimplifiedState": Equal(Connected),
+				"PIDs":            ConsistOf(model.PIDType(42)),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":            Equal(syscall.SOCK_DGRAM),
+				"Path":            Equal("@abstract"),
+				"SimplifiedState": Equal(Listening),
+			})),
+		))
+		Expect(discoverProcfsUnixSockets(osFiles{}, "./test/proc", 0, nil)).To(BeEmpty())
+	})
+
+	It("resolves peers across network namespaces and engine APIs", func() {
+		apisock := filepath.Join(GinkgoT().TempDir(), "engine.sock")
+		l, err := net.Listen("unix", apisock)
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		var stat unix.Stat_t
+		Expect(unix.Stat(apisock, &stat)).To(Succeed())
+
+		engine := &model.ContainerEngine{API: "unix://" + apisock, PID: 1}
+		server := &UnixSocket{Inode: 1, PeerInode: 2, VFSDev: stat.Dev, VFSInode: stat.Ino}
+		client := &UnixSocket{Inode: 2, PeerInode: 1}
+		stranger := &UnixSocket{Inode: 3, PeerInode: 4}
+		hostnetns := &NetworkNamespace{
+			UnixSockets: []*UnixSocket{server},
+			Tenants: Tenants{&Tenant{Process: &model.Process{
+				PID:       model.PIDType(os.Getpid()),
+				Container: &model.Container{Engine: engine},
+			}}},
+		}
+		cntrnetns := &NetworkNamespace{UnixSockets: []*UnixSocket{client, stranger}}
+		netspaces := NetworkNamespaces{
+			species.NamespaceID{Dev: 1, Ino: 1}: hostnetns,
+			species.NamespaceID{Dev: 1, Ino: 2}: cntrnetns,
+		}
+		resolveUnixSocketPeers(netspaces)
+		Expect(server.Peer).To(BeIdenticalTo(client))
+		Expect(client.Peer).To(BeIdenticalTo(server))
+		Expect(stranger.Peer).To(BeNil())
+
+		resolveEngineAPISockets(netspaces, live, nil)
+		Expect(server.EngineAPI).To(BeIdenticalTo(engine))
+		Expect(client.EngineAPI).To(BeIdenticalTo(engine))
+		Expect(stranger.EngineAPI).To(BeNil())
+		Expect(hostnetns.Tenants[0].EngineAPIMounts).To(BeEmpty())
+	})
+
+	It("discovers Unix domain sockets in the current network namespace", func() {
+		if unix.Geteuid() != 0 {
+			Skip("needs root")
+		}
+		path := filepath.Join(GinkgoT().TempDir(), "test.sock")
+		l, err := net.Listen("unix", path)
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		var stat unix.Stat_t
+		Expect(unix.Stat(path, &stat)).To(Succeed())
+
+		sox, err := diagUnixSockets(&liveNetnsAccess{ethtoolFd: -1}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sox).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Path":     Equal(path),
+			"State":    Equal(TCP_LISTEN),
+			"VFSDev":   Equal(stat.Dev),
+			"VFSInode": Equal(stat.Ino),
+		}))))
+	})
+
+})
@@ -38,7 +38,13 @@ func (n *VlanAttrs) Vlan() *VlanAttrs { return n }
 // Init initializes this VLAN Nif from information specified in the
 // NetworkNamespace and lots of netlink.Link information.
 func (n *VlanAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
-	n.NifAttrs.Init(nlh, netns, link)
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this VLAN Nif, getting its addresses from the
+// specified AddrLister.
+func (n *VlanAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.initFromSource(nlh, netns, link)
 	attrs := n.Link.(*netlink.Vlan)
 	n.VID = uint16(attrs.VlanId)
 	n.VlanProtocol = attrs.VlanProtocol
@@ -49,7 +49,13 @@ func (n *VxlanAttrs) Vxlan() *VxlanAttrs { return n }
 // Init initializes this VXLAN Nif from information specified in the
 // NetworkNamespace and lots of netlink.Link information.
 func (n *VxlanAttrs) Init(nlh *netlink.Handle, netns *NetworkNamespace, link netlink.Link) {
-	n.NifAttrs.Init(nlh, netns, link)
+	n.initFromSource(nlh, netns, link)
+}
+
+// initFromSource initializes this VXLAN Nif, getting its addresses from the
+// specified AddrLister.
+func (n *VxlanAttrs) initFromSource(nlh AddrLister, netns *NetworkNamespace, link netlink.Link) {
+	n.NifAttrs.initFromSource(nlh, netns, link)
 	attrs := n.Link.(*netlink.Vxlan)
 	n.VID = uint32(attrs.VxlanId)
 	if groupv4 := attrs.Group.To4(); groupv4 != nil {
@@ -0,0 +1,79 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/thediveo/lxkns/ops"
+)
+
+// parallelize calls fn for each index in [0, n) on at most the specified
+// number of concurrent workers and waits for all calls to finish. As soon as
+// the specified context is done, parallelize stops calling fn for any further
+// indices, returning the number of indices skipped.
+//
+// Each worker runs locked to its own OS thread: discovery switches network
+// namespaces in order to open sockets and read namespace-specific procfs
+// files. In case switching back fails, lxkns' ops.Visit leaves the thread
+// locked. A worker finding itself on such a tainted thread thus terminates
+// without unlocking, so that the Go runtime throws the thread away, and a
+// fresh worker takes over.
+func parallelize(ctx context.Context, n int, workers int, fn func(idx int)) (skipped int) {
+	if workers > n {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	var worker func()
+	worker = func() {
+		defer wg.Done()
+		runtime.LockOSThread()
+		for idx := range indices {
+			fn(idx)
+			if !inInitialNetns() {
+				wg.Add(1)
+				go worker()
+				return
+			}
+		}
+		runtime.UnlockOSThread()
+	}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go worker()
+	}
+dispatch:
+	for idx := 0; idx < n; idx++ {
+		if ctx.Err() != nil {
+			skipped = n - idx
+			break
+		}
+		select {
+		case indices <- idx:
+		case <-ctx.Done():
+			skipped = n - idx
+			break dispatch
+		}
+	}
+	close(indices)
+	wg.Wait()
+	return
+}
+
+// inInitialNetns returns true if the current OS thread is still attached to
+// the network namespace we started in, or if we cannot tell at all.
+func inInitialNetns() bool {
+	if gwnetnserr != nil {
+		return true
+	}
+	netnsid, err := ops.NamespacePath("/proc/thread-self/ns/net").ID()
+	return err != nil || netnsid == gwnetnsid
+}
@@ -0,0 +1,108 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package network
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/siemens/ghostwire/v2/diagnostics"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("discovery options and workers", func() {
+
+	It("defaults to as many workers as CPUs", func() {
+		Expect(newDiscoveryOptions().maxWorkers).To(Equal(runtime.GOMAXPROCS(0)))
+		Expect(newDiscoveryOptions(WithMaxWorkers(-1)).maxWorkers).To(Equal(runtime.GOMAXPROCS(0)))
+		Expect(newDiscoveryOptions(WithMaxWorkers(42)).maxWorkers).To(Equal(42))
+	})
+
+	DescribeTable("calls each index exactly once with bounded concurrency",
+		func(n int, workers int, maxExpected int32) {
+			var mu sync.Mutex
+			seen := map[int]int{}
+			var active, maxActive int32
+			Expect(parallelize(context.Background(), n, workers, func(idx int) {
+				now := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				for {
+					max := atomic.LoadInt32(&maxActive)
+					if now <= max || atomic.CompareAndSwapInt32(&maxActive, max, now) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				seen[idx]++
+				mu.Unlock()
+			})).To(BeZero())
+			Expect(seen).To(HaveLen(n))
+			for idx := 0; idx < n; idx++ {
+				Expect(seen).To(HaveKeyWithValue(idx, 1))
+			}
+			Expect(maxActive).To(BeNumerically("<=", maxExpected))
+		},
+		Entry("no work", 0, 4, int32(0)),
+		Entry("fewer items than workers", 2, 4, int32(2)),
+		Entry("more items than workers", 20, 3, int32(3)),
+		Entry("zero workers", 5, 0, int32(1)),
+	)
+
+	It("stops when cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int32
+		skipped := parallelize(ctx, 10, 1, func(idx int) {
+			if atomic.AddInt32(&calls, 1) == 2 {
+				cancel()
+			}
+		})
+		Expect(calls).To(BeNumerically(">=", 2))
+		Expect(int(calls) + skipped).To(Equal(10))
+		Expect(skipped).To(BeNumerically(">", 0))
+	})
+
+	It("restricts and slims down the discovery", func() {
+		dopts := newDiscoveryOptions(
+			WithNetworkNamespaces(species.NamespaceID{Dev: 1, Ino: 42}),
+			WithoutSockets(), WithoutForwardedPorts(), WithoutDNS())
+		Expect(dopts.ctx).NotTo(BeNil())
+		Expect(dopts.netns).To(HaveLen(1))
+		Expect(dopts.netns).To(HaveKey(species.NamespaceID{Dev: 1, Ino: 42}))
+		Expect(dopts.skipSockets).To(BeTrue())
+		Expect(dopts.skipForwardedPorts).To(BeTrue())
+		Expect(dopts.skipDNS).To(BeTrue())
+		Expect(newDiscoveryOptions(WithNetworkNamespaces()).netns).To(BeEmpty())
+		Expect(newDiscoveryOptions().netns).To(BeNil())
+
+		t := newTenant(live, &model.Process{PID: model.PIDType(os.Getpid())}, false)
+		Expect(t.DNS.Nameservers).NotTo(BeNil())
+		Expect(t.DNS.Hosts).NotTo(BeNil())
+	})
+
+	It("collects diagnostics", func() {
+		diags := diagnostics.New()
+		dopts := newDiscoveryOptions(WithDiagnostics(diags))
+		netns := refNamespace{
+			fakeNamespace: fakeNamespace{id: species.NamespaceID{Dev: 1, Ino: 42}},
+			ref:           model.NamespaceRef{"/non-existing/ns/net"},
+		}
+		Expect(newNetworkNamespace(netns, nil, &dopts)).To(BeNil())
+		Expect(diags.List()).To(ConsistOf(And(
+			HaveField("Severity", diagnostics.Error),
+			HaveField("Code", diagnostics.NetnsUnavailable),
+			HaveField("Scope", diagnostics.Scope{Netns: 42}),
+		)))
+	})
+
+})
@@ -0,0 +1,170 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package gostwire
+
+import (
+	"time"
+
+	"github.com/siemens/ghostwire/v2/internal/discover"
+	"github.com/thediveo/lxkns/species"
+)
+
+// DiscoveryOptions controls the scope of a discovery as well as which parts of
+// the discovery to skip.
+type DiscoveryOptions = discover.Options
+
+// DefaultPluginTimeout is the time each decorator and metadata plugin gets to
+// finish, unless specified otherwise.
+const DefaultPluginTimeout = discover.DefaultPluginTimeout
+
+// DiscoveryOption sets a particular discovery option when passed to Discover.
+type DiscoveryOption = discover.Option
+
+// WithNetworkNamespaces restricts the discovery to the specified network
+// namespaces. When combined with WithContainers and WithEngines, the discovery
+// is restricted to the union of all specified network namespaces.
+//
+// Please note that a restricted discovery cannot resolve relations to network
+// namespaces outside the discovered set, such as VETH peers.
+func WithNetworkNamespaces(netnsids ...species.NamespaceID) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.NetworkNamespaces = append(o.NetworkNamespaces, netnsids...)
+	}
+}
+
+// WithContainers restricts the discovery to the network namespaces of the
+// containers with the specified names or IDs.
+func WithContainers(nameorids ...string) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.Containers = append(o.Containers, nameorids...)
+	}
+}
+
+// WithEngines restricts the discovery to the network namespaces of the
+// containers managed by the container engines with the specified types or
+// IDs, such as "docker.com".
+func WithEngines(typeorids ...string) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.Engines = append(o.Engines, typeorids...)
+	}
+}
+
+// WithoutSockets skips discovering transport-layer ports, MPTCP connections,
+// packet sockets, Unix domain sockets, and their owning processes.
+func WithoutSockets() DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.SkipSockets = true
+	}
+}
+
+// WithoutForwardedPorts skips discovering forwarded ports.
+func WithoutForwardedPorts() DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.SkipForwardedPorts = true
+	}
+}
+
+// WithoutDNS skips discovering the DNS configuration of tenants.
+func WithoutDNS() DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.SkipDNS = true
+	}
+}
+
+// WithoutDecorators skips the decorator plugins with the specified names, such
+// as "dockernet".
+func WithoutDecorators(names ...string) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.SkipDecorators = append(o.SkipDecorators, names...)
+	}
+}
+
+// WithoutMetadata skips the metadata plugins with the specified names, such as
+// "host".
+func WithoutMetadata(names ...string) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.SkipMetadata = append(o.SkipMetadata, names...)
+	}
+}
+
+// WithNetworkTimeout limits the time spent in discovering the details of
+// network namespaces. Network namespaces not discovered in time are left out
+// of the discovery result.
+func WithNetworkTimeout(timeout time.Duration) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.NetworkTimeout = timeout
+	}
+}
+
+// WithDecoratorsTimeout limits the time spent in running the decorator
+// plugins. Decorators not yet run when the timeout is reached are skipped.
+func WithDecoratorsTimeout(timeout time.Duration) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.DecoratorsTimeout = timeout
+	}
+}
+
+// WithMetadataTimeout limits the time spent in running the metadata plugins.
+// Metadata plugins not yet run when the timeout is reached are skipped.
+func WithMetadataTimeout(timeout time.Duration) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.MetadataTimeout = timeout
+	}
+}
+
+// WithPluginTimeout limits the time each decorator and metadata plugin gets to
+// finish, unless overridden for individual plugins using WithPluginTimeoutFor.
+// A negative timeout means no timeout at all. Without this option, plugins are
+// limited to the DefaultPluginTimeout.
+//
+// Decorators modify the discovery results in place, so the discovery always
+// waits for them to return: a decorator ignoring its context thus still blocks
+// the discovery beyond its timeout, which then only counts as a failure. In
+// contrast, the discovery stops waiting for metadata plugins at their timeout,
+// but a metadata plugin ignoring its context keeps running in the background
+// until it finally returns.
+func WithPluginTimeout(timeout time.Duration) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.PluginTimeout = timeout
+	}
+}
+
+// WithPluginTimeoutFor limits the time the named decorator or metadata plugin
+// gets to finish. A negative timeout means no timeout at all.
+func WithPluginTimeoutFor(name string, timeout time.Duration) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		if o.PluginTimeouts == nil {
+			o.PluginTimeouts = map[string]time.Duration{}
+		}
+		o.PluginTimeouts[name] = timeout
+	}
+}
+
+// Source supplies a discovery with all the system information it is based on,
+// such as netlink dumps, procfs files, and container engine API responses.
+type Source = discover.Source
+
+// LiveSource returns the Source discovering the host the discovery runs on.
+// This is the default source, unless specified otherwise using WithSource.
+func LiveSource() Source {
+	return discover.LiveSource()
+}
+
+// WithSource runs the discovery on the system information from the specified
+// source instead of the host the discovery runs on, such as replaying a
+// support bundle taken on another host.
+func WithSource(src Source) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.Source = src
+	}
+}
+
+// WithMaxWorkers limits the number of network namespaces concurrently being
+// discovered; zero means as many as there are CPUs.
+func WithMaxWorkers(n int) DiscoveryOption {
+	return func(o *DiscoveryOptions) {
+		o.MaxWorkers = n
+	}
+}
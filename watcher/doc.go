@@ -0,0 +1,35 @@
+/*
+Package watcher keeps a Gostwire discovery result up to date in a long-running
+process, rediscovering the host only when something has changed instead of on
+every request.
+
+A Watcher subscribes to RTNETLINK link, address, route, and neighbor
+notifications in every discovered network namespace. In addition, it
+periodically runs a lightweight namespace and container scan in order to notice
+network namespaces getting created and destroyed, as well as containers
+starting and stopping. Events arriving in short succession are batched and then
+trigger a new full discovery.
+
+Please note that a Watcher doesn't patch the previous discovery result using
+the events; the events only tell when to rediscover and are passed on to
+subscribers as hints about what changed. The network model consists of heavily
+interlinked objects, such as network interfaces referencing their peers and
+masters in other network namespaces, so patching a shared model would leave
+readers with inconsistent views. Also, a Watcher doesn't subscribe to container
+engine events: the containerizer (such as a turtlefinder) keeps its engine
+watchers to itself, so container lifecycle changes only get noticed by the
+periodic scans.
+
+Each update produces a new immutable Snapshot. Library users can get the
+current Snapshot at any time using Watcher.Snapshot and subscribe to Change
+notifications using Watcher.Subscribe.
+
+	w := watcher.New(cizer)
+	go w.Run(ctx)
+	sub := w.Subscribe(16)
+	defer sub.Close()
+	for change := range sub.C {
+	    // ... change.Snapshot.Result ...
+	}
+*/
+package watcher
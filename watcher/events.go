@@ -0,0 +1,63 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"github.com/thediveo/lxkns/species"
+)
+
+// EventKind describes what happened.
+type EventKind string
+
+// The kinds of events a Watcher reacts to.
+const (
+	LinkEvent        EventKind = "link"              // network interface added, changed, or removed.
+	AddressEvent     EventKind = "address"           // IP address added or removed.
+	RouteEvent       EventKind = "route"             // route added or removed.
+	NeighborEvent    EventKind = "neighbor"          // static neighbor entry added or removed.
+	NetnsCreated     EventKind = "netns-created"     // network namespace appeared.
+	NetnsDestroyed   EventKind = "netns-destroyed"   // network namespace vanished.
+	ContainerStarted EventKind = "container-started" // container appeared.
+	ContainerStopped EventKind = "container-stopped" // container vanished.
+	Resync           EventKind = "resync"            // events were lost, so resynchronizing.
+)
+
+// Event describes a single change noticed by a Watcher.
+type Event struct {
+	Kind      EventKind
+	Netns     species.NamespaceID // network namespace concerned, if any.
+	Container string              // ID of the container concerned, if any.
+}
+
+// eventSet is a set of events, keeping the order of first occurrence.
+type eventSet struct {
+	events []Event
+	seen   map[Event]struct{}
+}
+
+// add adds the specified event, unless it already is in the set.
+func (s *eventSet) add(ev Event) {
+	if s.seen == nil {
+		s.seen = map[Event]struct{}{}
+	}
+	if _, ok := s.seen[ev]; ok {
+		return
+	}
+	s.seen[ev] = struct{}{}
+	s.events = append(s.events, ev)
+}
+
+// take returns the events in the set and empties the set.
+func (s *eventSet) take() []Event {
+	events := s.events
+	s.events = nil
+	s.seen = nil
+	return events
+}
+
+// empty returns true if there are no events in the set.
+func (s *eventSet) empty() bool {
+	return len(s.events) == 0
+}
@@ -0,0 +1,230 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/siemens/ghostwire/v2/network"
+
+	"github.com/thediveo/lxkns/log"
+	"github.com/thediveo/lxkns/species"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// rtnlGroups are the RTNETLINK multicast groups a Watcher subscribes to.
+var rtnlGroups = []uint{
+	unix.RTNLGRP_LINK,
+	unix.RTNLGRP_IPV4_IFADDR,
+	unix.RTNLGRP_IPV6_IFADDR,
+	unix.RTNLGRP_IPV4_ROUTE,
+	unix.RTNLGRP_IPV6_ROUTE,
+	unix.RTNLGRP_NEIGH,
+}
+
+// rtnlWatches manages the RTNETLINK subscriptions in individual network
+// namespaces, forwarding the notifications as events.
+type rtnlWatches struct {
+	ctx     context.Context
+	events  chan<- Event
+	watches map[species.NamespaceID]*rtnlWatch
+}
+
+// rtnlWatch is the RTNETLINK subscription in a single network namespace.
+//
+// We don't use netlink's LinkSubscribe and friends here, as their receiving
+// go routines stay blocked in the receive syscall even after their sockets
+// have been closed, until the next notification arrives. For network
+// namespaces that have been destroyed there is never going to be any next
+// notification, so these go routines would leak, and the blocked sockets would
+// keep the destroyed network namespaces alive. Instead, we wait for either
+// notifications or an eventfd to wake up.
+type rtnlWatch struct {
+	wakefd int           // eventfd signalling the watch to stop.
+	done   chan struct{} // closed after the watch has stopped.
+}
+
+// newRtnlWatches returns a new and still empty set of RTNETLINK watches,
+// forwarding events to the specified channel.
+func newRtnlWatches(ctx context.Context, events chan<- Event) *rtnlWatches {
+	return &rtnlWatches{
+		ctx:     ctx,
+		events:  events,
+		watches: map[species.NamespaceID]*rtnlWatch{},
+	}
+}
+
+// sync starts watching the specified network namespaces not yet watched and
+// stops watching the network namespaces not specified anymore.
+func (r *rtnlWatches) sync(allnetns network.NetworkNamespaces) {
+	for netnsid := range r.watches {
+		if _, ok := allnetns[netnsid]; !ok {
+			r.unwatch(netnsid)
+		}
+	}
+	for netnsid, netns := range allnetns {
+		if _, ok := r.watches[netnsid]; ok {
+			continue
+		}
+		watch, err := r.watch(netns)
+		if err != nil {
+			log.Warnf("cannot watch RTNETLINK in net:[%d], reason: %s", netnsid.Ino, err.Error())
+			continue
+		}
+		r.watches[netnsid] = watch
+	}
+}
+
+// unwatch stops watching the specified network namespace, so that the next
+// sync will start watching it afresh.
+func (r *rtnlWatches) unwatch(netnsid species.NamespaceID) {
+	if watch, ok := r.watches[netnsid]; ok {
+		watch.stop()
+		delete(r.watches, netnsid)
+	}
+}
+
+// close stops all watches.
+func (r *rtnlWatches) close() {
+	for netnsid := range r.watches {
+		r.unwatch(netnsid)
+	}
+}
+
+// watch subscribes to the link, address, route, and neighbor notifications of
+// the specified network namespace.
+func (r *rtnlWatches) watch(netwns *network.NetworkNamespace) (*rtnlWatch, error) {
+	fd, closer, err := network.NamespaceFd(netwns.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// The subscription socket references the network namespace on its own, so
+	// we don't need the fd anymore after subscribing.
+	defer closer()
+	sock, err := nl.SubscribeAt(netns.NsHandle(fd), netns.None(), unix.NETLINK_ROUTE, rtnlGroups...)
+	if err != nil {
+		return nil, err
+	}
+	wakefd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		sock.Close()
+		return nil, err
+	}
+	watch := &rtnlWatch{
+		wakefd: wakefd,
+		done:   make(chan struct{}),
+	}
+	go r.receive(netwns.ID(), sock, watch)
+	return watch, nil
+}
+
+// stop stops the watch and waits for it to have stopped.
+func (w *rtnlWatch) stop() {
+	var one [8]byte
+	binary.NativeEndian.PutUint64(one[:], 1)
+	_, _ = unix.Write(w.wakefd, one[:])
+	<-w.done
+	unix.Close(w.wakefd)
+}
+
+// receive receives RTNETLINK notifications and forwards them as events, until
+// the watch is stopped or the receive fails. In the latter case, it requests a
+// resynchronization.
+func (r *rtnlWatches) receive(netnsid species.NamespaceID, sock *nl.NetlinkSocket, watch *rtnlWatch) {
+	defer close(watch.done)
+	defer sock.Close()
+	fds := []unix.PollFd{
+		{Fd: int32(sock.GetFd()), Events: unix.POLLIN},
+		{Fd: int32(watch.wakefd), Events: unix.POLLIN},
+	}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			r.resync(netnsid, watch, err)
+			return
+		}
+		if fds[1].Revents != 0 {
+			return
+		}
+		msgs, from, err := sock.Receive()
+		if err != nil {
+			r.resync(netnsid, watch, err)
+			return
+		}
+		if from.Pid != nl.PidKernel {
+			continue
+		}
+		for _, msg := range msgs {
+			kind, ok := eventKind(msg.Header.Type, msg.Data)
+			if !ok {
+				continue
+			}
+			if !r.send(watch, Event{Kind: kind, Netns: netnsid}) {
+				return
+			}
+		}
+	}
+}
+
+// resync logs the error and requests a resynchronization.
+func (r *rtnlWatches) resync(netnsid species.NamespaceID, watch *rtnlWatch, err error) {
+	log.Warnf("RTNETLINK watch in net:[%d] failed, reason: %s", netnsid.Ino, err.Error())
+	r.send(watch, Event{Kind: Resync, Netns: netnsid})
+}
+
+// send sends the specified event, returning false if the watch has been
+// stopped in the meantime.
+func (r *rtnlWatches) send(watch *rtnlWatch, ev Event) bool {
+	fds := []unix.PollFd{{Fd: int32(watch.wakefd), Events: unix.POLLIN}}
+	for {
+		select {
+		case r.events <- ev:
+			return true
+		case <-r.ctx.Done():
+			return false
+		default:
+		}
+		// The Watcher might be just about to stop us, so we must not block on
+		// sending; instead, check for being stopped every now and then.
+		if n, _ := unix.Poll(fds, 10); n > 0 {
+			return false
+		}
+	}
+}
+
+// eventKind returns the kind of event for the specified RTNETLINK message type
+// and message data, or false if the message is irrelevant.
+func eventKind(msgtype uint16, data []byte) (EventKind, bool) {
+	switch msgtype {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		return LinkEvent, true
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		return AddressEvent, true
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		return RouteEvent, true
+	case unix.RTM_NEWNEIGH, unix.RTM_DELNEIGH:
+		neigh, err := netlink.NeighDeserialize(data)
+		if err != nil || !staticNeighbor(neigh) {
+			return "", false
+		}
+		return NeighborEvent, true
+	}
+	return "", false
+}
+
+// staticNeighbor returns true if the neighbor is a static neighbor entry, such
+// as those configured by overlay networks. Dynamic neighbor entries keep
+// changing their state all the time, without being of any relevance to the
+// discovery information model.
+func staticNeighbor(neigh *netlink.Neigh) bool {
+	return neigh.State&(unix.NUD_PERMANENT|unix.NUD_NOARP) != 0
+}
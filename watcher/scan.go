@@ -0,0 +1,78 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"github.com/thediveo/lxkns/containerizer"
+	lxknsdiscover "github.com/thediveo/lxkns/discover"
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+)
+
+// fingerprint identifies the network namespaces and containers seen, in order
+// to detect network namespaces and containers coming and going.
+type fingerprint struct {
+	netns      map[species.NamespaceID]struct{}
+	containers map[string]model.PIDType // container IDs and their PIDs.
+}
+
+// scan runs a lightweight namespace and container discovery without any
+// network details and returns its fingerprint.
+func scan(cizer containerizer.Containerizer) fingerprint {
+	result := lxknsdiscover.Namespaces(
+		lxknsdiscover.FromProcs(),
+		lxknsdiscover.FromBindmounts(),
+		lxknsdiscover.WithNamespaceTypes(
+			species.CLONE_NEWNET|species.CLONE_NEWPID|species.CLONE_NEWNS),
+		lxknsdiscover.WithHierarchy(),
+		lxknsdiscover.WithContainerizer(cizer),
+		lxknsdiscover.WithPIDMapper(),
+	)
+	return fingerprintOf(result)
+}
+
+// fingerprintOf returns the fingerprint of the specified namespaces and
+// containers discovery result.
+func fingerprintOf(result *lxknsdiscover.Result) fingerprint {
+	fp := fingerprint{
+		netns:      map[species.NamespaceID]struct{}{},
+		containers: map[string]model.PIDType{},
+	}
+	if result == nil {
+		return fp
+	}
+	for netnsid := range result.Namespaces[model.NetNS] {
+		fp.netns[netnsid] = struct{}{}
+	}
+	for _, cntr := range result.Containers {
+		fp.containers[cntr.ID] = cntr.PID
+	}
+	return fp
+}
+
+// diff adds the events to the specified set that turn the old fingerprint into
+// the new fingerprint.
+func (fp fingerprint) diff(newfp fingerprint, events *eventSet) {
+	for netnsid := range newfp.netns {
+		if _, ok := fp.netns[netnsid]; !ok {
+			events.add(Event{Kind: NetnsCreated, Netns: netnsid})
+		}
+	}
+	for netnsid := range fp.netns {
+		if _, ok := newfp.netns[netnsid]; !ok {
+			events.add(Event{Kind: NetnsDestroyed, Netns: netnsid})
+		}
+	}
+	for id, pid := range newfp.containers {
+		if oldpid, ok := fp.containers[id]; !ok || oldpid != pid {
+			events.add(Event{Kind: ContainerStarted, Container: id})
+		}
+	}
+	for id := range fp.containers {
+		if _, ok := newfp.containers[id]; !ok {
+			events.add(Event{Kind: ContainerStopped, Container: id})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"github.com/thediveo/lxkns/model"
+	"github.com/thediveo/lxkns/species"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scanning", func() {
+
+	It("deduplicates events", func() {
+		var s eventSet
+		Expect(s.empty()).To(BeTrue())
+		s.add(Event{Kind: LinkEvent, Netns: species.NamespaceIDfromInode(42)})
+		s.add(Event{Kind: AddressEvent, Netns: species.NamespaceIDfromInode(42)})
+		s.add(Event{Kind: LinkEvent, Netns: species.NamespaceIDfromInode(42)})
+		Expect(s.empty()).To(BeFalse())
+		Expect(s.take()).To(ConsistOf(
+			HaveField("Kind", LinkEvent),
+			HaveField("Kind", AddressEvent),
+		))
+		Expect(s.empty()).To(BeTrue())
+		s.add(Event{Kind: LinkEvent, Netns: species.NamespaceIDfromInode(42)})
+		Expect(s.take()).To(HaveLen(1))
+	})
+
+	It("diffs fingerprints", func() {
+		fp := func(netns []uint64, cntrs map[string]model.PIDType) fingerprint {
+			f := fingerprint{
+				netns:      map[species.NamespaceID]struct{}{},
+				containers: cntrs,
+			}
+			for _, ino := range netns {
+				f.netns[species.NamespaceIDfromInode(ino)] = struct{}{}
+			}
+			return f
+		}
+		oldfp := fp([]uint64{1, 2}, map[string]model.PIDType{"a": 100, "b": 200})
+		newfp := fp([]uint64{2, 3}, map[string]model.PIDType{"b": 201, "c": 300})
+		var events eventSet
+		oldfp.diff(newfp, &events)
+		Expect(events.take()).To(ConsistOf(
+			Event{Kind: NetnsCreated, Netns: species.NamespaceIDfromInode(3)},
+			Event{Kind: NetnsDestroyed, Netns: species.NamespaceIDfromInode(1)},
+			Event{Kind: ContainerStarted, Container: "b"},
+			Event{Kind: ContainerStarted, Container: "c"},
+			Event{Kind: ContainerStopped, Container: "a"},
+		))
+
+		oldfp.diff(oldfp, &events)
+		Expect(events.empty()).To(BeTrue())
+	})
+
+	It("fingerprints nothing", func() {
+		f := fingerprintOf(nil)
+		Expect(f.netns).To(BeEmpty())
+		Expect(f.containers).To(BeEmpty())
+	})
+
+})
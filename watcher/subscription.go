@@ -0,0 +1,60 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+// Subscription delivers Change notifications from a Watcher.
+type Subscription struct {
+	// C receives the changes; it gets closed when the subscription is closed
+	// or the Watcher stops running.
+	C <-chan Change
+
+	w      *Watcher
+	c      chan Change
+	missed bool
+}
+
+// Subscribe returns a new Subscription buffering up to the specified number of
+// changes. Changes are never blocking the Watcher: when the buffer is full,
+// changes get dropped and the next delivered Change has its Missed flag set.
+// As each Change carries the complete Snapshot, subscribers missing some
+// changes still get the most recent Snapshot eventually.
+func (w *Watcher) Subscribe(buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	c := make(chan Change, buffer)
+	sub := &Subscription{C: c, w: w, c: c}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		close(c)
+		return sub
+	}
+	w.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends this subscription, closing its channel C.
+func (s *Subscription) Close() {
+	s.w.mu.Lock()
+	defer s.w.mu.Unlock()
+	if _, ok := s.w.subs[s]; !ok {
+		return
+	}
+	delete(s.w.subs, s)
+	close(s.c)
+}
+
+// publish sends the change without blocking; it must be called with the
+// Watcher's lock held.
+func (s *Subscription) publish(change Change) {
+	change.Missed = s.missed
+	select {
+	case s.c <- change:
+		s.missed = false
+	default:
+		s.missed = true
+	}
+}
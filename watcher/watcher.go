@@ -0,0 +1,231 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gostwire "github.com/siemens/ghostwire/v2"
+
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/log"
+)
+
+// Default timings of a Watcher.
+const (
+	DefaultSettleTime     = 500 * time.Millisecond
+	DefaultRescanInterval = 5 * time.Second
+)
+
+// Snapshot is an immutable discovery result at a certain point in time.
+type Snapshot struct {
+	Seq    uint64    // sequence number, starting with 1 for the initial discovery.
+	Time   time.Time // when the discovery finished.
+	Result gostwire.DiscoveryResult
+}
+
+// Change notifies subscribers about a new Snapshot and the events that caused
+// it.
+type Change struct {
+	Snapshot *Snapshot
+	Events   []Event
+	Missed   bool // subscriber was too slow and missed earlier changes.
+}
+
+// Watcher keeps a discovery result up to date, rediscovering the host after
+// RTNETLINK notifications or after namespaces and containers came or went.
+type Watcher struct {
+	cizer          containerizer.Containerizer
+	labels         map[string]string
+	opts           []gostwire.DiscoveryOption
+	settleTime     time.Duration
+	rescanInterval time.Duration
+	retain         int // number of recent snapshots to retain.
+
+	ready chan struct{} // closed after the initial discovery.
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+	recent   []*Snapshot // retained recent snapshots, oldest first.
+	subs     map[*Subscription]struct{}
+	stopped  bool
+}
+
+// Option configures a Watcher when passed to New.
+type Option func(*Watcher)
+
+// WithLabels passes the specified labels to each discovery.
+func WithLabels(labels map[string]string) Option {
+	return func(w *Watcher) {
+		w.labels = labels
+	}
+}
+
+// WithDiscoveryOptions passes the specified options to each discovery.
+func WithDiscoveryOptions(opts ...gostwire.DiscoveryOption) Option {
+	return func(w *Watcher) {
+		w.opts = append(w.opts, opts...)
+	}
+}
+
+// WithSettleTime sets the time to wait after the first event before updating
+// the discovery result, batching all events arriving in the meantime.
+func WithSettleTime(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.settleTime = d
+	}
+}
+
+// WithRescanInterval sets the interval of the lightweight scans for network
+// namespaces and containers coming and going.
+func WithRescanInterval(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.rescanInterval = d
+	}
+}
+
+// WithRetain sets the number of recent snapshots to retain, so that they can
+// be retrieved later using SnapshotAt. By default, only the current snapshot
+// is retained.
+func WithRetain(n int) Option {
+	return func(w *Watcher) {
+		if n < 1 {
+			n = 1
+		}
+		w.retain = n
+	}
+}
+
+// New returns a new Watcher using the specified containerizer. The Watcher
+// starts working only after calling Run.
+func New(cizer containerizer.Containerizer, opts ...Option) *Watcher {
+	w := &Watcher{
+		cizer:          cizer,
+		settleTime:     DefaultSettleTime,
+		rescanInterval: DefaultRescanInterval,
+		retain:         1,
+		ready:          make(chan struct{}),
+		subs:           map[*Subscription]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Ready returns a channel that gets closed as soon as the initial discovery
+// has finished and a Snapshot is available.
+func (w *Watcher) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Snapshot returns the most recent Snapshot, or nil if the initial discovery
+// hasn't finished yet.
+func (w *Watcher) Snapshot() *Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.snapshot
+}
+
+// SnapshotAt returns the retained Snapshot with the specified sequence number,
+// or nil if there is no such Snapshot (anymore).
+func (w *Watcher) SnapshotAt(seq uint64) *Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, snap := range w.recent {
+		if snap.Seq == seq {
+			return snap
+		}
+	}
+	return nil
+}
+
+// Run runs the initial discovery and then reruns the discovery after events
+// until the specified context gets cancelled. When Run returns, all
+// subscriptions get closed.
+func (w *Watcher) Run(ctx context.Context) error {
+	events := make(chan Event, 64)
+	watches := newRtnlWatches(ctx, events)
+	defer func() {
+		watches.close()
+		w.stop()
+	}()
+
+	pending := eventSet{}
+	snap := w.update(ctx, nil)
+	last := fingerprintOf(snap.Result.Lxkns)
+	watches.sync(snap.Result.Netns)
+
+	rescan := time.NewTicker(w.rescanInterval)
+	defer rescan.Stop()
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-events:
+			if ev.Kind == Resync {
+				watches.unwatch(ev.Netns)
+			}
+			pending.add(ev)
+		case <-rescan.C:
+			fp := scan(w.cizer)
+			last.diff(fp, &pending)
+			last = fp
+		case <-settle:
+			settle = nil
+			snap = w.update(ctx, pending.take())
+			last = fingerprintOf(snap.Result.Lxkns)
+			watches.sync(snap.Result.Netns)
+			continue
+		}
+		if settle == nil && !pending.empty() {
+			settle = time.After(w.settleTime)
+		}
+	}
+}
+
+// update runs a full discovery, publishes its result as a new Snapshot, and
+// notifies all subscribers about the events that triggered it.
+func (w *Watcher) update(ctx context.Context, events []Event) *Snapshot {
+	result := gostwire.Discover(ctx, w.cizer, w.labels, w.opts...)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var seq uint64 = 1
+	if w.snapshot != nil {
+		seq = w.snapshot.Seq + 1
+	}
+	w.snapshot = &Snapshot{
+		Seq:    seq,
+		Time:   time.Now(),
+		Result: result,
+	}
+	w.recent = append(w.recent, w.snapshot)
+	if len(w.recent) > w.retain {
+		w.recent = append([]*Snapshot(nil), w.recent[len(w.recent)-w.retain:]...)
+	}
+	if seq == 1 {
+		close(w.ready)
+		return w.snapshot
+	}
+	log.Debugf("watcher updated discovery due to %d events", len(events))
+	for sub := range w.subs {
+		sub.publish(Change{Snapshot: w.snapshot, Events: events})
+	}
+	return w.snapshot
+}
+
+// stop closes all subscriptions and refuses new subscriptions.
+func (w *Watcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for sub := range w.subs {
+		delete(w.subs, sub)
+		close(sub.c)
+	}
+}
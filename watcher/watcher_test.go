@@ -0,0 +1,157 @@
+// (c) Siemens AG 2023
+//
+// SPDX-License-Identifier: MIT
+
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/siemens/turtlefinder"
+	"github.com/thediveo/lxkns/containerizer"
+	"github.com/thediveo/lxkns/species"
+	"github.com/thediveo/notwork/netns"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gleak"
+	. "github.com/thediveo/fdooze"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("watcher", func() {
+
+	It("doesn't block on slow subscribers", func() {
+		w := New(nil)
+		sub := w.Subscribe(1)
+		w.mu.Lock()
+		for seq := uint64(1); seq <= 3; seq++ {
+			sub.publish(Change{Snapshot: &Snapshot{Seq: seq}})
+		}
+		w.mu.Unlock()
+		Expect(sub.C).To(Receive(And(
+			HaveField("Snapshot.Seq", uint64(1)),
+			HaveField("Missed", false))))
+		w.mu.Lock()
+		sub.publish(Change{Snapshot: &Snapshot{Seq: 4}})
+		w.mu.Unlock()
+		Expect(sub.C).To(Receive(And(
+			HaveField("Snapshot.Seq", uint64(4)),
+			HaveField("Missed", true))))
+
+		sub.Close()
+		Expect(sub.C).To(BeClosed())
+		sub.Close()
+	})
+
+	It("closes subscriptions when stopping", func() {
+		w := New(nil)
+		sub := w.Subscribe(0)
+		w.stop()
+		Expect(sub.C).To(BeClosed())
+		Expect(w.Subscribe(1).C).To(BeClosed())
+	})
+
+	When("watching", func() {
+
+		var cizer containerizer.Containerizer
+
+		BeforeEach(func() {
+			if os.Getuid() != 0 {
+				Skip("needs root")
+			}
+
+			goodfds := Filedescriptors()
+			goodgos := Goroutines()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cizer = turtlefinder.New(func() context.Context { return ctx })
+
+			DeferCleanup(func() {
+				cancel()
+				cizer.Close()
+				Eventually(Goroutines).WithTimeout(2 * time.Second).WithPolling(250 * time.Millisecond).
+					ShouldNot(HaveLeaked(goodgos))
+				Expect(Filedescriptors()).NotTo(HaveLeakedFds(goodfds))
+			})
+		})
+
+		It("notices network namespaces and links coming and going", NodeTimeout(60*time.Second), func(ctx context.Context) {
+			w := New(cizer,
+				WithSettleTime(100*time.Millisecond),
+				WithRescanInterval(250*time.Millisecond),
+				WithRetain(100))
+			Expect(w.Snapshot()).To(BeNil())
+
+			ctx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(w.Run(ctx)).To(MatchError(context.Canceled))
+			}()
+			defer func() {
+				cancel()
+				Eventually(done).Should(BeClosed())
+			}()
+
+			Eventually(w.Ready()).WithTimeout(20 * time.Second).Should(BeClosed())
+			initial := w.Snapshot()
+			Expect(initial).NotTo(BeNil())
+			Expect(initial.Seq).To(Equal(uint64(1)))
+			Expect(initial.Result.Netns).NotTo(BeEmpty())
+
+			sub := w.Subscribe(16)
+			defer sub.Close()
+
+			By("creating a new network namespace")
+			sleepy := exec.Command("unshare", "-n", "sleep", "60")
+			Expect(sleepy.Start()).To(Succeed())
+			defer func() { _ = sleepy.Process.Kill(); _ = sleepy.Wait() }()
+			netnspath := fmt.Sprintf("/proc/%d/ns/net", sleepy.Process.Pid)
+			var netnsid species.NamespaceID
+			Eventually(func() uint64 {
+				netnsid, _ = species.IDwithType(
+					fmt.Sprintf("net:[%d]", netns.Ino(netnspath)))
+				return netnsid.Ino
+			}).Should(Not(Equal(netns.CurrentIno())))
+			Eventually(sub.C).WithTimeout(10 * time.Second).Should(Receive(
+				HaveField("Events", ContainElement(Event{Kind: NetnsCreated, Netns: netnsid}))))
+			Eventually(func() bool {
+				_, ok := w.Snapshot().Result.Netns[netnsid]
+				return ok
+			}).Should(BeTrue())
+
+			By("bringing up the loopback interface in the new network namespace")
+			fd := Successful(unix.Open(netnspath, unix.O_RDONLY, 0))
+			defer unix.Close(fd)
+			nlh := netns.NewNetlinkHandle(fd)
+			defer nlh.Close()
+			lo := Successful(nlh.LinkByName("lo"))
+			Expect(nlh.LinkSetUp(lo)).To(Succeed())
+			Eventually(sub.C).WithTimeout(10 * time.Second).Should(Receive(
+				HaveField("Events", ContainElement(Event{Kind: LinkEvent, Netns: netnsid}))))
+			Expect(w.Snapshot().Result.Netns[netnsid].Nifs).To(HaveLen(1))
+
+			By("destroying the new network namespace")
+			nlh.Close()
+			unix.Close(fd)
+			_ = sleepy.Process.Kill()
+			_ = sleepy.Wait()
+			Eventually(sub.C).WithTimeout(10 * time.Second).Should(Receive(
+				HaveField("Events", ContainElement(Event{Kind: NetnsDestroyed, Netns: netnsid}))))
+			Expect(w.Snapshot().Result.Netns).NotTo(HaveKey(netnsid))
+			Expect(w.Snapshot().Seq).To(BeNumerically(">", initial.Seq))
+			Expect(w.SnapshotAt(initial.Seq)).To(BeIdenticalTo(initial))
+			Expect(w.SnapshotAt(w.Snapshot().Seq)).To(BeIdenticalTo(w.Snapshot()))
+			Expect(w.SnapshotAt(w.Snapshot().Seq + 1)).To(BeNil())
+		})
+
+	})
+
+})